@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"go-agent-sdk/llm"
 	"go-agent-sdk/tools"
+	"math/rand/v2"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,12 +21,45 @@ import (
 // client. This lets you swap providers (OpenAI, Anthropic, Gemini, OpenRouter)
 // without changing agent code.
 type Agent struct {
-	provider     llm.ChatProvider // Any LLM backend that implements ChatProvider
-	SystemPrompt string           // Instructions for the LLM's behavior
-	MaxRetries   int              // How many times to retry on failure
-	History      []llm.Message    // The conversation so far
-	tools        *tools.Registry  // Registered tools the LLM can call
-	callback     Callback         // optional observer, fires at key moments during Run(). nil means silent.
+	provider      llm.ChatProvider  // Any LLM backend that implements ChatProvider
+	SystemPrompt  string            // Instructions for the LLM's behavior
+	MaxRetries    int               // How many times to retry a failed LLM call (see WithRetryPolicy)
+	History       []llm.Message     // The conversation so far
+	Usage         llm.Usage         // Tokens used across every Run so far
+	Cost          float64           // Dollars spent across every Run so far, with WithPricing
+	tools         *tools.Registry   // Registered tools the LLM can call
+	callbacks     callbacks         // observers, fire at key moments during Run(). empty means silent.
+	now           func() time.Time  // clock for latencies and timestamps, defaults to time.Now
+	rand          *rand.Rand        // random source handed to providers and tools. nil means their own default.
+	onDelta       llm.StreamHandler // receives streamed tokens. nil means blocking calls.
+	answerLimit   *AnswerLimit      // caps the final answer length. nil means no limit.
+	pruneFailed   bool              // drop failed tool call/error pairs after a final answer
+	failedCalls   map[string]bool   // tool call IDs that errored since the last final answer
+	effects       []tools.Effect    // side effects tools recorded, across every Run
+	memory        Memory            // compacts the history before each request. nil keeps everything.
+	session       *boundSession     // loaded before the first Run and saved after each. nil means none.
+	interrupts    interruptState    // requested with Agent.Interrupt, taken by the next Run
+	historyPolicy HistoryPolicy     // trims each request's messages. nil sends the whole history.
+	handoverOpts  *handover         // applied to sessions as they're loaded. nil loads them as they are.
+	price         PriceFunc         // prices each LLM call. nil leaves costs at 0.
+
+	maxToolIterations int                 // rounds of tool calls allowed per Run. 0 means unlimited.
+	toolConcurrency   int                 // tools run at once when the LLM asks for several. <= 1 means sequential.
+	toolMiddleware    []tools.Middleware  // wraps every tool call, outermost first
+	toolHandler       tools.Handler       // tools.Chain of the middleware. nil means a.tools.Handle.
+	toolFilters       []ToolFilter        // every one must allow a tool for it to be offered or run
+	observers         []*observerQueue    // get a copy of every finished Run, in the background
+	messageMiddleware []MessageMiddleware // sees each user message before it joins the history
+	contextProviders  []ContextProvider   // add to the system prompt for one Run
+	validators        []Validator         // check each final answer; a rejection asks the model for a fix
+	maxRepairs        int                 // fixes asked for per Run before giving up
+	inputGuardrails   []Guardrail         // check each user message; can reject or rewrite it
+	outputGuardrails  []Guardrail         // check each final answer; can reject or rewrite it
+
+	params params      // generation settings (temperature, max tokens, ...) copied into every request
+	retry  RetryPolicy // backoff between retries of failed LLM calls
+
+	sanitizers []ToolResultSanitizer // inspect each tool result before it joins the history
 }
 
 // Option is a function that configures an Agent.
@@ -60,6 +96,12 @@ func New(provider llm.ChatProvider, opts ...Option) *Agent {
 		MaxRetries: 1,
 		History:    make([]llm.Message, 0),
 		tools:      tools.NewRegistry(),
+		now:        time.Now,
+
+		maxToolIterations: DefaultMaxToolIterations,
+		params:            params{temperature: DefaultTemperature},
+		retry:             DefaultRetryPolicy,
+		maxRepairs:        DefaultMaxRepairs,
 	}
 
 	// Apply each option to customize the agent
@@ -76,6 +118,32 @@ func New(provider llm.ChatProvider, opts ...Option) *Agent {
 	return a
 }
 
+// SetProvider swaps the LLM backend on an existing agent, keeping the
+// conversation. Use it to escalate a long conversation from a cheap model
+// to a stronger one once the task gets hard:
+//
+//	if needsMorePower {
+//	    a.SetProvider(anthropic.New(key, "claude-opus-4-20250514"))
+//	}
+//	reply, err := a.Run(ctx, "Now refactor the whole module.")
+//
+// Histories recorded with one provider can contain tool call IDs another
+// provider rejects. If the new provider implements llm.ToolCallIDNormalizer,
+// the stored history is rewritten once here so every ID fits its rules -
+// calls and their results stay linked.
+//
+// Reasoning signatures are only valid for the provider that issued them,
+// so they are dropped from the history. The reasoning text is kept.
+func (a *Agent) SetProvider(provider llm.ChatProvider) {
+	a.provider = provider
+	for i := range a.History {
+		a.History[i].ReasoningSignature = ""
+	}
+	if n, ok := provider.(llm.ToolCallIDNormalizer); ok {
+		a.History = llm.NormalizeToolCallIDs(a.History, n.NormalizeToolCallID)
+	}
+}
+
 // WithSystemPrompts sets the system prompt for the agent.
 // The system prompt guides the LLM's behavior and personality.
 // It's automatically added as the first message in the history.
@@ -87,6 +155,9 @@ func WithSystemPrompts(prompt string) Option {
 
 // WithMaxRetries sets how many times to retry failed requests.
 // This is useful for handling temporary network issues or rate limits.
+// Only transient failures are retried (429, 5xx, network errors), with
+// exponential backoff between attempts - see WithRetryPolicy.
+// 0 disables retries. The default is 1.
 func WithMaxRetries(n int) Option {
 	return func(a *Agent) {
 		a.MaxRetries = n
@@ -95,7 +166,13 @@ func WithMaxRetries(n int) Option {
 
 // RegisterTool adds a function that the LLM can call.
 // The function must take a single struct argument with JSON tags
-// and return a string (or something convertible to string).
+// and return a string, or a string and an error. A returned error is
+// sent to the LLM as a tool error so it can retry or explain.
+//
+// If the function takes a context.Context as its first parameter, it gets
+// the ctx passed to Run - use it for any I/O so cancellation reaches the tool:
+//
+//	func FetchPage(ctx context.Context, args FetchArgs) (string, error) { ... }
 //
 // The struct's fields define what parameters the LLM should provide.
 // For example:
@@ -107,8 +184,160 @@ func WithMaxRetries(n int) Option {
 //	func GetWeather(args WeatherArgs) string { ... }
 //
 //	agent.RegisterTool("get_weather", "Get current weather", GetWeather)
-func (a *Agent) RegisterTool(name, description string, fn any) error {
-	return a.tools.Register(name, description, fn)
+//
+// opts configure the tool - tools.WithTimeout stops a slow or hung tool
+// from stalling the whole Run:
+//
+//	agent.RegisterTool("fetch_page", "Fetch a web page", FetchPage, tools.WithTimeout(10*time.Second))
+func (a *Agent) RegisterTool(name, description string, fn any, opts ...tools.ToolOption) error {
+	return a.tools.Register(name, description, fn, opts...)
+}
+
+// RegisterToolset registers a group of tools along with the guidance for
+// using them. The toolset's Prompt is appended to the system prompt of
+// every request from then on - the stored history isn't changed, so
+// toolsets can be registered at any time.
+//
+//	a.RegisterToolset(tools.Toolset{
+//	    Name:   "sql",
+//	    Prompt: "When using the SQL tools, never modify data.",
+//	    Tools:  []tools.Tool{{Name: "sql_query", Description: "Run a SQL query", Func: Query}},
+//	})
+func (a *Agent) RegisterToolset(ts tools.Toolset) error {
+	return a.tools.RegisterToolset(ts)
+}
+
+// withToolsetPrompts returns msgs with the toolsets' prompts and the Run's
+// context blocks appended to the system message, adding one if there isn't
+// any. msgs itself is left alone.
+func (a *Agent) withToolsetPrompts(msgs []llm.Message, blocks ...string) []llm.Message {
+	prompts := append(a.tools.Prompts(), blocks...)
+	if len(prompts) == 0 {
+		return msgs
+	}
+	guidance := strings.Join(prompts, "\n\n")
+
+	if len(msgs) > 0 && msgs[0].Role == "system" {
+		out := append([]llm.Message(nil), msgs...)
+		out[0].Content += "\n\n" + guidance
+		return out
+	}
+	return append([]llm.Message{llm.NewSystemMessage(guidance)}, msgs...)
+}
+
+// DefaultMaxToolIterations is how many rounds of tool calls a single Run
+// allows unless WithMaxToolIterations says otherwise. Real tasks rarely need
+// more than a handful; a model stuck calling tools burns tokens fast.
+const DefaultMaxToolIterations = 10
+
+// ToolMiddleware wraps tool execution; see tools.Middleware.
+type ToolMiddleware = tools.Middleware
+
+// WithToolMiddleware wraps every tool call in mw, for logging, metrics,
+// permission checks, argument redaction, caching and the like. The first
+// middleware is the outermost. Calling it again adds to the chain.
+//
+//	a := agent.New(provider,
+//	    agent.WithToolMiddleware(Logging, ReadOnly),
+//	)
+//
+// The callback's OnToolCall and OnToolResult fire outside the chain, so
+// they see what the LLM sent and what it gets back.
+func WithToolMiddleware(mw ...ToolMiddleware) Option {
+	return func(a *Agent) {
+		a.toolMiddleware = append(a.toolMiddleware, mw...)
+		a.toolHandler = tools.Chain(a.tools.Handle, a.toolMiddleware...)
+	}
+}
+
+// MessageMiddleware sees each user message before it joins the history,
+// and can change it - tag it in Metadata, rewrite its text, or return an
+// error to stop the Run before anything is sent.
+type MessageMiddleware func(ctx context.Context, msg *llm.Message) error
+
+// WithMessageMiddleware runs mw on every user message passed to Run, in
+// order. Calling it again adds to the list. See agent/tagging for one that
+// labels messages with intent and sentiment.
+func WithMessageMiddleware(mw ...MessageMiddleware) Option {
+	return func(a *Agent) {
+		a.messageMiddleware = append(a.messageMiddleware, mw...)
+	}
+}
+
+// ToolFilter reports whether the tool called name may be used right now.
+type ToolFilter func(name string) bool
+
+// WithToolFilter restricts which registered tools the model gets to see,
+// request by request. The filter runs before every LLM call; tools it
+// rejects are left out of the request, and if the model calls one anyway
+// (from memory of an earlier turn, say) the call fails with a
+// *ToolNotAllowedError instead of running. With several filters a tool
+// must pass all of them.
+//
+//	a := agent.New(provider, agent.WithToolFilter(func(name string) bool {
+//	    return !strings.HasPrefix(name, "admin_") || user.IsAdmin()
+//	}))
+//
+// Filters make tool availability depend on state - see agent/flow's
+// Phases for a state machine built on them.
+func WithToolFilter(filter ToolFilter) Option {
+	return func(a *Agent) {
+		a.toolFilters = append(a.toolFilters, filter)
+	}
+}
+
+// toolAllowed reports whether every filter allows the tool.
+func (a *Agent) toolAllowed(name string) bool {
+	for _, allow := range a.toolFilters {
+		if !allow(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// offeredTools is the tool list for the next request: the registered
+// tools the filters allow.
+func (a *Agent) offeredTools() []llm.Tool {
+	all := a.tools.GetAllTools()
+	if len(a.toolFilters) == 0 {
+		return all
+	}
+	out := make([]llm.Tool, 0, len(all))
+	for _, t := range all {
+		if a.toolAllowed(t.Function.Name) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// WithMaxToolIterations caps how many rounds of tool calls one Run may do.
+// A round is one LLM response asking for tools (however many calls it
+// contains) plus executing them. When the model asks for round n+1, Run
+// returns an error wrapping ErrMaxIterations - check for it with errors.Is.
+//
+// Pass 0 to remove the limit.
+func WithMaxToolIterations(n int) Option {
+	return func(a *Agent) {
+		a.maxToolIterations = n
+	}
+}
+
+// WithToolConcurrency lets the agent run up to n tool calls at the same time
+// when the LLM asks for several in one turn (e.g. weather for three cities).
+// Results still go into the history in the order the LLM requested them,
+// each linked by its tool_call_id, so the conversation looks the same as
+// with sequential execution - it just gets there faster.
+//
+// Your tools must be safe to run concurrently, and so must your Callback:
+// OnToolCall and OnToolResult fire from the worker goroutines.
+//
+// n <= 1 means sequential execution, which is the default.
+func WithToolConcurrency(n int) Option {
+	return func(a *Agent) {
+		a.toolConcurrency = n
+	}
 }
 
 // WithCallback attaches an observer to the agent's internal execution.
@@ -123,12 +352,148 @@ func (a *Agent) RegisterTool(name, description string, fn any) error {
 //	a := agent.New(provider,
 //	    agent.WithCallback(&agent.DebugCallback{}),
 //	)
-func WithCallback(cb Callback) Option {
+//
+// Several callbacks - given together or with several WithCallback options -
+// are all called, in the order they were added. To get the run's context
+// in every method, implement ContextCallback and use WithContextCallback.
+func WithCallback(cbs ...Callback) Option {
+	return func(a *Agent) {
+		for _, cb := range cbs {
+			if cb != nil {
+				a.callbacks = append(a.callbacks, legacyCallback{cb})
+			}
+		}
+	}
+}
+
+// WithContextCallback is WithCallback for callbacks that want the run's
+// context with each event. They're called in order with any added by
+// WithCallback.
+func WithContextCallback(cbs ...ContextCallback) Option {
+	return func(a *Agent) {
+		for _, cb := range cbs {
+			if cb != nil {
+				a.callbacks = append(a.callbacks, cb)
+			}
+		}
+	}
+}
+
+// WithClock replaces time.Now as the agent's source of time.
+// The clock is used for latency measurements and is attached to the Run
+// context, so providers and tools that call llm.Now(ctx) see the same time.
+// A nil clock puts time.Now back.
+//
+// Together with WithRandSeed this makes traces and histories reproducible:
+//
+//	t := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+//	a := agent.New(provider,
+//	    agent.WithClock(func() time.Time { return t }),
+//	    agent.WithRandSeed(42),
+//	)
+func WithClock(now func() time.Time) Option {
+	return func(a *Agent) {
+		if now == nil {
+			now = time.Now
+		}
+		a.now = now
+	}
+}
+
+// WithRandSeed gives the agent a seeded random source.
+// The source is attached to the Run context (see llm.RandFromContext), so
+// anything random during a run - generated tool call IDs, retry jitter -
+// comes out the same for the same seed.
+func WithRandSeed(seed uint64) Option {
+	return func(a *Agent) {
+		a.rand = llm.NewRand(seed)
+	}
+}
+
+// WithStreaming makes the agent stream responses, passing every text and
+// tool call fragment to fn as it arrives. Run still returns the complete
+// answer, and tool calls work exactly as they do without streaming. With
+// WithAnswerLimit, the text fn gets stops at the limit as well.
+//
+// If the provider doesn't implement llm.StreamingChatProvider, the agent
+// falls back to a blocking call and hands fn the whole answer as one delta.
+//
+// Example - print tokens as they come:
+//
+//	a := agent.New(provider,
+//	    agent.WithStreaming(func(d llm.StreamDelta) {
+//	        fmt.Print(d.Content)
+//	    }),
+//	)
+func WithStreaming(fn llm.StreamHandler) Option {
 	return func(a *Agent) {
-		a.callback = cb
+		a.onDelta = fn
 	}
 }
 
+// createChat sends req to the provider, streaming if the agent was
+// configured to and the provider supports it. Transient failures are
+// retried according to MaxRetries and the retry policy.
+func (a *Agent) createChat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	if a.onDelta == nil {
+		return a.withRetry(ctx, func() (*llm.ChatResponse, error) {
+			return a.provider.CreateChat(ctx, req)
+		})
+	}
+	clip := a.streamLimit()
+	if sp, ok := a.provider.(llm.StreamingChatProvider); ok {
+		// Once tokens have reached the handler a retry would repeat them,
+		// so a stream that fails part-way is not retried.
+		started := false
+		onDelta := func(d llm.StreamDelta) {
+			if d.Usage != nil {
+				a.callbacks.OnStreamUsage(ctx, *d.Usage)
+			}
+			// A delta with nothing but token counts isn't output.
+			if d != (llm.StreamDelta{Usage: d.Usage}) {
+				started = true
+			}
+			if d.Content != "" {
+				if d.Content = clip.text(d.Content); d == (llm.StreamDelta{}) {
+					return // all of it past the answer limit, or held back
+				}
+			}
+			a.onDelta(d)
+		}
+		resp, err := a.withRetry(ctx, func() (*llm.ChatResponse, error) {
+			resp, err := sp.CreateChatStream(ctx, req, onDelta)
+			if err != nil && started {
+				return nil, noRetry{err}
+			}
+			return resp, err
+		})
+		if rest := clip.rest(); err == nil && rest != "" {
+			a.onDelta(llm.StreamDelta{Content: rest})
+		}
+		return resp, err
+	}
+
+	resp, err := a.withRetry(ctx, func() (*llm.ChatResponse, error) {
+		return a.provider.CreateChat(ctx, req)
+	})
+	if err == nil && len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+		a.onDelta(llm.StreamDelta{Content: clip.text(resp.Choices[0].Message.Content) + clip.rest()})
+	}
+	return resp, err
+}
+
+// runContext attaches the agent's clock and random source to ctx
+// so providers and tools can pick them up, and notes the agent is running
+// for AsTool.
+func (a *Agent) runContext(ctx context.Context) context.Context {
+	ctx = withCallingAgent(ctx, a)
+	ctx = llm.ContextWithClock(ctx, a.now)
+	if a.rand != nil {
+		ctx = llm.ContextWithRand(ctx, a.rand)
+	}
+	return ctx
+}
+
 // Run sends a message to the LLM and returns the response.
 // It handles the full conversation flow including history management and tool execution.
 //
@@ -148,13 +513,20 @@ func WithCallback(cb Callback) Option {
 //   - Add assistant message containing the tool_calls to history (CRITICAL!)
 //   - Execute each requested tool using our registry
 //   - Add tool results to history with proper tool_call_id linkage
-//   - Recurse: Call Run again with empty message so LLM sees results
+//   - Loop: send the history again (no new user message) so the LLM sees results
 //   - LLM generates final text response incorporating tool results
 //   - Return final answer
 //
-// The recursion is key here - after executing tools, we call Run again
-// with an empty user message. This lets the LLM "see" the tool results
-// in the conversation history and generate a coherent response.
+// The loop is key here - after executing tools, we call the LLM again
+// without adding a user message. This lets the LLM "see" the tool results
+// in the conversation history and generate a coherent response. The LLM
+// may ask for more tools on that turn, so the loop can go round several times.
+//
+// A misbehaving model can keep asking for tools forever. Each round of tool
+// calls counts as one iteration; once WithMaxToolIterations is exceeded Run
+// stops and returns an error wrapping ErrMaxIterations. Asking the model to
+// repair an answer a validator rejected isn't a round of tool calls, and
+// doesn't count.
 //
 // Example tool calling flow:
 //
@@ -162,109 +534,338 @@ func WithCallback(cb Callback) Option {
 //	LLM decides to call get_weather with {"city": "Paris"}
 //	We execute get_weather - returns "Sunny, 22C"
 //	We add the tool result to history, linked by tool_call_id
-//	We recurse - call Run("") so the LLM sees the result
+//	We loop - call the LLM again so it sees the result
 //	LLM sees the tool result and responds: "It's sunny and 22C in Paris!"
 //
 // Example:
 //
 //	reply, err := agent.Run(ctx, "What is the weather in Paris?")
-func (a *Agent) Run(ctx context.Context, usrMsg string) (string, error) {
+//
+// RunOptions override the agent's settings for this call only:
+//
+//	reply, err := agent.Run(ctx, "Write a poem.", agent.RunWithTemperature(1.1))
+func (a *Agent) Run(ctx context.Context, usrMsg string, opts ...RunOption) (string, error) {
+	res, err := a.RunWithResult(ctx, usrMsg, opts...)
+	if err != nil {
+		return "", err
+	}
+	return res.Content, nil
+}
+
+// RunWithResult works exactly like Run but returns everything that happened
+// instead of just the final text: token usage summed across every LLM call
+// in the run, the model that answered, the finish reason, and a turn-by-turn
+// trace with each request, response, and tool execution.
+//
+// On error the partial result is still returned, so you can see how far
+// the run got:
+//
+//	res, err := a.RunWithResult(ctx, "What's the weather in Paris?")
+//	if err != nil {
+//	    log.Printf("failed after %d turns: %v", len(res.Turns), err)
+//	}
+//	fmt.Println(res.Content, res.Usage.TotalTokens)
+func (a *Agent) RunWithResult(ctx context.Context, usrMsg string, opts ...RunOption) (res *RunResult, err error) {
+	ctx = a.runContext(ctx)
+	cfg := a.runConfig(opts)
+
+	res = &RunResult{ID: llm.NewRunID(ctx), Input: usrMsg}
+	if a.session != nil {
+		res.Session = a.session.id
+	}
+	ctx = runTrace(ctx, res.ID)
+	a.callbacks.OnRunStart(ctx, res.ID, usrMsg)
+	// Deferred first so it runs last, once the session save has had its say.
+	defer func(ctx context.Context) {
+		if err != nil {
+			a.callbacks.OnError(ctx, err)
+		}
+		a.callbacks.OnRunEnd(ctx, res, err)
+	}(ctx)
+	if err := cfg.checkToolChoice(a.offeredTools()); err != nil {
+		return res, err
+	}
+	if err := a.loadSession(ctx); err != nil {
+		return res, err
+	}
+	ctx, interrupts := withInterrupts(ctx)
+	tc, _ := llm.TraceFromContext(ctx)
+	res.TraceID = tc.TraceID
+	runStart := a.now()
+	usageBefore := a.Usage
+	defer func() {
+		res.Duration = a.now().Sub(runStart)
+		res.Usage = a.Usage.Sub(usageBefore)
+		a.priceRun(res)
+		if saveErr := a.saveSession(context.WithoutCancel(ctx)); saveErr != nil && err == nil {
+			err = saveErr
+		}
+		a.notifyObservers(ctx, res, err)
+	}()
 
 	// Only add user message if it's not empty.
-	// Empty messages happen when we recurse after tool execution.
+	// An empty message just continues the conversation from the current history.
 	if usrMsg != "" {
-		userMessage := llm.NewUserMessage(usrMsg)
+		text, err := checkGuardrails(ctx, res, GuardrailInput, a.inputGuardrails, usrMsg)
+		if err != nil {
+			return res, err
+		}
+		userMessage := llm.NewUserMessage(text)
+		for _, mw := range a.messageMiddleware {
+			if err := mw(ctx, &userMessage); err != nil {
+				return res, err
+			}
+		}
 		a.History = append(a.History, userMessage)
 	}
-
-	// Build the chat request including all available tools.
-	// Tools must be included in EVERY request - most LLM providers validate
-	// the tool schema on each call, even when the LLM is responding
-	// to previous tool results.
-	req := llm.ChatRequest{
-		Model:       a.provider.ModelName(),
-		Messages:    a.History,
-		Tools:       a.tools.GetAllTools(),
-		Temperature: 0.7, // Hardcoded for now - could make this configurable
+	blocks, err := a.runContextBlocks(ctx, usrMsg)
+	if err != nil {
+		return res, err
 	}
 
-	// let the callback see the full request before we send it
-	if a.callback != nil {
-		a.callback.OnLLMRequest(req)
-	}
+	rejected := 0   // answers validators rejected this Run
+	toolRounds := 0 // rounds of tool calls run so far; repair rounds don't count
+	for {
+		if err := a.takeInterrupt(interrupts, res.ID); err != nil {
+			return res, err
+		}
+		if err := a.compactHistory(ctx); err != nil {
+			return res, err
+		}
 
-	// track how long the LLM takes to respond
-	start := time.Now()
-	resp, err := a.provider.CreateChat(ctx, req)
-	latency := time.Since(start)
+		// Build the chat request including all available tools.
+		// Tools must be included in EVERY request - most LLM providers validate
+		// the tool schema on each call, even when the LLM is responding
+		// to previous tool results.
+		req := llm.ChatRequest{
+			Model:    a.provider.ModelName(),
+			Messages: a.withToolsetPrompts(a.History, blocks...),
+			Tools:    a.offeredTools(),
+		}
+		cfg.apply(&req, toolRounds)
+		if a.historyPolicy != nil {
+			a.historyPolicy(&req)
+		}
 
-	if err != nil {
-		return "", fmt.Errorf("LLM call failed: %w", err)
-	}
+		// let the callback see the full request before we send it
+		a.callbacks.OnLLMRequest(ctx, req)
 
-	// let the callback see the full response and how long it took
-	if a.callback != nil {
-		a.callback.OnLLMResponse(*resp, latency)
-	}
+		// track how long the LLM takes to respond
+		start := a.now()
+		resp, err := a.createChat(ctx, req)
+		latency := a.now().Sub(start)
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("LLM returned no choices")
-	}
+		if err != nil {
+			return res, &ProviderError{Model: req.Model, Err: err}
+		}
+
+		a.Usage = a.Usage.Add(resp.Usage)
+		res.Turns = append(res.Turns, Turn{Request: req, Response: *resp, Latency: latency})
+		turn := &res.Turns[len(res.Turns)-1]
 
-	choice := resp.Choices[0]
-	finishReason := choice.FinishReason
+		// let the callback see the full response and how long it took
+		a.callbacks.OnLLMResponse(ctx, *resp, latency)
 
-	// Branch 1: LLM wants to call tools
-	if finishReason == "tool_calls" {
-		// CRITICAL: Must add the assistant's tool_calls message to history FIRST.
-		// The LLM needs to see its own request in the conversation context
-		// when we recurse. Without this, the tool_call_ids won't make sense.
-		assistantMsg := llm.NewToolCallMessage(choice.Message.ToolCalls)
-		a.History = append(a.History, assistantMsg)
+		if len(resp.Choices) == 0 {
+			return res, ErrNoChoices
+		}
+
+		choice := resp.Choices[0]
+		finishReason := choice.FinishReason
+		res.Model = resp.Model
+		res.FinishReason = finishReason
 
-		// Execute each tool the LLM requested.
-		// The LLM can request multiple tools in parallel (though we execute sequentially).
-		for _, call := range choice.Message.ToolCalls {
+		switch finishReason {
 
-			// let the callback see which tool is about to run and what args the LLM sent
-			if a.callback != nil {
-				a.callback.OnToolCall(call.Function.Name, call.Function.Arguments)
+		// Branch 1: LLM wants to call tools
+		case "tool_calls":
+			// Stop runaway loops before doing any more work. The history is left
+			// as it was after the last complete round, so it's still valid.
+			if a.maxToolIterations > 0 && toolRounds >= a.maxToolIterations {
+				return res, fmt.Errorf("%w: model still calling tools after %d rounds", ErrMaxIterations, a.maxToolIterations)
 			}
 
-			// run the tool and track how long it takes
-			toolStart := time.Now()
-			result, err := a.tools.Execute(call.Function.Name, call.Function.Arguments)
-			toolLatency := time.Since(toolStart)
+			// CRITICAL: Must add the assistant's tool_calls message to history FIRST.
+			// The LLM needs to see its own request in the conversation context
+			// on the next turn. Without this, the tool_call_ids won't make sense.
+			assistantMsg := llm.NewToolCallMessage(choice.Message.ToolCalls)
+			// Keep the thinking that led to the calls - providers with extended
+			// thinking need it (and its signature) back on the next request.
+			assistantMsg.Reasoning = choice.Message.Reasoning
+			assistantMsg.ReasoningSignature = choice.Message.ReasoningSignature
+			a.History = append(a.History, assistantMsg)
 
-			// let the callback see the outcome - result or error
-			if a.callback != nil {
-				a.callback.OnToolResult(call.Function.Name, result, err, toolLatency)
-			}
+			turn.ToolCalls = a.runToolCalls(ctx, choice.Message.ToolCalls)
+			toolRounds++
 
-			var toolMsg llm.Message
+			// Go round again so the LLM sees the tool results.
+			// It will either answer or ask for more tools.
+			continue
+
+		// Branch 2: Normal text response
+		case "stop":
+			if err := a.validateAnswer(ctx, choice.Message.Content); err != nil {
+				rejected++
+				if rejected > a.maxRepairs {
+					return res, &ValidationError{Answer: choice.Message.Content, Attempts: rejected, Err: err}
+				}
+				// Show the model its answer and what's wrong with it, and go round again.
+				a.History = append(a.History, llm.NewAssistantMessage(choice.Message.Content), repairRequest(err))
+				continue
+			}
+			assistantContent, err := a.enforceAnswerLimit(ctx, req, choice.Message.Content)
 			if err != nil {
-				// Tool execution failed - tell the LLM so it can try again or explain
-				toolMsg = llm.NewToolError(call.ID, call.Function.Name, err)
-			} else {
-				// Success - send the result back with the matching tool_call_id
-				toolMsg = llm.NewToolResult(call.ID, call.Function.Name, result)
+				return res, err
+			}
+			if assistantContent, err = checkGuardrails(ctx, res, GuardrailOutput, a.outputGuardrails, assistantContent); err != nil {
+				return res, err
 			}
-			a.History = append(a.History, toolMsg)
+			// The model recovered - failed attempts are just noise from here on.
+			if a.pruneFailed && len(a.failedCalls) > 0 {
+				a.History = pruneFailedToolCalls(a.History, a.failedCalls)
+				a.failedCalls = nil
+			}
+
+			assistantMessage := llm.NewAssistantMessage(assistantContent)
+			assistantMessage.Reasoning = choice.Message.Reasoning
+			a.History = append(a.History, assistantMessage)
+			res.Content = assistantContent
+			return res, nil
 		}
 
-		// Recurse with empty message so the LLM sees the tool results.
-		// The LLM will now generate a text response incorporating these results.
-		return a.Run(ctx, "")
+		// Handle other finish reasons (should be rare but good to catch)
+		return res, &UnexpectedFinishReasonError{Reason: finishReason}
 	}
+}
 
-	// Branch 2: Normal text response (finish_reason == "stop")
-	if finishReason == "stop" {
-		assistantContent := choice.Message.Content
-		assistantMessage := llm.NewAssistantMessage(assistantContent)
-		a.History = append(a.History, assistantMessage)
-		return assistantContent, nil
+// runToolCalls executes each tool the LLM requested and appends the results
+// to the history, each linked to its call by tool_call_id. It returns a
+// trace entry per call, in the same order.
+//
+// The LLM can request multiple tools in one turn. By default they run one
+// after another; with WithToolConcurrency they run in parallel. Either way
+// the results are appended in the order the LLM asked for them.
+func (a *Agent) runToolCalls(ctx context.Context, calls []llm.ToolCall) []ToolTrace {
+	results := make([]llm.Message, len(calls))
+	traces := make([]ToolTrace, len(calls))
+
+	if a.toolConcurrency <= 1 || len(calls) == 1 {
+		for i, call := range calls {
+			results[i], traces[i] = a.executeToolCall(ctx, call)
+		}
+	} else {
+		// Bounded fan-out: the semaphore holds at most toolConcurrency slots.
+		sem := make(chan struct{}, a.toolConcurrency)
+		var wg sync.WaitGroup
+		for i, call := range calls {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[i], traces[i] = a.executeToolCall(ctx, call)
+			}()
+		}
+		wg.Wait()
 	}
 
-	// Handle other finish reasons (should be rare but good to catch)
-	return "", fmt.Errorf("unexpected finish_reason: %s", finishReason)
+	for i, msg := range results {
+		if traces[i].Error != "" && a.pruneFailed {
+			if a.failedCalls == nil {
+				a.failedCalls = make(map[string]bool)
+			}
+			a.failedCalls[calls[i].ID] = true
+		}
+		a.History = append(a.History, msg)
+		a.effects = append(a.effects, traces[i].Effects...)
+	}
+	return traces
+}
+
+// runTrace puts the run's trace context on ctx: a span for the run,
+// continuing the caller's trace if ctx has one and starting a new trace
+// otherwise. Tool calls get child spans of it (see executeToolCall).
+func runTrace(ctx context.Context, runID string) context.Context {
+	tc, ok := llm.TraceFromContext(ctx)
+	if ok {
+		tc = tc.ChildSpan(ctx)
+	} else {
+		tc = llm.TraceContext{
+			TraceID: llm.NewTraceID(ctx),
+			SpanID:  llm.NewSpanID(ctx),
+			Sampled: true,
+		}
+	}
+	tc.RunID = runID
+	tc.ToolCallID = ""
+	return llm.ContextWithTrace(ctx, tc)
+}
+
+// executeToolCall runs one tool and builds the tool message for the history,
+// plus a trace entry describing what happened. Safe to call concurrently -
+// it doesn't touch the agent's history.
+func (a *Agent) executeToolCall(ctx context.Context, call llm.ToolCall) (llm.Message, ToolTrace) {
+	// let the callback see which tool is about to run and what args the LLM sent
+	a.callbacks.OnToolCall(ctx, call.Function.Name, call.Function.Arguments)
+
+	// each tool call is its own span under the run, so tools that call
+	// other services can continue the trace (llm.TraceFromContext)
+	tc, _ := llm.TraceFromContext(ctx)
+	tc = tc.ChildSpan(ctx)
+	tc.ToolCallID = call.ID
+	ctx = llm.ContextWithTrace(ctx, tc)
+
+	// side effects the tool declares with tools.RecordEffect land here
+	ctx, effects := tools.CollectEffects(ctx, call.Function.Name)
+
+	// run the tool and track how long it takes
+	toolStart := a.now()
+	handle := a.toolHandler
+	if handle == nil {
+		handle = a.tools.Handle
+	}
+	var res tools.Result
+	var err error
+	tcall := tools.Call{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments}
+	if a.toolAllowed(call.Function.Name) {
+		res, err = handle(ctx, tcall)
+	} else {
+		err = &ToolNotAllowedError{Name: call.Function.Name}
+	}
+	sanitized := false
+	if err == nil && len(a.sanitizers) > 0 {
+		var text string
+		if text, err = a.sanitize(ctx, tcall, res.Text); err == nil {
+			sanitized = text != res.Text
+			res.Text = text
+		}
+	}
+	result := res.Text
+	toolLatency := a.now().Sub(toolStart)
+
+	// let the callback see the outcome - result or error
+	a.callbacks.OnToolResult(ctx, call.Function.Name, result, err, toolLatency)
+
+	trace := ToolTrace{
+		ID:        call.ID,
+		Name:      call.Function.Name,
+		Arguments: call.Function.Arguments,
+		Result:    result,
+		Duration:  toolLatency,
+		SpanID:    tc.SpanID,
+		Sanitized: sanitized,
+		Effects:   effects.Effects(),
+	}
+
+	if err != nil {
+		// Tool execution failed - tell the LLM so it can try again or explain
+		trace.Error = err.Error()
+		trace.Err = err
+		return llm.NewToolError(call.ID, call.Function.Name, err), trace
+	}
+	// Success - send the result back with the matching tool_call_id
+	if res.IsRich() {
+		return llm.NewToolResultParts(call.ID, call.Function.Name, res.Parts()), trace
+	}
+	return llm.NewToolResult(call.ID, call.Function.Name, result), trace
 }
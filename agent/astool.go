@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/tools"
+	"strings"
+	"sync"
+)
+
+// AgentToolArgs are the arguments of a tool made with AsTool.
+type AgentToolArgs struct {
+	Task    string `json:"task" description:"What to do, written as a complete request: the worker sees nothing of your conversation except this."`
+	Context string `json:"context,omitempty" description:"Background the worker needs - facts, constraints, earlier findings - if any."`
+}
+
+// AsToolOption configures AsTool.
+type AsToolOption func(*agentTool)
+
+type agentTool struct {
+	agent       *Agent
+	keepHistory bool
+	runOpts     []RunOption
+	prompt      string
+
+	mu sync.Mutex // an Agent runs one conversation at a time
+}
+
+// AsToolKeepHistory lets the worker remember earlier tasks, so the
+// supervisor can follow up on them ("now shorten it"). By default every
+// call starts from a fresh conversation with just the system prompt.
+func AsToolKeepHistory() AsToolOption {
+	return func(t *agentTool) {
+		t.keepHistory = true
+	}
+}
+
+// AsToolRunOptions applies opts to every run of the worker, e.g. a cap on
+// the length of its answers with RunWithMaxTokens.
+func AsToolRunOptions(opts ...RunOption) AsToolOption {
+	return func(t *agentTool) {
+		t.runOpts = append(t.runOpts, opts...)
+	}
+}
+
+// AsToolPrompt adds guidance on when to use the worker to the
+// supervisor's system prompt, like a Toolset's Prompt.
+func AsToolPrompt(prompt string) AsToolOption {
+	return func(t *agentTool) {
+		t.prompt = prompt
+	}
+}
+
+// AsTool wraps the whole agent - its provider, system prompt and tools -
+// as a tool another agent can call. The calling agent hands over a task,
+// this one works on it with its own tool loop, and its final answer comes
+// back as the tool result. That's all a supervisor/worker setup needs:
+//
+//	researcher := agent.New(openai.New(key, "gpt-4o-mini"),
+//	    agent.WithSystemPrompts("You research topics on the web and report facts with sources."))
+//	researcher.RegisterTool("search", "Search the web", Search)
+//
+//	supervisor := agent.New(anthropic.New(key, "claude-sonnet-4-20250514"),
+//	    agent.WithSystemPrompts("You plan and write reports, delegating research."))
+//	supervisor.RegisterToolset(researcher.AsTool("researcher", "Research a topic and report the facts found."))
+//
+// The worker's conversation is separate from the supervisor's: it sees
+// only the task and context the supervisor writes, so the tool's argument
+// descriptions tell the model to make them self-contained. Calls to the
+// tool are run one at a time, even when the supervisor makes several in
+// parallel, since an Agent holds one conversation; give the supervisor
+// several worker agents to have them work in parallel.
+//
+// The worker's run is traced as a child of the tool call, and the effects
+// its tools record are passed up, so the supervisor's RunResult shows
+// everything that was done. Its token usage and cost are counted on the
+// worker (see Agent.Usage and Agent.Cost). An agent can't call itself,
+// directly or through other agents - that call fails rather than
+// deadlocking.
+func (a *Agent) AsTool(name, description string, opts ...AsToolOption) tools.Toolset {
+	t := &agentTool{agent: a}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return tools.Toolset{
+		Name:   name,
+		Prompt: t.prompt,
+		Tools:  []tools.Tool{{Name: name, Description: description, Func: t.call}},
+	}
+}
+
+// callingAgentsKey holds the agents working on the current request,
+// outermost first, to catch an agent delegating to itself.
+type callingAgentsKey struct{}
+
+// withCallingAgent records on ctx that a is running.
+func withCallingAgent(ctx context.Context, a *Agent) context.Context {
+	callers, _ := ctx.Value(callingAgentsKey{}).([]*Agent)
+	return context.WithValue(ctx, callingAgentsKey{}, append(callers[:len(callers):len(callers)], a))
+}
+
+func (t *agentTool) call(ctx context.Context, args AgentToolArgs) (string, error) {
+	if strings.TrimSpace(args.Task) == "" {
+		return "", errors.New("task is empty")
+	}
+	callers, _ := ctx.Value(callingAgentsKey{}).([]*Agent)
+	for _, c := range callers {
+		if c == t.agent {
+			return "", errors.New("this agent is already working on the request and can't be called again from inside it")
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a := t.agent
+	if !t.keepHistory {
+		a.History = make([]llm.Message, 0, 1)
+		if a.SystemPrompt != "" {
+			a.History = append(a.History, llm.NewSystemMessage(a.SystemPrompt))
+		}
+	}
+
+	msg := args.Task
+	if args.Context != "" {
+		msg = fmt.Sprintf("%s\n\nContext:\n%s", args.Task, args.Context)
+	}
+	res, err := a.RunWithResult(ctx, msg, t.runOpts...)
+	if res != nil {
+		for _, e := range res.Effects() {
+			tools.RecordEffect(ctx, e)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return res.Content, nil
+}
@@ -0,0 +1,245 @@
+// Package bandit picks between prompt and model variants automatically,
+// sending more traffic to whichever does best.
+//
+// You describe the variants - say, the current system prompt on gpt-4o, a
+// shorter prompt on gpt-4o, and the current prompt on a cheaper model - and
+// report a reward for every conversation (thumbs-up = 1, thumbs-down = 0,
+// task solved or not). The Optimizer treats this as a multi-armed bandit:
+// it keeps exploring every variant a little while steering most traffic to
+// the one with the best average reward so far, so it converges on the
+// winner without a fixed A/B split.
+//
+//	opt := bandit.New([]bandit.Variant{
+//	    {Name: "long-prompt", Provider: gpt4o, SystemPrompt: longPrompt},
+//	    {Name: "short-prompt", Provider: gpt4o, SystemPrompt: shortPrompt},
+//	    {Name: "mini", Provider: gpt4oMini, SystemPrompt: longPrompt},
+//	})
+//
+//	a, sel := opt.NewAgent(ctx, agent.WithMaxRetries(2))
+//	reply, err := a.Run(ctx, question)
+//	...
+//	sel.Reward(1) // the user clicked thumbs-up
+package bandit
+
+import (
+	"context"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// Variant is one configuration to try.
+type Variant struct {
+	Name         string           // identifies the variant in Stats
+	Provider     llm.ChatProvider // the model to use
+	SystemPrompt string           // the system prompt to use. Empty means none.
+}
+
+// Algorithm picks how exploration and exploitation are balanced.
+type Algorithm int
+
+const (
+	// UCB1 picks the variant with the highest upper confidence bound on its
+	// mean reward. Deterministic, no tuning, and rarely-tried variants get
+	// retried just often enough. This is the default.
+	UCB1 Algorithm = iota
+
+	// EpsilonGreedy sends a fixed fraction of traffic (see WithEpsilon) to a
+	// random variant and the rest to the best one so far. Simpler to reason
+	// about, and keeps exploring forever - useful when variants' quality
+	// drifts over time.
+	EpsilonGreedy
+)
+
+// Stats describes how a variant is doing.
+type Stats struct {
+	Name      string
+	Pulls     int     // times the variant was selected
+	Rewards   int     // rewards reported
+	MeanScore float64 // average reward, 0 until the first reward
+}
+
+type arm struct {
+	variant Variant
+	pulls   int
+	rewards int
+	total   float64
+}
+
+// Optimizer allocates traffic across variants. It is safe for concurrent use.
+type Optimizer struct {
+	mu        sync.Mutex
+	arms      []*arm
+	algorithm Algorithm
+	epsilon   float64
+}
+
+// Option configures an Optimizer.
+type Option func(*Optimizer)
+
+// WithAlgorithm sets the bandit algorithm. Defaults to UCB1.
+func WithAlgorithm(alg Algorithm) Option {
+	return func(o *Optimizer) {
+		o.algorithm = alg
+	}
+}
+
+// WithEpsilon sets the exploration rate for EpsilonGreedy. Defaults to 0.1.
+func WithEpsilon(eps float64) Option {
+	return func(o *Optimizer) {
+		o.epsilon = eps
+	}
+}
+
+// New creates an Optimizer over variants. It panics if there are none.
+func New(variants []Variant, opts ...Option) *Optimizer {
+	if len(variants) == 0 {
+		panic("bandit: New needs at least one variant")
+	}
+	o := &Optimizer{epsilon: 0.1}
+	for _, v := range variants {
+		o.arms = append(o.arms, &arm{variant: v})
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Selection is the variant chosen for one conversation. Report how it went
+// with Reward.
+type Selection struct {
+	Variant Variant
+	o       *Optimizer
+	arm     *arm
+	once    sync.Once
+}
+
+// Reward reports the outcome for this selection, from 0 (bad) to 1 (good);
+// values outside that range are clamped. Only the first call counts, so
+// it's safe to call from several places (e.g. a timeout handler and a
+// feedback handler).
+func (s *Selection) Reward(r float64) {
+	s.once.Do(func() {
+		r = min(max(r, 0), 1)
+		s.o.mu.Lock()
+		defer s.o.mu.Unlock()
+		s.arm.rewards++
+		s.arm.total += r
+	})
+}
+
+// Select picks a variant for a new conversation. Randomness (for
+// EpsilonGreedy and tie-breaking) comes from llm.RandFromContext, so a
+// seeded context gives reproducible choices.
+func (o *Optimizer) Select(ctx context.Context) *Selection {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	rng := llm.RandFromContext(ctx)
+	float := rand.Float64
+	intN := rand.IntN
+	if rng != nil {
+		float, intN = rng.Float64, rng.IntN
+	}
+
+	a := o.pick(float, intN)
+	a.pulls++
+	return &Selection{Variant: a.variant, o: o, arm: a}
+}
+
+// pick chooses an arm. The caller holds o.mu.
+func (o *Optimizer) pick(float func() float64, intN func(int) int) *arm {
+	// Every variant gets tried once before any scoring - in random order, so
+	// a burst of concurrent first requests doesn't all hit variant 0.
+	var untried []*arm
+	for _, a := range o.arms {
+		if a.rewards == 0 {
+			untried = append(untried, a)
+		}
+	}
+	if len(untried) > 0 {
+		// Prefer the ones selected least, since their rewards may be in flight.
+		least := untried[0].pulls
+		for _, a := range untried {
+			least = min(least, a.pulls)
+		}
+		var cands []*arm
+		for _, a := range untried {
+			if a.pulls == least {
+				cands = append(cands, a)
+			}
+		}
+		return cands[intN(len(cands))]
+	}
+
+	if o.algorithm == EpsilonGreedy && float() < o.epsilon {
+		return o.arms[intN(len(o.arms))]
+	}
+
+	total := 0
+	for _, a := range o.arms {
+		total += a.rewards
+	}
+	best, bestScore := o.arms[0], math.Inf(-1)
+	for _, a := range o.arms {
+		score := a.total / float64(a.rewards)
+		if o.algorithm == UCB1 {
+			score += math.Sqrt(2 * math.Log(float64(total)) / float64(a.rewards))
+		}
+		if score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+	return best
+}
+
+// NewAgent selects a variant and builds an agent for it. opts are applied
+// after the variant's system prompt, so they can add tools, callbacks and
+// so on (but shouldn't set a different system prompt).
+func (o *Optimizer) NewAgent(ctx context.Context, opts ...agent.Option) (*agent.Agent, *Selection) {
+	sel := o.Select(ctx)
+	all := opts
+	if sel.Variant.SystemPrompt != "" {
+		all = append([]agent.Option{agent.WithSystemPrompts(sel.Variant.SystemPrompt)}, opts...)
+	}
+	return agent.New(sel.Variant.Provider, all...), sel
+}
+
+// Stats reports every variant's numbers, in the order they were given.
+func (o *Optimizer) Stats() []Stats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	out := make([]Stats, len(o.arms))
+	for i, a := range o.arms {
+		out[i] = Stats{Name: a.variant.Name, Pulls: a.pulls, Rewards: a.rewards}
+		if a.rewards > 0 {
+			out[i].MeanScore = a.total / float64(a.rewards)
+		}
+	}
+	return out
+}
+
+// Best returns the variant with the highest mean reward so far, and false
+// if no rewards have been reported yet.
+func (o *Optimizer) Best() (Variant, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var best *arm
+	for _, a := range o.arms {
+		if a.rewards == 0 {
+			continue
+		}
+		if best == nil || a.total/float64(a.rewards) > best.total/float64(best.rewards) {
+			best = a
+		}
+	}
+	if best == nil {
+		return Variant{}, false
+	}
+	return best.variant, true
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"go-agent-sdk/llm"
+	"sync"
+)
+
+// HistoryPolicy shapes the messages of each request just before it is
+// sent. Unlike a Memory it doesn't change the stored history - what it
+// leaves out is still there for the next request, for SaveHistory and for
+// a bigger model after SetProvider.
+type HistoryPolicy func(req *llm.ChatRequest)
+
+// WithHistoryPolicy applies p to every request. The request it sees is
+// complete - toolset prompts, tools, max tokens - so a policy can budget
+// for all of it.
+//
+//	a := agent.New(provider, agent.WithHistoryPolicy(agent.TokenBudget(128_000)))
+func WithHistoryPolicy(p HistoryPolicy) Option {
+	return func(a *Agent) {
+		a.historyPolicy = p
+	}
+}
+
+// TokenBudgetOption configures TokenBudget.
+type TokenBudgetOption func(*tokenBudget)
+
+// TrimTo sets how far TokenBudget trims once it has to, as a fraction of
+// the budget. Trimming well below the limit means it doesn't happen again
+// on the very next request, which keeps the request prefix stable for
+// provider prompt caching. The default is 0.75.
+func TrimTo(fraction float64) TokenBudgetOption {
+	return func(b *tokenBudget) {
+		b.trimTo = fraction
+	}
+}
+
+// ReserveOutput sets how many tokens TokenBudget leaves for the answer
+// when the request doesn't set MaxTokens. The default is 4096.
+func ReserveOutput(tokens int) TokenBudgetOption {
+	return func(b *tokenBudget) {
+		b.reserve = tokens
+	}
+}
+
+// WithTokenEstimator counts tokens with estimate instead of
+// llm.EstimateTokens - plug in the model's real tokenizer for exact
+// budgets.
+func WithTokenEstimator(estimate func(llm.Message) int) TokenBudgetOption {
+	return func(b *tokenBudget) {
+		b.estimate = estimate
+	}
+}
+
+type tokenBudget struct {
+	window   int
+	trimTo   float64
+	reserve  int
+	estimate func(llm.Message) int
+
+	mu   sync.Mutex
+	skip int // messages after the system prompt left out last time
+}
+
+// TokenBudget keeps requests inside a model's context window of the given
+// size. When the messages, the tool definitions and the room for the
+// answer come close to it, the oldest turns are left out until the request
+// is back down to TrimTo of the window. Later requests leave out the same
+// messages, and trim again only when they reach the window once more.
+//
+// The system messages are always sent, and so is everything from the last
+// user message on - the turn in progress. An assistant message with tool
+// calls and the results of those calls go together or not at all, so the
+// request stays valid for every provider. If the must-keep part alone is
+// over the budget, it is sent as it is and the provider decides.
+//
+// The policy remembers where it cut, so give each agent its own.
+func TokenBudget(contextWindow int, opts ...TokenBudgetOption) HistoryPolicy {
+	b := &tokenBudget{window: contextWindow, trimTo: 0.75, reserve: 4096, estimate: llm.EstimateTokens}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b.apply
+}
+
+func (b *tokenBudget) apply(req *llm.ChatRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	reserve := b.reserve
+	if req.MaxTokens > 0 {
+		reserve = req.MaxTokens
+	}
+	used := reserve + llm.EstimateRequestTokens(llm.ChatRequest{Tools: req.Tools})
+
+	msgs := req.Messages
+	head := leadingSystem(msgs)
+	// Start where the last request did, unless the history has changed
+	// under us (a Memory compacted it, say) and that's no longer a turn.
+	if b.skip > len(msgs)-head || (b.skip > 0 && msgs[head+b.skip].Role != "user") {
+		b.skip = 0
+	}
+	rest := msgs[head+b.skip:]
+	for _, m := range msgs[:head] {
+		used += b.estimate(m)
+	}
+	for _, m := range rest {
+		used += b.estimate(m)
+	}
+
+	if used > b.window {
+		target := int(float64(b.window) * b.trimTo)
+		units := historyUnits(rest)
+		keepFrom := len(units) - 1 // the turn in progress: from the last user message on
+		for keepFrom > 0 && rest[units[keepFrom].start].Role != "user" {
+			keepFrom--
+		}
+
+		// Drop whole units from the front until under the target, then on
+		// to a user message - some providers insist on starting with one.
+		drop := 0
+		for drop < keepFrom && used > target {
+			for _, m := range rest[units[drop].start:units[drop].end] {
+				used -= b.estimate(m)
+			}
+			drop++
+		}
+		for drop > 0 && drop < keepFrom && rest[units[drop].start].Role != "user" {
+			drop++
+		}
+		if drop > 0 {
+			b.skip += units[drop].start
+		}
+	}
+
+	if b.skip == 0 {
+		return
+	}
+	out := make([]llm.Message, 0, len(msgs)-b.skip)
+	out = append(out, msgs[:head]...)
+	req.Messages = append(out, msgs[head+b.skip:]...)
+}
+
+// historyUnit is a run of messages that can only be dropped together.
+type historyUnit struct {
+	start, end int
+}
+
+// historyUnits splits msgs into units: an assistant message with tool
+// calls together with the tool results after it, and every other message
+// on its own.
+func historyUnits(msgs []llm.Message) []historyUnit {
+	var units []historyUnit
+	for i := 0; i < len(msgs); {
+		end := i + 1
+		if len(msgs[i].ToolCalls) > 0 {
+			for end < len(msgs) && msgs[end].Role == "tool" {
+				end++
+			}
+		}
+		units = append(units, historyUnit{i, end})
+		i = end
+	}
+	return units
+}
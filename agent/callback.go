@@ -1,10 +1,17 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"go-agent-sdk/llm"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // Callback lets you observe what happens inside the agent during Run().
@@ -31,6 +38,265 @@ type Callback interface {
 	OnToolResult(name string, result string, err error, latency time.Duration)
 }
 
+// StreamUsageCallback is an optional extension of Callback. When the agent
+// streams (see WithStreaming) and the callback also implements this
+// interface, OnStreamUsage is called whenever the provider reports token
+// counts mid-stream, so you can watch a long answer's cost climb.
+//
+// usage is cumulative for the current LLM call, not the whole run. The
+// final counts for each call also arrive in OnLLMResponse, and the run's
+// total is in RunResult.Usage.
+type StreamUsageCallback interface {
+	OnStreamUsage(usage llm.Usage)
+}
+
+// RunCallback is an optional extension of Callback for callbacks that
+// care about whole runs - a span per run, a count of runs in flight.
+// OnRunStart is called when RunWithResult starts, with the run's ID and
+// the user message. OnRunEnd is called as it returns, with what it
+// returns: res is never nil, err is nil if the run succeeded.
+type RunCallback interface {
+	OnRunStart(runID, input string)
+	OnRunEnd(res *RunResult, err error)
+}
+
+// ErrorCallback is an optional extension of Callback. OnError is called
+// once for a Run that fails, with the error it returns, just before
+// OnRunEnd. Failed attempts that are retried go to RetryCallback instead,
+// and a tool's error reaches OnToolResult and the model, not OnError -
+// the run carries on.
+type ErrorCallback interface {
+	OnError(err error)
+}
+
+// RetryCallback is an optional extension of Callback. OnRetry is called
+// when an LLM call fails and is about to be tried again, with the retry's
+// number (1 for the first), the error and how long the agent waits first.
+// An attempt that isn't retried ends the run and goes to OnError.
+type RetryCallback interface {
+	OnRetry(attempt int, err error, delay time.Duration)
+}
+
+// MultiCallback calls several callbacks, in order, for every event - a
+// metrics callback and a logging callback side by side. It passes the
+// optional events (StreamUsageCallback, RunCallback, ErrorCallback,
+// RetryCallback) on to the callbacks that implement them. WithCallback
+// doesn't need one - it takes several callbacks itself - but it's handy
+// for handing a group of callbacks around as one.
+type MultiCallback []Callback
+
+func (m MultiCallback) OnLLMRequest(req llm.ChatRequest) {
+	for _, cb := range m {
+		cb.OnLLMRequest(req)
+	}
+}
+
+func (m MultiCallback) OnLLMResponse(resp llm.ChatResponse, latency time.Duration) {
+	for _, cb := range m {
+		cb.OnLLMResponse(resp, latency)
+	}
+}
+
+func (m MultiCallback) OnToolCall(name string, args string) {
+	for _, cb := range m {
+		cb.OnToolCall(name, args)
+	}
+}
+
+func (m MultiCallback) OnToolResult(name string, result string, err error, latency time.Duration) {
+	for _, cb := range m {
+		cb.OnToolResult(name, result, err, latency)
+	}
+}
+
+func (m MultiCallback) OnStreamUsage(usage llm.Usage) {
+	for _, cb := range m {
+		if u, ok := cb.(StreamUsageCallback); ok {
+			u.OnStreamUsage(usage)
+		}
+	}
+}
+
+func (m MultiCallback) OnRunStart(runID, input string) {
+	for _, cb := range m {
+		if r, ok := cb.(RunCallback); ok {
+			r.OnRunStart(runID, input)
+		}
+	}
+}
+
+func (m MultiCallback) OnRunEnd(res *RunResult, err error) {
+	for _, cb := range m {
+		if r, ok := cb.(RunCallback); ok {
+			r.OnRunEnd(res, err)
+		}
+	}
+}
+
+func (m MultiCallback) OnError(err error) {
+	for _, cb := range m {
+		if e, ok := cb.(ErrorCallback); ok {
+			e.OnError(err)
+		}
+	}
+}
+
+func (m MultiCallback) OnRetry(attempt int, err error, delay time.Duration) {
+	for _, cb := range m {
+		if r, ok := cb.(RetryCallback); ok {
+			r.OnRetry(attempt, err, delay)
+		}
+	}
+}
+
+// ContextCallback is Callback with the run's context passed to every
+// method, so events can be tied to the trace, request ID or whatever else
+// the caller put in the context (see llm.TraceFromContext). It also has
+// the optional events built in - embed NopCallback and override only the
+// ones you need:
+//
+//	type spanLogger struct{ agent.NopCallback }
+//
+//	func (spanLogger) OnToolCall(ctx context.Context, name, args string) {
+//	    tc, _ := llm.TraceFromContext(ctx)
+//	    log.Printf("trace=%s tool=%s", tc.TraceID, name)
+//	}
+//
+// Attach one with WithContextCallback. Plain Callbacks keep working; both
+// kinds can be mixed and are called in the order they were added.
+type ContextCallback interface {
+	OnLLMRequest(ctx context.Context, req llm.ChatRequest)
+	OnLLMResponse(ctx context.Context, resp llm.ChatResponse, latency time.Duration)
+	OnToolCall(ctx context.Context, name string, args string)
+	OnToolResult(ctx context.Context, name string, result string, err error, latency time.Duration)
+	OnStreamUsage(ctx context.Context, usage llm.Usage)
+	OnRunStart(ctx context.Context, runID, input string)
+	OnRunEnd(ctx context.Context, res *RunResult, err error)
+	OnError(ctx context.Context, err error)
+	OnRetry(ctx context.Context, attempt int, err error, delay time.Duration)
+}
+
+// NopCallback is a ContextCallback that does nothing, for embedding.
+type NopCallback struct{}
+
+func (NopCallback) OnLLMRequest(context.Context, llm.ChatRequest)                      {}
+func (NopCallback) OnLLMResponse(context.Context, llm.ChatResponse, time.Duration)     {}
+func (NopCallback) OnToolCall(context.Context, string, string)                         {}
+func (NopCallback) OnToolResult(context.Context, string, string, error, time.Duration) {}
+func (NopCallback) OnStreamUsage(context.Context, llm.Usage)                           {}
+func (NopCallback) OnRunStart(context.Context, string, string)                         {}
+func (NopCallback) OnRunEnd(context.Context, *RunResult, error)                        {}
+func (NopCallback) OnError(context.Context, error)                                     {}
+func (NopCallback) OnRetry(context.Context, int, error, time.Duration)                 {}
+
+// legacyCallback lets a plain Callback sit among ContextCallbacks: it drops
+// the context and passes the optional events on only if cb implements them.
+type legacyCallback struct{ cb Callback }
+
+func (l legacyCallback) OnLLMRequest(_ context.Context, req llm.ChatRequest) {
+	l.cb.OnLLMRequest(req)
+}
+
+func (l legacyCallback) OnLLMResponse(_ context.Context, resp llm.ChatResponse, latency time.Duration) {
+	l.cb.OnLLMResponse(resp, latency)
+}
+
+func (l legacyCallback) OnToolCall(_ context.Context, name string, args string) {
+	l.cb.OnToolCall(name, args)
+}
+
+func (l legacyCallback) OnToolResult(_ context.Context, name string, result string, err error, latency time.Duration) {
+	l.cb.OnToolResult(name, result, err, latency)
+}
+
+func (l legacyCallback) OnStreamUsage(_ context.Context, usage llm.Usage) {
+	if u, ok := l.cb.(StreamUsageCallback); ok {
+		u.OnStreamUsage(usage)
+	}
+}
+
+func (l legacyCallback) OnRunStart(_ context.Context, runID, input string) {
+	if r, ok := l.cb.(RunCallback); ok {
+		r.OnRunStart(runID, input)
+	}
+}
+
+func (l legacyCallback) OnRunEnd(_ context.Context, res *RunResult, err error) {
+	if r, ok := l.cb.(RunCallback); ok {
+		r.OnRunEnd(res, err)
+	}
+}
+
+func (l legacyCallback) OnError(_ context.Context, err error) {
+	if e, ok := l.cb.(ErrorCallback); ok {
+		e.OnError(err)
+	}
+}
+
+func (l legacyCallback) OnRetry(_ context.Context, attempt int, err error, delay time.Duration) {
+	if r, ok := l.cb.(RetryCallback); ok {
+		r.OnRetry(attempt, err, delay)
+	}
+}
+
+// callbacks is every callback attached to an agent, in order. Calling it
+// with none attached does nothing.
+type callbacks []ContextCallback
+
+func (cs callbacks) OnLLMRequest(ctx context.Context, req llm.ChatRequest) {
+	for _, cb := range cs {
+		cb.OnLLMRequest(ctx, req)
+	}
+}
+
+func (cs callbacks) OnLLMResponse(ctx context.Context, resp llm.ChatResponse, latency time.Duration) {
+	for _, cb := range cs {
+		cb.OnLLMResponse(ctx, resp, latency)
+	}
+}
+
+func (cs callbacks) OnToolCall(ctx context.Context, name string, args string) {
+	for _, cb := range cs {
+		cb.OnToolCall(ctx, name, args)
+	}
+}
+
+func (cs callbacks) OnToolResult(ctx context.Context, name string, result string, err error, latency time.Duration) {
+	for _, cb := range cs {
+		cb.OnToolResult(ctx, name, result, err, latency)
+	}
+}
+
+func (cs callbacks) OnStreamUsage(ctx context.Context, usage llm.Usage) {
+	for _, cb := range cs {
+		cb.OnStreamUsage(ctx, usage)
+	}
+}
+
+func (cs callbacks) OnRunStart(ctx context.Context, runID, input string) {
+	for _, cb := range cs {
+		cb.OnRunStart(ctx, runID, input)
+	}
+}
+
+func (cs callbacks) OnRunEnd(ctx context.Context, res *RunResult, err error) {
+	for _, cb := range cs {
+		cb.OnRunEnd(ctx, res, err)
+	}
+}
+
+func (cs callbacks) OnError(ctx context.Context, err error) {
+	for _, cb := range cs {
+		cb.OnError(ctx, err)
+	}
+}
+
+func (cs callbacks) OnRetry(ctx context.Context, attempt int, err error, delay time.Duration) {
+	for _, cb := range cs {
+		cb.OnRetry(ctx, attempt, err, delay)
+	}
+}
+
 // DebugCallback is a built-in Callback that prints the raw JSON at every step.
 // It uses json.MarshalIndent so the output is human-readable in your terminal.
 //
@@ -41,15 +307,77 @@ type Callback interface {
 //
 // When the agent runs, you'll see the full ChatRequest JSON before each LLM call,
 // the full ChatResponse JSON after, and every tool call with its arguments and result.
-type DebugCallback struct{}
+//
+// The zero value prints everything to stdout. Use NewDebugCallback to write
+// somewhere else, cut long payloads short or hide more fields. Either way,
+// things that look like API keys and bearer tokens are replaced with
+// [REDACTED], as are the values of fields named like secrets (api_key,
+// authorization, password and so on), so debug output is safe to paste
+// into a bug report.
+type DebugCallback struct {
+	w       io.Writer
+	maxBody int
+	fields  map[string]bool
+
+	mu sync.Mutex // tool calls run concurrently
+}
+
+// DebugOption configures NewDebugCallback.
+type DebugOption func(*DebugCallback)
+
+// DebugWriter sends the output to w instead of stdout - a log file, or
+// os.Stderr to keep it apart from the program's own output.
+func DebugWriter(w io.Writer) DebugOption {
+	return func(d *DebugCallback) {
+		d.w = w
+	}
+}
+
+// DebugMaxBody cuts each printed payload to n bytes. Long conversations
+// make for very long requests; usually the end is what you want to see,
+// so the start is dropped.
+func DebugMaxBody(n int) DebugOption {
+	return func(d *DebugCallback) {
+		d.maxBody = n
+	}
+}
+
+// DebugRedactFields adds to the JSON field names whose values are
+// replaced with [REDACTED], wherever they appear - in requests, responses
+// and tool arguments. Names match case-insensitively, ignoring - and _.
+func DebugRedactFields(names ...string) DebugOption {
+	return func(d *DebugCallback) {
+		if d.fields == nil {
+			d.fields = make(map[string]bool)
+		}
+		for _, n := range names {
+			d.fields[fieldKey(n)] = true
+		}
+	}
+}
+
+// NewDebugCallback returns a DebugCallback configured by opts.
+//
+//	logFile, _ := os.Create("agent-debug.log")
+//	a := agent.New(provider, agent.WithCallback(agent.NewDebugCallback(
+//	    agent.DebugWriter(logFile),
+//	    agent.DebugMaxBody(4000),
+//	    agent.DebugRedactFields("customer_email", "ssn"),
+//	)))
+func NewDebugCallback(opts ...DebugOption) *DebugCallback {
+	d := &DebugCallback{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
 
 // OnLLMRequest prints the full ChatRequest as indented JSON.
 // This shows you exactly what we're sending to the LLM provider - the model,
 // all messages in the conversation history, all registered tools, and
 // the temperature setting.
 func (d *DebugCallback) OnLLMRequest(req llm.ChatRequest) {
-	data, _ := json.MarshalIndent(req, "", "  ")
-	fmt.Printf("[DEBUG] LLM Request:\n%s\n\n", string(data))
+	d.printf("[DEBUG] LLM Request:\n%s\n\n", d.body(d.indent(req)))
 }
 
 // OnLLMResponse prints the full ChatResponse as indented JSON.
@@ -57,15 +385,14 @@ func (d *DebugCallback) OnLLMRequest(req llm.ChatRequest) {
 // to call tools or is it a final answer?), the message content or tool_calls
 // array, and token usage for cost tracking.
 func (d *DebugCallback) OnLLMResponse(resp llm.ChatResponse, latency time.Duration) {
-	data, _ := json.MarshalIndent(resp, "", "  ")
-	fmt.Printf("[DEBUG] LLM Response [%s]:\n%s\n\n", latency, string(data))
+	d.printf("[DEBUG] LLM Response [%s]:\n%s\n\n", latency, d.body(d.indent(resp)))
 }
 
 // OnToolCall prints which tool the LLM wants to call and with what arguments.
 // The args string is raw JSON straight from the LLM - you can see exactly
 // what it generated, including any mistakes (wrong field names, extra fields, etc).
 func (d *DebugCallback) OnToolCall(name string, args string) {
-	fmt.Printf("[DEBUG] Tool Call: %s\n   Args: %s\n\n", name, args)
+	d.printf("[DEBUG] Tool Call: %s\n   Args: %s\n\n", name, d.body(d.redactString(args)))
 }
 
 // OnToolResult prints what the tool returned after execution.
@@ -73,8 +400,109 @@ func (d *DebugCallback) OnToolCall(name string, args string) {
 // string and how long the tool took to run.
 func (d *DebugCallback) OnToolResult(name string, result string, err error, latency time.Duration) {
 	if err != nil {
-		fmt.Printf("[DEBUG] Tool Error: %s - %v [%s]\n\n", name, err, latency)
+		d.printf("[DEBUG] Tool Error: %s - %s [%s]\n\n", name, d.body(d.redactString(err.Error())), latency)
 	} else {
-		fmt.Printf("[DEBUG] Tool Result: %s - %s [%s]\n\n", name, result, latency)
+		d.printf("[DEBUG] Tool Result: %s - %s [%s]\n\n", name, d.body(d.redactString(result)), latency)
+	}
+}
+
+func (d *DebugCallback) printf(format string, args ...any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w := d.w
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+// indent marshals v as indented JSON, redacted.
+func (d *DebugCallback) indent(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return d.redactString(string(data))
+	}
+	data, _ = json.MarshalIndent(d.redact(tree), "", "  ")
+	return string(data)
+}
+
+// body applies the DebugMaxBody limit. The cut moves forward to the start
+// of a character, so a multi-byte one isn't split.
+func (d *DebugCallback) body(s string) string {
+	if d.maxBody <= 0 || len(s) <= d.maxBody {
+		return s
+	}
+	cut := len(s) - d.maxBody
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return fmt.Sprintf("[%d bytes cut] ...", cut) + s[cut:]
+}
+
+// defaultSecretFields are always redacted, in fieldKey form.
+var defaultSecretFields = map[string]bool{
+	"apikey": true, "authorization": true, "password": true, "passwd": true, "secret": true,
+	"clientsecret": true, "token": true, "accesstoken": true, "refreshtoken": true,
+	"privatekey": true, "xapikey": true, "xgoogapikey": true, "cookie": true, "setcookie": true,
+}
+
+// secretPatterns match credentials wherever they turn up in text.
+var secretPatterns = regexp.MustCompile(
+	`\b(?:sk-(?:ant-|proj-)?[A-Za-z0-9_-]{16,}|AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|` +
+		`github_pat_[A-Za-z0-9_]{22,}|xox[abprs]-[A-Za-z0-9-]{10,}|AKIA[0-9A-Z]{16}|hf_[A-Za-z0-9]{30,})` +
+		`|(?i:bearer)\s+[A-Za-z0-9._~+/=-]{16,}`)
+
+const redacted = "[REDACTED]"
+
+func fieldKey(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+func (d *DebugCallback) secretField(name string) bool {
+	k := fieldKey(name)
+	return defaultSecretFields[k] || d.fields[k]
+}
+
+// redact walks decoded JSON, hiding secret fields and credential-looking
+// strings. Strings that hold JSON themselves, like tool call arguments,
+// are redacted inside too.
+func (d *DebugCallback) redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if d.secretField(k) {
+				if e != nil && e != "" {
+					v[k] = redacted
+				}
+				continue
+			}
+			v[k] = d.redact(e)
+		}
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = d.redact(e)
+		}
+		return v
+	case string:
+		return d.redactString(v)
+	default:
+		return v
+	}
+}
+
+func (d *DebugCallback) redactString(s string) string {
+	if t := strings.TrimSpace(s); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		var tree any
+		if json.Unmarshal([]byte(t), &tree) == nil {
+			if data, err := json.Marshal(d.redact(tree)); err == nil {
+				return string(data)
+			}
+		}
 	}
+	return secretPatterns.ReplaceAllString(s, redacted)
 }
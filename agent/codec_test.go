@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"go-agent-sdk/llm"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// codecHistory has one of everything the codecs carry, including the
+// cases protobuf's defaults make easy to lose: a part with an empty
+// ImageURL or File, which must come back non-nil, and empty metadata
+// values.
+var codecHistory = []llm.Message{
+	{Role: "system", Content: "You are terse."},
+	{
+		Role: "user",
+		Parts: []llm.ContentPart{
+			{Type: "text", Text: "What's in these?"},
+			{Type: "image_url", ImageURL: &llm.ImageURL{URL: "https://example.com/cat.png", Detail: "high"}},
+			{Type: "image_url", ImageURL: &llm.ImageURL{}},
+			{Type: "file", File: &llm.File{Filename: "report.pdf", FileData: "data:application/pdf;base64,JVBERi0="}},
+			{Type: "file", File: &llm.File{}},
+			{Type: "text"},
+		},
+		Metadata: map[string]string{"intent": "describe", "ticket": "", "": "empty key"},
+	},
+	{
+		Role:               "assistant",
+		Reasoning:          "Two tools will do.",
+		ReasoningSignature: "sig-123",
+		ToolCalls: []llm.ToolCall{
+			{ID: "call_1", Type: "function", Function: llm.FunctionCall{Name: "look", Arguments: `{"n":1}`}},
+			{ID: "call_2", Type: "function", Function: llm.FunctionCall{Name: "look", Arguments: "{}"}},
+		},
+	},
+	{Role: "tool", Name: "look", ToolCallID: "call_1", Content: "a cat"},
+	{Role: "tool", Name: "look", ToolCallID: "call_2", Content: ""},
+	{Role: "assistant", Content: "A cat, and a report. ünïcödé ✓"},
+}
+
+func TestProtobufCodecRoundTrip(t *testing.T) {
+	data, err := ProtobufCodec.Encode(codecHistory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ProtobufCodec.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, codecHistory) {
+		t.Errorf("round trip changed the history:\ngot  %+v\nwant %+v", got, codecHistory)
+	}
+
+	// Parts without an ImageURL or File must not gain one.
+	for i, p := range got[1].Parts {
+		if (p.ImageURL == nil) != (codecHistory[1].Parts[i].ImageURL == nil) {
+			t.Errorf("part %d: ImageURL = %v, want nil %v", i, p.ImageURL, codecHistory[1].Parts[i].ImageURL == nil)
+		}
+		if (p.File == nil) != (codecHistory[1].Parts[i].File == nil) {
+			t.Errorf("part %d: File = %v, want nil %v", i, p.File, codecHistory[1].Parts[i].File == nil)
+		}
+	}
+
+	again, _ := ProtobufCodec.Encode(got)
+	if !bytes.Equal(again, data) {
+		t.Error("encoding the same history twice gave different bytes")
+	}
+}
+
+func TestProtobufCodecEmpty(t *testing.T) {
+	for _, history := range [][]llm.Message{nil, {}} {
+		data, err := ProtobufCodec.Encode(history)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ProtobufCodec.Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("Decode of an empty history = %#v, want an empty slice", got)
+		}
+	}
+}
+
+func TestProtobufCodecTruncated(t *testing.T) {
+	data, err := ProtobufCodec.Encode(codecHistory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every cut inside the last message must fail, not decode short or
+	// panic. Cuts between messages are valid shorter histories.
+	for n := 1; n < len(data); n++ {
+		got, err := ProtobufCodec.Decode(data[:n])
+		if err == nil && len(got) >= len(codecHistory) {
+			t.Errorf("Decode of %d of %d bytes gave the whole history", n, len(data))
+		}
+	}
+}
+
+func TestPBFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		want    []int // fields seen
+		wantErr error
+	}{
+		{name: "empty", in: nil},
+		{name: "varint", in: []byte{0x08, 0x96, 0x01}, want: []int{1}},
+		{name: "bytes", in: []byte{0x12, 0x02, 'h', 'i'}, want: []int{2}},
+		{name: "fixed fields skipped", in: []byte{0x19, 1, 2, 3, 4, 5, 6, 7, 8, 0x25, 1, 2, 3, 4, 0x08, 0x01}, want: []int{1}},
+		{name: "key cut", in: []byte{0x80}, wantErr: errPBTruncated},
+		{name: "varint cut", in: []byte{0x08, 0x96}, wantErr: errPBTruncated},
+		{name: "length cut", in: []byte{0x12}, wantErr: errPBTruncated},
+		{name: "bytes shorter than length", in: []byte{0x12, 0x05, 'h', 'i'}, wantErr: errPBTruncated},
+		{name: "huge length", in: []byte{0x12, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, wantErr: errPBTruncated},
+		{name: "fixed64 cut", in: []byte{0x09, 1, 2, 3}, wantErr: errPBTruncated},
+		{name: "fixed32 cut", in: []byte{0x0d, 1}, wantErr: errPBTruncated},
+		{name: "varint too long", in: []byte{0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, wantErr: errPBTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			err := pbFields(tt.in, func(field int, _ uint64, _ []byte) error {
+				got = append(got, field)
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Groups, wire types 3 and 4, aren't supported.
+	if err := pbFields([]byte{0x0b}, func(int, uint64, []byte) error { return nil }); err == nil ||
+		!strings.Contains(err.Error(), "unsupported protobuf wire type 3") {
+		t.Errorf("group: error = %v", err)
+	}
+}
+
+func TestDecodeHistoryFormats(t *testing.T) {
+	history := []llm.Message{llm.NewUserMessage("hi"), llm.NewAssistantMessage("hello")}
+	pb, err := ProtobufCodec.Encode(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := JSONCodec.Encode(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"protobuf", pb},
+		{"json", js},
+		{"json with leading space", append([]byte(" \n"), js...)},
+		{"bare json array", []byte(`[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeHistory(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, history) {
+				t.Errorf("DecodeHistory = %+v, want %+v", got, history)
+			}
+		})
+	}
+
+	if isProtobuf(js) {
+		t.Error("JSON output taken for protobuf")
+	}
+	if !isProtobuf(pb) {
+		t.Error("protobuf output not recognised")
+	}
+	if _, err := DecodeHistory([]byte("not a history")); err == nil {
+		t.Error("DecodeHistory accepted garbage")
+	}
+	if _, err := DecodeHistory(pb[:len(pb)-1]); err == nil {
+		t.Error("DecodeHistory accepted truncated protobuf")
+	}
+	newer := []byte{0x08, historyVersion + 1}
+	if _, err := DecodeHistory(newer); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("DecodeHistory of a newer version: error = %v", err)
+	}
+}
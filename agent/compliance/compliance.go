@@ -0,0 +1,379 @@
+// Package compliance applies retention rules, deletion requests and
+// access requests to everything the SDK keeps about a user: their
+// conversations in a session store, the facts a memory learned from them,
+// and the runs a feedback Recorder holds, traces included.
+//
+//	c := compliance.New(store,
+//	    compliance.WithMemory(mem),
+//	    compliance.WithTraces(rec),
+//	    compliance.WithRetention(90*24*time.Hour),
+//	)
+//	go c.Run(ctx, time.Hour) // deletes what's older than 90 days
+//
+//	c.Hold("user-42", "case 2026-117") // kept, however old, until Release
+//
+//	// GDPR access and erasure requests
+//	bundle, err := c.ExportUser(ctx, "user-17")
+//	purged, err := c.PurgeUser(ctx, "user-17")
+//
+// Sessions are matched to users by their IDs, so make them with
+// session.Key (or use the user ID as the session ID). Facts are matched
+// by Fact.User, which memory.WithUser sets, or by the run they were
+// learned in; runs by their session, or, for a run without one, the user
+// ID on its feedback. Feedback a user left on someone else's run is theirs,
+// but the run isn't.
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent-sdk/agent/cost"
+	"go-agent-sdk/agent/feedback"
+	"go-agent-sdk/agent/memory"
+	"go-agent-sdk/agent/session"
+	"go-agent-sdk/llm"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrLegalHold is returned by PurgeUser for a user under a legal hold.
+var ErrLegalHold = errors.New("compliance: user is under legal hold")
+
+// Manager exports or deletes a user's data on request, and deletes
+// everyone's once it's older than the retention period. It is safe for
+// concurrent use.
+type Manager struct {
+	sessions session.Store
+	memory   memory.Lister
+	traces   *feedback.Recorder
+	costs    *cost.Tracker
+	maxAge   time.Duration
+	onError  func(error)
+
+	mu    sync.Mutex
+	holds map[string]string // user -> reason
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithMemory includes the facts in m.
+func WithMemory(m memory.Lister) Option {
+	return func(c *Manager) {
+		c.memory = m
+	}
+}
+
+// WithTraces includes the runs, feedback and traces kept by r.
+func WithTraces(r *feedback.Recorder) Option {
+	return func(c *Manager) {
+		c.traces = r
+	}
+}
+
+// WithCosts drops deleted sessions' sums from t.
+func WithCosts(t *cost.Tracker) Option {
+	return func(c *Manager) {
+		c.costs = t
+	}
+}
+
+// WithRetention sets how long data is kept: sessions not saved for
+// maxAge, and runs and facts older than that, are deleted by Expire,
+// unless their user is under a legal hold. The session store has to be a
+// session.Dated; for Redis, use its WithTTL instead, which knows nothing
+// of holds. The default, 0, keeps everything.
+func WithRetention(maxAge time.Duration) Option {
+	return func(c *Manager) {
+		c.maxAge = maxAge
+	}
+}
+
+// OnError is called when an Expire started by Run fails.
+func OnError(fn func(error)) Option {
+	return func(c *Manager) {
+		c.onError = fn
+	}
+}
+
+// New returns a Manager for the sessions in sessions and whatever else the
+// options add.
+func New(sessions session.Store, opts ...Option) *Manager {
+	c := &Manager{sessions: sessions, holds: make(map[string]string)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Hold puts user under a legal hold: their data is kept past the
+// retention period and PurgeUser refuses to delete it. Holds live in the
+// Manager only, so set them again at startup from wherever they're
+// recorded.
+func (c *Manager) Hold(user, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.holds[user] = reason
+}
+
+// Release lifts a legal hold.
+func (c *Manager) Release(user string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.holds, user)
+}
+
+// Held reports whether user is under a legal hold, and why.
+func (c *Manager) Held(user string) (reason string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	reason, ok = c.holds[user]
+	return reason, ok
+}
+
+// Purged lists what was deleted.
+type Purged struct {
+	Sessions []string `json:"sessions,omitempty"`
+	Runs     []string `json:"runs,omitempty"`
+	Facts    []string `json:"facts,omitempty"`    // fact IDs
+	Feedback []string `json:"feedback,omitempty"` // other users' runs the user's feedback was removed from
+}
+
+// PurgeUser deletes everything kept about user: their sessions, then the
+// facts learned about them or in their runs, then the runs themselves,
+// and their feedback on other users' runs. A user under a legal hold gets
+// ErrLegalHold and nothing is deleted; a run that also concerns someone
+// under a hold - they rated it, say - is kept.
+//
+// A failure to delete one thing doesn't stop the rest; the errors are
+// returned together, with what was deleted.
+func (c *Manager) PurgeUser(ctx context.Context, user string) (*Purged, error) {
+	if user == "" {
+		return nil, errors.New("compliance: no user to purge")
+	}
+	if reason, ok := c.Held(user); ok {
+		return nil, fmt.Errorf("%w: %s (%s)", ErrLegalHold, user, reason)
+	}
+	ids, err := c.sessions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: listing sessions: %w", err)
+	}
+
+	p := &Purged{}
+	var errs []error
+	for _, id := range ids {
+		if session.Owner(id) != user {
+			continue
+		}
+		if err := c.deleteSession(ctx, id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.Sessions = append(p.Sessions, id)
+	}
+
+	held := c.holding()
+	runs := make(map[string]bool)
+	for _, rec := range c.records() {
+		switch {
+		case owner(rec) == user:
+			if !held(users(rec)...) {
+				runs[rec.RunID] = true
+				p.Runs = append(p.Runs, rec.RunID)
+			}
+		case slices.ContainsFunc(rec.Feedback, func(fb feedback.Feedback) bool { return fb.UserID == user }):
+			c.traces.ForgetFeedback(rec.RunID, user)
+			p.Feedback = append(p.Feedback, rec.RunID)
+		}
+	}
+	if err := c.forgetFacts(ctx, p, func(f memory.Fact) bool {
+		return f.User == user || runs[f.Source]
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if c.traces != nil {
+		c.traces.Forget(p.Runs...)
+	}
+	return p, errors.Join(errs...)
+}
+
+// Expire deletes whatever is older than the retention period and doesn't
+// belong to a user under a legal hold: sessions last saved before then,
+// runs tracked before then or in a deleted session, and facts remembered
+// before then or learned in a deleted run. Without WithRetention it
+// deletes nothing.
+func (c *Manager) Expire(ctx context.Context) (*Purged, error) {
+	p := &Purged{}
+	if c.maxAge <= 0 {
+		return p, nil
+	}
+	dated, ok := c.sessions.(session.Dated)
+	if !ok {
+		return nil, fmt.Errorf("compliance: retention needs a session store that records when sessions were saved, and %T doesn't", c.sessions)
+	}
+	cutoff := llm.Now(ctx).Add(-c.maxAge)
+	held := c.holding()
+
+	ids, err := c.sessions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: listing sessions: %w", err)
+	}
+	var errs []error
+	sessions := make(map[string]bool)
+	for _, id := range ids {
+		if held(session.Owner(id)) {
+			continue
+		}
+		updated, err := dated.Updated(ctx, id)
+		if errors.Is(err, session.ErrNotFound) {
+			continue // deleted since List
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("compliance: session %s: %w", id, err))
+			continue
+		}
+		if !updated.Before(cutoff) {
+			continue
+		}
+		if err := c.deleteSession(ctx, id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sessions[id] = true
+		p.Sessions = append(p.Sessions, id)
+	}
+
+	runs := make(map[string]bool)
+	owners := make(map[string][]string) // run ID -> users
+	for _, rec := range c.records() {
+		owners[rec.RunID] = users(rec)
+		if held(owners[rec.RunID]...) {
+			continue
+		}
+		if rec.Tracked.Before(cutoff) || sessions[rec.Session] {
+			runs[rec.RunID] = true
+			p.Runs = append(p.Runs, rec.RunID)
+		}
+	}
+	if err := c.forgetFacts(ctx, p, func(f memory.Fact) bool {
+		if held(f.User) || held(owners[f.Source]...) {
+			return false
+		}
+		return f.Created.Before(cutoff) || runs[f.Source]
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	if c.traces != nil {
+		c.traces.Forget(p.Runs...)
+	}
+	return p, errors.Join(errs...)
+}
+
+// Run calls Expire every interval until ctx is done, reporting failures to
+// OnError.
+func (c *Manager) Run(ctx context.Context, every time.Duration) error {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if _, err := c.Expire(ctx); err != nil && c.onError != nil && ctx.Err() == nil {
+			c.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// holding returns a check of whether any of users is under a legal hold,
+// against the holds as they are now.
+func (c *Manager) holding() func(users ...string) bool {
+	c.mu.Lock()
+	holds := maps.Clone(c.holds)
+	c.mu.Unlock()
+	return func(users ...string) bool {
+		for _, u := range users {
+			if _, ok := holds[u]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (c *Manager) deleteSession(ctx context.Context, id string) error {
+	if err := c.sessions.Delete(ctx, id); err != nil {
+		return fmt.Errorf("compliance: deleting session %s: %w", id, err)
+	}
+	if c.costs != nil {
+		c.costs.ForgetSession(id)
+	}
+	return nil
+}
+
+func (c *Manager) records() []feedback.Record {
+	if c.traces == nil {
+		return nil
+	}
+	return c.traces.Records()
+}
+
+// forgetFacts forgets the facts match picks, noting them in p.
+func (c *Manager) forgetFacts(ctx context.Context, p *Purged, match func(memory.Fact) bool) error {
+	if c.memory == nil {
+		return nil
+	}
+	var ids []string
+	for _, f := range c.memory.Facts() {
+		if match(f) {
+			ids = append(ids, f.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := c.memory.Forget(ctx, ids...); err != nil {
+		return fmt.Errorf("compliance: forgetting facts: %w", err)
+	}
+	p.Facts = ids
+	return nil
+}
+
+// owner returns who a run belongs to: its session's owner or, without a
+// session, the user who left feedback on it if only one did. "" means it
+// can't be told.
+func owner(rec feedback.Record) string {
+	if rec.Session != "" {
+		return session.Owner(rec.Session)
+	}
+	var user string
+	for _, fb := range rec.Feedback {
+		switch {
+		case fb.UserID == "" || fb.UserID == user:
+		case user == "":
+			user = fb.UserID
+		default:
+			return ""
+		}
+	}
+	return user
+}
+
+// users returns everyone a run concerns, for legal holds: its session's
+// owner and whoever left feedback on it.
+func users(rec feedback.Record) []string {
+	var out []string
+	if rec.Session != "" {
+		out = append(out, session.Owner(rec.Session))
+	}
+	for _, fb := range rec.Feedback {
+		if fb.UserID != "" && !slices.Contains(out, fb.UserID) {
+			out = append(out, fb.UserID)
+		}
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent-sdk/agent/feedback"
+	"go-agent-sdk/agent/memory"
+	"go-agent-sdk/agent/session"
+	"go-agent-sdk/llm"
+	"slices"
+	"time"
+)
+
+// Bundle is everything kept about one user, as ExportUser gathers it. It
+// marshals to a single JSON document, for answering a subject access
+// request:
+//
+//	b, err := c.ExportUser(ctx, userID)
+//	if err != nil {
+//	    return err
+//	}
+//	json.NewEncoder(w).Encode(b)
+type Bundle struct {
+	User     string    `json:"user"`
+	Exported time.Time `json:"exported"`
+
+	Conversations []Conversation    `json:"conversations"`
+	Facts         []memory.Fact     `json:"facts"` // what the memory learned about them
+	Runs          []feedback.Record `json:"runs"`  // each run's usage, cost, feedback and, if kept, trace
+
+	// Feedback is what the user said about other users' runs. The runs
+	// themselves aren't theirs, so they're left out.
+	Feedback []feedback.Feedback `json:"feedback"`
+
+	// Usage and Cost add up Runs.
+	Usage llm.Usage `json:"usage"`
+	Cost  float64   `json:"cost,omitempty"`
+}
+
+// Conversation is one of the user's sessions.
+type Conversation struct {
+	Session  string        `json:"session"`
+	Updated  time.Time     `json:"updated,omitzero"` // if the store is a session.Dated
+	Messages []llm.Message `json:"messages"`
+	Cost     float64       `json:"cost,omitempty"` // the WithCosts Tracker's sum
+}
+
+// ExportUser gathers what PurgeUser would delete for user - their
+// conversations, the facts learned about them or in their runs, and the
+// runs with their usage, plus their feedback on other users' runs - into
+// a Bundle. Legal holds don't affect it.
+func (c *Manager) ExportUser(ctx context.Context, user string) (*Bundle, error) {
+	if user == "" {
+		return nil, errors.New("compliance: no user to export")
+	}
+	ids, err := c.sessions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: listing sessions: %w", err)
+	}
+
+	b := &Bundle{
+		User:          user,
+		Exported:      llm.Now(ctx),
+		Conversations: []Conversation{},
+		Facts:         []memory.Fact{},
+		Runs:          []feedback.Record{},
+		Feedback:      []feedback.Feedback{},
+	}
+	dated, _ := c.sessions.(session.Dated)
+	for _, id := range ids {
+		if session.Owner(id) != user {
+			continue
+		}
+		history, err := c.sessions.Get(ctx, id)
+		if errors.Is(err, session.ErrNotFound) {
+			continue // deleted since List
+		}
+		if err != nil {
+			return nil, fmt.Errorf("compliance: reading session %s: %w", id, err)
+		}
+		conv := Conversation{Session: id, Messages: history}
+		if dated != nil {
+			if conv.Updated, err = dated.Updated(ctx, id); err != nil && !errors.Is(err, session.ErrNotFound) {
+				return nil, fmt.Errorf("compliance: session %s: %w", id, err)
+			}
+		}
+		if c.costs != nil {
+			conv.Cost = c.costs.Session(id)
+		}
+		b.Conversations = append(b.Conversations, conv)
+	}
+
+	runs := make(map[string]bool)
+	for _, rec := range c.records() {
+		if owner(rec) != user {
+			for _, fb := range rec.Feedback {
+				if fb.UserID == user {
+					b.Feedback = append(b.Feedback, fb)
+				}
+			}
+			continue
+		}
+		// Someone else's feedback on the user's run is theirs to keep.
+		rec.Feedback = slices.DeleteFunc(rec.Feedback, func(fb feedback.Feedback) bool {
+			return fb.UserID != "" && fb.UserID != user
+		})
+		runs[rec.RunID] = true
+		b.Runs = append(b.Runs, rec)
+		b.Usage = b.Usage.Add(rec.Usage)
+		b.Cost += rec.Cost
+	}
+	if c.memory != nil {
+		for _, f := range c.memory.Facts() {
+			if f.User == user || runs[f.Source] {
+				b.Facts = append(b.Facts, f)
+			}
+		}
+	}
+	return b, nil
+}
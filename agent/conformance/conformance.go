@@ -0,0 +1,252 @@
+// Package conformance drives a provider through the agent's tool calling
+// loop - the scenarios every provider has to get right for agents to work
+// on it:
+//
+//   - SingleToolCall: one call, its result, a final answer
+//   - ParallelToolCalls: several calls in one response, all answered
+//   - ToolError: a failing tool, reported to the model, which carries on
+//   - MultiTurnAfterTools: a second Run on a history that contains tools
+//   - EmptyAssistantContent: a history whose tool-calling assistant message
+//     has no text, which some APIs reject unless the provider handles it
+//
+// Where llm/providertest checks single requests, this checks the
+// conversation the agent builds over several of them:
+//
+//	func TestAgentLoop(t *testing.T) {
+//	    conformance.Run(t, acme.New(key, "acme-large"))
+//	}
+//
+// The scenarios talk to the real model and check what the agent did - which
+// tools ran, with what, and whether the answer used their results - so run
+// them whenever a provider or its API version changes.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Scenario names, for Skip.
+const (
+	SingleToolCall        = "SingleToolCall"
+	ParallelToolCalls     = "ParallelToolCalls"
+	ToolError             = "ToolError"
+	MultiTurnAfterTools   = "MultiTurnAfterTools"
+	EmptyAssistantContent = "EmptyAssistantContent"
+)
+
+// Option configures Run.
+type Option func(*config)
+
+type config struct {
+	timeout time.Duration
+	skip    []string
+	agent   []agent.Option
+}
+
+// Skip leaves out the named scenarios - ParallelToolCalls for a model that
+// only ever calls one tool at a time, say.
+func Skip(scenarios ...string) Option {
+	return func(c *config) { c.skip = append(c.skip, scenarios...) }
+}
+
+// WithTimeout sets how long each scenario may take. Default 2 minutes.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) { c.timeout = d }
+}
+
+// WithAgentOptions adds options to every agent the scenarios create, to
+// run them with streaming, a history policy or whatever else the provider
+// will be used with.
+func WithAgentOptions(opts ...agent.Option) Option {
+	return func(c *config) { c.agent = append(c.agent, opts...) }
+}
+
+// Run runs every scenario not skipped as a subtest of t.
+func Run(t *testing.T, p llm.ChatProvider, opts ...Option) {
+	t.Helper()
+	cfg := config{timeout: 2 * time.Minute}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	scenarios := []struct {
+		name string
+		fn   func(t *testing.T, s *scenario)
+	}{
+		{SingleToolCall, singleToolCall},
+		{ParallelToolCalls, parallelToolCalls},
+		{ToolError, toolError},
+		{MultiTurnAfterTools, multiTurnAfterTools},
+		{EmptyAssistantContent, emptyAssistantContent},
+	}
+	for _, sc := range scenarios {
+		if slices.Contains(cfg.skip, sc.name) {
+			continue
+		}
+		t.Run(sc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+			defer cancel()
+			sc.fn(t, newScenario(t, ctx, p, cfg))
+		})
+	}
+}
+
+// scenario is an agent with the scenarios' tools, recording their calls.
+type scenario struct {
+	ctx   context.Context
+	agent *agent.Agent
+
+	mu    sync.Mutex // parallel calls may run concurrently
+	calls []string   // "tool(arguments)" in call order
+}
+
+func (s *scenario) record(call string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+type addArgs struct {
+	A int `json:"a" description:"First number"`
+	B int `json:"b" description:"Second number"`
+}
+
+type cityArgs struct {
+	City string `json:"city" description:"City name"`
+}
+
+// errStationOffline is what the weather tool fails with in ToolError.
+var errStationOffline = errors.New("weather station offline")
+
+func newScenario(t *testing.T, ctx context.Context, p llm.ChatProvider, cfg config) *scenario {
+	t.Helper()
+	s := &scenario{ctx: ctx}
+	opts := append([]agent.Option{
+		agent.WithSystemPrompts("You are a precise assistant. Use the tools whenever they can answer the question, and base your answer on their results."),
+	}, cfg.agent...)
+	s.agent = agent.New(p, opts...)
+
+	must := func(err error) {
+		if err != nil {
+			t.Fatalf("registering tools: %v", err)
+		}
+	}
+	must(s.agent.RegisterTool("add", "Add two integers and return the sum.", func(args addArgs) string {
+		s.record("add")
+		return strconv.Itoa(args.A + args.B)
+	}))
+	must(s.agent.RegisterTool("get_weather", "Get the current temperature in a city, in degrees Celsius.", func(args cityArgs) (string, error) {
+		s.record("get_weather(" + strings.ToLower(args.City) + ")")
+		switch strings.ToLower(args.City) {
+		case "paris":
+			return "Paris: 17 degrees, light rain", nil
+		case "tokyo":
+			return "Tokyo: 23 degrees, sunny", nil
+		case "reykjavik":
+			return "", errStationOffline
+		}
+		return args.City + ": 20 degrees, cloudy", nil
+	}))
+	return s
+}
+
+func (s *scenario) run(t *testing.T, input string) *agent.RunResult {
+	t.Helper()
+	res, err := s.agent.RunWithResult(s.ctx, input)
+	if err != nil {
+		t.Fatalf("Run(%q): %v", input, err)
+	}
+	if res.FinishReason != "stop" {
+		t.Errorf("run finished with %q, want \"stop\"", res.FinishReason)
+	}
+	if strings.TrimSpace(res.Content) == "" {
+		t.Error("the final answer is empty")
+	}
+	return res
+}
+
+func singleToolCall(t *testing.T, s *scenario) {
+	res := s.run(t, "What is 1234 + 4321? Use the add tool.")
+	if !slices.Equal(s.calls, []string{"add"}) {
+		t.Errorf("tool calls = %v, want one add", s.calls)
+	}
+	if !strings.Contains(strings.ReplaceAll(res.Content, ",", ""), "5555") {
+		t.Errorf("answer %q doesn't contain the tool's result 5555", res.Content)
+	}
+}
+
+func parallelToolCalls(t *testing.T, s *scenario) {
+	res := s.run(t, "What's the weather in Paris and in Tokyo? Look both up at once.")
+	if !slices.Contains(s.calls, "get_weather(paris)") || !slices.Contains(s.calls, "get_weather(tokyo)") {
+		t.Fatalf("tool calls = %v, want weather for Paris and Tokyo", s.calls)
+	}
+	parallel := false
+	for _, turn := range res.Turns {
+		if len(turn.ToolCalls) >= 2 {
+			parallel = true
+		}
+	}
+	if !parallel {
+		t.Errorf("the calls came one per response; want them in one (tool calls = %v)", s.calls)
+	}
+	if !strings.Contains(res.Content, "17") || !strings.Contains(res.Content, "23") {
+		t.Errorf("answer %q doesn't use both results (17 and 23)", res.Content)
+	}
+}
+
+func toolError(t *testing.T, s *scenario) {
+	res := s.run(t, "What's the weather in Reykjavik?")
+	if !slices.Contains(s.calls, "get_weather(reykjavik)") {
+		t.Fatalf("tool calls = %v, want weather for Reykjavik", s.calls)
+	}
+	failed := false
+	for _, trace := range res.ToolCalls() {
+		if errors.Is(trace.Err, errStationOffline) {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Error("the tool's error isn't in the run's tool traces")
+	}
+	if strings.Contains(res.Content, "20 degrees") {
+		t.Errorf("answer %q reports weather the tool never returned", res.Content)
+	}
+}
+
+func multiTurnAfterTools(t *testing.T, s *scenario) {
+	s.run(t, "What is 1234 + 4321? Use the add tool.")
+	res := s.run(t, "Now add 1000 to that result, using the tool again.")
+	if len(s.calls) < 2 {
+		t.Errorf("tool calls = %v, want add in both turns", s.calls)
+	}
+	if !strings.Contains(strings.ReplaceAll(res.Content, ",", ""), "6555") {
+		t.Errorf("second answer %q doesn't contain 6555", res.Content)
+	}
+}
+
+func emptyAssistantContent(t *testing.T, s *scenario) {
+	// A history where the model called a tool without saying anything -
+	// how most models call tools - then the result, then a new question.
+	s.agent.History = append(s.agent.History,
+		llm.NewUserMessage("What's the weather in Paris?"),
+		llm.Message{Role: "assistant", Content: "", ToolCalls: []llm.ToolCall{{
+			ID:       "call_conformance_1",
+			Type:     "function",
+			Function: llm.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+		}}},
+		llm.NewToolResult("call_conformance_1", "get_weather", "Paris: 17 degrees, light rain"),
+		llm.NewAssistantMessage("It's 17 degrees with light rain in Paris."),
+	)
+	res := s.run(t, "Should I take an umbrella there? Answer yes or no, then why.")
+	if !strings.Contains(strings.ToLower(res.Content), "yes") {
+		t.Errorf("answer %q doesn't follow from the earlier tool result (rain)", res.Content)
+	}
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ContextProvider supplies background for one Run: recalled memories,
+// the current date, facts about the user. It gets the user's message (""
+// when the Run continues the conversation) and returns text for the
+// system prompt, or "" for nothing this time.
+type ContextProvider func(ctx context.Context, input string) (string, error)
+
+// WithContextProvider calls p at the start of every Run, after the message
+// middleware, and adds what it returns to the system prompt of each
+// request in that Run. Calling it again adds to the list; their texts go
+// in that order.
+//
+// The text is not stored in the history, so it never piles up and is
+// fresh each Run.
+func WithContextProvider(p ...ContextProvider) Option {
+	return func(a *Agent) {
+		a.contextProviders = append(a.contextProviders, p...)
+	}
+}
+
+// runContextBlocks asks every context provider for this Run's text.
+func (a *Agent) runContextBlocks(ctx context.Context, input string) ([]string, error) {
+	var blocks []string
+	for _, p := range a.contextProviders {
+		text, err := p(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("agent: context provider: %w", err)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			blocks = append(blocks, text)
+		}
+	}
+	return blocks, nil
+}
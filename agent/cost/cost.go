@@ -0,0 +1,210 @@
+// Package cost puts dollar figures on agent runs: a pricing table per
+// model and a Tracker that adds up what agents spend, in total, per agent
+// and per session.
+//
+//	tracker := cost.NewTracker(cost.Default)
+//	a := agent.New(provider, tracker.Track("support"),
+//	    agent.WithSession(store, sessionID))
+//
+//	res, err := a.RunWithResult(ctx, msg)
+//	log.Printf("this run: $%.4f", res.Cost)
+//
+//	a.WaitObservers(ctx)
+//	log.Printf("this session: $%.4f", tracker.Session(sessionID))
+//
+// Prices change. Default holds list prices at the time of writing; copy
+// it and adjust for your contracts, or build your own Table.
+package cost
+
+import (
+	"context"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// Price is what a model charges, in US dollars per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`  // prompt tokens
+	Output float64 `json:"output"` // completion tokens, including reasoning
+}
+
+// Table prices models by name. Names match exactly, or as the longest
+// entry the model name starts with - so "gpt-4o-2024-08-06" is priced as
+// "gpt-4o", and "claude-sonnet-4-20250514" as "claude-sonnet-4". A
+// provider prefix like OpenRouter's "openai/" is ignored.
+type Table map[string]Price
+
+// Default has the list prices of common OpenAI, Anthropic and Google
+// models, for standard (not batch, not cached) usage.
+var Default = Table{
+	"gpt-5":        {Input: 1.25, Output: 10},
+	"gpt-5-mini":   {Input: 0.25, Output: 2},
+	"gpt-5-nano":   {Input: 0.05, Output: 0.40},
+	"gpt-4.1":      {Input: 2, Output: 8},
+	"gpt-4.1-mini": {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano": {Input: 0.10, Output: 0.40},
+	"gpt-4o":       {Input: 2.50, Output: 10},
+	"gpt-4o-mini":  {Input: 0.15, Output: 0.60},
+	"o3":           {Input: 2, Output: 8},
+	"o3-mini":      {Input: 1.10, Output: 4.40},
+	"o4-mini":      {Input: 1.10, Output: 4.40},
+
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-haiku-4-5":  {Input: 1, Output: 5},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4},
+
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+}
+
+// Lookup finds the price for model.
+func (t Table) Lookup(model string) (Price, bool) {
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+	if _, name, ok := strings.Cut(model, "/"); ok {
+		model = name
+		if p, ok := t[model]; ok {
+			return p, true
+		}
+	}
+	best, found := "", false
+	for name := range t {
+		if len(name) > len(best) && strings.HasPrefix(model, name) {
+			best, found = name, true
+		}
+	}
+	return t[best], found
+}
+
+// Cost returns what usage cost on model. It's an agent.PriceFunc:
+//
+//	a := agent.New(provider, agent.WithPricing(cost.Default.Cost))
+func (t Table) Cost(model string, usage llm.Usage) (float64, bool) {
+	p, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6, true
+}
+
+// Tracker adds up the cost of every run of the agents it's attached to.
+// It is safe for concurrent use, and one Tracker can serve any number of
+// agents - one per request, say, each on its own session.
+type Tracker struct {
+	table Table
+
+	mu       sync.Mutex
+	total    float64
+	agents   map[string]float64
+	sessions map[string]float64
+	unpriced map[string]int // models without a price, and how many calls they served
+}
+
+// NewTracker returns a Tracker pricing with table.
+func NewTracker(table Table) *Tracker {
+	return &Tracker{
+		table:    table,
+		agents:   make(map[string]float64),
+		sessions: make(map[string]float64),
+		unpriced: make(map[string]int),
+	}
+}
+
+// Track returns an option that prices the agent's runs with the
+// Tracker's table, so RunResult.Cost is set, and adds each run's cost to
+// the Tracker under name and the run's session.
+//
+// The Tracker is an observer of the agent, so the sums are updated in the
+// background right after each run. Call the agent's WaitObservers first if
+// a figure has to include the run that just returned.
+func (t *Tracker) Track(name string) agent.Option {
+	return func(a *agent.Agent) {
+		agent.WithPricing(t.price)(a)
+		agent.WithObserver(agent.ObserverFunc(func(_ context.Context, obs agent.Observation) {
+			t.add(name, obs.Result.Session, obs.Result.Cost)
+		}))(a)
+	}
+}
+
+// price is Table.Cost, noting models without a price.
+func (t *Tracker) price(model string, usage llm.Usage) (float64, bool) {
+	usd, ok := t.table.Cost(model, usage)
+	if !ok {
+		t.mu.Lock()
+		t.unpriced[model]++
+		t.mu.Unlock()
+	}
+	return usd, ok
+}
+
+func (t *Tracker) add(name, session string, usd float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total += usd
+	t.agents[name] += usd
+	if session != "" {
+		t.sessions[session] += usd
+	}
+}
+
+// Total returns what every tracked agent has spent.
+func (t *Tracker) Total() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Agent returns what the agents tracked under name have spent.
+func (t *Tracker) Agent(name string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.agents[name]
+}
+
+// Session returns what has been spent on the session with this ID.
+func (t *Tracker) Session(id string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessions[id]
+}
+
+// Report is a snapshot of a Tracker's sums.
+type Report struct {
+	Total    float64            `json:"total"`
+	Agents   map[string]float64 `json:"agents"`
+	Sessions map[string]float64 `json:"sessions"`
+
+	// Unpriced counts the calls to models the table had no price for,
+	// by model. They're missing from the sums.
+	Unpriced map[string]int `json:"unpriced,omitempty"`
+}
+
+// Report returns a copy of the sums.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Report{
+		Total:    t.total,
+		Agents:   maps.Clone(t.agents),
+		Sessions: maps.Clone(t.sessions),
+		Unpriced: maps.Clone(t.unpriced),
+	}
+}
+
+// ForgetSession drops a session's sum, once it's over and billed, so a
+// long-lived Tracker doesn't grow with every session it has seen.
+func (t *Tracker) ForgetSession(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, id)
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-agent-sdk/llm"
+)
+
+// CurrentContext returns a ContextProvider that tells the model where it
+// is in time: the date and time in the user's timezone, the weekday, the
+// week number, and optionally the locale, business hours, holidays and
+// facts about the business. Models have no clock and a training cutoff,
+// so without it they get "today", "next Friday" and "is the office
+// open?" wrong.
+//
+//	a := agent.New(provider, agent.WithContextProvider(agent.CurrentContext(
+//	    agent.CurrentTimezone(berlin),
+//	    agent.CurrentBusinessHours(agent.BusinessHours{Open: 9 * time.Hour, Close: 17 * time.Hour}),
+//	    agent.CurrentFact("Company", "Acme GmbH, selling garden furniture"),
+//	)))
+//
+// For per-user timezones and locales, put them on the Run's context with
+// ContextWithUserTimezone and ContextWithUserLocale; they win over the
+// options. The time comes from llm.Now, so replays and tests see the
+// clock they set.
+func CurrentContext(opts ...CurrentContextOption) ContextProvider {
+	c := &currentContext{loc: time.UTC}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c.provide
+}
+
+// CurrentContextOption configures CurrentContext.
+type CurrentContextOption func(*currentContext)
+
+type currentContext struct {
+	loc      *time.Location
+	locale   string
+	hours    *BusinessHours
+	holidays map[string]string // "2006-01-02" -> name
+	facts    []fact
+}
+
+type fact struct {
+	name  string
+	value func(ctx context.Context) (string, error)
+}
+
+// BusinessHours are the hours the business is open, in its timezone.
+type BusinessHours struct {
+	Days     []time.Weekday // nil means Monday to Friday
+	Open     time.Duration  // since midnight, e.g. 9*time.Hour
+	Close    time.Duration  // since midnight, e.g. 17*time.Hour + 30*time.Minute
+	Location *time.Location // nil means the user's timezone
+}
+
+// CurrentTimezone sets the timezone to give the time in when the Run's
+// context doesn't carry the user's. The default is UTC.
+func CurrentTimezone(loc *time.Location) CurrentContextOption {
+	return func(c *currentContext) {
+		c.loc = loc
+	}
+}
+
+// CurrentLocale sets the locale to mention, such as "en-GB", when the
+// Run's context doesn't carry the user's. The model uses it for date and
+// number formats and spelling.
+func CurrentLocale(locale string) CurrentContextOption {
+	return func(c *currentContext) {
+		c.locale = locale
+	}
+}
+
+// CurrentBusinessHours adds the opening hours and whether the business
+// is open right now.
+func CurrentBusinessHours(h BusinessHours) CurrentContextOption {
+	return func(c *currentContext) {
+		c.hours = &h
+	}
+}
+
+// CurrentHolidays adds holidays, keyed by date as "2006-01-02". The block
+// says when today is one, and names the next one within 60 days.
+func CurrentHolidays(holidays map[string]string) CurrentContextOption {
+	return func(c *currentContext) {
+		if c.holidays == nil {
+			c.holidays = make(map[string]string, len(holidays))
+		}
+		for day, name := range holidays {
+			c.holidays[day] = name
+		}
+	}
+}
+
+// CurrentFact adds a fixed fact, such as the company name or the return
+// policy's length, listed under its name.
+func CurrentFact(name, value string) CurrentContextOption {
+	return CurrentFactFunc(name, func(context.Context) (string, error) { return value, nil })
+}
+
+// CurrentFactFunc adds a fact looked up each Run, such as the user's plan
+// or today's on-call engineer. An empty value leaves it out; an error
+// fails the Run.
+func CurrentFactFunc(name string, value func(ctx context.Context) (string, error)) CurrentContextOption {
+	return func(c *currentContext) {
+		c.facts = append(c.facts, fact{name: name, value: value})
+	}
+}
+
+type userTimezoneKey struct{}
+type userLocaleKey struct{}
+
+// ContextWithUserTimezone returns a copy of ctx saying the user is in
+// loc, for CurrentContext.
+func ContextWithUserTimezone(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, userTimezoneKey{}, loc)
+}
+
+// ContextWithUserLocale returns a copy of ctx saying the user's locale,
+// for CurrentContext.
+func ContextWithUserLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, userLocaleKey{}, locale)
+}
+
+func (c *currentContext) provide(ctx context.Context, _ string) (string, error) {
+	loc := c.loc
+	if l, ok := ctx.Value(userTimezoneKey{}).(*time.Location); ok && l != nil {
+		loc = l
+	}
+	locale := c.locale
+	if l, ok := ctx.Value(userLocaleKey{}).(string); ok && l != "" {
+		locale = l
+	}
+	now := llm.Now(ctx).In(loc)
+
+	var b strings.Builder
+	b.WriteString("Current context:\n")
+	_, week := now.ISOWeek()
+	fmt.Fprintf(&b, "- Now: %s, %s (%s)\n", now.Format("Monday 2 January 2006, 15:04"), zoneName(now), now.Format("UTC-07:00"))
+	fmt.Fprintf(&b, "- ISO date: %s, week %d\n", now.Format("2006-01-02"), week)
+	fmt.Fprintf(&b, "- Yesterday was %s; tomorrow is %s\n",
+		now.AddDate(0, 0, -1).Format("Monday 2 January"), now.AddDate(0, 0, 1).Format("Monday 2 January"))
+	if locale != "" {
+		fmt.Fprintf(&b, "- User's locale: %s\n", locale)
+	}
+	if c.hours != nil {
+		b.WriteString("- " + c.hours.describe(now) + "\n")
+	}
+	if len(c.holidays) > 0 {
+		if name, ok := c.holidays[now.Format("2006-01-02")]; ok {
+			fmt.Fprintf(&b, "- Today is a holiday: %s\n", name)
+		}
+		if day, name, ok := c.nextHoliday(now); ok {
+			fmt.Fprintf(&b, "- Next holiday: %s on %s\n", name, day.Format("Monday 2 January 2006"))
+		}
+	}
+	for _, f := range c.facts {
+		v, err := f.value(ctx)
+		if err != nil {
+			return "", fmt.Errorf("fact %s: %w", f.name, err)
+		}
+		if v = strings.TrimSpace(v); v != "" {
+			fmt.Fprintf(&b, "- %s: %s\n", f.name, v)
+		}
+	}
+	b.WriteString(`Use this for "today", "tomorrow", "this week" and any date arithmetic; don't assume a date from your training data.`)
+	return b.String(), nil
+}
+
+// zoneName names now's timezone: the IANA name where there is one.
+func zoneName(now time.Time) string {
+	if name := now.Location().String(); name != "" && name != "Local" {
+		return name
+	}
+	name, _ := now.Zone()
+	return name
+}
+
+func (c *currentContext) nextHoliday(now time.Time) (time.Time, string, bool) {
+	var days []string
+	for day := range c.holidays {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	today := now.Format("2006-01-02")
+	limit := now.AddDate(0, 0, 60).Format("2006-01-02")
+	for _, day := range days {
+		if day > today && day <= limit {
+			t, err := time.ParseInLocation("2006-01-02", day, now.Location())
+			if err == nil {
+				return t, c.holidays[day], true
+			}
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// describe gives the opening hours and whether they cover now.
+func (h *BusinessHours) describe(now time.Time) string {
+	if h.Location != nil {
+		now = now.In(h.Location)
+	}
+	days := h.Days
+	if days == nil {
+		days = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	}
+	names := make([]string, len(days))
+	openToday := false
+	for i, d := range days {
+		names[i] = d.String()[:3]
+		openToday = openToday || d == now.Weekday()
+	}
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := now.Sub(midnight)
+	state := "closed now"
+	if openToday && since >= h.Open && since < h.Close {
+		state = "open now"
+	}
+	return fmt.Sprintf("Business hours: %s %s-%s %s; %s", strings.Join(names, ", "), clock(h.Open), clock(h.Close), zoneName(now), state)
+}
@@ -0,0 +1,29 @@
+package agent
+
+import "go-agent-sdk/tools"
+
+// Effect is a side effect a tool recorded; see tools.Effect.
+type Effect = tools.Effect
+
+// Effects returns the side effects tools have recorded with
+// tools.RecordEffect over the agent's lifetime, oldest first - the
+// agent's log of what it changed in the world, as opposed to what it
+// said. RunResult.Effects has just one run's.
+//
+//	for _, e := range a.Effects() {
+//	    audit.Log(e.RunID, e.Kind, e.Target)
+//	}
+func (a *Agent) Effects() []Effect {
+	return append([]Effect(nil), a.effects...)
+}
+
+// EffectsOfKind returns the recorded effects with the given Kind.
+func (a *Agent) EffectsOfKind(kind string) []Effect {
+	var out []Effect
+	for _, e := range a.effects {
+		if e.Kind == kind {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"errors"
+	"go-agent-sdk/tools"
+)
+
+// ErrMaxIterations is returned (wrapped) by Run when the model keeps asking
+// for tools past the limit set with WithMaxToolIterations.
+//
+//	reply, err := a.Run(ctx, msg)
+//	if errors.Is(err, agent.ErrMaxIterations) {
+//	    // the model is looping - give up, or raise the limit and retry
+//	}
+var ErrMaxIterations = errors.New("agent: max tool iterations exceeded")
+
+// ErrNoChoices is returned by Run when the provider answers with an empty
+// choices array - usually a content filter or a misbehaving proxy.
+var ErrNoChoices = errors.New("agent: LLM returned no choices")
+
+// UnexpectedFinishReasonError is returned by Run when the model stops for a
+// reason the agent can't continue from, such as "length" (max tokens hit)
+// or "content_filter".
+//
+//	var fr *agent.UnexpectedFinishReasonError
+//	if errors.As(err, &fr) && fr.Reason == "length" {
+//	    // answer was cut off - raise WithMaxTokens
+//	}
+type UnexpectedFinishReasonError struct {
+	Reason string
+}
+
+func (e *UnexpectedFinishReasonError) Error() string {
+	return "unexpected finish_reason: " + e.Reason
+}
+
+// ToolNotFoundError is what executing an unregistered tool fails with.
+// The agent doesn't return it from Run - the error goes back to the LLM so
+// it can pick another tool - but it shows up in ToolTrace.Err and in
+// callbacks. It's the same type as tools.NotFoundError.
+type ToolNotFoundError = tools.NotFoundError
+
+// ToolNotAllowedError is what a call fails with when a WithToolFilter
+// filter rejects the tool. Like ToolNotFoundError it goes back to the LLM
+// rather than out of Run.
+type ToolNotAllowedError struct {
+	Name string
+}
+
+func (e *ToolNotAllowedError) Error() string {
+	return "tool " + e.Name + " is not available right now"
+}
+
+// ProviderError wraps a failed LLM call, after any retries. Unwrap it to
+// get the provider's own error, e.g. an *llm.APIError with the HTTP status:
+//
+//	var pe *agent.ProviderError
+//	if errors.As(err, &pe) {
+//	    log.Printf("%s failed: %v", pe.Model, pe.Err)
+//	}
+//	var apiErr *llm.APIError
+//	if errors.As(err, &apiErr) && apiErr.StatusCode == 401 { ... }
+type ProviderError struct {
+	Model string // the provider's ModelName
+	Err   error
+}
+
+func (e *ProviderError) Error() string {
+	return "LLM call failed: " + e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"context"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/tools"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Escalation triggers.
+const (
+	EscalateToolFailures      = "tool_failures"      // too many failed tool calls in one Run
+	EscalateNegativeSentiment = "negative_sentiment" // the user has been unhappy for a while
+	EscalateRequested         = "requested"          // the user asked for a person
+)
+
+// EscalationPolicy decides when a conversation should go to a human. Any
+// one trigger is enough. Set it with WithEscalation.
+type EscalationPolicy struct {
+	// MaxToolFailures escalates when this many tool calls fail in one
+	// Run - the agent is stuck. 0 turns the trigger off.
+	MaxToolFailures int
+
+	// NegativeMessages escalates after this many user messages in a row
+	// tagged with negative sentiment - see agent/tagging, which has to be
+	// registered before the policy. 0 turns the trigger off.
+	NegativeMessages int
+
+	// Phrases escalate when a user message contains one of them, ignoring
+	// case. nil means DefaultEscalationPhrases; an empty, non-nil slice
+	// turns the trigger off.
+	Phrases []string
+
+	// Summarizer writes the summary handed to the human. nil means the
+	// summary is just the last few messages.
+	Summarizer llm.ChatProvider
+
+	// OnEscalate is called when the policy escalates, before the Run
+	// returns its *InterruptError.
+	OnEscalate func(ctx context.Context, e *Escalation)
+}
+
+// DefaultEscalationPhrases are the ways users usually ask for a person.
+var DefaultEscalationPhrases = []string{
+	"talk to a human", "speak to a human", "talk to a person", "speak to a person",
+	"real person", "human agent", "live agent", "talk to someone", "speak to someone",
+	"customer service representative", "speak to a manager", "talk to a manager",
+}
+
+// Escalation is the event emitted when a conversation goes to a human. It
+// is the Payload of the Run's *InterruptError.
+type Escalation struct {
+	RunID   string    `json:"run_id"`
+	Trigger string    `json:"trigger"` // one of the Escalate constants
+	Reason  string    `json:"reason"`  // what exactly happened
+	Summary string    `json:"summary"` // the conversation so far, for the human
+	Time    time.Time `json:"time"`
+}
+
+// WithEscalation hands conversations over to a human according to policy.
+// When a trigger fires the Run pauses with an *InterruptError whose
+// Payload is the *Escalation:
+//
+//	a := agent.New(provider,
+//	    agent.WithMessageMiddleware(tagging.New(cheap)),
+//	    agent.WithEscalation(agent.EscalationPolicy{
+//	        MaxToolFailures:  3,
+//	        NegativeMessages: 2,
+//	        Summarizer:       cheap,
+//	        OnEscalate:       func(ctx context.Context, e *agent.Escalation) { queue.Push(e) },
+//	    }),
+//	)
+//
+//	reply, err := a.Run(ctx, msg)
+//	var ie *agent.InterruptError
+//	if errors.As(err, &ie) {
+//	    reply = "I've passed you to a colleague, who will be with you shortly."
+//	}
+func WithEscalation(policy EscalationPolicy) Option {
+	return func(a *Agent) {
+		if policy.Phrases == nil {
+			policy.Phrases = DefaultEscalationPhrases
+		}
+		e := &escalator{agent: a, policy: policy}
+		WithMessageMiddleware(e.checkMessage)(a)
+		WithToolMiddleware(e.countFailures)(a)
+	}
+}
+
+type escalator struct {
+	agent  *Agent
+	policy EscalationPolicy
+
+	mu       sync.Mutex
+	negative int    // negative user messages in a row
+	runID    string // the run failures are being counted for
+	failures int
+}
+
+// checkMessage looks at each user message for a request for a person or
+// a run of negative sentiment.
+func (e *escalator) checkMessage(ctx context.Context, msg *llm.Message) error {
+	text := strings.ToLower(msg.Content)
+	for _, p := range e.policy.Phrases {
+		if strings.Contains(text, strings.ToLower(p)) {
+			e.escalate(ctx, EscalateRequested, "the user asked for a human: "+msg.Content, msg)
+			return nil
+		}
+	}
+
+	if e.policy.NegativeMessages <= 0 {
+		return nil
+	}
+	e.mu.Lock()
+	if msg.Metadata["sentiment"] == "negative" { // tagging.SentimentKey, tagging.Negative
+		e.negative++
+	} else {
+		e.negative = 0
+	}
+	hit := e.negative >= e.policy.NegativeMessages
+	if hit {
+		e.negative = 0
+	}
+	e.mu.Unlock()
+	if hit {
+		e.escalate(ctx, EscalateNegativeSentiment, "the user has been unhappy for several messages", msg)
+	}
+	return nil
+}
+
+// countFailures counts failed tool calls per run.
+func (e *escalator) countFailures(next tools.Handler) tools.Handler {
+	return func(ctx context.Context, call tools.Call) (tools.Result, error) {
+		res, err := next(ctx, call)
+		if err == nil || e.policy.MaxToolFailures <= 0 {
+			return res, err
+		}
+
+		tc, _ := llm.TraceFromContext(ctx)
+		e.mu.Lock()
+		if tc.RunID != e.runID {
+			e.runID, e.failures = tc.RunID, 0
+		}
+		e.failures++
+		hit := e.failures == e.policy.MaxToolFailures
+		e.mu.Unlock()
+		if hit {
+			e.escalate(ctx, EscalateToolFailures, "tool calls keep failing, the last: "+call.Name+": "+err.Error(), nil)
+		}
+		return res, err
+	}
+}
+
+// escalate builds the Escalation, reports it and pauses the run. pending
+// is a user message not yet in the history.
+func (e *escalator) escalate(ctx context.Context, trigger, reason string, pending *llm.Message) {
+	history := e.agent.History
+	if pending != nil {
+		history = append(history[:len(history):len(history)], *pending)
+	}
+	tc, _ := llm.TraceFromContext(ctx)
+	esc := &Escalation{
+		RunID:   tc.RunID,
+		Trigger: trigger,
+		Reason:  reason,
+		Summary: e.summarize(ctx, history),
+		Time:    llm.Now(ctx),
+	}
+	if e.policy.OnEscalate != nil {
+		e.policy.OnEscalate(ctx, esc)
+	}
+	RequestInterrupt(ctx, Interrupt{Reason: "escalated to a human: " + reason, Payload: esc})
+}
+
+// summarize describes the conversation for the human taking over. A
+// failed summary falls back to the transcript of the last messages.
+func (e *escalator) summarize(ctx context.Context, history []llm.Message) string {
+	if p := e.policy.Summarizer; p != nil {
+		var transcript strings.Builder
+		for _, m := range history {
+			if m.Role != "system" {
+				writeTranscript(&transcript, m)
+			}
+		}
+		resp, err := p.CreateChat(ctx, llm.ChatRequest{
+			Model: p.ModelName(),
+			Messages: []llm.Message{
+				llm.NewSystemMessage("A conversation between a user and an AI assistant is being handed to a human support agent. " +
+					"Summarize it for them in a few sentences: who the user is, what they want, what has been tried, and what is still open."),
+				llm.NewUserMessage(transcript.String()),
+			},
+		})
+		if err == nil && len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+			return resp.Choices[0].Message.Content
+		}
+	}
+
+	var recent strings.Builder
+	start := max(len(history)-10, 0)
+	for _, m := range history[start:] {
+		if m.Role != "system" {
+			writeTranscript(&recent, m)
+		}
+	}
+	return strings.TrimSpace(recent.String())
+}
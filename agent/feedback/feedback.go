@@ -0,0 +1,291 @@
+// Package feedback links what users say about an answer to the exact run
+// that produced it.
+//
+// Every agent.RunResult has an ID. Hand the result to a Recorder when you
+// show the answer, send the ID to your UI, and record whatever comes back -
+// a thumbs up or down, a comment, a corrected answer. The Recorder keeps the
+// run's input, output and model next to the feedback, so Export can write a
+// dataset for evals or fine-tuning without joining logs afterwards.
+//
+//	rec := feedback.NewRecorder(feedback.WithLog(logFile))
+//
+//	res, err := a.RunWithResult(ctx, question)
+//	rec.Track(res)
+//	respond(res.Content, res.ID)
+//
+//	// later, from the feedback endpoint
+//	rec.Record(ctx, feedback.Feedback{RunID: id, Rating: feedback.ThumbsDown,
+//	    Correction: "The refund window is 30 days, not 14."})
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Rating is a thumbs up or down.
+type Rating int
+
+const (
+	Unrated    Rating = 0
+	ThumbsUp   Rating = 1
+	ThumbsDown Rating = -1
+)
+
+// MarshalJSON writes ratings as "up", "down" or "" so exported datasets
+// are readable.
+func (r Rating) MarshalJSON() ([]byte, error) {
+	switch r {
+	case ThumbsUp:
+		return []byte(`"up"`), nil
+	case ThumbsDown:
+		return []byte(`"down"`), nil
+	}
+	return []byte(`""`), nil
+}
+
+// UnmarshalJSON accepts what MarshalJSON writes.
+func (r *Rating) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	switch s {
+	case "up":
+		*r = ThumbsUp
+	case "down":
+		*r = ThumbsDown
+	default:
+		*r = Unrated
+	}
+	return nil
+}
+
+// Feedback is one piece of user feedback about a run.
+type Feedback struct {
+	RunID      string    `json:"run_id"`
+	Rating     Rating    `json:"rating,omitempty"`
+	Comment    string    `json:"comment,omitempty"`    // free text from the user
+	Correction string    `json:"correction,omitempty"` // what the answer should have been
+	UserID     string    `json:"user_id,omitempty"`
+	Time       time.Time `json:"time"` // set by Record if zero
+}
+
+// Record is a run and all the feedback it received - one line of an
+// exported dataset.
+type Record struct {
+	RunID    string     `json:"run_id"`
+	Model    string     `json:"model"`
+	Input    string     `json:"input"`
+	Output   string     `json:"output"`
+	Feedback []Feedback `json:"feedback"`
+
+	Session string    `json:"session,omitempty"` // the run's session, if it had one
+	Tracked time.Time `json:"tracked"`           // when Track was called
+	Usage   llm.Usage `json:"usage"`             // tokens across the run
+	Cost    float64   `json:"cost,omitempty"`    // dollars, with agent.WithPricing
+
+	// Effects are the side effects the run's tools recorded - what the
+	// agent did, next to what it said.
+	Effects []agent.Effect `json:"effects,omitempty"`
+
+	// Trace is the full run (every request, response and tool call).
+	// Only exported when the Recorder was created WithTraces.
+	Trace *agent.RunResult `json:"trace,omitempty"`
+}
+
+// ErrUnknownRun is returned by Record when the run was never tracked, or
+// was tracked so long ago that it has been evicted.
+var ErrUnknownRun = errors.New("feedback: unknown run ID")
+
+// Recorder collects runs and feedback. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	records map[string]*Record
+	order   []string // tracking order, oldest first, for eviction and export
+	max     int
+	traces  bool
+	log     io.Writer
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithMaxRuns caps how many runs are kept in memory. The oldest are
+// evicted first. Defaults to 10000.
+func WithMaxRuns(n int) Option {
+	return func(r *Recorder) {
+		r.max = n
+	}
+}
+
+// WithTraces keeps the whole RunResult for each run and includes it in
+// exports. Useful for debugging bad answers; much bigger datasets.
+func WithTraces() Option {
+	return func(r *Recorder) {
+		r.traces = true
+	}
+}
+
+// WithLog appends every feedback entry to w as a JSON line the moment it's
+// recorded, including the run's input and output, so nothing is lost if
+// the process restarts before an Export.
+func WithLog(w io.Writer) Option {
+	return func(r *Recorder) {
+		r.log = w
+	}
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder(opts ...Option) *Recorder {
+	r := &Recorder{
+		records: make(map[string]*Record),
+		max:     10000,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Track remembers a run so feedback can be recorded against its ID.
+func (r *Recorder) Track(res *agent.RunResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.records[res.ID]; ok {
+		return
+	}
+	rec := &Record{
+		RunID:   res.ID,
+		Model:   res.Model,
+		Input:   res.Input,
+		Output:  res.Content,
+		Session: res.Session,
+		Tracked: time.Now(),
+		Usage:   res.Usage,
+		Cost:    res.Cost,
+		Effects: res.Effects(),
+	}
+	if r.traces {
+		rec.Trace = res
+	}
+	r.records[res.ID] = rec
+	r.order = append(r.order, res.ID)
+
+	for r.max > 0 && len(r.order) > r.max {
+		delete(r.records, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+// Record attaches feedback to a tracked run. A run can get several pieces
+// of feedback (a thumbs down, then a correction).
+func (r *Recorder) Record(ctx context.Context, fb Feedback) error {
+	if fb.Time.IsZero() {
+		fb.Time = llm.Now(ctx)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[fb.RunID]
+	if !ok {
+		return ErrUnknownRun
+	}
+	rec.Feedback = append(rec.Feedback, fb)
+
+	if r.log != nil {
+		line := struct {
+			Feedback
+			Model  string `json:"model"`
+			Input  string `json:"input"`
+			Output string `json:"output"`
+		}{fb, rec.Model, rec.Input, rec.Output}
+		if err := json.NewEncoder(r.log).Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Feedback returns the feedback recorded for a run, oldest first.
+func (r *Recorder) Feedback(runID string) []Feedback {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[runID]
+	if !ok {
+		return nil
+	}
+	return append([]Feedback(nil), rec.Feedback...)
+}
+
+// Export writes every run that has feedback to w as JSON lines, one Record
+// per line, oldest run first. Runs nobody rated are left out.
+func (r *Recorder) Export(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	for _, id := range r.order {
+		rec := r.records[id]
+		if len(rec.Feedback) == 0 {
+			continue
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records returns every tracked run, rated or not, oldest first.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Record, len(r.order))
+	for i, id := range r.order {
+		out[i] = *r.records[id]
+		out[i].Feedback = slices.Clone(out[i].Feedback)
+	}
+	return out
+}
+
+// ForgetFeedback removes the feedback userID left on a run, keeping the
+// run and everyone else's. It returns how many entries were removed.
+func (r *Recorder) ForgetFeedback(runID, userID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[runID]
+	if !ok {
+		return 0
+	}
+	n := len(rec.Feedback)
+	rec.Feedback = slices.DeleteFunc(rec.Feedback, func(fb Feedback) bool { return fb.UserID == userID })
+	return n - len(rec.Feedback)
+}
+
+// Forget drops runs and their feedback - to honour a deletion request, or
+// once they're too old to keep. Unknown IDs are ignored.
+func (r *Recorder) Forget(runIDs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range runIDs {
+		delete(r.records, id)
+	}
+	r.order = slices.DeleteFunc(r.order, func(id string) bool {
+		_, ok := r.records[id]
+		return !ok
+	})
+}
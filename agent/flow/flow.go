@@ -0,0 +1,249 @@
+// Package flow runs scripted conversations - onboarding questionnaires,
+// sign-up forms, intake interviews - where the agent has to collect a fixed
+// set of fields, in order, each one checked before moving on.
+//
+// The model still does the talking, so users can answer in their own words
+// ("sure, it's bob at example dot com") and ask questions along the way.
+// What it can't do is skip a field, invent a value, or accept one your
+// validation rejects: every answer goes through a record tool that checks
+// it against the current field. When the last field is in, the completion
+// callback gets the values.
+//
+//	signup := flow.New("signup", []flow.Field{
+//	    {Name: "name", Question: "What's your full name?"},
+//	    {Name: "email", Question: "What email should we use?", Validate: validEmail},
+//	    {Name: "company", Question: "Which company are you with?", Optional: true},
+//	}, flow.OnComplete(func(ctx context.Context, v flow.Values) error {
+//	    return crm.CreateLead(ctx, v["name"], v["email"], v["company"])
+//	}))
+//
+//	a := agent.New(provider, agent.WithSystemPrompts("You are Acme's onboarding assistant."))
+//	if err := signup.Attach(a); err != nil {
+//	    return err
+//	}
+//	reply, err := a.Run(ctx, "Hi, I'd like to sign up")
+//
+// A Flow holds the state of one conversation. Create one per user, like
+// the agent itself.
+//
+// For processes bigger than a form, Phases splits the conversation into
+// steps with their own tools and moves between them only when your
+// conditions say so.
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/tools"
+	"strings"
+	"sync"
+)
+
+// Field is one piece of information the flow collects.
+type Field struct {
+	Name        string // key in Values
+	Question    string // what the agent should ask, in spirit if not verbatim
+	Description string // extra guidance for the model: format, examples, what counts
+
+	// Validate checks an answer. The error message is shown to the model,
+	// which passes it on to the user and asks again - so make it something a
+	// user can act on ("that doesn't look like an email address"). nil
+	// accepts any non-empty answer.
+	Validate func(value string) error
+
+	// Optional fields may be skipped by recording an empty value.
+	Optional bool
+}
+
+// Values are the collected answers, by field name.
+type Values map[string]string
+
+// Decode fills a struct from the values, matching fields by their json
+// tags. The values are strings, so destination fields should be strings
+// too (or carry the ",string" json option).
+func (v Values) Decode(dst any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// Flow collects Fields in order. It is safe for concurrent use.
+type Flow struct {
+	name       string
+	fields     []Field
+	onComplete func(ctx context.Context, values Values) error
+
+	mu     sync.Mutex
+	values Values
+	next   int // index of the field being asked for
+}
+
+// Option configures a Flow.
+type Option func(*Flow)
+
+// OnComplete sets the function called once every field has been
+// collected. If it returns an error, the error goes back to the model (so
+// it can tell the user) and the flow stays complete; call Reset to start
+// over.
+func OnComplete(fn func(ctx context.Context, values Values) error) Option {
+	return func(f *Flow) {
+		f.onComplete = fn
+	}
+}
+
+// New creates a flow that collects fields in the given order. name
+// identifies the flow in tool names, so it should be short and
+// snake_case. It panics if there are no fields or names repeat.
+func New(name string, fields []Field, opts ...Option) *Flow {
+	if len(fields) == 0 {
+		panic("flow: New needs at least one field")
+	}
+	seen := map[string]bool{}
+	for _, fd := range fields {
+		if seen[fd.Name] {
+			panic("flow: duplicate field " + fd.Name)
+		}
+		seen[fd.Name] = true
+	}
+
+	f := &Flow{name: name, fields: fields, values: Values{}}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// recordArgs are the record tool's arguments.
+type recordArgs struct {
+	Field string `json:"field" description:"Name of the field being answered - must be the current field"`
+	Value string `json:"value" description:"The user's answer, normalized (e.g. an email address without surrounding text). Empty to skip an optional field."`
+}
+
+// Toolset returns the flow's record tool and the instructions that make
+// the model use it. Attach registers it on an agent.
+func (f *Flow) Toolset() tools.Toolset {
+	return tools.Toolset{
+		Name:   "flow:" + f.name,
+		Prompt: f.prompt(),
+		Tools: []tools.Tool{{
+			Name:        f.name + "_record",
+			Description: "Record the user's answer for the current " + f.name + " field. Returns the next field to ask about.",
+			Func:        f.record,
+		}},
+	}
+}
+
+// Attach registers the flow's toolset on a.
+func (f *Flow) Attach(a *agent.Agent) error {
+	return a.RegisterToolset(f.Toolset())
+}
+
+// prompt explains the flow to the model.
+func (f *Flow) prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are guiding the user through %q. Collect these fields, one at a time, in this order:\n", f.name)
+	for i, fd := range f.fields {
+		fmt.Fprintf(&b, "%d. %s: %s", i+1, fd.Name, fd.Question)
+		if fd.Description != "" {
+			fmt.Fprintf(&b, " (%s)", fd.Description)
+		}
+		if fd.Optional {
+			b.WriteString(" [optional]")
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "As soon as the user answers, call %s_record with the field and the answer. ", f.name)
+	b.WriteString("Never make up an answer. If recording fails, explain the problem to the user and ask again. ")
+	b.WriteString("The tool's result tells you what to ask next.")
+	return b.String()
+}
+
+// record is the record tool.
+func (f *Flow) record(ctx context.Context, args recordArgs) (string, error) {
+	f.mu.Lock()
+	if f.next >= len(f.fields) {
+		f.mu.Unlock()
+		return "All fields have already been collected.", nil
+	}
+	fd := f.fields[f.next]
+	if args.Field != fd.Name {
+		f.mu.Unlock()
+		return "", fmt.Errorf("the current field is %q (%s), not %q", fd.Name, fd.Question, args.Field)
+	}
+
+	value := strings.TrimSpace(args.Value)
+	switch {
+	case value == "" && !fd.Optional:
+		f.mu.Unlock()
+		return "", fmt.Errorf("%q is required", fd.Name)
+	case value != "" && fd.Validate != nil:
+		if err := fd.Validate(value); err != nil {
+			f.mu.Unlock()
+			return "", err
+		}
+	}
+
+	f.values[fd.Name] = value
+	f.next++
+	if f.next < len(f.fields) {
+		nf := f.fields[f.next]
+		f.mu.Unlock()
+		return fmt.Sprintf("Recorded %s. Next field: %s - %s", fd.Name, nf.Name, nf.Question), nil
+	}
+
+	values := f.snapshot()
+	f.mu.Unlock()
+
+	// Outside the lock: the callback may call back into the flow.
+	if f.onComplete != nil {
+		if err := f.onComplete(ctx, values); err != nil {
+			return "", fmt.Errorf("all fields were collected, but submitting them failed: %w", err)
+		}
+	}
+	return fmt.Sprintf("Recorded %s. All fields are collected and submitted - let the user know they're done.", fd.Name), nil
+}
+
+// snapshot copies the values. The caller holds f.mu.
+func (f *Flow) snapshot() Values {
+	out := make(Values, len(f.values))
+	for k, v := range f.values {
+		out[k] = v
+	}
+	return out
+}
+
+// Values returns the answers collected so far.
+func (f *Flow) Values() Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.snapshot()
+}
+
+// Current returns the field being asked for, and false once the flow is
+// complete.
+func (f *Flow) Current() (Field, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.next >= len(f.fields) {
+		return Field{}, false
+	}
+	return f.fields[f.next], true
+}
+
+// Done reports whether every field has been collected.
+func (f *Flow) Done() bool {
+	_, ok := f.Current()
+	return !ok
+}
+
+// Reset clears the answers and starts again from the first field.
+func (f *Flow) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values = Values{}
+	f.next = 0
+}
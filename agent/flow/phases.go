@@ -0,0 +1,243 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/tools"
+	"strings"
+	"sync"
+)
+
+// Phase is one step of a process, like "identify the user" in a support
+// conversation. While it is the current phase, only its Tools can be
+// used and its Instructions are part of the system prompt.
+type Phase struct {
+	Name         string
+	Instructions string   // what the model should do in this phase
+	Tools        []string // tools usable in this phase
+	Transitions  []Transition
+}
+
+// Transition moves the process to another phase once its condition holds.
+type Transition struct {
+	To   string
+	When Condition
+}
+
+// Condition decides whether a transition happens. It is checked after
+// every successful tool call made during the phase, with that call and
+// its result.
+type Condition func(call tools.Call, res tools.Result) bool
+
+// AfterTool is the Condition "the tool called name succeeded".
+func AfterTool(name string) Condition {
+	return func(call tools.Call, _ tools.Result) bool {
+		return call.Name == name
+	}
+}
+
+// If is a Condition that ignores the call and asks fn - use it with state
+// the tools keep, like a Flow collecting the phase's fields:
+//
+//	{To: "diagnose", When: flow.If(intake.Done)}
+func If(fn func() bool) Condition {
+	return func(tools.Call, tools.Result) bool {
+		return fn()
+	}
+}
+
+// Phases is a state machine over tool access. It guarantees the model
+// works through a process in order: it can't call a tool before the phase
+// that allows it, because until then the agent doesn't offer the tool and
+// refuses to run it. The phases move forward only when their transition
+// conditions - your code, not the model's judgement - say so.
+//
+//	support := flow.NewPhases("support", []flow.Phase{
+//	    {
+//	        Name:         "identify",
+//	        Instructions: "Find the customer's account before anything else.",
+//	        Tools:        []string{"lookup_account"},
+//	        Transitions:  []flow.Transition{{To: "diagnose", When: flow.AfterTool("lookup_account")}},
+//	    },
+//	    {
+//	        Name:         "diagnose",
+//	        Instructions: "Work out what's wrong. Check the service status and recent orders.",
+//	        Tools:        []string{"service_status", "recent_orders"},
+//	        Transitions:  []flow.Transition{{To: "resolve", When: flow.AfterTool("recent_orders")}},
+//	    },
+//	    {
+//	        Name:         "resolve",
+//	        Instructions: "Fix the problem: issue a refund or open a ticket.",
+//	        Tools:        []string{"issue_refund", "open_ticket"},
+//	    },
+//	})
+//	a := agent.New(provider, support.Option())
+//
+// Tools that aren't in any phase are left alone - a clock or a knowledge
+// base search can stay available throughout.
+//
+// Like a Flow, a Phases holds one conversation's state. It is safe for
+// concurrent use.
+type Phases struct {
+	name     string
+	phases   []Phase
+	index    map[string]int  // phase name -> position
+	governed map[string]bool // tools that appear in some phase
+	onChange func(ctx context.Context, from, to string)
+
+	mu      sync.Mutex
+	current int
+}
+
+// PhasesOption configures a Phases.
+type PhasesOption func(*Phases)
+
+// OnPhaseChange sets a function called after every transition, for
+// logging or to kick off work the new phase needs.
+func OnPhaseChange(fn func(ctx context.Context, from, to string)) PhasesOption {
+	return func(p *Phases) {
+		p.onChange = fn
+	}
+}
+
+// NewPhases creates a state machine starting in the first phase. It
+// panics if there are no phases, names repeat, or a transition points to a
+// phase that doesn't exist.
+func NewPhases(name string, phases []Phase, opts ...PhasesOption) *Phases {
+	if len(phases) == 0 {
+		panic("flow: NewPhases needs at least one phase")
+	}
+	p := &Phases{
+		name:     name,
+		phases:   phases,
+		index:    make(map[string]int, len(phases)),
+		governed: map[string]bool{},
+	}
+	for i, ph := range phases {
+		if _, dup := p.index[ph.Name]; dup {
+			panic("flow: duplicate phase " + ph.Name)
+		}
+		p.index[ph.Name] = i
+		for _, t := range ph.Tools {
+			p.governed[t] = true
+		}
+	}
+	for _, ph := range phases {
+		for _, tr := range ph.Transitions {
+			if _, ok := p.index[tr.To]; !ok {
+				panic(fmt.Sprintf("flow: phase %s has a transition to unknown phase %s", ph.Name, tr.To))
+			}
+		}
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Option returns the agent option that puts the machine in charge: it
+// filters the tools by phase, watches tool results for transitions, and
+// keeps the system prompt told which phase is current.
+func (p *Phases) Option() agent.Option {
+	return func(a *agent.Agent) {
+		agent.WithToolFilter(p.Allowed)(a)
+		agent.WithToolMiddleware(p.middleware)(a)
+		// A toolset without tools can't fail to register.
+		_ = a.RegisterToolset(tools.Toolset{Name: "phases:" + p.name, PromptFunc: p.prompt})
+	}
+}
+
+// Allowed reports whether the tool called name may be used in the
+// current phase. It is the machine's agent.ToolFilter.
+func (p *Phases) Allowed(name string) bool {
+	if !p.governed[name] {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range p.phases[p.current].Tools {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// middleware runs the call, then checks the current phase's transitions.
+func (p *Phases) middleware(next tools.Handler) tools.Handler {
+	return func(ctx context.Context, call tools.Call) (tools.Result, error) {
+		res, err := next(ctx, call)
+		if err != nil {
+			return res, err
+		}
+
+		p.mu.Lock()
+		from, to := p.phases[p.current].Name, ""
+		for _, tr := range p.phases[p.current].Transitions {
+			if tr.When(call, res) {
+				p.current = p.index[tr.To]
+				to = tr.To
+				break
+			}
+		}
+		p.mu.Unlock()
+
+		if to != "" && p.onChange != nil {
+			p.onChange(ctx, from, to)
+		}
+		return res, err
+	}
+}
+
+// prompt tells the model where it is in the process.
+func (p *Phases) prompt() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, len(p.phases))
+	for i, ph := range p.phases {
+		names[i] = ph.Name
+	}
+	ph := p.phases[p.current]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "This conversation follows the %q process: %s.\n", p.name, strings.Join(names, " -> "))
+	fmt.Fprintf(&b, "Current phase: %s.", ph.Name)
+	if ph.Instructions != "" {
+		b.WriteString(" " + ph.Instructions)
+	}
+	b.WriteString("\nOnly this phase's tools are available.")
+	if len(ph.Transitions) > 0 {
+		b.WriteString(" The next phase starts automatically once this one's work is done - don't try to skip ahead.")
+	}
+	return b.String()
+}
+
+// Current returns the name of the current phase.
+func (p *Phases) Current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.phases[p.current].Name
+}
+
+// Goto moves to the named phase regardless of transitions - for a human
+// operator stepping in, or restoring a saved conversation. OnPhaseChange
+// is not called.
+func (p *Phases) Goto(name string) error {
+	i, ok := p.index[name]
+	if !ok {
+		return fmt.Errorf("flow: unknown phase %s", name)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = i
+	return nil
+}
+
+// Reset goes back to the first phase.
+func (p *Phases) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = 0
+}
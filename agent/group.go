@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"go-agent-sdk/llm"
+	"sync"
+)
+
+// Group runs several agent calls concurrently with errgroup semantics:
+// they share one context, the first failure cancels the rest, and Wait
+// collects every result plus the combined token usage.
+//
+// Each call must use its own Agent - an Agent keeps conversation history
+// and is not safe for concurrent use. Giving the same agent to two calls
+// in one group is a data race.
+//
+// Example - ask three specialists in parallel:
+//
+//	g, ctx := agent.NewGroup(ctx)
+//	g.Go(researcher, "Find the facts about X")
+//	g.Go(critic, "List the weak points of X")
+//	g.Go(writer, "Draft an intro about X")
+//	results, err := g.Wait()
+//	fmt.Println("tokens:", g.Usage().TotalTokens)
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	wg  sync.WaitGroup
+	sem chan struct{} // nil means unlimited concurrency
+
+	mu      sync.Mutex
+	results []GroupResult
+	err     error // first error, returned by Wait
+}
+
+// GroupResult is the outcome of one call in a Group.
+// Results keep the order the calls were started in.
+type GroupResult struct {
+	Agent *Agent
+	Input string
+	Reply string
+	Usage llm.Usage // tokens this call used
+	Err   error
+}
+
+// NewGroup creates a Group and a derived context. The context is cancelled
+// the first time a call fails, or when Wait returns - whichever comes first.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// SetLimit caps how many calls run at once. Go blocks until a slot is free.
+// Must be called before the first Go. n <= 0 means no limit.
+func (g *Group) SetLimit(n int) {
+	if n <= 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go starts a.Run(ctx, msg, opts...) in its own goroutine using the group's context.
+func (g *Group) Go(a *Agent, msg string, opts ...RunOption) {
+	g.mu.Lock()
+	idx := len(g.results)
+	g.results = append(g.results, GroupResult{Agent: a, Input: msg})
+	g.mu.Unlock()
+
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+
+		res, err := a.RunWithResult(g.ctx, msg, opts...)
+
+		g.mu.Lock()
+		g.results[idx].Reply = res.Content
+		g.results[idx].Usage = res.Usage
+		g.results[idx].Err = err
+		if err != nil {
+			g.results[idx].Reply = ""
+		}
+		if err != nil && g.err == nil {
+			g.err = err
+			g.cancel(err)
+		}
+		g.mu.Unlock()
+	}()
+}
+
+// Wait blocks until every call has finished and returns all results
+// along with the first error (if any).
+func (g *Group) Wait() ([]GroupResult, error) {
+	g.wg.Wait()
+	g.cancel(nil)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	results := make([]GroupResult, len(g.results))
+	copy(results, g.results)
+	return results, g.err
+}
+
+// Usage returns the total tokens used by every call in the group so far.
+func (g *Group) Usage() llm.Usage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var total llm.Usage
+	for _, r := range g.results {
+		total = total.Add(r.Usage)
+	}
+	return total
+}
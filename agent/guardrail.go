@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Guardrail checks a piece of text - the user's message or the model's
+// final answer - against a policy: a profanity filter, a topic allowlist,
+// a PII check, a moderation model. What it returns decides what happens
+// to the text; an error fails the Run as it is.
+type Guardrail func(ctx context.Context, text string) (GuardrailResult, error)
+
+// GuardrailResult is a guardrail's decision. The zero value lets the text
+// through unchanged.
+type GuardrailResult struct {
+	// Reject stops the Run with a *GuardrailViolation giving Reason.
+	Reject bool
+
+	// Rewrite, when not empty, replaces the text - a masked swear word, a
+	// redacted account number, a canned refusal.
+	Rewrite string
+
+	// Reason says why the text was rejected or rewritten, or is a note on
+	// text let through ("mentions a competitor"). Any Reason is recorded
+	// in RunResult.Guardrails.
+	Reason string
+}
+
+// GuardrailStage says which side of the conversation a guardrail checked.
+type GuardrailStage string
+
+const (
+	GuardrailInput  GuardrailStage = "input"  // the user's message
+	GuardrailOutput GuardrailStage = "output" // the model's final answer
+)
+
+// GuardrailNote records a guardrail that rejected, rewrote or annotated
+// text during a Run.
+type GuardrailNote struct {
+	Stage     GuardrailStage `json:"stage"`
+	Reason    string         `json:"reason,omitempty"`
+	Rejected  bool           `json:"rejected,omitempty"`
+	Rewritten bool           `json:"rewritten,omitempty"`
+}
+
+// GuardrailViolation is returned by Run when a guardrail rejects the
+// user's message or the model's answer.
+//
+//	var gv *agent.GuardrailViolation
+//	if errors.As(err, &gv) && gv.Stage == agent.GuardrailInput {
+//	    reply = "Sorry, I can't help with that."
+//	}
+type GuardrailViolation struct {
+	Stage  GuardrailStage
+	Reason string
+	Text   string // the text it rejected, after any earlier rewrites
+}
+
+func (e *GuardrailViolation) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("agent: %s rejected by guardrail", e.Stage)
+	}
+	return fmt.Sprintf("agent: %s rejected by guardrail: %s", e.Stage, e.Reason)
+}
+
+// WithInputGuardrail checks every user message with each guardrail, in
+// order, before it joins the history and before any message middleware.
+// A rejected message never reaches the model or the history:
+//
+//	a := agent.New(provider, agent.WithInputGuardrail(func(ctx context.Context, text string) (agent.GuardrailResult, error) {
+//	    if strings.Contains(strings.ToLower(text), "ignore previous instructions") {
+//	        return agent.GuardrailResult{Reject: true, Reason: "prompt injection"}, nil
+//	    }
+//	    return agent.GuardrailResult{}, nil
+//	}))
+//
+// Each guardrail sees the text as the one before it left it.
+func WithInputGuardrail(g ...Guardrail) Option {
+	return func(a *Agent) {
+		a.inputGuardrails = append(a.inputGuardrails, g...)
+	}
+}
+
+// WithOutputGuardrail checks every final answer with each guardrail, in
+// order, after WithValidator and WithAnswerLimit have had their say. A
+// rejected answer is left out of the history; a rewritten one is stored
+// and returned as rewritten. Unlike a validator, a guardrail doesn't ask
+// the model to try again.
+func WithOutputGuardrail(g ...Guardrail) Option {
+	return func(a *Agent) {
+		a.outputGuardrails = append(a.outputGuardrails, g...)
+	}
+}
+
+// checkGuardrails runs guardrails over text, returning it as rewritten,
+// and records what they did on res.
+func checkGuardrails(ctx context.Context, res *RunResult, stage GuardrailStage, guardrails []Guardrail, text string) (string, error) {
+	for _, g := range guardrails {
+		r, err := g(ctx, text)
+		if err != nil {
+			// WithModeration's errors say what happened already.
+			if me := (*ModerationError)(nil); errors.As(err, &me) {
+				res.Guardrails = append(res.Guardrails, GuardrailNote{Stage: stage, Reason: "flagged by moderation: " + strings.Join(me.Categories, ", "), Rejected: true})
+				return text, err
+			}
+			return text, fmt.Errorf("agent: %s guardrail: %w", stage, err)
+		}
+		rewritten := !r.Reject && r.Rewrite != "" && r.Rewrite != text
+		if r.Reject || rewritten || r.Reason != "" {
+			res.Guardrails = append(res.Guardrails, GuardrailNote{Stage: stage, Reason: r.Reason, Rejected: r.Reject, Rewritten: rewritten})
+		}
+		if r.Reject {
+			return text, &GuardrailViolation{Stage: stage, Reason: r.Reason, Text: text}
+		}
+		if rewritten {
+			text = r.Rewrite
+		}
+	}
+	return text, nil
+}
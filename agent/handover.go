@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+	"strings"
+)
+
+// History issues found by CheckHistory.
+const (
+	IssueUnknownRole   = "unknown_role"   // a role no provider accepts
+	IssueUnknownTool   = "unknown_tool"   // a call to a tool this agent doesn't have
+	IssueOrphanResult  = "orphan_result"  // a tool result with no call before it
+	IssueMissingResult = "missing_result" // a tool call that was never answered
+)
+
+// HistoryIssue is something in a conversation this agent can't carry on
+// from as it is.
+type HistoryIssue struct {
+	Index  int    // the message it's in
+	Kind   string // one of the Issue constants
+	Detail string
+}
+
+func (i HistoryIssue) String() string {
+	return fmt.Sprintf("message %d: %s: %s", i.Index, i.Kind, i.Detail)
+}
+
+// HandoverError is returned by Handover when the history has issues it
+// was told not to repair.
+type HandoverError struct {
+	Issues []HistoryIssue
+}
+
+func (e *HandoverError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		parts[i] = is.String()
+	}
+	return "agent: history isn't compatible: " + strings.Join(parts, "; ")
+}
+
+// CheckHistory reports what in history this agent can't carry on from:
+// calls to tools it doesn't have (a newer version removed or renamed
+// them), tool calls and results that don't pair up, unknown roles. nil
+// means the history is fine as it is.
+func (a *Agent) CheckHistory(history []llm.Message) []HistoryIssue {
+	var issues []HistoryIssue
+	pending := map[string]bool{} // calls waiting for a result
+	for i, m := range history {
+		switch m.Role {
+		case "system", "user":
+		case "assistant":
+			for _, c := range m.ToolCalls {
+				if _, ok := a.tools.Schema(c.Function.Name); !ok {
+					issues = append(issues, HistoryIssue{i, IssueUnknownTool, c.Function.Name})
+				}
+				pending[c.ID] = true
+			}
+		case "tool":
+			if !pending[m.ToolCallID] {
+				issues = append(issues, HistoryIssue{i, IssueOrphanResult, "no call with ID " + m.ToolCallID})
+			}
+			delete(pending, m.ToolCallID)
+		default:
+			issues = append(issues, HistoryIssue{i, IssueUnknownRole, m.Role})
+		}
+		// Results belong right after their calls: anything still pending
+		// when the next non-tool message comes was never answered.
+		if next := i + 1; len(pending) > 0 && (next == len(history) || history[next].Role != "tool") {
+			for _, c := range history[lastCall(history, i)].ToolCalls {
+				if pending[c.ID] {
+					issues = append(issues, HistoryIssue{i, IssueMissingResult, c.Function.Name + " " + c.ID})
+				}
+			}
+			clear(pending)
+		}
+	}
+	return issues
+}
+
+// lastCall is the index of the last assistant message with tool calls at
+// or before i.
+func lastCall(history []llm.Message, i int) int {
+	for ; i > 0; i-- {
+		if len(history[i].ToolCalls) > 0 {
+			break
+		}
+	}
+	return i
+}
+
+// HandoverOption configures Handover.
+type HandoverOption func(*handover)
+
+type handover struct {
+	strict     bool
+	keepPrompt bool
+	migrate    Migration
+}
+
+// StrictHandover makes Handover fail with a *HandoverError instead of
+// repairing the history.
+func StrictHandover() HandoverOption {
+	return func(h *handover) {
+		h.strict = true
+	}
+}
+
+// KeepSystemPrompt keeps the conversation's own system prompt instead of
+// putting this agent's in its place.
+func KeepSystemPrompt() HandoverOption {
+	return func(h *handover) {
+		h.keepPrompt = true
+	}
+}
+
+// MigrateHistory runs migrate on the history after it's been checked and
+// repaired - for changes Handover can't know about, like a tool whose
+// arguments changed shape.
+func MigrateHistory(migrate Migration) HandoverOption {
+	return func(h *handover) {
+		h.migrate = migrate
+	}
+}
+
+// Handover takes over a conversation started by another version of the
+// agent - a redeploy with a new prompt or new tools - so users carry on
+// where they were instead of starting again. The history becomes a's,
+// after:
+//
+//   - its system prompt is replaced with a's SystemPrompt, unless
+//     KeepSystemPrompt;
+//   - CheckHistory's issues are repaired: exchanges with tools a no longer
+//     has are kept as text, results without calls are dropped, and calls
+//     without results are answered with an error. StrictHandover returns
+//     a *HandoverError instead; unknown roles always do;
+//   - the MigrateHistory function, if any, has run;
+//   - tool call IDs and reasoning signatures are adapted to a's provider,
+//     as SetProvider does.
+//
+// With WithHandover the agent does this to every session it loads.
+func (a *Agent) Handover(ctx context.Context, history []llm.Message, opts ...HandoverOption) error {
+	h := &handover{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	out, err := a.handover(ctx, history, h)
+	if err != nil {
+		return err
+	}
+	a.History = out
+	return nil
+}
+
+func (a *Agent) handover(ctx context.Context, history []llm.Message, h *handover) ([]llm.Message, error) {
+	if issues := a.CheckHistory(history); len(issues) > 0 {
+		fatal := h.strict
+		for _, is := range issues {
+			fatal = fatal || is.Kind == IssueUnknownRole
+		}
+		if fatal {
+			return nil, &HandoverError{Issues: issues}
+		}
+		history = a.repairHistory(history)
+	}
+
+	if !h.keepPrompt {
+		rest := history[leadingSystem(history):]
+		history = make([]llm.Message, 0, len(rest)+1)
+		if a.SystemPrompt != "" {
+			history = append(history, llm.NewSystemMessage(a.SystemPrompt))
+		}
+		history = append(history, rest...)
+	}
+
+	if h.migrate != nil {
+		var err error
+		if history, err = h.migrate(ctx, history); err != nil {
+			return nil, fmt.Errorf("agent: migrating history: %w", err)
+		}
+	}
+
+	for i := range history {
+		history[i].ReasoningSignature = ""
+	}
+	if n, ok := a.provider.(llm.ToolCallIDNormalizer); ok {
+		history = llm.NormalizeToolCallIDs(history, n.NormalizeToolCallID)
+	}
+	return history, nil
+}
+
+// repairHistory returns a copy of history with CheckHistory's issues,
+// other than unknown roles, fixed.
+func (a *Agent) repairHistory(history []llm.Message) []llm.Message {
+	out := make([]llm.Message, 0, len(history))
+	for i := 0; i < len(history); i++ {
+		m := history[i]
+		if m.Role == "tool" { // a result that isn't right after its call
+			continue
+		}
+		if len(m.ToolCalls) == 0 {
+			out = append(out, m)
+			continue
+		}
+
+		// The call and the results right after it.
+		results := map[string]llm.Message{}
+		for i+1 < len(history) && history[i+1].Role == "tool" {
+			i++
+			results[history[i].ToolCallID] = history[i]
+		}
+		known := true
+		for _, c := range m.ToolCalls {
+			if _, ok := a.tools.Schema(c.Function.Name); !ok {
+				known = false
+			}
+		}
+
+		if !known {
+			// Keep what happened, as text: the model can't be shown a call
+			// to a tool it doesn't have.
+			var b strings.Builder
+			if m.Content != "" {
+				b.WriteString(m.Content + "\n\n")
+			}
+			for _, c := range m.ToolCalls {
+				fmt.Fprintf(&b, "[Called %s(%s)", c.Function.Name, c.Function.Arguments)
+				if r, ok := results[c.ID]; ok {
+					fmt.Fprintf(&b, ": %s", r.Content)
+				}
+				b.WriteString("]\n")
+			}
+			text := m
+			text.ToolCalls = nil
+			text.Content = strings.TrimSpace(b.String())
+			out = append(out, text)
+			continue
+		}
+
+		out = append(out, m)
+		for _, c := range m.ToolCalls {
+			r, ok := results[c.ID]
+			if !ok {
+				r = llm.NewToolResult(c.ID, c.Function.Name, "Error: no result was recorded for this call.")
+			}
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// WithHandover hands over every session the agent loads with WithSession
+// (see Handover), so conversations saved by the previous version of the
+// agent carry on under this one.
+//
+//	a := agent.New(provider, agent.WithSystemPrompts(promptV2),
+//	    agent.WithSession(store, id),
+//	    agent.WithHandover(),
+//	)
+func WithHandover(opts ...HandoverOption) Option {
+	return func(a *Agent) {
+		h := &handover{}
+		for _, opt := range opts {
+			opt(h)
+		}
+		a.handoverOpts = h
+	}
+}
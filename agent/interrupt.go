@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Interrupt pauses a Run. The Run stops before its next LLM call and
+// returns an *InterruptError carrying the interrupt - for a human to
+// approve something, take over the conversation, or just look.
+//
+// Nothing is lost: the history keeps everything up to the pause,
+// including results of tools that already ran, so a later
+// Run(ctx, "") carries on from there, or Run(ctx, msg) with the
+// human's input.
+type Interrupt struct {
+	Reason  string // why, for logs and for the human
+	Payload any    // anything the handler needs, e.g. an *Escalation
+}
+
+// InterruptError is returned by Run when it was paused by an Interrupt.
+//
+//	res, err := a.RunWithResult(ctx, msg)
+//	var ie *agent.InterruptError
+//	if errors.As(err, &ie) {
+//	    handOver(ie.RunID, ie.Reason, ie.Payload)
+//	}
+type InterruptError struct {
+	Interrupt
+	RunID string
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("agent: run %s interrupted: %s", e.RunID, e.Reason)
+}
+
+// RequestInterrupt asks the Run that ctx belongs to to pause. Tools, tool
+// middleware and message middleware call it; the Run finishes the tool
+// calls in flight and returns before asking the LLM again. The first
+// request wins. It reports false when ctx isn't a Run's.
+//
+//	func Refund(ctx context.Context, args RefundArgs) (string, error) {
+//	    if args.Amount > 500 {
+//	        agent.RequestInterrupt(ctx, agent.Interrupt{Reason: "refund needs approval", Payload: args})
+//	        return "A supervisor has to approve this refund.", nil
+//	    }
+//	    ...
+//	}
+func RequestInterrupt(ctx context.Context, in Interrupt) bool {
+	s, ok := ctx.Value(interruptKey{}).(*interruptState)
+	if !ok {
+		return false
+	}
+	s.request(in)
+	return true
+}
+
+// Interrupt pauses the agent from outside - another goroutine, an admin
+// endpoint. The current Run, or the next one if none is running, stops
+// before its next LLM call.
+func (a *Agent) Interrupt(in Interrupt) {
+	a.interrupts.request(in)
+}
+
+type interruptKey struct{}
+
+// interruptState holds a requested interrupt until the Run takes it.
+type interruptState struct {
+	mu      sync.Mutex
+	pending *Interrupt
+}
+
+func (s *interruptState) request(in Interrupt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		s.pending = &in
+	}
+}
+
+func (s *interruptState) take() *Interrupt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in := s.pending
+	s.pending = nil
+	return in
+}
+
+// withInterrupts gives the Run its own interrupt state on ctx.
+func withInterrupts(ctx context.Context) (context.Context, *interruptState) {
+	s := &interruptState{}
+	return context.WithValue(ctx, interruptKey{}, s), s
+}
+
+// takeInterrupt returns the pending interrupt for the run as an error, nil
+// if there is none.
+func (a *Agent) takeInterrupt(run *interruptState, runID string) error {
+	in := run.take()
+	if in == nil {
+		in = a.interrupts.take()
+	}
+	if in == nil {
+		return nil
+	}
+	return &InterruptError{Interrupt: *in, RunID: runID}
+}
@@ -0,0 +1,211 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent-sdk/llm"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Scorer rates one candidate response to req, higher being better. A
+// judge-backed scorer returns a reason with the score; a plain function
+// can leave it empty.
+type Scorer func(ctx context.Context, req llm.ChatRequest, resp *llm.ChatResponse) (score float64, reason string, err error)
+
+// Grader scores candidates with j.Grade, asking how well each answers the
+// request's last user message.
+func Grader(j *Judge) Scorer {
+	return func(ctx context.Context, req llm.ChatRequest, resp *llm.ChatResponse) (float64, string, error) {
+		v, err := j.Grade(ctx, question(req), answer(resp))
+		return v.Score, v.Reason, err
+	}
+}
+
+// question is what the request asks: its last user message, after the
+// system prompt when there is one.
+func question(req llm.ChatRequest) string {
+	var system, user string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			system = m.Content
+		case "user":
+			user = m.Content
+		}
+	}
+	if system == "" {
+		return user
+	}
+	return "(Instructions: " + system + ")\n\n" + user
+}
+
+// answer renders a response for grading, tool calls included.
+func answer(resp *llm.ChatResponse) string {
+	if len(resp.Choices) == 0 {
+		return ""
+	}
+	m := resp.Choices[0].Message
+	var b strings.Builder
+	b.WriteString(m.Content)
+	for _, tc := range m.ToolCalls {
+		fmt.Fprintf(&b, "\n[calls tool %s with %s]", tc.Function.Name, tc.Function.Arguments)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Candidate is one of the completions BestOf sampled.
+type Candidate struct {
+	Model    string
+	Response *llm.ChatResponse // nil when the call failed
+	Score    float64
+	Reason   string
+	Err      error // the call or the scoring failed
+}
+
+// Sample is everything one BestOf call produced: the winner and every
+// candidate, best first, failures last.
+type Sample struct {
+	Best       *Candidate
+	Candidates []Candidate
+	Usage      llm.Usage // tokens of all the candidates, not the scoring
+}
+
+// BestOf asks for several completions of the same request in parallel,
+// scores each, and keeps the best - for the generations where quality is
+// worth paying N times for. It implements llm.ChatProvider, so an agent
+// can use it like any provider:
+//
+//	best := judge.NewBestOf([]llm.ChatProvider{gpt, claude}, judge.Grader(judge.New(strong)),
+//	    judge.WithSamples(4))
+//	a := agent.New(best)
+//
+// Samples are spread over the providers in turn. A call or scoring that
+// fails drops that candidate; the request fails only when none is left.
+type BestOf struct {
+	providers   []llm.ChatProvider
+	score       Scorer
+	n           int
+	temperature float64
+	onSample    func(Sample)
+}
+
+// BestOfOption configures a BestOf.
+type BestOfOption func(*BestOf)
+
+// WithSamples sets how many completions to sample per request. The
+// default is 3, or one per provider when there are more.
+func WithSamples(n int) BestOfOption {
+	return func(b *BestOf) {
+		b.n = n
+	}
+}
+
+// WithSampleTemperature sets the temperature for requests that don't set
+// one, so samples from the same model differ. The default is 0.8.
+func WithSampleTemperature(t float64) BestOfOption {
+	return func(b *BestOf) {
+		b.temperature = t
+	}
+}
+
+// OnSample calls fn after every request with all the candidates and
+// their scores, for logging which model wins how often.
+func OnSample(fn func(Sample)) BestOfOption {
+	return func(b *BestOf) {
+		b.onSample = fn
+	}
+}
+
+// NewBestOf returns a BestOf sampling from providers and ranking with
+// score. It panics without providers or a scorer.
+func NewBestOf(providers []llm.ChatProvider, score Scorer, opts ...BestOfOption) *BestOf {
+	if len(providers) == 0 || score == nil {
+		panic("judge: NewBestOf needs providers and a scorer")
+	}
+	b := &BestOf{providers: providers, score: score, n: max(3, len(providers)), temperature: 0.8}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.n = max(b.n, 1)
+	return b
+}
+
+// ModelName returns the first provider's model.
+func (b *BestOf) ModelName() string {
+	return b.providers[0].ModelName()
+}
+
+// CreateChat samples and returns the best response. Its Usage is the sum
+// over all the candidates, so cost tracking sees what was spent.
+func (b *BestOf) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	s, err := b.Sample(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := *s.Best.Response
+	resp.Usage = s.Usage
+	return &resp, nil
+}
+
+// Sample runs the N completions and scores them.
+func (b *BestOf) Sample(ctx context.Context, req llm.ChatRequest) (Sample, error) {
+	if req.Temperature == 0 {
+		req.Temperature = b.temperature
+	}
+	cands := make([]Candidate, b.n)
+	var wg sync.WaitGroup
+	for i := range cands {
+		p := b.providers[i%len(b.providers)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := &cands[i]
+			c.Model = p.ModelName()
+			r := req
+			r.Model = p.ModelName()
+			resp, err := p.CreateChat(ctx, r)
+			if err == nil && len(resp.Choices) == 0 {
+				err = errors.New("no choices")
+			}
+			if err != nil {
+				c.Err = fmt.Errorf("%s: %w", c.Model, err)
+				return
+			}
+			c.Response = resp
+			if c.Score, c.Reason, err = b.score(ctx, req, resp); err != nil {
+				c.Err = fmt.Errorf("%s: scoring: %w", c.Model, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var s Sample
+	var errs []error
+	for _, c := range cands {
+		if c.Response != nil {
+			s.Usage = s.Usage.Add(c.Response.Usage)
+		}
+		if c.Err != nil {
+			errs = append(errs, c.Err)
+		}
+	}
+	// Stable, so ties go to the earlier sample.
+	sort.SliceStable(cands, func(i, j int) bool {
+		if (cands[i].Err == nil) != (cands[j].Err == nil) {
+			return cands[i].Err == nil
+		}
+		return cands[i].Score > cands[j].Score
+	})
+	s.Candidates = cands
+	if cands[0].Err != nil {
+		return s, fmt.Errorf("judge: best of %d: every candidate failed: %w", b.n, errors.Join(errs...))
+	}
+	s.Best = &s.Candidates[0]
+	if b.onSample != nil {
+		b.onSample(s)
+	}
+	return s, nil
+}
@@ -0,0 +1,134 @@
+// Package judge scores model output with another model - or, when a model
+// would be overkill, with a plain text similarity.
+//
+//	j := judge.New(strongModel)
+//	v, err := j.Compare(ctx, question, answerA, answerB)
+//	if v.Score < 0.5 {
+//	    log.Printf("answers disagree: %s", v.Reason)
+//	}
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/llm"
+	"strings"
+	"unicode"
+)
+
+// Verdict is a judge's score with its reasoning.
+type Verdict struct {
+	Score  float64 `json:"score"`  // 0 to 1
+	Reason string  `json:"reason"` // one or two sentences
+}
+
+// Judge asks a model to grade.
+type Judge struct {
+	provider llm.ChatProvider
+	criteria string
+}
+
+// Option configures a Judge.
+type Option func(*Judge)
+
+// WithCriteria tells the judge what matters, e.g. "Factual agreement
+// matters; tone and length don't."
+func WithCriteria(criteria string) Option {
+	return func(j *Judge) {
+		j.criteria = criteria
+	}
+}
+
+// New returns a judge backed by provider. Use a model at least as strong
+// as the ones being judged.
+func New(provider llm.ChatProvider, opts ...Option) *Judge {
+	j := &Judge{provider: provider}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+var verdictSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"score":  map[string]any{"type": "number", "minimum": 0, "maximum": 1},
+		"reason": map[string]any{"type": "string"},
+	},
+	"required":             []string{"score", "reason"},
+	"additionalProperties": false,
+}
+
+// Compare grades how well two answers to the same question agree: 1 means
+// they say the same thing, 0 that they contradict each other or one misses
+// the point.
+func (j *Judge) Compare(ctx context.Context, question, a, b string) (Verdict, error) {
+	prompt := "You compare two answers to the same request. Score from 0 to 1 how well they agree in substance: " +
+		"1 if a reader would come away with the same information and outcome, 0 if they contradict each other " +
+		"or one fails the request. Reply with JSON: score and a one-sentence reason."
+	return j.ask(ctx, prompt, fmt.Sprintf("Request:\n%s\n\nAnswer A:\n%s\n\nAnswer B:\n%s", question, a, b))
+}
+
+// Grade scores a single answer: 1 for an excellent answer to question, 0
+// for a useless or wrong one.
+func (j *Judge) Grade(ctx context.Context, question, answer string) (Verdict, error) {
+	prompt := "You grade an AI assistant's answer. Score from 0 to 1: 1 for a correct, complete and helpful answer, " +
+		"0 for a wrong or useless one. Reply with JSON: score and a one-sentence reason."
+	return j.ask(ctx, prompt, fmt.Sprintf("Request:\n%s\n\nAnswer:\n%s", question, answer))
+}
+
+func (j *Judge) ask(ctx context.Context, prompt, input string) (Verdict, error) {
+	if j.criteria != "" {
+		prompt += "\n\n" + j.criteria
+	}
+	req := llm.ChatRequest{
+		Model:          j.provider.ModelName(),
+		Messages:       []llm.Message{llm.NewSystemMessage(prompt), llm.NewUserMessage(input)},
+		ResponseFormat: llm.NewJSONSchemaFormat("verdict", verdictSchema),
+	}
+	resp, err := j.provider.CreateChat(ctx, req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("judge: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return Verdict{}, fmt.Errorf("judge: no choices")
+	}
+
+	content := strings.TrimSpace(resp.Choices[0].Message.Content)
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "```json"), "```")
+	var v Verdict
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &v); err != nil {
+		return Verdict{}, fmt.Errorf("judge: bad verdict %q: %w", content, err)
+	}
+	v.Score = min(max(v.Score, 0), 1)
+	return v, nil
+}
+
+// Similarity is a cheap stand-in for a judge: the overlap of the two
+// texts' words (Jaccard index, ignoring case and punctuation), from 0 for
+// nothing in common to 1 for the same words. It can't tell "yes" from
+// "no, not yes" - use it to spot drift, not to grade.
+func Similarity(a, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 && len(wb) == 0 {
+		return 1
+	}
+	common := 0
+	for w := range wa {
+		if wb[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wa)+len(wb)-common)
+}
+
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		set[w] = true
+	}
+	return set
+}
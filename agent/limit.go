@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LimitStrategy says what the agent does with an answer that is over budget.
+type LimitStrategy int
+
+const (
+	// TruncateAnswer cuts the answer at the budget (on a word boundary when
+	// possible) and appends the ellipsis. Cheap and predictable.
+	TruncateAnswer LimitStrategy = iota
+
+	// ShortenAnswer asks the model once to rewrite its answer within the budget.
+	// If the rewrite is still too long it gets truncated anyway, so the
+	// limit always holds. With WithStreaming the start of the answer has
+	// already gone out, so it's truncated instead.
+	ShortenAnswer
+)
+
+// AnswerLimit caps the length of the final answer Run returns - for channels
+// like SMS or push notifications that can't take more than a few hundred
+// characters. Set MaxChars, MaxTokens, or both; the tighter one wins.
+type AnswerLimit struct {
+	MaxChars  int           // budget in characters (runes, not bytes)
+	MaxTokens int           // budget in estimated tokens (about 4 characters each)
+	Strategy  LimitStrategy // what to do when over budget, defaults to TruncateAnswer
+	Ellipsis  string        // appended when truncating, defaults to "…"
+}
+
+// WithAnswerLimit enforces a maximum answer length after the model responds.
+// The history stores the answer as returned to the caller, so the model sees
+// its shortened reply on the next turn.
+//
+// With WithStreaming, text stops going to the stream handler at the
+// budget and the ellipsis follows. Run's answer can end a few characters
+// sooner, where truncating backs up to the end of a word.
+//
+// Example - fit in one SMS:
+//
+//	a := agent.New(provider,
+//	    agent.WithAnswerLimit(agent.AnswerLimit{MaxChars: 160, Strategy: agent.ShortenAnswer}),
+//	)
+func WithAnswerLimit(limit AnswerLimit) Option {
+	return func(a *Agent) {
+		if limit.Ellipsis == "" {
+			limit.Ellipsis = "…"
+		}
+		a.answerLimit = &limit
+	}
+}
+
+// maxChars turns the configured budget into a single character count.
+// Zero means no limit.
+func (l *AnswerLimit) maxChars() int {
+	limit := l.MaxChars
+	if l.MaxTokens > 0 {
+		// Rough but provider-independent: ~4 characters per token for English.
+		byTokens := l.MaxTokens * 4
+		if limit == 0 || byTokens < limit {
+			limit = byTokens
+		}
+	}
+	return limit
+}
+
+// enforceAnswerLimit returns answer unchanged if it fits the budget, otherwise
+// a shortened version according to the strategy. req is the request that
+// produced the answer; ShortenAnswer reuses its settings for the rewrite.
+func (a *Agent) enforceAnswerLimit(ctx context.Context, req llm.ChatRequest, answer string) (string, error) {
+	if a.answerLimit == nil {
+		return answer, nil
+	}
+	limit := a.answerLimit.maxChars()
+	if limit <= 0 || utf8.RuneCountInString(answer) <= limit {
+		return answer, nil
+	}
+
+	if a.answerLimit.Strategy == ShortenAnswer && a.onDelta == nil {
+		// Show the model its own answer and ask for a shorter one. This exchange
+		// is not kept in history - only the final answer is.
+		rewrite := req
+		rewrite.Messages = append(append([]llm.Message{}, req.Messages...),
+			llm.NewAssistantMessage(answer),
+			llm.NewUserMessage(fmt.Sprintf(
+				"Your previous answer is %d characters long. Rewrite it in at most %d characters. Reply with the rewritten answer only.",
+				utf8.RuneCountInString(answer), limit)),
+		)
+		rewrite.Tools = nil
+		rewrite.ToolChoice = nil
+
+		resp, err := a.withRetry(ctx, func() (*llm.ChatResponse, error) {
+			return a.provider.CreateChat(ctx, rewrite)
+		})
+		if err != nil {
+			return "", fmt.Errorf("shortening answer: %w", &ProviderError{Model: rewrite.Model, Err: err})
+		}
+		a.Usage = a.Usage.Add(resp.Usage)
+		if len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+			answer = resp.Choices[0].Message.Content
+		}
+	}
+
+	return truncateAnswer(answer, limit, a.answerLimit.Ellipsis), nil
+}
+
+// truncateAnswer cuts s to at most limit runes including the ellipsis.
+// It backs up to the last space if that doesn't lose more than a fifth of
+// the budget, so words aren't chopped in half.
+func truncateAnswer(s string, limit int, ellipsis string) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+
+	keep := limit - utf8.RuneCountInString(ellipsis)
+	if keep <= 0 {
+		return string(runes[:limit])
+	}
+
+	cut := keep
+	for i := keep; i > keep-keep/5 && i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + ellipsis
+}
+
+// streamLimit returns what keeps one response's streamed text within the
+// answer limit, or nil - which passes everything - without one.
+func (a *Agent) streamLimit() *streamClip {
+	if a.answerLimit == nil || a.answerLimit.maxChars() <= 0 {
+		return nil
+	}
+	c := &streamClip{limit: a.answerLimit.maxChars(), ellipsis: a.answerLimit.Ellipsis}
+	// Matches truncateAnswer, which leaves the ellipsis off when it
+	// wouldn't fit.
+	c.keep = c.limit - utf8.RuneCountInString(c.ellipsis)
+	if c.keep <= 0 {
+		c.keep, c.ellipsis = c.limit, ""
+	}
+	return c
+}
+
+// streamClip passes on the first keep runes of streamed text. The runes
+// between keep and limit are held back until it's clear whether the text
+// runs over: if it does they're replaced by the ellipsis, and if not rest
+// returns them once the stream ends.
+type streamClip struct {
+	keep, limit int
+	ellipsis    string
+
+	seen int // runes of text so far
+	held strings.Builder
+	over bool
+}
+
+// text returns the part of the next piece of streamed text to pass on.
+func (c *streamClip) text(s string) string {
+	if c == nil {
+		return s
+	}
+	if c.over {
+		return ""
+	}
+	pass := 0 // bytes of s within keep
+	for i := range s {
+		_, n := utf8.DecodeRuneInString(s[i:])
+		c.seen++
+		switch {
+		case c.seen <= c.keep:
+			pass = i + n
+		case c.seen <= c.limit:
+			c.held.WriteString(s[i : i+n])
+		default:
+			c.over = true
+			c.held.Reset()
+			return s[:pass] + c.ellipsis
+		}
+	}
+	return s[:pass]
+}
+
+// rest returns the text held back, once the stream has ended within the
+// limit.
+func (c *streamClip) rest() string {
+	if c == nil || c.over {
+		return ""
+	}
+	s := c.held.String()
+	c.held.Reset()
+	return s
+}
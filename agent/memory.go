@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+	"strings"
+)
+
+// Memory decides how much of the conversation the agent keeps. Before
+// every request the agent hands it the whole history and stores what it
+// returns in place of it, so a Memory can drop old turns, fold them into a
+// summary, or leave everything alone.
+//
+// Set one with WithMemory, and change it on a live agent with SetMemory.
+// Without one the history grows forever.
+type Memory interface {
+	Compact(ctx context.Context, history []llm.Message) ([]llm.Message, error)
+}
+
+// MemoryFunc lets an ordinary function be a Memory.
+type MemoryFunc func(ctx context.Context, history []llm.Message) ([]llm.Message, error)
+
+func (f MemoryFunc) Compact(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+	return f(ctx, history)
+}
+
+// Migrator is implemented by memories that want to convert a history
+// kept under a different strategy when SetMemory swaps them in - to
+// summarize it straight away rather than at the next request, say.
+type Migrator interface {
+	Migrate(ctx context.Context, history []llm.Message) ([]llm.Message, error)
+}
+
+// Migration converts the existing history when SetMemory changes the
+// strategy.
+type Migration func(ctx context.Context, history []llm.Message) ([]llm.Message, error)
+
+// WithMemory sets the strategy that keeps the history in bounds.
+//
+//	a := agent.New(provider, agent.WithMemory(agent.SlidingWindow(40)))
+func WithMemory(m Memory) Option {
+	return func(a *Agent) {
+		a.memory = m
+	}
+}
+
+// SetMemory changes the memory strategy of an existing agent, keeping the
+// conversation - switch from a sliding window to summarization once a
+// session gets long, for example:
+//
+//	if len(a.History) > 200 {
+//	    err := a.SetMemory(ctx, agent.Summarizing(cheap, 100, 20), nil)
+//	}
+//
+// The existing history is converted right away: by migrate if it isn't
+// nil, otherwise by m's Migrate method if it has one. Either way m
+// compacts it again before the next request. On error the agent keeps its
+// old memory and history.
+func (a *Agent) SetMemory(ctx context.Context, m Memory, migrate Migration) error {
+	if migrate == nil {
+		if mg, ok := m.(Migrator); ok {
+			migrate = mg.Migrate
+		}
+	}
+	if migrate != nil {
+		history, err := migrate(ctx, a.History)
+		if err != nil {
+			return fmt.Errorf("agent: migrating history: %w", err)
+		}
+		a.History = history
+	}
+	a.memory = m
+	return nil
+}
+
+// Memory returns the agent's memory strategy, nil if it keeps everything.
+func (a *Agent) Memory() Memory {
+	return a.memory
+}
+
+// compactHistory runs the memory strategy over the history, if there is one.
+func (a *Agent) compactHistory(ctx context.Context) error {
+	if a.memory == nil {
+		return nil
+	}
+	history, err := a.memory.Compact(ctx, a.History)
+	if err != nil {
+		return fmt.Errorf("agent: memory: %w", err)
+	}
+	a.History = history
+	return nil
+}
+
+// SlidingWindow keeps the system messages at the start of the history and
+// the last n messages after them, dropping everything in between. The cut
+// never falls between a tool call and its results, and moves forward to a
+// user message when there is one, so the window always starts with a turn
+// the model can make sense of - which means it can hold a few fewer than n.
+func SlidingWindow(n int) Memory {
+	return MemoryFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		head := leadingSystem(history)
+		rest := history[head:]
+		if len(rest) <= n {
+			return history, nil
+		}
+		cut := cutPoint(rest, len(rest)-n)
+		out := make([]llm.Message, 0, head+len(rest)-cut)
+		out = append(out, history[:head]...)
+		return append(out, rest[cut:]...), nil
+	})
+}
+
+// leadingSystem returns how many system messages the history starts with.
+func leadingSystem(history []llm.Message) int {
+	n := 0
+	for n < len(history) && history[n].Role == "system" {
+		n++
+	}
+	return n
+}
+
+// cutPoint returns the first index at or after i where msgs can start: the
+// next user message if there is one, else the next message that isn't a
+// tool result (whose call would be cut off). len(msgs) if neither.
+func cutPoint(msgs []llm.Message, i int) int {
+	for j := i; j < len(msgs); j++ {
+		if msgs[j].Role == "user" {
+			return j
+		}
+	}
+	for i < len(msgs) && msgs[i].Role == "tool" {
+		i++
+	}
+	return i
+}
+
+// summaryPrefix starts the system message Summarizing keeps its summary
+// in, so the next summary can fold the previous one in.
+const summaryPrefix = "Summary of the conversation so far:\n"
+
+// Summarizing keeps the conversation short by having provider summarize
+// it. Once more than threshold messages follow the system prompt, all but
+// the last keep are replaced by a summary, held in a system message after
+// the prompt; the next time, the old summary is summarized along with the
+// messages that have aged out since.
+//
+// provider can be a cheaper model than the agent's. The summarization
+// tokens don't count towards Agent.Usage.
+//
+// Swapped in with SetMemory, it summarizes the existing history at once.
+func Summarizing(provider llm.ChatProvider, threshold, keep int) Memory {
+	return &summarizing{provider: provider, threshold: threshold, keep: keep}
+}
+
+type summarizing struct {
+	provider  llm.ChatProvider
+	threshold int
+	keep      int
+}
+
+func (s *summarizing) Compact(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+	if len(history)-leadingSystem(history) <= s.threshold {
+		return history, nil
+	}
+	return s.summarize(ctx, history)
+}
+
+func (s *summarizing) Migrate(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+	return s.summarize(ctx, history)
+}
+
+// summarize folds everything but the system prompt and the last keep
+// messages into one summary message.
+func (s *summarizing) summarize(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+	head := leadingSystem(history)
+	var prompts []llm.Message
+	var previous []string
+	for _, m := range history[:head] {
+		if text, ok := strings.CutPrefix(m.Content, summaryPrefix); ok {
+			previous = append(previous, text)
+		} else {
+			prompts = append(prompts, m)
+		}
+	}
+
+	rest := history[head:]
+	cut := cutPoint(rest, max(len(rest)-s.keep, 0))
+	if cut == 0 {
+		return history, nil
+	}
+
+	var transcript strings.Builder
+	for _, text := range previous {
+		fmt.Fprintf(&transcript, "[earlier summary]\n%s\n\n", text)
+	}
+	for _, m := range rest[:cut] {
+		writeTranscript(&transcript, m)
+	}
+
+	req := llm.ChatRequest{
+		Model: s.provider.ModelName(),
+		Messages: []llm.Message{
+			llm.NewSystemMessage("You summarize conversations between a user and an AI assistant. " +
+				"Keep every fact, decision, name, number and open question the assistant will need to carry on. " +
+				"Reply with the summary only."),
+			llm.NewUserMessage(transcript.String()),
+		},
+	}
+	resp, err := s.provider.CreateChat(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("summarizing: %w", &ProviderError{Model: req.Model, Err: err})
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("summarizing: %w", ErrNoChoices)
+	}
+
+	out := make([]llm.Message, 0, len(prompts)+1+len(rest)-cut)
+	out = append(out, prompts...)
+	out = append(out, llm.NewSystemMessage(summaryPrefix+resp.Choices[0].Message.Content))
+	return append(out, rest[cut:]...), nil
+}
+
+// writeTranscript renders one message as plain text for the summarizer.
+func writeTranscript(b *strings.Builder, m llm.Message) {
+	switch {
+	case m.Role == "tool":
+		fmt.Fprintf(b, "[tool result]\n%s\n\n", m.Content)
+	case m.Content != "":
+		fmt.Fprintf(b, "[%s]\n%s\n\n", m.Role, m.Content)
+	}
+	for _, tc := range m.ToolCalls {
+		fmt.Fprintf(b, "[%s called %s(%s)]\n\n", m.Role, tc.Function.Name, tc.Function.Arguments)
+	}
+}
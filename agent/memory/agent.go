@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"strings"
+)
+
+// Option configures WithMemory.
+type Option func(*options)
+
+type options struct {
+	topK     int
+	minScore float64
+	user     string
+	onError  func(error)
+}
+
+// WithTopK sets how many facts are recalled into each Run. The default
+// is 5.
+func WithTopK(k int) Option {
+	return func(o *options) {
+		o.topK = k
+	}
+}
+
+// WithMinScore leaves out recalled facts less similar than score to the
+// user's message. The default is 0.3; what's sensible depends on the
+// embedding model.
+func WithMinScore(score float64) Option {
+	return func(o *options) {
+		o.minScore = score
+	}
+}
+
+// WithUser stamps the facts learned from the agent's runs as being about
+// user, so they can be found to export or delete along with the user's
+// conversations. It doesn't limit what's recalled: give each user a
+// Memory of their own for that.
+func WithUser(user string) Option {
+	return func(o *options) {
+		o.user = user
+	}
+}
+
+// OnError is called when recalling or learning facts fails. Memory never
+// fails a Run: without it the agent just answers without what it would
+// have recalled.
+func OnError(fn func(error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// WithMemory connects m to an agent. Before each Run the facts most
+// relevant to the user's message go into the system prompt; after each
+// successful Run, extractor (a small model will do) picks out new facts
+// from the exchange and m remembers them, in the background. With a nil
+// extractor nothing is learned automatically: call m.Remember yourself.
+//
+// Call the agent's WaitObservers before exiting so the last facts are
+// stored.
+func WithMemory(m Memory, extractor llm.ChatProvider, opts ...Option) agent.Option {
+	o := &options{topK: 5, minScore: 0.3}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(a *agent.Agent) {
+		agent.WithContextProvider(o.recallInto(m))(a)
+		if extractor != nil {
+			agent.WithObserver(agent.ObserverFunc(o.learnFrom(m, extractor)))(a)
+		}
+	}
+}
+
+func (o *options) fail(err error) {
+	if o.onError != nil {
+		o.onError(err)
+	}
+}
+
+// recallInto returns the context provider that recalls facts for a Run.
+func (o *options) recallInto(m Memory) agent.ContextProvider {
+	return func(ctx context.Context, input string) (string, error) {
+		if input == "" {
+			return "", nil
+		}
+		facts, err := m.Recall(ctx, input, o.topK)
+		if err != nil {
+			o.fail(err)
+			return "", nil
+		}
+		var b strings.Builder
+		for _, f := range facts {
+			if f.Score >= o.minScore {
+				fmt.Fprintf(&b, "- %s\n", f.Text)
+			}
+		}
+		if b.Len() == 0 {
+			return "", nil
+		}
+		return "What you remember from earlier conversations with this user:\n" + b.String(), nil
+	}
+}
+
+var factsSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"facts": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	},
+	"required":             []string{"facts"},
+	"additionalProperties": false,
+}
+
+const extractPrompt = "You maintain an AI assistant's long-term memory of a user. From the exchange below, " +
+	"list facts worth remembering in future conversations: who the user is, their preferences, plans, " +
+	"decisions and anything they asked to be remembered. Write each as a short, self-contained statement " +
+	"(\"The user's daughter is called Mia.\"). Skip small talk, the details of this one task, and anything " +
+	"already known. Reply with JSON: {\"facts\": [...]}, an empty list if there is nothing new."
+
+// learnFrom returns the observer that extracts and remembers facts after
+// each Run.
+func (o *options) learnFrom(m Memory, extractor llm.ChatProvider) func(context.Context, agent.Observation) {
+	return func(ctx context.Context, obs agent.Observation) {
+		res := obs.Result
+		if obs.Err != nil || res.Input == "" {
+			return
+		}
+		facts, err := Extract(ctx, extractor, m, res.Input, res.Content)
+		if err != nil {
+			o.fail(err)
+			return
+		}
+		for i := range facts {
+			facts[i].Source = res.ID
+			facts[i].User = o.user
+		}
+		if err := m.Remember(ctx, facts...); err != nil {
+			o.fail(err)
+		}
+	}
+}
+
+// Extract asks provider for the facts worth remembering from one exchange
+// between the user and the assistant. Facts m already has that are close
+// to the exchange are shown to the model so it doesn't repeat them; m may
+// be nil.
+func Extract(ctx context.Context, provider llm.ChatProvider, m Memory, user, assistant string) ([]Fact, error) {
+	var input strings.Builder
+	if m != nil {
+		if known, err := m.Recall(ctx, user, 10); err == nil && len(known) > 0 {
+			input.WriteString("Already known:\n")
+			for _, f := range known {
+				fmt.Fprintf(&input, "- %s\n", f.Text)
+			}
+			input.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&input, "User: %s\nAssistant: %s\n", user, assistant)
+
+	resp, err := provider.CreateChat(ctx, llm.ChatRequest{
+		Model:          provider.ModelName(),
+		Messages:       []llm.Message{llm.NewSystemMessage(extractPrompt), llm.NewUserMessage(input.String())},
+		ResponseFormat: llm.NewJSONSchemaFormat("facts", factsSchema),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("memory: extracting facts: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("memory: extracting facts: %w", agent.ErrNoChoices)
+	}
+
+	content := strings.TrimSpace(resp.Choices[0].Message.Content)
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "```json"), "```")
+	var out struct {
+		Facts []string `json:"facts"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &out); err != nil {
+		return nil, fmt.Errorf("memory: bad facts %q: %w", content, err)
+	}
+	var facts []Fact
+	for _, text := range out.Facts {
+		if text = strings.TrimSpace(text); text != "" {
+			facts = append(facts, Fact{Text: text})
+		}
+	}
+	return facts, nil
+}
@@ -0,0 +1,199 @@
+// Package memory gives an agent a long-term memory: facts it learns in one
+// conversation that it can use in the next - the user's name, their
+// preferences, decisions made along the way.
+//
+// Facts are embedded and kept in a Memory. At the start of each Run the
+// ones closest to the user's message are recalled into the system prompt,
+// and after each Run a model picks out what's worth remembering:
+//
+//	embedder := openai.New(key, "text-embedding-3-small")
+//	mem := memory.NewInMemory(embedder)
+//	a := agent.New(provider,
+//	    memory.WithMemory(mem, cheap),
+//	)
+//
+// This is not agent.WithMemory, which keeps a single conversation's
+// history in bounds; the two go well together.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Fact is one thing remembered.
+type Fact struct {
+	ID      string    `json:"id"`               // set by Remember if empty
+	Text    string    `json:"text"`             // a short, self-contained statement
+	Source  string    `json:"source,omitempty"` // the run it was learned in, if any
+	User    string    `json:"user,omitempty"`   // who it's about, if known (see WithUser)
+	Created time.Time `json:"created"`          // set by Remember if zero
+
+	Score float64 `json:"-"` // similarity to the query, set by Recall
+}
+
+// Memory stores facts and finds the ones relevant to a query.
+type Memory interface {
+	// Remember stores facts. A fact whose ID is already stored replaces it.
+	Remember(ctx context.Context, facts ...Fact) error
+	// Recall returns up to k facts, most relevant to query first.
+	Recall(ctx context.Context, query string, k int) ([]Fact, error)
+	// Forget removes facts by ID. Unknown IDs are ignored.
+	Forget(ctx context.Context, ids ...string) error
+}
+
+// Lister is a Memory that can list every fact it holds, which exporting
+// or deleting everything about one user takes (see agent/compliance).
+// InMemory is one.
+type Lister interface {
+	Memory
+	Facts() []Fact
+}
+
+var _ Lister = (*InMemory)(nil)
+
+// InMemory is a Memory held in the process, searched by brute force.
+// That's quick enough for thousands of facts - a user's memory, say - and
+// it can be saved and restored with Facts and Remember.
+type InMemory struct {
+	embedder  llm.EmbeddingsProvider
+	duplicate float64
+
+	mu      sync.Mutex
+	entries []entry
+	seq     int
+}
+
+type entry struct {
+	fact   Fact
+	vector []float32
+}
+
+// InMemoryOption configures an InMemory.
+type InMemoryOption func(*InMemory)
+
+// DuplicateThreshold sets how similar a new fact has to be to a stored one
+// to replace it rather than sit next to it - "prefers tea" after "likes
+// tea". The default is 0.95; 1 or more keeps every fact.
+func DuplicateThreshold(similarity float64) InMemoryOption {
+	return func(m *InMemory) {
+		m.duplicate = similarity
+	}
+}
+
+// NewInMemory returns an empty InMemory that embeds with embedder.
+func NewInMemory(embedder llm.EmbeddingsProvider, opts ...InMemoryOption) *InMemory {
+	m := &InMemory{embedder: embedder, duplicate: 0.95}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Remember embeds and stores facts.
+func (m *InMemory) Remember(ctx context.Context, facts ...Fact) error {
+	if len(facts) == 0 {
+		return nil
+	}
+	texts := make([]string, len(facts))
+	for i, f := range facts {
+		texts[i] = f.Text
+	}
+	vectors, err := m.embedder.CreateEmbeddings(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("memory: embedding facts: %w", err)
+	}
+	if len(vectors) != len(facts) {
+		return fmt.Errorf("memory: got %d embeddings for %d facts", len(vectors), len(facts))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, f := range facts {
+		if f.Created.IsZero() {
+			f.Created = time.Now()
+		}
+		f.Score = 0
+		e := entry{fact: f, vector: vectors[i]}
+
+		at := -1
+		if f.ID != "" {
+			at = slices.IndexFunc(m.entries, func(old entry) bool { return old.fact.ID == f.ID })
+		} else {
+			best := m.duplicate
+			for j, old := range m.entries {
+				if s := llm.CosineSimilarity(e.vector, old.vector); s >= best {
+					at, best = j, s
+				}
+			}
+			if at >= 0 {
+				e.fact.ID = m.entries[at].fact.ID
+			} else {
+				m.seq++
+				e.fact.ID = fmt.Sprintf("mem_%d", m.seq)
+			}
+		}
+		if at >= 0 {
+			m.entries[at] = e
+		} else {
+			m.entries = append(m.entries, e)
+		}
+	}
+	return nil
+}
+
+// Recall returns the k facts most similar to query.
+func (m *InMemory) Recall(ctx context.Context, query string, k int) ([]Fact, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	m.mu.Lock()
+	empty := len(m.entries) == 0
+	m.mu.Unlock()
+	if empty {
+		return nil, nil
+	}
+
+	vectors, err := m.embedder.CreateEmbeddings(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("memory: embedding query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("memory: got %d embeddings for 1 query", len(vectors))
+	}
+
+	m.mu.Lock()
+	facts := make([]Fact, len(m.entries))
+	for i, e := range m.entries {
+		facts[i] = e.fact
+		facts[i].Score = llm.CosineSimilarity(vectors[0], e.vector)
+	}
+	m.mu.Unlock()
+
+	slices.SortStableFunc(facts, func(a, b Fact) int { return cmp.Compare(b.Score, a.Score) })
+	return facts[:min(k, len(facts))], nil
+}
+
+// Forget removes facts by ID.
+func (m *InMemory) Forget(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = slices.DeleteFunc(m.entries, func(e entry) bool { return slices.Contains(ids, e.fact.ID) })
+	return nil
+}
+
+// Facts returns every stored fact, oldest first.
+func (m *InMemory) Facts() []Fact {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	facts := make([]Fact, len(m.entries))
+	for i, e := range m.entries {
+		facts[i] = e.fact
+	}
+	return facts
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-agent-sdk/llm"
+)
+
+// ModerationError is what Run fails with when WithModeration flags the
+// user's message or the model's answer.
+//
+//	var me *agent.ModerationError
+//	if errors.As(err, &me) {
+//	    log.Printf("flagged %s: %v", me.Stage, me.Categories)
+//	    reply = "Sorry, I can't help with that."
+//	}
+type ModerationError struct {
+	Stage      GuardrailStage     // GuardrailInput or GuardrailOutput
+	Categories []string           // the flagged categories, sorted
+	Scores     map[string]float64 // every category's score, from 0 to 1
+}
+
+func (e *ModerationError) Error() string {
+	return fmt.Sprintf("agent: %s flagged by moderation: %s", e.Stage, strings.Join(e.Categories, ", "))
+}
+
+// ModerationOption configures WithModeration.
+type ModerationOption func(*moderation)
+
+type moderation struct {
+	m          llm.Moderator
+	input      bool
+	output     bool
+	thresholds map[string]float64
+}
+
+// ModerateInputOnly screens user messages but not answers.
+func ModerateInputOnly() ModerationOption {
+	return func(mo *moderation) {
+		mo.input, mo.output = true, false
+	}
+}
+
+// ModerateOutputOnly screens answers but not user messages.
+func ModerateOutputOnly() ModerationOption {
+	return func(mo *moderation) {
+		mo.input, mo.output = false, true
+	}
+}
+
+// ModerationThreshold flags text whose score for category reaches score,
+// whatever the provider decided - stricter for a children's app, say.
+// The category "*" applies to every category without its own threshold.
+func ModerationThreshold(category string, score float64) ModerationOption {
+	return func(mo *moderation) {
+		if mo.thresholds == nil {
+			mo.thresholds = make(map[string]float64)
+		}
+		mo.thresholds[category] = score
+	}
+}
+
+// WithModeration screens every user message and every final answer with
+// m, and fails the Run with a *ModerationError when either is flagged:
+//
+//	mod := openai.New(os.Getenv("OPENAI_API_KEY"), "omni-moderation-latest")
+//	a := agent.New(provider, agent.WithModeration(mod))
+//
+// It works through guardrails - a flagged message never reaches the model
+// or the history, a flagged answer isn't stored - and runs after the
+// guardrails added before it. An error from m fails the Run too, so
+// nothing goes unscreened.
+func WithModeration(m llm.Moderator, opts ...ModerationOption) Option {
+	mo := &moderation{m: m, input: true, output: true}
+	for _, opt := range opts {
+		opt(mo)
+	}
+	return func(a *Agent) {
+		if mo.input {
+			a.inputGuardrails = append(a.inputGuardrails, mo.guardrail(GuardrailInput))
+		}
+		if mo.output {
+			a.outputGuardrails = append(a.outputGuardrails, mo.guardrail(GuardrailOutput))
+		}
+	}
+}
+
+func (mo *moderation) guardrail(stage GuardrailStage) Guardrail {
+	return func(ctx context.Context, text string) (GuardrailResult, error) {
+		results, err := mo.m.Moderate(ctx, []string{text})
+		if err != nil {
+			return GuardrailResult{}, fmt.Errorf("moderation: %w", err)
+		}
+		if len(results) == 0 {
+			return GuardrailResult{}, fmt.Errorf("moderation: no result")
+		}
+		if flagged := mo.flagged(results[0]); len(flagged) > 0 {
+			return GuardrailResult{}, &ModerationError{Stage: stage, Categories: flagged, Scores: results[0].Scores}
+		}
+		return GuardrailResult{}, nil
+	}
+}
+
+// flagged lists the categories the provider flagged or that cross a
+// threshold.
+func (mo *moderation) flagged(r llm.ModerationResult) []string {
+	set := make(map[string]bool)
+	for _, c := range r.FlaggedCategories() {
+		set[c] = true
+	}
+	for c, score := range r.Scores {
+		limit, ok := mo.thresholds[c]
+		if !ok {
+			limit, ok = mo.thresholds["*"]
+		}
+		if ok && score >= limit {
+			set[c] = true
+		}
+	}
+	if r.Flagged && len(set) == 0 {
+		set["unspecified"] = true
+	}
+	out := make([]string, 0, len(set))
+	for c := range set {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Observation is what an Observer gets after each Run: the run itself and
+// the conversation as it stands afterwards. Both are copies - observers
+// can keep them without racing the agent.
+type Observation struct {
+	Result  *RunResult    // input, answer, tool calls, usage
+	History []llm.Message // the whole conversation after the run
+	Err     error         // what the run returned, nil if it succeeded
+}
+
+// Observer watches a conversation without taking part in it - a compliance
+// check, a sentiment tracker, a quality monitor. It never changes what the
+// agent does: it runs in the background, after the user has their answer.
+type Observer interface {
+	Observe(ctx context.Context, obs Observation)
+}
+
+// ObserverFunc lets an ordinary function be an Observer.
+type ObserverFunc func(ctx context.Context, obs Observation)
+
+func (f ObserverFunc) Observe(ctx context.Context, obs Observation) {
+	f(ctx, obs)
+}
+
+// WithObserver attaches an observer. After every Run it gets an
+// Observation, on a goroutine of its own, in the order the runs happened -
+// a slow observer falls behind but never holds up the agent or the other
+// observers. Its context carries the run's trace but isn't cancelled with
+// the run.
+//
+// Call WaitObservers before exiting to let them catch up.
+func WithObserver(o Observer) Option {
+	return func(a *Agent) {
+		a.observers = append(a.observers, newObserverQueue(o))
+	}
+}
+
+// WaitObservers blocks until every observer has seen every finished Run,
+// or ctx is done.
+func (a *Agent) WaitObservers(ctx context.Context) error {
+	for _, q := range a.observers {
+		if err := q.wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyObservers queues a copy of the finished run for every observer.
+func (a *Agent) notifyObservers(ctx context.Context, res *RunResult, err error) {
+	if len(a.observers) == 0 {
+		return
+	}
+	obs := Observation{
+		Result:  res.clone(),
+		History: append([]llm.Message(nil), a.History...),
+		Err:     err,
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, q := range a.observers {
+		q.push(ctx, obs)
+	}
+}
+
+// clone copies the result deeply enough that appending to or rewriting
+// the original's turns doesn't show through.
+func (r *RunResult) clone() *RunResult {
+	c := *r
+	c.Turns = make([]Turn, len(r.Turns))
+	for i, t := range r.Turns {
+		t.ToolCalls = append([]ToolTrace(nil), t.ToolCalls...)
+		c.Turns[i] = t
+	}
+	return &c
+}
+
+// observerQueue delivers observations to one observer in order, from a
+// goroutine that runs while there's work. The queue is unbounded: an
+// observation is never dropped.
+type observerQueue struct {
+	observer Observer
+
+	mu      sync.Mutex
+	pending []queuedObservation
+	running bool
+	idle    chan struct{} // closed when the queue drains; replaced when work arrives
+}
+
+type queuedObservation struct {
+	ctx context.Context
+	obs Observation
+}
+
+func newObserverQueue(o Observer) *observerQueue {
+	idle := make(chan struct{})
+	close(idle)
+	return &observerQueue{observer: o, idle: idle}
+}
+
+func (q *observerQueue) push(ctx context.Context, obs Observation) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, queuedObservation{ctx, obs})
+	if !q.running {
+		q.running = true
+		q.idle = make(chan struct{})
+		go q.drain()
+	}
+}
+
+func (q *observerQueue) drain() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			close(q.idle)
+			q.mu.Unlock()
+			return
+		}
+		next := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		q.observe(next)
+	}
+}
+
+// observe calls the observer, keeping a panicking one from taking the
+// process down with it.
+func (q *observerQueue) observe(next queuedObservation) {
+	defer func() { recover() }()
+	q.observer.Observe(next.ctx, next.obs)
+}
+
+func (q *observerQueue) wait(ctx context.Context) error {
+	q.mu.Lock()
+	idle := q.idle
+	q.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flag is something an observer wants a human to look at.
+type Flag struct {
+	Observer string    `json:"observer"`
+	RunID    string    `json:"run_id"`
+	Severity string    `json:"severity"` // "info", "warning" or "critical"
+	Reason   string    `json:"reason"`
+	Time     time.Time `json:"time"`
+}
+
+// AgentObserver makes an agent an observer: after each run of the agent
+// it's attached to, watcher is shown the exchange - the user's message,
+// the tools called, the answer - and can call a raise_flag tool, which
+// reaches onFlag. The watcher only ever talks to itself, so its history
+// is the whole conversation as seen from the outside.
+//
+//	compliance := agent.New(cheap, agent.WithSystemPrompts(
+//	    "You review a support conversation for promises of refunds over $100. "+
+//	        "Raise a flag when you see one; otherwise reply OK."))
+//	a := agent.New(provider, agent.WithObserver(agent.AgentObserver("compliance", compliance,
+//	    func(f agent.Flag) { alerts.Send(f) })))
+//
+// Errors from the watcher's own runs are dropped; attach a callback to
+// the watcher to see them.
+func AgentObserver(name string, watcher *Agent, onFlag func(Flag)) Observer {
+	o := &agentObserver{name: name, watcher: watcher, onFlag: onFlag}
+	watcher.RegisterTool("raise_flag",
+		"Flag this exchange for human review. Call it once per problem you find.",
+		o.raiseFlag)
+	return o
+}
+
+type agentObserver struct {
+	name    string
+	watcher *Agent
+	onFlag  func(Flag)
+	runID   string // the run being observed; observations come one at a time
+}
+
+type raiseFlagArgs struct {
+	Severity string `json:"severity" enum:"info,warning,critical" description:"How urgent it is"`
+	Reason   string `json:"reason" description:"What is wrong, quoting the exchange"`
+}
+
+func (o *agentObserver) raiseFlag(ctx context.Context, args raiseFlagArgs) string {
+	if o.onFlag != nil {
+		o.onFlag(Flag{Observer: o.name, RunID: o.runID, Severity: args.Severity, Reason: args.Reason, Time: llm.Now(ctx)})
+	}
+	return "Flag raised."
+}
+
+func (o *agentObserver) Observe(ctx context.Context, obs Observation) {
+	o.runID = obs.Result.ID
+	o.watcher.Run(ctx, describeExchange(obs))
+}
+
+// describeExchange renders one run for a watcher agent to read.
+func describeExchange(obs Observation) string {
+	var b strings.Builder
+	res := obs.Result
+	fmt.Fprintf(&b, "User: %s\n", res.Input)
+	for _, t := range res.ToolCalls() {
+		fmt.Fprintf(&b, "Assistant called %s(%s)", t.Name, t.Arguments)
+		if t.Error != "" {
+			fmt.Fprintf(&b, " -> error: %s\n", t.Error)
+		} else {
+			fmt.Fprintf(&b, " -> %s\n", t.Result)
+		}
+	}
+	if obs.Err != nil {
+		fmt.Fprintf(&b, "The assistant failed: %v\n", obs.Err)
+	} else {
+		fmt.Fprintf(&b, "Assistant: %s\n", res.Content)
+	}
+	return b.String()
+}
@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"fmt"
+	"go-agent-sdk/llm"
+)
+
+// DefaultTemperature is the sampling temperature the agent sends unless
+// WithTemperature says otherwise.
+const DefaultTemperature = 0.7
+
+// params holds the generation settings copied into every ChatRequest the
+// agent sends. Zero values are left out of the request, so the provider's
+// own default applies.
+type params struct {
+	temperature      float64
+	topP             float64
+	maxTokens        int
+	stop             []string
+	presencePenalty  float64
+	frequencyPenalty float64
+	seed             int
+	reasoning        *llm.Reasoning
+	responseFormat   *llm.ResponseFormat
+}
+
+// apply copies the settings into req.
+func (p params) apply(req *llm.ChatRequest) {
+	req.Temperature = p.temperature
+	req.TopP = p.topP
+	req.MaxTokens = p.maxTokens
+	req.Stop = p.stop
+	req.PresencePenalty = p.presencePenalty
+	req.FrequencyPenalty = p.frequencyPenalty
+	req.Seed = p.seed
+	req.Reasoning = p.reasoning
+	req.ResponseFormat = p.responseFormat
+}
+
+// WithTemperature sets the sampling temperature (usually 0.0 to 2.0).
+// Lower is more focused, higher is more creative. Defaults to 0.7.
+//
+// Temperature 0 is indistinguishable from "not set" on the wire, so
+// WithTemperature(0) means "use the provider's default". For near-greedy
+// decoding use a small value like 0.01.
+func WithTemperature(t float64) Option {
+	return func(a *Agent) {
+		a.params.temperature = t
+	}
+}
+
+// WithTopP sets nucleus sampling: only tokens within the top p probability
+// mass are considered. Providers usually recommend changing this or the
+// temperature, not both.
+func WithTopP(p float64) Option {
+	return func(a *Agent) {
+		a.params.topP = p
+	}
+}
+
+// WithMaxTokens caps how many tokens the model may generate per response.
+// Anthropic requires a limit, so its provider picks one when this isn't set.
+//
+// This is a hard limit on generation - the answer is cut off mid-sentence
+// with finish_reason "length". To keep answers short but complete,
+// see WithAnswerLimit.
+func WithMaxTokens(n int) Option {
+	return func(a *Agent) {
+		a.params.maxTokens = n
+	}
+}
+
+// WithStop sets sequences that end generation when the model produces them.
+// The stop sequence itself isn't included in the answer.
+func WithStop(sequences ...string) Option {
+	return func(a *Agent) {
+		a.params.stop = sequences
+	}
+}
+
+// WithPresencePenalty penalizes tokens that already appeared at all,
+// nudging the model towards new topics (OpenAI-style, -2.0 to 2.0).
+// Providers without an equivalent ignore it.
+func WithPresencePenalty(p float64) Option {
+	return func(a *Agent) {
+		a.params.presencePenalty = p
+	}
+}
+
+// WithFrequencyPenalty penalizes tokens by how often they already appeared,
+// reducing verbatim repetition (OpenAI-style, -2.0 to 2.0).
+// Providers without an equivalent ignore it.
+func WithFrequencyPenalty(p float64) Option {
+	return func(a *Agent) {
+		a.params.frequencyPenalty = p
+	}
+}
+
+// WithSeed asks the provider to sample deterministically, where supported.
+// This is the model's seed; it is unrelated to WithRandSeed, which seeds
+// the agent's own random source.
+func WithSeed(seed int) Option {
+	return func(a *Agent) {
+		a.params.seed = seed
+	}
+}
+
+// WithReasoning turns on extended thinking for models that support it
+// (Claude, Gemini 2.5+, OpenAI o-series and friends). The model's thinking
+// is returned in Message.Reasoning, streamed as StreamDelta.Reasoning, and
+// kept in the history alongside the answer.
+//
+//	a := agent.New(provider, agent.WithReasoning(llm.Reasoning{Effort: "high"}))
+//
+// Thinking tokens are billed as output and count towards WithMaxTokens.
+func WithReasoning(r llm.Reasoning) Option {
+	return func(a *Agent) {
+		a.params.reasoning = &r
+	}
+}
+
+// WithResponseFormat makes every final answer JSON in the given format.
+// With a strict json_schema format OpenAI guarantees the shape:
+//
+//	a := agent.New(provider, agent.WithResponseFormat(
+//	    llm.NewJSONSchemaFormat("invoice", jsonschema.Of(Invoice{})),
+//	))
+//	out, err := a.Run(ctx, "Extract the invoice: "+text)
+//	var inv Invoice
+//	err = json.Unmarshal([]byte(out), &inv)
+func WithResponseFormat(f *llm.ResponseFormat) Option {
+	return func(a *Agent) {
+		a.params.responseFormat = f
+	}
+}
+
+// RunOption overrides agent settings for a single Run. The agent itself is
+// not changed, so one agent can serve creative and deterministic calls:
+//
+//	summary, err := a.Run(ctx, "Summarise the ticket.", agent.RunWithTemperature(0.1))
+//	slogan, err := a.Run(ctx, "Now a catchy slogan.", agent.RunWithTemperature(1.2))
+type RunOption func(*runConfig)
+
+// runConfig is the per-run view of the agent's settings.
+type runConfig struct {
+	params     params
+	toolChoice any // nil means let the provider decide
+}
+
+// runConfig starts from the agent's settings and applies the run options.
+func (a *Agent) runConfig(opts []RunOption) runConfig {
+	cfg := runConfig{params: a.params}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// apply copies the settings into the request, given how many rounds of tool
+// calls the run has done so far. A tool choice that forces a call only
+// applies until the first round - forcing it again after the tool ran
+// would loop until the iteration limit. Repair rounds don't count, so a
+// request re-asked after a rejected answer still gets it.
+func (c runConfig) apply(req *llm.ChatRequest, toolRounds int) {
+	c.params.apply(req)
+	if c.toolChoice == nil || len(req.Tools) == 0 {
+		return
+	}
+	if choice, err := llm.ParseToolChoice(c.toolChoice); err == nil && (toolRounds == 0 || !choice.Forces()) {
+		req.ToolChoice = c.toolChoice
+	}
+}
+
+// checkToolChoice rejects a tool choice no provider would accept: one
+// that can't be parsed, or that forces a tool the request doesn't offer.
+// Catching it here beats a 400 from the provider, or - from providers that
+// quietly ignore it - a run that never calls the tool.
+func (c runConfig) checkToolChoice(offered []llm.Tool) error {
+	choice, err := llm.ParseToolChoice(c.toolChoice)
+	if err != nil {
+		return fmt.Errorf("agent: %w", err)
+	}
+	if choice.Forces() && len(offered) == 0 {
+		return fmt.Errorf("agent: tool choice %q but no tools are offered", choice.Mode)
+	}
+	if choice == nil || choice.Mode != llm.ToolChoiceModeFunction {
+		return nil
+	}
+	for _, t := range offered {
+		if t.Function.Name == choice.Function {
+			return nil
+		}
+	}
+	return fmt.Errorf("agent: tool choice names %q, which isn't offered", choice.Function)
+}
+
+// RunWithTemperature overrides the sampling temperature for one Run.
+func RunWithTemperature(t float64) RunOption {
+	return func(c *runConfig) {
+		c.params.temperature = t
+	}
+}
+
+// RunWithTopP overrides nucleus sampling for one Run.
+func RunWithTopP(p float64) RunOption {
+	return func(c *runConfig) {
+		c.params.topP = p
+	}
+}
+
+// RunWithMaxTokens overrides the per-response token limit for one Run.
+func RunWithMaxTokens(n int) RunOption {
+	return func(c *runConfig) {
+		c.params.maxTokens = n
+	}
+}
+
+// RunWithStop overrides the stop sequences for one Run.
+func RunWithStop(sequences ...string) RunOption {
+	return func(c *runConfig) {
+		c.params.stop = sequences
+	}
+}
+
+// RunWithSeed overrides the model's sampling seed for one Run.
+func RunWithSeed(seed int) RunOption {
+	return func(c *runConfig) {
+		c.params.seed = seed
+	}
+}
+
+// RunWithReasoning turns on extended thinking for one Run - e.g. only for
+// the hard questions.
+func RunWithReasoning(r llm.Reasoning) RunOption {
+	return func(c *runConfig) {
+		c.params.reasoning = &r
+	}
+}
+
+// RunWithResponseFormat asks for JSON in the given format for one Run -
+// e.g. a structured extraction in the middle of a chat.
+func RunWithResponseFormat(f *llm.ResponseFormat) RunOption {
+	return func(c *runConfig) {
+		c.params.responseFormat = f
+	}
+}
+
+// RunWithToolChoice controls tool use for one Run. It takes the same values
+// as llm.ChatRequest.ToolChoice - best built with the llm.ToolChoice
+// constructors:
+//
+//	a.Run(ctx, "Look up order 42.", agent.RunWithToolChoice(llm.ToolChoiceFunction("get_order")))
+//
+// A choice that can't be parsed, or that names a tool the agent doesn't
+// offer, fails the Run before anything is sent.
+//
+// "auto" and "none" apply to every request in the run. Anything that forces
+// a tool call applies only to the first request, so the model can answer
+// once it has the result.
+func RunWithToolChoice(choice any) RunOption {
+	return func(c *runConfig) {
+		c.toolChoice = choice
+	}
+}
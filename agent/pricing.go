@@ -0,0 +1,42 @@
+package agent
+
+import "go-agent-sdk/llm"
+
+// PriceFunc returns what a call to model using usage cost, in US dollars.
+// ok is false for a model it has no price for. cost.Table.Cost is one.
+type PriceFunc func(model string, usage llm.Usage) (usd float64, ok bool)
+
+// WithPricing prices every LLM call, filling in Turn.Cost, RunResult.Cost
+// and Agent.Cost:
+//
+//	a := agent.New(provider, agent.WithPricing(cost.Default.Cost))
+//	res, err := a.RunWithResult(ctx, "Summarize this thread")
+//	fmt.Printf("$%.4f\n", res.Cost)
+//
+// Calls to a model price doesn't know count as free.
+func WithPricing(price PriceFunc) Option {
+	return func(a *Agent) {
+		a.price = price
+	}
+}
+
+// priceRun fills in the cost of each of the run's turns and of the run,
+// and adds it to the agent's total.
+func (a *Agent) priceRun(res *RunResult) {
+	if a.price == nil {
+		return
+	}
+	res.Cost = 0
+	for i := range res.Turns {
+		t := &res.Turns[i]
+		model := t.Response.Model // the exact version that answered, if the API says
+		if model == "" {
+			model = t.Request.Model
+		}
+		if usd, ok := a.price(model, t.Response.Usage); ok {
+			t.Cost = usd
+			res.Cost += usd
+		}
+	}
+	a.Cost += res.Cost
+}
@@ -0,0 +1,50 @@
+package agent
+
+import "go-agent-sdk/llm"
+
+// WithPruneFailedToolCalls drops failed tool attempts from the history once
+// the model recovers and produces a final answer.
+//
+// When the model fumbles its arguments a few times before getting a tool
+// call right, every failed call and its error message stay in the history
+// and get resent on every later turn. With this option, once Run reaches a
+// final answer those failed call/error pairs are removed - both the tool_call
+// entry on the assistant message and the matching tool error message, so the
+// remaining history is still valid for every provider.
+func WithPruneFailedToolCalls() Option {
+	return func(a *Agent) {
+		a.pruneFailed = true
+	}
+}
+
+// pruneFailedToolCalls returns history without the tool calls whose IDs are in
+// failed, and without their tool result messages. Assistant messages left with
+// no tool calls and no text are dropped entirely.
+func pruneFailedToolCalls(history []llm.Message, failed map[string]bool) []llm.Message {
+	if len(failed) == 0 {
+		return history
+	}
+
+	out := make([]llm.Message, 0, len(history))
+	for _, msg := range history {
+		if msg.Role == "tool" && failed[msg.ToolCallID] {
+			continue
+		}
+
+		if len(msg.ToolCalls) > 0 {
+			kept := make([]llm.ToolCall, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				if !failed[call.ID] {
+					kept = append(kept, call)
+				}
+			}
+			if len(kept) == 0 && msg.Content == "" {
+				continue
+			}
+			msg.ToolCalls = kept
+		}
+
+		out = append(out, msg)
+	}
+	return out
+}
@@ -0,0 +1,209 @@
+// Package replay steps through a recorded agent run one event at a time:
+// each request with what changed in the history since the last one, each
+// response, each tool call with its input and output. When an agent did
+// something strange, walking the run step by step shows the exact moment
+// it went wrong - the tool result it misread, the message that fell out of
+// the history.
+//
+// Record runs with a Writer (or save RunWithResult's result as JSON, or
+// use feedback.WithTraces), then open them with the replay command:
+//
+//	go run go-agent-sdk/cmd/replay traces.jsonl.gz
+//
+// or in code:
+//
+//	runs, err := replay.Load(f)
+//	for _, step := range replay.Steps(runs[0]) {
+//	    step.Render(os.Stdout, replay.RenderOptions{})
+//	}
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"io"
+	"strings"
+	"time"
+)
+
+// Kind is what a Step shows.
+type Kind string
+
+const (
+	Request  Kind = "request"  // a request sent to the LLM
+	Response Kind = "response" // what the LLM sent back
+	ToolCall Kind = "tool"     // one tool execution
+)
+
+// Step is one event of a run.
+type Step struct {
+	Kind Kind
+	Turn int // index into RunResult.Turns
+	Tool int // index into the turn's ToolCalls, for ToolCall steps
+
+	// Request steps: the messages added since the previous request, and
+	// the ones that were there before but are gone now (pruned, compacted,
+	// rewritten). The first request's Added is the whole history.
+	Added   []llm.Message
+	Removed []llm.Message
+
+	Request  *llm.ChatRequest  // Request steps
+	Response *llm.ChatResponse // Response steps
+	Latency  time.Duration     // Response steps
+	Trace    *agent.ToolTrace  // ToolCall steps
+}
+
+// Steps flattens a run into its events, in the order they happened.
+func Steps(res *agent.RunResult) []Step {
+	var steps []Step
+	var prev []llm.Message
+	for i := range res.Turns {
+		turn := &res.Turns[i]
+		added, removed := diff(prev, turn.Request.Messages)
+		steps = append(steps, Step{Kind: Request, Turn: i, Request: &turn.Request, Added: added, Removed: removed})
+		steps = append(steps, Step{Kind: Response, Turn: i, Response: &turn.Response, Latency: turn.Latency})
+		for j := range turn.ToolCalls {
+			steps = append(steps, Step{Kind: ToolCall, Turn: i, Tool: j, Trace: &turn.ToolCalls[j]})
+		}
+		prev = turn.Request.Messages
+	}
+	return steps
+}
+
+// diff compares two requests' histories. The history is append-only in
+// the normal case, so it finds the longest common prefix: everything
+// after it in cur was added, everything after it in prev was removed.
+func diff(prev, cur []llm.Message) (added, removed []llm.Message) {
+	n := 0
+	for n < len(prev) && n < len(cur) && sameMessage(prev[n], cur[n]) {
+		n++
+	}
+	return cur[n:], prev[n:]
+}
+
+func sameMessage(a, b llm.Message) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// Load reads every recorded run from r: a trace file made by Writer
+// (compressed or not), a single RunResult, JSON lines of them, or
+// feedback export lines whose "trace" holds one. Records without a trace
+// are skipped.
+func Load(r io.Reader) ([]*agent.RunResult, error) {
+	tr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var runs []*agent.RunResult
+	for {
+		res, err := tr.Next()
+		if err == io.EOF {
+			return runs, nil
+		}
+		if err != nil {
+			return runs, err
+		}
+		runs = append(runs, res)
+	}
+}
+
+// RenderOptions controls how a Step is printed.
+type RenderOptions struct {
+	// MaxLen truncates long texts (message contents, tool results) to
+	// this many characters. 0 means 400; negative means never truncate.
+	MaxLen int
+}
+
+func (o RenderOptions) clip(s string) string {
+	max := o.MaxLen
+	if max == 0 {
+		max = 400
+	}
+	if max < 0 || len([]rune(s)) <= max {
+		return s
+	}
+	return string([]rune(s)[:max]) + fmt.Sprintf("... (%d more characters)", len([]rune(s))-max)
+}
+
+// Render writes a human-readable description of the step.
+func (s Step) Render(w io.Writer, opts RenderOptions) {
+	switch s.Kind {
+	case Request:
+		req := s.Request
+		fmt.Fprintf(w, "REQUEST  turn %d  model %s  %d messages, %d tools offered\n",
+			s.Turn+1, req.Model, len(req.Messages), len(req.Tools))
+		for _, m := range s.Removed {
+			renderMessage(w, "- ", m, opts)
+		}
+		for _, m := range s.Added {
+			renderMessage(w, "+ ", m, opts)
+		}
+		if len(s.Added) == 0 && len(s.Removed) == 0 {
+			fmt.Fprintln(w, "  (history unchanged)")
+		}
+
+	case Response:
+		resp := s.Response
+		fmt.Fprintf(w, "RESPONSE turn %d  model %s  %s  %d+%d tokens\n", s.Turn+1, resp.Model,
+			s.Latency.Round(time.Millisecond), resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		if len(resp.Choices) == 0 {
+			fmt.Fprintln(w, "  (no choices)")
+			return
+		}
+		c := resp.Choices[0]
+		fmt.Fprintf(w, "  finish_reason: %s\n", c.FinishReason)
+		if c.Message.Reasoning != "" {
+			fmt.Fprintf(w, "  reasoning: %s\n", indent(opts.clip(c.Message.Reasoning)))
+		}
+		renderMessage(w, "  ", c.Message, opts)
+
+	case ToolCall:
+		t := s.Trace
+		fmt.Fprintf(w, "TOOL     turn %d  %s  (%s)  %s\n", s.Turn+1, t.Name, t.ID, t.Duration.Round(time.Millisecond))
+		fmt.Fprintf(w, "  arguments: %s\n", indent(opts.clip(prettyJSON(t.Arguments))))
+		if t.Error != "" {
+			fmt.Fprintf(w, "  ERROR: %s\n", indent(opts.clip(t.Error)))
+		} else {
+			fmt.Fprintf(w, "  result: %s\n", indent(opts.clip(t.Result)))
+		}
+		for _, e := range t.Effects {
+			fmt.Fprintf(w, "  effect: %s %s %s\n", e.Kind, e.Target, e.Summary)
+		}
+	}
+}
+
+// renderMessage writes one history message on a line (or a few).
+func renderMessage(w io.Writer, prefix string, m llm.Message, opts RenderOptions) {
+	content := m.Content
+	if content == "" && len(m.Parts) > 0 {
+		content = fmt.Sprintf("(%d content parts)", len(m.Parts))
+	}
+	switch {
+	case m.Role == "tool":
+		fmt.Fprintf(w, "%s[tool %s] %s\n", prefix, m.ToolCallID, indent(opts.clip(content)))
+	case content != "":
+		fmt.Fprintf(w, "%s[%s] %s\n", prefix, m.Role, indent(opts.clip(content)))
+	}
+	for _, tc := range m.ToolCalls {
+		fmt.Fprintf(w, "%s[%s] -> %s(%s)  %s\n", prefix, m.Role, tc.Function.Name,
+			opts.clip(tc.Function.Arguments), tc.ID)
+	}
+}
+
+// indent keeps continuation lines of multi-line text under their label.
+func indent(s string) string {
+	return strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -0,0 +1,273 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"io"
+	"sync"
+)
+
+// Trace files store RunResults compactly. A RunResult repeats the whole
+// history in every turn's request, so a long conversation's traces grow
+// with the square of its length. The history is append-only, though: the
+// Writer stores each request as "the first N messages of the previous
+// request, plus these new ones", and compresses the stream. Load and
+// Reader undo both transparently, and still read plain RunResult JSON.
+//
+// The compression is gzip, not zstd. zstd does better on traces, but the
+// standard library has no zstd and this module takes no dependencies, so
+// it's left to the caller: WithCompressor plugs in an encoder such as
+// github.com/klauspost/compress/zstd, and RegisterDecompressor teaches
+// Load and Reader to read what it writes.
+//
+// On disk each run is one JSON line shaped like a RunResult, except that
+// each request's "messages" holds only the new messages and
+// "base_messages" says how many to take from the previous request. The
+// previous request may be in an earlier run - consecutive runs of one
+// agent share their history - so records have to be read in order.
+
+// storedRun is a RunResult as written to a trace file. Its fields shadow
+// the embedded ones with the same JSON name.
+type storedRun struct {
+	agent.RunResult
+	Turns []storedTurn `json:"turns"`
+}
+
+type storedTurn struct {
+	agent.Turn
+	Request storedRequest `json:"request"`
+}
+
+type storedRequest struct {
+	llm.ChatRequest
+	Base     int           `json:"base_messages,omitempty"` // messages repeated from the previous request
+	Messages []llm.Message `json:"messages"`                // the rest
+}
+
+// Compressor wraps a trace file as it's written.
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// WriterOption configures a Writer.
+type WriterOption func(*Writer)
+
+// WithCompressor compresses with c instead of gzip - zstd, for example,
+// which compresses traces better and faster but isn't in the standard
+// library:
+//
+//	w, err := replay.NewWriter(f, replay.WithCompressor(func(w io.Writer) (io.WriteCloser, error) {
+//	    return zstd.NewWriter(w) // github.com/klauspost/compress/zstd
+//	}))
+//
+// Readers need the matching RegisterDecompressor.
+func WithCompressor(c Compressor) WriterOption {
+	return func(w *Writer) {
+		w.compress = c
+	}
+}
+
+// WithoutCompression writes plain JSON lines, delta-encoded but readable
+// with any tool.
+func WithoutCompression() WriterOption {
+	return func(w *Writer) {
+		w.compress = nil
+	}
+}
+
+// Writer appends runs to a trace file. It is safe for concurrent use.
+type Writer struct {
+	compress Compressor
+
+	mu   sync.Mutex
+	zw   io.WriteCloser // the compressor, nil without compression
+	enc  *json.Encoder
+	prev []llm.Message // the last request written
+}
+
+// NewWriter starts a trace file on w. Call Close when done, to flush the
+// compressor; it doesn't close w.
+//
+//	f, _ := os.Create("traces.jsonl.gz")
+//	tw, _ := replay.NewWriter(f)
+//	defer tw.Close()
+//
+//	res, err := a.RunWithResult(ctx, question)
+//	tw.Write(res)
+func NewWriter(w io.Writer, opts ...WriterOption) (*Writer, error) {
+	tw := &Writer{compress: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }}
+	for _, opt := range opts {
+		opt(tw)
+	}
+
+	out := w
+	if tw.compress != nil {
+		zw, err := tw.compress(w)
+		if err != nil {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+		tw.zw = zw
+		out = zw
+	}
+	tw.enc = json.NewEncoder(out)
+	return tw, nil
+}
+
+// Write appends a run.
+func (w *Writer) Write(res *agent.RunResult) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run := storedRun{RunResult: *res, Turns: make([]storedTurn, len(res.Turns))}
+	for i, t := range res.Turns {
+		msgs := t.Request.Messages
+		base := len(msgs) - len(appended(w.prev, msgs))
+		run.Turns[i] = storedTurn{
+			Turn:    t,
+			Request: storedRequest{ChatRequest: t.Request, Base: base, Messages: msgs[base:]},
+		}
+		// A copy: the agent may still change its history in place (see
+		// Agent.SetProvider), and the reader only ever sees what we wrote.
+		w.prev = append(w.prev[:0:0], msgs...)
+	}
+	if err := w.enc.Encode(run); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	return nil
+}
+
+// Flush pushes buffered compressed data to the underlying writer, so a
+// reader (or a crash) sees everything written so far.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if f, ok := w.zw.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close finishes the compressed stream. The underlying writer stays open.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.zw == nil {
+		return nil
+	}
+	return w.zw.Close()
+}
+
+// appended returns the messages of cur after its common prefix with prev.
+func appended(prev, cur []llm.Message) []llm.Message {
+	added, _ := diff(prev, cur)
+	return added
+}
+
+// decompressors are the compressed formats Reader recognizes, by magic
+// number. gzip is built in.
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = []decompressor{{
+		magic: []byte{0x1f, 0x8b},
+		open:  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}}
+)
+
+type decompressor struct {
+	magic []byte
+	open  func(io.Reader) (io.Reader, error)
+}
+
+// RegisterDecompressor teaches Load and Reader a compressed format, by
+// the magic bytes its streams start with - the way image.RegisterFormat
+// works. For zstd:
+//
+//	replay.RegisterDecompressor([]byte{0x28, 0xb5, 0x2f, 0xfd}, func(r io.Reader) (io.Reader, error) {
+//	    return zstd.NewReader(r)
+//	})
+func RegisterDecompressor(magic []byte, open func(r io.Reader) (io.Reader, error)) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	decompressors = append(decompressors, decompressor{magic: magic, open: open})
+}
+
+// Reader reads runs back from a trace file one at a time, for files too
+// big to Load at once.
+type Reader struct {
+	dec  *json.Decoder
+	prev []llm.Message
+}
+
+// NewReader detects the file's compression and returns a Reader for it.
+// Uncompressed files - including plain RunResult JSON - work too.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(8) // shorter files just match nothing
+
+	var in io.Reader = br
+	decompressorsMu.RLock()
+	for _, d := range decompressors {
+		if bytes.HasPrefix(head, d.magic) {
+			zr, err := d.open(br)
+			if err != nil {
+				decompressorsMu.RUnlock()
+				return nil, fmt.Errorf("replay: %w", err)
+			}
+			in = zr
+			break
+		}
+	}
+	decompressorsMu.RUnlock()
+	return &Reader{dec: json.NewDecoder(in)}, nil
+}
+
+// Next returns the next run, or io.EOF after the last. Lines that hold no
+// run - feedback records without a trace, say - are skipped.
+func (r *Reader) Next() (*agent.RunResult, error) {
+	for {
+		var line struct {
+			storedRun
+			Trace *storedRun `json:"trace"` // a feedback export record
+		}
+		if err := r.dec.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+
+		stored := &line.storedRun
+		if line.Trace != nil {
+			stored = line.Trace
+		} else if stored.Turns == nil {
+			continue
+		}
+		return r.expand(stored)
+	}
+}
+
+// expand rebuilds each request's full history from the deltas.
+func (r *Reader) expand(s *storedRun) (*agent.RunResult, error) {
+	res := s.RunResult
+	res.Turns = make([]agent.Turn, len(s.Turns))
+	for i, t := range s.Turns {
+		if t.Request.Base > len(r.prev) {
+			return nil, fmt.Errorf("replay: run %s turn %d repeats %d messages but the previous request had %d - records out of order?",
+				res.ID, i+1, t.Request.Base, len(r.prev))
+		}
+		msgs := make([]llm.Message, 0, t.Request.Base+len(t.Request.Messages))
+		msgs = append(msgs, r.prev[:t.Request.Base]...)
+		msgs = append(msgs, t.Request.Messages...)
+
+		turn := t.Turn
+		turn.Request = t.Request.ChatRequest
+		turn.Request.Messages = msgs
+		res.Turns[i] = turn
+		r.prev = msgs
+	}
+	return &res, nil
+}
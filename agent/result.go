@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"go-agent-sdk/llm"
+	"go-agent-sdk/tools"
+	"time"
+)
+
+// RunResult is everything that happened during one RunWithResult call.
+// It marshals to JSON, so you can store it as a trace of the run.
+type RunResult struct {
+	ID           string        `json:"id"`                // unique per run, e.g. to attach user feedback
+	Input        string        `json:"input"`             // the user message that started the run
+	TraceID      string        `json:"trace_id"`          // the distributed trace the run belongs to (see llm.TraceContext)
+	Content      string        `json:"content"`           // the final answer (same as Run returns)
+	Model        string        `json:"model"`             // the model that served the last response
+	FinishReason string        `json:"finish_reason"`     // finish_reason of the last response
+	Usage        llm.Usage     `json:"usage"`             // tokens summed across every LLM call in the run
+	Cost         float64       `json:"cost,omitempty"`    // dollars across every LLM call in the run (see WithPricing)
+	Session      string        `json:"session,omitempty"` // the WithSession ID, if the agent has one
+	Duration     time.Duration `json:"duration"`          // wall time of the whole run
+	Turns        []Turn        `json:"turns"`             // one entry per LLM call, in order
+
+	// Guardrails records what WithInputGuardrail and WithOutputGuardrail
+	// guardrails rejected, rewrote or noted, in order.
+	Guardrails []GuardrailNote `json:"guardrails,omitempty"`
+}
+
+// Turn is one LLM round trip inside a run, plus any tools it triggered.
+type Turn struct {
+	Request   llm.ChatRequest  `json:"request"`              // exactly what was sent
+	Response  llm.ChatResponse `json:"response"`             // exactly what came back
+	Latency   time.Duration    `json:"latency"`              // how long the provider took
+	Cost      float64          `json:"cost,omitempty"`       // dollars, with WithPricing
+	ToolCalls []ToolTrace      `json:"tool_calls,omitempty"` // tools executed because of this response
+}
+
+// ToolTrace records one tool execution.
+type ToolTrace struct {
+	ID        string        `json:"id"`              // tool_call_id linking call and result
+	Name      string        `json:"name"`            // tool name
+	Arguments string        `json:"arguments"`       // raw JSON arguments from the LLM
+	Result    string        `json:"result"`          // what the tool returned
+	Error     string        `json:"error,omitempty"` // set if the tool failed
+	Duration  time.Duration `json:"duration"`        // how long the tool took
+	SpanID    string        `json:"span_id"`         // the tool call's span in the run's trace
+
+	// Sanitized says a WithToolResultSanitizer sanitizer changed the
+	// result; Result is what the model saw.
+	Sanitized bool `json:"sanitized,omitempty"`
+
+	// Effects are the side effects the tool declared (tools.RecordEffect),
+	// kept even when the tool went on to fail.
+	Effects []tools.Effect `json:"effects,omitempty"`
+
+	// Err is the tool's error itself, for errors.As / errors.Is.
+	// Error holds its text, which is what survives JSON encoding.
+	Err error `json:"-"`
+}
+
+// ToolCalls returns every tool execution in the run, in order.
+func (r *RunResult) ToolCalls() []ToolTrace {
+	var all []ToolTrace
+	for _, t := range r.Turns {
+		all = append(all, t.ToolCalls...)
+	}
+	return all
+}
+
+// Effects returns every side effect the run's tools recorded, in order.
+func (r *RunResult) Effects() []tools.Effect {
+	var all []tools.Effect
+	for _, tc := range r.ToolCalls() {
+		all = append(all, tc.Effects...)
+	}
+	return all
+}
+
+// Artifacts returns the files the run's tools produced, in order: the
+// "artifact.created" effects recorded by tools.SaveArtifact. Each one's
+// Target is the reference the store returned, its Data the file's name,
+// MIME type and size.
+func (r *RunResult) Artifacts() []tools.Effect {
+	var out []tools.Effect
+	for _, e := range r.Effects() {
+		if e.Kind == "artifact.created" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"go-agent-sdk/llm"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy decides how failed LLM calls are retried. How many times is
+// still Agent.MaxRetries (see WithMaxRetries); the policy controls the
+// waiting in between and which errors count.
+//
+// The delay before retry n (starting at 0) is BaseDelay * Multiplier^n,
+// capped at MaxDelay, then randomised by ±Jitter so a fleet of agents that
+// hit a rate limit together don't all come back at the same instant.
+// If the provider sent a Retry-After header, that wins when it's longer.
+type RetryPolicy struct {
+	BaseDelay  time.Duration // first delay. Default 500ms.
+	MaxDelay   time.Duration // upper bound for any single delay, including Retry-After. Default 30s.
+	Multiplier float64       // growth per attempt. Default 2.
+	Jitter     float64       // fraction of the delay to randomise, 0 to 1. Default 0.2.
+
+	// Retryable reports whether an error is worth another attempt.
+	// Defaults to llm.IsTransient: 429, 408, 5xx, and network errors.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy is what agents use unless WithRetryPolicy says otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+	Retryable:  llm.IsTransient,
+}
+
+// WithRetryPolicy customises how failed LLM calls are retried.
+// Zero fields fall back to DefaultRetryPolicy.
+//
+// Example - back off harder against a strict rate limit:
+//
+//	a := agent.New(provider,
+//	    agent.WithMaxRetries(5),
+//	    agent.WithRetryPolicy(agent.RetryPolicy{BaseDelay: 2 * time.Second}),
+//	)
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(a *Agent) {
+		a.retry = p
+	}
+}
+
+// delay works out how long to wait before retry number attempt (0-based).
+func (p RetryPolicy) delay(ctx context.Context, attempt int, err error) time.Duration {
+	base, maxDelay, mult, jitter := p.BaseDelay, p.MaxDelay, p.Multiplier, p.Jitter
+	if base <= 0 {
+		base = DefaultRetryPolicy.BaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if mult < 1 {
+		mult = DefaultRetryPolicy.Multiplier
+	}
+	if jitter <= 0 || jitter > 1 {
+		jitter = DefaultRetryPolicy.Jitter
+	}
+
+	d := float64(base)
+	for i := 0; i < attempt && d < float64(maxDelay); i++ {
+		d *= mult
+	}
+	d = min(d, float64(maxDelay))
+
+	// Scale by a random factor in [1-jitter, 1+jitter). The source comes from
+	// the context so WithRandSeed makes the delays reproducible.
+	var r float64
+	if rng := llm.RandFromContext(ctx); rng != nil {
+		r = rng.Float64()
+	} else {
+		r = rand.Float64()
+	}
+	d *= 1 - jitter + 2*jitter*r
+
+	wait := time.Duration(d)
+	if hint := retryAfter(err); hint > wait {
+		wait = min(hint, maxDelay)
+	}
+	return wait
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return llm.IsTransient(err)
+}
+
+// retryAfter digs the server's Retry-After hint out of err, if any.
+func retryAfter(err error) time.Duration {
+	var apiErr *llm.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// withRetry calls fn until it succeeds, fails with an error the policy
+// doesn't consider retryable, or MaxRetries retries have been used up.
+// It stops early if ctx is cancelled while waiting. Each retry is reported
+// to OnRetry; the error it gives up with is the caller's to report.
+func (a *Agent) withRetry(ctx context.Context, fn func() (*llm.ChatResponse, error)) (*llm.ChatResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := fn()
+		if nr, ok := err.(noRetry); ok {
+			return resp, nr.error
+		}
+		if err == nil || attempt >= a.MaxRetries || !a.retry.retryable(err) {
+			return resp, err
+		}
+
+		delay := a.retry.delay(ctx, attempt, err)
+		a.callbacks.OnRetry(ctx, attempt+1, err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// noRetry marks an error that must not be retried whatever the policy says,
+// e.g. a stream that already delivered part of its answer. withRetry unwraps it.
+type noRetry struct{ error }
@@ -0,0 +1,348 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-agent-sdk/llm"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// historyVersion is written into saved histories so the format can change
+// without breaking files already on disk.
+const historyVersion = 1
+
+// savedHistory is the JSON SaveHistory writes.
+type savedHistory struct {
+	Version  int           `json:"version"`
+	Messages []llm.Message `json:"messages"`
+}
+
+// SaveHistory writes the conversation to w as JSON, so it can survive a
+// restart and be picked up with LoadHistory:
+//
+//	f, _ := os.Create("chat.json")
+//	err := a.SaveHistory(f)
+func (a *Agent) SaveHistory(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(savedHistory{Version: historyVersion, Messages: a.History}); err != nil {
+		return fmt.Errorf("agent: saving history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory replaces the conversation with one written by SaveHistory.
+// A plain JSON array of messages works too. The loaded history keeps the
+// system message it was saved with, whatever the agent's SystemPrompt is
+// now.
+func (a *Agent) LoadHistory(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("agent: loading history: %w", err)
+	}
+	history, err := DecodeHistory(data)
+	if err != nil {
+		return fmt.Errorf("agent: loading history: %w", err)
+	}
+	a.History = history
+	return nil
+}
+
+// EncodeHistory returns history in the JSON format SaveHistory writes, for
+// SessionStores that keep bytes.
+func EncodeHistory(history []llm.Message) ([]byte, error) {
+	return json.Marshal(savedHistory{Version: historyVersion, Messages: history})
+}
+
+// DecodeHistory reads what EncodeHistory or SaveHistory wrote, a plain
+// JSON array of messages, or ProtobufCodec output.
+func DecodeHistory(data []byte) ([]llm.Message, error) {
+	if isProtobuf(data) {
+		return ProtobufCodec.Decode(data)
+	}
+	var saved savedHistory
+	if err := json.Unmarshal(data, &saved); err != nil {
+		// Not an object - maybe a bare message array.
+		var msgs []llm.Message
+		if json.Unmarshal(data, &msgs) != nil {
+			return nil, err
+		}
+		return msgs, nil
+	}
+	if saved.Version > historyVersion {
+		return nil, fmt.Errorf("history format version %d is newer than this package supports (%d)", saved.Version, historyVersion)
+	}
+	if saved.Messages == nil {
+		saved.Messages = make([]llm.Message, 0)
+	}
+	return saved.Messages, nil
+}
+
+// ErrSessionNotFound is returned by SessionStore.Get for a session that
+// was never saved.
+var ErrSessionNotFound = errors.New("agent: session not found")
+
+// SessionStore keeps conversations by session ID, so a process that
+// restarts - or a request that lands on another replica - can carry on
+// where the last one stopped. NewMemorySessionStore and
+// NewFileSessionStore are ready-made; anything with a key-value API is
+// easy to add.
+type SessionStore interface {
+	// Get returns the history saved for id, or ErrSessionNotFound.
+	Get(ctx context.Context, id string) ([]llm.Message, error)
+	// Put saves history for id, replacing what was there.
+	Put(ctx context.Context, id string, history []llm.Message) error
+}
+
+// SaveSession saves the conversation in store under id.
+//
+//	defer a.SaveSession(ctx, store, userID)
+func (a *Agent) SaveSession(ctx context.Context, store SessionStore, id string) error {
+	if err := store.Put(ctx, id, a.History); err != nil {
+		return fmt.Errorf("agent: saving session %s: %w", id, err)
+	}
+	return nil
+}
+
+// LoadSession replaces the conversation with the one saved in store under
+// id. It returns ErrSessionNotFound (wrapped) for an unknown id and leaves
+// the history alone, so a new session just starts fresh:
+//
+//	if err := a.LoadSession(ctx, store, userID); err != nil && !errors.Is(err, agent.ErrSessionNotFound) {
+//	    return err
+//	}
+func (a *Agent) LoadSession(ctx context.Context, store SessionStore, id string) error {
+	history, err := store.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("agent: loading session %s: %w", id, err)
+	}
+	a.History = history
+	return nil
+}
+
+// MemorySessionStore keeps sessions in a map - for tests, and for
+// processes that only need sessions to outlive an Agent, not a restart.
+// It is safe for concurrent use.
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string][]llm.Message
+	updated  map[string]time.Time
+}
+
+// NewMemorySessionStore returns an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string][]llm.Message), updated: make(map[string]time.Time)}
+}
+
+func (s *MemorySessionStore) Get(ctx context.Context, id string) ([]llm.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	history, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return append([]llm.Message(nil), history...), nil
+}
+
+func (s *MemorySessionStore) Put(ctx context.Context, id string, history []llm.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A copy: the agent keeps appending to its own slice.
+	s.sessions[id] = append(make([]llm.Message, 0, len(history)), history...)
+	s.updated[id] = llm.Now(ctx)
+	return nil
+}
+
+// Updated returns when the session was last saved, or ErrSessionNotFound.
+func (s *MemorySessionStore) Updated(ctx context.Context, id string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.updated[id]
+	if !ok {
+		return time.Time{}, ErrSessionNotFound
+	}
+	return t, nil
+}
+
+// List returns the IDs of the saved sessions, sorted.
+func (s *MemorySessionStore) List(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete forgets a session. Deleting one that doesn't exist is not an error.
+func (s *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	delete(s.updated, id)
+	return nil
+}
+
+// FileSessionStore keeps each session in its own JSON file in a directory,
+// in the format SaveHistory writes. Files are replaced atomically, so a
+// crash mid-save leaves the previous version. It is safe for concurrent
+// use within one process.
+type FileSessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileSessionStore stores sessions in dir, creating it if needed.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("agent: session store: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// path maps a session ID to its file. IDs are escaped, so any string -
+// an email address, a URL - is a safe ID.
+func (s *FileSessionStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".json")
+}
+
+func (s *FileSessionStore) Get(ctx context.Context, id string) ([]llm.Message, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return DecodeHistory(data)
+}
+
+func (s *FileSessionStore) Put(ctx context.Context, id string, history []llm.Message) error {
+	data, err := json.MarshalIndent(savedHistory{Version: historyVersion, Messages: history}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(id))
+}
+
+// Updated returns when the session was last saved - its file's
+// modification time - or ErrSessionNotFound.
+func (s *FileSessionStore) Updated(ctx context.Context, id string) (time.Time, error) {
+	info, err := os.Stat(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// List returns the IDs of the saved sessions, sorted.
+func (s *FileSessionStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if id, err := url.PathUnescape(name); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete removes a session's file. Deleting one that doesn't exist is not
+// an error.
+func (s *FileSessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// WithSession ties the agent to a stored conversation: the first Run
+// loads the history saved under id (a new id starts from the system
+// prompt), and every Run saves it afterwards, even when the run fails
+// part-way. One agent per session - the usual shape for a request handler:
+//
+//	a := agent.New(provider, agent.WithSystemPrompts(prompt),
+//	    agent.WithSession(store, req.SessionID))
+//	reply, err := a.Run(ctx, req.Message)
+//
+// Any SessionStore works, including the Redis and SQL ones in
+// agent/session. A failed load or save fails the Run.
+func WithSession(store SessionStore, id string) Option {
+	return func(a *Agent) {
+		a.session = &boundSession{store: store, id: id}
+	}
+}
+
+// boundSession is the store and ID set with WithSession.
+type boundSession struct {
+	store  SessionStore
+	id     string
+	loaded bool
+}
+
+// loadSession loads the session's history before the first Run.
+func (a *Agent) loadSession(ctx context.Context) error {
+	if a.session == nil || a.session.loaded {
+		return nil
+	}
+	err := a.LoadSession(ctx, a.session.store, a.session.id)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	if err == nil && a.handoverOpts != nil {
+		history, err := a.handover(ctx, a.History, a.handoverOpts)
+		if err != nil {
+			return fmt.Errorf("agent: handing over session %s: %w", a.session.id, err)
+		}
+		a.History = history
+	}
+	a.session.loaded = true
+	return nil
+}
+
+// saveSession saves the history after a Run.
+func (a *Agent) saveSession(ctx context.Context) error {
+	if a.session == nil || !a.session.loaded {
+		return nil
+	}
+	return a.SaveSession(ctx, a.session.store, a.session.id)
+}
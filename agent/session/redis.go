@@ -0,0 +1,311 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redis stores each session as a string key holding its history, as JSON
+// unless WithRedisCodec says otherwise.
+// It speaks the Redis protocol itself over one connection, redialled when
+// it breaks, so it needs no client library; calls are serialized, which is
+// plenty for loading and saving a conversation per Run. It is safe for
+// concurrent use.
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	prefix   string
+	ttl      time.Duration
+	dial     func(ctx context.Context, network, addr string) (net.Conn, error)
+	codec    agent.HistoryCodec
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// RedisOption configures a Redis store.
+type RedisOption func(*Redis)
+
+// WithAuth logs in with AUTH. Leave username empty for a password-only
+// server.
+func WithAuth(username, password string) RedisOption {
+	return func(r *Redis) {
+		r.username = username
+		r.password = password
+	}
+}
+
+// WithDB selects a database other than 0.
+func WithDB(db int) RedisOption {
+	return func(r *Redis) {
+		r.db = db
+	}
+}
+
+// WithKeyPrefix puts sessions under keys other than "session:<id>".
+func WithKeyPrefix(prefix string) RedisOption {
+	return func(r *Redis) {
+		r.prefix = prefix
+	}
+}
+
+// WithTTL expires sessions that haven't been saved for d. Every Put
+// starts the clock again.
+func WithTTL(d time.Duration) RedisOption {
+	return func(r *Redis) {
+		r.ttl = d
+	}
+}
+
+// WithDialer connects with dial instead of a plain TCP dialer - for TLS,
+// say, with tls.Dialer.DialContext.
+func WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) RedisOption {
+	return func(r *Redis) {
+		r.dial = dial
+	}
+}
+
+// WithRedisCodec stores histories with codec instead of as JSON -
+// agent.ProtobufCodec, to save memory on a busy server. Sessions already
+// saved in the other format still load.
+func WithRedisCodec(codec agent.HistoryCodec) RedisOption {
+	return func(r *Redis) {
+		r.codec = codec
+	}
+}
+
+// NewRedis returns a store on the Redis server at addr ("host:port"). It
+// connects on first use.
+func NewRedis(addr string, opts ...RedisOption) *Redis {
+	var d net.Dialer
+	r := &Redis{addr: addr, prefix: "session:", dial: d.DialContext, codec: agent.JSONCodec}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Redis) Get(ctx context.Context, id string) ([]llm.Message, error) {
+	reply, err := r.do(ctx, "GET", r.prefix+id)
+	if err != nil {
+		return nil, fmt.Errorf("session: redis: %w", err)
+	}
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("session: redis: unexpected GET reply %v", reply)
+	}
+	return agent.DecodeHistory([]byte(data))
+}
+
+func (r *Redis) Put(ctx context.Context, id string, history []llm.Message) error {
+	data, err := r.codec.Encode(history)
+	if err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	args := []string{"SET", r.prefix + id, string(data)}
+	if r.ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(r.ttl.Milliseconds(), 10))
+	}
+	if _, err := r.do(ctx, args...); err != nil {
+		return fmt.Errorf("session: redis: %w", err)
+	}
+	return nil
+}
+
+// List walks the keys with SCAN, so it doesn't block the server the way
+// KEYS would.
+func (r *Redis) List(ctx context.Context) ([]string, error) {
+	var ids []string
+	cursor := "0"
+	for {
+		reply, err := r.do(ctx, "SCAN", cursor, "MATCH", escapeGlob(r.prefix)+"*", "COUNT", "500")
+		if err != nil {
+			return nil, fmt.Errorf("session: redis: %w", err)
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return nil, fmt.Errorf("session: redis: unexpected SCAN reply %v", reply)
+		}
+		keys, _ := page[1].([]any)
+		for _, k := range keys {
+			if key, ok := k.(string); ok {
+				ids = append(ids, strings.TrimPrefix(key, r.prefix))
+			}
+		}
+		if cursor, _ = page[0].(string); cursor == "0" || cursor == "" {
+			return ids, nil
+		}
+	}
+}
+
+func (r *Redis) Delete(ctx context.Context, id string) error {
+	if _, err := r.do(ctx, "DEL", r.prefix+id); err != nil {
+		return fmt.Errorf("session: redis: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection. The store reconnects if used again.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+// redisError is an error reply from the server. The connection is fine
+// after one, as long as it was the whole reply and not an item of an
+// array.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// do sends one command and reads its reply: a string, an int64, a []any,
+// or nil for a nil reply.
+func (r *Redis) do(ctx context.Context, args ...string) (any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		if err := r.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := r.roundTrip(ctx, args)
+	var re redisError
+	if err != nil && !errors.As(err, &re) {
+		// The connection is in an unknown state - drop it.
+		r.conn.Close()
+		r.conn = nil
+	}
+	return reply, err
+}
+
+func (r *Redis) connect(ctx context.Context) error {
+	conn, err := r.dial(ctx, "tcp", r.addr)
+	if err != nil {
+		return err
+	}
+	r.conn, r.rd = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	switch {
+	case r.username != "":
+		setup = append(setup, []string{"AUTH", r.username, r.password})
+	case r.password != "":
+		setup = append(setup, []string{"AUTH", r.password})
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, cmd := range setup {
+		if _, err := r.roundTrip(ctx, cmd); err != nil {
+			conn.Close()
+			r.conn = nil
+			return fmt.Errorf("%s: %w", cmd[0], err)
+		}
+	}
+	return nil
+}
+
+func (r *Redis) roundTrip(ctx context.Context, args []string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	r.conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(r.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(r.rd)
+}
+
+// readReply reads one RESP2 reply.
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch body := line[1:]; line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2) // and the trailing \r\n
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("bad array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(rd); err != nil {
+				// Not a redisError even if the item was one: the rest of
+				// the array is still unread, so the connection has to go.
+				return nil, fmt.Errorf("array item %d: %v", i, err)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown reply type %q", line[0])
+}
+
+// escapeGlob escapes the characters SCAN's MATCH treats specially.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,293 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"go-agent-sdk/llm"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis answers the commands the store sends over net.Pipe
+// connections. Its keys are shared by every connection; reply, if set,
+// answers a command instead, with a raw RESP reply.
+type fakeRedis struct {
+	mu       sync.Mutex
+	keys     map[string]string
+	dials    int
+	commands [][]string
+	reply    func(cmd []string) string
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{keys: make(map[string]string)}
+}
+
+func (f *fakeRedis) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+	f.mu.Lock()
+	f.dials++
+	f.mu.Unlock()
+	go f.serve(server)
+	return client, nil
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		cmd, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, cmd)
+		var reply string
+		if f.reply != nil {
+			reply = f.reply(cmd)
+		}
+		if reply == "" {
+			reply = f.answer(cmd)
+		}
+		f.mu.Unlock()
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// answer runs cmd against keys. f.mu is held.
+func (f *fakeRedis) answer(cmd []string) string {
+	switch strings.ToUpper(cmd[0]) {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.keys[cmd[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		f.keys[cmd[1]] = cmd[2]
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := f.keys[cmd[1]]
+		delete(f.keys, cmd[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "SCAN":
+		// Everything in one page; MATCH is always "<prefix>*" here.
+		prefix := strings.TrimSuffix(strings.ReplaceAll(cmd[3], `\`, ""), "*")
+		var b strings.Builder
+		var n int
+		for k := range f.keys {
+			if strings.HasPrefix(k, prefix) {
+				b.WriteString(bulk(k))
+				n++
+			}
+		}
+		return fmt.Sprintf("*2\r\n%s*%d\r\n%s", bulk("0"), n, b.String())
+	}
+	return "-ERR unknown command '" + cmd[0] + "'\r\n"
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// readCommand reads one command the way a server would: an array of bulk
+// strings.
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	reply, err := readReply(rd)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("command is a %T", reply)
+	}
+	cmd := make([]string, len(items))
+	for i, item := range items {
+		cmd[i], _ = item.(string)
+	}
+	return cmd, nil
+}
+
+func TestRedisRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis()
+	r := NewRedis("fake:6379", WithDialer(f.dial), WithAuth("agent", "secret"), WithDB(2))
+	defer r.Close()
+
+	if _, err := r.Get(ctx, "u1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing session = %v, want ErrNotFound", err)
+	}
+	history := []llm.Message{llm.NewUserMessage("hi"), llm.NewAssistantMessage("hello")}
+	if err := r.Put(ctx, "u1", history); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put(ctx, "u2", history[:1]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Get(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Content != "hi" || got[1].Content != "hello" {
+		t.Errorf("Get = %+v", got)
+	}
+
+	ids, err := r.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"u1", "u2"}) {
+		t.Errorf("List = %v", ids)
+	}
+	if err := r.Delete(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, "u1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dials != 1 {
+		t.Errorf("dialled %d times, want 1", f.dials)
+	}
+	if want := []string{"AUTH", "agent", "secret"}; !slices.Equal(f.commands[0], want) {
+		t.Errorf("first command = %q, want %q", f.commands[0], want)
+	}
+	if want := []string{"SELECT", "2"}; !slices.Equal(f.commands[1], want) {
+		t.Errorf("second command = %q, want %q", f.commands[1], want)
+	}
+	if _, ok := f.keys["session:u2"]; !ok {
+		t.Errorf("keys = %v, want session:u2", f.keys)
+	}
+}
+
+func TestRedisErrorReplies(t *testing.T) {
+	tests := []struct {
+		name     string
+		reply    string // the raw reply to the first GET
+		wantErr  string
+		redialed bool // whether the next command needs a new connection
+	}{
+		{
+			name:    "top-level error keeps the connection",
+			reply:   "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+			wantErr: "WRONGTYPE",
+		},
+		{
+			name:     "error inside an array drops it",
+			reply:    "*3\r\n$1\r\na\r\n-ERR nested\r\n$1\r\nb\r\n",
+			wantErr:  "array item 1: ERR nested",
+			redialed: true,
+		},
+		{
+			name:     "error inside a nested array drops it",
+			reply:    "*2\r\n*1\r\n-ERR deep\r\n:1\r\n",
+			wantErr:  "ERR deep",
+			redialed: true,
+		},
+		{
+			name:     "garbage drops it",
+			reply:    "?what\r\n",
+			wantErr:  "unknown reply type",
+			redialed: true,
+		},
+		{
+			name:     "bad integer drops it",
+			reply:    ":twelve\r\n",
+			wantErr:  "invalid syntax",
+			redialed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFakeRedis()
+			f.keys["session:u1"] = `[{"role":"user","content":"hi"}]`
+			sent := false
+			f.reply = func(cmd []string) string {
+				if cmd[0] == "GET" && !sent {
+					sent = true
+					return tt.reply
+				}
+				return ""
+			}
+			r := NewRedis("fake:6379", WithDialer(f.dial))
+			defer r.Close()
+
+			_, err := r.Get(ctx, "u1")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Get = %v, want an error containing %q", err, tt.wantErr)
+			}
+			// The next command must get its own reply, not the rest of
+			// the broken one.
+			got, err := r.Get(ctx, "u1")
+			if err != nil {
+				t.Fatalf("Get after the error: %v", err)
+			}
+			if len(got) != 1 || got[0].Content != "hi" {
+				t.Errorf("Get after the error = %+v", got)
+			}
+
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if want := map[bool]int{false: 1, true: 2}[tt.redialed]; f.dials != want {
+				t.Errorf("dialled %d times, want %d", f.dials, want)
+			}
+		})
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    any
+		wantErr string
+	}{
+		{in: "+OK\r\n", want: "OK"},
+		{in: ":42\r\n", want: int64(42)},
+		{in: "$5\r\nhello\r\n", want: "hello"},
+		{in: "$0\r\n\r\n", want: ""},
+		{in: "$-1\r\n", want: nil},
+		{in: "*-1\r\n", want: nil},
+		{in: "*2\r\n$1\r\na\r\n:1\r\n", want: []any{"a", int64(1)}},
+		{in: "*0\r\n", want: []any{}},
+		{in: "$12\r\nline\r\nbreaks\r\n", want: "line\r\nbreaks"},
+		{in: "-ERR bad\r\n", wantErr: "ERR bad"},
+		{in: "\r\n", wantErr: "empty reply"},
+		{in: "$x\r\n", wantErr: "bad bulk length"},
+		{in: "*x\r\n", wantErr: "bad array length"},
+		{in: "$5\r\nhel", wantErr: "EOF"},
+		{in: "*2\r\n:1\r\n", wantErr: "EOF"},
+	}
+	for _, tt := range tests {
+		got, err := readReply(bufio.NewReader(strings.NewReader(tt.in)))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readReply(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readReply(%q): %v", tt.in, err)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("readReply(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
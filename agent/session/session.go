@@ -0,0 +1,83 @@
+// Package session stores conversations by session ID, so they survive
+// restarts and can be served by any replica.
+//
+// A Store is an agent.SessionStore that can also list and delete
+// sessions. Pass one to agent.WithSession and the agent loads the history
+// before its first Run and saves it after every Run:
+//
+//	store := session.NewRedis("localhost:6379", session.WithTTL(24*time.Hour))
+//
+//	func handle(w http.ResponseWriter, r *http.Request) {
+//	    a := agent.New(provider, agent.WithSystemPrompts(prompt),
+//	        agent.WithSession(store, r.FormValue("session")))
+//	    reply, err := a.Run(r.Context(), r.FormValue("message"))
+//	    ...
+//	}
+//
+// NewRedis and NewSQL are here; agent.NewMemorySessionStore and
+// agent.NewFileSessionStore are Stores too.
+package session
+
+import (
+	"context"
+	"go-agent-sdk/agent"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a session that was never saved. It
+// is agent.ErrSessionNotFound, so the agent starts such sessions fresh.
+var ErrNotFound = agent.ErrSessionNotFound
+
+// Store keeps conversation histories by session ID.
+type Store interface {
+	agent.SessionStore // Get and Put
+
+	// List returns the IDs of the stored sessions.
+	List(ctx context.Context) ([]string, error)
+	// Delete removes a session. Deleting one that doesn't exist is not an
+	// error.
+	Delete(ctx context.Context, id string) error
+}
+
+// Dated is a Store that knows when each session was last saved, which is
+// what deleting old ones takes (see agent/compliance). Redis isn't one:
+// it expires sessions itself, with WithTTL.
+type Dated interface {
+	Store
+
+	// Updated returns when the session was last saved, or ErrNotFound.
+	Updated(ctx context.Context, id string) (time.Time, error)
+}
+
+var (
+	_ Dated = (*agent.MemorySessionStore)(nil)
+	_ Dated = (*agent.FileSessionStore)(nil)
+	_ Store = (*Redis)(nil)
+	_ Dated = (*SQL)(nil)
+)
+
+// Key returns the session ID for one of user's conversations,
+// "user/conversation", with the user part escaped. Session IDs made this
+// way say whose they are, so a user's sessions can be found to export or
+// delete them:
+//
+//	a := agent.New(provider, agent.WithSession(store, session.Key(userID, chatID)))
+func Key(user, conversation string) string {
+	return url.PathEscape(user) + "/" + conversation
+}
+
+// Owner returns the user a session ID belongs to: the user part of an ID
+// made with Key, or the whole ID if it has no "/" - one session per user,
+// keyed by their ID.
+func Owner(id string) string {
+	user, _, ok := strings.Cut(id, "/")
+	if !ok {
+		return id
+	}
+	if u, err := url.PathUnescape(user); err == nil {
+		return u
+	}
+	return user
+}
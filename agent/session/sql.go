@@ -0,0 +1,219 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"strconv"
+	"time"
+)
+
+// Dialect covers what differs between SQL databases for SQL: how
+// placeholders are written, which column types hold a long text or
+// binary data, and how an INSERT updates the row that's already there.
+type Dialect struct {
+	Placeholder func(n int) string // the n-th query parameter, from 1
+	TextType    string             // column type for the history JSON
+	BlobType    string             // column type for binary histories (WithSQLCodec)
+
+	// Upsert follows "INSERT INTO t (id, history, updated_at) VALUES
+	// (...)" to overwrite history and updated_at when the id exists.
+	// Without one, Put updates and then inserts, which two first saves of
+	// the same session can race on.
+	Upsert string
+}
+
+var (
+	// Postgres uses $1, $2, ..., TEXT, BYTEA and ON CONFLICT.
+	Postgres = Dialect{Placeholder: func(n int) string { return "$" + strconv.Itoa(n) }, TextType: "TEXT", BlobType: "BYTEA",
+		Upsert: "ON CONFLICT (id) DO UPDATE SET history = excluded.history, updated_at = excluded.updated_at"}
+	// MySQL uses ?, LONGTEXT, LONGBLOB - plain TEXT and BLOB stop at 64KB -
+	// and ON DUPLICATE KEY UPDATE.
+	MySQL = Dialect{Placeholder: func(int) string { return "?" }, TextType: "LONGTEXT", BlobType: "LONGBLOB",
+		Upsert: "ON DUPLICATE KEY UPDATE history = VALUES(history), updated_at = VALUES(updated_at)"}
+	// SQLite uses ?, TEXT, BLOB and ON CONFLICT, which needs SQLite 3.24.
+	SQLite = Dialect{Placeholder: func(int) string { return "?" }, TextType: "TEXT", BlobType: "BLOB",
+		Upsert: "ON CONFLICT (id) DO UPDATE SET history = excluded.history, updated_at = excluded.updated_at"}
+)
+
+// SQL stores sessions in a database/sql table with one row per session:
+//
+//	CREATE TABLE sessions (
+//	    id         VARCHAR(255) PRIMARY KEY,
+//	    history    TEXT NOT NULL,
+//	    updated_at TIMESTAMP NOT NULL
+//	)
+//
+// CreateTable makes it. Bring your own driver:
+//
+//	db, err := sql.Open("pgx", dsn)
+//	store := session.NewSQL(db, session.Postgres)
+//	err = store.CreateTable(ctx)
+type SQL struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+	codec   agent.HistoryCodec
+	now     func() time.Time
+}
+
+// SQLOption configures a SQL store.
+type SQLOption func(*SQL)
+
+// WithTable uses a table other than "sessions". The name goes into the
+// queries as it is, so don't take it from user input.
+func WithTable(name string) SQLOption {
+	return func(s *SQL) {
+		s.table = name
+	}
+}
+
+// WithSQLCodec stores histories with codec instead of as JSON. A binary
+// codec like agent.ProtobufCodec needs a binary history column: CreateTable
+// makes one of the dialect's BlobType, and an existing table has to be
+// altered to match. Rows saved as JSON still load.
+func WithSQLCodec(codec agent.HistoryCodec) SQLOption {
+	return func(s *SQL) {
+		s.codec = codec
+	}
+}
+
+// NewSQL returns a store on db, writing queries for dialect.
+func NewSQL(db *sql.DB, dialect Dialect, opts ...SQLOption) *SQL {
+	s := &SQL{db: db, dialect: dialect, table: "sessions", codec: agent.JSONCodec, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateTable creates the sessions table if it doesn't exist.
+func (s *SQL) CreateTable(ctx context.Context) error {
+	historyType := s.dialect.TextType
+	if s.binary() {
+		historyType = s.dialect.BlobType
+	}
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id VARCHAR(255) PRIMARY KEY, history %s NOT NULL, updated_at TIMESTAMP NOT NULL)",
+		s.table, historyType))
+	if err != nil {
+		return fmt.Errorf("session: sql: %w", err)
+	}
+	return nil
+}
+
+// binary reports whether the codec writes bytes a text column can't hold.
+func (s *SQL) binary() bool {
+	return s.codec != agent.JSONCodec
+}
+
+func (s *SQL) p(n int) string {
+	return s.dialect.Placeholder(n)
+}
+
+func (s *SQL) Get(ctx context.Context, id string) ([]llm.Message, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT history FROM %s WHERE id = %s", s.table, s.p(1)), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session: sql: %w", err)
+	}
+	return agent.DecodeHistory(data)
+}
+
+// Put inserts the session's row, or overwrites it if there is one, in a
+// single statement with the dialect's Upsert. A Dialect without one gets
+// an UPDATE and, when that touches nothing, an INSERT, in one transaction.
+func (s *SQL) Put(ctx context.Context, id string, history []llm.Message) error {
+	encoded, err := s.codec.Encode(history)
+	if err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	// Text columns want a string, binary ones bytes.
+	var data any = string(encoded)
+	if s.binary() {
+		data = encoded
+	}
+	now := s.now().UTC()
+
+	insert := fmt.Sprintf("INSERT INTO %s (id, history, updated_at) VALUES (%s, %s, %s)", s.table, s.p(1), s.p(2), s.p(3))
+	if s.dialect.Upsert != "" {
+		if _, err := s.db.ExecContext(ctx, insert+" "+s.dialect.Upsert, id, data, now); err != nil {
+			return fmt.Errorf("session: sql: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("session: sql: %w", err)
+	}
+	defer tx.Rollback() // a no-op after Commit
+
+	res, err := tx.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET history = %s, updated_at = %s WHERE id = %s", s.table, s.p(1), s.p(2), s.p(3)),
+		data, now, id)
+	if err != nil {
+		return fmt.Errorf("session: sql: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := tx.ExecContext(ctx, insert, id, data, now); err != nil {
+			return fmt.Errorf("session: sql: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("session: sql: %w", err)
+	}
+	return nil
+}
+
+// List returns the session IDs, most recently saved first.
+func (s *SQL) List(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT id FROM %s ORDER BY updated_at DESC", s.table))
+	if err != nil {
+		return nil, fmt.Errorf("session: sql: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("session: sql: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("session: sql: %w", err)
+	}
+	return ids, nil
+}
+
+// Updated returns when the session was last saved, from its updated_at
+// column, or ErrNotFound.
+func (s *SQL) Updated(ctx context.Context, id string) (time.Time, error) {
+	var t time.Time
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT updated_at FROM %s WHERE id = %s", s.table, s.p(1)), id).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("session: sql: %w", err)
+	}
+	return t, nil
+}
+
+func (s *SQL) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = %s", s.table, s.p(1)), id)
+	if err != nil {
+		return fmt.Errorf("session: sql: %w", err)
+	}
+	return nil
+}
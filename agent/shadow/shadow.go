@@ -0,0 +1,325 @@
+// Package shadow tries a candidate model or prompt on real traffic without
+// showing it to anyone. A sample of the agent's runs is replayed against
+// the candidate in the background - the same requests, turn by turn - and
+// each candidate response is compared with the one users got. The running
+// report says how often the two agree: that's the drift a model upgrade
+// or prompt change would cause.
+//
+//	sh := shadow.New(candidate,
+//	    shadow.WithSampleRate(0.1),
+//	    shadow.WithComparator(shadow.JudgeComparator(judge.New(strong))),
+//	    shadow.OnDrift(0.5, func(r shadow.Result) { log.Printf("drift on run %s: %s", r.RunID, r.Reason) }),
+//	)
+//	a := agent.New(provider, agent.WithObserver(sh))
+//	...
+//	fmt.Printf("%+v\n", sh.Report())
+//
+// Tools aren't run again - the candidate sees the tool results the
+// production model got - so shadowing has no side effects. It does cost
+// the candidate's tokens.
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/agent/judge"
+	"go-agent-sdk/llm"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Result is one shadowed LLM call.
+type Result struct {
+	RunID string
+	Turn  int // index into the run's Turns
+
+	Primary llm.Message // what production answered
+	Shadow  llm.Message // what the candidate answered
+
+	Score  float64 // agreement from 0 to 1, by the Comparator
+	Reason string  // the comparator's explanation, if it gives one
+
+	PrimaryLatency time.Duration
+	ShadowLatency  time.Duration
+	Usage          llm.Usage // the candidate's tokens
+	Err            error     // the candidate or the comparison failed; Score is 0
+}
+
+// Comparator scores how well the candidate's answer agrees with the
+// production one. req is the request both got.
+type Comparator func(ctx context.Context, req llm.ChatRequest, primary, shadow llm.Message) (score float64, reason string, err error)
+
+// Report sums up the shadowed calls so far.
+type Report struct {
+	Runs      int     // runs shadowed
+	Calls     int     // LLM calls shadowed
+	Errors    int     // calls the candidate or comparator failed on
+	MeanScore float64 // mean agreement over the calls without errors
+	Drifted   int     // calls scoring below the OnDrift threshold
+
+	PrimaryLatency time.Duration // mean
+	ShadowLatency  time.Duration // mean
+	Usage          llm.Usage     // the candidate's tokens in total
+}
+
+// Shadow replays sampled runs against a candidate provider. It is an
+// agent.Observer: attach it with agent.WithObserver.
+type Shadow struct {
+	provider     llm.ChatProvider
+	systemPrompt string
+	rate         float64
+	compare      Comparator
+	threshold    float64
+	onDrift      func(Result)
+	onResult     func(Result)
+
+	mu       sync.Mutex
+	report   Report
+	scoreSum float64
+	primSum  time.Duration
+	shadSum  time.Duration
+}
+
+// Option configures a Shadow.
+type Option func(*Shadow)
+
+// WithSampleRate shadows this fraction of runs, from 0 to 1. The default
+// is 1, every run. Sampling goes by run ID, so the same runs are picked
+// whatever the order.
+func WithSampleRate(rate float64) Option {
+	return func(s *Shadow) {
+		s.rate = rate
+	}
+}
+
+// WithSystemPrompt gives the candidate a different system prompt - to try
+// a prompt change rather than, or as well as, a model change.
+func WithSystemPrompt(prompt string) Option {
+	return func(s *Shadow) {
+		s.systemPrompt = prompt
+	}
+}
+
+// WithComparator sets how answers are compared. The default is
+// SimilarityComparator.
+func WithComparator(c Comparator) Option {
+	return func(s *Shadow) {
+		s.compare = c
+	}
+}
+
+// OnDrift calls fn for every call scoring below threshold, and counts
+// them in Report.Drifted.
+func OnDrift(threshold float64, fn func(Result)) Option {
+	return func(s *Shadow) {
+		s.threshold = threshold
+		s.onDrift = fn
+	}
+}
+
+// OnResult calls fn for every shadowed call - to log them all, or to
+// keep your own statistics.
+func OnResult(fn func(Result)) Option {
+	return func(s *Shadow) {
+		s.onResult = fn
+	}
+}
+
+// New returns a Shadow that replays runs against provider.
+func New(provider llm.ChatProvider, opts ...Option) *Shadow {
+	s := &Shadow{provider: provider, rate: 1, compare: SimilarityComparator}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Observe shadows the run, if it's sampled. The agent calls it in the
+// background after each Run.
+func (s *Shadow) Observe(ctx context.Context, obs agent.Observation) {
+	res := obs.Result
+	if len(res.Turns) == 0 || !s.sampled(res.ID) {
+		return
+	}
+	s.mu.Lock()
+	s.report.Runs++
+	s.mu.Unlock()
+
+	for i, turn := range res.Turns {
+		if len(turn.Response.Choices) == 0 {
+			continue
+		}
+		r := s.shadowTurn(ctx, res.ID, i, turn)
+		s.record(r)
+	}
+}
+
+func (s *Shadow) sampled(runID string) bool {
+	if s.rate >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(runID))
+	return float64(h.Sum32()%10000) < s.rate*10000
+}
+
+// shadowTurn sends one turn's request to the candidate and compares.
+func (s *Shadow) shadowTurn(ctx context.Context, runID string, i int, turn agent.Turn) Result {
+	req := turn.Request
+	req.Model = s.provider.ModelName()
+	req.Stream = false
+	if s.systemPrompt != "" {
+		req.Messages = withSystemPrompt(req.Messages, s.systemPrompt)
+	}
+
+	r := Result{RunID: runID, Turn: i, Primary: turn.Response.Choices[0].Message, PrimaryLatency: turn.Latency}
+	start := time.Now()
+	resp, err := s.provider.CreateChat(ctx, req)
+	r.ShadowLatency = time.Since(start)
+	if err != nil {
+		r.Err = fmt.Errorf("shadow: %w", err)
+		return r
+	}
+	r.Usage = resp.Usage
+	if len(resp.Choices) == 0 {
+		r.Err = fmt.Errorf("shadow: %w", agent.ErrNoChoices)
+		return r
+	}
+	r.Shadow = resp.Choices[0].Message
+
+	r.Score, r.Reason, err = s.compare(ctx, turn.Request, r.Primary, r.Shadow)
+	if err != nil {
+		r.Score, r.Err = 0, fmt.Errorf("shadow: comparing: %w", err)
+	}
+	return r
+}
+
+func (s *Shadow) record(r Result) {
+	drifted := r.Err == nil && s.onDrift != nil && r.Score < s.threshold
+
+	s.mu.Lock()
+	s.report.Calls++
+	s.report.Usage = s.report.Usage.Add(r.Usage)
+	s.primSum += r.PrimaryLatency
+	s.shadSum += r.ShadowLatency
+	if r.Err != nil {
+		s.report.Errors++
+	} else {
+		s.scoreSum += r.Score
+	}
+	if drifted {
+		s.report.Drifted++
+	}
+	s.mu.Unlock()
+
+	if s.onResult != nil {
+		s.onResult(r)
+	}
+	if drifted {
+		s.onDrift(r)
+	}
+}
+
+// Report returns the summary so far. Call agent.WaitObservers first to
+// include runs still being shadowed.
+func (s *Shadow) Report() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.report
+	if ok := r.Calls - r.Errors; ok > 0 {
+		r.MeanScore = s.scoreSum / float64(ok)
+	}
+	if r.Calls > 0 {
+		r.PrimaryLatency = s.primSum / time.Duration(r.Calls)
+		r.ShadowLatency = s.shadSum / time.Duration(r.Calls)
+	}
+	return r
+}
+
+// withSystemPrompt replaces the leading system message, or adds one.
+func withSystemPrompt(msgs []llm.Message, prompt string) []llm.Message {
+	out := append([]llm.Message{llm.NewSystemMessage(prompt)}, msgs...)
+	if len(msgs) > 0 && msgs[0].Role == "system" {
+		out = append(out[:1], msgs[1:]...)
+	}
+	return out
+}
+
+// SimilarityComparator compares answers without a model. Tool calls agree
+// as far as the same tools are called - full marks when the arguments
+// match too, half when they don't. Text answers score judge.Similarity.
+// A tool call against a text answer scores 0.
+func SimilarityComparator(ctx context.Context, req llm.ChatRequest, primary, shadow llm.Message) (float64, string, error) {
+	if score, reason, ok := compareToolCalls(primary, shadow); ok {
+		return score, reason, nil
+	}
+	return judge.Similarity(primary.Content, shadow.Content), "", nil
+}
+
+// JudgeComparator compares text answers with j, and tool calls the way
+// SimilarityComparator does.
+func JudgeComparator(j *judge.Judge) Comparator {
+	return func(ctx context.Context, req llm.ChatRequest, primary, shadow llm.Message) (float64, string, error) {
+		if score, reason, ok := compareToolCalls(primary, shadow); ok {
+			return score, reason, nil
+		}
+		v, err := j.Compare(ctx, lastUserMessage(req.Messages), primary.Content, shadow.Content)
+		return v.Score, v.Reason, err
+	}
+}
+
+// compareToolCalls scores answers where either side called tools. ok is
+// false when neither did.
+func compareToolCalls(primary, shadow llm.Message) (score float64, reason string, ok bool) {
+	p, s := len(primary.ToolCalls) > 0, len(shadow.ToolCalls) > 0
+	switch {
+	case !p && !s:
+		return 0, "", false
+	case p != s:
+		return 0, "one called tools, the other answered", true
+	}
+
+	names := func(m llm.Message) []string {
+		var out []string
+		for _, c := range m.ToolCalls {
+			out = append(out, c.Function.Name)
+		}
+		slices.Sort(out)
+		return out
+	}
+	if !slices.Equal(names(primary), names(shadow)) {
+		return 0, fmt.Sprintf("called %v instead of %v", names(shadow), names(primary)), true
+	}
+	for _, c := range primary.ToolCalls {
+		match := slices.ContainsFunc(shadow.ToolCalls, func(sc llm.ToolCall) bool {
+			return sc.Function.Name == c.Function.Name && sameJSON(sc.Function.Arguments, c.Function.Arguments)
+		})
+		if !match {
+			return 0.5, "same tools, different arguments", true
+		}
+	}
+	return 1, "", true
+}
+
+func sameJSON(a, b string) bool {
+	var va, vb any
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return a == b
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return string(ja) == string(jb)
+}
+
+func lastUserMessage(msgs []llm.Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "user" {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,285 @@
+// Package swarm lets agents hand a conversation to each other. Each agent
+// in a Swarm gets a transfer_to_agent tool; when it decides a peer is
+// better placed to help - billing questions to the billing agent, a
+// refund to the agent allowed to issue one - it calls the tool, and the
+// Swarm carries on the conversation with that peer. The whole history
+// goes with it, so the user never repeats themselves.
+//
+//	triage := agent.New(cheapModel, agent.WithSystemPrompts("Work out what the customer needs and transfer them."))
+//	billing := agent.New(model, agent.WithSystemPrompts("You handle invoices and payments."))
+//	billing.RegisterTool("lookup_invoice", "Find an invoice", LookupInvoice)
+//
+//	s := swarm.New()
+//	s.Add("triage", "Greets customers and routes them", triage)
+//	s.Add("billing", "Invoices, payments and refunds", billing)
+//
+//	res, err := s.Run(ctx, "I was charged twice last month")
+//	fmt.Println(res.Agent, "says:", res.Content) // billing says: ...
+//
+// The Swarm remembers who owns the conversation, so the next Run goes
+// straight to the agent that answered last. Like an Agent, a Swarm holds
+// one conversation and is not safe for concurrent use.
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/tools"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxHandoffs is how many times the conversation may change hands
+// in one Run unless WithMaxHandoffs says otherwise. Agents that keep
+// passing it back and forth are stuck, not busy.
+const DefaultMaxHandoffs = 5
+
+// ErrTooManyHandoffs is returned by Run when the agents hand the
+// conversation on more than the allowed number of times.
+var ErrTooManyHandoffs = errors.New("swarm: too many handoffs")
+
+// Handoff is one transfer of the conversation.
+type Handoff struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Result is the outcome of a Run.
+type Result struct {
+	Agent    string             // who gave the final answer, and owns the conversation now
+	Content  string             // the final answer
+	Handoffs []Handoff          // transfers during the run, in order
+	Runs     []*agent.RunResult // one per agent turn, in order
+	Usage    llm.Usage          // tokens across every agent turn
+}
+
+// Option configures a Swarm.
+type Option func(*Swarm)
+
+// WithMaxHandoffs changes how many handoffs one Run allows.
+func WithMaxHandoffs(n int) Option {
+	return func(s *Swarm) {
+		s.maxHandoffs = n
+	}
+}
+
+// WithOnHandoff calls fn every time the conversation changes hands, for
+// logging or to tell the user who they're talking to now.
+func WithOnHandoff(fn func(Handoff)) Option {
+	return func(s *Swarm) {
+		s.onHandoff = fn
+	}
+}
+
+// Swarm is a set of agents sharing one conversation.
+type Swarm struct {
+	members     map[string]*member
+	order       []string // names, in the order they were added
+	active      string
+	history     []llm.Message // the shared conversation, without system prompts
+	maxHandoffs int
+	onHandoff   func(Handoff)
+
+	mu      sync.Mutex
+	pending *Handoff // requested by a tool in the current turn, to refuse a second
+}
+
+type member struct {
+	description string
+	agent       *agent.Agent
+}
+
+// New returns an empty Swarm.
+func New(opts ...Option) *Swarm {
+	s := &Swarm{members: make(map[string]*member), maxHandoffs: DefaultMaxHandoffs}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// TransferArgs are the transfer_to_agent tool's arguments.
+type TransferArgs struct {
+	Agent  string `json:"agent" description:"Name of the agent to hand the conversation to."`
+	Reason string `json:"reason,omitempty" description:"Why, in a sentence - the other agent sees this."`
+}
+
+// Add puts a in the swarm as name. The description tells the other agents
+// what it's for, so they know when to transfer to it. The first agent
+// added owns the conversation until someone hands it on (see SetActive).
+//
+// Add registers the transfer_to_agent tool on a, with guidance listing
+// its peers. An agent belongs in one swarm at a time.
+func (s *Swarm) Add(name, description string, a *agent.Agent) error {
+	if name == "" {
+		return errors.New("swarm: agent name is empty")
+	}
+	if _, ok := s.members[name]; ok {
+		return fmt.Errorf("swarm: agent %q already added", name)
+	}
+	err := a.RegisterToolset(tools.Toolset{
+		Name:       "swarm",
+		PromptFunc: func() string { return s.prompt(name) },
+		Tools: []tools.Tool{{
+			Name:        "transfer_to_agent",
+			Description: "Hand the conversation to another agent who is better suited to help. They see the whole conversation.",
+			Func: func(ctx context.Context, args TransferArgs) (string, error) {
+				return s.transfer(ctx, name, args)
+			},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("swarm: %w", err)
+	}
+	s.members[name] = &member{description: description, agent: a}
+	s.order = append(s.order, name)
+	if s.active == "" {
+		s.active = name
+	}
+	return nil
+}
+
+// Active returns the name of the agent that owns the conversation.
+func (s *Swarm) Active() string {
+	return s.active
+}
+
+// SetActive gives the conversation to the named agent, for the next Run.
+func (s *Swarm) SetActive(name string) error {
+	if _, ok := s.members[name]; !ok {
+		return fmt.Errorf("swarm: no agent %q", name)
+	}
+	s.active = name
+	return nil
+}
+
+// History returns the shared conversation so far, without any agent's
+// system prompt.
+func (s *Swarm) History() []llm.Message {
+	return append([]llm.Message(nil), s.history...)
+}
+
+// Run sends msg to the agent that owns the conversation and follows any
+// handoffs until an agent answers. Run(ctx, "") carries on without a new
+// message, e.g. after an agent was interrupted.
+//
+// Errors from an agent are returned as they are, with the conversation
+// kept and still owned by that agent - an *agent.InterruptError from an
+// escalation, say, can be dealt with and the Run resumed.
+func (s *Swarm) Run(ctx context.Context, msg string, opts ...agent.RunOption) (*Result, error) {
+	if s.active == "" {
+		return nil, errors.New("swarm: no agents")
+	}
+	res := &Result{}
+	for {
+		m := s.members[s.active]
+		s.load(m.agent)
+		run, err := m.agent.RunWithResult(ctx, msg, opts...)
+		s.save(m.agent)
+		msg = "" // the user's message is in the history now
+		if run != nil {
+			res.Runs = append(res.Runs, run)
+			res.Usage = res.Usage.Add(run.Usage)
+		}
+		res.Agent = s.active
+
+		s.clearPending()
+		var h Handoff
+		var ie *agent.InterruptError
+		if errors.As(err, &ie) {
+			h, _ = ie.Payload.(Handoff)
+		}
+		if h.To == "" {
+			if err != nil {
+				return res, err
+			}
+			res.Content = run.Content
+			return res, nil
+		}
+
+		if len(res.Handoffs) >= s.maxHandoffs {
+			return res, fmt.Errorf("%w: %d in one run, last %s to %s", ErrTooManyHandoffs, len(res.Handoffs)+1, h.From, h.To)
+		}
+		res.Handoffs = append(res.Handoffs, h)
+		s.active = h.To
+		if s.onHandoff != nil {
+			s.onHandoff(h)
+		}
+	}
+}
+
+// transfer is the transfer_to_agent tool of the agent called from.
+func (s *Swarm) transfer(ctx context.Context, from string, args TransferArgs) (string, error) {
+	to := strings.TrimSpace(args.Agent)
+	if to == from {
+		return "", errors.New("you already have the conversation; pick another agent")
+	}
+	if _, ok := s.members[to]; !ok {
+		return "", fmt.Errorf("there is no agent %q; the agents are: %s", to, strings.Join(s.peers(from), ", "))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending != nil {
+		return "", fmt.Errorf("the conversation is already being handed to %s", s.pending.To)
+	}
+	h := Handoff{From: from, To: to, Reason: args.Reason}
+	if !agent.RequestInterrupt(ctx, agent.Interrupt{Reason: "handoff to " + to, Payload: h}) {
+		return "", errors.New("transfers only work inside a swarm run")
+	}
+	s.pending = &h
+	return fmt.Sprintf("Transferred the conversation to %s.", to), nil
+}
+
+func (s *Swarm) clearPending() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = nil
+}
+
+// prompt is the swarm guidance for the named agent.
+func (s *Swarm) prompt(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are %s, one of a team of agents sharing this conversation. "+
+		"If another agent is better suited to what the user needs, hand the conversation over with transfer_to_agent "+
+		"instead of answering yourself. Don't mention the transfer mechanics to the user.\n\nThe other agents:", name)
+	for _, p := range s.peers(name) {
+		fmt.Fprintf(&b, "\n- %s: %s", p, s.members[p].description)
+	}
+	return b.String()
+}
+
+func (s *Swarm) peers(name string) []string {
+	var out []string
+	for _, n := range s.order {
+		if n != name {
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// load gives a the shared conversation, behind its own system prompt.
+func (s *Swarm) load(a *agent.Agent) {
+	history := make([]llm.Message, 0, len(s.history)+1)
+	if a.SystemPrompt != "" {
+		history = append(history, llm.NewSystemMessage(a.SystemPrompt))
+	}
+	a.History = append(history, s.history...)
+}
+
+// save takes the conversation back from a, leaving out its system prompt.
+func (s *Swarm) save(a *agent.Agent) {
+	s.history = s.history[:0:0]
+	for _, m := range a.History {
+		if m.Role != "system" {
+			s.history = append(s.history, m)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package tagging labels each user message with its intent and sentiment,
+// using a cheap model, so you can route conversations and chart what
+// users want without a separate classification service.
+//
+//	tagger := tagging.New(cheapProvider,
+//	    tagging.WithIntents("billing", "technical_support", "cancel", "other"))
+//	a := agent.New(provider, agent.WithMessageMiddleware(tagger))
+//
+//	reply, err := a.Run(ctx, "I was charged twice and I'm furious")
+//	// the user message in a.History now has
+//	// Metadata{"intent": "billing", "sentiment": "negative"}
+//
+// The tags go in the message's Metadata, which is saved with the history
+// but never sent to the model.
+package tagging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"slices"
+	"strings"
+)
+
+// Metadata keys the tagger writes.
+const (
+	IntentKey    = "intent"
+	SentimentKey = "sentiment"
+)
+
+// Sentiments.
+const (
+	Positive = "positive"
+	Neutral  = "neutral"
+	Negative = "negative"
+)
+
+// Tags is what the classifier decided about one message.
+type Tags struct {
+	Intent    string `json:"intent"`
+	Sentiment string `json:"sentiment"`
+}
+
+// Tagger classifies messages. Use it as an agent.MessageMiddleware via
+// New, or call Classify directly.
+type Tagger struct {
+	provider llm.ChatProvider
+	intents  []string
+	onError  func(error)
+}
+
+// Option configures a Tagger.
+type Option func(*Tagger)
+
+// WithIntents limits intents to a fixed set, which is what you want for
+// routing. Without it the model names the intent freely, in a word or two.
+func WithIntents(intents ...string) Option {
+	return func(t *Tagger) {
+		t.intents = intents
+	}
+}
+
+// OnError is called when classifying a message fails. The message then
+// goes through untagged - tagging never fails a Run.
+func OnError(fn func(error)) Option {
+	return func(t *Tagger) {
+		t.onError = fn
+	}
+}
+
+// NewTagger returns a Tagger that classifies with provider.
+func NewTagger(provider llm.ChatProvider, opts ...Option) *Tagger {
+	t := &Tagger{provider: provider}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// New returns a middleware that tags every user message with
+// IntentKey and SentimentKey.
+func New(provider llm.ChatProvider, opts ...Option) agent.MessageMiddleware {
+	return NewTagger(provider, opts...).Middleware
+}
+
+// Middleware tags msg. Tags already set on it are kept.
+func (t *Tagger) Middleware(ctx context.Context, msg *llm.Message) error {
+	tags, err := t.Classify(ctx, msg.Content)
+	if err != nil {
+		if t.onError != nil {
+			t.onError(err)
+		}
+		return nil
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]string)
+	}
+	setDefault(msg.Metadata, IntentKey, tags.Intent)
+	setDefault(msg.Metadata, SentimentKey, tags.Sentiment)
+	return nil
+}
+
+func setDefault(m map[string]string, key, value string) {
+	if _, ok := m[key]; !ok && value != "" {
+		m[key] = value
+	}
+}
+
+// Classify asks the model for text's intent and sentiment.
+func (t *Tagger) Classify(ctx context.Context, text string) (Tags, error) {
+	intent := map[string]any{"type": "string", "description": "What the user wants, in a word or two, snake_case"}
+	if len(t.intents) > 0 {
+		intent = map[string]any{"type": "string", "enum": t.intents}
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"intent":    intent,
+			"sentiment": map[string]any{"type": "string", "enum": []string{Positive, Neutral, Negative}},
+		},
+		"required":             []string{"intent", "sentiment"},
+		"additionalProperties": false,
+	}
+
+	req := llm.ChatRequest{
+		Model: t.provider.ModelName(),
+		Messages: []llm.Message{
+			llm.NewSystemMessage("Classify the user's message for a customer conversation. " +
+				"Reply with JSON: the intent and the sentiment (positive, neutral or negative)." + t.intentHint()),
+			llm.NewUserMessage(text),
+		},
+		ResponseFormat: llm.NewJSONSchemaFormat("message_tags", schema),
+	}
+	resp, err := t.provider.CreateChat(ctx, req)
+	if err != nil {
+		return Tags{}, fmt.Errorf("tagging: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return Tags{}, fmt.Errorf("tagging: %w", agent.ErrNoChoices)
+	}
+
+	var tags Tags
+	content := strings.TrimSpace(resp.Choices[0].Message.Content)
+	if err := json.Unmarshal([]byte(content), &tags); err != nil {
+		return Tags{}, fmt.Errorf("tagging: bad classification %q: %w", content, err)
+	}
+	tags.Sentiment = strings.ToLower(tags.Sentiment)
+	if !slices.Contains([]string{Positive, Neutral, Negative}, tags.Sentiment) {
+		tags.Sentiment = ""
+	}
+	if len(t.intents) > 0 && !slices.Contains(t.intents, tags.Intent) {
+		tags.Intent = ""
+	}
+	return tags, nil
+}
+
+// intentHint lists the allowed intents for providers that don't enforce
+// the schema.
+func (t *Tagger) intentHint() string {
+	if len(t.intents) == 0 {
+		return ""
+	}
+	return " The intent must be one of: " + strings.Join(t.intents, ", ") + "."
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+)
+
+// Validator checks a final answer against a contract the code downstream
+// depends on - it must be valid SQL, must parse as Go, must be JSON of
+// some shape. A non-nil error rejects the answer; its message is what the
+// model is told, so make it say what's wrong and where.
+type Validator func(ctx context.Context, answer string) error
+
+// DefaultMaxRepairs is how many times the agent asks the model to fix a
+// rejected answer before giving up, unless WithMaxRepairs says otherwise.
+const DefaultMaxRepairs = 2
+
+// WithValidator checks every final answer with each validator, in order.
+// When one rejects it, the agent shows the model the error and asks for a
+// corrected answer - up to WithMaxRepairs times - and returns the first
+// answer every validator accepts:
+//
+//	a := agent.New(provider,
+//	    agent.WithSystemPrompts("Answer with a single SQLite query and nothing else."),
+//	    agent.WithValidator(func(ctx context.Context, answer string) error {
+//	        _, err := db.PrepareContext(ctx, answer)
+//	        return err
+//	    }),
+//	)
+//
+// If repairs run out, Run fails with a *ValidationError. The rejected
+// answers and the feedback on them stay in the history, like any other
+// turn.
+//
+// Validators see the answer before WithAnswerLimit shortens it.
+func WithValidator(v ...Validator) Option {
+	return func(a *Agent) {
+		a.validators = append(a.validators, v...)
+	}
+}
+
+// WithMaxRepairs sets how many corrected answers the agent asks for when a
+// validator rejects one. 0 fails the run on the first rejection.
+func WithMaxRepairs(n int) Option {
+	return func(a *Agent) {
+		a.maxRepairs = n
+	}
+}
+
+// ValidationError is returned by Run when the final answer still fails a
+// validator after every repair attempt.
+type ValidationError struct {
+	Answer   string // the last answer the model gave
+	Attempts int    // answers rejected, including the last
+	Err      error  // what the validator said about the last one
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("agent: answer rejected after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// validateAnswer runs the validators on answer, stopping at the first
+// that rejects it.
+func (a *Agent) validateAnswer(ctx context.Context, answer string) error {
+	for _, v := range a.validators {
+		if err := v(ctx, answer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repairRequest is the message that tells the model its answer was
+// rejected.
+func repairRequest(err error) llm.Message {
+	return llm.NewUserMessage(fmt.Sprintf(
+		"Your answer was rejected: %v\n\nFix the problem and give the complete corrected answer, in the same format.", err))
+}
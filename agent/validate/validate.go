@@ -0,0 +1,96 @@
+// Package validate has ready-made agent.Validators for common output
+// contracts: JSON, JSON of a given shape, Go source, and text matching a
+// pattern.
+//
+//	a := agent.New(provider,
+//	    agent.WithSystemPrompts("Reply with the invoice as JSON."),
+//	    agent.WithValidator(validate.JSONOf(Invoice{})),
+//	)
+//
+// Models often wrap code and JSON in a Markdown code fence even when told
+// not to. The validators here look inside a fence that wraps the whole
+// answer; Unfence does the same for the code that uses the answer.
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/tools/jsonschema"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// Unfence returns the content of a Markdown code fence that wraps the
+// whole answer, or the answer trimmed if there isn't one.
+func Unfence(answer string) string {
+	s := strings.TrimSpace(answer)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	s = strings.TrimSuffix(s[3:], "```")
+	// Drop the info string ("json", "go", ...) on the opening line.
+	if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+		s = s[nl+1:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// JSON accepts answers that are a single valid JSON value.
+func JSON() agent.Validator {
+	return func(_ context.Context, answer string) error {
+		var v any
+		if err := json.Unmarshal([]byte(Unfence(answer)), &v); err != nil {
+			return fmt.Errorf("the answer must be valid JSON and nothing else: %v", err)
+		}
+		return nil
+	}
+}
+
+// JSONSchema accepts JSON answers that match schema, reporting every
+// field that doesn't.
+func JSONSchema(schema map[string]any) agent.Validator {
+	return func(_ context.Context, answer string) error {
+		data := []byte(Unfence(answer))
+		if !json.Valid(data) {
+			return fmt.Errorf("the answer must be valid JSON and nothing else")
+		}
+		if err := jsonschema.Validate(schema, data); err != nil {
+			return fmt.Errorf("the JSON doesn't match the required schema: %v", err)
+		}
+		return nil
+	}
+}
+
+// JSONOf is JSONSchema with the schema of v's type, as jsonschema.Of
+// generates it.
+func JSONOf(v any) agent.Validator {
+	return JSONSchema(jsonschema.Of(v))
+}
+
+// GoSource accepts answers that parse as a Go source file, package
+// clause included. It checks syntax only - it doesn't type-check or
+// resolve imports.
+func GoSource() agent.Validator {
+	return func(_ context.Context, answer string) error {
+		fset := token.NewFileSet()
+		if _, err := parser.ParseFile(fset, "answer.go", Unfence(answer), parser.AllErrors); err != nil {
+			return fmt.Errorf("the answer must be a complete, syntactically valid Go source file: %v", err)
+		}
+		return nil
+	}
+}
+
+// Matches accepts answers (unfenced) that re matches. what describes
+// the expected format to the model, e.g. "a date as YYYY-MM-DD".
+func Matches(re *regexp.Regexp, what string) agent.Validator {
+	return func(_ context.Context, answer string) error {
+		if !re.MatchString(Unfence(answer)) {
+			return fmt.Errorf("the answer must be %s", what)
+		}
+		return nil
+	}
+}
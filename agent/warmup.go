@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+)
+
+// WarmupOption configures Warmup.
+type WarmupOption func(*warmupConfig)
+
+type warmupConfig struct {
+	request bool
+}
+
+// WarmupWithRequest makes Warmup also send a real one-token chat request.
+// That goes further than opening the connection - it also wakes up
+// whatever is behind the API (a scaled-to-zero vLLM server, a cold Ollama
+// model that has to load into memory) - at the price of a few tokens,
+// which are added to the agent's Usage.
+func WarmupWithRequest() WarmupOption {
+	return func(c *warmupConfig) {
+		c.request = true
+	}
+}
+
+// Warmup prepares the agent's provider so the first Run doesn't pay for
+// connection setup. Call it at startup, next to your other initialization:
+//
+//	a := agent.New(provider)
+//	if err := a.Warmup(ctx); err != nil {
+//	    log.Printf("warmup failed, first request will be slow: %v", err)
+//	}
+//
+// Providers that implement llm.Warmer (all the built-in ones do) open
+// their connection without spending tokens. Others are left alone unless
+// WarmupWithRequest is given. The history is never touched.
+//
+// Connections idle in the pool eventually get closed (90 seconds with
+// Go's default transport), so warm up shortly before traffic arrives.
+func (a *Agent) Warmup(ctx context.Context, opts ...WarmupOption) error {
+	var cfg warmupConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx = a.runContext(ctx)
+	if err := llm.Warmup(ctx, a.provider); err != nil {
+		return err
+	}
+	if !cfg.request {
+		return nil
+	}
+
+	req := llm.ChatRequest{
+		Model:     a.provider.ModelName(),
+		Messages:  []llm.Message{llm.NewUserMessage("ping")},
+		MaxTokens: 1,
+	}
+	resp, err := a.provider.CreateChat(ctx, req)
+	if err != nil {
+		return fmt.Errorf("warmup: %w", &ProviderError{Model: req.Model, Err: err})
+	}
+	a.Usage = a.Usage.Add(resp.Usage)
+	return nil
+}
@@ -0,0 +1,264 @@
+// Package watermark marks AI-generated messages in exported transcripts,
+// for rules that say people must be told when they're reading machine
+// output - and be able to check later.
+//
+// Export writes a conversation with every assistant message labelled by a
+// visible disclosure and carrying an invisible watermark: the generator,
+// the conversation and the time, encoded in zero-width characters that
+// survive copy and paste. Extract reads the watermark back:
+//
+//	err := watermark.Export(w, a.History,
+//	    watermark.WithGenerator("support-bot v3 (gpt-4o)"),
+//	    watermark.WithConversation(sessionID),
+//	)
+//
+//	if mark, _, ok := watermark.Extract(pastedText); ok {
+//	    fmt.Println("written by", mark.Generator, "at", mark.Time)
+//	}
+//
+// An invisible watermark is a disclosure aid, not a security measure:
+// anyone who knows it's there can strip it.
+package watermark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go-agent-sdk/llm"
+)
+
+// Mark is what the invisible watermark records.
+type Mark struct {
+	Generator    string    `json:"g,omitempty"` // the system that wrote the text
+	Conversation string    `json:"c,omitempty"` // session or conversation ID
+	Time         time.Time `json:"t"`           // when it was exported
+}
+
+// The zero-width characters the watermark is made of. Most renderers
+// show nothing for them, and they are kept by copy and paste.
+const (
+	markStart = '\u2063' // invisible separator
+	markEnd   = '\u2064' // invisible plus
+	bitZero   = '\u200b' // zero width space
+	bitOne    = '\u200c' // zero width non-joiner
+)
+
+// Embed returns text with m appended as an invisible watermark. Any
+// watermark already in text is replaced.
+func Embed(text string, m Mark) string {
+	data, _ := json.Marshal(m)
+	var b strings.Builder
+	b.WriteString(Strip(text))
+	b.WriteRune(markStart)
+	for _, c := range data {
+		for bit := 7; bit >= 0; bit-- {
+			if c>>bit&1 == 1 {
+				b.WriteRune(bitOne)
+			} else {
+				b.WriteRune(bitZero)
+			}
+		}
+	}
+	b.WriteRune(markEnd)
+	return b.String()
+}
+
+// Extract finds the watermark in text. It returns the mark, the text
+// without it, and whether there was one.
+func Extract(text string) (Mark, string, bool) {
+	start := strings.IndexRune(text, markStart)
+	if start < 0 {
+		return Mark{}, text, false
+	}
+	end := strings.IndexRune(text[start:], markEnd)
+	if end < 0 {
+		return Mark{}, text, false
+	}
+	end += start
+	encoded := text[start+len(string(markStart)) : end]
+	clean := text[:start] + text[end+len(string(markEnd)):]
+
+	var data []byte
+	var c byte
+	n := 0
+	for _, r := range encoded {
+		switch r {
+		case bitZero:
+			c <<= 1
+		case bitOne:
+			c = c<<1 | 1
+		default:
+			return Mark{}, text, false
+		}
+		if n++; n%8 == 0 {
+			data = append(data, c)
+			c = 0
+		}
+	}
+	var m Mark
+	if n%8 != 0 || json.Unmarshal(data, &m) != nil {
+		return Mark{}, text, false
+	}
+	return m, clean, true
+}
+
+// Strip removes any watermark from text.
+func Strip(text string) string {
+	_, clean, _ := Extract(text)
+	return clean
+}
+
+// Format is a transcript format.
+type Format int
+
+const (
+	Text     Format = iota // "Assistant (AI-generated): ..." blocks
+	Markdown               // a heading per message, disclosures in italics
+	JSON                   // an object per message with an ai_generated flag
+)
+
+// Option configures Export.
+type Option func(*exporter)
+
+type exporter struct {
+	format     Format
+	disclosure string
+	invisible  bool
+	mark       Mark
+	now        func() time.Time
+}
+
+// WithFormat sets the transcript format. The default is Text.
+func WithFormat(f Format) Option {
+	return func(e *exporter) {
+		e.format = f
+	}
+}
+
+// WithDisclosure sets the visible label on AI-generated messages. The
+// default is "AI-generated"; "" leaves the label out.
+func WithDisclosure(label string) Option {
+	return func(e *exporter) {
+		e.disclosure = label
+	}
+}
+
+// WithoutInvisibleMark leaves the invisible watermark out.
+func WithoutInvisibleMark() Option {
+	return func(e *exporter) {
+		e.invisible = false
+	}
+}
+
+// WithGenerator names the system that wrote the AI messages - product,
+// version, model - in the watermark and the JSON export.
+func WithGenerator(name string) Option {
+	return func(e *exporter) {
+		e.mark.Generator = name
+	}
+}
+
+// WithConversation puts a conversation ID in the watermark.
+func WithConversation(id string) Option {
+	return func(e *exporter) {
+		e.mark.Conversation = id
+	}
+}
+
+// WithClock sets the clock the watermark's time comes from, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(e *exporter) {
+		e.now = now
+	}
+}
+
+// exportedMessage is one message of a JSON transcript.
+type exportedMessage struct {
+	Role        string `json:"role"`
+	Content     string `json:"content"`
+	AIGenerated bool   `json:"ai_generated"`
+	Disclosure  string `json:"disclosure,omitempty"`
+}
+
+// exportedTranscript is a JSON transcript.
+type exportedTranscript struct {
+	Generator    string            `json:"generator,omitempty"`
+	Conversation string            `json:"conversation,omitempty"`
+	ExportedAt   time.Time         `json:"exported_at"`
+	Messages     []exportedMessage `json:"messages"`
+}
+
+// Export writes the conversation in history to w for people to read:
+// user and assistant messages, with the assistant's labelled and
+// watermarked. System prompts and tool traffic are left out; assistant
+// messages that only called tools are too.
+func Export(w io.Writer, history []llm.Message, opts ...Option) error {
+	e := &exporter{disclosure: "AI-generated", invisible: true, now: time.Now}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.mark.Time = e.now().UTC().Truncate(time.Second)
+
+	var msgs []exportedMessage
+	for _, m := range history {
+		if (m.Role != "user" && m.Role != "assistant") || m.Content == "" {
+			continue
+		}
+		out := exportedMessage{Role: m.Role, Content: m.Content}
+		if m.Role == "assistant" {
+			out.AIGenerated = true
+			out.Disclosure = e.disclosure
+			if e.invisible {
+				out.Content = Embed(out.Content, e.mark)
+			}
+		}
+		msgs = append(msgs, out)
+	}
+
+	var err error
+	switch e.format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(exportedTranscript{
+			Generator:    e.mark.Generator,
+			Conversation: e.mark.Conversation,
+			ExportedAt:   e.mark.Time,
+			Messages:     msgs,
+		})
+	case Markdown:
+		for _, m := range msgs {
+			heading := roleName(m.Role)
+			if m.Disclosure != "" {
+				heading += " *(" + m.Disclosure + ")*"
+			}
+			if _, err = fmt.Fprintf(w, "### %s\n\n%s\n\n", heading, m.Content); err != nil {
+				break
+			}
+		}
+	default:
+		for _, m := range msgs {
+			label := roleName(m.Role)
+			if m.Disclosure != "" {
+				label += " (" + m.Disclosure + ")"
+			}
+			if _, err = fmt.Fprintf(w, "%s:\n%s\n\n", label, m.Content); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("watermark: exporting transcript: %w", err)
+	}
+	return nil
+}
+
+func roleName(role string) string {
+	if role == "assistant" {
+		return "Assistant"
+	}
+	return "User"
+}
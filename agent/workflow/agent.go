@@ -0,0 +1,40 @@
+package workflow
+
+import (
+	"context"
+	"sync"
+
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+)
+
+// agentLocks serializes AgentNodes sharing an agent, since an Agent holds
+// one conversation.
+var agentLocks sync.Map // *agent.Agent -> *sync.Mutex
+
+// AgentNode returns a node that runs a on a task built from the state by
+// prompt, then stores the result in the state with store. Each run starts
+// from a fresh conversation with just a's system prompt, so everything
+// the agent needs must be in the prompt - the state is the workflow's
+// memory, not the agent's history.
+//
+// The same agent can back several nodes, even in parallel branches: its
+// runs take turns. Use separate agents for branches meant to work at the
+// same time.
+func AgentNode[S any](a *agent.Agent, prompt func(S) string, store func(S, *agent.RunResult) S, opts ...agent.RunOption) NodeFunc[S] {
+	return func(ctx context.Context, state S) (S, error) {
+		mu, _ := agentLocks.LoadOrStore(a, &sync.Mutex{})
+		mu.(*sync.Mutex).Lock()
+		defer mu.(*sync.Mutex).Unlock()
+
+		a.History = make([]llm.Message, 0, 1)
+		if a.SystemPrompt != "" {
+			a.History = append(a.History, llm.NewSystemMessage(a.SystemPrompt))
+		}
+		res, err := a.RunWithResult(ctx, prompt(state), opts...)
+		if err != nil {
+			return state, err
+		}
+		return store(state, res), nil
+	}
+}
@@ -0,0 +1,359 @@
+// Package workflow wires agents and plain Go functions into a graph that
+// passes a typed state from node to node. Edges can be fixed, chosen at
+// run time from the state, fan out to branches that run in parallel and
+// meet again at a join, or point backwards to loop. That covers most
+// pipelines people build by hand - research, draft, review, and back to
+// drafting until the reviewer is happy:
+//
+//	type Report struct {
+//	    Topic, Notes, Draft, Review string
+//	    Approved                    bool
+//	    Rounds                      int
+//	}
+//
+//	g := workflow.New[Report]("research")
+//	g.AddNode("research", workflow.AgentNode(researcher,
+//	    func(r Report) string { return "Research " + r.Topic },
+//	    func(r Report, res *agent.RunResult) Report { r.Notes = res.Content; return r }))
+//	g.AddNode("draft", workflow.AgentNode(writer, draftPrompt, storeDraft))
+//	g.AddNode("review", workflow.AgentNode(reviewer, reviewPrompt, storeReview))
+//	g.AddNode("finalize", publish)
+//	g.AddEdge("research", "draft")
+//	g.AddEdge("draft", "review")
+//	g.AddBranch("review", func(r Report) string {
+//	    if r.Approved || r.Rounds >= 3 {
+//	        return "finalize"
+//	    }
+//	    return "draft"
+//	})
+//
+//	report, err := g.Run(ctx, Report{Topic: "solid-state batteries"})
+//
+// A node with no outgoing edge ends the run, as does a branch returning
+// End.
+//
+// # Fan-out and fan-in
+//
+// An edge to several nodes runs them in parallel, each on its own copy of
+// the state, until every branch reaches the same join node (added with
+// AddJoin). The join's merge function combines the branches' states, and
+// the run carries on from the join as one again:
+//
+//	g.AddEdge("plan", "search_web", "search_papers", "search_news")
+//	g.AddEdge("search_web", "combine")
+//	g.AddEdge("search_papers", "combine")
+//	g.AddEdge("search_news", "combine")
+//	g.AddJoin("combine", func(base Report, branches []Report) (Report, error) {
+//	    for _, b := range branches {
+//	        base.Notes += b.Notes
+//	    }
+//	    return base, nil
+//	}, nil)
+//
+// A copy of a struct shares the contents of its maps and slices, so
+// branches should assign new ones rather than change them in place - two
+// branches appending to the same slice race.
+//
+// A Graph is safe to Run concurrently once it's built, provided its nodes
+// are; AgentNode runs one conversation per agent at a time.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// End is where a run stops. Return it from a branch's route to finish.
+const End = ""
+
+// DefaultMaxSteps is how many nodes one Run may execute, counting every
+// branch, unless SetMaxSteps says otherwise. It stops a loop whose exit
+// condition never comes true.
+const DefaultMaxSteps = 100
+
+// ErrMaxSteps is returned by Run when the step limit is reached.
+var ErrMaxSteps = errors.New("workflow: too many steps")
+
+// NodeFunc is the work a node does: it gets the state and returns it
+// updated.
+type NodeFunc[S any] func(ctx context.Context, state S) (S, error)
+
+// MergeFunc combines the states of parallel branches at a join. base is
+// the state when the branches started; branches are their states on
+// reaching the join, in the order of the edge that fanned out.
+type MergeFunc[S any] func(base S, branches []S) (S, error)
+
+// Step describes one node execution, for OnStep.
+type Step struct {
+	Node     string
+	Started  time.Time
+	Duration time.Duration
+	Err      error
+}
+
+type node[S any] struct {
+	fn    NodeFunc[S]
+	merge MergeFunc[S] // set on join nodes
+}
+
+// Graph is a workflow over state of type S.
+type Graph[S any] struct {
+	entry    string
+	nodes    map[string]*node[S]
+	edges    map[string][]string // from -> to; several fan out
+	routes   map[string]func(S) string
+	maxSteps int
+	onStep   func(Step)
+}
+
+// New returns an empty graph whose runs start at the node called entry.
+func New[S any](entry string) *Graph[S] {
+	return &Graph[S]{
+		entry:    entry,
+		nodes:    make(map[string]*node[S]),
+		edges:    make(map[string][]string),
+		routes:   make(map[string]func(S) string),
+		maxSteps: DefaultMaxSteps,
+	}
+}
+
+// AddNode adds a node. It panics if the name is empty or taken.
+func (g *Graph[S]) AddNode(name string, fn NodeFunc[S]) *Graph[S] {
+	g.add(name, &node[S]{fn: fn})
+	return g
+}
+
+// AddJoin adds a node where branches that fanned out meet: merge combines
+// their states, then fn, which may be nil, runs on the result. A join
+// reached along a single path just runs fn.
+func (g *Graph[S]) AddJoin(name string, merge MergeFunc[S], fn NodeFunc[S]) *Graph[S] {
+	if merge == nil {
+		panic("workflow: join " + name + " needs a merge function")
+	}
+	g.add(name, &node[S]{fn: fn, merge: merge})
+	return g
+}
+
+func (g *Graph[S]) add(name string, n *node[S]) {
+	if name == End {
+		panic("workflow: node name is empty")
+	}
+	if _, dup := g.nodes[name]; dup {
+		panic("workflow: duplicate node " + name)
+	}
+	g.nodes[name] = n
+}
+
+// AddEdge makes from continue at to, or at all of to in parallel when
+// there are several. A node has one way out: AddEdge or AddBranch, once.
+func (g *Graph[S]) AddEdge(from string, to ...string) *Graph[S] {
+	if len(to) == 0 {
+		panic("workflow: edge from " + from + " goes nowhere")
+	}
+	g.checkUnrouted(from)
+	g.edges[from] = append([]string(nil), to...)
+	return g
+}
+
+// AddBranch makes from continue at whichever node route picks from the
+// state it left, or finish if route returns End.
+func (g *Graph[S]) AddBranch(from string, route func(S) string) *Graph[S] {
+	g.checkUnrouted(from)
+	g.routes[from] = route
+	return g
+}
+
+func (g *Graph[S]) checkUnrouted(from string) {
+	_, edge := g.edges[from]
+	_, branch := g.routes[from]
+	if edge || branch {
+		panic("workflow: node " + from + " already has a way out")
+	}
+}
+
+// SetMaxSteps changes the step limit.
+func (g *Graph[S]) SetMaxSteps(n int) *Graph[S] {
+	g.maxSteps = n
+	return g
+}
+
+// OnStep calls fn after every node runs, for logging and tracing. Nodes in
+// parallel branches call it concurrently.
+func (g *Graph[S]) OnStep(fn func(Step)) *Graph[S] {
+	g.onStep = fn
+	return g
+}
+
+// Validate checks that the entry node exists and every edge leads to one.
+// Run calls it first.
+func (g *Graph[S]) Validate() error {
+	if _, ok := g.nodes[g.entry]; !ok {
+		return fmt.Errorf("workflow: entry node %q doesn't exist", g.entry)
+	}
+	var errs []error
+	for _, from := range sortedKeys(g.edges) {
+		if _, ok := g.nodes[from]; !ok {
+			errs = append(errs, fmt.Errorf("workflow: edge from unknown node %q", from))
+		}
+		for _, to := range g.edges[from] {
+			if _, ok := g.nodes[to]; !ok {
+				errs = append(errs, fmt.Errorf("workflow: edge from %q to unknown node %q", from, to))
+			}
+		}
+	}
+	for _, from := range sortedKeys(g.routes) {
+		if _, ok := g.nodes[from]; !ok {
+			errs = append(errs, fmt.Errorf("workflow: branch from unknown node %q", from))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run executes the graph from the entry node and returns the final state.
+// On error the state is the one the failing node was given.
+func (g *Graph[S]) Run(ctx context.Context, state S) (S, error) {
+	if err := g.Validate(); err != nil {
+		return state, err
+	}
+	r := &run[S]{g: g}
+	state, _, err := r.walk(ctx, g.entry, state, false)
+	return state, err
+}
+
+// run is the bookkeeping of one Run.
+type run[S any] struct {
+	g     *Graph[S]
+	steps atomic.Int64
+}
+
+// walk executes nodes from name until the run ends or, for a branch
+// (inBranch), until it reaches a join, whose name it returns without
+// running it.
+func (r *run[S]) walk(ctx context.Context, name string, state S, inBranch bool) (S, string, error) {
+	joined := false // name is a join whose branches have just been merged
+	for name != End {
+		n := r.g.nodes[name]
+		if n.merge != nil && inBranch && !joined {
+			return state, name, nil
+		}
+		joined = false
+		if err := ctx.Err(); err != nil {
+			return state, name, err
+		}
+		if r.steps.Add(1) > int64(r.g.maxSteps) {
+			return state, name, fmt.Errorf("%w: limit of %d reached at %q", ErrMaxSteps, r.g.maxSteps, name)
+		}
+
+		var err error
+		if state, err = r.exec(ctx, name, n, state); err != nil {
+			return state, name, err
+		}
+
+		from, next := name, r.g.edges[name]
+		switch {
+		case r.g.routes[from] != nil:
+			name = r.g.routes[from](state)
+			if _, ok := r.g.nodes[name]; !ok && name != End {
+				return state, from, fmt.Errorf("workflow: branch from %q chose unknown node %q", from, name)
+			}
+		case len(next) == 0:
+			name = End
+		case len(next) == 1:
+			name = next[0]
+		default:
+			if state, name, err = r.fanOut(ctx, from, next, state); err != nil {
+				return state, name, err
+			}
+			joined = true
+		}
+	}
+	return state, End, nil
+}
+
+// exec runs one node, reporting it to OnStep.
+func (r *run[S]) exec(ctx context.Context, name string, n *node[S], state S) (S, error) {
+	if n.fn == nil {
+		return state, nil
+	}
+	start := time.Now()
+	out, err := n.fn(ctx, state)
+	if err != nil {
+		err = fmt.Errorf("workflow: node %q: %w", name, err)
+	}
+	if r.g.onStep != nil {
+		r.g.onStep(Step{Node: name, Started: start, Duration: time.Since(start), Err: err})
+	}
+	if err != nil {
+		return state, err
+	}
+	return out, nil
+}
+
+// fanOut runs a branch per target in parallel, waits for all of them to
+// reach the same join, and merges their states there. The first failure
+// cancels the other branches.
+func (r *run[S]) fanOut(ctx context.Context, from string, targets []string, state S) (S, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	states := make([]S, len(targets))
+	joins := make([]string, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			states[i], joins[i], errs[i] = r.walk(ctx, t, state, true)
+			if errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the error that caused the others, not the cancellations.
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return state, from, firstErr
+	}
+
+	join := joins[0]
+	for i, j := range joins {
+		if j != join || j == End {
+			return state, from, fmt.Errorf("workflow: branches from %q don't meet at one join: %q reached %s, %q reached %s",
+				from, targets[0], orEnd(joins[0]), targets[i], orEnd(j))
+		}
+	}
+	merged, err := r.g.nodes[join].merge(state, states)
+	if err != nil {
+		return state, join, fmt.Errorf("workflow: join %q: %w", join, err)
+	}
+	return merged, join, nil
+}
+
+func orEnd(name string) string {
+	if name == End {
+		return "the end"
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,228 @@
+// Package artifacts keeps the files agents produce - charts, CSV exports,
+// generated PDFs - with metadata tying each to the run and tool call that
+// made it, and serves them to people over signed, expiring URLs.
+//
+// Store is the interface; Memory keeps artifacts in the process, Dir in a
+// local directory, and S3 in an S3 (or S3-compatible) bucket. A Server
+// wraps a store as an http.Handler and a tools.ArtifactSaver, so tools
+// that produce files hand back links the model can put in its answer:
+//
+//	store, _ := artifacts.NewDir("./artifacts")
+//	srv := artifacts.NewServer(store, signingKey, "https://example.com/artifacts")
+//	http.Handle("/artifacts/", http.StripPrefix("/artifacts", srv))
+//
+//	a.RegisterToolset(tools.NewChartTool(srv))
+//	res, _ := a.RunWithResult(ctx, "Chart last year's signups by month")
+//	for _, e := range res.Artifacts() {
+//	    fmt.Println(e.Summary, e.Target) // signups-by-month.svg https://example.com/artifacts/art_...?expires=...
+//	}
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"go-agent-sdk/llm"
+	"go-agent-sdk/tools"
+)
+
+// ErrNotFound is returned for an artifact ID a store doesn't have.
+var ErrNotFound = errors.New("artifacts: not found")
+
+// Artifact describes one stored file.
+type Artifact struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"` // file name, e.g. "revenue.svg"
+	MIMEType   string            `json:"mime_type"`
+	Size       int64             `json:"size"`
+	SHA256     string            `json:"sha256"` // hex
+	Created    time.Time         `json:"created"`
+	RunID      string            `json:"run_id,omitempty"`       // the agent run that made it
+	ToolCallID string            `json:"tool_call_id,omitempty"` // the tool call that made it
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// Store keeps artifacts.
+type Store interface {
+	// Put stores data under a. Put fills in the ID if it's empty, the
+	// size, hash and creation time, and the run and tool call IDs from
+	// ctx's trace if they're empty, and returns the result.
+	Put(ctx context.Context, a Artifact, data io.Reader) (Artifact, error)
+	// Get returns the artifact with the given ID and its content, which
+	// the caller closes.
+	Get(ctx context.Context, id string) (Artifact, io.ReadCloser, error)
+	// List returns the artifacts a run produced, or all of them when
+	// runID is empty, oldest first.
+	List(ctx context.Context, runID string) ([]Artifact, error)
+	// Delete removes an artifact. Unknown IDs are ignored.
+	Delete(ctx context.Context, id string) error
+}
+
+// Saver returns a tools.ArtifactSaver that puts files in store and
+// returns "artifact:<id>" references - for when people reach artifacts
+// through your own application rather than a Server's URLs.
+func Saver(store Store) tools.ArtifactSaver {
+	return tools.ArtifactSaverFunc(func(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+		a, err := store.Put(ctx, Artifact{Name: name, MIMEType: mimeType}, bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		return "artifact:" + a.ID, nil
+	})
+}
+
+// NewID returns a random artifact ID.
+func NewID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return "art_" + hex.EncodeToString(b[:])
+}
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// checkID refuses IDs that could escape a directory or bucket prefix.
+func checkID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("artifacts: invalid ID %q", id)
+	}
+	return nil
+}
+
+// prepare fills in what Put fills in, apart from size and hash.
+func prepare(ctx context.Context, a Artifact) (Artifact, error) {
+	if a.ID == "" {
+		a.ID = NewID()
+	}
+	if err := checkID(a.ID); err != nil {
+		return a, err
+	}
+	if a.MIMEType == "" {
+		a.MIMEType = "application/octet-stream"
+	}
+	if a.Created.IsZero() {
+		a.Created = llm.Now(ctx)
+	}
+	if tc, ok := llm.TraceFromContext(ctx); ok {
+		if a.RunID == "" {
+			a.RunID = tc.RunID
+		}
+		if a.ToolCallID == "" {
+			a.ToolCallID = tc.ToolCallID
+		}
+	}
+	a.Metadata = maps.Clone(a.Metadata)
+	return a, nil
+}
+
+// hashingWriter counts and hashes what passes through it.
+type hashingWriter struct {
+	n int64
+	h interface {
+		io.Writer
+		Sum([]byte) []byte
+	}
+}
+
+func newHashingWriter() *hashingWriter {
+	return &hashingWriter{h: sha256.New()}
+}
+
+func (w *hashingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return w.h.Write(p)
+}
+
+func (w *hashingWriter) fill(a *Artifact) {
+	a.Size = w.n
+	a.SHA256 = hex.EncodeToString(w.h.Sum(nil))
+}
+
+// Memory is a Store held in the process, for tests and short-lived
+// programs.
+type Memory struct {
+	mu        sync.RWMutex
+	artifacts map[string]Artifact
+	data      map[string][]byte
+}
+
+// NewMemory returns an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{artifacts: make(map[string]Artifact), data: make(map[string][]byte)}
+}
+
+func (m *Memory) Put(ctx context.Context, a Artifact, data io.Reader) (Artifact, error) {
+	a, err := prepare(ctx, a)
+	if err != nil {
+		return a, err
+	}
+	hw := newHashingWriter()
+	content, err := io.ReadAll(io.TeeReader(data, hw))
+	if err != nil {
+		return a, fmt.Errorf("artifacts: reading %s: %w", a.Name, err)
+	}
+	hw.fill(&a)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.artifacts[a.ID] = a
+	m.data[a.ID] = content
+	return a, nil
+}
+
+func (m *Memory) Get(ctx context.Context, id string) (Artifact, io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.artifacts[id]
+	if !ok {
+		return Artifact{}, nil, ErrNotFound
+	}
+	return a, io.NopCloser(bytes.NewReader(m.data[id])), nil
+}
+
+func (m *Memory) List(ctx context.Context, runID string) ([]Artifact, error) {
+	m.mu.RLock()
+	var out []Artifact
+	for _, a := range m.artifacts {
+		if runID == "" || a.RunID == runID {
+			out = append(out, a)
+		}
+	}
+	m.mu.RUnlock()
+	sortArtifacts(out)
+	return out, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.artifacts, id)
+	delete(m.data, id)
+	return nil
+}
+
+// sortArtifacts puts artifacts oldest first, by ID among equals.
+func sortArtifacts(as []Artifact) {
+	slices.SortFunc(as, func(a, b Artifact) int {
+		if c := a.Created.Compare(b.Created); c != 0 {
+			return c
+		}
+		if a.ID < b.ID {
+			return -1
+		}
+		if a.ID > b.ID {
+			return 1
+		}
+		return 0
+	})
+}
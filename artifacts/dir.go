@@ -0,0 +1,138 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir stores artifacts in a local directory, two files each: the content
+// under the artifact's ID and its metadata next to it as <id>.json.
+type Dir struct {
+	dir string
+}
+
+// NewDir returns a store in dir, creating the directory if needed.
+func NewDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("artifacts: %w", err)
+	}
+	return &Dir{dir: dir}, nil
+}
+
+func (d *Dir) Put(ctx context.Context, a Artifact, data io.Reader) (Artifact, error) {
+	a, err := prepare(ctx, a)
+	if err != nil {
+		return a, err
+	}
+
+	// Write to a temporary file and rename, so a reader never sees half an
+	// artifact and a failed Put leaves nothing behind.
+	f, err := os.CreateTemp(d.dir, ".put-*")
+	if err != nil {
+		return a, fmt.Errorf("artifacts: %w", err)
+	}
+	defer os.Remove(f.Name())
+	hw := newHashingWriter()
+	_, err = io.Copy(io.MultiWriter(f, hw), data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return a, fmt.Errorf("artifacts: writing %s: %w", a.Name, err)
+	}
+	hw.fill(&a)
+
+	meta, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return a, fmt.Errorf("artifacts: %w", err)
+	}
+	if err := os.WriteFile(d.metaPath(a.ID), meta, 0o644); err != nil {
+		return a, fmt.Errorf("artifacts: %w", err)
+	}
+	if err := os.Rename(f.Name(), d.dataPath(a.ID)); err != nil {
+		os.Remove(d.metaPath(a.ID))
+		return a, fmt.Errorf("artifacts: %w", err)
+	}
+	return a, nil
+}
+
+func (d *Dir) Get(ctx context.Context, id string) (Artifact, io.ReadCloser, error) {
+	a, err := d.load(id)
+	if err != nil {
+		return a, nil, err
+	}
+	f, err := os.Open(d.dataPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return a, nil, ErrNotFound
+	}
+	if err != nil {
+		return a, nil, fmt.Errorf("artifacts: %w", err)
+	}
+	return a, f, nil
+}
+
+func (d *Dir) List(ctx context.Context, runID string) ([]Artifact, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("artifacts: %w", err)
+	}
+	var out []Artifact
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() || checkID(id) != nil {
+			continue
+		}
+		a, err := d.load(id)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted since ReadDir
+		}
+		if err != nil {
+			return nil, err
+		}
+		if runID == "" || a.RunID == runID {
+			out = append(out, a)
+		}
+	}
+	sortArtifacts(out)
+	return out, nil
+}
+
+func (d *Dir) Delete(ctx context.Context, id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	for _, p := range []string{d.metaPath(id), d.dataPath(id)} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("artifacts: %w", err)
+		}
+	}
+	return nil
+}
+
+func (d *Dir) load(id string) (Artifact, error) {
+	var a Artifact
+	if err := checkID(id); err != nil {
+		return a, ErrNotFound
+	}
+	b, err := os.ReadFile(d.metaPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return a, ErrNotFound
+	}
+	if err != nil {
+		return a, fmt.Errorf("artifacts: %w", err)
+	}
+	if err := json.Unmarshal(b, &a); err != nil {
+		return a, fmt.Errorf("artifacts: reading metadata of %s: %w", id, err)
+	}
+	return a, nil
+}
+
+func (d *Dir) dataPath(id string) string { return filepath.Join(d.dir, id) }
+func (d *Dir) metaPath(id string) string { return filepath.Join(d.dir, id+".json") }
@@ -0,0 +1,364 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 stores artifacts in an S3 bucket, or any service speaking the S3 API
+// (MinIO, Cloudflare R2, ...) with S3Endpoint. Like Dir it keeps two
+// objects per artifact: the content under <prefix><id>, served with the
+// artifact's MIME type, and the metadata under <prefix><id>.json.
+//
+// Requests are signed with AWS Signature Version 4. Credentials default to
+// the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables.
+type S3 struct {
+	bucket       string
+	region       string
+	endpoint     string // set for path-style requests to a custom endpoint
+	prefix       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	httpClient   *http.Client
+}
+
+// S3Option configures an S3 store.
+type S3Option func(*S3)
+
+// S3Credentials sets the access key, secret and, for temporary
+// credentials, session token, instead of reading them from the
+// environment.
+func S3Credentials(accessKey, secretKey, sessionToken string) S3Option {
+	return func(s *S3) {
+		s.accessKey, s.secretKey, s.sessionToken = accessKey, secretKey, sessionToken
+	}
+}
+
+// S3Endpoint sends requests to an S3-compatible service at endpoint, e.g.
+// "http://localhost:9000" for MinIO, addressing the bucket by path.
+func S3Endpoint(endpoint string) S3Option {
+	return func(s *S3) {
+		s.endpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+// S3Prefix puts every object under prefix, e.g. "agents/artifacts/".
+func S3Prefix(prefix string) S3Option {
+	return func(s *S3) {
+		s.prefix = prefix
+	}
+}
+
+// S3HTTPClient overrides the default HTTP client.
+func S3HTTPClient(hc *http.Client) S3Option {
+	return func(s *S3) {
+		s.httpClient = hc
+	}
+}
+
+// NewS3 returns a store in bucket, in the given AWS region.
+func NewS3(bucket, region string, opts ...S3Option) *S3 {
+	s := &S3{
+		bucket:       bucket,
+		region:       region,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		httpClient:   &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *S3) Put(ctx context.Context, a Artifact, data io.Reader) (Artifact, error) {
+	a, err := prepare(ctx, a)
+	if err != nil {
+		return a, err
+	}
+	// The signature covers the payload hash, so the content is read in
+	// full first. Artifacts are reports and charts, not backups.
+	hw := newHashingWriter()
+	content, err := io.ReadAll(io.TeeReader(data, hw))
+	if err != nil {
+		return a, fmt.Errorf("artifacts: reading %s: %w", a.Name, err)
+	}
+	hw.fill(&a)
+	meta, err := json.Marshal(a)
+	if err != nil {
+		return a, fmt.Errorf("artifacts: %w", err)
+	}
+
+	// Metadata last: an artifact exists once its metadata does.
+	if _, err := s.do(ctx, "PUT", s.prefix+a.ID, nil, content, a.MIMEType); err != nil {
+		return a, err
+	}
+	if _, err := s.do(ctx, "PUT", s.prefix+a.ID+".json", nil, meta, "application/json"); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+func (s *S3) Get(ctx context.Context, id string) (Artifact, io.ReadCloser, error) {
+	a, err := s.load(ctx, id)
+	if err != nil {
+		return a, nil, err
+	}
+	resp, err := s.do(ctx, "GET", s.prefix+id, nil, nil, "")
+	if err != nil {
+		return a, nil, err
+	}
+	return a, resp.Body, nil
+}
+
+func (s *S3) List(ctx context.Context, runID string) ([]Artifact, error) {
+	var out []Artifact
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {s.prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, "GET", "", q, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("artifacts: listing bucket: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			id, ok := strings.CutSuffix(strings.TrimPrefix(obj.Key, s.prefix), ".json")
+			if !ok || checkID(id) != nil {
+				continue
+			}
+			a, err := s.load(ctx, id)
+			if err == ErrNotFound {
+				continue // deleted since the listing
+			}
+			if err != nil {
+				return nil, err
+			}
+			if runID == "" || a.RunID == runID {
+				out = append(out, a)
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	sortArtifacts(out)
+	return out, nil
+}
+
+func (s *S3) Delete(ctx context.Context, id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	// S3 answers 204 for keys that don't exist, so there's nothing to
+	// ignore.
+	for _, key := range []string{s.prefix + id + ".json", s.prefix + id} {
+		resp, err := s.do(ctx, "DELETE", key, nil, nil, "")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (s *S3) load(ctx context.Context, id string) (Artifact, error) {
+	var a Artifact
+	if err := checkID(id); err != nil {
+		return a, ErrNotFound
+	}
+	resp, err := s.do(ctx, "GET", s.prefix+id+".json", nil, nil, "")
+	if err != nil {
+		return a, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&a); err != nil {
+		return a, fmt.Errorf("artifacts: reading metadata of %s: %w", id, err)
+	}
+	return a, nil
+}
+
+// do sends a signed request for key (or the bucket itself when key is
+// empty) and returns the response if it succeeded, with ErrNotFound for a
+// missing key. The caller closes the body.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body []byte, contentType string) (*http.Response, error) {
+	u := s.url(key)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("artifacts: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("artifacts: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound && key != "" {
+			return nil, ErrNotFound
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("artifacts: S3 %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// url addresses key virtual-hosted style on AWS, path style on a custom
+// endpoint.
+func (s *S3) url(key string) *url.URL {
+	if s.endpoint != "" {
+		u, err := url.Parse(s.endpoint)
+		if err == nil {
+			u.Path = strings.TrimRight(u.Path, "/") + "/" + s.bucket + "/" + key
+			u.RawPath = uriEncode(u.Path, false)
+			return u
+		}
+	}
+	return &url.URL{
+		Scheme:  "https",
+		Host:    s.bucket + ".s3." + s.region + ".amazonaws.com",
+		Path:    "/" + key,
+		RawPath: uriEncode("/"+key, false),
+	}
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	// Sign the host and every x-amz-* header, plus the content type.
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" || lk == "range" {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes q the way SigV4 wants it: sorted by key, with
+// RFC 3986 escaping.
+func canonicalQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode escapes everything but RFC 3986 unreserved characters, and
+// slashes too unless it's encoding a path.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,150 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURLTTL is how long Server URLs stay valid unless ServerURLTTL
+// says otherwise.
+const DefaultURLTTL = 24 * time.Hour
+
+// Server serves a store's artifacts over HTTP to whoever holds a signed
+// URL, so a link in an agent's answer works in a browser without the
+// reader logging in, and stops working when it expires. It is also a
+// tools.ArtifactSaver whose references are such URLs.
+//
+// Mount it under the prefix of its base URL:
+//
+//	srv := artifacts.NewServer(store, key, "https://example.com/artifacts")
+//	mux.Handle("/artifacts/", http.StripPrefix("/artifacts", srv))
+type Server struct {
+	store   Store
+	key     []byte
+	baseURL string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// ServerURLTTL changes how long the URLs SaveArtifact returns stay valid.
+func ServerURLTTL(ttl time.Duration) ServerOption {
+	return func(s *Server) {
+		s.ttl = ttl
+	}
+}
+
+// NewServer returns a Server for store whose URLs start with baseURL and
+// are signed with key. Use a random key of at least 32 bytes, and the same
+// one on every replica serving the URLs.
+func NewServer(store Store, key []byte, baseURL string, opts ...ServerOption) *Server {
+	s := &Server{
+		store:   store,
+		key:     key,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		ttl:     DefaultURLTTL,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// URL returns a link to the artifact with the given ID that works for
+// ttl.
+func (s *Server) URL(id string, ttl time.Duration) string {
+	expires := strconv.FormatInt(s.now().Add(ttl).Unix(), 10)
+	q := url.Values{"expires": {expires}, "signature": {s.signature(id, expires)}}
+	return s.baseURL + "/" + url.PathEscape(id) + "?" + q.Encode()
+}
+
+// SaveArtifact puts data in the store and returns a signed URL for it,
+// valid for the server's URL TTL.
+func (s *Server) SaveArtifact(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	a, err := s.store.Put(ctx, Artifact{Name: name, MIMEType: mimeType}, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return s.URL(a.ID, s.ttl), nil
+}
+
+// ServeHTTP sends the artifact named by the last element of the path, if
+// the URL's signature is valid and hasn't expired.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := path.Base(r.URL.Path)
+	if err := s.verify(id, r.URL.Query()); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	a, body, err := s.store.Get(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "artifact unavailable", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", a.MIMEType)
+	h.Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	h.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.Name}))
+	h.Set("Cache-Control", "private, max-age=3600")
+	h.Set("ETag", `"`+a.SHA256+`"`)
+	// Artifacts are made from model output; an SVG or HTML file must not
+	// be able to run script on this origin.
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src data:; sandbox")
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.Copy(w, body)
+}
+
+func (s *Server) verify(id string, q url.Values) error {
+	if checkID(id) != nil {
+		return errors.New("invalid artifact URL")
+	}
+	expires, sig := q.Get("expires"), q.Get("signature")
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || sig == "" {
+		return errors.New("invalid artifact URL")
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(id, expires))) {
+		return errors.New("invalid artifact URL")
+	}
+	if s.now().Unix() > exp {
+		return fmt.Errorf("artifact URL expired at %s", time.Unix(exp, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (s *Server) signature(id, expires string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(id + "\n" + expires))
+	return hex.EncodeToString(h.Sum(nil))
+}
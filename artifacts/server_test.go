@@ -0,0 +1,153 @@
+package artifacts
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) (*Server, *time.Time, string) {
+	t.Helper()
+	store := NewMemory()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	srv := NewServer(store, []byte("0123456789abcdef0123456789abcdef"), "https://example.com/artifacts/")
+	srv.now = func() time.Time { return now }
+
+	link, err := srv.SaveArtifact(context.Background(), "chart.svg", "image/svg+xml", []byte("<svg/>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, &now, link
+}
+
+// get requests link's path and query from srv, as mounted under
+// /artifacts.
+func get(t *testing.T, srv *Server, link string) *httptest.ResponseRecorder {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(u.Path, "/artifacts")+"?"+u.RawQuery, nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerServesSignedURL(t *testing.T) {
+	srv, _, link := newTestServer(t)
+	if !strings.HasPrefix(link, "https://example.com/artifacts/art_") {
+		t.Fatalf("link = %s", link)
+	}
+	rec := get(t, srv, link)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if body, _ := io.ReadAll(rec.Body); string(body) != "<svg/>" {
+		t.Errorf("body = %q", body)
+	}
+	h := rec.Header()
+	if h.Get("Content-Type") != "image/svg+xml" || h.Get("X-Content-Type-Options") != "nosniff" ||
+		!strings.Contains(h.Get("Content-Security-Policy"), "sandbox") {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestServerRejectsBadURLs(t *testing.T) {
+	srv, now, link := newTestServer(t)
+	u, _ := url.Parse(link)
+	id := u.Path[strings.LastIndex(u.Path, "/")+1:]
+	q := u.Query()
+
+	other, err := srv.SaveArtifact(context.Background(), "other.txt", "text/plain", []byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID := other[strings.LastIndex(other, "/")+1 : strings.Index(other, "?")]
+
+	with := func(id string, change func(q url.Values)) string {
+		q := url.Values{"expires": {q.Get("expires")}, "signature": {q.Get("signature")}}
+		if change != nil {
+			change(q)
+		}
+		return "https://example.com/artifacts/" + id + "?" + q.Encode()
+	}
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{"other artifact's ID", with(otherID, nil), "invalid artifact URL"},
+		{"unknown ID", with("art_000000000000000000000000", nil), "invalid artifact URL"},
+		{"invalid ID", with("..", nil), "invalid artifact URL"},
+		{"later expiry", with(id, func(q url.Values) {
+			exp, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+			q.Set("expires", strconv.FormatInt(exp+3600, 10))
+		}), "invalid artifact URL"},
+		{"tampered signature", with(id, func(q url.Values) {
+			sig := []byte(q.Get("signature"))
+			sig[0] ^= 1
+			q.Set("signature", string(sig))
+		}), "invalid artifact URL"},
+		{"no signature", with(id, func(q url.Values) { q.Del("signature") }), "invalid artifact URL"},
+		{"no expiry", with(id, func(q url.Values) { q.Del("expires") }), "invalid artifact URL"},
+		{"expiry not a number", with(id, func(q url.Values) { q.Set("expires", "tomorrow") }), "invalid artifact URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(t, srv, tt.link)
+			if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("status %d, body %q; want 403 and %q", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+
+	// A signed link to an artifact that isn't there is a 404.
+	if rec := get(t, srv, srv.URL("art_000000000000000000000000", time.Hour)); rec.Code != http.StatusNotFound {
+		t.Errorf("missing artifact: status %d", rec.Code)
+	}
+
+	// The link works until its expiry, and not a second after.
+	*now = now.Add(DefaultURLTTL)
+	if rec := get(t, srv, link); rec.Code != http.StatusOK {
+		t.Errorf("at expiry: status %d: %s", rec.Code, rec.Body)
+	}
+	*now = now.Add(time.Second)
+	rec := get(t, srv, link)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "artifact URL expired at 2026-03-02T12:00:00Z") {
+		t.Errorf("after expiry: status %d, body %q", rec.Code, rec.Body)
+	}
+}
+
+func TestServerOtherKey(t *testing.T) {
+	srv, _, link := newTestServer(t)
+	replica := NewServer(srv.store, []byte("a different key of 32 bytes....."), "https://example.com/artifacts")
+	replica.now = srv.now
+	if rec := get(t, replica, link); rec.Code != http.StatusForbidden {
+		t.Errorf("a server with another key served the link: status %d", rec.Code)
+	}
+}
+
+func TestServerMethods(t *testing.T) {
+	srv, _, link := newTestServer(t)
+	u, _ := url.Parse(link)
+	path := strings.TrimPrefix(u.Path, "/artifacts") + "?" + u.RawQuery
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, path, nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "6" {
+		t.Errorf("HEAD: status %d, body %q, headers %v", rec.Code, rec.Body, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST: status %d, headers %v", rec.Code, rec.Header())
+	}
+}
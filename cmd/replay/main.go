@@ -0,0 +1,173 @@
+// Command replay steps through a recorded agent run, event by event.
+//
+//	go run go-agent-sdk/cmd/replay [flags] run.json
+//
+// The file holds RunResults as JSON (one, or one per line) or a feedback
+// export made WithTraces. At the prompt:
+//
+//	enter, n   next step
+//	p          previous step
+//	<number>   jump to that step
+//	l          list all steps
+//	q          quit
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go-agent-sdk/agent"
+	"go-agent-sdk/agent/replay"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	runID := flag.String("run", "", "ID of the run to replay (default: the first in the file)")
+	list := flag.Bool("list", false, "list the runs in the file and exit")
+	all := flag.Bool("all", false, "print every step and exit, without prompting")
+	maxLen := flag.Int("max", 400, "truncate texts to this many characters (-1 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: replay [flags] FILE")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	runs, err := replay.Load(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(runs) == 0 {
+		log.Fatal("no runs with turns in ", flag.Arg(0))
+	}
+
+	if *list {
+		for _, r := range runs {
+			fmt.Printf("%s  %d turns  %d tool calls  %q\n", r.ID, len(r.Turns), len(r.ToolCalls()), clip(r.Input, 60))
+		}
+		return
+	}
+
+	run := runs[0]
+	if *runID != "" {
+		run = nil
+		for _, r := range runs {
+			if r.ID == *runID {
+				run = r
+			}
+		}
+		if run == nil {
+			log.Fatalf("run %s not found (use -list)", *runID)
+		}
+	}
+
+	steps := replay.Steps(run)
+	opts := replay.RenderOptions{MaxLen: *maxLen}
+	header(run, len(steps))
+
+	if *all {
+		for i, s := range steps {
+			fmt.Printf("\n--- step %d/%d ---\n", i+1, len(steps))
+			s.Render(os.Stdout, opts)
+		}
+		return
+	}
+	interact(steps, opts)
+}
+
+func header(run *agent.RunResult, n int) {
+	fmt.Printf("run %s  model %s  %s  %d tokens  %d steps\n", run.ID, run.Model, run.Duration, run.Usage.TotalTokens, n)
+	fmt.Printf("input:  %s\n", clip(run.Input, 200))
+	fmt.Printf("answer: %s\n", clip(run.Content, 200))
+}
+
+// interact shows one step at a time, driven by commands on stdin.
+func interact(steps []replay.Step, opts replay.RenderOptions) {
+	in := bufio.NewScanner(os.Stdin)
+	i := 0
+	show := func() {
+		fmt.Printf("\n--- step %d/%d ---\n", i+1, len(steps))
+		steps[i].Render(os.Stdout, opts)
+	}
+	show()
+	for {
+		fmt.Print("[n]ext [p]rev [l]ist <step> [q]uit > ")
+		if !in.Scan() {
+			fmt.Println()
+			return
+		}
+		cmd := strings.TrimSpace(in.Text())
+		switch {
+		case cmd == "" || cmd == "n":
+			if i == len(steps)-1 {
+				fmt.Println("(last step)")
+				continue
+			}
+			i++
+		case cmd == "p":
+			if i == 0 {
+				fmt.Println("(first step)")
+				continue
+			}
+			i--
+		case cmd == "l":
+			for j, s := range steps {
+				marker := " "
+				if j == i {
+					marker = ">"
+				}
+				fmt.Printf("%s %3d  %s\n", marker, j+1, summary(s))
+			}
+			continue
+		case cmd == "q":
+			return
+		default:
+			n, err := strconv.Atoi(cmd)
+			if err != nil || n < 1 || n > len(steps) {
+				fmt.Printf("unknown command %q\n", cmd)
+				continue
+			}
+			i = n - 1
+		}
+		show()
+	}
+}
+
+// summary is a step's one-line entry in the step list.
+func summary(s replay.Step) string {
+	switch s.Kind {
+	case replay.Request:
+		return fmt.Sprintf("turn %d request   +%d/-%d messages", s.Turn+1, len(s.Added), len(s.Removed))
+	case replay.Response:
+		reason := ""
+		if len(s.Response.Choices) > 0 {
+			reason = s.Response.Choices[0].FinishReason
+		}
+		return fmt.Sprintf("turn %d response  %s", s.Turn+1, reason)
+	default:
+		status := "ok"
+		if s.Trace.Error != "" {
+			status = "error"
+		}
+		return fmt.Sprintf("turn %d tool      %s (%s)", s.Turn+1, s.Trace.Name, status)
+	}
+}
+
+func clip(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
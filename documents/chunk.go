@@ -0,0 +1,187 @@
+package documents
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunker cuts text into passages. Sizes are in characters (runes): about
+// four to a token for English.
+type Chunker interface {
+	Split(text string) []string
+}
+
+// ChunkerFunc lets an ordinary function be a Chunker.
+type ChunkerFunc func(text string) []string
+
+func (f ChunkerFunc) Split(text string) []string {
+	return f(text)
+}
+
+// FixedSize cuts text into pieces of size characters, each starting
+// overlap characters before the last one ended. It's blind to words and
+// sentences - quick, predictable, and the baseline to beat.
+func FixedSize(size, overlap int) Chunker {
+	size = max(size, 1)
+	step := max(size-max(overlap, 0), 1)
+	return ChunkerFunc(func(text string) []string {
+		runes := []rune(text)
+		var out []string
+		for start := 0; start < len(runes); start += step {
+			end := min(start+size, len(runes))
+			out = append(out, string(runes[start:end]))
+			if end == len(runes) {
+				break
+			}
+		}
+		return out
+	})
+}
+
+// Sentences packs whole sentences into chunks of up to size characters,
+// and starts each chunk with as many of the previous chunk's last
+// sentences as fit in overlap characters. A sentence longer than size is
+// cut with FixedSize.
+func Sentences(size, overlap int) Chunker {
+	return ChunkerFunc(func(text string) []string {
+		var pieces []string
+		for _, s := range splitSentences(text) {
+			if runeLen(s) > size {
+				pieces = append(pieces, FixedSize(size, 0).Split(s)...)
+			} else {
+				pieces = append(pieces, s)
+			}
+		}
+		return merge(pieces, size, overlap)
+	})
+}
+
+// DefaultSeparators are what Recursive splits on, coarsest first:
+// paragraphs, lines, sentences, words, then anywhere.
+var DefaultSeparators = []string{"\n\n", "\n", ". ", " ", ""}
+
+// Recursive splits text on the coarsest separator that gives pieces of up
+// to size characters - paragraphs where it can, then lines, sentences and
+// words - and packs the pieces back into chunks of up to size, with
+// overlap characters of context carried from one chunk into the next.
+// It keeps related text together better than the others, which makes it
+// the usual choice. With no separators it uses DefaultSeparators.
+func Recursive(size, overlap int, separators ...string) Chunker {
+	if len(separators) == 0 {
+		separators = DefaultSeparators
+	}
+	return ChunkerFunc(func(text string) []string {
+		return merge(splitRecursive(text, max(size, 1), separators), size, overlap)
+	})
+}
+
+// splitRecursive cuts text into pieces no longer than size, on the first
+// separator that occurs in it, going down the list for pieces still too
+// long. Separators stay at the end of the piece before them, so joining
+// the pieces gives the text back.
+func splitRecursive(text string, size int, separators []string) []string {
+	if runeLen(text) <= size {
+		return []string{text}
+	}
+	sep, rest := "", []string(nil)
+	for i, s := range separators {
+		if s == "" || strings.Contains(text, s) {
+			sep, rest = s, separators[i+1:]
+			break
+		}
+	}
+	if sep == "" {
+		return FixedSize(size, 0).Split(text)
+	}
+
+	var out []string
+	for _, piece := range strings.SplitAfter(text, sep) {
+		if piece == "" {
+			continue
+		}
+		if runeLen(piece) > size {
+			out = append(out, splitRecursive(piece, size, rest)...)
+		} else {
+			out = append(out, piece)
+		}
+	}
+	return out
+}
+
+// merge packs pieces, each at most size long, into chunks of up to size,
+// starting each new chunk with the trailing pieces of the last that fit in
+// overlap.
+func merge(pieces []string, size, overlap int) []string {
+	var out []string
+	var cur []string
+	curLen := 0
+	flush := func() {
+		if chunk := strings.TrimSpace(strings.Join(cur, "")); chunk != "" {
+			out = append(out, chunk)
+		}
+	}
+
+	for _, p := range pieces {
+		n := runeLen(p)
+		if curLen+n > size && len(cur) > 0 {
+			flush()
+			// Carry the tail of the chunk over, as far as overlap allows
+			// and the new piece still fits.
+			keep, keepLen := len(cur), 0
+			for keep > 0 {
+				l := runeLen(cur[keep-1])
+				if keepLen+l > overlap || keepLen+l+n > size {
+					break
+				}
+				keep--
+				keepLen += l
+			}
+			cur, curLen = append([]string(nil), cur[keep:]...), keepLen
+		}
+		cur = append(cur, p)
+		curLen += n
+	}
+	flush()
+	return out
+}
+
+// splitSentences cuts text after sentence-ending punctuation followed by
+// a space, and at blank lines. Whitespace stays with the sentence before
+// it. Abbreviations like "e.g. this" end a sentence too; for chunking
+// that rarely matters.
+func splitSentences(text string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, w := utf8.DecodeRuneInString(text[i:])
+		i += w
+		end := false
+		switch r {
+		case '.', '!', '?', '。', '！', '？':
+			// Closing quotes and brackets belong to the sentence.
+			for i < len(text) && strings.IndexByte(`"')]`, text[i]) >= 0 {
+				i++
+			}
+			end = i == len(text) || unicode.IsSpace(rune(text[i])) || r > unicode.MaxASCII
+		case '\n':
+			end = i < len(text) && text[i] == '\n'
+		}
+		if !end {
+			continue
+		}
+		for i < len(text) && unicode.IsSpace(rune(text[i])) {
+			i++
+		}
+		out = append(out, text[start:i])
+		start = i
+	}
+	if start < len(text) {
+		out = append(out, text[start:])
+	}
+	return out
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
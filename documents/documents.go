@@ -0,0 +1,129 @@
+// Package documents turns files into chunks ready for embedding: loaders
+// read plain text, Markdown, HTML and PDF into Documents, a Chunker cuts
+// them into passages of a useful size, and Ingest embeds the passages
+// into a vector store.
+//
+//	docs, err := documents.LoadFile("handbook.pdf") // one Document per page
+//	chunks := documents.Split(docs, documents.Recursive(1000, 150))
+//	err = documents.Ingest(ctx, store, embedder, chunks)
+//
+// After that, tools.NewRetriever(store, embedder) lets an agent search
+// them.
+package documents
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-agent-sdk/llm"
+	"go-agent-sdk/vectorstore"
+)
+
+// Document is text loaded from a source, with what's known about it.
+type Document struct {
+	Source   string            // file name or URL
+	Text     string            // the content, as plain text (Markdown stays Markdown)
+	Metadata map[string]string // title, page and whatever else the loader found
+}
+
+// Loader reads the content of r, which came from source, into documents.
+// Most formats give one Document; PDFs give one per page.
+type Loader func(r io.Reader, source string) ([]Document, error)
+
+// Loaders picks the loader for LoadFile by file extension. Add to it for
+// other formats.
+var Loaders = map[string]Loader{
+	".txt":      LoadText,
+	".text":     LoadText,
+	".md":       LoadMarkdown,
+	".markdown": LoadMarkdown,
+	".html":     LoadHTML,
+	".htm":      LoadHTML,
+	".pdf":      LoadPDF,
+}
+
+// LoadFile loads the file at path with the loader for its extension. Files
+// with an extension Loaders doesn't know are read as plain text.
+func LoadFile(path string) ([]Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("documents: %w", err)
+	}
+	defer f.Close()
+
+	load, ok := Loaders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		load = LoadText
+	}
+	return load(f, path)
+}
+
+// Chunk is a passage of a document, the unit that's embedded and
+// retrieved.
+type Chunk struct {
+	ID       string            // the source and the chunk's number in it: "handbook.pdf#12"
+	Text     string            // the passage
+	Metadata map[string]string // the document's, plus "source" and "chunk"
+}
+
+// Split cuts every document into chunks with c. Chunks are numbered per
+// source, so a PDF's chunks run on from one page to the next.
+func Split(docs []Document, c Chunker) []Chunk {
+	var out []Chunk
+	seen := make(map[string]int) // chunks so far per source
+	for _, d := range docs {
+		for _, text := range c.Split(d.Text) {
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			n := seen[d.Source]
+			seen[d.Source]++
+			meta := maps.Clone(d.Metadata)
+			if meta == nil {
+				meta = make(map[string]string)
+			}
+			meta["source"] = d.Source
+			meta["chunk"] = strconv.Itoa(n)
+			out = append(out, Chunk{ID: fmt.Sprintf("%s#%d", d.Source, n), Text: text, Metadata: meta})
+		}
+	}
+	return out
+}
+
+// ingestBatch is how many chunks go into one embeddings call.
+const ingestBatch = 100
+
+// Ingest embeds the chunks with embedder and upserts them into store, a
+// batch at a time. Chunks keep their IDs, so ingesting a document again
+// replaces its chunks - but a new version with fewer chunks leaves the
+// old tail behind; delete by ID first if that matters.
+func Ingest(ctx context.Context, store vectorstore.Store, embedder llm.EmbeddingsProvider, chunks []Chunk) error {
+	for start := 0; start < len(chunks); start += ingestBatch {
+		batch := chunks[start:min(start+ingestBatch, len(chunks))]
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = c.Text
+		}
+		vectors, err := embedder.CreateEmbeddings(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("documents: embedding chunks: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("documents: got %d embeddings for %d chunks", len(vectors), len(batch))
+		}
+		records := make([]vectorstore.Record, len(batch))
+		for i, c := range batch {
+			records[i] = vectorstore.Record{ID: c.ID, Vector: vectors[i], Text: c.Text, Metadata: c.Metadata}
+		}
+		if err := store.Upsert(ctx, records...); err != nil {
+			return fmt.Errorf("documents: storing chunks: %w", err)
+		}
+	}
+	return nil
+}
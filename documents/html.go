@@ -0,0 +1,190 @@
+package documents
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// htmlSkipped are elements whose content isn't text for a reader.
+var htmlSkipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "iframe": true, "object": true, "canvas": true,
+}
+
+// htmlBlocks are elements that start on a new line.
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "br": true, "hr": true, "li": true, "ul": true, "ol": true,
+	"table": true, "tr": true, "section": true, "article": true, "header": true,
+	"footer": true, "nav": true, "aside": true, "main": true, "blockquote": true,
+	"pre": true, "dl": true, "dt": true, "dd": true, "figure": true, "figcaption": true,
+	"form": true, "address": true, "details": true, "summary": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// LoadHTML reads r as HTML and keeps the text a reader would see: scripts,
+// styles and markup go, block elements become line breaks, headings become
+// Markdown headings and list items bullets. The <title> becomes the
+// "title" metadata.
+//
+// It's a tolerant scanner, not a full HTML parser - good for articles and
+// documentation, not for pages that build their content with JavaScript.
+func LoadHTML(r io.Reader, source string) ([]Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("documents: reading %s: %w", source, err)
+	}
+	text, title := htmlText(normalizeText(data))
+	doc := Document{Source: source, Text: text, Metadata: map[string]string{}}
+	if title != "" {
+		doc.Metadata["title"] = title
+	}
+	return []Document{doc}, nil
+}
+
+// htmlText extracts the readable text and the title from an HTML page.
+func htmlText(s string) (text, title string) {
+	var out, titleBuf strings.Builder
+	inPre, inTitle := 0, false
+
+	write := func(raw string) {
+		t := html.UnescapeString(raw)
+		if inTitle {
+			titleBuf.WriteString(t)
+			return
+		}
+		if inPre == 0 {
+			t = strings.Join(strings.Fields(t), " ")
+			if t == "" {
+				if raw != "" {
+					out.WriteString(" ")
+				}
+				return
+			}
+			if raw[0] == ' ' || raw[0] == '\n' || raw[0] == '\t' {
+				t = " " + t
+			}
+			if last := raw[len(raw)-1]; last == ' ' || last == '\n' || last == '\t' {
+				t += " "
+			}
+		}
+		out.WriteString(t)
+	}
+
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			write(s)
+			break
+		}
+		write(s[:lt])
+		s = s[lt:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				s = ""
+			} else {
+				s = s[end+3:]
+			}
+			continue
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			s = skipPast(s, '>')
+			continue
+		}
+
+		end := tagEnd(s)
+		tag := s[1:end]
+		s = s[min(end+1, len(s)):]
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimLeft(tag, "/"))
+		if i := strings.IndexAny(name, " \t\n/"); i >= 0 {
+			name = name[:i]
+		}
+		if name == "" {
+			// Not a tag after all, like "a < b".
+			write("<" + tag)
+			continue
+		}
+
+		switch {
+		case htmlSkipped[name] && !closing:
+			closeTag := "</" + name
+			if i := strings.Index(strings.ToLower(s), closeTag); i >= 0 {
+				s = skipPast(s[i:], '>')
+			} else {
+				s = ""
+			}
+		case name == "title":
+			inTitle = !closing
+		case name == "pre":
+			if closing {
+				inPre = max(inPre-1, 0)
+			} else {
+				inPre++
+			}
+			out.WriteString("\n")
+		case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+			out.WriteString("\n\n")
+			if !closing {
+				out.WriteString(strings.Repeat("#", int(name[1]-'0')) + " ")
+			}
+		case name == "li" && !closing:
+			out.WriteString("\n- ")
+		case name == "p" || name == "tr" || name == "table":
+			out.WriteString("\n\n")
+		case name == "td" || name == "th":
+			out.WriteString(" ")
+		case htmlBlocks[name]:
+			out.WriteString("\n")
+		}
+	}
+	return tidyLines(out.String()), strings.Join(strings.Fields(titleBuf.String()), " ")
+}
+
+// tagEnd returns the index of the '>' closing the tag at the start of s,
+// skipping quoted attribute values, or len(s) if there is none.
+func tagEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+	return len(s)
+}
+
+func skipPast(s string, c byte) string {
+	if i := strings.IndexByte(s, c); i >= 0 {
+		return s[i+1:]
+	}
+	return ""
+}
+
+// tidyLines trims every line and allows at most one blank line in a row.
+func tidyLines(s string) string {
+	var out []string
+	blank := true // drops leading blank lines
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,935 @@
+package documents
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrEncryptedPDF is returned by LoadPDF for encrypted PDFs, whose text
+// can't be read without decrypting them first.
+var ErrEncryptedPDF = errors.New("documents: PDF is encrypted")
+
+// ErrNoText is returned by LoadPDF for PDFs without extractable text -
+// usually scans, which need OCR.
+var ErrNoText = errors.New("documents: PDF has no extractable text")
+
+// LoadPDF extracts the text of a PDF, one Document per page with "page"
+// (from 1) in its metadata, and "title" if the PDF has one. Empty pages
+// are left out.
+//
+// It reads the text-showing operators of each page's content, decoded
+// with the fonts' ToUnicode maps where there are any and as Latin text
+// where there aren't. That covers what word processors, browsers and
+// LaTeX produce. Text in form XObjects, layout beyond line breaks, and
+// scanned pages are out of reach.
+func LoadPDF(r io.Reader, source string) ([]Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("documents: reading %s: %w", source, err)
+	}
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, fmt.Errorf("documents: %s is not a PDF", source)
+	}
+
+	f := parsePDF(data)
+	if _, ok := f.trailer["Encrypt"]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrEncryptedPDF, source)
+	}
+	title := ""
+	if info := f.dict(f.trailer["Info"]); info != nil {
+		if s, ok := f.resolve(info["Title"]).(pdfString); ok {
+			title = strings.TrimSpace(pdfTextString(s))
+		}
+	}
+
+	var docs []Document
+	for i, page := range f.pages() {
+		text := f.pageText(page)
+		if text == "" {
+			continue
+		}
+		meta := map[string]string{"page": strconv.Itoa(i + 1)}
+		if title != "" {
+			meta["title"] = title
+		}
+		docs = append(docs, Document{Source: source, Text: text, Metadata: meta})
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoText, source)
+	}
+	return docs, nil
+}
+
+// The PDF object model, as far as text extraction needs it.
+type (
+	pdfName    string
+	pdfKeyword string // operators in content streams, "obj", "R", ...
+	pdfString  []byte // literal or hex, decoded to its bytes
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte // still encoded
+	}
+)
+
+// pdfLexer reads PDF tokens and objects from b.
+type pdfLexer struct {
+	b    []byte
+	pos  int
+	refs bool // combine "n g R" into a pdfRef; off in content streams
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.b) {
+		switch c := l.b[l.pos]; {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.b) && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object reads the next object, with dictionaries, arrays and - if refs
+// is set - references assembled. ok is false at the end of the input.
+func (l *pdfLexer) object() (v any, ok bool) {
+	v, ok = l.token()
+	if !ok || !l.refs {
+		return v, ok
+	}
+	num, isInt := pdfInt(v)
+	if !isInt {
+		return v, true
+	}
+	// Maybe "num gen R".
+	save := l.pos
+	if g, ok := l.token(); ok {
+		if gen, isInt := pdfInt(g); isInt {
+			if r, ok := l.token(); ok && r == pdfKeyword("R") {
+				return pdfRef{num, gen}, true
+			}
+		}
+	}
+	l.pos = save
+	return v, true
+}
+
+func (l *pdfLexer) token() (any, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.b) {
+		return nil, false
+	}
+	switch c := l.b[l.pos]; c {
+	case '/':
+		return l.name(), true
+	case '(':
+		return l.literal(), true
+	case '<':
+		if l.pos+1 < len(l.b) && l.b[l.pos+1] == '<' {
+			return l.dict(), true
+		}
+		return l.hex(), true
+	case '[':
+		l.pos++
+		var arr pdfArray
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.b) {
+				return arr, true
+			}
+			if l.b[l.pos] == ']' {
+				l.pos++
+				return arr, true
+			}
+			v, ok := l.object()
+			if !ok {
+				return arr, true
+			}
+			arr = append(arr, v)
+		}
+	case '>':
+		if l.pos+1 < len(l.b) && l.b[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), true
+		}
+		l.pos++
+		return pdfKeyword(">"), true
+	case ']', '{', '}', ')':
+		l.pos++
+		return pdfKeyword(c), true
+	}
+
+	start := l.pos
+	for l.pos < len(l.b) && !isPDFSpace(l.b[l.pos]) && !isPDFDelim(l.b[l.pos]) {
+		l.pos++
+	}
+	word := string(l.b[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, true
+	}
+	switch word {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	case "null":
+		return nil, true
+	}
+	return pdfKeyword(word), true
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++ // the slash
+	var b []byte
+	for l.pos < len(l.b) && !isPDFSpace(l.b[l.pos]) && !isPDFDelim(l.b[l.pos]) {
+		c := l.b[l.pos]
+		if c == '#' && l.pos+2 < len(l.b) {
+			if v, err := strconv.ParseUint(string(l.b[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return pdfName(b)
+}
+
+func (l *pdfLexer) literal() pdfString {
+	l.pos++ // the parenthesis
+	var out []byte
+	depth := 1
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.b) {
+				return out
+			}
+			e := l.b[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n': // a line continuation
+				if e == '\r' && l.pos < len(l.b) && l.b[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.b) && l.b[l.pos] >= '0' && l.b[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.b[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e // \( \) \\ and unknown escapes
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) hex() pdfString {
+	l.pos++ // the angle bracket
+	var digits []byte
+	for l.pos < len(l.b) && l.b[l.pos] != '>' {
+		if c := l.b[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(out, digits)
+	return out[:n]
+}
+
+func (l *pdfLexer) dict() pdfDict {
+	l.pos += 2
+	d := pdfDict{}
+	for {
+		key, ok := l.object()
+		if !ok || key == pdfKeyword(">>") {
+			return d
+		}
+		name, isName := key.(pdfName)
+		if !isName {
+			continue // malformed; resynchronize on the next name
+		}
+		v, ok := l.object()
+		if !ok {
+			return d
+		}
+		if v == pdfKeyword(">>") {
+			return d
+		}
+		d[name] = v
+	}
+}
+
+func pdfInt(v any) (int, bool) {
+	f, ok := v.(float64)
+	if !ok || f != math.Trunc(f) || f < 0 || f > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func pdfNumber(v any) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+// pdfFile is a parsed PDF: its objects by number and the trailer.
+type pdfFile struct {
+	objs    map[int]any
+	trailer pdfDict
+}
+
+var (
+	pdfObjHeader = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfTrailer   = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// parsePDF finds every object by scanning for "n g obj" rather than
+// trusting the cross-reference table, which is often wrong in the wild.
+// Later definitions win, as they do with incremental updates.
+func parsePDF(data []byte) *pdfFile {
+	f := &pdfFile{objs: make(map[int]any), trailer: pdfDict{}}
+	for pos := 0; pos < len(data); {
+		loc := pdfObjHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{b: data, pos: pos + loc[1], refs: true}
+		obj, ok := l.object()
+		if !ok {
+			break
+		}
+		end := l.pos
+
+		l.skipSpace()
+		if d, isDict := obj.(pdfDict); isDict && bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+			start := l.pos + len("stream")
+			if bytes.HasPrefix(data[start:], []byte("\r\n")) {
+				start += 2
+			} else if start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+				start++
+			}
+			raw := streamData(data[start:], d)
+			obj = &pdfStream{dict: d, raw: raw}
+			end = start + len(raw)
+		}
+		f.objs[num] = obj
+		pos = max(end, pos+loc[1])
+	}
+
+	// The trailer: classic trailer dictionaries, or cross-reference stream
+	// dictionaries in newer files. The last one is the current one.
+	for _, loc := range pdfTrailer.FindAllIndex(data, -1) {
+		l := &pdfLexer{b: data, pos: loc[1] - 2, refs: true}
+		if d, ok := l.object(); ok {
+			if d, ok := d.(pdfDict); ok {
+				for k, v := range d {
+					f.trailer[k] = v
+				}
+			}
+		}
+	}
+	nums := make([]int, 0, len(f.objs))
+	for n := range f.objs {
+		nums = append(nums, n)
+	}
+	slices.Sort(nums)
+	for _, n := range nums {
+		if s, ok := f.objs[n].(*pdfStream); ok && s.dict["Type"] == pdfName("XRef") {
+			for _, k := range []pdfName{"Root", "Info", "Encrypt"} {
+				if v, ok := s.dict[k]; ok {
+					f.trailer[k] = v
+				}
+			}
+		}
+	}
+
+	// Objects packed in object streams, unless defined directly.
+	for _, n := range nums {
+		s, ok := f.objs[n].(*pdfStream)
+		if !ok || s.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := f.decode(s)
+		if err != nil {
+			continue
+		}
+		count, _ := pdfInt(f.resolve(s.dict["N"]))
+		first, _ := pdfInt(f.resolve(s.dict["First"]))
+		header := &pdfLexer{b: data}
+		for i := 0; i < count; i++ {
+			numTok, ok1 := header.token()
+			offTok, ok2 := header.token()
+			num, isNum := pdfInt(numTok)
+			off, isOff := pdfInt(offTok)
+			if !ok1 || !ok2 || !isNum || !isOff {
+				break
+			}
+			if _, exists := f.objs[num]; exists || first+off >= len(data) {
+				continue
+			}
+			l := &pdfLexer{b: data, pos: first + off, refs: true}
+			if v, ok := l.object(); ok {
+				f.objs[num] = v
+			}
+		}
+	}
+	return f
+}
+
+// streamData finds the end of a stream's data: Length when it's given
+// directly and checks out, else the "endstream" keyword.
+func streamData(b []byte, d pdfDict) []byte {
+	if n, ok := pdfInt(d["Length"]); ok && n <= len(b) {
+		if rest := bytes.TrimLeft(b[n:], " \t\r\n"); bytes.HasPrefix(rest, []byte("endstream")) {
+			return b[:n]
+		}
+	}
+	end := bytes.Index(b, []byte("endstream"))
+	if end < 0 {
+		return b
+	}
+	return bytes.TrimRight(b[:end], "\r\n")
+}
+
+func (f *pdfFile) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		r, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = f.objs[r.num]
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(v any) pdfDict {
+	switch v := f.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode undoes a stream's filters. Image filters aren't supported - text
+// never needs them.
+func (f *pdfFile) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{v}
+	case pdfArray:
+		filters = v
+	}
+	data := s.raw
+	for _, filter := range filters {
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			out, err := io.ReadAll(zr)
+			// Truncated streams are common; keep what inflated.
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && len(out) == 0 {
+				return nil, err
+			}
+			data = out
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = (&pdfLexer{b: append([]byte("<"), data...)}).hex()
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			enc := bytes.TrimSuffix(bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))), []byte("~>"))
+			out := make([]byte, len(enc)*4/5+4)
+			n, _, err := ascii85.Decode(out, enc, true)
+			if err != nil {
+				return nil, err
+			}
+			data = out[:n]
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", filter)
+		}
+	}
+	return data, nil
+}
+
+// pdfPage is a page dictionary with the resources it inherits.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages in order, walking the page tree from the
+// catalog - or, in a file too broken for that, every page object in
+// object number order.
+func (f *pdfFile) pages() []pdfPage {
+	var pages []pdfPage
+	var walk func(node pdfDict, inherited pdfDict, depth int)
+	walk = func(node pdfDict, inherited pdfDict, depth int) {
+		if node == nil || depth > 64 || len(pages) > 100_000 {
+			return
+		}
+		res := f.dict(node["Resources"])
+		if res == nil {
+			res = inherited
+		}
+		if kids, ok := f.resolve(node["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(f.dict(kid), res, depth+1)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: node, resources: res})
+	}
+
+	if root := f.dict(f.trailer["Root"]); root != nil {
+		walk(f.dict(root["Pages"]), nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+	nums := make([]int, 0, len(f.objs))
+	for n, o := range f.objs {
+		if d, ok := o.(pdfDict); ok && d["Type"] == pdfName("Page") {
+			nums = append(nums, n)
+		}
+	}
+	slices.Sort(nums)
+	for _, n := range nums {
+		d := f.objs[n].(pdfDict)
+		pages = append(pages, pdfPage{dict: d, resources: f.dict(d["Resources"])})
+	}
+	return pages
+}
+
+// pageText extracts a page's text.
+func (f *pdfFile) pageText(p pdfPage) string {
+	var content []byte
+	var streams []any
+	switch v := f.resolve(p.dict["Contents"]).(type) {
+	case *pdfStream:
+		streams = []any{v}
+	case pdfArray:
+		streams = v
+	}
+	for _, s := range streams {
+		if s, ok := f.resolve(s).(*pdfStream); ok {
+			if data, err := f.decode(s); err == nil {
+				content = append(content, data...)
+				content = append(content, '\n')
+			}
+		}
+	}
+	if len(content) == 0 {
+		return ""
+	}
+
+	fonts := make(map[pdfName]*pdfFont)
+	for name, ref := range f.dict(p.resources["Font"]) {
+		fonts[name] = f.font(f.dict(ref))
+	}
+	return extractText(content, fonts)
+}
+
+// pdfFont knows how to turn a font's character codes into text.
+type pdfFont struct {
+	toUnicode  map[uint32]string // from the ToUnicode CMap. nil means none.
+	codeLen    int               // bytes per code in toUnicode
+	cid        bool              // a composite font; without a ToUnicode map its codes are unreadable
+	difference map[byte]string   // from the encoding's Differences
+}
+
+func (f *pdfFile) font(d pdfDict) *pdfFont {
+	font := &pdfFont{codeLen: 1}
+	if d == nil {
+		return font
+	}
+	font.cid = d["Subtype"] == pdfName("Type0")
+	if s, ok := f.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := f.decode(s); err == nil {
+			font.toUnicode, font.codeLen = parseCMap(data)
+		}
+	}
+	if enc := f.dict(d["Encoding"]); enc != nil {
+		if diffs, ok := f.resolve(enc["Differences"]).(pdfArray); ok {
+			font.difference = make(map[byte]string)
+			code := 0
+			for _, v := range diffs {
+				switch v := f.resolve(v).(type) {
+				case float64:
+					code = int(v)
+				case pdfName:
+					if s := glyphText(string(v)); s != "" && code >= 0 && code < 256 {
+						font.difference[byte(code)] = s
+					}
+					code++
+				}
+			}
+		}
+	}
+	return font
+}
+
+// decode turns a shown string into text.
+func (font *pdfFont) decode(s pdfString) string {
+	var b strings.Builder
+	if font != nil && font.toUnicode != nil {
+		for i := 0; i+font.codeLen <= len(s); i += font.codeLen {
+			var code uint32
+			for _, c := range s[i : i+font.codeLen] {
+				code = code<<8 | uint32(c)
+			}
+			if t, ok := font.toUnicode[code]; ok {
+				b.WriteString(t)
+			} else if font.codeLen == 1 {
+				b.WriteRune(winAnsi(s[i]))
+			}
+		}
+		return b.String()
+	}
+	if font != nil && font.cid {
+		return ""
+	}
+	for _, c := range s {
+		if font != nil {
+			if t, ok := font.difference[c]; ok {
+				b.WriteString(t)
+				continue
+			}
+		}
+		b.WriteRune(winAnsi(c))
+	}
+	return b.String()
+}
+
+// winAnsi maps a byte of the standard Latin encoding to its character.
+func winAnsi(c byte) rune {
+	switch c {
+	case 0x80:
+		return '€'
+	case 0x85:
+		return '…'
+	case 0x91:
+		return '‘'
+	case 0x92:
+		return '’'
+	case 0x93:
+		return '“'
+	case 0x94:
+		return '”'
+	case 0x95:
+		return '•'
+	case 0x96:
+		return '–'
+	case 0x97:
+		return '—'
+	case 0xa0:
+		return ' '
+	}
+	if c < 0x20 && c != '\t' && c != '\n' {
+		return ' '
+	}
+	return rune(c)
+}
+
+// glyphNames are the common glyph names that aren't a single character.
+var glyphNames = map[string]string{
+	"space": " ", "period": ".", "comma": ",", "colon": ":", "semicolon": ";",
+	"hyphen": "-", "endash": "–", "emdash": "—", "quoteright": "’", "quoteleft": "‘",
+	"quotedblleft": "“", "quotedblright": "”", "quotesingle": "'", "quotedbl": "\"",
+	"exclam": "!", "question": "?", "parenleft": "(", "parenright": ")",
+	"bracketleft": "[", "bracketright": "]", "slash": "/", "ampersand": "&",
+	"percent": "%", "dollar": "$", "at": "@", "bullet": "•", "ellipsis": "…",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"plus": "+", "equal": "=", "asterisk": "*", "numbersign": "#", "underscore": "_",
+}
+
+// glyphText returns the text for a glyph name, "" if it's not known.
+func glyphText(name string) string {
+	if len(name) == 1 {
+		return name
+	}
+	if t, ok := glyphNames[name]; ok {
+		return t
+	}
+	if hexCode, ok := strings.CutPrefix(name, "uni"); ok && len(hexCode) == 4 {
+		if v, err := strconv.ParseUint(hexCode, 16, 16); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+// parseCMap reads a ToUnicode CMap's bfchar and bfrange mappings, and the
+// code length from its codespace range.
+func parseCMap(data []byte) (map[uint32]string, int) {
+	m := make(map[uint32]string)
+	codeLen := 0
+	l := &pdfLexer{b: data}
+	var operands []any
+	for {
+		tok, ok := l.token()
+		if !ok {
+			break
+		}
+		kw, isKw := tok.(pdfKeyword)
+		if !isKw {
+			operands = append(operands, tok)
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].(pdfString); ok && codeLen == 0 {
+					codeLen = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					m[cmapCode(src)] = utf16BE(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := cmapCode(lo), cmapCode(hi)
+				if end < start || end-start > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					for code := start; code <= end; code++ {
+						m[code] = utf16BE(incrementLast(dst, int(code-start)))
+					}
+				case pdfArray:
+					for j, d := range dst {
+						if s, ok := d.(pdfString); ok && start+uint32(j) <= end {
+							m[start+uint32(j)] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(string(kw), "end") || strings.HasPrefix(string(kw), "begin") {
+			operands = operands[:0]
+		}
+	}
+	if codeLen == 0 {
+		codeLen = 2
+	}
+	return m, codeLen
+}
+
+func cmapCode(s pdfString) uint32 {
+	var code uint32
+	for _, c := range s {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+// incrementLast adds n to the last byte pair of a UTF-16BE string, as a
+// bfrange destination increases along the range.
+func incrementLast(s pdfString, n int) pdfString {
+	out := slices.Clone(s)
+	if len(out) < 2 {
+		return out
+	}
+	v := int(out[len(out)-2])<<8 | int(out[len(out)-1]) + n
+	out[len(out)-2], out[len(out)-1] = byte(v>>8), byte(v)
+	return out
+}
+
+func utf16BE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfTextString decodes a text string outside content streams, like the
+// title: UTF-16BE with a byte order mark, or else Latin.
+func pdfTextString(s pdfString) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		return utf16BE(s[2:])
+	}
+	var b strings.Builder
+	for _, c := range s {
+		b.WriteRune(winAnsi(c))
+	}
+	return b.String()
+}
+
+// extractText runs a content stream's text operators and returns what
+// they show, with line breaks where the text moves down.
+func extractText(content []byte, fonts map[pdfName]*pdfFont) string {
+	var out strings.Builder
+	var operands []any
+	var font *pdfFont
+	lastY, haveY := 0.0, false
+
+	newline := func() {
+		if s := out.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			out.WriteByte('\n')
+		}
+	}
+	space := func() {
+		if s := out.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+			out.WriteByte(' ')
+		}
+	}
+	lastString := func() (pdfString, bool) {
+		if len(operands) == 0 {
+			return nil, false
+		}
+		s, ok := operands[len(operands)-1].(pdfString)
+		return s, ok
+	}
+
+	l := &pdfLexer{b: content}
+	for {
+		tok, ok := l.token()
+		if !ok {
+			break
+		}
+		kw, isKw := tok.(pdfKeyword)
+		if !isKw {
+			operands = append(operands, tok)
+			continue
+		}
+		switch kw {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = fonts[name]
+				}
+			}
+		case "Tj":
+			if s, ok := lastString(); ok {
+				out.WriteString(font.decode(s))
+			}
+		case "'", "\"":
+			newline()
+			if s, ok := lastString(); ok {
+				out.WriteString(font.decode(s))
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				if arr, ok := operands[len(operands)-1].(pdfArray); ok {
+					for _, el := range arr {
+						switch el := el.(type) {
+						case pdfString:
+							out.WriteString(font.decode(el))
+						case float64:
+							// A big enough step back is a word space.
+							if el < -200 {
+								space()
+							}
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 && pdfNumber(operands[len(operands)-1]) != 0 {
+				newline()
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				y := pdfNumber(operands[5])
+				if haveY && math.Abs(y-lastY) > 1 {
+					newline()
+				} else {
+					space()
+				}
+				lastY, haveY = y, true
+			}
+		case "ET":
+			space()
+		case "BI":
+			// An inline image: skip its binary data, from ID to EI.
+			if i := bytes.Index(content[l.pos:], []byte("ID")); i >= 0 {
+				l.pos += i + 2
+				if j := bytes.Index(content[l.pos:], []byte("EI")); j >= 0 {
+					l.pos += j + 2
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return tidyLines(strings.Join(lines, "\n"))
+}
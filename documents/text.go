@@ -0,0 +1,69 @@
+package documents
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// LoadText reads r as UTF-8 text. Invalid bytes become U+FFFD, and line
+// endings are normalized to \n.
+func LoadText(r io.Reader, source string) ([]Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("documents: reading %s: %w", source, err)
+	}
+	return []Document{{Source: source, Text: normalizeText(data)}}, nil
+}
+
+func normalizeText(data []byte) string {
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "\ufffd")
+	}
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// LoadMarkdown reads r as Markdown. The text stays Markdown - models read
+// it well, and headings help them place a passage. YAML front matter is
+// taken out and its simple "key: value" lines become metadata; the title
+// is the front matter's, or else the first heading.
+func LoadMarkdown(r io.Reader, source string) ([]Document, error) {
+	docs, err := LoadText(r, source)
+	if err != nil {
+		return nil, err
+	}
+	doc := &docs[0]
+	doc.Metadata = make(map[string]string)
+
+	if rest, ok := strings.CutPrefix(doc.Text, "---\n"); ok {
+		if front, body, ok := strings.Cut(rest, "\n---\n"); ok {
+			for _, line := range strings.Split(front, "\n") {
+				key, value, ok := strings.Cut(line, ":")
+				if !ok || strings.HasPrefix(line, " ") {
+					continue
+				}
+				value = strings.Trim(strings.TrimSpace(value), `"'`)
+				if value != "" {
+					doc.Metadata[strings.TrimSpace(key)] = value
+				}
+			}
+			doc.Text = body
+		}
+	}
+
+	if doc.Metadata["title"] == "" {
+		sc := bufio.NewScanner(strings.NewReader(doc.Text))
+		for sc.Scan() {
+			if title, ok := strings.CutPrefix(sc.Text(), "# "); ok {
+				doc.Metadata["title"] = strings.TrimSpace(title)
+				break
+			}
+		}
+	}
+	return docs, nil
+}
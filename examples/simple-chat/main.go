@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/demo"
 	"go-agent-sdk/llm/openai"
 	// "go-agent-sdk/llm/anthropic"
 	// "go-agent-sdk/llm/gemini"
@@ -15,15 +17,21 @@ import (
 
 // Simple chat example — the most basic SDK usage.
 // Creates an agent and sends a single message without any tools.
+// Without OPENROUTER_API_KEY it runs against the offline demo provider.
 
 func main() {
+	var provider llm.ChatProvider
+
 	apiKey := os.Getenv("OPENROUTER_API_KEY")
 	if apiKey == "" {
-		log.Fatal("Set OPENROUTER_API_KEY environment variable")
+		log.Println("OPENROUTER_API_KEY not set - using the offline demo provider")
+		provider = demo.New([]demo.Rule{
+			{Match: "goroutines", Reply: "Goroutines are lightweight threads managed by the Go runtime. You start one with the go keyword and they communicate over channels."},
+		})
+	} else {
+		// Pick your provider (uncomment one). See README for the full list.
+		provider = openai.NewOpenRouter(apiKey, "google/gemini-3-flash-preview")
 	}
-
-	// Pick your provider (uncomment one). See README for the full list.
-	provider := openai.NewOpenRouter(apiKey, "google/gemini-3-flash-preview")
 	// provider := openai.New(os.Getenv("OPENAI_API_KEY"), "gpt-4o")
 	// provider := anthropic.New(os.Getenv("ANTHROPIC_API_KEY"), "claude-sonnet-4-20250514")
 	// provider := gemini.New(os.Getenv("GEMINI_API_KEY"), "gemini-2.5-flash")
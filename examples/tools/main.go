@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/demo"
 	"go-agent-sdk/llm/openai"
 	// "go-agent-sdk/llm/anthropic"
 	// "go-agent-sdk/llm/gemini"
@@ -40,13 +42,19 @@ func LookupFact(args LookupArgs) string {
 }
 
 func main() {
+	var provider llm.ChatProvider
+
 	apiKey := os.Getenv("OPENROUTER_API_KEY")
 	if apiKey == "" {
-		log.Fatal("Set OPENROUTER_API_KEY environment variable")
+		// No key - script the model so the tool round trip still happens offline.
+		log.Println("OPENROUTER_API_KEY not set - using the offline demo provider")
+		provider = demo.New([]demo.Rule{
+			{Match: "go", Tool: "lookup_fact", Args: `{"topic":"go"}`, Reply: "Here's a bit of history: {result}"},
+		})
+	} else {
+		// Pick your provider (uncomment one). See README for the full list.
+		provider = openai.NewOpenRouter(apiKey, "google/gemini-3-flash-preview")
 	}
-
-	// Pick your provider (uncomment one). See README for the full list.
-	provider := openai.NewOpenRouter(apiKey, "google/gemini-3-flash-preview")
 	// provider := openai.New(os.Getenv("OPENAI_API_KEY"), "gpt-4o")
 	// provider := anthropic.New(os.Getenv("ANTHROPIC_API_KEY"), "claude-sonnet-4-20250514")
 	// provider := gemini.New(os.Getenv("GEMINI_API_KEY"), "gemini-2.5-flash")
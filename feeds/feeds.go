@@ -0,0 +1,228 @@
+// Package feeds watches RSS and Atom feeds and hands new entries to an
+// agent on a schedule - a morning news digest, a changelog watcher, a
+// competitor monitor. Entries already handled are remembered in a State,
+// so each one is processed once even across restarts:
+//
+//	state, err := feeds.NewFileState("seen.json")
+//	if err != nil {
+//	    return err
+//	}
+//	m := feeds.New([]string{"https://go.dev/blog/feed.atom", "https://news.ycombinator.com/rss"}, state)
+//	digest := feeds.AgentHandler(a, "Summarise these posts in five bullet points for the team.",
+//	    func(ctx context.Context, res *agent.RunResult) error {
+//	        return slack.Post(ctx, res.Content)
+//	    })
+//	err = m.Run(ctx, 24*time.Hour, digest) // until ctx is cancelled
+//
+// Entries are marked seen only once the handler succeeds, so a failed run
+// gets them again next time.
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-agent-sdk/documents"
+)
+
+// Item is one feed entry.
+type Item struct {
+	Feed      string // the feed's URL
+	FeedTitle string // the feed's own title
+	ID        string // the entry's guid or id, or its link when it has none
+	Title     string
+	Link      string
+	Summary   string // the description or summary, as plain text
+	Author    string
+	Published time.Time // zero when the feed doesn't say
+}
+
+// key identifies the item across feeds, for State.
+func (it Item) key() string {
+	return it.Feed + " " + it.ID
+}
+
+type xmlFeed struct {
+	XMLName xml.Name
+	Title   string `xml:"title"` // Atom
+	// RSS 2.0 and RSS 1.0
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []xmlItem `xml:"item"` // RSS 2.0
+	} `xml:"channel"`
+	Items   []xmlItem  `xml:"item"` // RSS 1.0 (RDF)
+	Entries []xmlEntry `xml:"entry"`
+}
+
+type xmlItem struct {
+	Title       string   `xml:"title"`
+	Links       []string `xml:"link"`
+	GUID        string   `xml:"guid"`
+	About       string   `xml:"about,attr"` // rdf:about
+	Description string   `xml:"description"`
+	Content     string   `xml:"encoded"` // content:encoded
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"` // dc:date
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"creator"` // dc:creator
+}
+
+type xmlEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Author    struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom feed. feed is its URL, recorded
+// on the items.
+func Parse(r io.Reader, feed string) ([]Item, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.CharsetReader = charsetReader
+	var f xmlFeed
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("feeds: parsing %s: %w", feed, err)
+	}
+
+	var items []Item
+	switch f.XMLName.Local {
+	case "feed":
+		for _, e := range f.Entries {
+			it := Item{Feed: feed, FeedTitle: clean(f.Title), ID: strings.TrimSpace(e.ID), Title: clean(e.Title), Author: clean(e.Author.Name)}
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					it.Link = strings.TrimSpace(l.Href)
+					break
+				}
+			}
+			it.Summary = plain(first(e.Summary, e.Content))
+			it.Published = parseTime(first(e.Published, e.Updated))
+			items = append(items, finish(it))
+		}
+	case "rss", "RDF":
+		title := clean(f.Channel.Title)
+		for _, x := range append(f.Channel.Items, f.Items...) {
+			it := Item{Feed: feed, FeedTitle: title, ID: strings.TrimSpace(first(x.GUID, x.About)), Title: clean(x.Title)}
+			for _, l := range x.Links {
+				if l = strings.TrimSpace(l); l != "" {
+					it.Link = l
+					break
+				}
+			}
+			it.Summary = plain(first(x.Description, x.Content))
+			it.Author = clean(first(x.Creator, x.Author))
+			it.Published = parseTime(first(x.PubDate, x.Date))
+			items = append(items, finish(it))
+		}
+	default:
+		return nil, fmt.Errorf("feeds: %s is not an RSS or Atom feed (root element <%s>)", feed, f.XMLName.Local)
+	}
+	return items, nil
+}
+
+// finish fills in an ID for entries that have none.
+func finish(it Item) Item {
+	if it.ID == "" {
+		it.ID = it.Link
+	}
+	if it.ID == "" {
+		it.ID = it.Title + " " + it.Published.Format(time.RFC3339)
+	}
+	return it
+}
+
+func first(s ...string) string {
+	for _, v := range s {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// maxSummary caps summaries, since some feeds carry whole articles.
+const maxSummary = 1000
+
+// plain turns an HTML description into text.
+func plain(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	text := s
+	if docs, err := documents.LoadHTML(strings.NewReader(s), ""); err == nil && len(docs) > 0 {
+		text = docs[0].Text
+	}
+	text = clean(text)
+	if len(text) > maxSummary {
+		cut := maxSummary
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "…"
+	}
+	return text
+}
+
+var timeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// charsetReader decodes the Latin-1 feeds that are still around; UTF-8 is
+// read as it is. windows-1252 is read as Latin-1, which differs only in
+// punctuation.
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return r, nil
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		for _, c := range data {
+			b.WriteRune(rune(c))
+		}
+		return &b, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
@@ -0,0 +1,302 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+)
+
+// Handler processes a batch of new items, oldest first. An error leaves
+// them unmarked, to be handled again on the next poll.
+type Handler func(ctx context.Context, items []Item) error
+
+// Monitor polls a set of feeds for items not handled yet.
+type Monitor struct {
+	feeds      []string
+	state      State
+	httpClient *http.Client
+	userAgent  string
+	maxItems   int
+	maxAge     time.Duration
+	filter     func(Item) bool
+	onError    func(error)
+
+	mu    sync.Mutex
+	cache map[string]cached // feed URL -> its last response
+}
+
+// cached is a feed's last response, so a poll can ask for it only if it
+// changed and reuse the items if not.
+type cached struct {
+	etag, lastModified string
+	items              []Item
+}
+
+// Option configures a Monitor.
+type Option func(*Monitor)
+
+// WithHTTPClient sets the HTTP client. The default is
+// llm.DefaultHTTPClient, which an llm.EgressPolicy can restrict.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(m *Monitor) {
+		m.httpClient = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent sent with each request.
+func WithUserAgent(ua string) Option {
+	return func(m *Monitor) {
+		m.userAgent = ua
+	}
+}
+
+// WithMaxItems caps the items a poll returns, keeping the newest; the
+// rest are left for later polls. The default is 50, so a first poll of a
+// long feed doesn't hand the agent hundreds of entries.
+func WithMaxItems(n int) Option {
+	return func(m *Monitor) {
+		m.maxItems = n
+	}
+}
+
+// WithMaxAge skips items published longer ago than d - on the first run,
+// that's the feed's whole backlog. Items with no date are kept.
+func WithMaxAge(d time.Duration) Option {
+	return func(m *Monitor) {
+		m.maxAge = d
+	}
+}
+
+// WithFilter keeps only the items keep returns true for, such as ones
+// mentioning the product. Items filtered out are marked seen all the same.
+func WithFilter(keep func(Item) bool) Option {
+	return func(m *Monitor) {
+		m.filter = keep
+	}
+}
+
+// OnError calls fn with errors Run carries on after: a feed that can't be
+// fetched, a handler that failed. By default they're dropped.
+func OnError(fn func(error)) Option {
+	return func(m *Monitor) {
+		m.onError = fn
+	}
+}
+
+// New returns a Monitor for the feeds at urls, remembering what it has
+// handled in state.
+func New(urls []string, state State, opts ...Option) *Monitor {
+	m := &Monitor{
+		feeds:     urls,
+		state:     state,
+		userAgent: "go-agent-sdk feeds",
+		maxItems:  50,
+		cache:     make(map[string]cached),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Poll fetches every feed and returns the items not marked yet, oldest
+// first. It doesn't mark them - call Mark once they're handled, or use
+// Process. A feed that fails doesn't stop the others; its error is
+// returned alongside the items from the rest.
+func (m *Monitor) Poll(ctx context.Context) ([]Item, error) {
+	var all []Item
+	var errs []error
+	for _, feed := range m.feeds {
+		items, err := m.fetch(ctx, feed)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		all = append(all, items...)
+	}
+
+	keys := make([]string, len(all))
+	for i, it := range all {
+		keys[i] = it.key()
+	}
+	seen, err := m.state.Seen(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("feeds: %w", err)
+	}
+	var fresh, skipped []Item
+	now := llm.Now(ctx)
+	for _, it := range all {
+		switch {
+		case seen[it.key()]:
+		case m.maxAge > 0 && !it.Published.IsZero() && now.Sub(it.Published) > m.maxAge,
+			m.filter != nil && !m.filter(it):
+			skipped = append(skipped, it)
+		default:
+			seen[it.key()] = true // a feed can list an entry twice
+			fresh = append(fresh, it)
+		}
+	}
+	if len(skipped) > 0 {
+		if err := m.Mark(ctx, skipped); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].Published.Before(fresh[j].Published) })
+	if m.maxItems > 0 && len(fresh) > m.maxItems {
+		fresh = fresh[len(fresh)-m.maxItems:]
+	}
+	return fresh, errors.Join(errs...)
+}
+
+// Mark records items as handled.
+func (m *Monitor) Mark(ctx context.Context, items []Item) error {
+	keys := make([]string, len(items))
+	for i, it := range items {
+		keys[i] = it.key()
+	}
+	if err := m.state.Mark(ctx, keys); err != nil {
+		return fmt.Errorf("feeds: %w", err)
+	}
+	return nil
+}
+
+// Process polls once and, if there's anything new, hands it to h and
+// marks it. It reports how many items h got. Feed errors are returned
+// after the items from the other feeds are processed.
+func (m *Monitor) Process(ctx context.Context, h Handler) (int, error) {
+	items, pollErr := m.Poll(ctx)
+	if len(items) == 0 {
+		return 0, pollErr
+	}
+	if err := h(ctx, items); err != nil {
+		return 0, errors.Join(fmt.Errorf("feeds: handler: %w", err), pollErr)
+	}
+	if err := m.Mark(ctx, items); err != nil {
+		return len(items), errors.Join(err, pollErr)
+	}
+	return len(items), pollErr
+}
+
+// Run calls Process now and then every interval until ctx is done, and
+// returns ctx's error. Errors along the way go to OnError and don't stop
+// it.
+func (m *Monitor) Run(ctx context.Context, every time.Duration, h Handler) error {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Process(ctx, h); err != nil && m.onError != nil && ctx.Err() == nil {
+			m.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetch downloads and parses one feed, asking only for changes since the
+// last time.
+func (m *Monitor) fetch(ctx context.Context, feed string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("feeds: %w", err)
+	}
+	req.Header.Set("User-Agent", m.userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5")
+	m.mu.Lock()
+	c := m.cache[feed]
+	m.mu.Unlock()
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	if c.lastModified != "" {
+		req.Header.Set("If-Modified-Since", c.lastModified)
+	}
+
+	hc := m.httpClient
+	if hc == nil {
+		hc = llm.DefaultHTTPClient()
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("feeds: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		// The same items as last time; the state says which are handled.
+		return c.items, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("feeds: %s: %s: %s", feed, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	items, err := Parse(io.LimitReader(resp.Body, 20<<20), feed)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.cache[feed] = cached{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified"), items: items}
+	m.mu.Unlock()
+	return items, nil
+}
+
+// AgentHandler returns a Handler that gives the items to a, after
+// instructions, and passes the result to out - to post the digest, mail
+// it, store it. Each batch starts a fresh conversation with just a's
+// system prompt, so digests don't pile up in the history.
+func AgentHandler(a *agent.Agent, instructions string, out func(ctx context.Context, res *agent.RunResult) error, opts ...agent.RunOption) Handler {
+	var mu sync.Mutex
+	return func(ctx context.Context, items []Item) error {
+		mu.Lock()
+		defer mu.Unlock()
+		a.History = make([]llm.Message, 0, 1)
+		if a.SystemPrompt != "" {
+			a.History = append(a.History, llm.NewSystemMessage(a.SystemPrompt))
+		}
+		res, err := a.RunWithResult(ctx, Prompt(instructions, items), opts...)
+		if err != nil {
+			return err
+		}
+		return out(ctx, res)
+	}
+}
+
+// Prompt lays out items for a model, after instructions: one section per
+// item with its title, source, date, link and summary.
+func Prompt(instructions string, items []Item) string {
+	var b strings.Builder
+	b.WriteString(instructions)
+	fmt.Fprintf(&b, "\n\n%d new items:\n", len(items))
+	for i, it := range items {
+		fmt.Fprintf(&b, "\n## %d. %s\n", i+1, it.Title)
+		source := it.FeedTitle
+		if source == "" {
+			source = it.Feed
+		}
+		fmt.Fprintf(&b, "Source: %s\n", source)
+		if !it.Published.IsZero() {
+			fmt.Fprintf(&b, "Published: %s\n", it.Published.UTC().Format("2006-01-02 15:04 UTC"))
+		}
+		if it.Author != "" {
+			fmt.Fprintf(&b, "Author: %s\n", it.Author)
+		}
+		if it.Link != "" {
+			fmt.Fprintf(&b, "Link: %s\n", it.Link)
+		}
+		if it.Summary != "" {
+			fmt.Fprintf(&b, "\n%s\n", it.Summary)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State remembers which items have been handled. Keys are opaque strings,
+// one per item.
+type State interface {
+	// Seen reports which of keys have been marked.
+	Seen(ctx context.Context, keys []string) (map[string]bool, error)
+	// Mark records keys as handled.
+	Mark(ctx context.Context, keys []string) error
+}
+
+// DefaultRetention is how long FileState and MemoryState remember an item.
+// Feeds drop old entries long before that, so one won't come back.
+const DefaultRetention = 90 * 24 * time.Hour
+
+// MemoryState is a State that lasts as long as the process.
+type MemoryState struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	retention time.Duration
+}
+
+// NewMemoryState returns an empty MemoryState.
+func NewMemoryState() *MemoryState {
+	return &MemoryState{seen: make(map[string]time.Time), retention: DefaultRetention}
+}
+
+// Seen implements State.
+func (s *MemoryState) Seen(_ context.Context, keys []string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := s.seen[k]; ok {
+			out[k] = true
+		}
+	}
+	return out, nil
+}
+
+// Mark implements State, and forgets items older than the retention.
+func (s *MemoryState) Mark(_ context.Context, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mark(keys)
+	return nil
+}
+
+func (s *MemoryState) mark(keys []string) {
+	now := time.Now()
+	for _, k := range keys {
+		s.seen[k] = now
+	}
+	for k, t := range s.seen {
+		if now.Sub(t) > s.retention {
+			delete(s.seen, k)
+		}
+	}
+}
+
+// FileState is a State kept in a JSON file, rewritten on every Mark.
+type FileState struct {
+	MemoryState
+	path string
+}
+
+// NewFileState loads the state at path, or starts an empty one if the
+// file doesn't exist yet.
+func NewFileState(path string) (*FileState, error) {
+	s := &FileState{MemoryState: *NewMemoryState(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("feeds: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.seen); err != nil {
+		return nil, fmt.Errorf("feeds: reading %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Mark implements State.
+func (s *FileState) Mark(_ context.Context, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mark(keys)
+	data, err := json.MarshalIndent(s.seen, "", "  ")
+	if err != nil {
+		return fmt.Errorf("feeds: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".feeds-*")
+	if err != nil {
+		return fmt.Errorf("feeds: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("feeds: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("feeds: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("feeds: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+// Package anonymize replaces personal data in conversations with stable
+// placeholders, so transcripts from production can be shared (for example
+// with a model-tuning team) without the people in them.
+//
+// Every distinct value gets its own placeholder - the first email address
+// seen becomes USER_EMAIL_1, the next different one USER_EMAIL_2 - and the
+// same value always maps to the same placeholder, across messages and across
+// conversations anonymized with the same Anonymizer. The conversations stay
+// coherent: "email USER_EMAIL_1" in one turn still matches USER_EMAIL_1 in
+// the tool call two turns later.
+//
+// The mapping back to real values is returned separately as a KeyMap.
+// Keep it out of the shared dataset; with it, Restore undoes the
+// anonymization.
+//
+//	anon := anonymize.New()
+//	clean := anon.Messages(a.History)
+//	saveDataset(clean)
+//	saveSecret(anon.Keys())
+//
+// Detection is pattern based (emails, phone numbers, card numbers, IP
+// addresses, US SSNs). It catches the structured identifiers that matter
+// most, not names or addresses in free text - add your own Detectors for
+// anything domain-specific (customer IDs, account numbers).
+package anonymize
+
+import (
+	"fmt"
+	"go-agent-sdk/llm"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Detector finds one kind of personal data.
+type Detector struct {
+	// Kind names the data in placeholders: "EMAIL" gives USER_EMAIL_1.
+	Kind string
+
+	// Pattern matches candidate values.
+	Pattern *regexp.Regexp
+
+	// Valid, if set, filters matches - e.g. a Luhn check so order numbers
+	// aren't mistaken for card numbers.
+	Valid func(match string) bool
+}
+
+// DefaultDetectors are used when New is called without WithDetectors.
+// Order matters: earlier detectors claim text first, so card numbers are
+// checked before the looser phone pattern.
+var DefaultDetectors = []Detector{
+	{Kind: "EMAIL", Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+	{Kind: "CARD", Pattern: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), Valid: luhn},
+	{Kind: "SSN", Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{Kind: "IP", Pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+	{Kind: "PHONE", Pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{2,4}\)[ .\-]?)?\d{2,4}[ .\-]\d{3,4}[ .\-]?\d{3,4}\b`)},
+}
+
+// KeyMap maps placeholders back to the original values.
+// It is the secret half of an anonymized dataset.
+type KeyMap map[string]string
+
+// Anonymizer replaces personal data with placeholders, remembering which
+// value got which placeholder. It is safe for concurrent use.
+type Anonymizer struct {
+	mu        sync.Mutex
+	detectors []Detector
+	prefix    string
+	byValue   map[string]string // "EMAIL\x00bob@example.com" -> USER_EMAIL_1
+	counts    map[string]int    // kind -> placeholders handed out
+	keys      KeyMap
+}
+
+// Option configures an Anonymizer.
+type Option func(*Anonymizer)
+
+// WithDetectors replaces the default detectors. To add to them instead:
+//
+//	anonymize.WithDetectors(append(anonymize.DefaultDetectors, customerIDs)...)
+func WithDetectors(detectors ...Detector) Option {
+	return func(a *Anonymizer) {
+		a.detectors = detectors
+	}
+}
+
+// WithPrefix sets the placeholder prefix. Defaults to "USER_".
+func WithPrefix(prefix string) Option {
+	return func(a *Anonymizer) {
+		a.prefix = prefix
+	}
+}
+
+// New creates an Anonymizer. Use one Anonymizer for a whole dataset so
+// placeholders are consistent across conversations.
+func New(opts ...Option) *Anonymizer {
+	a := &Anonymizer{
+		detectors: DefaultDetectors,
+		prefix:    "USER_",
+		byValue:   make(map[string]string),
+		counts:    make(map[string]int),
+		keys:      make(KeyMap),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Text anonymizes a single string.
+func (a *Anonymizer) Text(s string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.text(s)
+}
+
+// Messages returns an anonymized copy of msgs. Message content, tool call
+// arguments and tool results are all rewritten; msgs itself is untouched.
+func (a *Anonymizer) Messages(msgs []llm.Message) []llm.Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make([]llm.Message, len(msgs))
+	for i, m := range msgs {
+		m.Content = a.text(m.Content)
+		if len(m.ToolCalls) > 0 {
+			calls := make([]llm.ToolCall, len(m.ToolCalls))
+			for j, c := range m.ToolCalls {
+				c.Function.Arguments = a.text(c.Function.Arguments)
+				calls[j] = c
+			}
+			m.ToolCalls = calls
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// Keys returns a copy of the placeholder-to-value map built so far.
+func (a *Anonymizer) Keys() KeyMap {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	keys := make(KeyMap, len(a.keys))
+	for k, v := range a.keys {
+		keys[k] = v
+	}
+	return keys
+}
+
+// span is a detected value's position in a string.
+type span struct {
+	start, end int
+	kind       string
+}
+
+func (a *Anonymizer) text(s string) string {
+	if s == "" {
+		return s
+	}
+
+	// Collect matches from every detector, then drop overlaps - the
+	// detector listed first wins.
+	var spans []span
+	taken := func(start, end int) bool {
+		for _, sp := range spans {
+			if start < sp.end && sp.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, d := range a.detectors {
+		for _, loc := range d.Pattern.FindAllStringIndex(s, -1) {
+			if taken(loc[0], loc[1]) || (d.Valid != nil && !d.Valid(s[loc[0]:loc[1]])) {
+				continue
+			}
+			spans = append(spans, span{loc[0], loc[1], d.Kind})
+		}
+	}
+	if len(spans) == 0 {
+		return s
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(s[last:sp.start])
+		b.WriteString(a.placeholder(sp.kind, s[sp.start:sp.end]))
+		last = sp.end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// placeholder returns the stable placeholder for a value, creating it the
+// first time the value is seen.
+func (a *Anonymizer) placeholder(kind, value string) string {
+	key := kind + "\x00" + value
+	if p, ok := a.byValue[key]; ok {
+		return p
+	}
+	a.counts[kind]++
+	p := fmt.Sprintf("%s%s_%d", a.prefix, kind, a.counts[kind])
+	a.byValue[key] = p
+	a.keys[p] = value
+	return p
+}
+
+// Restore puts the original values back into anonymized messages.
+// It returns a copy; msgs is untouched.
+func Restore(msgs []llm.Message, keys KeyMap) []llm.Message {
+	// Replace longer placeholders first so USER_EMAIL_12 isn't mangled
+	// by USER_EMAIL_1.
+	pairs := make([]string, 0, 2*len(keys))
+	names := make([]string, 0, len(keys))
+	for p := range keys {
+		names = append(names, p)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, p := range names {
+		pairs = append(pairs, p, keys[p])
+	}
+	r := strings.NewReplacer(pairs...)
+
+	out := make([]llm.Message, len(msgs))
+	for i, m := range msgs {
+		m.Content = r.Replace(m.Content)
+		if len(m.ToolCalls) > 0 {
+			calls := make([]llm.ToolCall, len(m.ToolCalls))
+			for j, c := range m.ToolCalls {
+				c.Function.Arguments = r.Replace(c.Function.Arguments)
+				calls[j] = c
+			}
+			m.ToolCalls = calls
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum used by
+// payment cards.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -16,7 +16,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"go-agent-sdk/llm"
-	"io"
+	"go-agent-sdk/llm/devkit"
+	"go-agent-sdk/llm/internal/bufpool"
+	"go-agent-sdk/llm/schema"
 	"net/http"
 )
 
@@ -30,9 +32,26 @@ type anthropicRequest struct {
 	System      string             `json:"system,omitempty"`
 	Messages    []anthropicMessage `json:"messages"`
 	Tools       []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice  *toolChoice        `json:"tool_choice,omitempty"`
 	Temperature float64            `json:"temperature,omitempty"`
 	TopP        float64            `json:"top_p,omitempty"`
 	StopSeqs    []string           `json:"stop_sequences,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+	Thinking    *thinkingConfig    `json:"thinking,omitempty"`
+}
+
+// thinkingConfig enables extended thinking. budget_tokens must be at least
+// 1024 and below max_tokens, and temperature can't be set alongside it.
+type thinkingConfig struct {
+	Type         string `json:"type"` // always "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// toolChoice is Anthropic's tool_choice: "auto", "any" (some tool), "tool"
+// (the named one) or "none".
+type toolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 // anthropicMessage is a single message in the conversation.
@@ -56,17 +75,27 @@ type anthropicMessage struct {
 // Which fields are populated depends on the "type" field:
 //
 //	type="text"        : Text is set
+//	type="image"       : Source is set (base64 bytes or a URL)
+//	type="document"    : Source is set (base64 bytes, e.g. a PDF)
+//	type="thinking"    : Thinking, Signature are set (earlier reasoning, sent back)
 //	type="tool_use"    : ID, Name, Input are set (assistant asking to call a tool)
 //	type="tool_result" : ToolUseID, Content are set (us returning a tool's output)
 //
 // We use omitempty on everything except Type so the JSON stays clean —
 // a text block won't have empty "id" or "name" fields cluttering it up.
 type contentBlock struct {
-	Type string `json:"type"` // "text", "tool_use", or "tool_result"
+	Type string `json:"type"` // "text", "image", "document", "thinking", "tool_use", or "tool_result"
 
 	// Fields for type="text"
 	Text string `json:"text,omitempty"`
 
+	// Fields for type="image" and type="document"
+	Source *imageSource `json:"source,omitempty"`
+
+	// Fields for type="thinking"
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+
 	// Fields for type="tool_use" (assistant requesting a tool call)
 	ID    string `json:"id,omitempty"`
 	Name  string `json:"name,omitempty"`
@@ -76,7 +105,7 @@ type contentBlock struct {
 	ToolUseID string `json:"tool_use_id,omitempty"`
 	// Content here is the tool's output. We use any because Anthropic accepts
 	// either a plain string or an array of content blocks for rich results.
-	// For our purposes we always send a plain string.
+	// We send a string unless the tool returned images or files.
 	ResultContent any `json:"content,omitempty"`
 
 	// IsError signals to Claude that the tool execution failed.
@@ -85,6 +114,44 @@ type contentBlock struct {
 	IsError bool `json:"is_error,omitempty"`
 }
 
+// imageSource is where an image or document block's data comes from:
+//
+//	{"type": "base64", "media_type": "image/png", "data": "iVBOR..."}
+//	{"type": "url", "url": "https://..."}
+type imageSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// mapParts converts multimodal content parts to text, image and document
+// blocks. Data URLs become base64 sources; anything else is passed as a URL
+// for Anthropic to fetch.
+func mapParts(parts []llm.ContentPart) []contentBlock {
+	blocks := make([]contentBlock, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case p.Type == "text":
+			blocks = append(blocks, contentBlock{Type: "text", Text: p.Text})
+		case p.ImageURL != nil:
+			src := &imageSource{Type: "url", URL: p.ImageURL.URL}
+			if mime, data, ok := llm.ParseDataURL(p.ImageURL.URL); ok {
+				src = &imageSource{Type: "base64", MediaType: mime, Data: data}
+			}
+			blocks = append(blocks, contentBlock{Type: "image", Source: src})
+		case p.File != nil:
+			if mime, data, ok := llm.ParseDataURL(p.File.FileData); ok {
+				blocks = append(blocks, contentBlock{
+					Type:   "document",
+					Source: &imageSource{Type: "base64", MediaType: mime, Data: data},
+				})
+			}
+		}
+	}
+	return blocks
+}
+
 // anthropicTool describes a tool available to Claude.
 //
 // Anthropic's format is flatter than OpenAI's. Compare:
@@ -115,17 +182,21 @@ type anthropicResponse struct {
 }
 
 // responseBlock is a content block in the response.
-// Same union pattern as contentBlock, but only "text" and "tool_use" appear
-// in responses (the API never sends back "tool_result" — that's only in requests).
+// Same union pattern as contentBlock, but only "text", "thinking" and
+// "tool_use" appear in responses (the API never sends back "tool_result" —
+// that's only in requests).
 //
 //	type="text"     : Text is populated
+//	type="thinking" : Thinking and Signature are populated (extended thinking)
 //	type="tool_use" : ID, Name, Input are populated
 type responseBlock struct {
-	Type  string `json:"type"`            // "text" or "tool_use"
-	Text  string `json:"text,omitempty"`  // for type="text"
-	ID    string `json:"id,omitempty"`    // for type="tool_use"
-	Name  string `json:"name,omitempty"`  // for type="tool_use"
-	Input any    `json:"input,omitempty"` // for type="tool_use" — JSON object (map), NOT a string
+	Type      string `json:"type"`                // "text", "thinking" or "tool_use"
+	Text      string `json:"text,omitempty"`      // for type="text"
+	Thinking  string `json:"thinking,omitempty"`  // for type="thinking"
+	Signature string `json:"signature,omitempty"` // for type="thinking" — must be sent back with tool use
+	ID        string `json:"id,omitempty"`        // for type="tool_use"
+	Name      string `json:"name,omitempty"`      // for type="tool_use"
+	Input     any    `json:"input,omitempty"`     // for type="tool_use" — JSON object (map), NOT a string
 }
 
 // anthropicUsage tracks token consumption.
@@ -140,6 +211,15 @@ type anthropicUsage struct {
 	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
+// mapUsage converts Anthropic's counts to ours, computing the total.
+func mapUsage(u anthropicUsage) llm.Usage {
+	return llm.Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
+
 const (
 	// Base URL only — CreateChat appends "/v1/messages".
 	// If this included the path, WithBaseURL("https://my-proxy.com") would break
@@ -152,6 +232,7 @@ type Client struct {
 	model      string
 	baseURL    string
 	httpClient *http.Client
+	transport  []llm.TransportMiddleware // wraps httpClient's transport, see WithTransportMiddleware
 }
 
 type Option func(*Client)
@@ -168,9 +249,32 @@ func WithHTTPClient(hc *http.Client) Option {
 	}
 }
 
+// WithTransportMiddleware wraps the transport requests go through in mw,
+// the first outermost - to add tracing, metrics or retries from a library
+// you already use, on top of the default client or one from WithHTTPClient:
+//
+//	provider := anthropic.New(key, "claude-sonnet-4-20250514",
+//	    anthropic.WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+//	        return otelhttp.NewTransport(next)
+//	    }),
+//	)
+//
+// It can be given more than once and in any order with WithHTTPClient;
+// the middleware is applied once every option has been.
+func WithTransportMiddleware(mw ...llm.TransportMiddleware) Option {
+	return func(c *Client) {
+		c.transport = append(c.transport, mw...)
+	}
+}
+
 func (c *Client) ModelName() string {
 	return c.model
 }
+
+// NormalizeToolCallID implements llm.ToolCallIDNormalizer.
+func (c *Client) NormalizeToolCallID(id string) string {
+	return llm.AnthropicToolCallID(id)
+}
 func New(apiKey string, model string, opts ...Option) *Client {
 	c := &Client{
 		apiKey:     apiKey,
@@ -182,6 +286,7 @@ func New(apiKey string, model string, opts ...Option) *Client {
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.httpClient = llm.WrapHTTPClient(c.httpClient, c.transport...)
 	return c
 }
 
@@ -192,7 +297,10 @@ func mapRequest(req llm.ChatRequest) anthropicRequest {
 	var systemPrompt string
 	var messages []anthropicMessage
 
-	for _, msg := range req.Messages {
+	// Anthropic only accepts tool_use IDs matching [a-zA-Z0-9_-]+. IDs from
+	// other providers usually pass, but normalize anyway so replayed
+	// histories never get rejected. Calls and results map to the same ID.
+	for _, msg := range llm.NormalizeToolCallIDs(req.Messages, llm.AnthropicToolCallID) {
 		switch msg.Role {
 
 		case "system":
@@ -203,7 +311,12 @@ func mapRequest(req llm.ChatRequest) anthropicRequest {
 			systemPrompt += msg.Content
 
 		case "user":
-			contentJSON, _ := json.Marshal(msg.Content)
+			var contentJSON []byte
+			if len(msg.Parts) > 0 {
+				contentJSON, _ = json.Marshal(mapParts(msg.Parts))
+			} else {
+				contentJSON, _ = json.Marshal(msg.Content)
+			}
 			messages = append(messages, anthropicMessage{
 				Role:    "user",
 				Content: contentJSON,
@@ -214,6 +327,16 @@ func mapRequest(req llm.ChatRequest) anthropicRequest {
 				// Assistant with tool calls: text + tool_use blocks in one content array.
 				var blocks []contentBlock
 
+				// With extended thinking, the thinking block must come back first,
+				// signature intact, or the API rejects the tool result turn.
+				if msg.ReasoningSignature != "" {
+					blocks = append(blocks, contentBlock{
+						Type:      "thinking",
+						Thinking:  msg.Reasoning,
+						Signature: msg.ReasoningSignature,
+					})
+				}
+
 				if msg.Content != "" {
 					blocks = append(blocks, contentBlock{
 						Type: "text",
@@ -252,11 +375,15 @@ func mapRequest(req llm.ChatRequest) anthropicRequest {
 		case "tool":
 			// OpenAI has role="tool". Anthropic has no "tool" role — tool results
 			// go inside a role="user" message as a tool_result content block.
+			var result any = msg.Content
+			if len(msg.Parts) > 0 {
+				result = mapParts(msg.Parts)
+			}
 			blocks := []contentBlock{
 				{
 					Type:          "tool_result",
 					ToolUseID:     msg.ToolCallID,
-					ResultContent: msg.Content,
+					ResultContent: result,
 				},
 			}
 			contentJSON, _ := json.Marshal(blocks)
@@ -273,7 +400,7 @@ func mapRequest(req llm.ChatRequest) anthropicRequest {
 		tools = append(tools, anthropicTool{
 			Name:        t.Function.Name,
 			Description: t.Function.Description,
-			InputSchema: t.Function.Parameters,
+			InputSchema: schema.Anthropic(t.Function.Parameters),
 		})
 	}
 
@@ -283,7 +410,7 @@ func mapRequest(req llm.ChatRequest) anthropicRequest {
 		maxTokens = 4096
 	}
 
-	return anthropicRequest{
+	nativeReq := anthropicRequest{
 		Model:       req.Model,
 		MaxTokens:   maxTokens,
 		System:      systemPrompt,
@@ -293,14 +420,47 @@ func mapRequest(req llm.ChatRequest) anthropicRequest {
 		TopP:        req.TopP,
 		StopSeqs:    req.Stop,
 	}
+	if len(tools) > 0 {
+		nativeReq.ToolChoice = mapToolChoice(req.ToolChoice)
+	}
+
+	// Extended thinking: the budget counts against max_tokens, so make room
+	// for an answer after it. Temperature and top_p must be left unset.
+	if req.Reasoning != nil {
+		budget := max(req.Reasoning.Budget(), 1024)
+		nativeReq.Thinking = &thinkingConfig{Type: "enabled", BudgetTokens: budget}
+		if nativeReq.MaxTokens <= budget {
+			nativeReq.MaxTokens = budget + maxTokens
+		}
+		nativeReq.Temperature = 0
+		nativeReq.TopP = 0
+	}
+
+	return nativeReq
+}
+
+// mapToolChoice converts ChatRequest.ToolChoice. Values llm.ParseToolChoice
+// doesn't understand are left out, which means "auto".
+func mapToolChoice(v any) *toolChoice {
+	c, err := llm.ParseToolChoice(v)
+	if err != nil || c == nil {
+		return nil
+	}
+	switch c.Mode {
+	case llm.ToolChoiceModeRequired:
+		return &toolChoice{Type: "any"}
+	case llm.ToolChoiceModeFunction:
+		return &toolChoice{Type: "tool", Name: c.Function}
+	}
+	return &toolChoice{Type: c.Mode}
 }
 
 // mapResponse translates Anthropic's native response into our common llm.ChatResponse.
 // The reverse of mapRequest: Anthropic's shape goes in, OpenAI-shaped common types come out.
 func mapResponse(resp anthropicResponse) *llm.ChatResponse {
 
-	// Walk content blocks, collecting text and tool calls separately.
-	var textContent string
+	// Walk content blocks, collecting text, thinking and tool calls separately.
+	var textContent, thinking, signature string
 	var toolCalls []llm.ToolCall
 
 	for _, block := range resp.Content {
@@ -310,6 +470,10 @@ func mapResponse(resp anthropicResponse) *llm.ChatResponse {
 			// There can be multiple text blocks. Concatenate them.
 			textContent += block.Text
 
+		case "thinking":
+			thinking += block.Thinking
+			signature = block.Signature
+
 		case "tool_use":
 			// Reverse of what mapRequest did: Anthropic Input is a JSON object,
 			// but our common ToolCall.Function.Arguments needs a JSON string.
@@ -328,17 +492,7 @@ func mapResponse(resp anthropicResponse) *llm.ChatResponse {
 
 	// Normalize stop_reason to our common finish_reason values.
 	// These are the only strings Run() checks, so they must match exactly.
-	var finishReason string
-	switch resp.StopReason {
-	case "end_turn":
-		finishReason = "stop"
-	case "tool_use":
-		finishReason = "tool_calls"
-	case "max_tokens":
-		finishReason = "length"
-	default:
-		finishReason = resp.StopReason
-	}
+	finishReason := devkit.NormalizeFinishReason(resp.StopReason)
 
 	// Build the common response. Anthropic returns one response directly,
 	// but our common format wraps it in a Choices array (OpenAI convention).
@@ -349,18 +503,16 @@ func mapResponse(resp anthropicResponse) *llm.ChatResponse {
 			{
 				Index: 0,
 				Message: llm.Message{
-					Role:      "assistant",
-					Content:   textContent,
-					ToolCalls: toolCalls,
+					Role:               "assistant",
+					Content:            textContent,
+					ToolCalls:          toolCalls,
+					Reasoning:          thinking,
+					ReasoningSignature: signature,
 				},
 				FinishReason: finishReason,
 			},
 		},
-		Usage: llm.Usage{
-			PromptTokens:     resp.Usage.InputTokens,
-			CompletionTokens: resp.Usage.OutputTokens,
-			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
-		},
+		Usage: mapUsage(resp.Usage),
 	}
 }
 
@@ -371,12 +523,51 @@ func (c *Client) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.Chat
 	// Translate common format to Anthropic's native format.
 	nativeReq := mapRequest(req)
 
-	jsonData, err := json.Marshal(nativeReq)
+	resp, err := c.post(ctx, nativeReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := bufpool.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: failed to read response body: %w", err)
+	}
+	defer bufpool.Put(body)
+
+	// Unmarshal into Anthropic's native response type, not our common type.
+	// The JSON shape is different — no "choices" array, different field names.
+	var nativeResp anthropicResponse
+	if err := json.Unmarshal(body.Bytes(), &nativeResp); err != nil {
+		return nil, fmt.Errorf("anthropic: failed to decode response: %w", err)
+	}
+
+	// Translate native response back to common format.
+	return mapResponse(nativeResp), nil
+}
+
+// Warmup implements llm.Warmer: it opens a connection to the API by
+// listing models, which costs no tokens.
+func (c *Client) Warmup(ctx context.Context) error {
+	header := http.Header{}
+	header.Set("x-api-key", c.apiKey)
+	header.Set("anthropic-version", "2023-06-01")
+	if err := llm.WarmConnection(ctx, c.httpClient, c.baseURL+"/v1/models", header); err != nil {
+		return fmt.Errorf("anthropic: %w", err)
+	}
+	return nil
+}
+
+// post sends a native request to /v1/messages and returns the response once
+// the status is known to be 200. The caller must close the body.
+// Shared by CreateChat and CreateChatStream - only the body handling differs.
+func (c *Client) post(ctx context.Context, nativeReq anthropicRequest) (*http.Response, error) {
+	reqBuf, err := bufpool.EncodeJSON(nativeReq)
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(reqBuf.Bytes()))
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: failed to create HTTP request: %w", err)
 	}
@@ -391,24 +582,12 @@ func (c *Client) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.Chat
 	if err != nil {
 		return nil, fmt.Errorf("anthropic: HTTP request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("anthropic: failed to read response body: %w", err)
-	}
+	resp.Body = bufpool.ReleaseOnClose(resp.Body, reqBuf)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("anthropic: unexpected status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Unmarshal into Anthropic's native response type, not our common type.
-	// The JSON shape is different — no "choices" array, different field names.
-	var nativeResp anthropicResponse
-	if err := json.Unmarshal(body, &nativeResp); err != nil {
-		return nil, fmt.Errorf("anthropic: failed to decode response: %w", err)
+	// Read the body so the error says what Anthropic complained about.
+	if err := devkit.CheckResponse("anthropic", resp); err != nil {
+		return nil, err
 	}
 
-	// Translate native response back to common format.
-	return mapResponse(nativeResp), nil
+	return resp, nil
 }
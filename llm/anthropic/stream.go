@@ -0,0 +1,198 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/internal/sse"
+	"strings"
+)
+
+// Streaming uses the same /v1/messages endpoint with "stream": true.
+// The response is a Server-Sent Events stream. The events we care about:
+//
+//	message_start        : id, model, and input token usage (reported as a usage delta)
+//	content_block_start  : a new text or tool_use block begins (tool_use has id + name)
+//	content_block_delta  : text_delta (text), input_json_delta (partial tool args),
+//	                       thinking_delta / signature_delta (extended thinking)
+//	message_delta        : stop_reason and the final output token count (usage delta)
+//	error                : the API failed mid-stream
+//
+// Tool call arguments arrive as fragments of a JSON string (partial_json) that
+// only parse once the block is complete. We concatenate them per block, then
+// build the same anthropicResponse a blocking call would have returned and run
+// it through mapResponse - so the agent loop can't tell the difference.
+
+// streamEvent is the union of every event payload we read.
+type streamEvent struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+
+	// message_start
+	Message *anthropicResponse `json:"message,omitempty"`
+
+	// content_block_start
+	ContentBlock *responseBlock `json:"content_block,omitempty"`
+
+	// content_block_delta and message_delta
+	Delta *struct {
+		Type        string  `json:"type"`
+		Text        string  `json:"text,omitempty"`
+		PartialJSON string  `json:"partial_json,omitempty"`
+		Thinking    string  `json:"thinking,omitempty"`
+		Signature   string  `json:"signature,omitempty"`
+		StopReason  string  `json:"stop_reason,omitempty"`
+		StopSeq     *string `json:"stop_sequence,omitempty"`
+	} `json:"delta,omitempty"`
+
+	// message_delta
+	Usage *anthropicUsage `json:"usage,omitempty"`
+
+	// error
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// CreateChatStream sends a streaming request to Anthropic's Messages API.
+// It implements llm.StreamingChatProvider: onDelta sees text and tool call
+// fragments as they arrive, and the assembled response is returned at the end.
+func (c *Client) CreateChatStream(ctx context.Context, req llm.ChatRequest, onDelta llm.StreamHandler) (*llm.ChatResponse, error) {
+	nativeReq := mapRequest(req)
+	nativeReq.Stream = true
+
+	resp, err := c.post(ctx, nativeReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		msg      anthropicResponse
+		partials = map[int]*strings.Builder{} // block index -> accumulated tool input JSON
+		toolIdx  = map[int]int{}              // block index -> position among tool calls
+	)
+
+	// emitUsage reports the token counts so far: input tokens arrive with
+	// message_start, output tokens with message_delta.
+	emitUsage := func() {
+		if onDelta != nil {
+			u := mapUsage(msg.Usage)
+			onDelta(llm.StreamDelta{Usage: &u})
+		}
+	}
+
+	err = sse.Read(resp.Body, "anthropic", func(event, data string) error {
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("anthropic: failed to decode %s event: %w", event, err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				msg = *ev.Message
+				msg.Content = nil
+				emitUsage()
+			}
+
+		case "content_block_start":
+			if ev.ContentBlock == nil {
+				return nil
+			}
+			// Grow the content slice so the block lands at its index.
+			for len(msg.Content) <= ev.Index {
+				msg.Content = append(msg.Content, responseBlock{})
+			}
+			msg.Content[ev.Index] = *ev.ContentBlock
+
+			if ev.ContentBlock.Type == "tool_use" {
+				toolIdx[ev.Index] = len(toolIdx)
+				partials[ev.Index] = &strings.Builder{}
+				if onDelta != nil {
+					onDelta(llm.StreamDelta{
+						ToolCallIndex: toolIdx[ev.Index],
+						ToolCallID:    ev.ContentBlock.ID,
+						ToolName:      ev.ContentBlock.Name,
+					})
+				}
+			}
+
+		case "content_block_delta":
+			if ev.Delta == nil || ev.Index >= len(msg.Content) {
+				return nil
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				msg.Content[ev.Index].Text += ev.Delta.Text
+				if onDelta != nil && ev.Delta.Text != "" {
+					onDelta(llm.StreamDelta{Content: ev.Delta.Text})
+				}
+			case "thinking_delta":
+				msg.Content[ev.Index].Thinking += ev.Delta.Thinking
+				if onDelta != nil && ev.Delta.Thinking != "" {
+					onDelta(llm.StreamDelta{Reasoning: ev.Delta.Thinking})
+				}
+			case "signature_delta":
+				msg.Content[ev.Index].Signature += ev.Delta.Signature
+			case "input_json_delta":
+				if b, ok := partials[ev.Index]; ok {
+					b.WriteString(ev.Delta.PartialJSON)
+				}
+				if onDelta != nil && ev.Delta.PartialJSON != "" {
+					onDelta(llm.StreamDelta{
+						ToolCallIndex:  toolIdx[ev.Index],
+						ArgumentsDelta: ev.Delta.PartialJSON,
+					})
+				}
+			}
+
+		case "message_delta":
+			if ev.Delta != nil {
+				msg.StopReason = ev.Delta.StopReason
+				msg.StopSeq = ev.Delta.StopSeq
+			}
+			// Counts on message_delta are cumulative for the whole message.
+			// output_tokens is always there; input_tokens only when it
+			// changed since message_start (e.g. server-side tool use).
+			if ev.Usage != nil {
+				msg.Usage.OutputTokens = ev.Usage.OutputTokens
+				if ev.Usage.InputTokens > 0 {
+					msg.Usage.InputTokens = ev.Usage.InputTokens
+				}
+				emitUsage()
+			}
+
+		case "error":
+			if ev.Error != nil {
+				// Overloaded is what a 529 looks like once the stream has started.
+				// Report it the same way so it's treated as transient.
+				if ev.Error.Type == "overloaded_error" {
+					return &llm.APIError{Provider: "anthropic", StatusCode: 529, Body: data}
+				}
+				return fmt.Errorf("anthropic: stream error (%s): %s", ev.Error.Type, ev.Error.Message)
+			}
+			return fmt.Errorf("anthropic: stream error: %s", data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tool inputs are complete now - parse the accumulated JSON.
+	// A tool with no arguments streams nothing, which means an empty object.
+	for idx, b := range partials {
+		var input any = map[string]any{}
+		if b.Len() > 0 {
+			if err := json.Unmarshal([]byte(b.String()), &input); err != nil {
+				return nil, fmt.Errorf("anthropic: invalid streamed tool input: %w", err)
+			}
+		}
+		msg.Content[idx].Input = input
+	}
+
+	return mapResponse(msg), nil
+}
@@ -0,0 +1,275 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Strategy decides which backend a LoadBalancer sends the next call to.
+type Strategy int
+
+const (
+	// RoundRobin takes healthy backends in turn.
+	RoundRobin Strategy = iota
+
+	// LeastLatency picks the healthy backend with the lowest recent average
+	// latency. Backends that haven't answered yet go first, so every
+	// backend gets measured.
+	LeastLatency
+)
+
+// BackendStats is a snapshot of one backend's health, from LoadBalancer.Stats.
+type BackendStats struct {
+	Model          string
+	Healthy        bool
+	Requests       int           // calls sent to this backend
+	Failures       int           // calls that failed
+	AvgLatency     time.Duration // moving average over successful calls
+	UnhealthyUntil time.Time     // zero when healthy
+}
+
+// backend is a provider plus what the balancer knows about it.
+type backend struct {
+	provider         ChatProvider
+	requests         int
+	failures         int
+	consecutiveFails int
+	avgLatency       time.Duration
+	unhealthyUntil   time.Time
+}
+
+// LoadBalancer spreads CreateChat calls over several providers - typically
+// the same model behind different API keys or regions - to get past
+// per-key rate limits. It implements ChatProvider and StreamingChatProvider,
+// so an agent uses it like any single provider.
+//
+// Each backend's health is tracked: after FailureThreshold transient errors
+// in a row (429, 5xx, network - see IsTransient) it is skipped for the
+// cooldown period. A call that fails transiently is retried on the next
+// backend straight away, so one bad key doesn't fail the request.
+// Non-transient errors (a bad request) are returned as-is.
+type LoadBalancer struct {
+	mu        sync.Mutex
+	backends  []*backend
+	strategy  Strategy
+	next      int // round-robin cursor
+	threshold int
+	cooldown  time.Duration
+}
+
+// BalancerOption configures a LoadBalancer.
+type BalancerOption func(*LoadBalancer)
+
+// WithFailureThreshold sets how many transient failures in a row take a
+// backend out of rotation. Defaults to 3.
+func WithFailureThreshold(n int) BalancerOption {
+	return func(lb *LoadBalancer) {
+		lb.threshold = n
+	}
+}
+
+// WithCooldown sets how long an unhealthy backend is skipped before it gets
+// another chance. Defaults to 30 seconds.
+func WithCooldown(d time.Duration) BalancerOption {
+	return func(lb *LoadBalancer) {
+		lb.cooldown = d
+	}
+}
+
+// NewLoadBalancer creates a balancer over providers. It panics if
+// providers is empty, since there'd be nothing to call.
+//
+//	lb := llm.NewLoadBalancer([]llm.ChatProvider{
+//	    openai.New(key1, "gpt-4o"),
+//	    openai.New(key2, "gpt-4o"),
+//	    openai.New(azureKey, "gpt-4o", openai.WithBaseURL(azureURL)),
+//	}, llm.LeastLatency)
+//	a := agent.New(lb)
+func NewLoadBalancer(providers []ChatProvider, strategy Strategy, opts ...BalancerOption) *LoadBalancer {
+	if len(providers) == 0 {
+		panic("llm: NewLoadBalancer needs at least one provider")
+	}
+	lb := &LoadBalancer{
+		strategy:  strategy,
+		threshold: 3,
+		cooldown:  30 * time.Second,
+	}
+	for _, p := range providers {
+		lb.backends = append(lb.backends, &backend{provider: p})
+	}
+	for _, opt := range opts {
+		opt(lb)
+	}
+	return lb
+}
+
+// ModelName returns the first backend's model name. Backends are expected
+// to serve the same model.
+func (lb *LoadBalancer) ModelName() string {
+	return lb.backends[0].provider.ModelName()
+}
+
+// CreateChat sends req to a backend chosen by the strategy, failing over
+// to the others on transient errors.
+func (lb *LoadBalancer) CreateChat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return lb.do(ctx, func(p ChatProvider) (*ChatResponse, error) {
+		return p.CreateChat(ctx, req)
+	})
+}
+
+// CreateChatStream streams from a backend chosen by the strategy. Backends
+// that can't stream answer with a blocking call delivered as one delta.
+// Failover only happens before the first delta - after that the partial
+// answer has been seen and the error is returned.
+func (lb *LoadBalancer) CreateChatStream(ctx context.Context, req ChatRequest, onDelta StreamHandler) (*ChatResponse, error) {
+	started := false
+	handler := func(d StreamDelta) {
+		if d != (StreamDelta{Usage: d.Usage}) { // token counts alone aren't output
+			started = true
+		}
+		if onDelta != nil {
+			onDelta(d)
+		}
+	}
+	return lb.do(ctx, func(p ChatProvider) (*ChatResponse, error) {
+		if started {
+			return nil, errStreamStarted
+		}
+		if sp, ok := p.(StreamingChatProvider); ok {
+			return sp.CreateChatStream(ctx, req, handler)
+		}
+		resp, err := p.CreateChat(ctx, req)
+		if err == nil && len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+			handler(StreamDelta{Content: resp.Choices[0].Message.Content})
+		}
+		return resp, err
+	})
+}
+
+// errStreamStarted stops failover once a stream has delivered deltas.
+var errStreamStarted = errors.New("llm: stream already started")
+
+// do tries backends in the strategy's order until one succeeds or fails
+// with a non-transient error. Each backend is tried at most once per call.
+func (lb *LoadBalancer) do(ctx context.Context, call func(ChatProvider) (*ChatResponse, error)) (*ChatResponse, error) {
+	tried := make([]bool, len(lb.backends))
+	var lastErr error
+	for range lb.backends {
+		idx := lb.pick(ctx, tried)
+		tried[idx] = true
+		b := lb.backends[idx]
+
+		start := Now(ctx)
+		resp, err := call(b.provider)
+		if errors.Is(err, errStreamStarted) {
+			return nil, lastErr
+		}
+		lb.record(ctx, b, Now(ctx).Sub(start), err)
+
+		if err == nil || !IsTransient(err) {
+			return resp, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// pick chooses the next backend that hasn't been tried in this call.
+// Unhealthy backends are only used when every remaining one is unhealthy -
+// a degraded answer beats no answer.
+func (lb *LoadBalancer) pick(ctx context.Context, tried []bool) int {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	now := Now(ctx)
+	n := len(lb.backends)
+	best := -1
+	for _, healthyOnly := range []bool{true, false} {
+		for k := 0; k < n; k++ {
+			i := (lb.next + k) % n
+			b := lb.backends[i]
+			if tried[i] || (healthyOnly && now.Before(b.unhealthyUntil)) {
+				continue
+			}
+			if lb.strategy == RoundRobin {
+				lb.next = (i + 1) % n
+				return i
+			}
+			if best == -1 || b.avgLatency < lb.backends[best].avgLatency {
+				best = i
+			}
+		}
+		if best != -1 {
+			return best
+		}
+	}
+	return best
+}
+
+// record updates a backend's stats after a call.
+func (lb *LoadBalancer) record(ctx context.Context, b *backend, latency time.Duration, err error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	b.requests++
+	if err == nil {
+		b.consecutiveFails = 0
+		b.unhealthyUntil = time.Time{}
+		// Exponential moving average, weighting the latest call by a fifth.
+		if b.avgLatency == 0 {
+			b.avgLatency = latency
+		} else {
+			b.avgLatency = (4*b.avgLatency + latency) / 5
+		}
+		return
+	}
+
+	b.failures++
+	if IsTransient(err) {
+		b.consecutiveFails++
+		if lb.threshold > 0 && b.consecutiveFails >= lb.threshold {
+			b.unhealthyUntil = Now(ctx).Add(lb.cooldown)
+		}
+	}
+}
+
+// Stats returns a snapshot of every backend's health, in the order the
+// providers were given.
+func (lb *LoadBalancer) Stats() []BackendStats {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]BackendStats, len(lb.backends))
+	for i, b := range lb.backends {
+		stats[i] = BackendStats{
+			Model:      b.provider.ModelName(),
+			Healthy:    !now.Before(b.unhealthyUntil),
+			Requests:   b.requests,
+			Failures:   b.failures,
+			AvgLatency: b.avgLatency,
+		}
+		if !stats[i].Healthy {
+			stats[i].UnhealthyUntil = b.unhealthyUntil
+		}
+	}
+	return stats
+}
+
+// Warmup warms up every backend at once. The error joins the backends'
+// errors; backends that warmed up fine are ready either way.
+func (lb *LoadBalancer) Warmup(ctx context.Context) error {
+	errs := make([]error, len(lb.backends))
+	var wg sync.WaitGroup
+	for i, b := range lb.backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = Warmup(ctx, b.provider)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
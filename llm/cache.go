@@ -0,0 +1,234 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CacheStore is where a CachedProvider keeps responses. Implement it to
+// share a cache between processes (Redis, a database); NewLRUCache is the
+// in-memory version.
+//
+// Stores should treat the responses they're given and return as read-only -
+// CachedProvider copies them on the way in and out.
+type CacheStore interface {
+	// Get returns the response stored under key, if present and not expired.
+	Get(ctx context.Context, key string) (*ChatResponse, bool)
+
+	// Set stores resp under key. A ttl of 0 means no expiry.
+	Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration)
+}
+
+// CachedProvider wraps a ChatProvider and answers identical requests from a
+// cache instead of calling the API again. Two requests are identical when
+// everything in them - model, messages, tools, temperature, every field -
+// encodes to the same JSON.
+//
+// This is for tests, evals and deterministic prompts you send repeatedly.
+// With a temperature above zero a cache makes the model look more
+// deterministic than it is, which may or may not be what you want.
+// Errors are never cached.
+type CachedProvider struct {
+	provider ChatProvider
+	store    CacheStore
+	ttl      time.Duration
+}
+
+// CacheOption configures a CachedProvider.
+type CacheOption func(*CachedProvider)
+
+// WithCacheTTL sets how long responses stay cached. 0 (the default) means
+// until the store evicts them.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *CachedProvider) {
+		c.ttl = ttl
+	}
+}
+
+// NewCachedProvider wraps p with a response cache.
+//
+//	provider := llm.NewCachedProvider(openai.New(key, "gpt-4o"), llm.NewLRUCache(1000),
+//	    llm.WithCacheTTL(time.Hour),
+//	)
+func NewCachedProvider(p ChatProvider, store CacheStore, opts ...CacheOption) *CachedProvider {
+	c := &CachedProvider{provider: p, store: store}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ModelName returns the wrapped provider's model name.
+func (c *CachedProvider) ModelName() string {
+	return c.provider.ModelName()
+}
+
+// CreateChat returns a cached response for req if there is one, otherwise
+// calls the wrapped provider and caches its answer.
+func (c *CachedProvider) CreateChat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	key, ok := CacheKey(req)
+	if ok {
+		if resp, hit := c.store.Get(ctx, key); hit {
+			return cloneResponse(resp), nil
+		}
+	}
+
+	resp, err := c.provider.CreateChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		c.store.Set(ctx, key, cloneResponse(resp), c.ttl)
+	}
+	return resp, nil
+}
+
+// CreateChatStream replays a cached answer as a single delta, or streams
+// from the wrapped provider (if it can) and caches the final response.
+func (c *CachedProvider) CreateChatStream(ctx context.Context, req ChatRequest, onDelta StreamHandler) (*ChatResponse, error) {
+	key, ok := CacheKey(req)
+	if ok {
+		if resp, hit := c.store.Get(ctx, key); hit {
+			resp = cloneResponse(resp)
+			if onDelta != nil && len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+				onDelta(StreamDelta{Content: resp.Choices[0].Message.Content})
+			}
+			return resp, nil
+		}
+	}
+
+	var resp *ChatResponse
+	var err error
+	if sp, isStreaming := c.provider.(StreamingChatProvider); isStreaming {
+		resp, err = sp.CreateChatStream(ctx, req, onDelta)
+	} else {
+		resp, err = c.provider.CreateChat(ctx, req)
+		if err == nil && onDelta != nil && len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+			onDelta(StreamDelta{Content: resp.Choices[0].Message.Content})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		c.store.Set(ctx, key, cloneResponse(resp), c.ttl)
+	}
+	return resp, nil
+}
+
+// Warmup warms up the wrapped provider.
+func (c *CachedProvider) Warmup(ctx context.Context) error {
+	return Warmup(ctx, c.provider)
+}
+
+// CacheKey returns the key a CachedProvider uses for req: a SHA-256 of the
+// request's JSON encoding. Stream is ignored, so streamed and blocking calls
+// share entries. ok is false if the request can't be encoded (it then just
+// isn't cached).
+func CacheKey(req ChatRequest) (key string, ok bool) {
+	req.Stream = false
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// cloneResponse copies resp deeply enough that the caller can't change
+// what's in the cache: the choices and their tool calls.
+func cloneResponse(resp *ChatResponse) *ChatResponse {
+	out := *resp
+	out.Choices = make([]Choice, len(resp.Choices))
+	for i, ch := range resp.Choices {
+		if ch.Message.ToolCalls != nil {
+			ch.Message.ToolCalls = append([]ToolCall(nil), ch.Message.ToolCalls...)
+		}
+		out.Choices[i] = ch
+	}
+	return &out
+}
+
+// LRUCache is an in-memory CacheStore that holds up to a fixed number of
+// responses, evicting the least recently used. It is safe for concurrent use.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	resp    *ChatResponse
+	expires time.Time // zero means never
+}
+
+// NewLRUCache creates an in-memory cache holding at most capacity responses.
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStore. Expired entries are dropped when found.
+// Time comes from Now(ctx), so a fixed clock makes expiry deterministic.
+func (l *LRUCache) Get(ctx context.Context, key string) (*ChatResponse, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !e.expires.IsZero() && !Now(ctx).Before(e.expires) {
+		l.order.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return e.resp, true
+}
+
+// Set implements CacheStore.
+func (l *LRUCache) Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = Now(ctx).Add(ttl)
+	}
+
+	if el, ok := l.items[key]; ok {
+		el.Value = &lruEntry{key: key, resp: resp, expires: expires}
+		l.order.MoveToFront(el)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, resp: resp, expires: expires})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns how many responses are cached, including expired ones not
+// yet noticed.
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ContentPart is one piece of a multimodal message: text, an image or a
+// file. The JSON shape is OpenAI's content part format:
+//
+//	{"type": "text", "text": "What's in this picture?"}
+//	{"type": "image_url", "image_url": {"url": "https://...", "detail": "auto"}}
+//	{"type": "file", "file": {"filename": "report.pdf", "file_data": "data:application/pdf;base64,..."}}
+//
+// Images can be remote URLs or inline bytes as a data URL
+// ("data:image/png;base64,..."); NewImagePart builds the latter.
+// Anthropic and Gemini translate both forms to their own image blocks.
+type ContentPart struct {
+	Type     string    `json:"type"`                // "text", "image_url" or "file"
+	Text     string    `json:"text,omitempty"`      // for type="text"
+	ImageURL *ImageURL `json:"image_url,omitempty"` // for type="image_url"
+	File     *File     `json:"file,omitempty"`      // for type="file"
+}
+
+// ImageURL points at an image, remote or inline.
+type ImageURL struct {
+	URL    string `json:"url"`              // https://... or data:<mime>;base64,<data>
+	Detail string `json:"detail,omitempty"` // "low", "high" or "auto" (OpenAI only)
+}
+
+// File is an inline document, such as a PDF.
+type File struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"` // data:<mime>;base64,<data>
+}
+
+// NewTextPart creates a text content part.
+func NewTextPart(text string) ContentPart {
+	return ContentPart{Type: "text", Text: text}
+}
+
+// NewImageURLPart creates an image part that points at a URL.
+// Not every provider can fetch arbitrary URLs (Gemini can't), so prefer
+// NewImagePart when you have the bytes.
+func NewImageURLPart(url string) ContentPart {
+	return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}}
+}
+
+// NewImagePart creates an image part from raw bytes (PNG, JPEG, GIF, WebP),
+// inlined as a base64 data URL. The MIME type is detected from the data.
+func NewImagePart(data []byte) ContentPart {
+	url := DataURL(http.DetectContentType(data), data)
+	return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}}
+}
+
+// NewFilePart creates a file part from raw bytes. Providers differ in
+// which file types they read; PDF and plain text are the safe ones.
+func NewFilePart(filename, mimeType string, data []byte) ContentPart {
+	return ContentPart{Type: "file", File: &File{
+		Filename: filename,
+		FileData: DataURL(mimeType, data),
+	}}
+}
+
+// DataURL encodes data as a base64 data URL.
+func DataURL(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// ParseDataURL splits a base64 data URL into its MIME type and the still
+// base64-encoded data - the form Anthropic and Gemini want. ok is false for
+// anything that isn't a base64 data URL (e.g. an https URL).
+func ParseDataURL(url string) (mimeType, data string, ok bool) {
+	rest, found := strings.CutPrefix(url, "data:")
+	if !found {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mimeType, found = strings.CutSuffix(meta, ";base64")
+	if !found {
+		return "", "", false
+	}
+	return mimeType, data, true
+}
+
+// NewUserImageMessage creates a user message with a caption (or question)
+// and an image:
+//
+//	img, _ := os.ReadFile("receipt.jpg")
+//	msg := llm.NewUserImageMessage("What's the total on this receipt?", img)
+//
+// Content is set to the caption too, so code that only looks at Content
+// (logs, memory) still sees the text.
+func NewUserImageMessage(caption string, imageData []byte) Message {
+	parts := []ContentPart{NewImagePart(imageData)}
+	if caption != "" {
+		parts = append([]ContentPart{NewTextPart(caption)}, parts...)
+	}
+	return Message{Role: "user", Content: caption, Parts: parts}
+}
+
+// MarshalJSON writes Content as a string, or as an array of parts when the
+// message has Parts - the two forms OpenAI's API accepts.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain Message // same fields, no methods - avoids recursing into MarshalJSON
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []ContentPart `json:"content"` // shadows plain.Content
+	}{plain(m), m.Parts})
+}
+
+// UnmarshalJSON accepts Content as a string or as an array of parts.
+// For arrays, Parts is filled and Content gets the text parts joined,
+// mirroring NewUserImageMessage.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	var raw struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.plain)
+
+	c := raw.Content
+	if len(c) == 0 || string(c) == "null" {
+		return nil
+	}
+	if c[0] != '[' {
+		return json.Unmarshal(c, &m.Content)
+	}
+	if err := json.Unmarshal(c, &m.Parts); err != nil {
+		return err
+	}
+	var text []string
+	for _, p := range m.Parts {
+		if p.Type == "text" {
+			text = append(text, p.Text)
+		}
+	}
+	m.Content = strings.Join(text, "\n")
+	return nil
+}
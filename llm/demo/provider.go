@@ -0,0 +1,226 @@
+// Package demo implements an offline llm.ChatProvider driven by a script.
+//
+// It exists so the examples run without an API key - in workshops, on a
+// plane, or when you're changing the agent loop and just want to see it
+// go round. It is not a model: it matches the latest user message against
+// a list of rules and answers with canned text, optionally calling a tool
+// first the same way a real model would (tool_calls, then a final answer
+// once the tool results are in the history).
+//
+// Responses can also be streamed word by word, so streaming code paths
+// work offline too.
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"go-agent-sdk/llm"
+	"strings"
+	"time"
+)
+
+// Rule scripts one kind of exchange.
+//
+// When the latest user message contains Match (case-insensitive), the rule
+// applies. If Tool is set and the request offers a tool with that name, the
+// provider first answers with a tool call using Args. Once the tool result
+// is in the history it answers with Reply, where "{result}" is replaced by
+// the tool output. Without a Tool, Reply is returned straight away.
+type Rule struct {
+	Match string // substring of the user message; empty matches anything
+	Tool  string // tool to call first, if offered in the request
+	Args  string // JSON arguments for the tool call, defaults to "{}"
+	Reply string // final answer; "{result}" becomes the tool result
+}
+
+// Provider is a scripted, offline llm.ChatProvider and llm.StreamingChatProvider.
+type Provider struct {
+	rules    []Rule
+	model    string
+	delay    time.Duration // pause between streamed words
+	fallback string        // reply when no rule matches
+	newID    llm.IDGenerator
+}
+
+// Option configures a Provider.
+type Option func(*Provider)
+
+// WithModel sets the name returned by ModelName and reported in responses.
+// Defaults to "demo".
+func WithModel(name string) Option {
+	return func(p *Provider) {
+		p.model = name
+	}
+}
+
+// WithWordDelay sets the pause between words when streaming,
+// to make the demo look like a real model typing. Defaults to 40ms.
+func WithWordDelay(d time.Duration) Option {
+	return func(p *Provider) {
+		p.delay = d
+	}
+}
+
+// WithFallback sets the reply used when no rule matches.
+// "{input}" is replaced by the user's message.
+func WithFallback(reply string) Option {
+	return func(p *Provider) {
+		p.fallback = reply
+	}
+}
+
+// New creates a demo provider that follows the given rules in order -
+// the first matching rule wins.
+//
+//	provider := demo.New([]demo.Rule{
+//	    {Match: "weather", Tool: "get_weather", Args: `{"city":"Paris"}`,
+//	        Reply: "Here's what I found: {result}"},
+//	    {Match: "hello", Reply: "Hi there!"},
+//	})
+func New(rules []Rule, opts ...Option) *Provider {
+	p := &Provider{
+		rules:    rules,
+		model:    "demo",
+		delay:    40 * time.Millisecond,
+		fallback: "(demo provider) I don't have a scripted answer for: {input}",
+		newID:    llm.NewCallID,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// ModelName returns the configured model name.
+func (p *Provider) ModelName() string {
+	return p.model
+}
+
+// CreateChat answers from the script. It never fails.
+func (p *Provider) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	return p.respond(ctx, req), nil
+}
+
+// CreateChatStream answers from the script, streaming the reply word by word.
+// Tool calls are streamed as a header delta followed by the arguments.
+func (p *Provider) CreateChatStream(ctx context.Context, req llm.ChatRequest, onDelta llm.StreamHandler) (*llm.ChatResponse, error) {
+	resp := p.respond(ctx, req)
+	msg := resp.Choices[0].Message
+
+	for i, call := range msg.ToolCalls {
+		if onDelta != nil {
+			onDelta(llm.StreamDelta{ToolCallIndex: i, ToolCallID: call.ID, ToolName: call.Function.Name})
+			onDelta(llm.StreamDelta{ToolCallIndex: i, ArgumentsDelta: call.Function.Arguments})
+		}
+	}
+
+	words := strings.SplitAfter(msg.Content, " ")
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		if onDelta != nil {
+			onDelta(llm.StreamDelta{Content: w})
+		}
+		if p.delay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(p.delay):
+			}
+		}
+	}
+	return resp, nil
+}
+
+// respond works out the scripted response for the current state of the conversation.
+func (p *Provider) respond(ctx context.Context, req llm.ChatRequest) *llm.ChatResponse {
+	// Find the latest user message and any tool results that came after it.
+	lastUser := -1
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			lastUser = i
+			break
+		}
+	}
+	var input string
+	var results []string
+	if lastUser >= 0 {
+		input = req.Messages[lastUser].Content
+		for _, msg := range req.Messages[lastUser+1:] {
+			if msg.Role == "tool" {
+				results = append(results, msg.Content)
+			}
+		}
+	}
+
+	rule, ok := p.match(input)
+
+	// First pass through a tool rule: ask for the tool.
+	if ok && rule.Tool != "" && len(results) == 0 && offers(req, rule.Tool) {
+		args := rule.Args
+		if args == "" || !json.Valid([]byte(args)) {
+			args = "{}"
+		}
+		return p.response(llm.Message{
+			Role: "assistant",
+			ToolCalls: []llm.ToolCall{{
+				ID:       p.newID(ctx),
+				Type:     "function",
+				Function: llm.FunctionCall{Name: rule.Tool, Arguments: args},
+			}},
+		}, "tool_calls", input)
+	}
+
+	reply := strings.ReplaceAll(p.fallback, "{input}", input)
+	if ok {
+		reply = strings.ReplaceAll(rule.Reply, "{result}", strings.Join(results, "\n"))
+	}
+	return p.response(llm.Message{Role: "assistant", Content: reply}, "stop", input)
+}
+
+// match returns the first rule whose Match appears in input.
+func (p *Provider) match(input string) (Rule, bool) {
+	lower := strings.ToLower(input)
+	for _, r := range p.rules {
+		if strings.Contains(lower, strings.ToLower(r.Match)) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// response wraps a message in a ChatResponse with rough token counts
+// (about 4 characters per token) so usage tracking has something to show.
+func (p *Provider) response(msg llm.Message, finishReason string, input string) *llm.ChatResponse {
+	completion := len(msg.Content) / 4
+	for _, call := range msg.ToolCalls {
+		completion += len(call.Function.Arguments) / 4
+	}
+	prompt := len(input) / 4
+
+	return &llm.ChatResponse{
+		ID:     "demo",
+		Object: "chat.completion",
+		Model:  p.model,
+		Choices: []llm.Choice{{
+			Message:      msg,
+			FinishReason: finishReason,
+		}},
+		Usage: llm.Usage{
+			PromptTokens:     prompt,
+			CompletionTokens: completion,
+			TotalTokens:      prompt + completion,
+		},
+	}
+}
+
+// offers reports whether the request makes a tool with this name available.
+func offers(req llm.ChatRequest, name string) bool {
+	for _, t := range req.Tools {
+		if t.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
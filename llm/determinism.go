@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// The agent attaches a clock and a random source to the context of every Run.
+// Providers and tools read them back with Now and RandFromContext instead of
+// calling time.Now or crypto/rand directly, so a test that pins both gets
+// byte-identical histories and traces on every run (golden files stay stable).
+//
+// When nothing was attached, Now falls back to time.Now and RandFromContext
+// returns nil so callers can pick their own default.
+
+type clockKey struct{}
+type randKey struct{}
+
+// ContextWithClock returns a copy of ctx that carries the given clock.
+func ContextWithClock(ctx context.Context, now func() time.Time) context.Context {
+	return context.WithValue(ctx, clockKey{}, now)
+}
+
+// Now returns the current time according to the clock attached to ctx,
+// or time.Now() if there isn't one.
+func Now(ctx context.Context) time.Time {
+	if now, ok := ctx.Value(clockKey{}).(func() time.Time); ok && now != nil {
+		return now()
+	}
+	return time.Now()
+}
+
+// ContextWithRand returns a copy of ctx that carries the given random source.
+// The source may be shared by concurrent tool executions, so it should come
+// from NewRand (which is safe for concurrent use).
+func ContextWithRand(ctx context.Context, r *rand.Rand) context.Context {
+	return context.WithValue(ctx, randKey{}, r)
+}
+
+// RandFromContext returns the random source attached to ctx, or nil.
+func RandFromContext(ctx context.Context) *rand.Rand {
+	r, _ := ctx.Value(randKey{}).(*rand.Rand)
+	return r
+}
+
+// NewRand creates a seeded random source that is safe for concurrent use.
+// The same seed always produces the same sequence.
+func NewRand(seed uint64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewPCG(seed, seed)})
+}
+
+// lockedSource serializes access to a rand.Source.
+// *rand.Rand itself is not goroutine-safe; parallel tools would race on it.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
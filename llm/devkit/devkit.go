@@ -0,0 +1,130 @@
+// Package devkit is for people writing their own llm.ChatProvider. It
+// holds the pieces every provider in this module needs and that are
+// fiddly to get right: mapping native finish reasons to the ones the agent
+// loop acts on, filling in tool call IDs, turning error responses into
+// *llm.APIError, and reading Server-Sent Events.
+//
+// The built-in providers use it too, so a custom provider built on it
+// behaves like they do. Run llm/providertest against it to check.
+//
+//	func (c *Client) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+//	    resp, err := c.http.Do(httpReq)
+//	    if err != nil {
+//	        return nil, fmt.Errorf("acme: HTTP request failed: %w", err)
+//	    }
+//	    if err := devkit.CheckResponse("acme", resp); err != nil {
+//	        return nil, err
+//	    }
+//	    ...
+//	    choice.FinishReason = devkit.NormalizeFinishReason(native.StopReason)
+//	}
+package devkit
+
+import (
+	"context"
+	"fmt"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/internal/sse"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The finish reasons the agent loop understands. A provider must report
+// one of these on Choice.FinishReason: "tool_calls" makes the agent run the
+// tools and call the model again, "stop" ends the run, and anything else
+// ends it with an *agent.FinishReasonError.
+const (
+	FinishStop          = "stop"
+	FinishToolCalls     = "tool_calls"
+	FinishLength        = "length"
+	FinishContentFilter = "content_filter"
+)
+
+// FinishReasons maps the native finish reasons of the APIs this module
+// knows about to the common ones. Keys are lower case.
+var FinishReasons = map[string]string{
+	// OpenAI and compatible servers
+	"stop":           FinishStop,
+	"tool_calls":     FinishToolCalls,
+	"function_call":  FinishToolCalls,
+	"length":         FinishLength,
+	"content_filter": FinishContentFilter,
+
+	// Anthropic
+	"end_turn":      FinishStop,
+	"stop_sequence": FinishStop,
+	"tool_use":      FinishToolCalls,
+	"max_tokens":    FinishLength,
+	"refusal":       FinishContentFilter,
+
+	// Gemini
+	"finish_reason_unspecified": FinishStop,
+	"safety":                    FinishContentFilter,
+	"recitation":                FinishContentFilter,
+	"blocklist":                 FinishContentFilter,
+	"prohibited_content":        FinishContentFilter,
+	"spii":                      FinishContentFilter,
+	"image_safety":              FinishContentFilter,
+
+	// Mistral, Cohere
+	"model_length": FinishLength,
+	"complete":     FinishStop,
+	"tool_call":    FinishToolCalls,
+}
+
+// NormalizeFinishReason maps a native finish reason to a common one using
+// FinishReasons, ignoring case. Unknown reasons come back unchanged, which
+// the agent reports rather than mistaking for a normal stop.
+//
+// Some APIs (Gemini) say "stop" even when the model called tools; decide
+// on FinishToolCalls from the response's content before calling this.
+func NormalizeFinishReason(native string) string {
+	if r, ok := FinishReasons[strings.ToLower(native)]; ok {
+		return r
+	}
+	return native
+}
+
+// EnsureToolCallIDs gives every call without an ID one from gen
+// (llm.NewCallID if gen is nil), and fills in Type "function" where it's
+// missing. Use it for APIs that don't return call IDs - the agent needs
+// them to link results to calls.
+func EnsureToolCallIDs(ctx context.Context, calls []llm.ToolCall, gen llm.IDGenerator) {
+	if gen == nil {
+		gen = llm.NewCallID
+	}
+	for i := range calls {
+		if calls[i].ID == "" {
+			calls[i].ID = gen(ctx)
+		}
+		if calls[i].Type == "" {
+			calls[i].Type = "function"
+		}
+	}
+}
+
+// CheckResponse returns nil for a 2xx response. For anything else it reads
+// and closes the body and returns an *llm.APIError, which carries the
+// status and the Retry-After hint the agent's retries go by.
+func CheckResponse(provider string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: failed to read response body: %w", provider, err)
+	}
+	return llm.NewAPIError(provider, resp, body)
+}
+
+// ReadSSE reads a Server-Sent Events stream and calls fn once per event
+// with its name and data (multi-line data joined with "\n"). Comments and
+// events without data are skipped.
+//
+// An error from fn stops the stream and is returned as-is; read errors
+// are prefixed with provider.
+func ReadSSE(r io.Reader, provider string, fn func(event, data string) error) error {
+	return sse.Read(r, provider, fn)
+}
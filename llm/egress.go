@@ -0,0 +1,263 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrEgressDenied is returned for a request or connection an EgressPolicy
+// doesn't allow.
+var ErrEgressDenied = errors.New("llm: egress denied")
+
+// EgressPolicy restricts where the SDK's HTTP traffic may go - providers,
+// and tools fetching URLs the model picked, which is where SSRF comes
+// from: a prompt-injected "fetch http://169.254.169.254/latest/meta-data"
+// shouldn't reach the cloud metadata service.
+//
+// Install makes the policy's client the default, which covers the tools,
+// feeds and triggers in this module that fetch over HTTP - wikipedia,
+// weather, feeds.Monitor, the Kafka trigger - unless they were given a
+// client of their own:
+//
+//	policy := llm.EgressPolicy{
+//	    BlockPrivate: true,
+//	    DenyHosts:    []string{"*.internal.example.com"},
+//	}
+//	if err := policy.Install(); err != nil {
+//	    return err
+//	}
+//
+// Providers, and any tool handed a client with its WithHTTPClient option,
+// bypass the default; give them the policy's client as well:
+//
+//	hc, err := policy.HTTPClient()
+//	provider := openai.New(key, "gpt-4o", openai.WithHTTPClient(hc))
+//
+// Hosts are checked on every request, redirects included; addresses are
+// checked on every connection, after DNS, so a name that resolves to a
+// private address is caught too.
+type EgressPolicy struct {
+	// AllowHosts, if not empty, is the only hosts requests may go to.
+	// "example.com" matches that host, "*.example.com" any host under it.
+	AllowHosts []string
+
+	// DenyHosts are hosts requests may never go to, in the same form.
+	DenyHosts []string
+
+	// DenyNets are address ranges (CIDR, "203.0.113.0/24" or
+	// "2001:db8::/32") connections may never go to.
+	DenyNets []string
+
+	// BlockPrivate refuses connections to loopback, private, link-local
+	// (cloud metadata lives there), carrier-grade NAT, unspecified and
+	// multicast addresses - IPv4 and IPv6, including IPv4 addresses
+	// embedded in IPv6 ones.
+	BlockPrivate bool
+
+	// PrivateExceptions are ranges BlockPrivate lets through, such as an
+	// internal LLM gateway's.
+	PrivateExceptions []string
+
+	// IPv4Only connects over IPv4 only, for networks where IPv6 egress
+	// isn't filtered.
+	IPv4Only bool
+}
+
+type egress struct {
+	policy     EgressPolicy
+	deny       []netip.Prefix
+	exceptions []netip.Prefix
+}
+
+func (p EgressPolicy) compile() (*egress, error) {
+	e := &egress{policy: p}
+	var err error
+	if e.deny, err = parsePrefixes(p.DenyNets); err != nil {
+		return nil, err
+	}
+	if e.exceptions, err = parsePrefixes(p.PrivateExceptions); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			// A bare address is a range of one.
+			addr, aerr := netip.ParseAddr(c)
+			if aerr != nil {
+				return nil, fmt.Errorf("llm: invalid address range %q: %w", c, err)
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+// CheckURL reports whether the policy allows a request to rawURL, as far
+// as can be told without connecting: the scheme, the host, and the
+// address if the host is one. Tools can call it to refuse a URL before
+// doing any work.
+func (p EgressPolicy) CheckURL(rawURL string) error {
+	e, err := p.compile()
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEgressDenied, err)
+	}
+	return e.checkURL(u)
+}
+
+func (e *egress) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrEgressDenied, u.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: no host in %s", ErrEgressDenied, u.Redacted())
+	}
+	if matchHosts(e.policy.DenyHosts, host) {
+		return fmt.Errorf("%w: host %s is denied", ErrEgressDenied, host)
+	}
+	if len(e.policy.AllowHosts) > 0 && !matchHosts(e.policy.AllowHosts, host) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrEgressDenied, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return e.checkAddr(addr)
+	}
+	return nil
+}
+
+func matchHosts(patterns []string, host string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if suffix, ok := strings.CutPrefix(p, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *egress) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap().WithZone("")
+	if e.policy.IPv4Only && !addr.Is4() {
+		return fmt.Errorf("%w: %s is not IPv4", ErrEgressDenied, addr)
+	}
+	for _, p := range e.deny {
+		if p.Contains(addr) {
+			return fmt.Errorf("%w: %s is in denied range %s", ErrEgressDenied, addr, p)
+		}
+	}
+	if e.policy.BlockPrivate && isPrivate(addr) {
+		for _, p := range e.exceptions {
+			if p.Contains(addr) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s is a private address", ErrEgressDenied, addr)
+	}
+	return nil
+}
+
+var (
+	carrierNAT = netip.MustParsePrefix("100.64.0.0/10")
+	thisNet    = netip.MustParsePrefix("0.0.0.0/8")
+	nat64      = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour  = netip.MustParsePrefix("2002::/16")
+)
+
+// isPrivate reports whether addr is one a public service has no business
+// being told to connect to.
+func isPrivate(addr netip.Addr) bool {
+	// IPv6 forms that carry an IPv4 address are as private as it is.
+	if addr.Is6() {
+		b := addr.As16()
+		switch {
+		case nat64.Contains(addr):
+			return isPrivate(netip.AddrFrom4([4]byte(b[12:16])))
+		case sixToFour.Contains(addr):
+			return isPrivate(netip.AddrFrom4([4]byte(b[2:6])))
+		}
+	}
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		carrierNAT.Contains(addr) || thisNet.Contains(addr)
+}
+
+// control is the net.Dialer hook that checks the address actually being
+// connected to, after DNS resolution.
+func (e *egress) control(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: can't parse %s", ErrEgressDenied, address)
+	}
+	return e.checkAddr(ap.Addr())
+}
+
+// Transport returns an http.RoundTripper that enforces the policy. It is a
+// clone of http.DefaultTransport without the proxy from the environment -
+// a proxy would do the dialing where the policy can't see it.
+func (p EgressPolicy) Transport() (http.RoundTripper, error) {
+	e, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: e.control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if p.IPv4Only {
+			network = "tcp4"
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := e.checkURL(req.URL); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		return transport.RoundTrip(req)
+	}), nil
+}
+
+// HTTPClient returns an *http.Client that enforces the policy, with the
+// middleware mw around its transport.
+func (p EgressPolicy) HTTPClient(mw ...TransportMiddleware) (*http.Client, error) {
+	t, err := p.Transport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: ChainTransport(t, mw...)}, nil
+}
+
+// Install builds the policy's client, with the middleware mw around its
+// transport, and makes it the one DefaultHTTPClient returns.
+func (p EgressPolicy) Install(mw ...TransportMiddleware) error {
+	hc, err := p.HTTPClient(mw...)
+	if err != nil {
+		return err
+	}
+	SetDefaultHTTPClient(hc)
+	return nil
+}
@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"context"
+	"math"
+)
+
+// EmbeddingsProvider turns texts into vectors - embeddings - placed so
+// that texts with similar meanings are close together. It's what semantic
+// search, retrieval and long-term memory are built on.
+//
+// The OpenAI and Gemini clients implement it; create one with an
+// embedding model:
+//
+//	embedder := openai.New(os.Getenv("OPENAI_API_KEY"), "text-embedding-3-small")
+//	vectors, err := embedder.CreateEmbeddings(ctx, []string{"first text", "second text"})
+type EmbeddingsProvider interface {
+	// CreateEmbeddings returns one vector per text, in the same order.
+	// Providers split long lists into as many API calls as they need.
+	CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// CosineSimilarity compares two embeddings: 1 for the same direction, 0
+// for unrelated, -1 for opposite. Vectors of different lengths - from
+// different models - compare as 0.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
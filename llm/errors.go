@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is returned by providers when the API answers with a non-200
+// status. It keeps the status code and the server's Retry-After hint so
+// callers (and the agent's retry logic) can decide what to do without
+// parsing error strings.
+//
+//	var apiErr *llm.APIError
+//	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+//	    log.Fatal("check your API key")
+//	}
+type APIError struct {
+	Provider   string        // "openai", "anthropic", "gemini", ...
+	StatusCode int           // HTTP status code
+	Body       string        // raw response body, usually a JSON error object
+	RetryAfter time.Duration // from the Retry-After header. 0 if absent.
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the request may succeed if sent again:
+// rate limits (429), timeouts (408), and server-side errors (5xx, which
+// includes Anthropic's 529 "overloaded").
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode >= 500
+}
+
+// NewAPIError builds an APIError from a non-200 response and its body.
+// Providers call this instead of formatting the error themselves.
+func NewAPIError(provider string, resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// ParseRetryAfter reads a Retry-After header value, which is either a
+// number of seconds or an HTTP date. It returns 0 if the value is empty,
+// malformed, or in the past.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// IsTransient reports whether err looks like a failure worth retrying:
+// an APIError with a temporary status, a network error or timeout, or a
+// connection dropped mid-response.
+//
+// Cancellation and deadlines are never transient - the caller asked to
+// stop. Neither are TLS policy violations: a pin mismatch won't fix itself.
+func IsTransient(err error) bool {
+	if err == nil ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrPinMismatch) || errors.Is(err, ErrInsecureTransport) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+
+	var opErr *net.OpError // connection refused, reset, DNS failure...
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
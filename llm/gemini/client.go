@@ -9,6 +9,7 @@
 //   - Roles: "assistant" becomes "model", "tool" becomes "user" with functionResponse
 //   - Tool calls are "functionCall" parts, tool results are "functionResponse" parts
 //   - Tool call args are a JSON object, not a JSON string (unlike OpenAI)
+//   - Tool schemas must be in Gemini's JSON Schema subset (see schema.Gemini)
 //   - Finish reason is ALWAYS "STOP" even for tool calls — we detect tool calls
 //     by inspecting response parts for functionCall, not by finish reason
 //   - Config (temperature, maxTokens, etc.) goes in a nested "generationConfig" object
@@ -17,12 +18,12 @@ package gemini
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"go-agent-sdk/llm"
-	"io"
+	"go-agent-sdk/llm/devkit"
+	"go-agent-sdk/llm/internal/bufpool"
+	"go-agent-sdk/llm/schema"
 	"net/http"
 )
 
@@ -33,9 +34,22 @@ type geminiRequest struct {
 	Contents          []geminiContent    `json:"contents"`
 	SystemInstruction *systemInstruction `json:"systemInstruction,omitempty"`
 	Tools             []geminiTool       `json:"tools,omitempty"`
+	ToolConfig        *toolConfig        `json:"toolConfig,omitempty"`
 	GenerationConfig  *generationConfig  `json:"generationConfig,omitempty"`
 }
 
+// toolConfig says whether the model may, must or mustn't call functions.
+type toolConfig struct {
+	FunctionCallingConfig functionCallingConfig `json:"functionCallingConfig"`
+}
+
+// functionCallingConfig's Mode is "AUTO", "ANY" (must call one of
+// AllowedFunctionNames, or any function if empty) or "NONE".
+type functionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
 // systemInstruction holds the system prompt as a top-level field.
 // Gemini requires role to be "user" here (not "system").
 type systemInstruction struct {
@@ -54,10 +68,54 @@ type geminiContent struct {
 
 // gPart is the union type for content parts. One content can mix text,
 // functionCall, and functionResponse parts in the same array.
+//
+// With thinking enabled, the model's thoughts come back as text parts with
+// Thought set, and function calls may carry a ThoughtSignature that has to
+// be echoed back on the same part in the next request.
 type gPart struct {
 	Text             string             `json:"text,omitempty"`
+	Thought          bool               `json:"thought,omitempty"`
+	ThoughtSignature string             `json:"thoughtSignature,omitempty"`
 	FunctionCall     *gFunctionCall     `json:"functionCall,omitempty"`
 	FunctionResponse *gFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *gBlob             `json:"inlineData,omitempty"`
+	FileData         *gFileData         `json:"fileData,omitempty"`
+}
+
+// gBlob is inline media (an image or a document) as base64.
+type gBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// gFileData references media by URI. Gemini only fetches URIs it knows
+// (Files API uploads, gs:// buckets), not arbitrary web URLs.
+type gFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+// mapParts converts multimodal content parts to Gemini parts. Data URLs
+// and files become inlineData; other URLs become fileData.
+func mapParts(parts []llm.ContentPart) []gPart {
+	out := make([]gPart, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case p.Type == "text":
+			out = append(out, gPart{Text: p.Text})
+		case p.ImageURL != nil:
+			if mime, data, ok := llm.ParseDataURL(p.ImageURL.URL); ok {
+				out = append(out, gPart{InlineData: &gBlob{MimeType: mime, Data: data}})
+			} else {
+				out = append(out, gPart{FileData: &gFileData{FileURI: p.ImageURL.URL}})
+			}
+		case p.File != nil:
+			if mime, data, ok := llm.ParseDataURL(p.File.FileData); ok {
+				out = append(out, gPart{InlineData: &gBlob{MimeType: mime, Data: data}})
+			}
+		}
+	}
+	return out
 }
 
 // gFunctionCall is a tool invocation from the model.
@@ -94,10 +152,23 @@ type gFunctionDeclaration struct {
 // generationConfig holds model configuration parameters.
 // These are nested under a single object, not top-level like OpenAI.
 type generationConfig struct {
-	Temperature     float64  `json:"temperature,omitempty"`
-	TopP            float64  `json:"topP,omitempty"`
-	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
-	StopSequences   []string `json:"stopSequences,omitempty"`
+	Temperature     float64         `json:"temperature,omitempty"`
+	TopP            float64         `json:"topP,omitempty"`
+	MaxOutputTokens int             `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string        `json:"stopSequences,omitempty"`
+	ThinkingConfig  *thinkingConfig `json:"thinkingConfig,omitempty"`
+
+	// JSON output: responseMimeType "application/json", optionally with a
+	// schema the output must follow.
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
+	ResponseSchema   any    `json:"responseSchema,omitempty"`
+}
+
+// thinkingConfig controls Gemini 2.5+ thinking. includeThoughts asks for
+// a summary of the thoughts in the response, which we surface as Reasoning.
+type thinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts"`
 }
 
 // geminiResponse is the top-level response from generateContent.
@@ -139,6 +210,9 @@ type Client struct {
 	model      string
 	baseURL    string
 	httpClient *http.Client
+	newID      llm.IDGenerator           // makes up tool call IDs, Gemini doesn't reliably send them
+	dimensions int                       // embedding size to ask for. 0 means the model's default.
+	transport  []llm.TransportMiddleware // wraps httpClient's transport, see WithTransportMiddleware
 }
 
 type Option func(*Client)
@@ -159,6 +233,34 @@ func WithHTTPClient(hc *http.Client) Option {
 	}
 }
 
+// WithTransportMiddleware wraps the transport requests go through in mw,
+// the first outermost - to add tracing, metrics or retries from a library
+// you already use, on top of the default client or one from WithHTTPClient:
+//
+//	provider := gemini.New(key, "gemini-2.5-flash",
+//	    gemini.WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+//	        return otelhttp.NewTransport(next)
+//	    }),
+//	)
+//
+// It can be given more than once and in any order with WithHTTPClient;
+// the middleware is applied once every option has been.
+func WithTransportMiddleware(mw ...llm.TransportMiddleware) Option {
+	return func(c *Client) {
+		c.transport = append(c.transport, mw...)
+	}
+}
+
+// WithIDGenerator overrides how tool call IDs are generated.
+// Gemini doesn't reliably return IDs on functionCall, so we make our own and
+// the agent passes them through ToolCall.ID, then ToolCallID, then back here.
+// The default is llm.NewCallID; use llm.SequentialIDs for predictable IDs in tests.
+func WithIDGenerator(gen llm.IDGenerator) Option {
+	return func(c *Client) {
+		c.newID = gen
+	}
+}
+
 // New creates a Gemini provider.
 //
 // Example:
@@ -171,10 +273,12 @@ func New(apiKey string, model string, opts ...Option) *Client {
 		model:      model,
 		baseURL:    DefaultBaseURL,
 		httpClient: &http.Client{},
+		newID:      llm.NewCallID,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.httpClient = llm.WrapHTTPClient(c.httpClient, c.transport...)
 	return c
 }
 
@@ -183,15 +287,6 @@ func (c *Client) ModelName() string {
 	return c.model
 }
 
-// generateCallID creates a random ID for linking tool calls to tool results.
-// Gemini doesn't reliably return IDs on functionCall, so we generate our own.
-// The agent passes these through ToolCall.ID, then ToolCallID, then back here.
-func generateCallID() string {
-	b := make([]byte, 12)
-	_, _ = rand.Read(b)
-	return "call_" + hex.EncodeToString(b)
-}
-
 // mapRequest translates our common llm.ChatRequest into Gemini's native format.
 func mapRequest(req llm.ChatRequest) geminiRequest {
 
@@ -210,9 +305,13 @@ func mapRequest(req llm.ChatRequest) geminiRequest {
 			sysInst.Parts = append(sysInst.Parts, gPart{Text: msg.Content})
 
 		case "user":
+			parts := []gPart{{Text: msg.Content}}
+			if len(msg.Parts) > 0 {
+				parts = mapParts(msg.Parts)
+			}
 			contents = append(contents, geminiContent{
 				Role:  "user",
-				Parts: []gPart{{Text: msg.Content}},
+				Parts: parts,
 			})
 
 		case "assistant":
@@ -224,18 +323,23 @@ func mapRequest(req llm.ChatRequest) geminiRequest {
 					parts = append(parts, gPart{Text: msg.Content})
 				}
 
-				for _, call := range msg.ToolCalls {
+				for i, call := range msg.ToolCalls {
 					// OpenAI Arguments is a JSON string, Gemini wants a JSON object.
 					var argsObj any
 					if err := json.Unmarshal([]byte(call.Function.Arguments), &argsObj); err != nil {
 						argsObj = map[string]any{}
 					}
-					parts = append(parts, gPart{
+					part := gPart{
 						FunctionCall: &gFunctionCall{
 							Name: call.Function.Name,
 							Args: argsObj,
 						},
-					})
+					}
+					// The thought signature belongs on the first function call.
+					if i == 0 {
+						part.ThoughtSignature = msg.ReasoningSignature
+					}
+					parts = append(parts, part)
 				}
 
 				contents = append(contents, geminiContent{
@@ -254,19 +358,27 @@ func mapRequest(req llm.ChatRequest) geminiRequest {
 			// Tool results go in role="user" with functionResponse parts.
 			// Gemini requires the response to be an object, not a plain string,
 			// so we wrap it in {"return_value": "..."}.
+			// Images and files the tool returned ride along as inline parts
+			// next to the functionResponse.
 			respObj := map[string]any{"return_value": msg.Content}
-
-			contents = append(contents, geminiContent{
-				Role: "user",
-				Parts: []gPart{
-					{
-						FunctionResponse: &gFunctionResponse{
-							Name:     msg.Name,
-							Response: respObj,
-							ID:       msg.ToolCallID,
-						},
+			parts := []gPart{
+				{
+					FunctionResponse: &gFunctionResponse{
+						Name:     msg.Name,
+						Response: respObj,
+						ID:       msg.ToolCallID,
 					},
 				},
+			}
+			for _, p := range mapParts(msg.Parts) {
+				if p.Text == "" {
+					parts = append(parts, p)
+				}
+			}
+
+			contents = append(contents, geminiContent{
+				Role:  "user",
+				Parts: parts,
 			})
 		}
 	}
@@ -280,38 +392,89 @@ func mapRequest(req llm.ChatRequest) geminiRequest {
 			decls = append(decls, gFunctionDeclaration{
 				Name:        t.Function.Name,
 				Description: t.Function.Description,
-				Parameters:  t.Function.Parameters,
+				Parameters:  schema.Gemini(t.Function.Parameters),
 			})
 		}
 		tools = append(tools, geminiTool{FunctionDeclarations: decls})
 	}
+	var toolCfg *toolConfig
+	if len(tools) > 0 {
+		toolCfg = mapToolChoice(req.ToolChoice)
+	}
 
 	// Build generation config from request fields.
 	var genConfig *generationConfig
-	if req.Temperature != 0 || req.TopP != 0 || req.MaxTokens != 0 || len(req.Stop) > 0 {
+	rf := req.ResponseFormat
+	jsonOutput := rf != nil && (rf.Type == "json_object" || rf.Type == "json_schema")
+	if req.Temperature != 0 || req.TopP != 0 || req.MaxTokens != 0 || len(req.Stop) > 0 || req.Reasoning != nil || jsonOutput {
 		genConfig = &generationConfig{
 			Temperature:     req.Temperature,
 			TopP:            req.TopP,
 			MaxOutputTokens: req.MaxTokens,
 			StopSequences:   req.Stop,
 		}
+		if req.Reasoning != nil {
+			genConfig.ThinkingConfig = &thinkingConfig{
+				ThinkingBudget:  req.Reasoning.Budget(),
+				IncludeThoughts: true,
+			}
+		}
+		if jsonOutput {
+			genConfig.ResponseMimeType = "application/json"
+			if rf.JSONSchema != nil {
+				genConfig.ResponseSchema = schema.Gemini(rf.JSONSchema.Schema)
+			}
+		}
 	}
 
 	return geminiRequest{
 		Contents:          contents,
 		SystemInstruction: sysInst,
 		Tools:             tools,
+		ToolConfig:        toolCfg,
 		GenerationConfig:  genConfig,
 	}
 }
 
+// mapToolChoice converts ChatRequest.ToolChoice. Values llm.ParseToolChoice
+// doesn't understand are left out, which means "AUTO".
+func mapToolChoice(v any) *toolConfig {
+	c, err := llm.ParseToolChoice(v)
+	if err != nil || c == nil {
+		return nil
+	}
+	cfg := functionCallingConfig{Mode: "AUTO"}
+	switch c.Mode {
+	case llm.ToolChoiceModeNone:
+		cfg.Mode = "NONE"
+	case llm.ToolChoiceModeRequired:
+		cfg.Mode = "ANY"
+	case llm.ToolChoiceModeFunction:
+		cfg.Mode = "ANY"
+		cfg.AllowedFunctionNames = []string{c.Function}
+	}
+	return &toolConfig{FunctionCallingConfig: cfg}
+}
+
+// mapUsage converts Gemini's counts to ours. Thinking tokens are billed as
+// output, so they count as completion tokens.
+func mapUsage(u geminiUsage) llm.Usage {
+	return llm.Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		TotalTokens:      u.TotalTokenCount,
+	}
+}
+
 // mapResponse translates Gemini's native response into our common llm.ChatResponse.
 //
 // The critical difference from OpenAI/Anthropic: Gemini returns finishReason="STOP"
 // for BOTH text responses and tool calls. We detect tool calls by checking whether
 // any part contains a functionCall, and set finish_reason accordingly so the agent's
 // Run() loop branches correctly.
-func mapResponse(resp geminiResponse) *llm.ChatResponse {
+//
+// Gemini doesn't give us call IDs, so newID makes them up.
+func mapResponse(ctx context.Context, resp geminiResponse, newID llm.IDGenerator) *llm.ChatResponse {
 
 	if len(resp.Candidates) == 0 {
 		return &llm.ChatResponse{
@@ -321,16 +484,23 @@ func mapResponse(resp geminiResponse) *llm.ChatResponse {
 
 	candidate := resp.Candidates[0]
 
-	// Walk parts, collecting text and tool calls separately.
-	var textContent string
+	// Walk parts, collecting text, thoughts and tool calls separately.
+	var textContent, thoughts, signature string
 	var toolCalls []llm.ToolCall
 
 	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thoughts += part.Text
+			continue
+		}
 		if part.Text != "" {
 			textContent += part.Text
 		}
 
 		if part.FunctionCall != nil {
+			if signature == "" {
+				signature = part.ThoughtSignature
+			}
 			// Gemini Args is a JSON object, our common format wants a JSON string.
 			argsJSON, err := json.Marshal(part.FunctionCall.Args)
 			if err != nil {
@@ -338,7 +508,7 @@ func mapResponse(resp geminiResponse) *llm.ChatResponse {
 			}
 
 			toolCalls = append(toolCalls, llm.ToolCall{
-				ID:   generateCallID(),
+				ID:   newID(ctx),
 				Type: "function",
 				Function: llm.FunctionCall{
 					Name:      part.FunctionCall.Name,
@@ -357,25 +527,12 @@ func mapResponse(resp geminiResponse) *llm.ChatResponse {
 		finishReason = "tool_calls"
 	} else {
 		// No tool calls — map Gemini's native finish reason.
-		switch candidate.FinishReason {
-		case "STOP":
-			finishReason = "stop"
-		case "MAX_TOKENS":
-			finishReason = "length"
-		case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT":
-			finishReason = "content_filter"
-		default:
-			finishReason = candidate.FinishReason
-		}
+		finishReason = devkit.NormalizeFinishReason(candidate.FinishReason)
 	}
 
 	var usage llm.Usage
 	if resp.UsageMetadata != nil {
-		usage = llm.Usage{
-			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount + resp.UsageMetadata.ThoughtsTokenCount,
-			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
-		}
+		usage = mapUsage(*resp.UsageMetadata)
 	}
 
 	return &llm.ChatResponse{
@@ -384,9 +541,11 @@ func mapResponse(resp geminiResponse) *llm.ChatResponse {
 			{
 				Index: 0,
 				Message: llm.Message{
-					Role:      "assistant",
-					Content:   textContent,
-					ToolCalls: toolCalls,
+					Role:               "assistant",
+					Content:            textContent,
+					ToolCalls:          toolCalls,
+					Reasoning:          thoughts,
+					ReasoningSignature: signature,
 				},
 				FinishReason: finishReason,
 			},
@@ -398,18 +557,51 @@ func mapResponse(resp geminiResponse) *llm.ChatResponse {
 // CreateChat sends a chat completion request to Gemini's generateContent endpoint.
 // It implements the llm.ChatProvider interface.
 func (c *Client) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	resp, err := c.post(ctx, mapRequest(req), "generateContent")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	nativeReq := mapRequest(req)
+	body, err := bufpool.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to read response body: %w", err)
+	}
+	defer bufpool.Put(body)
+
+	var nativeResp geminiResponse
+	if err := json.Unmarshal(body.Bytes(), &nativeResp); err != nil {
+		return nil, fmt.Errorf("gemini: failed to decode response: %w", err)
+	}
+	return mapResponse(ctx, nativeResp, c.newID), nil
+}
+
+// Warmup implements llm.Warmer: it opens a connection to the API by
+// fetching the model's metadata, which costs no tokens.
+func (c *Client) Warmup(ctx context.Context) error {
+	header := http.Header{}
+	header.Set("x-goog-api-key", c.apiKey)
+	url := fmt.Sprintf("%s/v1beta/models/%s", c.baseURL, c.model)
+	if err := llm.WarmConnection(ctx, c.httpClient, url, header); err != nil {
+		return fmt.Errorf("gemini: %w", err)
+	}
+	return nil
+}
 
-	jsonData, err := json.Marshal(nativeReq)
+// post sends a native request to one of the model's methods
+// (generateContent, streamGenerateContent?alt=sse, batchEmbedContents). On a non-200 status
+// it reads the body into an llm.APIError; otherwise the caller must close
+// the response.
+func (c *Client) post(ctx context.Context, nativeReq any, method string) (*http.Response, error) {
+	reqBuf, err := bufpool.EncodeJSON(nativeReq)
 	if err != nil {
 		return nil, fmt.Errorf("gemini: failed to marshal request: %w", err)
 	}
 
 	// Gemini puts the model name in the URL path, not in the request body.
-	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent", c.baseURL, c.model)
+	url := fmt.Sprintf("%s/v1beta/models/%s:%s", c.baseURL, c.model, method)
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBuf.Bytes()))
 	if err != nil {
 		return nil, fmt.Errorf("gemini: failed to create HTTP request: %w", err)
 	}
@@ -422,21 +614,10 @@ func (c *Client) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.Chat
 	if err != nil {
 		return nil, fmt.Errorf("gemini: HTTP request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("gemini: failed to read response body: %w", err)
-	}
+	resp.Body = bufpool.ReleaseOnClose(resp.Body, reqBuf)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("gemini: unexpected status %d: %s", resp.StatusCode, string(body))
+	if err := devkit.CheckResponse("gemini", resp); err != nil {
+		return nil, err
 	}
-
-	var nativeResp geminiResponse
-	if err := json.Unmarshal(body, &nativeResp); err != nil {
-		return nil, fmt.Errorf("gemini: failed to decode response: %w", err)
-	}
-
-	return mapResponse(nativeResp), nil
+	return resp, nil
 }
@@ -0,0 +1,89 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-agent-sdk/llm/internal/bufpool"
+)
+
+// maxEmbeddingInputs is how many texts Gemini accepts in one
+// batchEmbedContents call.
+const maxEmbeddingInputs = 100
+
+// WithDimensions asks for embeddings of n dimensions instead of the
+// model's default (outputDimensionality). Shorter vectors are cheaper to
+// store and search, for a little accuracy.
+func WithDimensions(n int) Option {
+	return func(c *Client) {
+		c.dimensions = n
+	}
+}
+
+type embedContentRequest struct {
+	Model                string        `json:"model"`
+	Content              geminiContent `json:"content"`
+	OutputDimensionality int           `json:"outputDimensionality,omitempty"`
+}
+
+type batchEmbedRequest struct {
+	Requests []embedContentRequest `json:"requests"`
+}
+
+type batchEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// CreateEmbeddings implements llm.EmbeddingsProvider with
+// batchEmbedContents, the batch form of embedContent. The client's model
+// has to be an embedding model, e.g. "gemini-embedding-001".
+func (c *Client) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingInputs {
+		batch := texts[start:min(start+maxEmbeddingInputs, len(texts))]
+		vectors, err := c.embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vectors...)
+	}
+	return out, nil
+}
+
+func (c *Client) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	req := batchEmbedRequest{Requests: make([]embedContentRequest, len(texts))}
+	for i, text := range texts {
+		req.Requests[i] = embedContentRequest{
+			Model:                "models/" + c.model,
+			Content:              geminiContent{Role: "user", Parts: []gPart{{Text: text}}},
+			OutputDimensionality: c.dimensions,
+		}
+	}
+	resp, err := c.post(ctx, req, "batchEmbedContents")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := bufpool.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: failed to read response body: %w", err)
+	}
+	defer bufpool.Put(body)
+
+	var native batchEmbedResponse
+	if err := json.Unmarshal(body.Bytes(), &native); err != nil {
+		return nil, fmt.Errorf("gemini: failed to decode response: %w", err)
+	}
+	if len(native.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("gemini: got %d embeddings for %d texts", len(native.Embeddings), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, e := range native.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
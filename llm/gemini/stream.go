@@ -0,0 +1,127 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/internal/sse"
+)
+
+// Streaming uses streamGenerateContent?alt=sse. Every event is a complete
+// generateContent response holding only the new parts:
+//
+//	data: {"candidates": [{"content": {"parts": [{"text": "Hel"}]}}], "usageMetadata": {...}}
+//	data: {"candidates": [{"content": {"parts": [{"text": "lo"}]}, "finishReason": "STOP"}], ...}
+//
+// Text (and thoughts) arrive in pieces; function calls arrive whole, in one
+// part. usageMetadata comes with every chunk and is cumulative, so the last
+// one has the totals. We merge the parts into one response and run it
+// through mapResponse, like a blocking call.
+
+// CreateChatStream sends a streaming request to Gemini. It implements
+// llm.StreamingChatProvider: onDelta sees text, thoughts, tool calls and
+// token counts as they arrive, and the assembled response is returned at
+// the end.
+func (c *Client) CreateChatStream(ctx context.Context, req llm.ChatRequest, onDelta llm.StreamHandler) (*llm.ChatResponse, error) {
+	resp, err := c.post(ctx, mapRequest(req), "streamGenerateContent?alt=sse")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		merged  geminiResponse
+		cand    geminiCandidate
+		callIDs []string // IDs handed out in deltas, reused in the response
+	)
+
+	err = sse.Read(resp.Body, "gemini", func(_, data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("gemini: failed to decode stream chunk: %w", err)
+		}
+		if chunk.ModelVersion != "" {
+			merged.ModelVersion = chunk.ModelVersion
+		}
+
+		if len(chunk.Candidates) > 0 {
+			cc := chunk.Candidates[0]
+			if cc.FinishReason != "" {
+				cand.FinishReason = cc.FinishReason
+			}
+			for _, part := range cc.Content.Parts {
+				cand.Content.Parts = appendPart(cand.Content.Parts, part)
+				if onDelta == nil {
+					continue
+				}
+				switch {
+				case part.Thought:
+					onDelta(llm.StreamDelta{Reasoning: part.Text})
+				case part.Text != "":
+					onDelta(llm.StreamDelta{Content: part.Text})
+				case part.FunctionCall != nil:
+					args, err := json.Marshal(part.FunctionCall.Args)
+					if err != nil {
+						args = []byte("{}")
+					}
+					id := c.newID(ctx)
+					callIDs = append(callIDs, id)
+					onDelta(llm.StreamDelta{
+						ToolCallIndex:  len(callIDs) - 1,
+						ToolCallID:     id,
+						ToolName:       part.FunctionCall.Name,
+						ArgumentsDelta: string(args),
+					})
+				}
+			}
+		}
+
+		if chunk.UsageMetadata != nil {
+			merged.UsageMetadata = chunk.UsageMetadata
+			if onDelta != nil {
+				u := mapUsage(*chunk.UsageMetadata)
+				onDelta(llm.StreamDelta{Usage: &u})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cand.Content.Parts) > 0 || cand.FinishReason != "" {
+		cand.Content.Role = "model"
+		merged.Candidates = []geminiCandidate{cand}
+	}
+	out := mapResponse(ctx, merged, c.newID)
+
+	// Keep the IDs the deltas announced.
+	if len(out.Choices) > 0 {
+		calls := out.Choices[0].Message.ToolCalls
+		for i := range calls {
+			if i < len(callIDs) {
+				calls[i].ID = callIDs[i]
+			}
+		}
+	}
+	return out, nil
+}
+
+// appendPart adds a streamed part, joining it onto the previous one when
+// both are plain text (or both thoughts), so the merged response looks like
+// a blocking one.
+func appendPart(parts []gPart, p gPart) []gPart {
+	if n := len(parts); n > 0 && isPlainText(p) && isPlainText(parts[n-1]) && parts[n-1].Thought == p.Thought {
+		parts[n-1].Text += p.Text
+		if p.ThoughtSignature != "" {
+			parts[n-1].ThoughtSignature = p.ThoughtSignature
+		}
+		return parts
+	}
+	return append(parts, p)
+}
+
+func isPlainText(p gPart) bool {
+	return p.FunctionCall == nil && p.FunctionResponse == nil && p.InlineData == nil && p.FileData == nil
+}
@@ -0,0 +1,178 @@
+// Package history provides an immutable, segment-based conversation log.
+//
+// Agent.History is a plain []llm.Message, which is the right thing for
+// sending a request. But code that keeps many versions of a long
+// conversation around - memory strategies trimming it, forks exploring
+// alternatives, stores persisting it after every turn - ends up copying the
+// whole slice each time. With tens of thousands of messages that's megabytes
+// per turn.
+//
+// A Snapshot stores the messages in fixed-size segments. Appending creates a
+// new Snapshot that shares every full segment with the old one and copies at
+// most one partial segment, so a Snapshot is cheap to keep, pass around and
+// fork. Old snapshots never change.
+//
+//	s := history.New(llm.NewSystemMessage("You are terse."))
+//	s = s.Append(llm.NewUserMessage("hi"))
+//	fork := s.Append(llm.NewUserMessage("try another way"))  // s is untouched
+//	req.Messages = fork.Messages()
+package history
+
+import (
+	"iter"
+
+	"go-agent-sdk/llm"
+)
+
+// SegmentSize is how many messages each segment holds. Appends copy at most
+// one segment, and Slice shares whole segments.
+const SegmentSize = 64
+
+// Snapshot is an immutable view of a conversation. The zero value is an
+// empty conversation, ready to use.
+//
+// Snapshots are values: copying one is cheap and both copies see the same
+// messages. They are safe for concurrent use since nothing ever mutates them.
+type Snapshot struct {
+	segs [][]llm.Message // all segments are frozen once published
+	off  int             // index of the first visible message in segs[0]
+	n    int             // number of visible messages
+}
+
+// New returns a snapshot holding copies of msgs.
+func New(msgs ...llm.Message) Snapshot {
+	return Snapshot{}.Append(msgs...)
+}
+
+// Len returns the number of messages.
+func (s Snapshot) Len() int {
+	return s.n
+}
+
+// At returns the i-th message. It panics if i is out of range, like a slice.
+//
+// Don't modify the message's ToolCalls slice - it is shared.
+func (s Snapshot) At(i int) llm.Message {
+	if i < 0 || i >= s.n {
+		panic("history: index out of range")
+	}
+	i += s.off
+	return s.segs[i/SegmentSize][i%SegmentSize]
+}
+
+// Last returns the final message, or false if the snapshot is empty.
+func (s Snapshot) Last() (llm.Message, bool) {
+	if s.n == 0 {
+		return llm.Message{}, false
+	}
+	return s.At(s.n - 1), true
+}
+
+// Append returns a new snapshot with msgs added at the end. s itself is
+// unchanged, so appending to the same snapshot twice forks the conversation.
+func (s Snapshot) Append(msgs ...llm.Message) Snapshot {
+	if len(msgs) == 0 {
+		return s
+	}
+
+	// Copy the segment list (small - one entry per SegmentSize messages) and
+	// the partial last segment, if any. Full segments are shared.
+	end := s.off + s.n
+	full := end / SegmentSize
+	segs := make([][]llm.Message, full, full+1+len(msgs)/SegmentSize)
+	copy(segs, s.segs[:full])
+
+	var cur []llm.Message
+	if end%SegmentSize != 0 {
+		cur = make([]llm.Message, end%SegmentSize, SegmentSize)
+		copy(cur, s.segs[full])
+	}
+
+	for _, m := range msgs {
+		if cur == nil {
+			cur = make([]llm.Message, 0, SegmentSize)
+		}
+		cur = append(cur, m)
+		if len(cur) == SegmentSize {
+			segs = append(segs, cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		segs = append(segs, cur)
+	}
+
+	return Snapshot{segs: segs, off: s.off, n: s.n + len(msgs)}
+}
+
+// Slice returns the messages from i up to (not including) j, sharing
+// storage with s. Memory strategies use it to drop old turns without
+// copying the rest. It panics if the bounds are invalid, like a slice.
+func (s Snapshot) Slice(i, j int) Snapshot {
+	if i < 0 || j < i || j > s.n {
+		panic("history: slice bounds out of range")
+	}
+	if i == j {
+		return Snapshot{}
+	}
+	start := s.off + i
+	first := start / SegmentSize
+	last := (s.off + j - 1) / SegmentSize
+	return Snapshot{
+		segs: s.segs[first : last+1 : last+1],
+		off:  start % SegmentSize,
+		n:    j - i,
+	}
+}
+
+// Messages returns the conversation as a fresh slice, e.g. for a
+// ChatRequest. This is the one operation that copies everything; call it
+// once per request, not once per message.
+func (s Snapshot) Messages() []llm.Message {
+	out := make([]llm.Message, 0, s.n)
+	for _, seg := range s.Segments() {
+		out = append(out, seg...)
+	}
+	return out
+}
+
+// All iterates over the messages in order without copying them.
+func (s Snapshot) All() iter.Seq2[int, llm.Message] {
+	return func(yield func(int, llm.Message) bool) {
+		i := 0
+		for _, seg := range s.Segments() {
+			for _, m := range seg {
+				if !yield(i, m) {
+					return
+				}
+				i++
+			}
+		}
+	}
+}
+
+// Segments returns the visible messages as the underlying segments, in
+// order. Stores can persist a long conversation incrementally by writing
+// only the segments they haven't seen. The segments are shared - don't
+// modify them.
+func (s Snapshot) Segments() [][]llm.Message {
+	if s.n == 0 {
+		return nil
+	}
+	out := make([][]llm.Message, 0, len(s.segs))
+	remaining := s.n
+	for k, seg := range s.segs {
+		if k == 0 {
+			seg = seg[s.off:]
+		}
+		if len(seg) > remaining {
+			seg = seg[:remaining]
+		}
+		out = append(out, seg)
+		remaining -= len(seg)
+		if remaining == 0 {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// IDGenerator produces tool call IDs for providers whose API doesn't return
+// them (Gemini, some OpenAI-compatible servers). Every provider that has to
+// make up IDs uses one of these, so the format is the same everywhere.
+//
+// The context is the Run context, which lets a generator pick up the
+// agent's seeded random source.
+type IDGenerator func(ctx context.Context) string
+
+// NewCallID is the default IDGenerator. It returns "call_" followed by
+// 24 hex characters - the same shape OpenAI uses.
+//
+// If ctx carries a seeded random source (agent.WithRandSeed) the ID is
+// drawn from it. Otherwise it uses math/rand/v2's global generator, which is
+// goroutine-safe and doesn't make a syscall per ID like crypto/rand does.
+// Call IDs only need to be unique within a conversation, not unguessable.
+func NewCallID(ctx context.Context) string {
+	return randomID(ctx, "call_")
+}
+
+// NewRunID returns "run_" followed by 24 hex characters, drawn the same
+// way as NewCallID. The agent uses it to identify runs in traces and
+// feedback.
+func NewRunID(ctx context.Context) string {
+	return randomID(ctx, "run_")
+}
+
+// randomID returns prefix plus 24 random hex characters.
+func randomID(ctx context.Context, prefix string) string {
+	return prefix + randomHex(ctx, 12)
+}
+
+// randomHex returns n random bytes as hex, drawn from the context's random
+// source if it has one.
+func randomHex(ctx context.Context, n int) string {
+	r := RandFromContext(ctx)
+
+	raw := make([]byte, (n+3)/4*4)
+	for i := 0; i < len(raw); i += 4 {
+		var v uint32
+		if r != nil {
+			v = r.Uint32()
+		} else {
+			v = rand.Uint32()
+		}
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = byte(v), byte(v>>8), byte(v>>16), byte(v>>24)
+	}
+	return hex.EncodeToString(raw[:n])
+}
+
+// SequentialIDs returns an IDGenerator that counts up: prefix_1, prefix_2, ...
+// Handy in tests where you want to assert on exact IDs. Safe for concurrent use.
+//
+//	provider := gemini.New(key, "gemini-2.5-flash",
+//	    gemini.WithIDGenerator(llm.SequentialIDs("call")),
+//	)
+func SequentialIDs(prefix string) IDGenerator {
+	var n atomic.Uint64
+	return func(context.Context) string {
+		return prefix + "_" + strconv.FormatUint(n.Add(1), 10)
+	}
+}
+
+// IDNormalizer rewrites a tool call ID into a shape a particular provider
+// accepts. Providers disagree on what a valid ID looks like - Mistral wants
+// exactly 9 alphanumeric characters, Anthropic only allows [a-zA-Z0-9_-] -
+// so a history recorded with one provider can be rejected by another.
+//
+// A normalizer must be a pure function: the same input always gives the same
+// output. That's what keeps a tool call and its tool result linked after both
+// IDs are rewritten independently.
+type IDNormalizer func(id string) string
+
+// NormalizeToolCallIDs returns a copy of msgs with every tool call ID rewritten
+// by normalize - both ToolCalls[].ID on assistant messages and ToolCallID on
+// tool messages. The input slice is not modified, so it's safe to call on the
+// agent's history right before sending a request.
+//
+// If two different IDs happen to normalize to the same value, the later one
+// is re-normalized with a salt until it's unique, so linkage is never mixed up.
+func NormalizeToolCallIDs(msgs []Message, normalize IDNormalizer) []Message {
+	if normalize == nil {
+		return msgs
+	}
+
+	mapped := make(map[string]string) // original ID -> normalized ID
+	taken := make(map[string]string)  // normalized ID -> original ID
+	lookup := func(id string) string {
+		if id == "" {
+			return id
+		}
+		if out, ok := mapped[id]; ok {
+			return out
+		}
+		out := normalize(id)
+		for salt := 1; ; salt++ {
+			if owner, clash := taken[out]; !clash || owner == id {
+				break
+			}
+			out = normalize(id + "#" + strconv.Itoa(salt))
+		}
+		mapped[id] = out
+		taken[out] = id
+		return out
+	}
+
+	out := make([]Message, len(msgs))
+	for i, msg := range msgs {
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(msg.ToolCalls))
+			copy(calls, msg.ToolCalls)
+			for j := range calls {
+				calls[j].ID = lookup(calls[j].ID)
+			}
+			msg.ToolCalls = calls
+		}
+		if msg.ToolCallID != "" {
+			msg.ToolCallID = lookup(msg.ToolCallID)
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// OpenAIToolCallID keeps IDs that already look like OpenAI's ("call_" prefix,
+// at most 40 characters) and hashes anything else into "call_" + 24 hex chars.
+func OpenAIToolCallID(id string) string {
+	if strings.HasPrefix(id, "call_") && len(id) <= 40 {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	return "call_" + hex.EncodeToString(sum[:12])
+}
+
+// AnthropicToolCallID replaces every character outside [a-zA-Z0-9_-] with an
+// underscore, which is the only thing Anthropic's tool_use ID pattern rejects.
+func AnthropicToolCallID(id string) string {
+	clean := true
+	for i := 0; i < len(id); i++ {
+		if !isIDChar(id[i]) {
+			clean = false
+			break
+		}
+	}
+	if clean && id != "" {
+		return id
+	}
+	if id == "" {
+		return OpenAIToolCallID(id)
+	}
+
+	b := []byte(id)
+	for i := range b {
+		if !isIDChar(b[i]) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// MistralToolCallID maps any ID to exactly 9 alphanumeric characters,
+// which is what Mistral's API requires. IDs already in that shape are kept.
+func MistralToolCallID(id string) string {
+	if len(id) == 9 {
+		ok := true
+		for i := 0; i < len(id); i++ {
+			if !isAlnum(id[i]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return id
+		}
+	}
+
+	const alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	sum := sha256.Sum256([]byte(id))
+	out := make([]byte, 9)
+	for i := range out {
+		out[i] = alphabet[int(sum[i])%len(alphabet)]
+	}
+	return string(out)
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isIDChar(c byte) bool {
+	return isAlnum(c) || c == '_' || c == '-'
+}
@@ -0,0 +1,80 @@
+// Package bufpool recycles the byte buffers providers use to encode
+// requests and read responses.
+//
+// Every LLM call marshals the whole conversation and reads back a response
+// of similar size. At a few hundred runs per second that garbage dominates
+// the profile, so the providers borrow buffers from here instead of calling
+// json.Marshal and io.ReadAll.
+package bufpool
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxPooled is the largest buffer kept for reuse. A single huge request
+// (a pasted log file) shouldn't pin megabytes in the pool forever.
+const maxPooled = 4 << 20
+
+var pool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Get returns an empty buffer from the pool.
+func Get() *bytes.Buffer {
+	return pool.Get().(*bytes.Buffer)
+}
+
+// Put resets b and returns it to the pool. b must not be used afterwards.
+func Put(b *bytes.Buffer) {
+	if b == nil || b.Cap() > maxPooled {
+		return
+	}
+	b.Reset()
+	pool.Put(b)
+}
+
+// EncodeJSON marshals v into a pooled buffer. The output matches
+// json.Marshal plus a trailing newline, which JSON APIs ignore.
+func EncodeJSON(v any) (*bytes.Buffer, error) {
+	b := Get()
+	if err := json.NewEncoder(b).Encode(v); err != nil {
+		Put(b)
+		return nil, err
+	}
+	return b, nil
+}
+
+// ReadAll reads r to EOF into a pooled buffer.
+func ReadAll(r io.Reader) (*bytes.Buffer, error) {
+	b := Get()
+	if _, err := b.ReadFrom(r); err != nil {
+		Put(b)
+		return nil, err
+	}
+	return b, nil
+}
+
+// ReleaseOnClose wraps a response body so that closing it also returns the
+// request buffer to the pool. The transport may read the request body until
+// the response body is closed, so this is the earliest safe point.
+//
+// If the request failed (no response), don't release the buffer at all -
+// the transport may still be reading it. The GC will collect it.
+func ReleaseOnClose(body io.ReadCloser, reqBuf *bytes.Buffer) io.ReadCloser {
+	return &releasingBody{ReadCloser: body, buf: reqBuf}
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once sync.Once
+	buf  *bytes.Buffer
+}
+
+func (r *releasingBody) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() { Put(r.buf) })
+	return err
+}
@@ -0,0 +1,66 @@
+// Package sse reads Server-Sent Events streams, which is how all three
+// providers stream responses.
+package sse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Read reads a Server-Sent Events stream and calls fn once per event with
+// the event name and its data (multi-line data joined with "\n").
+// Comment lines (starting with ":") and events without data are skipped.
+//
+// An error from fn stops the stream and is returned as-is. Read errors are
+// prefixed with provider, like the providers' other errors.
+func Read(r io.Reader, provider string, fn func(event, data string) error) error {
+	reader := bufio.NewReader(r)
+	var event string
+	var data strings.Builder
+
+	dispatch := func() error {
+		defer func() {
+			event = ""
+			data.Reset()
+		}()
+		if data.Len() == 0 {
+			return nil
+		}
+		return fn(event, data.String())
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("%s: failed to read stream: %w", provider, err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if derr := dispatch(); derr != nil {
+				return derr
+			}
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(value)
+			}
+		}
+
+		if err == io.EOF {
+			return dispatch()
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Package llmtest is for unit testing code built on the agent without a
+// model. A MockProvider answers with a script of turns, in order, and
+// keeps every request it was sent, so a test can check both what the
+// agent did with the answers and what it asked:
+//
+//	func TestWeather(t *testing.T) {
+//	    mock := llmtest.NewMockProvider(t,
+//	        llmtest.CallTools(llmtest.Call("get_weather", `{"city":"Paris"}`)).
+//	            Expect(llmtest.HasTools("get_weather")),
+//	        llmtest.Reply("It's sunny in Paris.").
+//	            Expect(llmtest.LastMessage("tool", "sunny")),
+//	    )
+//	    a := agent.New(mock)
+//	    a.RegisterTool("get_weather", "Get the weather", getWeather)
+//
+//	    answer, err := a.Run(context.Background(), "Weather in Paris?")
+//	    if err != nil || answer != "It's sunny in Paris." {
+//	        t.Fatalf("got %q, %v", answer, err)
+//	    }
+//	}
+//
+// With a testing.TB, a failed expectation, a call past the end of the
+// script and turns left unused when the test ends are all test failures.
+// Nothing touches the network.
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-agent-sdk/llm"
+)
+
+// Turn is one scripted exchange: the response (or error) the mock gives,
+// and optionally checks on the request it expects.
+type Turn struct {
+	Response *llm.ChatResponse
+	Err      error
+	Checks   []func(req llm.ChatRequest) error
+}
+
+// Expect adds checks on the request this turn answers. A check that fails
+// fails the call with its error, and the test if the mock has one.
+func (t Turn) Expect(checks ...func(req llm.ChatRequest) error) Turn {
+	t.Checks = append(slices.Clone(t.Checks), checks...)
+	return t
+}
+
+// Reply is a turn answering with text, finish reason "stop".
+func Reply(text string) Turn {
+	return Respond(&llm.ChatResponse{Choices: []llm.Choice{{
+		Message:      llm.Message{Role: "assistant", Content: text},
+		FinishReason: "stop",
+	}}})
+}
+
+// CallTools is a turn asking for tool calls, finish reason "tool_calls".
+func CallTools(calls ...llm.ToolCall) Turn {
+	return Respond(&llm.ChatResponse{Choices: []llm.Choice{{
+		Message:      llm.Message{Role: "assistant", ToolCalls: calls},
+		FinishReason: "tool_calls",
+	}}})
+}
+
+// Respond is a turn answering with resp as it is, for finish reasons,
+// usage or reasoning the other helpers don't set. The mock fills in the
+// ID, model and tool call IDs if they're empty.
+func Respond(resp *llm.ChatResponse) Turn {
+	return Turn{Response: resp}
+}
+
+// Fail is a turn where the call fails with err - an *llm.APIError, say,
+// to test retries and fallbacks.
+func Fail(err error) Turn {
+	return Turn{Err: err}
+}
+
+// Call is a tool call for CallTools. With no ID given, the mock numbers it
+// "call_<turn>_<index>", so tests can predict it.
+func Call(name, args string) llm.ToolCall {
+	return llm.ToolCall{Type: "function", Function: llm.FunctionCall{Name: name, Arguments: args}}
+}
+
+// MockProvider is a scripted llm.ChatProvider and
+// llm.StreamingChatProvider. It's safe for concurrent use, though turns
+// are handed out in the order calls arrive.
+type MockProvider struct {
+	t     testing.TB
+	model string
+
+	mu       sync.Mutex
+	turns    []Turn
+	requests []llm.ChatRequest
+}
+
+// NewMockProvider returns a mock answering with turns, in order. t may be
+// nil; then problems show up only as errors from CreateChat.
+func NewMockProvider(t testing.TB, turns ...Turn) *MockProvider {
+	m := &MockProvider{t: t, model: "mock", turns: turns}
+	if t != nil {
+		t.Cleanup(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if left := len(m.turns) - len(m.requests); left > 0 {
+				t.Errorf("llmtest: %d scripted turns never used, after %d calls", left, len(m.requests))
+			}
+		})
+	}
+	return m
+}
+
+// WithModel sets the name ModelName returns. The default is "mock".
+func (m *MockProvider) WithModel(name string) *MockProvider {
+	m.model = name
+	return m
+}
+
+// Add appends turns to the script.
+func (m *MockProvider) Add(turns ...Turn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.turns = append(m.turns, turns...)
+}
+
+// ModelName implements llm.ChatProvider.
+func (m *MockProvider) ModelName() string {
+	return m.model
+}
+
+// CreateChat implements llm.ChatProvider with the next turn.
+func (m *MockProvider) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	n := len(m.requests)
+	req.Messages = slices.Clone(req.Messages)
+	req.Tools = slices.Clone(req.Tools)
+	m.requests = append(m.requests, req)
+	var turn Turn
+	ok := n < len(m.turns)
+	if ok {
+		turn = m.turns[n]
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, m.fail(fmt.Errorf("llmtest: call %d, but only %d turns are scripted", n+1, n))
+	}
+	for _, check := range turn.Checks {
+		if err := check(req); err != nil {
+			return nil, m.fail(fmt.Errorf("llmtest: call %d: %w", n+1, err))
+		}
+	}
+	if turn.Err != nil {
+		return nil, turn.Err
+	}
+	if turn.Response == nil {
+		return nil, m.fail(fmt.Errorf("llmtest: call %d: turn has neither a response nor an error", n+1))
+	}
+	return m.finish(turn.Response, n), nil
+}
+
+// CreateChatStream implements llm.StreamingChatProvider: the next turn,
+// with its content and tool calls sent as deltas first.
+func (m *MockProvider) CreateChatStream(ctx context.Context, req llm.ChatRequest, onDelta llm.StreamHandler) (*llm.ChatResponse, error) {
+	resp, err := m.CreateChat(ctx, req)
+	if err != nil || onDelta == nil || len(resp.Choices) == 0 {
+		return resp, err
+	}
+	msg := resp.Choices[0].Message
+	if msg.Reasoning != "" {
+		onDelta(llm.StreamDelta{Reasoning: msg.Reasoning})
+	}
+	for _, w := range strings.SplitAfter(msg.Content, " ") {
+		if w != "" {
+			onDelta(llm.StreamDelta{Content: w})
+		}
+	}
+	for i, call := range msg.ToolCalls {
+		onDelta(llm.StreamDelta{ToolCallIndex: i, ToolCallID: call.ID, ToolName: call.Function.Name})
+		onDelta(llm.StreamDelta{ToolCallIndex: i, ArgumentsDelta: call.Function.Arguments})
+	}
+	usage := resp.Usage
+	onDelta(llm.StreamDelta{Usage: &usage})
+	return resp, nil
+}
+
+// finish copies resp, filling in what a real provider would.
+func (m *MockProvider) finish(resp *llm.ChatResponse, n int) *llm.ChatResponse {
+	out := *resp
+	if out.ID == "" {
+		out.ID = fmt.Sprintf("mock_%d", n+1)
+	}
+	if out.Object == "" {
+		out.Object = "chat.completion"
+	}
+	if out.Model == "" {
+		out.Model = m.model
+	}
+	out.Choices = slices.Clone(resp.Choices)
+	for i := range out.Choices {
+		c := &out.Choices[i]
+		c.Index = i
+		if c.Message.Role == "" {
+			c.Message.Role = "assistant"
+		}
+		c.Message.ToolCalls = slices.Clone(c.Message.ToolCalls)
+		for j := range c.Message.ToolCalls {
+			tc := &c.Message.ToolCalls[j]
+			if tc.ID == "" {
+				tc.ID = fmt.Sprintf("call_%d_%d", n+1, j)
+			}
+			if tc.Type == "" {
+				tc.Type = "function"
+			}
+			if tc.Function.Arguments == "" {
+				tc.Function.Arguments = "{}"
+			}
+		}
+	}
+	return &out
+}
+
+func (m *MockProvider) fail(err error) error {
+	if m.t != nil {
+		m.t.Helper()
+		m.t.Error(err)
+	}
+	return err
+}
+
+// Requests returns every request received so far, in order.
+func (m *MockProvider) Requests() []llm.ChatRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.requests)
+}
+
+// Calls returns how many requests have been received.
+func (m *MockProvider) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.requests)
+}
+
+// LastRequest returns the most recent request, or the zero ChatRequest if
+// there hasn't been one.
+func (m *MockProvider) LastRequest() llm.ChatRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.requests) == 0 {
+		return llm.ChatRequest{}
+	}
+	return m.requests[len(m.requests)-1]
+}
+
+// LastMessage checks that the request's last message has role and
+// contains text.
+func LastMessage(role, text string) func(llm.ChatRequest) error {
+	return func(req llm.ChatRequest) error {
+		if len(req.Messages) == 0 {
+			return fmt.Errorf("request has no messages, want a %s message containing %q", role, text)
+		}
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role != role || !strings.Contains(last.Content, text) {
+			return fmt.Errorf("last message is %s %q, want a %s message containing %q", last.Role, last.Content, role, text)
+		}
+		return nil
+	}
+}
+
+// HasMessage checks that some message in the request has role and
+// contains text - the system prompt, say.
+func HasMessage(role, text string) func(llm.ChatRequest) error {
+	return func(req llm.ChatRequest) error {
+		for _, msg := range req.Messages {
+			if msg.Role == role && strings.Contains(msg.Content, text) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no %s message contains %q", role, text)
+	}
+}
+
+// HasTools checks that the request offers every one of the named tools.
+func HasTools(names ...string) func(llm.ChatRequest) error {
+	return func(req llm.ChatRequest) error {
+		var missing []string
+		for _, name := range names {
+			if !slices.ContainsFunc(req.Tools, func(t llm.Tool) bool { return t.Function.Name == name }) {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("request doesn't offer tools %s", strings.Join(missing, ", "))
+		}
+		return nil
+	}
+}
@@ -1,6 +1,9 @@
 package llm
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // NewSystemMessage creates a system message to set up the LLM's behavior.
 // This is typically the first message in the conversation and sets the context
@@ -60,6 +63,29 @@ func NewToolResult(toolCallID string, name string, output string) Message {
 	}
 }
 
+// NewToolResultParts is NewToolResult for rich results: text plus images
+// or files. Content holds the text parts joined, for providers and code
+// that only deal in strings.
+//
+// Anthropic and Gemini put the images in the tool result itself. OpenAI
+// only accepts text there, so its client follows the tool message with a
+// user message carrying the images.
+func NewToolResultParts(toolCallID string, name string, parts []ContentPart) Message {
+	var text []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			text = append(text, p.Text)
+		}
+	}
+	return Message{
+		Role:       "tool",
+		ToolCallID: toolCallID,
+		Name:       name,
+		Content:    strings.Join(text, "\n"),
+		Parts:      parts,
+	}
+}
+
 // NewToolError creates a message indicating a tool failed to execute.
 // Use this when Execute returns an error - it formats the error nicely
 // and tells the LLM to fix its arguments.
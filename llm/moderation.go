@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"context"
+	"sort"
+)
+
+// Moderator screens text for harmful content - harassment, hate, self-harm,
+// sexual content, violence and the like.
+//
+// The OpenAI client implements it with the /moderations endpoint; create
+// one with a moderation model:
+//
+//	mod := openai.New(os.Getenv("OPENAI_API_KEY"), "omni-moderation-latest")
+//	results, err := mod.Moderate(ctx, []string{userMessage})
+type Moderator interface {
+	// Moderate returns one result per text, in the same order.
+	Moderate(ctx context.Context, texts []string) ([]ModerationResult, error)
+}
+
+// ModerationResult is the verdict on one text. Category names are the
+// provider's, such as "harassment" or "self-harm/intent".
+type ModerationResult struct {
+	Flagged    bool               `json:"flagged"`    // the provider considers the text harmful
+	Categories map[string]bool    `json:"categories"` // which categories it flagged
+	Scores     map[string]float64 `json:"scores"`     // confidence per category, from 0 to 1
+}
+
+// FlaggedCategories returns the names of the flagged categories, sorted.
+func (r ModerationResult) FlaggedCategories() []string {
+	var out []string
+	for c, flagged := range r.Categories {
+		if flagged {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
 	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/devkit"
+	"go-agent-sdk/llm/internal/bufpool"
+	"go-agent-sdk/llm/schema"
 )
 
 // Base URLs for known OpenAI-compatible services.
@@ -44,10 +46,14 @@ const (
 // Use the functional options (WithBaseURL, WithHTTPClient) to configure
 // the client for different endpoints.
 type Client struct {
-	apiKey     string
-	model      string
-	baseURL    string
-	httpClient *http.Client
+	apiKey      string
+	model       string
+	baseURL     string
+	httpClient  *http.Client
+	newID       llm.IDGenerator           // fills in tool call IDs the server left empty
+	normalizeID llm.IDNormalizer          // rewrites history IDs the server would reject. nil means send as-is.
+	dimensions  int                       // embedding size to ask for. 0 means the model's default.
+	transport   []llm.TransportMiddleware // wraps httpClient's transport, see WithTransportMiddleware
 }
 
 // Option is a function that configures a Client.
@@ -92,6 +98,47 @@ func WithHTTPClient(hc *http.Client) Option {
 	}
 }
 
+// WithTransportMiddleware wraps the transport requests go through in mw,
+// the first outermost - to add tracing, metrics or retries from a library
+// you already use, on top of the default client or one from WithHTTPClient:
+//
+//	provider := openai.New(key, "gpt-4o",
+//	    openai.WithTransportMiddleware(func(next http.RoundTripper) http.RoundTripper {
+//	        return otelhttp.NewTransport(next)
+//	    }),
+//	)
+//
+// It can be given more than once and in any order with WithHTTPClient;
+// the middleware is applied once every option has been.
+func WithTransportMiddleware(mw ...llm.TransportMiddleware) Option {
+	return func(c *Client) {
+		c.transport = append(c.transport, mw...)
+	}
+}
+
+// WithIDGenerator sets how missing tool call IDs are filled in.
+// OpenAI always sends IDs, but some compatible servers (older Ollama builds,
+// some vLLM setups) return tool calls with an empty "id", which breaks the
+// tool_call_id linkage. Those get an ID from this generator.
+// The default is llm.NewCallID.
+func WithIDGenerator(gen llm.IDGenerator) Option {
+	return func(c *Client) {
+		c.newID = gen
+	}
+}
+
+// WithToolCallIDNormalizer rewrites tool call IDs in the history before each
+// request. Use this when replaying a conversation recorded with another
+// provider against an endpoint with strict ID rules.
+//
+// Clients pointed at openai.MistralBaseURL get llm.MistralToolCallID
+// automatically, since Mistral rejects anything that isn't 9 alphanumerics.
+func WithToolCallIDNormalizer(n llm.IDNormalizer) Option {
+	return func(c *Client) {
+		c.normalizeID = n
+	}
+}
+
 // New creates an OpenAI-compatible provider.
 // By default it points at api.openai.com. Use WithBaseURL to change the endpoint.
 //
@@ -115,13 +162,40 @@ func New(apiKey string, model string, opts ...Option) *Client {
 		model:      model,
 		baseURL:    DefaultBaseURL,
 		httpClient: &http.Client{},
+		newID:      llm.NewCallID,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.httpClient = llm.WrapHTTPClient(c.httpClient, c.transport...)
+	if c.normalizeID == nil && c.baseURL == MistralBaseURL {
+		c.normalizeID = llm.MistralToolCallID
+	}
 	return c
 }
 
+// NormalizeToolCallID implements llm.ToolCallIDNormalizer. IDs are returned
+// unchanged unless a normalizer was configured (or implied by the base URL).
+func (c *Client) NormalizeToolCallID(id string) string {
+	if c.normalizeID == nil {
+		return id
+	}
+	return c.normalizeID(id)
+}
+
+// Warmup implements llm.Warmer: it opens a connection to the endpoint by
+// listing models, which costs no tokens.
+func (c *Client) Warmup(ctx context.Context) error {
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if err := llm.WarmConnection(ctx, c.httpClient, c.baseURL+"/models", header); err != nil {
+		return fmt.Errorf("openai: %w", err)
+	}
+	return nil
+}
+
 // NewOpenRouter is a convenience constructor for OpenRouter.
 // Equivalent to New(apiKey, model, WithBaseURL(OpenRouterBaseURL)).
 func NewOpenRouter(apiKey string, model string, opts ...Option) *Client {
@@ -147,13 +221,60 @@ func (c *Client) ModelName() string {
 // No field translation is needed — this is the advantage of using OpenAI's
 // format as the common protocol.
 func (c *Client) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
-	// basic marshal with error handling
-	jsonData, err := json.Marshal(req)
+	// History may have been recorded with a provider that uses different
+	// tool call ID shapes. This copies the messages, it doesn't touch the caller's.
+	req.Messages = llm.NormalizeToolCallIDs(req.Messages, c.normalizeID)
+
+	resp, err := c.post(ctx, "/chat/completions", c.wireRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := bufpool.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to read response body: %w", err)
+	}
+	defer bufpool.Put(body)
+
+	var chatResp llm.ChatResponse
+	if err := json.Unmarshal(body.Bytes(), &chatResp); err != nil {
+		return nil, fmt.Errorf("openai: failed to decode response: %w", err)
+	}
+	if req.Reasoning != nil {
+		readReasoningContent(body.Bytes(), &chatResp)
+	}
+
+	c.fillToolCallIDs(ctx, &chatResp)
+	return &chatResp, nil
+}
+
+// fillToolCallIDs gives tool calls without an ID one. Some compatible
+// servers leave them empty, and tool results can't be linked back to their
+// calls without them.
+func (c *Client) fillToolCallIDs(ctx context.Context, resp *llm.ChatResponse) {
+	for i := range resp.Choices {
+		calls := resp.Choices[i].Message.ToolCalls
+		for j := range calls {
+			if calls[j].ID == "" {
+				calls[j].ID = c.newID(ctx)
+			}
+		}
+	}
+}
+
+// post sends body to path (/chat/completions, /embeddings). On a non-200
+// status it reads the body into an llm.APIError; otherwise the caller must
+// close the response.
+func (c *Client) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	// Marshal into a pooled buffer - at high QPS the request body is the
+	// biggest allocation per call.
+	reqBuf, err := bufpool.EncodeJSON(body)
 	if err != nil {
 		return nil, fmt.Errorf("openai: failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(reqBuf.Bytes()))
 	if err != nil {
 		return nil, fmt.Errorf("openai: failed to create HTTP request: %w", err)
 	}
@@ -167,23 +288,149 @@ func (c *Client) CreateChat(ctx context.Context, req llm.ChatRequest) (*llm.Chat
 	if err != nil {
 		return nil, fmt.Errorf("openai: HTTP request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	resp.Body = bufpool.ReleaseOnClose(resp.Body, reqBuf)
 
-	// Read the full body so we can include it in error messages.
-	// The old client discarded error bodies, which made debugging painful.
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("openai: failed to read response body: %w", err)
+	// The full body goes into the error. The old client discarded error
+	// bodies, which made debugging painful.
+	if err := devkit.CheckResponse("openai", resp); err != nil {
+		return nil, err
 	}
+	return resp, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("openai: unexpected status %d: %s", resp.StatusCode, string(body))
+// wireRequest is the request body. It's llm.ChatRequest as-is, except for
+// tool schemas, which are converted to OpenAI's dialect, and reasoning,
+// which compatible servers spell differently: OpenAI takes a top-level
+// reasoning_effort, OpenRouter a reasoning object. Fields declared here
+// shadow the embedded ones with the same JSON name.
+type wireRequest struct {
+	llm.ChatRequest
+	Reasoning       *openRouterReasoning `json:"reasoning,omitempty"`
+	ReasoningEffort string               `json:"reasoning_effort,omitempty"`
+	StreamOptions   *streamOptions       `json:"stream_options,omitempty"`
+}
+
+// openRouterReasoning takes either an effort or a token budget, not both.
+type openRouterReasoning struct {
+	Effort    string `json:"effort,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+}
+
+func (c *Client) wireRequest(req llm.ChatRequest) wireRequest {
+	w := wireRequest{ChatRequest: req}
+	if r := req.Reasoning; r != nil {
+		if c.baseURL == OpenRouterBaseURL {
+			w.Reasoning = &openRouterReasoning{Effort: r.Effort, MaxTokens: r.BudgetTokens}
+			if r.BudgetTokens > 0 {
+				w.Reasoning.Effort = ""
+			}
+		} else {
+			w.ReasoningEffort = r.EffortLevel()
+		}
 	}
 
-	var chatResp llm.ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return nil, fmt.Errorf("openai: failed to decode response: %w", err)
+	// Tool schemas go out in OpenAI's dialect; strict tools need the
+	// stricter one or the API rejects them.
+	if len(req.Tools) > 0 {
+		w.Tools = make([]llm.Tool, len(req.Tools))
+		for i, t := range req.Tools {
+			if t.Function.Strict {
+				t.Function.Parameters = schema.OpenAIStrict(t.Function.Parameters)
+			} else {
+				t.Function.Parameters = schema.OpenAI(t.Function.Parameters)
+			}
+			w.Tools[i] = t
+		}
 	}
 
-	return &chatResp, nil
+	// Other dialects' tool choices ("any") get OpenAI's spelling.
+	if c, err := llm.ParseToolChoice(req.ToolChoice); err == nil && c != nil {
+		w.ToolChoice = c
+	}
+
+	// Same for a strict response schema.
+	if rf := req.ResponseFormat; rf != nil && rf.JSONSchema != nil && rf.JSONSchema.Strict {
+		js := *rf.JSONSchema
+		js.Schema = schema.OpenAIStrict(js.Schema)
+		w.ResponseFormat = &llm.ResponseFormat{Type: rf.Type, JSONSchema: &js}
+	}
+
+	if needsRewrite(req.Messages) {
+		w.Messages = wireMessages(req.Messages)
+	}
+	return w
+}
+
+// needsRewrite reports whether the history has anything the API won't
+// take as-is, so the common case sends req.Messages without copying.
+func needsRewrite(msgs []llm.Message) bool {
+	for _, m := range msgs {
+		if m.Reasoning != "" || m.ReasoningSignature != "" || len(m.Metadata) > 0 || (m.Role == "tool" && len(m.Parts) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// wireMessages copies the history into a form the API accepts:
+//
+//   - Reasoning we got back is for display; the API doesn't accept it in
+//     messages, so it's dropped. So is Metadata, which is only ours.
+//   - Tool messages can only hold text. Images and files a tool returned
+//     go in a user message right after the batch of tool results (the
+//     tool messages must directly follow the assistant's tool calls).
+func wireMessages(msgs []llm.Message) []llm.Message {
+	out := make([]llm.Message, 0, len(msgs)+1)
+	var pending []llm.ContentPart
+	flush := func() {
+		if len(pending) > 0 {
+			out = append(out, llm.Message{Role: "user", Parts: pending})
+			pending = nil
+		}
+	}
+	for _, m := range msgs {
+		m.Reasoning = ""
+		m.ReasoningSignature = ""
+		m.Metadata = nil
+		if m.Role != "tool" {
+			flush()
+			out = append(out, m)
+			continue
+		}
+		labelled := false
+		for _, p := range m.Parts {
+			if p.Type == "text" {
+				continue
+			}
+			if !labelled {
+				pending = append(pending, llm.NewTextPart("Attachments returned by tool "+m.Name+":"))
+				labelled = true
+			}
+			pending = append(pending, p)
+		}
+		m.Parts = nil
+		out = append(out, m)
+	}
+	flush()
+	return out
+}
+
+// readReasoningContent picks up reasoning_content, which DeepSeek, vLLM and
+// others use instead of "reasoning" for the model's thinking.
+func readReasoningContent(body []byte, resp *llm.ChatResponse) {
+	var extra struct {
+		Choices []struct {
+			Message struct {
+				ReasoningContent string `json:"reasoning_content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if json.Unmarshal(body, &extra) != nil {
+		return
+	}
+	for i, ch := range extra.Choices {
+		if i < len(resp.Choices) && resp.Choices[i].Message.Reasoning == "" {
+			resp.Choices[i].Message.Reasoning = ch.Message.ReasoningContent
+		}
+	}
 }
@@ -0,0 +1,90 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-agent-sdk/llm/internal/bufpool"
+)
+
+// maxEmbeddingInputs is how many texts OpenAI accepts in one /embeddings
+// call.
+const maxEmbeddingInputs = 2048
+
+// WithDimensions asks for embeddings of n dimensions instead of the
+// model's default. Only newer models (text-embedding-3 and later) support
+// it; shorter vectors are cheaper to store and search, for a little
+// accuracy.
+func WithDimensions(n int) Option {
+	return func(c *Client) {
+		c.dimensions = n
+	}
+}
+
+type embeddingsRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// CreateEmbeddings implements llm.EmbeddingsProvider with the /embeddings
+// endpoint, which OpenAI-compatible servers (Ollama, vLLM, Together,
+// Mistral, ...) mostly have too. The client's model has to be an
+// embedding model.
+func (c *Client) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingInputs {
+		batch := texts[start:min(start+maxEmbeddingInputs, len(texts))]
+		vectors, err := c.embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vectors...)
+	}
+	return out, nil
+}
+
+func (c *Client) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := c.post(ctx, "/embeddings", embeddingsRequest{
+		Model:          c.model,
+		Input:          texts,
+		Dimensions:     c.dimensions,
+		EncodingFormat: "float",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := bufpool.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to read response body: %w", err)
+	}
+	defer bufpool.Put(body)
+
+	var native embeddingsResponse
+	if err := json.Unmarshal(body.Bytes(), &native); err != nil {
+		return nil, fmt.Errorf("openai: failed to decode response: %w", err)
+	}
+	if len(native.Data) != len(texts) {
+		return nil, fmt.Errorf("openai: got %d embeddings for %d texts", len(native.Data), len(texts))
+	}
+	// The data comes with its index; don't rely on the order.
+	vectors := make([][]float32, len(texts))
+	for _, d := range native.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("openai: embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,53 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/internal/bufpool"
+)
+
+type moderationRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// Moderate implements llm.Moderator with the /moderations endpoint. The
+// client's model has to be a moderation model, such as
+// "omni-moderation-latest". OpenAI doesn't charge for it.
+func (c *Client) Moderate(ctx context.Context, texts []string) ([]llm.ModerationResult, error) {
+	resp, err := c.post(ctx, "/moderations", moderationRequest{Model: c.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := bufpool.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("openai: failed to read response body: %w", err)
+	}
+	defer bufpool.Put(body)
+
+	var native moderationResponse
+	if err := json.Unmarshal(body.Bytes(), &native); err != nil {
+		return nil, fmt.Errorf("openai: failed to decode response: %w", err)
+	}
+	if len(native.Results) != len(texts) {
+		return nil, fmt.Errorf("openai: got %d moderation results for %d texts", len(native.Results), len(texts))
+	}
+	results := make([]llm.ModerationResult, len(texts))
+	for i, r := range native.Results {
+		results[i] = llm.ModerationResult{Flagged: r.Flagged, Categories: r.Categories, Scores: r.CategoryScores}
+	}
+	return results, nil
+}
@@ -0,0 +1,187 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/internal/sse"
+	"strings"
+)
+
+// Streaming is the same /chat/completions call with "stream": true. The
+// response is Server-Sent Events, one chat.completion.chunk per event, then
+// a literal "data: [DONE]". Each chunk carries a delta per choice:
+//
+//	{"choices": [{"index": 0, "delta": {"content": "Hel"}}]}
+//	{"choices": [{"index": 0, "delta": {"tool_calls": [{"index": 0, "id": "call_1",
+//	    "function": {"name": "get_weather", "arguments": ""}}]}}]}
+//	{"choices": [{"index": 0, "delta": {"tool_calls": [{"index": 0,
+//	    "function": {"arguments": "{\"city\":"}}]}}]}
+//
+// Token usage is only sent when asked for with stream_options.include_usage,
+// in one extra chunk at the end with an empty choices array. Some
+// compatible servers ignore the option and send nothing; Groq puts the
+// counts in x_groq.usage instead.
+
+// streamOptions asks for the final usage chunk.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// streamChunk is one chat.completion.chunk.
+type streamChunk struct {
+	ID                string `json:"id"`
+	Created           int64  `json:"created"`
+	Model             string `json:"model"`
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	Choices           []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role             string `json:"role,omitempty"`
+			Content          string `json:"content,omitempty"`
+			Reasoning        string `json:"reasoning,omitempty"`         // OpenRouter
+			ReasoningContent string `json:"reasoning_content,omitempty"` // DeepSeek, vLLM
+			ToolCalls        []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id,omitempty"`
+				Function struct {
+					Name      string `json:"name,omitempty"`
+					Arguments string `json:"arguments,omitempty"`
+				} `json:"function"`
+			} `json:"tool_calls,omitempty"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *llm.Usage `json:"usage,omitempty"`
+	XGroq *struct {
+		Usage *llm.Usage `json:"usage,omitempty"`
+	} `json:"x_groq,omitempty"`
+}
+
+// CreateChatStream sends a streaming chat completion request. It implements
+// llm.StreamingChatProvider: onDelta sees text, reasoning and tool call
+// fragments as they arrive, then the token counts, and the assembled
+// response is returned at the end.
+func (c *Client) CreateChatStream(ctx context.Context, req llm.ChatRequest, onDelta llm.StreamHandler) (*llm.ChatResponse, error) {
+	req.Messages = llm.NormalizeToolCallIDs(req.Messages, c.normalizeID)
+	req.Stream = true
+	w := c.wireRequest(req)
+	w.StreamOptions = &streamOptions{IncludeUsage: true}
+
+	resp, err := c.post(ctx, "/chat/completions", w)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out := &llm.ChatResponse{Object: "chat.completion"}
+	var (
+		choices   []llm.Choice
+		content   []*strings.Builder   // per choice
+		reasoning []*strings.Builder   // per choice
+		args      [][]*strings.Builder // per choice, per tool call
+	)
+	choice := func(i int) int {
+		for len(choices) <= i {
+			choices = append(choices, llm.Choice{Index: len(choices), Message: llm.Message{Role: "assistant"}})
+			content = append(content, &strings.Builder{})
+			reasoning = append(reasoning, &strings.Builder{})
+			args = append(args, nil)
+		}
+		return i
+	}
+
+	err = sse.Read(resp.Body, "openai", func(_, data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("openai: failed to decode stream chunk: %w", err)
+		}
+		if out.ID == "" {
+			out.ID, out.Created, out.Model = chunk.ID, chunk.Created, chunk.Model
+			out.SystemFingerprint = chunk.SystemFingerprint
+		}
+
+		for _, ch := range chunk.Choices {
+			i := choice(ch.Index)
+			d := ch.Delta
+			if d.Content != "" {
+				content[i].WriteString(d.Content)
+				if onDelta != nil && i == 0 {
+					onDelta(llm.StreamDelta{Content: d.Content})
+				}
+			}
+			if r := d.Reasoning + d.ReasoningContent; r != "" {
+				reasoning[i].WriteString(r)
+				if onDelta != nil && i == 0 {
+					onDelta(llm.StreamDelta{Reasoning: r})
+				}
+			}
+			for _, tc := range d.ToolCalls {
+				calls := choices[i].Message.ToolCalls
+				for len(calls) <= tc.Index {
+					calls = append(calls, llm.ToolCall{Type: "function"})
+					args[i] = append(args[i], &strings.Builder{})
+				}
+				// The ID comes with a call's first chunk. Servers that never
+				// send one get an ID now, so the deltas carry it too.
+				id := tc.ID
+				if id == "" && calls[tc.Index].ID == "" {
+					id = c.newID(ctx)
+				}
+				if id != "" {
+					calls[tc.Index].ID = id
+				}
+				if tc.Function.Name != "" {
+					calls[tc.Index].Function.Name += tc.Function.Name
+				}
+				args[i][tc.Index].WriteString(tc.Function.Arguments)
+				choices[i].Message.ToolCalls = calls
+
+				if onDelta != nil && i == 0 {
+					onDelta(llm.StreamDelta{
+						ToolCallIndex:  tc.Index,
+						ToolCallID:     id,
+						ToolName:       tc.Function.Name,
+						ArgumentsDelta: tc.Function.Arguments,
+					})
+				}
+			}
+			if ch.FinishReason != nil {
+				choices[i].FinishReason = *ch.FinishReason
+			}
+		}
+
+		usage := chunk.Usage
+		if usage == nil && chunk.XGroq != nil {
+			usage = chunk.XGroq.Usage
+		}
+		if usage != nil {
+			out.Usage = *usage
+			if onDelta != nil {
+				u := *usage
+				onDelta(llm.StreamDelta{Usage: &u})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range choices {
+		choices[i].Message.Content = content[i].String()
+		choices[i].Message.Reasoning = reasoning[i].String()
+		for j := range choices[i].Message.ToolCalls {
+			choices[i].Message.ToolCalls[j].Function.Arguments = args[i][j].String()
+		}
+	}
+	if choices == nil {
+		choices = []llm.Choice{}
+	}
+	out.Choices = choices
+	return out, nil
+}
@@ -30,3 +30,55 @@ type ChatProvider interface {
 	// providers don't need to worry about it — the agent handles it.
 	ModelName() string
 }
+
+// StreamDelta is one incremental piece of a streamed response.
+// Text arrives in Content. Tool calls arrive in pieces too: the first delta
+// for a call carries its ToolCallID and ToolName, later ones carry chunks
+// of the JSON arguments in ArgumentsDelta. ToolCallIndex says which call
+// (0-based, in the order the model emitted them) a piece belongs to.
+//
+// The arguments are only valid JSON once the stream is complete - use the
+// ChatResponse returned by CreateChatStream for anything that needs them.
+//
+// When reasoning is enabled, the model's thinking arrives in Reasoning
+// before the answer starts.
+//
+// Usage is set on deltas that carry token counts - whenever the provider
+// reports them, which differs: Anthropic sends input tokens first and
+// output tokens at the end, Gemini updates counts on every chunk, OpenAI
+// only sends them in the last chunk. The counts are cumulative for the
+// call so far, not increments. The returned ChatResponse has the final
+// numbers either way.
+type StreamDelta struct {
+	Content        string `json:"content,omitempty"`
+	Reasoning      string `json:"reasoning,omitempty"`
+	ToolCallIndex  int    `json:"tool_call_index,omitempty"`
+	ToolCallID     string `json:"tool_call_id,omitempty"`
+	ToolName       string `json:"tool_name,omitempty"`
+	ArgumentsDelta string `json:"arguments_delta,omitempty"`
+	Usage          *Usage `json:"usage,omitempty"`
+}
+
+// StreamHandler receives deltas as they arrive. It's called from the
+// goroutine that called CreateChatStream, one delta at a time.
+type StreamHandler func(delta StreamDelta)
+
+// StreamingChatProvider is an optional interface for providers that can
+// stream tokens as they are generated.
+//
+// CreateChatStream calls onDelta for every increment and, once the stream
+// ends, returns the fully assembled response - exactly what CreateChat would
+// have returned. That's what lets the agent loop treat streamed and blocking
+// calls the same way, including tool calls.
+type StreamingChatProvider interface {
+	ChatProvider
+	CreateChatStream(ctx context.Context, req ChatRequest, onDelta StreamHandler) (*ChatResponse, error)
+}
+
+// ToolCallIDNormalizer is an optional interface for providers with rules about
+// what a tool call ID may look like. The agent uses it to rewrite the stored
+// history when you switch providers mid-conversation, so the new provider
+// never sees IDs it would reject.
+type ToolCallIDNormalizer interface {
+	NormalizeToolCallID(id string) string
+}
@@ -0,0 +1,346 @@
+// Package providerdiff sends one ChatRequest to two providers and lists
+// where their responses differ in ways the agent cares about: finish
+// reasons, tool calls and their arguments, whether there's an answer,
+// usage. It's for "works on OpenAI, breaks on Gemini" reports - run the
+// request that breaks through both, and the mapping bug is usually in the
+// first line of the diff:
+//
+//	r, err := providerdiff.Compare(ctx, req, openai.New(okey, "gpt-4o"), gemini.New(gkey, "gemini-2.5-flash"))
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Print(r)
+//
+// Wording is expected to differ between models, so answers are compared
+// by shape - there or not - unless CompareContent asks for the text.
+package providerdiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/devkit"
+)
+
+// Side is one provider's half of a comparison.
+type Side struct {
+	Model    string // the provider's ModelName
+	Response *llm.ChatResponse
+	Err      error
+	Latency  time.Duration
+}
+
+// Difference is one place the two responses disagree. Path names it the
+// way the JSON does, "choices[0].message.tool_calls[1].function.arguments".
+type Difference struct {
+	Path string
+	A    string
+	B    string
+	Note string // why it matters, when that isn't obvious
+}
+
+func (d Difference) String() string {
+	s := fmt.Sprintf("%s: %s != %s", d.Path, d.A, d.B)
+	if d.Note != "" {
+		s += " (" + d.Note + ")"
+	}
+	return s
+}
+
+// Report is the outcome of Compare.
+type Report struct {
+	A, B        Side
+	Differences []Difference
+}
+
+// Equal reports whether no differences were found.
+func (r *Report) Equal() bool {
+	return len(r.Differences) == 0
+}
+
+// String lays the report out for a terminal or a bug report.
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, s := range []struct {
+		label string
+		side  Side
+	}{{"A", r.A}, {"B", r.B}} {
+		fmt.Fprintf(&b, "%s: %s in %s", s.label, s.side.Model, s.side.Latency.Round(time.Millisecond))
+		if s.side.Err != nil {
+			fmt.Fprintf(&b, ", error: %v", s.side.Err)
+		}
+		b.WriteString("\n")
+	}
+	if r.Equal() {
+		b.WriteString("no differences\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d differences:\n", len(r.Differences))
+	for _, d := range r.Differences {
+		fmt.Fprintf(&b, "  %s\n", d)
+	}
+	return b.String()
+}
+
+// Option configures Compare.
+type Option func(*config)
+
+type config struct {
+	content    bool
+	sequential bool
+}
+
+// CompareContent compares answers by their text, ignoring differences in
+// whitespace, rather than only by whether there is one. Useful for two
+// routes to the same model, at temperature 0.
+func CompareContent() Option {
+	return func(c *config) {
+		c.content = true
+	}
+}
+
+// Sequential calls b only once a has answered, rather than both at once -
+// for providers sharing a rate limit, or a local server that takes one
+// request at a time.
+func Sequential() Option {
+	return func(c *config) {
+		c.sequential = true
+	}
+}
+
+// Compare sends req to a and b and compares what comes back. Each gets
+// req with Model set to its own ModelName, as the agent would send it.
+// A provider's error is recorded on its Side and compared like the rest;
+// Compare itself fails only when ctx is done.
+func Compare(ctx context.Context, req llm.ChatRequest, a, b llm.ChatProvider, opts ...Option) (*Report, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	r := &Report{}
+	call := func(p llm.ChatProvider, s *Side) {
+		rq := req
+		s.Model = p.ModelName()
+		rq.Model = s.Model
+		start := time.Now()
+		s.Response, s.Err = p.CreateChat(ctx, rq)
+		s.Latency = time.Since(start)
+	}
+	if cfg.sequential {
+		call(a, &r.A)
+		call(b, &r.B)
+	} else {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); call(a, &r.A) }()
+		go func() { defer wg.Done(); call(b, &r.B) }()
+		wg.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.Differences = Diff(r.A.Response, r.B.Response, opts...)
+	if (r.A.Err == nil) != (r.B.Err == nil) {
+		r.Differences = append([]Difference{{Path: "error", A: errText(r.A.Err), B: errText(r.B.Err)}}, r.Differences...)
+	}
+	return r, nil
+}
+
+func errText(err error) string {
+	if err == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%q", err.Error())
+}
+
+// Diff compares two responses already in hand - from a log, say, or a
+// recorded fixture. Either may be nil.
+func Diff(a, b *llm.ChatResponse, opts ...Option) []Difference {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return nil
+		}
+		return []Difference{{Path: "response", A: present(a != nil), B: present(b != nil)}}
+	}
+	var d differ
+	d.cfg = cfg
+	if len(a.Choices) != len(b.Choices) {
+		d.add("len(choices)", len(a.Choices), len(b.Choices), "the agent reads choices[0]")
+	}
+	for i := range min(len(a.Choices), len(b.Choices)) {
+		d.choice(fmt.Sprintf("choices[%d]", i), a.Choices[i], b.Choices[i])
+	}
+	d.usage(a.Usage, b.Usage)
+	return d.out
+}
+
+type differ struct {
+	cfg config
+	out []Difference
+}
+
+func (d *differ) add(path string, a, b any, note string) {
+	d.out = append(d.out, Difference{Path: path, A: fmt.Sprint(a), B: fmt.Sprint(b), Note: note})
+}
+
+func (d *differ) choice(path string, a, b llm.Choice) {
+	// A finish reason that disagrees with the tool calls is worth flagging
+	// even when both sides agree on it.
+	var notes []string
+	for _, s := range []struct {
+		side   string
+		reason string
+		calls  int
+	}{{"A", a.FinishReason, len(a.Message.ToolCalls)}, {"B", b.FinishReason, len(b.Message.ToolCalls)}} {
+		if (devkit.NormalizeFinishReason(s.reason) == devkit.FinishToolCalls) != (s.calls > 0) {
+			notes = append(notes, fmt.Sprintf("%s says %q with %d tool calls", s.side, s.reason, s.calls))
+		}
+	}
+	if devkit.NormalizeFinishReason(a.FinishReason) != devkit.NormalizeFinishReason(b.FinishReason) || len(notes) > 0 {
+		d.add(path+".finish_reason", quote(a.FinishReason), quote(b.FinishReason), strings.Join(notes, "; "))
+	}
+
+	m := path + ".message"
+	if a.Message.Role != b.Message.Role {
+		d.add(m+".role", quote(a.Message.Role), quote(b.Message.Role), "")
+	}
+	ca, cb := strings.Join(strings.Fields(a.Message.Content), " "), strings.Join(strings.Fields(b.Message.Content), " ")
+	if d.cfg.content {
+		if ca != cb {
+			d.add(m+".content", quote(clip(ca)), quote(clip(cb)), "")
+		}
+	} else if (ca == "") != (cb == "") {
+		d.add(m+".content", present(ca != ""), present(cb != ""), "")
+	}
+	if (a.Message.Reasoning == "") != (b.Message.Reasoning == "") {
+		d.add(m+".reasoning", present(a.Message.Reasoning != ""), present(b.Message.Reasoning != ""), "")
+	}
+	d.toolCalls(m+".tool_calls", a.Message.ToolCalls, b.Message.ToolCalls)
+}
+
+// toolCalls pairs the calls up by name and arguments, since parallel calls
+// can come back in any order, then compares them pair by pair.
+func (d *differ) toolCalls(path string, a, b []llm.ToolCall) {
+	if len(a) != len(b) {
+		d.add("len("+path+")", len(a), len(b), "")
+	}
+	a, b = sortCalls(a), sortCalls(b)
+	for i := range min(len(a), len(b)) {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		ta, tb := a[i], b[i]
+		if (ta.ID == "") != (tb.ID == "") {
+			d.add(p+".id", present(ta.ID != ""), present(tb.ID != ""), "the tool result is sent back with this ID")
+		}
+		if ta.Type != tb.Type {
+			d.add(p+".type", quote(ta.Type), quote(tb.Type), "")
+		}
+		if ta.Function.Name != tb.Function.Name {
+			d.add(p+".function.name", quote(ta.Function.Name), quote(tb.Function.Name), "")
+		}
+		va, errA := args(ta.Function.Arguments)
+		vb, errB := args(tb.Function.Arguments)
+		switch {
+		case (errA == nil) != (errB == nil):
+			d.add(p+".function.arguments", validity(errA), validity(errB), "")
+		case errA == nil && !reflect.DeepEqual(va, vb):
+			d.add(p+".function.arguments", clip(canonical(va)), clip(canonical(vb)), "")
+		}
+	}
+	for i := len(b); i < len(a); i++ {
+		d.add(fmt.Sprintf("%s[%d]", path, i), callKey(a[i]), "missing", "")
+	}
+	for i := len(a); i < len(b); i++ {
+		d.add(fmt.Sprintf("%s[%d]", path, i), "missing", callKey(b[i]), "")
+	}
+}
+
+func (d *differ) usage(a, b llm.Usage) {
+	for _, f := range []struct {
+		name string
+		a, b int
+	}{{"prompt_tokens", a.PromptTokens, b.PromptTokens}, {"completion_tokens", a.CompletionTokens, b.CompletionTokens}, {"total_tokens", a.TotalTokens, b.TotalTokens}} {
+		if (f.a == 0) != (f.b == 0) {
+			d.add("usage."+f.name, f.a, f.b, "reported by one side only")
+		}
+	}
+	// Token counts differ between tokenizers; a total that isn't the sum
+	// of its parts is a mapping bug.
+	for _, s := range []struct {
+		side string
+		u    llm.Usage
+	}{{"A", a}, {"B", b}} {
+		if s.u.TotalTokens != 0 && s.u.TotalTokens < s.u.PromptTokens+s.u.CompletionTokens {
+			d.out = append(d.out, Difference{Path: "usage.total_tokens", A: fmt.Sprint(a.TotalTokens), B: fmt.Sprint(b.TotalTokens),
+				Note: fmt.Sprintf("%s's total is less than prompt plus completion", s.side)})
+		}
+	}
+}
+
+func sortCalls(calls []llm.ToolCall) []llm.ToolCall {
+	out := append([]llm.ToolCall(nil), calls...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return callKey(out[i]) < callKey(out[j])
+	})
+	return out
+}
+
+// callKey is a tool call as name(arguments), with the arguments canonical.
+func callKey(c llm.ToolCall) string {
+	if v, err := args(c.Function.Arguments); err == nil {
+		return c.Function.Name + "(" + canonical(v) + ")"
+	}
+	return c.Function.Name + "(" + c.Function.Arguments + ")"
+}
+
+// args decodes tool call arguments. Empty arguments count as {}, which is
+// how several APIs send a call with none.
+func args(s string) (any, error) {
+	if strings.TrimSpace(s) == "" {
+		return map[string]any{}, nil
+	}
+	var v any
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func canonical(v any) string {
+	data, _ := json.Marshal(v) // sorts object keys
+	return string(data)
+}
+
+func validity(err error) string {
+	if err != nil {
+		return "invalid JSON"
+	}
+	return "valid JSON"
+}
+
+func present(ok bool) string {
+	if ok {
+		return "present"
+	}
+	return "absent"
+}
+
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+func clip(s string) string {
+	const max = 200
+	if r := []rune(s); len(r) > max {
+		return string(r[:max]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,282 @@
+// Package providertest checks that an llm.ChatProvider behaves the way the
+// agent expects: answers come back as one assistant choice with a finish
+// reason the loop understands, tool calls carry IDs and JSON arguments,
+// tool results are accepted, streaming adds up to the final response, and
+// cancellation stops the call.
+//
+// Call Run from a test in your provider's package:
+//
+//	func TestConformance(t *testing.T) {
+//	    key := os.Getenv("ACME_API_KEY")
+//	    if key == "" {
+//	        t.Skip("ACME_API_KEY not set")
+//	    }
+//	    providertest.Run(t, acme.New(key, "acme-large"))
+//	}
+//
+// The checks talk to the real model, so they cost a few small requests and
+// ask for answers any competent model gives. They check shape, not wording.
+// agent/conformance goes on from here to whole tool calling conversations.
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/devkit"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Option configures Run.
+type Option func(*config)
+
+type config struct {
+	timeout   time.Duration
+	tools     bool
+	streaming bool
+	maxTokens int
+}
+
+// SkipTools leaves out the tool calling checks, for providers (or models)
+// without tool support. The agent can still use them, just without tools.
+func SkipTools() Option {
+	return func(c *config) { c.tools = false }
+}
+
+// SkipStreaming leaves out the streaming check even if the provider
+// implements llm.StreamingChatProvider.
+func SkipStreaming() Option {
+	return func(c *config) { c.streaming = false }
+}
+
+// WithTimeout sets how long each request may take. Default 60s.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) { c.timeout = d }
+}
+
+// WithMaxTokens sets MaxTokens on every request. Default 256 - enough for
+// the short answers the checks ask for, and some providers require it.
+func WithMaxTokens(n int) Option {
+	return func(c *config) { c.maxTokens = n }
+}
+
+// weatherTool is the tool the tool calling checks offer.
+var weatherTool = llm.Tool{
+	Type: "function",
+	Function: llm.FunctionDescription{
+		Name:        "get_weather",
+		Description: "Get the current weather for a city.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"city": map[string]any{"type": "string", "description": "City name"},
+			},
+			"required": []string{"city"},
+		},
+	},
+}
+
+// Run runs every check as a subtest of t.
+func Run(t *testing.T, p llm.ChatProvider, opts ...Option) {
+	t.Helper()
+	cfg := config{timeout: 60 * time.Second, tools: true, streaming: true, maxTokens: 256}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	s := &suite{p: p, cfg: cfg}
+
+	t.Run("ModelName", s.modelName)
+	t.Run("Text", s.text)
+	t.Run("SystemPrompt", s.systemPrompt)
+	if cfg.tools {
+		t.Run("ToolCall", s.toolCall)
+		t.Run("ToolResult", s.toolResult)
+	}
+	if sp, ok := p.(llm.StreamingChatProvider); ok && cfg.streaming {
+		t.Run("Stream", func(t *testing.T) { s.stream(t, sp) })
+	}
+	t.Run("Canceled", s.canceled)
+}
+
+type suite struct {
+	p   llm.ChatProvider
+	cfg config
+}
+
+func (s *suite) request(msgs ...llm.Message) llm.ChatRequest {
+	return llm.ChatRequest{Model: s.p.ModelName(), Messages: msgs, MaxTokens: s.cfg.maxTokens}
+}
+
+func (s *suite) call(t *testing.T, req llm.ChatRequest) *llm.ChatResponse {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.timeout)
+	defer cancel()
+	resp, err := s.p.CreateChat(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateChat: %v", err)
+	}
+	checkResponse(t, resp)
+	return resp
+}
+
+// checkResponse checks what the agent relies on in every response.
+func checkResponse(t *testing.T, resp *llm.ChatResponse) {
+	t.Helper()
+	if resp == nil {
+		t.Fatal("CreateChat returned a nil response and no error")
+	}
+	if len(resp.Choices) == 0 {
+		t.Fatal("response has no choices")
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Role != "assistant" {
+		t.Errorf("message role = %q, want \"assistant\"", choice.Message.Role)
+	}
+	known := []string{devkit.FinishStop, devkit.FinishToolCalls, devkit.FinishLength, devkit.FinishContentFilter}
+	if !slices.Contains(known, choice.FinishReason) {
+		t.Errorf("finish reason %q isn't one the agent understands; map it with devkit.NormalizeFinishReason", choice.FinishReason)
+	}
+	if choice.FinishReason == devkit.FinishToolCalls && len(choice.Message.ToolCalls) == 0 {
+		t.Error("finish reason is \"tool_calls\" but the message has no tool calls")
+	}
+	if len(choice.Message.ToolCalls) > 0 && choice.FinishReason != devkit.FinishToolCalls {
+		t.Errorf("message has tool calls but finish reason is %q, want \"tool_calls\" - the agent wouldn't run them", choice.FinishReason)
+	}
+	ids := make(map[string]bool)
+	for i, call := range choice.Message.ToolCalls {
+		if call.ID == "" {
+			t.Errorf("tool call %d has no ID; fill it in with devkit.EnsureToolCallIDs", i)
+		} else if ids[call.ID] {
+			t.Errorf("tool call ID %q is used twice", call.ID)
+		}
+		ids[call.ID] = true
+		if call.Function.Name == "" {
+			t.Errorf("tool call %d has no function name", i)
+		}
+		var args map[string]any
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			t.Errorf("tool call %d arguments aren't a JSON object: %q", i, call.Function.Arguments)
+		}
+	}
+	if resp.Usage.TotalTokens < resp.Usage.PromptTokens+resp.Usage.CompletionTokens {
+		t.Errorf("usage total %d is less than prompt %d + completion %d",
+			resp.Usage.TotalTokens, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	}
+}
+
+func (s *suite) modelName(t *testing.T) {
+	if s.p.ModelName() == "" {
+		t.Error("ModelName() is empty")
+	}
+}
+
+func (s *suite) text(t *testing.T) {
+	resp := s.call(t, s.request(llm.NewUserMessage("Reply with the single word: pong")))
+	choice := resp.Choices[0]
+	if choice.FinishReason != devkit.FinishStop {
+		t.Errorf("finish reason = %q, want \"stop\"", choice.FinishReason)
+	}
+	if !strings.Contains(strings.ToLower(choice.Message.Content), "pong") {
+		t.Errorf("content = %q, want it to contain \"pong\"", choice.Message.Content)
+	}
+}
+
+func (s *suite) systemPrompt(t *testing.T) {
+	resp := s.call(t, s.request(
+		llm.NewSystemMessage("Whatever the user says, reply with exactly the word BANANA."),
+		llm.NewUserMessage("Hello!"),
+	))
+	if !strings.Contains(strings.ToUpper(resp.Choices[0].Message.Content), "BANANA") {
+		t.Errorf("content = %q; the system prompt seems to be ignored", resp.Choices[0].Message.Content)
+	}
+}
+
+// askWeather gets the model to call get_weather, which the ToolCall and
+// ToolResult checks both start from.
+func (s *suite) askWeather(t *testing.T) (llm.ChatRequest, llm.Message) {
+	t.Helper()
+	req := s.request(llm.NewUserMessage("What's the weather in Paris right now? Use the tool."))
+	req.Tools = []llm.Tool{weatherTool}
+	req.ToolChoice = llm.ToolChoiceRequired()
+	resp := s.call(t, req)
+	msg := resp.Choices[0].Message
+	if len(msg.ToolCalls) == 0 {
+		t.Fatalf("no tool call with tool_choice \"required\"; content = %q", msg.Content)
+	}
+	return req, msg
+}
+
+func (s *suite) toolCall(t *testing.T) {
+	_, msg := s.askWeather(t)
+	call := msg.ToolCalls[0]
+	if call.Function.Name != weatherTool.Function.Name {
+		t.Errorf("called %q, want %q", call.Function.Name, weatherTool.Function.Name)
+	}
+	var args struct{ City string }
+	json.Unmarshal([]byte(call.Function.Arguments), &args)
+	if !strings.Contains(strings.ToLower(args.City), "paris") {
+		t.Errorf("arguments = %s, want city Paris", call.Function.Arguments)
+	}
+}
+
+func (s *suite) toolResult(t *testing.T) {
+	req, msg := s.askWeather(t)
+	req.Messages = append(req.Messages, msg)
+	for _, call := range msg.ToolCalls {
+		req.Messages = append(req.Messages,
+			llm.NewToolResult(call.ID, call.Function.Name, `{"temperature_c": 17, "conditions": "light rain"}`))
+	}
+	req.ToolChoice = llm.ToolChoiceAuto()
+
+	resp := s.call(t, req)
+	choice := resp.Choices[0]
+	if choice.FinishReason != devkit.FinishStop {
+		t.Errorf("finish reason after the tool result = %q, want \"stop\"", choice.FinishReason)
+	}
+	if !strings.Contains(choice.Message.Content, "17") && !strings.Contains(strings.ToLower(choice.Message.Content), "rain") {
+		t.Errorf("answer doesn't use the tool result: %q", choice.Message.Content)
+	}
+}
+
+func (s *suite) stream(t *testing.T, sp llm.StreamingChatProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.timeout)
+	defer cancel()
+
+	var streamed strings.Builder
+	deltas := 0
+	req := s.request(llm.NewUserMessage("Count from 1 to 5, separated by spaces."))
+	req.Stream = true
+	resp, err := sp.CreateChatStream(ctx, req, func(d llm.StreamDelta) {
+		deltas++
+		streamed.WriteString(d.Content)
+	})
+	if err != nil {
+		t.Fatalf("CreateChatStream: %v", err)
+	}
+	checkResponse(t, resp)
+	if deltas == 0 {
+		t.Error("the handler was never called")
+	}
+	if got, want := streamed.String(), resp.Choices[0].Message.Content; got != want {
+		t.Errorf("streamed content %q differs from the response's %q", got, want)
+	}
+}
+
+func (s *suite) canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.p.CreateChat(ctx, s.request(llm.NewUserMessage("Hello")))
+	if err == nil {
+		t.Fatal("CreateChat with a canceled context returned no error")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v; want it to wrap context.Canceled", err)
+	}
+	if llm.IsTransient(err) {
+		t.Errorf("a canceled call must not look transient, or the agent would retry it: %v", err)
+	}
+}
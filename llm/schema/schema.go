@@ -0,0 +1,433 @@
+// Package schema translates JSON Schema into the dialects the providers
+// accept for tool parameters.
+//
+// tools/jsonschema generates one schema per Go type, and OpenAI, Anthropic
+// and Gemini each accept a different subset of JSON Schema:
+//
+//   - OpenAI takes nearly anything, except in strict mode, where every
+//     object must set additionalProperties to false and list all of its
+//     properties as required, and a handful of keywords are rejected.
+//   - Anthropic takes nearly anything, but the root must be a plain object:
+//     no oneOf/anyOf/allOf at the top level.
+//   - Gemini takes an OpenAPI 3.0 flavoured subset: no $ref, no
+//     additionalProperties, no const, no type arrays.
+//
+// Each provider runs its converter in mapRequest, so the same generated
+// schema works everywhere. The converters are exported so you can see what
+// a provider will actually receive, or prepare schemas by hand.
+//
+// Converters never modify their input. They accept a map[string]any, raw
+// JSON (json.RawMessage, which is what tools.Registry sends) or anything
+// else that encodes to a JSON object; values that don't are returned as-is.
+package schema
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// OpenAI returns s in the form OpenAI accepts for non-strict tools. That's
+// almost everything, so this only makes sure the root is an object with a
+// properties map - the API rejects a parameterless {"type": "object"}.
+func OpenAI(s any) any {
+	root, ok := toMap(s)
+	if !ok {
+		return s
+	}
+	out := ensureObjectRoot(root)
+	if _, has := out["properties"]; !has {
+		out["properties"] = map[string]any{}
+	}
+	return out
+}
+
+// OpenAIStrict returns s in the form OpenAI's strict mode (tools with
+// "strict": true, and structured outputs) accepts:
+//
+//   - every object gets additionalProperties: false
+//   - every property is listed in required; properties that weren't required
+//     become nullable instead, so the model can still leave them out by
+//     sending null
+//   - keywords strict mode rejects (minLength, maxLength, uniqueItems,
+//     allOf, not, ...) are dropped; allOf parts are merged first
+func OpenAIStrict(s any) any {
+	root, ok := toMap(s)
+	if !ok {
+		return s
+	}
+	out := strictNode(ensureObjectRoot(root))
+	if defs, ok := root["$defs"].(map[string]any); ok {
+		converted := make(map[string]any, len(defs))
+		for name, d := range defs {
+			if dm, ok := d.(map[string]any); ok {
+				converted[name] = strictNode(dm)
+			}
+		}
+		out["$defs"] = converted
+	}
+	return out
+}
+
+// strictUnsupported are keywords strict mode rejects.
+var strictUnsupported = map[string]bool{
+	"minLength": true, "maxLength": true,
+	"uniqueItems": true, "contains": true, "minContains": true, "maxContains": true,
+	"unevaluatedItems": true, "unevaluatedProperties": true,
+	"patternProperties": true, "propertyNames": true,
+	"minProperties": true, "maxProperties": true,
+	"allOf": true, "not": true, "if": true, "then": true, "else": true,
+	"dependentRequired": true, "dependentSchemas": true,
+	"default": true, "examples": true, "$schema": true,
+}
+
+func strictNode(node map[string]any) map[string]any {
+	out := make(map[string]any, len(node))
+	if parts, ok := node["allOf"].([]any); ok {
+		for _, p := range parts {
+			if pm, ok := p.(map[string]any); ok {
+				for k, v := range strictNode(pm) {
+					out[k] = v
+				}
+			}
+		}
+	}
+	for k, v := range node {
+		switch {
+		case strictUnsupported[k] || k == "$defs":
+			// $defs is converted separately, at the root only.
+		case k == "properties" || k == "required" || k == "additionalProperties":
+			// rebuilt below
+		case k == "items":
+			if im, ok := v.(map[string]any); ok {
+				out[k] = strictNode(im)
+			}
+		case k == "anyOf" || k == "oneOf":
+			// Strict mode has anyOf but not oneOf; for arguments they're
+			// the same thing.
+			out["anyOf"] = mapList(v, strictNode)
+		default:
+			out[k] = v
+		}
+	}
+
+	props, isObject := node["properties"].(map[string]any)
+	if !isObject && node["type"] != "object" {
+		return out
+	}
+
+	required := stringSet(node["required"])
+	converted := make(map[string]any, len(props))
+	names := make([]any, 0, len(props))
+	for _, name := range sortedKeys(props) {
+		pm, ok := props[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		p := strictNode(pm)
+		if !required[name] {
+			p = nullable(p)
+		}
+		converted[name] = p
+		names = append(names, name)
+	}
+	out["properties"] = converted
+	out["required"] = names
+	out["additionalProperties"] = false
+	return out
+}
+
+// nullable lets a strict-mode property be null, which is how an optional
+// field is expressed when everything must be required.
+func nullable(p map[string]any) map[string]any {
+	switch t := p["type"].(type) {
+	case string:
+		if t != "null" {
+			p["type"] = []any{t, "null"}
+		}
+		return p
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			p["type"] = append(slices.Clone(t), "null")
+		}
+		return p
+	}
+	if anyOf, ok := p["anyOf"].([]any); ok {
+		p["anyOf"] = append(anyOf, map[string]any{"type": "null"})
+		return p
+	}
+	// A $ref or an untyped schema - wrap it.
+	return map[string]any{"anyOf": []any{p, map[string]any{"type": "null"}}}
+}
+
+// Anthropic returns s in the form Anthropic accepts for a tool's
+// input_schema. Everything below the root is passed through; the root must
+// be an object, so root-level allOf is merged, and root-level oneOf/anyOf
+// is flattened into one object with the alternatives' properties (only
+// those required by every alternative stay required).
+func Anthropic(s any) any {
+	root, ok := toMap(s)
+	if !ok {
+		return s
+	}
+	out := ensureObjectRoot(root)
+	delete(out, "$schema")
+	return out
+}
+
+// ensureObjectRoot returns a copy of root whose top level is a plain object
+// schema: allOf/oneOf/anyOf there are merged away and type is "object".
+func ensureObjectRoot(root map[string]any) map[string]any {
+	out := make(map[string]any, len(root))
+	for k, v := range root {
+		if k != "allOf" && k != "oneOf" && k != "anyOf" {
+			out[k] = v
+		}
+	}
+
+	props := map[string]any{}
+	if p, ok := root["properties"].(map[string]any); ok {
+		for k, v := range p {
+			props[k] = v
+		}
+	}
+	required := stringSet(root["required"])
+
+	if parts, ok := root["allOf"].([]any); ok {
+		for _, p := range parts {
+			pm, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			if pp, ok := pm["properties"].(map[string]any); ok {
+				for k, v := range pp {
+					props[k] = v
+				}
+			}
+			for name := range stringSet(pm["required"]) {
+				required[name] = true
+			}
+		}
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		alts, ok := root[key].([]any)
+		if !ok {
+			continue
+		}
+		var common map[string]bool
+		for _, a := range alts {
+			am, ok := a.(map[string]any)
+			if !ok {
+				continue
+			}
+			if ap, ok := am["properties"].(map[string]any); ok {
+				for k, v := range ap {
+					props[k] = v
+				}
+			}
+			req := stringSet(am["required"])
+			if common == nil {
+				common = req
+				continue
+			}
+			for name := range common {
+				if !req[name] {
+					delete(common, name)
+				}
+			}
+		}
+		for name := range common {
+			required[name] = true
+		}
+	}
+
+	out["type"] = "object"
+	if len(props) > 0 {
+		out["properties"] = props
+	}
+	if len(required) > 0 {
+		names := make([]any, 0, len(required))
+		for _, name := range sortedKeys(required) {
+			names = append(names, name)
+		}
+		out["required"] = names
+	}
+	return out
+}
+
+// geminiKeywords are the keywords Gemini's function declaration schema
+// understands. Everything else is dropped by the Gemini converter.
+var geminiKeywords = map[string]bool{
+	"type": true, "format": true, "title": true, "description": true,
+	"nullable": true, "enum": true, "items": true, "minItems": true, "maxItems": true,
+	"properties": true, "required": true, "propertyOrdering": true,
+	"minimum": true, "maximum": true, "minLength": true, "maxLength": true,
+	"pattern": true, "anyOf": true, "minProperties": true, "maxProperties": true,
+}
+
+// maxRefDepth limits how many times a recursive $ref is inlined. Gemini has
+// no references, so a tree type gets unrolled this far and then stops at a
+// plain object.
+const maxRefDepth = 3
+
+// Gemini returns s in the subset Gemini accepts: references are inlined,
+// allOf is merged, const becomes a one-value enum, oneOf becomes anyOf,
+// type arrays become a type plus nullable, and unknown keywords are dropped.
+func Gemini(s any) any {
+	root, ok := toMap(s)
+	if !ok {
+		return s
+	}
+	defs, _ := root["$defs"].(map[string]any)
+	if defs == nil {
+		defs, _ = root["definitions"].(map[string]any)
+	}
+	g := gemini{root: root, defs: defs}
+	return g.node(root, 0)
+}
+
+type gemini struct {
+	root, defs map[string]any
+}
+
+func (g gemini) node(node map[string]any, depth int) map[string]any {
+	// Inline references. "#" is the root itself (recursive root types).
+	if ref, ok := node["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			return map[string]any{"type": "object"}
+		}
+		var target map[string]any
+		switch {
+		case ref == "#":
+			target = g.root
+		case strings.HasPrefix(ref, "#/$defs/"):
+			target, _ = g.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		case strings.HasPrefix(ref, "#/definitions/"):
+			target, _ = g.defs[strings.TrimPrefix(ref, "#/definitions/")].(map[string]any)
+		}
+		if target == nil {
+			return map[string]any{"type": "object"}
+		}
+		out := g.node(target, depth+1)
+		if desc, ok := node["description"]; ok {
+			out["description"] = desc
+		}
+		return out
+	}
+
+	out := make(map[string]any, len(node))
+	recurse := func(m map[string]any) map[string]any { return g.node(m, depth) }
+
+	// allOf is how a described $ref is written - merge the parts in.
+	if parts, ok := node["allOf"].([]any); ok {
+		for _, p := range parts {
+			if pm, ok := p.(map[string]any); ok {
+				for k, v := range recurse(pm) {
+					out[k] = v
+				}
+			}
+		}
+	}
+
+	for k, v := range node {
+		switch {
+		case k == "const":
+			out["enum"] = []any{v}
+		case k == "oneOf":
+			// Close enough for argument generation; Gemini has anyOf.
+			out["anyOf"] = mapList(v, recurse)
+		case !geminiKeywords[k]:
+			// dropped: additionalProperties, $schema, $defs, default, examples...
+		case k == "properties":
+			if props, ok := v.(map[string]any); ok {
+				converted := make(map[string]any, len(props))
+				for name, p := range props {
+					if pm, ok := p.(map[string]any); ok {
+						converted[name] = recurse(pm)
+					}
+				}
+				out[k] = converted
+			}
+		case k == "items":
+			if im, ok := v.(map[string]any); ok {
+				out[k] = recurse(im)
+			}
+		case k == "anyOf":
+			out[k] = mapList(v, recurse)
+		case k == "type":
+			// ["string", "null"] is not allowed; use the first real type plus nullable.
+			if types, ok := v.([]any); ok {
+				for _, t := range types {
+					if t == "null" {
+						out["nullable"] = true
+					} else if _, set := out["type"]; !set {
+						out["type"] = t
+					}
+				}
+			} else {
+				out[k] = v
+			}
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// toMap gets a schema as a map of plain JSON values, going through JSON.
+// Even a map[string]any is round-tripped: one built in Go (like
+// jsonschema.GenerateSchema's) holds []string and typed numbers where the
+// converters expect []any and float64.
+func toMap(s any) (map[string]any, bool) {
+	if s == nil {
+		return nil, false
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, false
+	}
+	var m map[string]any
+	if json.Unmarshal(data, &m) != nil || m == nil {
+		return nil, false
+	}
+	return m, true
+}
+
+func mapList(v any, fn func(map[string]any) map[string]any) []any {
+	list, _ := v.([]any)
+	out := make([]any, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, fn(m))
+		}
+	}
+	return out
+}
+
+func stringSet(v any) map[string]bool {
+	set := map[string]bool{}
+	switch list := v.(type) {
+	case []any:
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				set[s] = true
+			}
+		}
+	case []string:
+		for _, s := range list {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
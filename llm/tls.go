@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrPinMismatch is returned when none of the server's certificates match a
+// configured pin. In practice this almost always means a TLS-intercepting
+// proxy (corporate MITM box) is sitting between you and the provider.
+var ErrPinMismatch = errors.New("llm: server certificate does not match any pinned key")
+
+// ErrInsecureTransport is returned when RequireHTTPS is set and a provider
+// is pointed at a plain http:// URL.
+var ErrInsecureTransport = errors.New("llm: refusing to send request over plain HTTP")
+
+// TLSPolicy describes the transport guarantees a regulated deployment needs.
+// Build an *http.Client from it with HTTPClient and hand that to any
+// provider's WithHTTPClient option:
+//
+//	hc, err := llm.TLSPolicy{
+//	    MinVersion:   tls.VersionTLS13,
+//	    Pins:         []string{"r/mIkG3eEpVdm+u/ko/cwxzOMo1bk4TyHIlByibiA5E="},
+//	    RequireHTTPS: true,
+//	}.HTTPClient()
+//	provider := openai.New(key, "gpt-4o", openai.WithHTTPClient(hc))
+type TLSPolicy struct {
+	// MinVersion is the lowest TLS version to negotiate (tls.VersionTLS12,
+	// tls.VersionTLS13). Zero means TLS 1.2.
+	MinVersion uint16
+
+	// Pins are base64-encoded SHA-256 hashes of a certificate's
+	// SubjectPublicKeyInfo - the same format as HPKP and `openssl ... | base64`.
+	// At least one certificate in the verified chain must match one pin.
+	// Pinning an intermediate or root survives leaf rotation.
+	// Empty means no pinning, just normal certificate verification.
+	Pins []string
+
+	// RequireHTTPS rejects requests to http:// URLs before they are sent,
+	// so a misconfigured base URL can't leak prompts in cleartext.
+	RequireHTTPS bool
+}
+
+// SPKIHash returns the pin string for a certificate, in the format Pins expects.
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// TLSConfig builds a *tls.Config that enforces the policy.
+// Normal chain verification still happens first; pinning is checked on top of it.
+func (p TLSPolicy) TLSConfig() (*tls.Config, error) {
+	minVersion := p.MinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+
+	pins := make(map[string]bool, len(p.Pins))
+	for _, pin := range p.Pins {
+		raw, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(raw) != sha256.Size {
+			return nil, fmt.Errorf("llm: invalid pin %q: want base64 SHA-256", pin)
+		}
+		pins[pin] = true
+	}
+
+	cfg := &tls.Config{MinVersion: minVersion}
+	if len(pins) > 0 {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					if pins[SPKIHash(cert)] {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("%w (host %s)", ErrPinMismatch, cs.ServerName)
+		}
+	}
+	return cfg, nil
+}
+
+// HTTPClient builds an *http.Client that enforces the policy. It starts
+// from a clone of http.DefaultTransport, so proxies from the environment,
+// HTTP/2 and connection pooling behave as usual.
+func (p TLSPolicy) HTTPClient() (*http.Client, error) {
+	cfg, err := p.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+
+	var rt http.RoundTripper = transport
+	if p.RequireHTTPS {
+		rt = httpsOnly{next: transport}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// httpsOnly refuses to round-trip anything that isn't https.
+type httpsOnly struct {
+	next http.RoundTripper
+}
+
+func (h httpsOnly) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInsecureTransport, req.URL.Redacted())
+	}
+	return h.next.RoundTrip(req)
+}
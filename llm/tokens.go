@@ -0,0 +1,52 @@
+package llm
+
+import "encoding/json"
+
+// Token estimates. Exact counts need the model's own tokenizer, which
+// differs between providers and isn't something to ship in a library; for
+// deciding whether a conversation is getting close to the context window,
+// "about four characters per token" is close enough for English text and
+// code, and errs on the high side for most other text.
+const (
+	charsPerToken   = 4
+	messageOverhead = 4   // role and separators each message costs
+	imageTokens     = 765 // a typical high-detail image
+)
+
+// EstimateTokens estimates how many prompt tokens a message costs.
+func EstimateTokens(m Message) int {
+	n := messageOverhead + estimateText(m.Content)
+	for _, p := range m.Parts {
+		switch p.Type {
+		case "text":
+			if m.Content == "" {
+				n += estimateText(p.Text)
+			}
+		default:
+			n += imageTokens
+		}
+	}
+	for _, tc := range m.ToolCalls {
+		n += messageOverhead + estimateText(tc.Function.Name) + estimateText(tc.Function.Arguments)
+	}
+	return n
+}
+
+// EstimateRequestTokens estimates the prompt tokens of a whole request:
+// every message plus the tool definitions, which count against the
+// context window too.
+func EstimateRequestTokens(req ChatRequest) int {
+	n := 0
+	for _, m := range req.Messages {
+		n += EstimateTokens(m)
+	}
+	if len(req.Tools) > 0 {
+		data, _ := json.Marshal(req.Tools)
+		n += estimateText(string(data))
+	}
+	return n
+}
+
+func estimateText(s string) int {
+	return (len(s) + charsPerToken - 1) / charsPerToken
+}
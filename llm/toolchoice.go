@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Tool choice modes.
+const (
+	ToolChoiceModeAuto     = "auto"     // the model decides whether to call a tool
+	ToolChoiceModeNone     = "none"     // the model must not call tools
+	ToolChoiceModeRequired = "required" // the model must call some tool
+	ToolChoiceModeFunction = "function" // the model must call one particular tool
+)
+
+// ToolChoice is a typed value for ChatRequest.ToolChoice. Build one with
+// ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired or ToolChoiceFunction:
+//
+//	req.ToolChoice = llm.ToolChoiceFunction("get_weather")
+//
+// Every provider maps it to its own setting - OpenAI's tool_choice,
+// Anthropic's tool_choice, Gemini's toolConfig. It marshals to OpenAI's
+// shape, so it also goes through OpenAI-compatible servers untouched.
+type ToolChoice struct {
+	Mode     string // one of the ToolChoiceMode constants
+	Function string // the tool to call, for ToolChoiceModeFunction
+}
+
+// ToolChoiceAuto lets the model decide whether to call a tool. It's what
+// every provider does when ToolChoice is unset.
+func ToolChoiceAuto() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceModeAuto}
+}
+
+// ToolChoiceNone stops the model calling tools, even though they're in
+// the request.
+func ToolChoiceNone() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceModeNone}
+}
+
+// ToolChoiceRequired makes the model call at least one tool of its choice.
+func ToolChoiceRequired() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceModeRequired}
+}
+
+// ToolChoiceFunction makes the model call the named tool.
+func ToolChoiceFunction(name string) *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceModeFunction, Function: name}
+}
+
+// Forces reports whether the choice makes the model call a tool.
+func (c *ToolChoice) Forces() bool {
+	return c != nil && (c.Mode == ToolChoiceModeRequired || c.Mode == ToolChoiceModeFunction)
+}
+
+// MarshalJSON writes OpenAI's tool_choice: a string for the modes, an
+// object for a particular function.
+func (c *ToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Mode == ToolChoiceModeFunction {
+		return json.Marshal(map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": c.Function},
+		})
+	}
+	return json.Marshal(c.Mode)
+}
+
+// ParseToolChoice reads whatever was put in ChatRequest.ToolChoice: a
+// ToolChoice, one of the mode strings ("any" counts as "required", the
+// way Anthropic and Gemini call it), or OpenAI's object naming a function,
+// as decoded from JSON. nil gives nil.
+func ParseToolChoice(v any) (*ToolChoice, error) {
+	switch c := v.(type) {
+	case nil:
+		return nil, nil
+	case *ToolChoice:
+		return c, nil
+	case ToolChoice:
+		return &c, nil
+	case string:
+		switch c {
+		case ToolChoiceModeAuto, ToolChoiceModeNone, ToolChoiceModeRequired:
+			return &ToolChoice{Mode: c}, nil
+		case "any":
+			return ToolChoiceRequired(), nil
+		}
+		return nil, fmt.Errorf("llm: unknown tool choice %q", c)
+	}
+
+	// An object: round-trip it through JSON so map[string]any and structs
+	// of the same shape both work.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("llm: tool choice: %w", err)
+	}
+	var obj struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil || obj.Type != "function" || obj.Function.Name == "" {
+		return nil, fmt.Errorf("llm: unknown tool choice %s", data)
+	}
+	return ToolChoiceFunction(obj.Function.Name), nil
+}
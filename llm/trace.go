@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TraceContext identifies where in a distributed trace some work happens.
+// The agent puts one on the context of every tool call, so a tool that
+// calls another service can pass the trace along and the whole request -
+// user, agent, tool, downstream service - shows up as one trace:
+//
+//	func LookupOrder(ctx context.Context, args OrderArgs) (string, error) {
+//	    req, _ := http.NewRequestWithContext(ctx, "GET", ordersURL+args.ID, nil)
+//	    if tc, ok := llm.TraceFromContext(ctx); ok {
+//	        tc.Inject(req.Header) // sets the W3C traceparent header
+//	    }
+//	    ...
+//	}
+//
+// The IDs follow W3C Trace Context, so any OpenTelemetry-instrumented
+// service picks them up. To continue an incoming trace rather than start a
+// new one, put it on the context you pass to Run:
+//
+//	tc, err := llm.ParseTraceparent(r.Header.Get("traceparent"))
+//	if err == nil {
+//	    ctx = llm.ContextWithTrace(ctx, tc)
+//	}
+//	reply, err := a.Run(ctx, question)
+type TraceContext struct {
+	TraceID      string // 32 hex characters, shared by every span in the trace
+	SpanID       string // 16 hex characters, this unit of work (the tool call)
+	ParentSpanID string // the span this one belongs to (the run), if any
+	Sampled      bool   // whether the trace is being recorded
+
+	RunID      string // the agent run (RunResult.ID)
+	ToolCallID string // the tool call being executed, inside tools
+}
+
+type traceKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying tc.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+// TraceFromContext returns the trace context on ctx. Inside a tool run by
+// the agent it is always there.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok
+}
+
+// NewTraceID returns a random 32-hex-character trace ID, drawn from the
+// context's random source like NewCallID.
+func NewTraceID(ctx context.Context) string {
+	return randomHex(ctx, 16)
+}
+
+// NewSpanID returns a random 16-hex-character span ID.
+func NewSpanID(ctx context.Context) string {
+	return randomHex(ctx, 8)
+}
+
+// ChildSpan returns a trace context for work done on behalf of tc: same
+// trace, a new span whose parent is tc's span.
+func (tc TraceContext) ChildSpan(ctx context.Context) TraceContext {
+	child := tc
+	child.ParentSpanID = tc.SpanID
+	child.SpanID = NewSpanID(ctx)
+	return child
+}
+
+// Traceparent formats tc as a W3C traceparent header value:
+// "00-<trace id>-<span id>-<flags>".
+func (tc TraceContext) Traceparent() string {
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + flags
+}
+
+// Inject sets the traceparent header on h, so the receiving service
+// continues this trace.
+func (tc TraceContext) Inject(h http.Header) {
+	h.Set("traceparent", tc.Traceparent())
+}
+
+// ParseTraceparent parses a W3C traceparent header value. The result has
+// the caller's span as SpanID; the agent starts its run span under it.
+func ParseTraceparent(s string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return TraceContext{}, fmt.Errorf("llm: invalid traceparent %q", s)
+	}
+	if !isHex(parts[1]) || !isHex(parts[2]) || !isHex(parts[3]) ||
+		parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return TraceContext{}, fmt.Errorf("llm: invalid traceparent %q", s)
+	}
+	flags, _ := strconv.ParseUint(parts[3], 16, 8) // already checked it's hex
+	return TraceContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Sampled: flags&1 == 1,
+	}, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// TransportMiddleware wraps the HTTP transport providers send requests
+// through - to sign them, log them, restrict where they go. It sees the
+// finished HTTP request, after the provider has translated everything to
+// its wire format.
+type TransportMiddleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc lets an ordinary function be an http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ChainTransport wraps base in mw, the first middleware outermost. A nil
+// base means http.DefaultTransport.
+func ChainTransport(base http.RoundTripper, mw ...TransportMiddleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(mw) - 1; i >= 0; i-- {
+		base = mw[i](base)
+	}
+	return base
+}
+
+// NewHTTPClient returns an *http.Client whose transport is the default one
+// wrapped in mw. Hand it to any provider's WithHTTPClient option, and the
+// same middleware applies whatever the provider:
+//
+//	hc := llm.NewHTTPClient(llm.SignHMAC(gatewayKey))
+//	provider := openai.New(key, "gpt-4o", openai.WithBaseURL(gateway), openai.WithHTTPClient(hc))
+func NewHTTPClient(mw ...TransportMiddleware) *http.Client {
+	return &http.Client{Transport: ChainTransport(nil, mw...)}
+}
+
+// WrapHTTPClient returns a copy of hc whose transport is wrapped in mw,
+// leaving hc itself alone. Its timeout, cookie jar and redirect policy
+// carry over. With no middleware, hc comes back as it is.
+//
+// Providers use it for their WithTransportMiddleware options, which is
+// how instrumentation like otelhttp slots in without giving up the
+// client the provider would otherwise use.
+func WrapHTTPClient(hc *http.Client, mw ...TransportMiddleware) *http.Client {
+	if len(mw) == 0 {
+		return hc
+	}
+	if hc == nil {
+		hc = &http.Client{}
+	}
+	wrapped := *hc
+	wrapped.Transport = ChainTransport(hc.Transport, mw...)
+	return &wrapped
+}
+
+var defaultHTTPClient atomic.Pointer[http.Client]
+
+// DefaultHTTPClient returns the client the SDK's built-in tools, feeds and
+// triggers use when they aren't given one. It's http.DefaultClient unless
+// SetDefaultHTTPClient or EgressPolicy.Install has replaced it. Each of
+// them asks on every request, so a replacement applies to ones built
+// before it too.
+func DefaultHTTPClient() *http.Client {
+	if hc := defaultHTTPClient.Load(); hc != nil {
+		return hc
+	}
+	return http.DefaultClient
+}
+
+// SetDefaultHTTPClient replaces the client DefaultHTTPClient returns. nil
+// puts http.DefaultClient back. Providers aren't affected; they keep
+// their own clients, set with their WithHTTPClient options.
+func SetDefaultHTTPClient(hc *http.Client) {
+	defaultHTTPClient.Store(hc)
+}
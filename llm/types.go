@@ -24,6 +24,10 @@ type ChatRequest struct {
 	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`   // Force JSON output
 	Seed             int             `json:"seed,omitempty"`              // For deterministic outputs
 
+	// Reasoning turns on extended thinking for models that support it.
+	// Each provider maps it to its own setting - see Reasoning.
+	Reasoning *Reasoning `json:"reasoning,omitempty"`
+
 	// Tool Calling Configuration
 	// Tools tells the LLM what functions it can call.
 	// The LLM doesn't actually run them - it just tells us to.
@@ -31,7 +35,11 @@ type ChatRequest struct {
 	// ToolChoice controls when the LLM can use tools:
 	//   "auto" - LLM decides when to use tools
 	//   "none" - Never use tools
+	//   "required" - Must use some tool
 	//   specific object - Force a specific tool
+	// Prefer the typed constructors - llm.ToolChoiceAuto(),
+	// llm.ToolChoiceFunction("name") and so on - which every provider maps
+	// to its own setting. See ParseToolChoice for what else is accepted.
 	ToolChoice interface{} `json:"tool_choice,omitempty"`
 }
 
@@ -45,13 +53,80 @@ type ChatRequest struct {
 //
 // Content is the actual text. Note that Content is empty (null in JSON)
 // when the assistant is making tool calls - the ToolCalls field holds
-// that information instead.
+// that information instead. Messages with images carry them in Parts
+// (see NewUserImageMessage).
 type Message struct {
 	Role       string     `json:"role"`    // "user", "assistant", "system", or "tool"
 	Content    string     `json:"content"` // The text content (empty for tool call messages)
 	Name       string     `json:"name,omitempty"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Present when assistant wants to call tools
 	ToolCallID string     `json:"tool_call_id,omitempty"` // Required for "tool" role messages
+
+	// Parts makes the message multimodal: text and images in one message.
+	// When set, providers send Parts instead of Content. On the wire it
+	// replaces "content" with an array (see Message.MarshalJSON).
+	Parts []ContentPart `json:"-"`
+
+	// Reasoning is the model's thinking before it answered, when the
+	// request asked for it and the provider returns it. Display or log it;
+	// it is not part of the answer.
+	Reasoning string `json:"reasoning,omitempty"`
+
+	// ReasoningSignature is an opaque token some providers (Anthropic)
+	// attach to their thinking. It has to be sent back unchanged with the
+	// assistant message when a tool call follows, or the API rejects the
+	// next request. Providers that don't use it ignore it.
+	ReasoningSignature string `json:"reasoning_signature,omitempty"`
+
+	// Metadata holds your own annotations - an intent, a sentiment, a
+	// ticket number. It's saved with the history but never sent to the
+	// model.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Reasoning configures extended thinking ("reasoning") for a request.
+// Set Effort, BudgetTokens, or both - each provider uses what it
+// understands and derives the other if needed:
+//
+//   - Anthropic: thinking.budget_tokens (Effort maps to 1024/4096/16384)
+//   - Gemini: generationConfig.thinkingConfig.thinkingBudget (same mapping)
+//   - OpenAI: reasoning_effort (BudgetTokens maps to low/medium/high)
+//   - OpenRouter: the reasoning object, passed through
+type Reasoning struct {
+	Effort       string `json:"effort,omitempty"`     // "low", "medium" or "high"
+	BudgetTokens int    `json:"max_tokens,omitempty"` // tokens the model may spend thinking
+}
+
+// Budget returns BudgetTokens, or a budget derived from Effort.
+func (r *Reasoning) Budget() int {
+	if r.BudgetTokens > 0 {
+		return r.BudgetTokens
+	}
+	switch r.Effort {
+	case "low":
+		return 1024
+	case "high":
+		return 16384
+	default:
+		return 4096
+	}
+}
+
+// EffortLevel returns Effort, or an effort derived from BudgetTokens.
+func (r *Reasoning) EffortLevel() string {
+	if r.Effort != "" {
+		return r.Effort
+	}
+	switch {
+	case r.BudgetTokens == 0:
+		return "medium"
+	case r.BudgetTokens < 2048:
+		return "low"
+	case r.BudgetTokens < 8192:
+		return "medium"
+	default:
+		return "high"
+	}
 }
 
 // Tool describes a function the LLM can call.
@@ -67,6 +142,11 @@ type FunctionDescription struct {
 	Name        string      `json:"name"`                  // Unique identifier for the function
 	Description string      `json:"description,omitempty"` // What the function does
 	Parameters  interface{} `json:"parameters"`            // JSON Schema describing the arguments
+
+	// Strict asks OpenAI to guarantee the arguments match Parameters
+	// exactly. The schema is converted to what strict mode accepts (see
+	// schema.OpenAIStrict); other providers ignore the flag.
+	Strict bool `json:"strict,omitempty"`
 }
 
 // ToolCall is the LLM's request to execute a specific tool.
@@ -117,8 +197,59 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`      // Total for billing
 }
 
+// Add returns the sum of two usage counts.
+// Handy for totalling usage across the several LLM calls one agent run makes.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// Sub returns the difference between two usage counts (u minus other).
+func (u Usage) Sub(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens - other.PromptTokens,
+		CompletionTokens: u.CompletionTokens - other.CompletionTokens,
+		TotalTokens:      u.TotalTokens - other.TotalTokens,
+	}
+}
+
 // ResponseFormat forces the LLM to output valid JSON.
-// Set Type to "json_object" to get structured output.
+// Set Type to "json_object" to get structured output, or to "json_schema"
+// with a JSONSchema to get output of a particular shape (see
+// NewJSONSchemaFormat).
 type ResponseFormat struct {
-	Type string `json:"type"` // "text" or "json_object"
+	Type       string      `json:"type"`                  // "text", "json_object" or "json_schema"
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"` // required for "json_schema"
+}
+
+// JSONSchema describes the JSON the model must answer with.
+type JSONSchema struct {
+	Name        string `json:"name"` // letters, digits, _ and -; identifies the format
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema"` // a JSON Schema, as a map or json.RawMessage
+
+	// Strict makes OpenAI guarantee the output matches Schema, using
+	// constrained decoding. The schema is converted to the subset strict
+	// mode accepts (see schema.OpenAIStrict) before it's sent.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// NewJSONSchemaFormat returns a strict "json_schema" response format:
+//
+//	type Invoice struct {
+//	    Number string  `json:"number"`
+//	    Total  float64 `json:"total"`
+//	}
+//	req.ResponseFormat = llm.NewJSONSchemaFormat("invoice", jsonschema.Of(Invoice{}))
+//
+// OpenAI enforces it exactly. Gemini gets it as its response schema; other
+// providers ignore ResponseFormat, so validate the output yourself there.
+func NewJSONSchemaFormat(name string, schema any) *ResponseFormat {
+	return &ResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &JSONSchema{Name: name, Schema: schema, Strict: true},
+	}
 }
@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Warmer is an optional interface for providers that can open their
+// connection ahead of time. The first request of a fresh process pays for
+// DNS, TCP, TLS and HTTP/2 setup - often most of a second - and Warmup
+// moves that cost to startup, before a user is waiting.
+//
+// Warmup shouldn't cost tokens. The built-in providers send a cheap
+// request (listing models) and keep the connection in the HTTP client's
+// pool for the real calls.
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// Warmup warms p up if it implements Warmer, and does nothing otherwise.
+func Warmup(ctx context.Context, p ChatProvider) error {
+	if w, ok := p.(Warmer); ok {
+		return w.Warmup(ctx)
+	}
+	return nil
+}
+
+// WarmConnection sends a GET to url with the given headers and discards
+// the response, leaving an open connection in hc's pool. Any HTTP status
+// counts as success - a 401 or 404 still means DNS, TLS and the
+// connection are done. Only transport failures are returned.
+//
+// It's what the built-in providers' Warmup methods use; custom providers
+// can use it too.
+func WarmConnection(ctx context.Context, hc *http.Client, url string, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("warmup: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("warmup: %w", err)
+	}
+	// Read the body to the end, or the connection can't be reused.
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
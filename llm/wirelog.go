@@ -0,0 +1,305 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WireLogOption configures LogWire.
+type WireLogOption func(*wireLogger)
+
+type wireLogger struct {
+	log     *slog.Logger
+	level   slog.Level
+	maxBody int
+	bodies  bool
+	fields  map[string]bool
+}
+
+// WireLevel sets the level exchanges are logged at. The default is
+// slog.LevelDebug. Failed exchanges - a transport error or an HTTP status
+// of 400 or more - are logged at slog.LevelWarn at least.
+func WireLevel(level slog.Level) WireLogOption {
+	return func(w *wireLogger) {
+		w.level = level
+	}
+}
+
+// WireMaxBody cuts each logged body to its first n bytes. The default is
+// 2000; 0 or less logs them whole.
+func WireMaxBody(n int) WireLogOption {
+	return func(w *wireLogger) {
+		w.maxBody = n
+	}
+}
+
+// WireOmitBodies logs method, URL, status and latency only.
+func WireOmitBodies() WireLogOption {
+	return func(w *wireLogger) {
+		w.bodies = false
+	}
+}
+
+// WireRedactFields adds JSON field names whose values are logged as
+// [REDACTED] - "content", say, to keep what users wrote out of the logs
+// altogether. Names match case-insensitively, ignoring - and _.
+func WireRedactFields(names ...string) WireLogOption {
+	return func(w *wireLogger) {
+		for _, n := range names {
+			w.fields[wireFieldKey(n)] = true
+		}
+	}
+}
+
+// LogWire logs each HTTP exchange a provider makes to logger, as one
+// record with the method, URL, status, latency and both bodies - the
+// request as the provider translated it and the response before it's
+// parsed. That's the place to look when a field goes missing between
+// the SDK and the API, and unlike agent.DebugCallback it can stay on in
+// production:
+//
+//	provider := openai.New(key, "gpt-4o", openai.WithTransportMiddleware(
+//	    llm.LogWire(slog.Default(), llm.WireMaxBody(500)),
+//	))
+//
+// Credentials are redacted: API keys in the URL, the values of fields
+// named like secrets (api_key, authorization, token and so on) and
+// anything shaped like a key or bearer token wherever it appears. Headers
+// aren't logged, apart from the provider's request ID.
+//
+// Streamed responses pass through as they arrive; the record is written
+// once the provider closes the body, with the first part of the stream.
+func LogWire(logger *slog.Logger, opts ...WireLogOption) TransportMiddleware {
+	w := &wireLogger{log: logger, level: slog.LevelDebug, maxBody: 2000, bodies: true, fields: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.log == nil {
+		w.log = slog.Default()
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return w.roundTrip(next, req)
+		})
+	}
+}
+
+func (w *wireLogger) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !w.log.Enabled(ctx, w.level) && !w.log.Enabled(ctx, max(w.level, slog.LevelWarn)) {
+		return next.RoundTrip(req)
+	}
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", w.redactURL(req.URL)),
+	}
+	if w.bodies {
+		body, err := w.requestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			attrs = append(attrs, slog.String("request_body", w.format(body)))
+		}
+	}
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	latency := time.Since(start)
+	attrs = append(attrs, slog.Duration("latency", latency))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", w.redactText(err.Error())))
+		w.log.LogAttrs(ctx, max(w.level, slog.LevelWarn), "llm: http exchange", attrs...)
+		return nil, err
+	}
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	for _, h := range []string{"X-Request-Id", "Request-Id", "Anthropic-Request-Id", "X-Goog-Request-Id"} {
+		if id := resp.Header.Get(h); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+			break
+		}
+	}
+	level := w.level
+	if resp.StatusCode >= 400 {
+		level = max(level, slog.LevelWarn)
+	}
+	if !w.bodies || resp.Body == nil {
+		w.log.LogAttrs(ctx, level, "llm: http exchange", attrs...)
+		return resp, nil
+	}
+	resp.Body = &wireBody{ReadCloser: resp.Body, limit: wireCaptureLimit, done: func(body []byte, more int64) {
+		logged := w.format(body)
+		if more > 0 {
+			logged += fmt.Sprintf(" …[%d more bytes]", more)
+		}
+		w.log.LogAttrs(ctx, level, "llm: http exchange", append(attrs, slog.String("response_body", logged))...)
+	}}
+	return resp, nil
+}
+
+// requestBody returns a copy of the whole request body, leaving the
+// request able to send it. It's read whole, not cut to WireMaxBody, so
+// that the JSON can be parsed and redacted first.
+func (w *wireLogger) requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err == nil {
+			defer rc.Close()
+			if body, err := io.ReadAll(rc); err == nil {
+				return body, nil
+			}
+		}
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("llm: reading request body to log: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// wireCaptureLimit is how much of a response body is kept for logging.
+// Bodies are redacted whole and only then cut to WireMaxBody, so this is
+// well past any chat response; a longer one can't be parsed, and is
+// logged as unparseable if WireRedactFields is set.
+const wireCaptureLimit = 16 << 20
+
+// format redacts body and then cuts it to WireMaxBody.
+func (w *wireLogger) format(body []byte) string {
+	s := w.redactText(string(body))
+	if w.maxBody > 0 && len(s) > w.maxBody {
+		s = fmt.Sprintf("%s…[%d bytes cut]", s[:w.maxBody], len(s)-w.maxBody)
+	}
+	return s
+}
+
+// wireBody captures the start of a response body as it's read and calls
+// done once, on Close.
+type wireBody struct {
+	io.ReadCloser
+	limit int
+	buf   bytes.Buffer
+	more  int64
+	once  sync.Once
+	done  func(body []byte, more int64)
+}
+
+func (b *wireBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+		b.more += int64(max(n-room, 0))
+	} else {
+		b.more += int64(n)
+	}
+	return n, err
+}
+
+func (b *wireBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes(), b.more) })
+	return err
+}
+
+// wireSecretFields are always redacted, in wireFieldKey form.
+var wireSecretFields = map[string]bool{
+	"apikey": true, "authorization": true, "password": true, "secret": true,
+	"clientsecret": true, "token": true, "accesstoken": true, "refreshtoken": true,
+	"privatekey": true, "xapikey": true, "xgoogapikey": true,
+}
+
+// wireSecretPattern matches provider keys and bearer tokens in free text.
+var wireSecretPattern = regexp.MustCompile(
+	`\b(?:sk-(?:ant-|proj-)?[A-Za-z0-9_-]{16,}|AIza[0-9A-Za-z_-]{35}|AKIA[0-9A-Z]{16}|hf_[A-Za-z0-9]{30,})` +
+		`|(?i:bearer)\s+[A-Za-z0-9._~+/=-]{16,}`)
+
+func wireFieldKey(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+func (w *wireLogger) secret(name string) bool {
+	k := wireFieldKey(name)
+	return wireSecretFields[k] || w.fields[k]
+}
+
+// redactURL hides secret query parameters, like Gemini's ?key=.
+func (w *wireLogger) redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	if q := c.Query(); len(q) > 0 {
+		for k := range q {
+			if k == "key" || w.secret(k) {
+				q.Set(k, "REDACTED")
+			}
+		}
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// wireUnparseable replaces a body that looks like JSON but can't be
+// parsed when WireRedactFields is set, since the fields can't be found.
+const wireUnparseable = "[unparseable body redacted]"
+
+// redactText redacts a body: as JSON if it is JSON, line by line for
+// server-sent events, and by pattern otherwise.
+func (w *wireLogger) redactText(s string) string {
+	t := strings.TrimSpace(s)
+	if strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		var tree any
+		if json.Unmarshal([]byte(t), &tree) == nil {
+			if data, err := json.Marshal(w.redact(tree)); err == nil {
+				return string(data)
+			}
+		}
+		if len(w.fields) > 0 {
+			return wireUnparseable
+		}
+	}
+	if strings.Contains(s, "data: {") {
+		lines := strings.Split(s, "\n")
+		for i, l := range lines {
+			if rest, ok := strings.CutPrefix(l, "data: "); ok {
+				lines[i] = "data: " + w.redactText(rest)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return wireSecretPattern.ReplaceAllString(s, "[REDACTED]")
+}
+
+func (w *wireLogger) redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if w.secret(k) && e != nil && e != "" {
+				v[k] = "[REDACTED]"
+				continue
+			}
+			v[k] = w.redact(e)
+		}
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = w.redact(e)
+		}
+		return v
+	case string:
+		return w.redactText(v)
+	default:
+		return v
+	}
+}
@@ -9,17 +9,21 @@ import (
 	"time"
 
 	"go-agent-sdk/agent"
+	"go-agent-sdk/llm"
+	"go-agent-sdk/llm/demo"
 	"go-agent-sdk/llm/openai"
+	"go-agent-sdk/tools/weather"
 	// "go-agent-sdk/llm/anthropic"
 	// "go-agent-sdk/llm/gemini"
 )
 
 // WeatherArgs defines what the LLM needs to provide to get weather info.
 type WeatherArgs struct {
-	City string `json:"city" description:"The city to get weather for"`
+	Location string `json:"location" description:"The city to get weather for"`
 }
 
-// GetWeather is a mock weather tool - in a real app this would call a weather API.
+// GetWeather is a mock weather tool. Set LIVE_WEATHER=1 to use the real
+// one from tools/weather instead, which calls Open-Meteo and needs no key.
 func GetWeather(args WeatherArgs) string {
 	weather := map[string]string{
 		"paris":    "Sunny, 22C",
@@ -29,11 +33,11 @@ func GetWeather(args WeatherArgs) string {
 		"mumbai":   "Humid, 33C",
 	}
 
-	city := strings.ToLower(args.City)
+	city := strings.ToLower(args.Location)
 	if w, ok := weather[city]; ok {
-		return fmt.Sprintf("Weather in %s: %s", args.City, w)
+		return fmt.Sprintf("Weather in %s: %s", args.Location, w)
 	}
-	return fmt.Sprintf("Weather in %s: No data available", args.City)
+	return fmt.Sprintf("Weather in %s: No data available", args.Location)
 }
 
 // CalculatorArgs defines parameters for basic math operations.
@@ -64,14 +68,29 @@ func Calculate(args CalculatorArgs) string {
 	return fmt.Sprintf("%.2f %s %.2f = %.2f", args.A, args.Operation, args.B, result)
 }
 
+// demoProvider scripts answers for the four examples below so the demo runs
+// offline when no API key is set.
+func demoProvider() llm.ChatProvider {
+	return demo.New([]demo.Rule{
+		{Match: "programming language", Reply: "Go is a statically typed, compiled language from Google built for simple, reliable and efficient software."},
+		{Match: "weather", Tool: "get_weather", Args: `{"location":"Paris"}`, Reply: "{result} - a lovely day to be outside!"},
+		{Match: "my name is", Reply: "Nice to meet you, Parth! I'll remember that."},
+		{Match: "my name", Reply: "Your name is Parth."},
+		{Match: "multiplied", Tool: "calculator", Args: `{"operation":"multiply","a":1337,"b":42}`, Reply: "The answer: {result}"},
+	})
+}
+
 func main() {
+	var provider llm.ChatProvider
+
 	apiKey := os.Getenv("OPENROUTER_API_KEY")
 	if apiKey == "" {
-		log.Fatal("Set OPENROUTER_API_KEY environment variable")
+		fmt.Println("OPENROUTER_API_KEY not set - running against the offline demo provider.")
+		provider = demoProvider()
+	} else {
+		// Pick your provider (uncomment one). See README for the full list.
+		provider = openai.NewOpenRouter(apiKey, "google/gemini-3-flash-preview")
 	}
-
-	// Pick your provider (uncomment one). See README for the full list.
-	provider := openai.NewOpenRouter(apiKey, "google/gemini-3-flash-preview")
 	// provider := openai.New(os.Getenv("OPENAI_API_KEY"), "gpt-4o")
 	// provider := anthropic.New(os.Getenv("ANTHROPIC_API_KEY"), "claude-sonnet-4-20250514")
 	// provider := gemini.New(os.Getenv("GEMINI_API_KEY"), "gemini-2.5-flash")
@@ -104,7 +123,13 @@ func main() {
 		agent.WithSystemPrompts("You are a helpful assistant with access to weather data and a calculator. Use them when needed."),
 	)
 
-	toolAgent.RegisterTool("get_weather", "Get current weather for a city", GetWeather)
+	if os.Getenv("LIVE_WEATHER") != "" {
+		if err := toolAgent.RegisterToolset(weather.Toolset()); err != nil {
+			log.Fatalf("Weather tools failed: %v", err)
+		}
+	} else {
+		toolAgent.RegisterTool("get_weather", "Get current weather for a city", GetWeather)
+	}
 	toolAgent.RegisterTool("calculator", "Perform basic math operations", Calculate)
 
 	reply, err = toolAgent.Run(ctx, "What's the weather like in Paris right now?")
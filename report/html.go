@@ -0,0 +1,195 @@
+package report
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const baseCSS = `body{font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;line-height:1.5;color:#222;max-width:46em;margin:2em auto;padding:0 1em}
+header{border-bottom:1px solid #ddd;margin-bottom:1.5em}header h1{margin-bottom:.2em}.meta{color:#666;margin-top:0}
+pre{background:#f6f8fa;padding:.8em;overflow-x:auto}code{font-family:Menlo,Consolas,monospace;font-size:.9em}
+blockquote{border-left:4px solid #ddd;margin-left:0;padding-left:1em;color:#555}
+table{border-collapse:collapse;margin:1em 0}th,td{border:1px solid #ccc;padding:.3em .6em;text-align:left}th{background:#f3f3f3}
+figure{margin:1.5em 0;text-align:center}figure img{max-width:100%}figcaption{color:#555;font-size:.9em}
+@media print{body{max-width:none;margin:0}}`
+
+// HTML writes r as a standalone HTML page. Artifacts are embedded as data
+// URLs, so the file can be mailed or archived on its own.
+func HTML(w io.Writer, r Report, opts ...Option) error {
+	c := newConfig(opts)
+	bw := bufio.NewWriter(w)
+	title := html.EscapeString(r.Title)
+
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n%s\n</style>\n</head>\n<body>\n", title, baseCSS, c.css)
+	if r.Title != "" || r.Author != "" || !r.Date.IsZero() {
+		bw.WriteString("<header>\n")
+		if r.Title != "" {
+			fmt.Fprintf(bw, "<h1>%s</h1>\n", title)
+		}
+		if meta := r.meta(); meta != "" {
+			fmt.Fprintf(bw, "<p class=\"meta\">%s</p>\n", html.EscapeString(meta))
+		}
+		bw.WriteString("</header>\n")
+	}
+
+	blocks := parse(r.Body)
+	var lists []string // open list tags, innermost last
+	closeLists := func(depth int) {
+		for len(lists) > depth {
+			fmt.Fprintf(bw, "</%s>\n", lists[len(lists)-1])
+			lists = lists[:len(lists)-1]
+		}
+	}
+	for _, b := range blocks {
+		if b.kind != listItem {
+			closeLists(0)
+		}
+		switch b.kind {
+		case paragraph:
+			fmt.Fprintf(bw, "<p>%s</p>\n", r.inlineHTML(b.spans))
+		case heading:
+			fmt.Fprintf(bw, "<h%d>%s</h%d>\n", b.level, r.inlineHTML(b.spans), b.level)
+		case listItem:
+			tag := "ul"
+			if b.ordered {
+				tag = "ol"
+			}
+			closeLists(b.level + 1)
+			if len(lists) == b.level+1 && lists[b.level] != tag {
+				closeLists(b.level)
+			}
+			for len(lists) <= b.level {
+				if tag == "ol" && b.number != 1 {
+					fmt.Fprintf(bw, "<ol start=\"%d\">\n", b.number)
+				} else {
+					fmt.Fprintf(bw, "<%s>\n", tag)
+				}
+				lists = append(lists, tag)
+			}
+			fmt.Fprintf(bw, "<li>%s</li>\n", r.inlineHTML(b.spans))
+		case codeBlock:
+			class := ""
+			if b.lang != "" {
+				class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(b.lang))
+			}
+			fmt.Fprintf(bw, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(b.code))
+		case quote:
+			fmt.Fprintf(bw, "<blockquote><p>%s</p></blockquote>\n", r.inlineHTML(b.spans))
+		case rule:
+			bw.WriteString("<hr>\n")
+		case table:
+			bw.WriteString("<table>\n")
+			for i, row := range b.rows {
+				cell := "td"
+				if i == 0 {
+					cell = "th"
+				}
+				bw.WriteString("<tr>")
+				for _, c := range row {
+					fmt.Fprintf(bw, "<%s>%s</%s>", cell, r.inlineHTML(c), cell)
+				}
+				bw.WriteString("</tr>\n")
+			}
+			bw.WriteString("</table>\n")
+		case figure:
+			src := b.src
+			if a, ok := r.artifact(b.src); ok {
+				src = dataURL(a)
+			} else if !safeURL(src) {
+				src = ""
+			}
+			fmt.Fprintf(bw, "<figure><img src=\"%s\" alt=\"%s\">", html.EscapeString(src), html.EscapeString(b.alt))
+			if b.alt != "" {
+				fmt.Fprintf(bw, "<figcaption>%s</figcaption>", html.EscapeString(b.alt))
+			}
+			bw.WriteString("</figure>\n")
+		}
+	}
+	closeLists(0)
+	bw.WriteString("</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// inlineHTML renders spans. Links to artifacts become downloads of the
+// embedded file.
+func (r *Report) inlineHTML(spans []span) string {
+	var b strings.Builder
+	for i := 0; i < len(spans); i++ {
+		s := spans[i]
+		if s.link != "" {
+			// Consecutive spans with the same target make one link.
+			j := i
+			for j+1 < len(spans) && spans[j+1].link == s.link {
+				j++
+			}
+			href, download := s.link, ""
+			if a, ok := r.artifact(s.link); ok {
+				href, download = dataURL(a), fmt.Sprintf(" download=\"%s\"", html.EscapeString(a.Name))
+			} else if !safeURL(s.link) {
+				href = "#"
+			}
+			fmt.Fprintf(&b, "<a href=\"%s\"%s>", html.EscapeString(href), download)
+			for _, t := range spans[i : j+1] {
+				b.WriteString(formatHTML(t))
+			}
+			b.WriteString("</a>")
+			i = j
+			continue
+		}
+		b.WriteString(formatHTML(s))
+	}
+	return b.String()
+}
+
+func formatHTML(s span) string {
+	text := html.EscapeString(s.text)
+	switch {
+	case s.code:
+		return "<code>" + text + "</code>"
+	case s.bold && s.italic:
+		return "<strong><em>" + text + "</em></strong>"
+	case s.bold:
+		return "<strong>" + text + "</strong>"
+	case s.italic:
+		return "<em>" + text + "</em>"
+	}
+	return text
+}
+
+// safeURL keeps model-written links from running script when the report
+// is opened.
+func safeURL(u string) bool {
+	scheme, _, ok := strings.Cut(u, ":")
+	if !ok || strings.ContainsAny(scheme, "/?#") {
+		return true // relative
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func dataURL(a Artifact) string {
+	mime := a.MIMEType
+	if mime == "" {
+		mime = "application/octet-stream"
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// meta is the line under the title.
+func (r *Report) meta() string {
+	var parts []string
+	if r.Author != "" {
+		parts = append(parts, r.Author)
+	}
+	if !r.Date.IsZero() {
+		parts = append(parts, r.Date.Format("2 January 2006"))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,292 @@
+package report
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The Markdown agents write in reports is a small part of the language:
+// headings, paragraphs, lists, code blocks, quotes, rules, tables and
+// images, with emphasis, code and links inside. That is what parse
+// understands; anything else comes through as text.
+
+type blockKind int
+
+const (
+	paragraph blockKind = iota
+	heading
+	listItem
+	codeBlock
+	quote
+	rule
+	table
+	figure
+)
+
+type block struct {
+	kind    blockKind
+	level   int    // heading level; list nesting depth
+	ordered bool   // numbered list item
+	number  int    // its number
+	spans   []span // inline content
+	code    string // code block text
+	lang    string // code block language
+	rows    [][][]span
+	src     string // figure image source
+	alt     string // figure caption
+}
+
+type span struct {
+	text   string
+	bold   bool
+	italic bool
+	code   bool
+	link   string
+}
+
+var (
+	headingRE  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletRE   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	numberedRE = regexp.MustCompile(`^(\s*)(\d{1,9})[.)]\s+(.*)$`)
+	ruleRE     = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	tableSepRE = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	figureRE   = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)$`)
+)
+
+// parse splits Markdown into blocks.
+func parse(md string) []block {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	var out []block
+	var para []string
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		text := strings.Join(para, " ")
+		para = nil
+		if m := figureRE.FindStringSubmatch(text); m != nil {
+			out = append(out, block{kind: figure, alt: m[1], src: m[2]})
+			return
+		}
+		out = append(out, block{kind: paragraph, spans: parseInline(text)})
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			lang := strings.TrimSpace(trimmed[3:])
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			out = append(out, block{kind: codeBlock, code: strings.Join(code, "\n"), lang: lang})
+
+		case headingRE.MatchString(trimmed):
+			flush()
+			m := headingRE.FindStringSubmatch(trimmed)
+			out = append(out, block{kind: heading, level: len(m[1]), spans: parseInline(m[2])})
+
+		case ruleRE.MatchString(line):
+			flush()
+			out = append(out, block{kind: rule})
+
+		case bulletRE.MatchString(line):
+			flush()
+			m := bulletRE.FindStringSubmatch(line)
+			out = append(out, block{kind: listItem, level: indent(m[1]), spans: parseInline(m[2])})
+
+		case numberedRE.MatchString(line):
+			flush()
+			m := numberedRE.FindStringSubmatch(line)
+			n := 0
+			for _, c := range m[2] {
+				n = n*10 + int(c-'0')
+			}
+			out = append(out, block{kind: listItem, level: indent(m[1]), ordered: true, number: n, spans: parseInline(m[3])})
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var q []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q = append(q, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			out = append(out, block{kind: quote, spans: parseInline(strings.Join(q, " "))})
+
+		case strings.Contains(trimmed, "|") && i+1 < len(lines) && tableSepRE.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			flush()
+			rows := [][][]span{cells(trimmed)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				rows = append(rows, cells(strings.TrimSpace(lines[i])))
+			}
+			i--
+			out = append(out, block{kind: table, rows: rows})
+
+		default:
+			// A line following a list item without a blank line in between
+			// continues the item.
+			if len(para) == 0 && len(out) > 0 && out[len(out)-1].kind == listItem && i > 0 && strings.TrimSpace(lines[i-1]) != "" {
+				last := &out[len(out)-1]
+				last.spans = append(last.spans, span{text: " "})
+				last.spans = append(last.spans, parseInline(trimmed)...)
+				continue
+			}
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+	return out
+}
+
+// indent turns leading whitespace into a list nesting depth.
+func indent(ws string) int {
+	n := 0
+	for _, c := range ws {
+		if c == '\t' {
+			n += 4
+		} else {
+			n++
+		}
+	}
+	return n / 2
+}
+
+// cells splits a table row.
+func cells(row string) [][]span {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var out [][]span
+	for _, c := range splitUnescaped(row, '|') {
+		out = append(out, parseInline(strings.TrimSpace(c)))
+	}
+	return out
+}
+
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseInline splits text into runs of the same formatting.
+func parseInline(text string) []span {
+	var out []span
+	var cur strings.Builder
+	var bold, italic bool
+	emit := func(s span) {
+		if cur.Len() > 0 {
+			out = append(out, span{text: cur.String(), bold: bold, italic: italic})
+			cur.Reset()
+		}
+		if s.text != "" {
+			out = append(out, s)
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_{}[]()#+-.!|>~", text[i+1]) >= 0:
+			i++
+			cur.WriteByte(text[i])
+
+		case c == '`':
+			end := strings.IndexByte(text[i+1:], '`')
+			if end < 0 {
+				cur.WriteByte(c)
+				continue
+			}
+			emit(span{text: text[i+1 : i+1+end], code: true})
+			i += end + 1
+
+		case (c == '*' || c == '_') && i+1 < len(text) && text[i+1] == c:
+			emit(span{})
+			bold = !bold
+			i++
+
+		case c == '*' || (c == '_' && (i == 0 || !isWordByte(text[i-1]) || i+1 == len(text) || !isWordByte(text[i+1]))):
+			emit(span{})
+			italic = !italic
+
+		case c == '[' || (c == '!' && i+1 < len(text) && text[i+1] == '['):
+			image := c == '!'
+			start := i
+			if image {
+				start++
+			}
+			label, url, n, ok := linkAt(text[start:])
+			if !ok {
+				cur.WriteByte(c)
+				continue
+			}
+			emit(span{})
+			for _, s := range parseInline(label) {
+				s.bold, s.italic = s.bold || bold, s.italic || italic
+				s.link = url
+				out = append(out, s)
+			}
+			i = start + n - 1
+
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	emit(span{})
+	return out
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// linkAt reads "[label](url)" at the start of s, returning its length.
+func linkAt(s string) (label, url string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				if i+1 >= len(s) || s[i+1] != '(' {
+					return "", "", 0, false
+				}
+				end := strings.IndexByte(s[i+2:], ')')
+				if end < 0 {
+					return "", "", 0, false
+				}
+				target := strings.TrimSpace(s[i+2 : i+2+end])
+				if sp := strings.IndexAny(target, " \t"); sp >= 0 {
+					target = target[:sp] // drop a "title"
+				}
+				return s[1:i], strings.Trim(target, "<>"), i + 3 + end, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// plain returns spans' text without formatting.
+func plain(spans []span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.text)
+	}
+	return b.String()
+}
@@ -0,0 +1,719 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registers GIF for image.Decode
+	"image/jpeg"
+	_ "image/png" // registers PNG for image.Decode
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF writes r as a PDF. It uses the fonts every PDF reader has
+// (Helvetica and Courier), which cover Western European text; characters
+// outside it print as "?".
+func PDF(w io.Writer, r Report, opts ...Option) error {
+	c := newConfig(opts)
+	l := &layout{
+		r:      &r,
+		w:      c.pageWidth,
+		h:      c.pageHeight,
+		margin: c.margin,
+		images: map[string]int{},
+	}
+	l.newPage()
+
+	if r.Title != "" {
+		l.text([]span{{text: r.Title, bold: true}}, 22, 0, 0)
+	}
+	if meta := r.meta(); meta != "" {
+		l.gap(2)
+		l.color = "0.4 0.4 0.4"
+		l.text([]span{{text: meta}}, 10, 0, 0)
+		l.color = ""
+	}
+	if r.Title != "" || r.Author != "" || !r.Date.IsZero() {
+		l.gap(6)
+		l.hline(l.margin, l.w-l.margin, 0.8)
+		l.gap(14)
+	}
+
+	for _, b := range parse(r.Body) {
+		l.block(b)
+	}
+	return l.write(w)
+}
+
+// Layout of the page, in points. y runs down from the top of the page;
+// the PDF's coordinates run up from the bottom, and the drawing
+// methods convert.
+const (
+	bodySize   = 10.5
+	codeSize   = 9
+	leading    = 1.4 // line height as a multiple of the font size
+	listIndent = 16
+)
+
+var headingSizes = [7]float64{0, 20, 16, 13, 11.5, 10.5, 10.5}
+
+// The standard fonts, as numbered in the page resources.
+const (
+	fontRegular = iota + 1
+	fontBold
+	fontItalic
+	fontBoldItalic
+	fontMono
+)
+
+var fontNames = [...]string{"", "Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique", "Courier"}
+
+type page struct {
+	content bytes.Buffer
+	annots  []string
+}
+
+type pdfImage struct {
+	width, height int
+	colorSpace    string
+	filter        string
+	data          []byte
+}
+
+type layout struct {
+	r            *Report
+	w, h, margin float64
+	pages        []*page
+	y            float64
+	color        string         // fill colour for text, "" for black
+	images       map[string]int // artifact name -> index in imgs
+	imgs         []pdfImage
+}
+
+func (l *layout) cur() *page { return l.pages[len(l.pages)-1] }
+
+func (l *layout) newPage() {
+	l.pages = append(l.pages, &page{})
+	l.y = l.margin
+}
+
+// need starts a new page unless height fits below the cursor.
+func (l *layout) need(height float64) {
+	if l.y+height > l.h-l.margin && l.y > l.margin {
+		l.newPage()
+	}
+}
+
+func (l *layout) gap(height float64) {
+	l.y += height
+}
+
+func (l *layout) block(b block) {
+	switch b.kind {
+	case paragraph:
+		l.text(b.spans, bodySize, 0, 0)
+		l.gap(bodySize * 0.8)
+
+	case heading:
+		size := headingSizes[b.level]
+		l.gap(size * 0.5)
+		l.need(size*leading*2 + bodySize*leading) // keep it with the next lines
+		spans := make([]span, len(b.spans))
+		for i, s := range b.spans {
+			s.bold = true
+			spans[i] = s
+		}
+		l.text(spans, size, 0, 0)
+		l.gap(size * 0.4)
+
+	case listItem:
+		indent := float64(b.level+1) * listIndent
+		marker := "•"
+		if b.ordered {
+			marker = fmt.Sprintf("%d.", b.number)
+		}
+		l.need(bodySize * leading)
+		pg, markerY := l.cur(), l.y
+		l.drawText(pg, l.margin+indent-listIndent+2, markerY+bodySize, fontRegular, bodySize, encode(marker))
+		l.text(b.spans, bodySize, indent, 0)
+		l.gap(bodySize * 0.3)
+
+	case codeBlock:
+		lines := strings.Split(strings.ReplaceAll(b.code, "\t", "    "), "\n")
+		perLine := int((l.w - 2*l.margin - 12) / (codeSize * 0.6))
+		var wrapped []string
+		for _, line := range lines {
+			runes := []rune(line)
+			for len(runes) > perLine {
+				wrapped = append(wrapped, string(runes[:perLine]))
+				runes = runes[perLine:]
+			}
+			wrapped = append(wrapped, string(runes))
+		}
+		lh := codeSize * leading
+		for i := 0; i < len(wrapped); {
+			l.need(lh + 8)
+			// As many lines as fit on this page, on one shaded box.
+			n := int((l.h - l.margin - l.y - 8) / lh)
+			n = max(1, min(n, len(wrapped)-i))
+			l.rect(l.margin, l.y, l.w-2*l.margin, float64(n)*lh+8, "0.96 0.97 0.98")
+			l.y += 4
+			for _, line := range wrapped[i : i+n] {
+				l.drawText(l.cur(), l.margin+6, l.y+codeSize, fontMono, codeSize, encode(line))
+				l.y += lh
+			}
+			l.y += 4
+			i += n
+		}
+		l.gap(bodySize * 0.8)
+
+	case quote:
+		top, pg := l.y, len(l.pages)
+		l.color = "0.33 0.33 0.33"
+		l.text(b.spans, bodySize, 14, 0)
+		l.color = ""
+		if pg != len(l.pages) {
+			top = l.margin
+		}
+		l.vline(l.margin+3, top, l.y, 3, "0.85 0.85 0.85")
+		l.gap(bodySize * 0.8)
+
+	case rule:
+		l.need(12)
+		l.gap(4)
+		l.hline(l.margin, l.w-l.margin, 0.5)
+		l.gap(10)
+
+	case table:
+		l.table(b.rows)
+		l.gap(bodySize * 0.8)
+
+	case figure:
+		l.figure(b)
+		l.gap(bodySize * 0.8)
+	}
+}
+
+// piece is a run of text in one font, part of a word.
+type piece struct {
+	text  []byte // WinAnsi
+	font  int
+	link  string
+	width float64
+}
+
+type word struct {
+	pieces []piece
+	width  float64
+}
+
+// words breaks spans into words for wrapping.
+func words(spans []span, size float64) []word {
+	var out []word
+	inWord := false
+	for _, s := range spans {
+		font := fontFor(s)
+		for _, c := range s.text {
+			if c == ' ' || c == '\t' || c == '\n' {
+				inWord = false
+				continue
+			}
+			if !inWord {
+				out = append(out, word{})
+				inWord = true
+			}
+			w := &out[len(out)-1]
+			if n := len(w.pieces); n == 0 || w.pieces[n-1].font != font || w.pieces[n-1].link != s.link {
+				w.pieces = append(w.pieces, piece{font: font, link: s.link})
+			}
+			p := &w.pieces[len(w.pieces)-1]
+			b := encodeRune(c)
+			cw := charWidth(font, b) * size / 1000
+			p.text = append(p.text, b)
+			p.width += cw
+			w.width += cw
+		}
+	}
+	return out
+}
+
+func fontFor(s span) int {
+	switch {
+	case s.code:
+		return fontMono
+	case s.bold && s.italic:
+		return fontBoldItalic
+	case s.bold:
+		return fontBold
+	case s.italic:
+		return fontItalic
+	}
+	return fontRegular
+}
+
+// text lays out spans as a wrapped paragraph, indent points in from the
+// left margin and right points in from the right one.
+func (l *layout) text(spans []span, size, indent, right float64) {
+	l.flow(spans, size, l.margin+indent, l.w-l.margin-right, func(lh float64) { l.need(lh) })
+}
+
+// flow wraps spans between x0 and x1 from the cursor down, calling
+// before for each line so the caller can break the page.
+func (l *layout) flow(spans []span, size, x0, x1 float64, before func(lineHeight float64)) {
+	lh := size * leading
+	space := charWidth(fontRegular, ' ') * size / 1000
+	ws := words(spans, size)
+	for len(ws) > 0 {
+		// Fill the line; a word too long for any line is split.
+		n, width := 0, 0.0
+		for n < len(ws) {
+			add := ws[n].width
+			if n > 0 {
+				add += space
+			}
+			if width+add > x1-x0 && n > 0 {
+				break
+			}
+			width += add
+			n++
+		}
+		if n == 1 && ws[0].width > x1-x0 {
+			head, tail := splitWord(ws[0], x1-x0)
+			ws = append([]word{head, tail}, ws[1:]...)
+		}
+
+		before(lh)
+		pg := l.cur()
+		x, baseline := x0, l.y+size
+		// One text object per line, positioned word by word; the pieces of
+		// a word follow each other.
+		pg.content.WriteString("BT\n")
+		for _, w := range ws[:n] {
+			fmt.Fprintf(&pg.content, "1 0 0 1 %.2f %.2f Tm", x, l.h-baseline)
+			for _, p := range w.pieces {
+				fill := "0 g"
+				if l.color != "" {
+					fill = l.color + " rg"
+				}
+				if p.link != "" {
+					fill = "0 0.27 0.6 rg"
+					l.link(pg, x, baseline-size, p.width, lh, p.link)
+				}
+				fmt.Fprintf(&pg.content, " %s /F%d %.2f Tf (%s) Tj", fill, p.font, size, escapePDF(p.text))
+				x += p.width
+			}
+			pg.content.WriteString("\n")
+			x += space
+		}
+		pg.content.WriteString("0 g ET\n")
+		l.y += lh
+		ws = ws[n:]
+	}
+}
+
+// splitWord cuts a word to fit width, keeping at least one character in
+// the head.
+func splitWord(w word, width float64) (word, word) {
+	var head, tail word
+	for _, p := range w.pieces {
+		cw := p.width / float64(len(p.text))
+		for i, c := range p.text {
+			dst := &head
+			if len(tail.pieces) > 0 || (head.width+cw > width && head.width > 0) {
+				dst = &tail
+			}
+			if n := len(dst.pieces); n == 0 || dst.pieces[n-1].font != p.font || dst.pieces[n-1].link != p.link || (dst == &tail && i == 0) {
+				dst.pieces = append(dst.pieces, piece{font: p.font, link: p.link})
+			}
+			last := &dst.pieces[len(dst.pieces)-1]
+			last.text = append(last.text, c)
+			last.width += cw
+			dst.width += cw
+		}
+	}
+	return head, tail
+}
+
+// table draws rows as a grid with equal columns and a bold header row.
+func (l *layout) table(rows [][][]span) {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return
+	}
+	const pad = 4
+	colWidth := (l.w - 2*l.margin) / float64(cols)
+	for i, row := range rows {
+		cellSpans := make([][]span, cols)
+		for j := range cols {
+			if j < len(row) {
+				cellSpans[j] = row[j]
+			}
+			if i == 0 {
+				bold := make([]span, len(cellSpans[j]))
+				for k, s := range cellSpans[j] {
+					s.bold = true
+					bold[k] = s
+				}
+				cellSpans[j] = bold
+			}
+		}
+
+		// Measure the row by laying it out on a scratch page.
+		height := 0.0
+		for j := range cols {
+			height = max(height, l.measure(cellSpans[j], bodySize, colWidth-2*pad))
+		}
+		height += 2 * pad
+		l.need(height)
+
+		top := l.y
+		if i == 0 {
+			l.rect(l.margin, top, colWidth*float64(cols), height, "0.95 0.95 0.95")
+		}
+		for j := range cols {
+			x := l.margin + float64(j)*colWidth
+			l.y = top + pad
+			l.flow(cellSpans[j], bodySize, x+pad, x+colWidth-pad, func(float64) {})
+			l.strokeRect(x, top, colWidth, height)
+		}
+		l.y = top + height
+	}
+}
+
+// measure returns the height spans take wrapped to width.
+func (l *layout) measure(spans []span, size, width float64) float64 {
+	scratch := &layout{r: l.r, w: width, h: 1e9, pages: []*page{{}}}
+	scratch.flow(spans, size, 0, width, func(float64) {})
+	return scratch.y
+}
+
+// figure draws an embedded image, scaled to fit, with its caption.
+func (l *layout) figure(b block) {
+	a, found := l.r.artifact(b.src)
+	idx, ok := -1, false
+	if found {
+		idx, ok = l.image(a)
+	}
+	if !ok {
+		note := b.alt
+		switch {
+		case found && note != "":
+			note = fmt.Sprintf("[%s: %s is in the HTML version of this report]", note, a.Name)
+		case found:
+			note = fmt.Sprintf("[%s is in the HTML version of this report]", a.Name)
+		default:
+			note = fmt.Sprintf("[image: %s]", firstNonEmpty(b.alt, b.src))
+		}
+		l.color = "0.4 0.4 0.4"
+		l.text([]span{{text: note, italic: true}}, bodySize, 0, 0)
+		l.color = ""
+		return
+	}
+
+	img := l.imgs[idx]
+	maxW, maxH := l.w-2*l.margin, (l.h-2*l.margin)*0.6
+	scale := min(1, maxW/float64(img.width), maxH/float64(img.height))
+	w, h := float64(img.width)*scale, float64(img.height)*scale
+	l.need(h + bodySize*leading)
+	x := l.margin + (maxW-w)/2
+	fmt.Fprintf(&l.cur().content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, l.h-l.y-h, idx+1)
+	l.y += h + 4
+	if b.alt != "" {
+		width := 0.0
+		for i, cw := range words([]span{{text: b.alt, italic: true}}, 9) {
+			if i > 0 {
+				width += charWidth(fontRegular, ' ') * 9 / 1000
+			}
+			width += cw.width
+		}
+		indent := max(0, (maxW-width)/2)
+		l.color = "0.33 0.33 0.33"
+		l.text([]span{{text: b.alt, italic: true}}, 9, indent, 0)
+		l.color = ""
+	}
+}
+
+// image adds an artifact as a PDF image, once, and returns its index.
+// JPEG files are embedded as they are; other raster formats are decoded
+// and stored as compressed RGB on white.
+func (l *layout) image(a Artifact) (int, bool) {
+	if i, ok := l.images[a.Name]; ok {
+		return i, true
+	}
+	var img pdfImage
+	if cfg, err := jpeg.DecodeConfig(bytes.NewReader(a.Data)); err == nil && cfg.ColorModel != color.CMYKModel {
+		img = pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: "DeviceRGB", filter: "DCTDecode", data: a.Data}
+		if cfg.ColorModel == color.GrayModel {
+			img.colorSpace = "DeviceGray"
+		}
+	} else {
+		m, _, err := image.Decode(bytes.NewReader(a.Data))
+		if err != nil {
+			return -1, false
+		}
+		bounds := m.Bounds()
+		raw := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, alpha := m.At(x, y).RGBA()
+				// Premultiplied, so adding the missing white composites.
+				white := 0xffff - alpha
+				raw = append(raw, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+			}
+		}
+		img = pdfImage{width: bounds.Dx(), height: bounds.Dy(), colorSpace: "DeviceRGB", filter: "FlateDecode", data: deflate(raw)}
+	}
+	l.imgs = append(l.imgs, img)
+	l.images[a.Name] = len(l.imgs) - 1
+	return len(l.imgs) - 1, true
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Drawing, in layout coordinates.
+
+func (l *layout) drawText(pg *page, x, baseline float64, font int, size float64, text []byte) {
+	fmt.Fprintf(&pg.content, "BT /F%d %.2f Tf 1 0 0 1 %.2f %.2f Tm (%s) Tj ET\n", font, size, x, l.h-baseline, escapePDF(text))
+}
+
+func (l *layout) link(pg *page, x, top, width, height float64, uri string) {
+	if !safeURL(uri) {
+		return
+	}
+	pg.annots = append(pg.annots, fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] /A << /S /URI /URI (%s) >> >>",
+		x, l.h-top-height, x+width, l.h-top, escapePDF([]byte(uri))))
+}
+
+func (l *layout) hline(x0, x1, width float64) {
+	fmt.Fprintf(&l.cur().content, "0.8 G %.2f w %.2f %.2f m %.2f %.2f l S 0 G\n", width, x0, l.h-l.y, x1, l.h-l.y)
+}
+
+func (l *layout) vline(x, top, bottom, width float64, rgb string) {
+	fmt.Fprintf(&l.cur().content, "%s RG %.2f w %.2f %.2f m %.2f %.2f l S 0 G\n", rgb, width, x, l.h-top, x, l.h-bottom)
+}
+
+func (l *layout) rect(x, top, width, height float64, rgb string) {
+	fmt.Fprintf(&l.cur().content, "%s rg %.2f %.2f %.2f %.2f re f 0 g\n", rgb, x, l.h-top-height, width, height)
+}
+
+func (l *layout) strokeRect(x, top, width, height float64) {
+	fmt.Fprintf(&l.cur().content, "0.8 G 0.5 w %.2f %.2f %.2f %.2f re S 0 G\n", x, l.h-top-height, width, height)
+}
+
+// write assembles the document: catalog, page tree, fonts, images, then
+// each page with its content and footer.
+func (l *layout) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var offsets []int
+	pos := 0
+	out := func(format string, args ...any) {
+		n, _ := fmt.Fprintf(bw, format, args...)
+		pos += n
+	}
+	obj := func(body string) {
+		offsets = append(offsets, pos)
+		out("%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dict string, data []byte) {
+		offsets = append(offsets, pos)
+		out("%d 0 obj\n<< %s /Length %d >>\nstream\n", len(offsets), dict, len(data))
+		n, _ := bw.Write(data)
+		pos += n
+		out("\nendstream\nendobj\n")
+	}
+
+	// Object numbers: 1 catalog, 2 pages, 3 info, then fonts, images, and
+	// two per page (the page and its content).
+	fontBase := 4
+	imageBase := fontBase + len(fontNames) - 1
+	pageBase := imageBase + len(l.imgs)
+
+	out("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(l.pages))
+	for i := range l.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageBase+2*i)
+	}
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(l.pages)))
+
+	info := []string{"/Producer (go-agent-sdk report)"}
+	if l.r.Title != "" {
+		info = append(info, "/Title "+pdfTextString(l.r.Title))
+	}
+	if l.r.Author != "" {
+		info = append(info, "/Author "+pdfTextString(l.r.Author))
+	}
+	if !l.r.Date.IsZero() {
+		info = append(info, "/CreationDate (D:"+l.r.Date.UTC().Format("20060102150405")+"Z)")
+	}
+	obj("<< " + strings.Join(info, " ") + " >>")
+
+	for _, name := range fontNames[1:] {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	var xobjects []string
+	for i, img := range l.imgs {
+		stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s",
+			img.width, img.height, img.colorSpace, img.filter), img.data)
+		xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", i+1, imageBase+i))
+	}
+	var fonts []string
+	for i := range fontNames[1:] {
+		fonts = append(fonts, fmt.Sprintf("/F%d %d 0 R", i+1, fontBase+i))
+	}
+	resources := "/Font << " + strings.Join(fonts, " ") + " >>"
+	if len(xobjects) > 0 {
+		resources += " /XObject << " + strings.Join(xobjects, " ") + " >>"
+	}
+
+	for i, pg := range l.pages {
+		footer := encode(fmt.Sprintf("%d / %d", i+1, len(l.pages)))
+		fw := 0.0
+		for _, c := range footer {
+			fw += charWidth(fontRegular, c) * 8 / 1000
+		}
+		fmt.Fprintf(&pg.content, "0.5 g ")
+		l.drawText(pg, (l.w-fw)/2, l.h-l.margin/2, fontRegular, 8, footer)
+		pg.content.WriteString("0 g\n")
+
+		annots := ""
+		if len(pg.annots) > 0 {
+			annots = " /Annots [" + strings.Join(pg.annots, " ") + "]"
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents %d 0 R%s >>",
+			l.w, l.h, resources, pageBase+2*i+1, annots))
+		stream("/Filter /FlateDecode", deflate(pg.content.Bytes()))
+	}
+
+	xref := pos
+	out("xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		out("%010d 00000 n \n", off)
+	}
+	out("trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return bw.Flush()
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+// escapePDF escapes a literal string's bytes.
+func escapePDF(b []byte) string {
+	var s strings.Builder
+	for _, c := range b {
+		switch c {
+		case '\\', '(', ')':
+			s.WriteByte('\\')
+			s.WriteByte(c)
+		case '\r', '\n':
+			s.WriteByte(' ')
+		default:
+			s.WriteByte(c)
+		}
+	}
+	return s.String()
+}
+
+// pdfTextString encodes metadata text as UTF-16, which PDF readers show
+// in any script.
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// encode converts text to the standard fonts' WinAnsi encoding.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, c := range s {
+		out = append(out, encodeRune(c))
+	}
+	return out
+}
+
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+func encodeRune(c rune) byte {
+	switch {
+	case c == '\t' || c == '\n':
+		return ' '
+	case c >= 0x20 && c < 0x7f, c >= 0xa0 && c <= 0xff:
+		return byte(c)
+	}
+	if b, ok := winAnsiExtra[c]; ok {
+		return b
+	}
+	return '?'
+}
+
+// charWidth is a character's advance in thousandths of the font size,
+// from the standard fonts' metrics.
+func charWidth(font int, c byte) float64 {
+	switch {
+	case font == fontMono:
+		return 600
+	case c >= 0x20 && c < 0x7f:
+		if font == fontBold || font == fontBoldItalic {
+			return float64(helveticaBoldWidths[c-0x20])
+		}
+		return float64(helveticaWidths[c-0x20])
+	case c == 0x95:
+		return 350
+	case c == 0x85, c == 0x89, c == 0x97, c == 0x99:
+		return 1000
+	case c >= 0x91 && c <= 0x94:
+		return 333
+	case c >= 0xc0 && c < 0xe0:
+		return 722
+	}
+	return 556
+}
+
+// Widths of ' ' through '~' in Helvetica and Helvetica-Bold. The oblique
+// faces have the same widths.
+var helveticaWidths = [95]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]uint16{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
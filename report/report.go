@@ -0,0 +1,136 @@
+// Package report turns an agent's final output - Markdown, plus the
+// charts and files its tools produced - into a finished document: a
+// self-contained HTML page or a PDF. It is the last step of a
+// report-writing workflow:
+//
+//	res, err := a.RunWithResult(ctx, "Write the quarterly sales report, with charts")
+//	files, err := report.FromStore(ctx, store, res.ID)
+//
+//	r := report.Report{Title: "Q3 Sales", Date: time.Now(), Body: res.Content, Artifacts: files}
+//	f, _ := os.Create("q3.pdf")
+//	defer f.Close()
+//	err = report.PDF(f, r)
+//
+// The body refers to artifacts like any Markdown image, by name, by
+// "artifact:<id>" reference, or by a URL ending in the artifact's ID -
+// whichever the agent was given:
+//
+//	![Revenue by month](revenue-by-month.svg)
+//
+// HTML embeds every kind of image, SVG charts included. PDF embeds PNG
+// and JPEG images; others, such as SVG, appear as a captioned note,
+// so render to HTML when charts matter.
+package report
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go-agent-sdk/artifacts"
+)
+
+// Report is a document to render.
+type Report struct {
+	Title     string
+	Author    string
+	Date      time.Time // shown under the title unless zero
+	Body      string    // Markdown
+	Artifacts []Artifact
+}
+
+// Artifact is a file the body can show.
+type Artifact struct {
+	ID       string // from an artifacts.Store, if it came from one
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// Option configures rendering.
+type Option func(*config)
+
+type config struct {
+	pageWidth, pageHeight float64 // points
+	margin                float64
+	css                   string
+}
+
+// Page sizes in points, for WithPageSize.
+const (
+	A4Width      = 595.28
+	A4Height     = 841.89
+	LetterWidth  = 612
+	LetterHeight = 792
+)
+
+// WithPageSize sets the PDF page size in points. The default is A4.
+func WithPageSize(width, height float64) Option {
+	return func(c *config) {
+		c.pageWidth, c.pageHeight = width, height
+	}
+}
+
+// WithMargin sets the PDF page margin in points. The default is 56 (about
+// 2 cm).
+func WithMargin(points float64) Option {
+	return func(c *config) {
+		c.margin = points
+	}
+}
+
+// WithCSS adds a style sheet to the HTML report, after the built-in one,
+// for house fonts and colours.
+func WithCSS(css string) Option {
+	return func(c *config) {
+		c.css = css
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{pageWidth: A4Width, pageHeight: A4Height, margin: 56}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// FromStore reads the artifacts a run produced from store, oldest first.
+func FromStore(ctx context.Context, store artifacts.Store, runID string) ([]Artifact, error) {
+	list, err := store.List(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Artifact, 0, len(list))
+	for _, a := range list {
+		_, body, err := store.Get(ctx, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("report: reading artifact %s: %w", a.Name, err)
+		}
+		out = append(out, Artifact{ID: a.ID, Name: a.Name, MIMEType: a.MIMEType, Data: data})
+	}
+	return out, nil
+}
+
+// artifact finds the artifact an image source refers to.
+func (r *Report) artifact(src string) (Artifact, bool) {
+	id := strings.TrimPrefix(src, "artifact:")
+	if u, err := url.Parse(src); err == nil && u.Path != "" {
+		id = path.Base(u.Path)
+	}
+	for _, a := range r.Artifacts {
+		if a.Name == src || (a.ID != "" && a.ID == id) {
+			return a, true
+		}
+	}
+	return Artifact{}, false
+}
@@ -0,0 +1,41 @@
+package tools
+
+import "context"
+
+// ArtifactSaver keeps files tools produce for people rather than for the
+// model - a chart for a report, a CSV export - and returns a reference to
+// each, such as a path or URL, that the model can pass on in its answer.
+type ArtifactSaver interface {
+	SaveArtifact(ctx context.Context, name, mimeType string, data []byte) (ref string, err error)
+}
+
+// ArtifactSaverFunc adapts a function to ArtifactSaver:
+//
+//	saver := tools.ArtifactSaverFunc(func(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+//	    path := filepath.Join("out", name)
+//	    return path, os.WriteFile(path, data, 0o644)
+//	})
+type ArtifactSaverFunc func(ctx context.Context, name, mimeType string, data []byte) (string, error)
+
+// SaveArtifact calls f.
+func (f ArtifactSaverFunc) SaveArtifact(ctx context.Context, name, mimeType string, data []byte) (string, error) {
+	return f(ctx, name, mimeType, data)
+}
+
+// SaveArtifact saves data to saver and records an "artifact.created"
+// Effect for it - the Target is the reference, the Data has the name,
+// MIME type and size - so the agent's RunResult.Artifacts lists it.
+// Tools that produce files call this rather than saver directly.
+func SaveArtifact(ctx context.Context, saver ArtifactSaver, name, mimeType string, data []byte) (string, error) {
+	ref, err := saver.SaveArtifact(ctx, name, mimeType, data)
+	if err != nil {
+		return "", err
+	}
+	RecordEffect(ctx, Effect{
+		Kind:    "artifact.created",
+		Target:  ref,
+		Summary: name,
+		Data:    map[string]any{"name": name, "mime_type": mimeType, "size": len(data)},
+	})
+	return ref, nil
+}
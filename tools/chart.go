@@ -0,0 +1,423 @@
+package tools
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ChartOption configures NewChartTool.
+type ChartOption func(*charter)
+
+type charter struct {
+	store         ArtifactSaver
+	width, height int
+	palette       []string
+}
+
+// ChartSize sets the size of the charts in pixels. The default is 800
+// by 500.
+func ChartSize(width, height int) ChartOption {
+	return func(c *charter) {
+		c.width, c.height = width, height
+	}
+}
+
+// ChartPalette replaces the colors series and slices are drawn in, as
+// CSS colors. They're used in order and repeat when there are more series
+// than colors.
+func ChartPalette(colors ...string) ChartOption {
+	return func(c *charter) {
+		c.palette = colors
+	}
+}
+
+// ChartSeries is one set of values in a chart.
+type ChartSeries struct {
+	Name   string    `json:"name,omitempty" description:"Series name, shown in the legend."`
+	Values []float64 `json:"values" description:"One value per label, in the same order as the labels."`
+}
+
+// ChartArgs are the create_chart tool's arguments.
+type ChartArgs struct {
+	Type     string        `json:"type" enum:"bar,line,pie" description:"bar to compare categories, line for a trend over time, pie for parts of a whole."`
+	Title    string        `json:"title,omitempty" description:"Chart title."`
+	Labels   []string      `json:"labels" description:"Category labels: the x axis of a bar or line chart, the slices of a pie chart."`
+	Series   []ChartSeries `json:"series" description:"The data. A pie chart takes exactly one series."`
+	XLabel   string        `json:"x_label,omitempty" description:"Title of the x axis."`
+	YLabel   string        `json:"y_label,omitempty" description:"Title of the y axis, including the unit."`
+	Filename string        `json:"filename,omitempty" description:"Name for the file, without extension. Made from the title when left out."`
+}
+
+// NewChartTool returns a create_chart tool that draws bar, line and pie
+// charts from data the model supplies and saves them to store as SVG.
+// The model gets back the artifact's reference, to link or mention in
+// its answer - the picture itself is for people, not the model.
+//
+//	charts := tools.NewChartTool(store)
+//	a.RegisterToolset(charts)
+//	reply, _ := a.Run(ctx, "Chart our quarterly revenue: Q1 1.2M, Q2 1.5M, Q3 1.1M, Q4 1.9M")
+//
+// Every chart is recorded as an "artifact.created" Effect (see
+// SaveArtifact).
+//
+// The charts are drawn here rather than with a charting library such as
+// go-chart, because this module takes no dependencies. Three chart types
+// written as SVG are a few hundred lines; a library would pull in its
+// font and raster packages for PNGs the model can't read anyway. If you
+// want other chart types or PNG output, render with whatever you like and
+// save the file with SaveArtifact, the way this tool does.
+func NewChartTool(store ArtifactSaver, opts ...ChartOption) Toolset {
+	c := &charter{
+		store:  store,
+		width:  800,
+		height: 500,
+		palette: []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+			"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return Toolset{
+		Name: "charts",
+		Prompt: "Use create_chart when a picture makes numbers easier to take in: bar charts to compare categories, " +
+			"line charts for trends, pie charts for shares of a total (with few slices). " +
+			"Mention or link the returned reference in your answer so the user can open the chart.",
+		Tools: []Tool{
+			{Name: "create_chart", Description: "Draw a bar, line or pie chart and save it as an image file.", Func: c.create},
+		},
+	}
+}
+
+func (c *charter) create(ctx context.Context, args ChartArgs) (string, error) {
+	if err := checkChart(args); err != nil {
+		return "", err
+	}
+	svg := c.render(args)
+
+	name := slug(args.Filename)
+	if name == "" {
+		name = slug(args.Title)
+	}
+	if name == "" {
+		name = args.Type + "-chart"
+	}
+	name += ".svg"
+	ref, err := SaveArtifact(ctx, c.store, name, "image/svg+xml", svg)
+	if err != nil {
+		return "", fmt.Errorf("saving chart: %w", err)
+	}
+	summary := fmt.Sprintf("%s chart", args.Type)
+	if args.Title != "" {
+		summary += fmt.Sprintf(" %q", args.Title)
+	}
+	return fmt.Sprintf("Created %s (%d labels, %d series): %s", summary, len(args.Labels), len(args.Series), ref), nil
+}
+
+func checkChart(args ChartArgs) error {
+	switch args.Type {
+	case "bar", "line", "pie":
+	default:
+		return fmt.Errorf("unknown chart type %q; use bar, line or pie", args.Type)
+	}
+	if len(args.Labels) == 0 {
+		return errors.New("no labels")
+	}
+	if len(args.Labels) > 200 {
+		return fmt.Errorf("%d labels is too many to read; aggregate the data first", len(args.Labels))
+	}
+	if len(args.Series) == 0 {
+		return errors.New("no series")
+	}
+	if args.Type == "pie" && len(args.Series) != 1 {
+		return fmt.Errorf("a pie chart takes one series, got %d", len(args.Series))
+	}
+	for i, s := range args.Series {
+		if len(s.Values) != len(args.Labels) {
+			return fmt.Errorf("series %d has %d values for %d labels", i+1, len(s.Values), len(args.Labels))
+		}
+		for _, v := range s.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("series %d has a value that isn't a number", i+1)
+			}
+			if args.Type == "pie" && v < 0 {
+				return errors.New("a pie chart can't show negative values")
+			}
+		}
+	}
+	if args.Type == "pie" && sum(args.Series[0].Values) == 0 {
+		return errors.New("a pie chart needs values above zero")
+	}
+	return nil
+}
+
+// svg builds an SVG document.
+type svg struct {
+	strings.Builder
+}
+
+func (s *svg) printf(format string, args ...any) {
+	fmt.Fprintf(s, format, args...)
+}
+
+func (s *svg) text(x, y float64, anchor, extra, text string) {
+	s.printf(`<text x="%.1f" y="%.1f" text-anchor="%s"%s>%s</text>`+"\n", x, y, anchor, extra, escapeXML(text))
+}
+
+func (c *charter) color(i int) string {
+	return c.palette[i%len(c.palette)]
+}
+
+func (c *charter) render(args ChartArgs) []byte {
+	w, h := float64(c.width), float64(c.height)
+	var s svg
+	s.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		c.width, c.height, c.width, c.height)
+	s.printf(`<rect width="100%%" height="100%%" fill="white"/>` + "\n")
+
+	top := 20.0
+	if args.Title != "" {
+		s.text(w/2, 28, "middle", ` font-size="18" font-weight="bold"`, args.Title)
+		top = 50
+	}
+	legend := args.Type == "pie" || len(args.Series) > 1 || args.Series[0].Name != ""
+	right := 20.0
+	if legend {
+		right = 170
+	}
+	box := rect{x: 70, y: top, w: w - 70 - right, h: h - top - 60}
+	if args.YLabel == "" {
+		box.x, box.w = 50, box.w+20
+	}
+
+	if args.Type == "pie" {
+		c.pie(&s, args, box)
+	} else {
+		c.axes(&s, args, box)
+	}
+	if legend {
+		c.legend(&s, args, w-right+20, top+10)
+	}
+	s.printf("</svg>\n")
+	return []byte(s.String())
+}
+
+type rect struct{ x, y, w, h float64 }
+
+// axes draws a bar or line chart.
+func (c *charter) axes(s *svg, args ChartArgs, box rect) {
+	lo, hi := 0.0, 0.0
+	for _, series := range args.Series {
+		for _, v := range series.Values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	ticks, decimals := niceTicks(lo, hi, 6)
+	lo, hi = ticks[0], ticks[len(ticks)-1]
+	yPos := func(v float64) float64 { return box.y + box.h - (v-lo)/(hi-lo)*box.h }
+
+	// Grid and y axis.
+	for _, t := range ticks {
+		y := yPos(t)
+		s.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e0e0e0"/>`+"\n", box.x, y, box.x+box.w, y)
+		s.text(box.x-6, y+4, "end", "", fmt.Sprintf("%.*f", decimals, t))
+	}
+	zero := yPos(0)
+	s.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#333"/>`+"\n", box.x, zero, box.x+box.w, zero)
+	s.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#333"/>`+"\n", box.x, box.y, box.x, box.y+box.h)
+
+	// x labels, turned when they'd overlap.
+	n := len(args.Labels)
+	band := box.w / float64(n)
+	longest := 0
+	for _, l := range args.Labels {
+		longest = max(longest, len([]rune(l)))
+	}
+	turn := float64(longest)*7 > band
+	step := 1
+	if turn && band < 14 {
+		step = int(math.Ceil(14 / band))
+	}
+	for i := 0; i < n; i += step {
+		x := box.x + band*(float64(i)+0.5)
+		y := box.y + box.h + 16
+		if turn {
+			s.text(x, y, "end", fmt.Sprintf(` transform="rotate(-40 %.1f %.1f)"`, x, y), args.Labels[i])
+		} else {
+			s.text(x, y, "middle", "", args.Labels[i])
+		}
+	}
+	if args.XLabel != "" && !turn {
+		s.text(box.x+box.w/2, box.y+box.h+44, "middle", ` font-size="13"`, args.XLabel)
+	}
+	if args.YLabel != "" {
+		x, y := 16.0, box.y+box.h/2
+		s.text(x, y, "middle", fmt.Sprintf(` font-size="13" transform="rotate(-90 %.1f %.1f)"`, x, y), args.YLabel)
+	}
+
+	switch args.Type {
+	case "bar":
+		group := band * 0.8
+		bar := group / float64(len(args.Series))
+		for si, series := range args.Series {
+			for i, v := range series.Values {
+				x := box.x + band*float64(i) + band*0.1 + bar*float64(si)
+				y := math.Min(yPos(v), zero)
+				s.printf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`+"\n",
+					x, y, math.Max(bar-1, 1), math.Abs(yPos(v)-zero), c.color(si), escapeXML(tooltip(series.Name, args.Labels[i], v)))
+			}
+		}
+	case "line":
+		for si, series := range args.Series {
+			points := make([]string, len(series.Values))
+			for i, v := range series.Values {
+				points[i] = fmt.Sprintf("%.1f,%.1f", box.x+band*(float64(i)+0.5), yPos(v))
+			}
+			s.printf(`<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), c.color(si))
+			if n <= 60 {
+				for i, v := range series.Values {
+					s.printf(`<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s</title></circle>`+"\n",
+						box.x+band*(float64(i)+0.5), yPos(v), c.color(si), escapeXML(tooltip(series.Name, args.Labels[i], v)))
+				}
+			}
+		}
+	}
+}
+
+func (c *charter) pie(s *svg, args ChartArgs, box rect) {
+	values := args.Series[0].Values
+	total := sum(values)
+	cx, cy := box.x+box.w/2, box.y+box.h/2
+	r := math.Min(box.w, box.h) / 2
+	angle := -math.Pi / 2
+	for i, v := range values {
+		if v == 0 {
+			continue
+		}
+		share := v / total
+		label := escapeXML(tooltip("", args.Labels[i], v))
+		if share >= 0.9999 {
+			s.printf(`<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"><title>%s</title></circle>`+"\n", cx, cy, r, c.color(i), label)
+			break
+		}
+		end := angle + share*2*math.Pi
+		large := 0
+		if share > 0.5 {
+			large = 1
+		}
+		s.printf(`<path d="M%.1f,%.1f L%.1f,%.1f A%.1f,%.1f 0 %d 1 %.1f,%.1f Z" fill="%s" stroke="white"><title>%s</title></path>`+"\n",
+			cx, cy, cx+r*math.Cos(angle), cy+r*math.Sin(angle), r, r, large, cx+r*math.Cos(end), cy+r*math.Sin(end), c.color(i), label)
+		if share >= 0.05 {
+			mid := (angle + end) / 2
+			s.text(cx+r*0.65*math.Cos(mid), cy+r*0.65*math.Sin(mid)+4, "middle", ` fill="white" font-weight="bold"`,
+				fmt.Sprintf("%.0f%%", share*100))
+		}
+		angle = end
+	}
+}
+
+func (c *charter) legend(s *svg, args ChartArgs, x, y float64) {
+	var entries []string
+	if args.Type == "pie" {
+		total := sum(args.Series[0].Values)
+		for i, l := range args.Labels {
+			entries = append(entries, fmt.Sprintf("%s (%.1f%%)", l, args.Series[0].Values[i]/total*100))
+		}
+	} else {
+		for i, series := range args.Series {
+			name := series.Name
+			if name == "" {
+				name = fmt.Sprintf("Series %d", i+1)
+			}
+			entries = append(entries, name)
+		}
+	}
+	for i, e := range entries {
+		if len([]rune(e)) > 22 {
+			e = string([]rune(e)[:21]) + "…"
+		}
+		ey := y + float64(i)*20
+		s.printf(`<rect x="%.1f" y="%.1f" width="12" height="12" fill="%s"/>`+"\n", x, ey, c.color(i))
+		s.text(x+18, ey+10, "start", "", e)
+	}
+}
+
+// niceTicks returns round tick values covering lo to hi, and how many
+// decimals they need.
+func niceTicks(lo, hi float64, n int) ([]float64, int) {
+	if hi == lo {
+		hi = lo + 1
+	}
+	step := niceNum(niceNum(hi-lo, false)/float64(n-1), true)
+	start := math.Floor(lo/step) * step
+	end := math.Ceil(hi/step) * step
+	var ticks []float64
+	for v := start; v <= end+step/2; v += step {
+		ticks = append(ticks, v)
+	}
+	return ticks, max(0, -int(math.Floor(math.Log10(step))))
+}
+
+// niceNum rounds x to 1, 2 or 5 times a power of ten.
+func niceNum(x float64, round bool) float64 {
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+	var nice float64
+	switch {
+	case round && f < 1.5, !round && f <= 1:
+		nice = 1
+	case round && f < 3, !round && f <= 2:
+		nice = 2
+	case round && f < 7, !round && f <= 5:
+		nice = 5
+	default:
+		nice = 10
+	}
+	return nice * math.Pow(10, exp)
+}
+
+func tooltip(series, label string, v float64) string {
+	if series != "" {
+		return fmt.Sprintf("%s, %s: %g", series, label, v)
+	}
+	return fmt.Sprintf("%s: %g", label, v)
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// slug makes a file name from s: lower case letters, digits and dashes.
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"go-agent-sdk/llm"
+	"sync"
+	"time"
+)
+
+// Effect is something a tool changed outside the conversation: a ticket it
+// opened, an email it sent, a refund it issued. The text a tool returns is
+// for the model; an Effect is for you - recorded separately, so after a
+// run you can answer "what did the agent actually do?" without parsing
+// tool output.
+//
+// Tools declare effects with RecordEffect as they happen:
+//
+//	func OpenTicket(ctx context.Context, args TicketArgs) (string, error) {
+//	    id, err := tracker.Create(ctx, args.Title, args.Body)
+//	    if err != nil {
+//	        return "", err
+//	    }
+//	    tools.RecordEffect(ctx, tools.Effect{
+//	        Kind:    "ticket.created",
+//	        Target:  "ticket#" + id,
+//	        Summary: args.Title,
+//	    })
+//	    return "Opened ticket " + id, nil
+//	}
+type Effect struct {
+	Kind    string         `json:"kind"`             // what happened, e.g. "ticket.created", "email.sent"
+	Target  string         `json:"target,omitempty"` // what it happened to, e.g. "ticket#123"
+	Summary string         `json:"summary,omitempty"`
+	Data    map[string]any `json:"data,omitempty"` // anything else worth keeping
+
+	// Filled in by RecordEffect.
+	Time       time.Time `json:"time"`
+	Tool       string    `json:"tool,omitempty"`
+	ToolCallID string    `json:"tool_call_id,omitempty"`
+	RunID      string    `json:"run_id,omitempty"`
+}
+
+// EffectLog collects the effects recorded under a context. It is safe for
+// concurrent use.
+type EffectLog struct {
+	tool string
+
+	mu      sync.Mutex
+	effects []Effect
+}
+
+// Effects returns the recorded effects, oldest first.
+func (l *EffectLog) Effects() []Effect {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Effect(nil), l.effects...)
+}
+
+type effectsKey struct{}
+
+// CollectEffects returns a context whose RecordEffect calls go to the
+// returned log, attributed to the named tool. The agent does this for
+// every tool call; use it yourself to run a tool outside an agent and
+// still see what it did.
+func CollectEffects(ctx context.Context, tool string) (context.Context, *EffectLog) {
+	log := &EffectLog{tool: tool}
+	return context.WithValue(ctx, effectsKey{}, log), log
+}
+
+// RecordEffect records e in the log on ctx, filling in the time, the tool
+// and the run and tool call IDs from the trace context. It reports whether
+// there was a log to record to - there isn't when the tool is called
+// directly rather than by an agent, and the effect is dropped.
+func RecordEffect(ctx context.Context, e Effect) bool {
+	log, ok := ctx.Value(effectsKey{}).(*EffectLog)
+	if !ok {
+		return false
+	}
+	if e.Time.IsZero() {
+		e.Time = llm.Now(ctx)
+	}
+	if e.Tool == "" {
+		e.Tool = log.tool
+	}
+	if tc, ok := llm.TraceFromContext(ctx); ok {
+		if e.ToolCallID == "" {
+			e.ToolCallID = tc.ToolCallID
+		}
+		if e.RunID == "" {
+			e.RunID = tc.RunID
+		}
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.effects = append(log.effects, e)
+	return true
+}
@@ -1,11 +1,24 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go-agent-sdk/tools/jsonschema"
 	"reflect"
 )
 
+// NotFoundError is returned by Execute when no tool with that name is
+// registered - typically the LLM hallucinated a tool name.
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("tool %s not found", e.Name)
+}
+
 // Execute runs a tool that the LLM requested.
 //
 // This is where the magic happens - we take a tool name and JSON arguments
@@ -15,27 +28,59 @@ import (
 //
 // The pipeline:
 //  1. Look up the tool by name in our registry
-//  2. Create an empty instance of the tool's argument struct using reflect.New()
+//  2. Validate the JSON against the tool's schema (see jsonschema.Validate)
+//  3. Create an empty instance of the tool's argument struct using reflect.New()
 //     (this gives us something like *WeatherArgs{City: ""})
-//  3. Unmarshal the LLM's JSON into that empty struct
+//  4. Unmarshal the LLM's JSON into that empty struct
 //     (now we have *WeatherArgs{City: "Paris"})
-//  4. Call the actual function using reflect.Value.Call()
-//     (this runs GetWeather(args) under the hood)
-//  5. Extract the result and convert it to a string
+//  5. Call the actual function using reflect.Value.Call()
+//     (this runs GetWeather(args) under the hood, or GetWeather(ctx, args)
+//     if the function takes a context)
+//  6. Extract the result and convert it to a string
 //
 // The tricky part is that Call() needs the actual value, not the pointer,
 // so we use argsInstance.Elem() to dereference it.
 //
 // If the function returns a plain string, we use that directly.
 // If it returns interface{}, we try to cast it to string.
-// This handles both simple functions and ones that might return errors too.
-func (r *Registry) Execute(name string, argsJson string) (string, error) {
+// If it returns a Result, only its Text is returned - use ExecuteResult to
+// get the images and files too.
+// If it returns (string, error) and the error is non-nil, we return the error
+// so the agent can report it to the LLM as a failed tool call.
+//
+// ctx is the agent's Run context. Tools that take a context.Context receive
+// it, so a cancelled run or an expired deadline reaches the tool's I/O.
+func (r *Registry) Execute(ctx context.Context, name string, argsJson string) (string, error) {
+	res, err := r.ExecuteResult(ctx, name, argsJson)
+	return res.Text, err
+}
+
+// ExecuteResult is Execute for tools that may return rich output. Tools
+// that return a string get it wrapped as Result{Text: ...}.
+func (r *Registry) ExecuteResult(ctx context.Context, name string, argsJson string) (Result, error) {
 
+	r.mu.RLock()
 	def, exists := r.definitions[name]
+	r.mu.RUnlock()
 	if !exists {
-		return "", fmt.Errorf("tool %s not found", name)
+		return Result{}, &NotFoundError{Name: name}
 	}
 
+	// Check the arguments against the schema the LLM was given before
+	// touching the function. json.Unmarshal alone would quietly accept a
+	// missing field or a number where a string belongs; a ValidationError
+	// lists every problem so the LLM can fix them all in one retry.
+	schema := jsonschema.Cached(def.ArgsType)
+	if err := jsonschema.Validate(schema, []byte(argsJson)); err != nil {
+		var verr *jsonschema.ValidationError
+		if errors.As(err, &verr) {
+			return Result{}, err
+		}
+		return Result{}, fmt.Errorf("invalid args: %w", err)
+	}
+	// Fields the LLM left out get their default:"..." tag values.
+	args := jsonschema.ApplyDefaults(schema, []byte(argsJson))
+
 	// reflect.New creates a pointer to a new zero value of the type.
 	// So if ArgsType is WeatherArgs, we get *WeatherArgs.
 	// We need a pointer because json.Unmarshal requires one.
@@ -44,27 +89,48 @@ func (r *Registry) Execute(name string, argsJson string) (string, error) {
 	// Unmarshal fills the struct with the LLM's arguments.
 	// We have to call .Interface() because json.Unmarshal doesn't understand
 	// reflect.Value - it needs a regular Go interface{}.
-	if err := json.Unmarshal([]byte(argsJson), argsInstance.Interface()); err != nil {
-		return "", fmt.Errorf("invalid args: %w", err)
+	if err := json.Unmarshal(args, argsInstance.Interface()); err != nil {
+		return Result{}, fmt.Errorf("invalid args: %w", err)
 	}
 
 	// Call the function! We pass a slice of arguments.
 	// argsInstance.Elem() gets us the actual struct value (not the pointer).
-	results := def.Func.Call([]reflect.Value{argsInstance.Elem()})
+	in := []reflect.Value{argsInstance.Elem()}
+	if def.TakesContext {
+		in = append([]reflect.Value{reflect.ValueOf(&ctx).Elem()}, in...)
+	}
+	var results []reflect.Value
+	if def.Timeout > 0 {
+		var err error
+		if results, err = callWithTimeout(ctx, def, in); err != nil {
+			return Result{}, err
+		}
+	} else {
+		results = def.Func.Call(in)
+	}
 
 	// Handle different return types:
-	// Most tools return just a string, but some might return (string, error).
-	// We check the first result's type and convert appropriately.
+	// Most tools return just a string, but some return (string, error).
+	// A non-nil error wins over whatever string came with it.
 	if len(results) == 0 {
-		return "", fmt.Errorf("function returned no results")
+		return Result{}, fmt.Errorf("function returned no results")
+	}
+	if len(results) == 2 && !results[1].IsNil() {
+		return Result{}, results[1].Interface().(error)
 	}
 	if results[0].Kind() == reflect.String {
-		return results[0].String(), nil
+		return Result{Text: results[0].String()}, nil
 	}
-	if results[0].Kind() == reflect.Interface {
-		if str, ok := results[0].Interface().(string); ok {
-			return str, nil
+	switch v := results[0].Interface().(type) {
+	case string:
+		return Result{Text: v}, nil
+	case Result:
+		return v, nil
+	case *Result:
+		if v != nil {
+			return *v, nil
 		}
+		return Result{}, nil
 	}
-	return "", fmt.Errorf("function did not return a string")
+	return Result{}, fmt.Errorf("function did not return a string")
 }
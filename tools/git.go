@@ -0,0 +1,509 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// GitOption configures NewGit.
+type GitOption func(*gitTools)
+
+type gitTools struct {
+	root      string
+	template  string             // commit message template, empty means the message as given
+	tmpl      *template.Template // template, parsed by NewGit
+	protected []string           // branch patterns git_push refuses
+	author    string             // "Name <email>", empty means git's configured identity
+	noPush    bool
+	maxOutput int
+}
+
+// GitCommitTemplate shapes every commit message the agent writes. The
+// template is a text/template executed with a GitCommitInfo, so house
+// conventions hold however the model phrases things:
+//
+//	tools.GitCommitTemplate("{{.Subject}}\n\n{{.Body}}\n\nGenerated on branch {{.Branch}}")
+//
+// Blank lines the template leaves at the end are trimmed. NewGit reports a
+// template that doesn't parse.
+func GitCommitTemplate(tmpl string) GitOption {
+	return func(g *gitTools) {
+		g.template = tmpl
+	}
+}
+
+// GitCommitInfo is what a GitCommitTemplate is executed with.
+type GitCommitInfo struct {
+	Message string   // the message the model wrote
+	Subject string   // its first line
+	Body    string   // the rest, without the blank line after the subject
+	Branch  string   // the branch being committed to
+	Files   []string // the staged files, relative to the repository
+}
+
+// GitProtectedBranches replaces the list of branches git_push refuses to
+// push to. Names may be path.Match patterns, such as "release/*". The
+// default is main and master.
+func GitProtectedBranches(patterns ...string) GitOption {
+	return func(g *gitTools) {
+		g.protected = patterns
+	}
+}
+
+// GitAuthor commits as the given identity instead of the one in the
+// repository's git config.
+func GitAuthor(name, email string) GitOption {
+	return func(g *gitTools) {
+		g.author = fmt.Sprintf("%s <%s>", name, email)
+	}
+}
+
+// GitNoPush leaves out the git_push tool, for agents that prepare commits
+// locally and leave publishing them to a human.
+func GitNoPush() GitOption {
+	return func(g *gitTools) {
+		g.noPush = true
+	}
+}
+
+// GitMaxOutput caps how many bytes of git's output a tool returns - a big
+// diff would otherwise fill the context window. The default is 64 KiB.
+func GitMaxOutput(n int) GitOption {
+	return func(g *gitTools) {
+		g.maxOutput = n
+	}
+}
+
+// GitStatusArgs are the git_status tool's arguments.
+type GitStatusArgs struct{}
+
+// GitDiffArgs are the git_diff tool's arguments.
+type GitDiffArgs struct {
+	Staged bool     `json:"staged,omitempty" description:"Show the changes staged for the next commit instead of the unstaged ones."`
+	Ref    string   `json:"ref,omitempty" description:"Compare the working tree against this commit or branch instead, e.g. main or HEAD~1."`
+	Paths  []string `json:"paths,omitempty" description:"Only show changes to these files or directories."`
+}
+
+// GitLogArgs are the git_log tool's arguments.
+type GitLogArgs struct {
+	Limit int    `json:"limit,omitempty" description:"How many commits to show, newest first. Defaults to 10, at most 100."`
+	Ref   string `json:"ref,omitempty" description:"Branch or commit to start from. Defaults to the current branch."`
+	Path  string `json:"path,omitempty" description:"Only show commits that touched this file or directory."`
+}
+
+// GitCommitArgs are the git_commit tool's arguments.
+type GitCommitArgs struct {
+	Message string   `json:"message" description:"The commit message: a short summary line, then optionally a blank line and more detail."`
+	Paths   []string `json:"paths,omitempty" description:"Files to stage before committing. Leave empty to commit what is already staged."`
+	All     bool     `json:"all,omitempty" description:"Stage every change, including new and deleted files, before committing."`
+}
+
+// GitBranchArgs are the git_branch tool's arguments.
+type GitBranchArgs struct {
+	Name     string `json:"name,omitempty" description:"Branch to create or switch to. Leave empty to list branches."`
+	Create   bool   `json:"create,omitempty" description:"Create the branch."`
+	From     string `json:"from,omitempty" description:"When creating, the branch or commit to start from. Defaults to the current commit."`
+	Checkout bool   `json:"checkout,omitempty" description:"Switch to the branch."`
+}
+
+// GitPushArgs are the git_push tool's arguments.
+type GitPushArgs struct {
+	Remote      string `json:"remote,omitempty" description:"Remote to push to. Defaults to origin."`
+	Branch      string `json:"branch,omitempty" description:"Branch to push. Defaults to the current branch."`
+	SetUpstream bool   `json:"set_upstream,omitempty" description:"Make the remote branch the local branch's upstream."`
+}
+
+// NewGit returns tools for working with the git repository root is in:
+// git_status, git_diff, git_log, git_commit, git_branch and git_push.
+// Together with NewPatcher they let an agent prepare a change for review
+// end to end - branch, edit, check the diff, commit, push.
+//
+// Everything stays inside root: status, diffs, logs and staging are
+// limited to it even when root is a subdirectory of a larger repository,
+// and paths the model gives can't leave it. Force pushes aren't offered
+// at all, and protected branches (main and master unless changed with
+// GitProtectedBranches) can't be pushed to.
+//
+// Commits, new branches and pushes are recorded as "git.committed",
+// "git.branch_created" and "git.pushed" Effects.
+//
+//	git, err := tools.NewGit("./workspace",
+//	    tools.GitAuthor("Release Bot", "bot@example.com"),
+//	    tools.GitProtectedBranches("main", "release/*"),
+//	)
+//	a.RegisterToolset(git)
+//
+// The tools run the git binary, which must be on the PATH.
+func NewGit(root string, opts ...GitOption) (Toolset, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return Toolset{}, fmt.Errorf("tools: git root: %w", err)
+	}
+	if !info.IsDir() {
+		return Toolset{}, fmt.Errorf("tools: git root %s is not a directory", root)
+	}
+	g := &gitTools{root: root, protected: []string{"main", "master"}, maxOutput: 64 << 10}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.template != "" {
+		if g.tmpl, err = template.New("commit").Parse(g.template); err != nil {
+			return Toolset{}, fmt.Errorf("tools: git commit template: %w", err)
+		}
+	}
+	if _, err := g.git(context.Background(), nil, "rev-parse", "--show-toplevel"); err != nil {
+		return Toolset{}, fmt.Errorf("tools: %s is not in a git repository: %w", root, err)
+	}
+
+	ts := Toolset{
+		Name: "git",
+		Prompt: "Use the git tools to manage version control. Check git_status and git_diff before committing, " +
+			"and commit related changes together with a message that explains why, not just what. " +
+			"Do your work on a new branch rather than the main branch.",
+		Tools: []Tool{
+			{Name: "git_status", Description: "Show the current branch and which files are changed, staged or untracked.", Func: g.status},
+			{Name: "git_diff", Description: "Show changes as a unified diff.", Func: g.diff},
+			{Name: "git_log", Description: "Show recent commits.", Func: g.log},
+			{Name: "git_commit", Description: "Stage files and commit them.", Func: g.commit},
+			{Name: "git_branch", Description: "List, create or switch branches.", Func: g.branch},
+		},
+	}
+	if !g.noPush {
+		ts.Tools = append(ts.Tools, Tool{Name: "git_push", Description: "Push a branch to a remote.", Func: g.push})
+		ts.Prompt += " Protected branches (" + strings.Join(g.protected, ", ") + ") can't be pushed to."
+	}
+	return ts, nil
+}
+
+func (g *gitTools) status(ctx context.Context, _ GitStatusArgs) (string, error) {
+	out, err := g.git(ctx, nil, "status", "--short", "--branch", "--", ".")
+	if err != nil {
+		return "", err
+	}
+	if strings.Count(out, "\n") <= 1 {
+		out += "Nothing to commit, working tree clean.\n"
+	}
+	return out, nil
+}
+
+func (g *gitTools) diff(ctx context.Context, args GitDiffArgs) (string, error) {
+	cmd := []string{"diff"}
+	if args.Staged {
+		cmd = append(cmd, "--cached")
+	}
+	if args.Ref != "" {
+		if err := checkRef(args.Ref); err != nil {
+			return "", err
+		}
+		cmd = append(cmd, args.Ref)
+	}
+	paths, err := cleanPaths(args.Paths)
+	if err != nil {
+		return "", err
+	}
+	out, err := g.git(ctx, nil, append(append(cmd, "--"), paths...)...)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "No changes.", nil
+	}
+	return g.truncate(out), nil
+}
+
+func (g *gitTools) log(ctx context.Context, args GitLogArgs) (string, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	limit = min(limit, 100)
+	cmd := []string{"log", fmt.Sprintf("-n%d", limit), "--date=short", "--format=%h %ad %an: %s"}
+	if args.Ref != "" {
+		if err := checkRef(args.Ref); err != nil {
+			return "", err
+		}
+		cmd = append(cmd, args.Ref)
+	}
+	paths, err := cleanPaths([]string{args.Path})
+	if err != nil {
+		return "", err
+	}
+	out, err := g.git(ctx, nil, append(append(cmd, "--"), paths...)...)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "No commits.", nil
+	}
+	return g.truncate(out), nil
+}
+
+func (g *gitTools) commit(ctx context.Context, args GitCommitArgs) (string, error) {
+	if strings.TrimSpace(args.Message) == "" {
+		return "", errors.New("the commit message is empty")
+	}
+	switch {
+	case args.All:
+		if _, err := g.git(ctx, nil, "add", "--all", "--", "."); err != nil {
+			return "", err
+		}
+	case len(args.Paths) > 0:
+		paths, err := cleanPaths(args.Paths)
+		if err != nil {
+			return "", err
+		}
+		if _, err := g.git(ctx, nil, append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+			return "", err
+		}
+	}
+	files, err := g.staged(ctx, ".")
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", errors.New("nothing is staged; give paths or set all to stage changes first")
+	}
+	// A commit takes everything staged, so refuse if that reaches outside
+	// root.
+	if all, err := g.staged(ctx); err != nil {
+		return "", err
+	} else if len(all) != len(files) {
+		return "", fmt.Errorf("files outside %s are staged too; unstage them before committing", g.root)
+	}
+	branch, err := g.currentBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	msg, err := g.message(args.Message, branch, files)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := []string{"commit", "--file=-", "--cleanup=strip"}
+	if g.author != "" {
+		cmd = append(cmd, "--author="+g.author)
+	}
+	if _, err := g.git(ctx, strings.NewReader(msg), cmd...); err != nil {
+		return "", err
+	}
+	hash, err := g.git(ctx, nil, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	hash = strings.TrimSpace(hash)
+	subject, _, _ := strings.Cut(msg, "\n")
+	RecordEffect(ctx, Effect{Kind: "git.committed", Target: hash, Summary: subject,
+		Data: map[string]any{"branch": branch, "files": files}})
+	return fmt.Sprintf("Committed %s on %s (%d files): %s", hash, branch, len(files), subject), nil
+}
+
+// staged lists the staged files under the pathspecs, or in the whole
+// repository with none, relative to the repository's top.
+func (g *gitTools) staged(ctx context.Context, pathspecs ...string) ([]string, error) {
+	out, err := g.git(ctx, nil, append([]string{"diff", "--cached", "--name-only", "-z", "--"}, pathspecs...)...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// message applies the commit template, if there is one.
+func (g *gitTools) message(msg, branch string, files []string) (string, error) {
+	msg = strings.TrimSpace(msg)
+	if g.tmpl == nil {
+		return msg + "\n", nil
+	}
+	subject, body, _ := strings.Cut(msg, "\n")
+	info := GitCommitInfo{
+		Message: msg,
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.TrimSpace(body),
+		Branch:  branch,
+		Files:   files,
+	}
+	var b bytes.Buffer
+	if err := g.tmpl.Execute(&b, info); err != nil {
+		return "", fmt.Errorf("commit message template: %w", err)
+	}
+	return strings.TrimRight(b.String(), "\n\t ") + "\n", nil
+}
+
+func (g *gitTools) branch(ctx context.Context, args GitBranchArgs) (string, error) {
+	if args.Name == "" {
+		out, err := g.git(ctx, nil, "branch", "--list", "--format=%(HEAD) %(refname:short)")
+		if err != nil {
+			return "", err
+		}
+		if out == "" {
+			return "No branches yet.", nil
+		}
+		return g.truncate(out), nil
+	}
+	if err := g.checkBranchName(ctx, args.Name); err != nil {
+		return "", err
+	}
+	if !args.Create && !args.Checkout {
+		return "", errors.New("set create, checkout or both; leave name empty to list branches")
+	}
+	var done []string
+	if args.Create {
+		cmd := []string{"branch", args.Name}
+		if args.From != "" {
+			if err := checkRef(args.From); err != nil {
+				return "", err
+			}
+			cmd = append(cmd, args.From)
+		}
+		if _, err := g.git(ctx, nil, cmd...); err != nil {
+			return "", err
+		}
+		RecordEffect(ctx, Effect{Kind: "git.branch_created", Target: args.Name, Summary: "created branch " + args.Name})
+		done = append(done, "created")
+	}
+	if args.Checkout {
+		if _, err := g.git(ctx, nil, "switch", args.Name); err != nil {
+			return "", err
+		}
+		done = append(done, "switched to")
+	}
+	return fmt.Sprintf("%s branch %s.", capitalize(strings.Join(done, " and ")), args.Name), nil
+}
+
+func (g *gitTools) push(ctx context.Context, args GitPushArgs) (string, error) {
+	remote := args.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	if err := checkRef(remote); err != nil {
+		return "", err
+	}
+	branch := args.Branch
+	if branch == "" {
+		var err error
+		if branch, err = g.currentBranch(ctx); err != nil {
+			return "", err
+		}
+	}
+	if err := g.checkBranchName(ctx, branch); err != nil {
+		return "", err
+	}
+	if p, ok := g.isProtected(branch); ok {
+		return "", fmt.Errorf("%s is a protected branch (%s) and can't be pushed to; push a feature branch instead", branch, p)
+	}
+	cmd := []string{"push", "--porcelain"}
+	if args.SetUpstream {
+		cmd = append(cmd, "--set-upstream")
+	}
+	ref := "refs/heads/" + branch
+	if _, err := g.git(ctx, nil, append(cmd, remote, ref+":"+ref)...); err != nil {
+		return "", err
+	}
+	RecordEffect(ctx, Effect{Kind: "git.pushed", Target: remote + "/" + branch, Summary: "pushed " + branch + " to " + remote})
+	return fmt.Sprintf("Pushed %s to %s.", branch, remote), nil
+}
+
+func (g *gitTools) isProtected(branch string) (string, bool) {
+	for _, p := range g.protected {
+		if ok, _ := path.Match(p, branch); ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func (g *gitTools) currentBranch(ctx context.Context) (string, error) {
+	out, err := g.git(ctx, nil, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", errors.New("HEAD is detached; switch to a branch first")
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (g *gitTools) checkBranchName(ctx context.Context, name string) error {
+	if err := checkRef(name); err != nil {
+		return err
+	}
+	if _, err := g.git(ctx, nil, "check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", name)
+	}
+	return nil
+}
+
+// git runs git in the root with stdin, returning its output, or its error
+// output as the error.
+func (g *gitTools) git(ctx context.Context, stdin *strings.Reader, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = g.root
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",   // fail rather than wait for credentials
+		"GIT_LITERAL_PATHSPECS=1", // no :(top) or globs to reach outside root
+		"GIT_OPTIONAL_LOCKS=0",
+	)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+func (g *gitTools) truncate(out string) string {
+	if g.maxOutput <= 0 || len(out) <= g.maxOutput {
+		return out
+	}
+	return out[:g.maxOutput] + fmt.Sprintf("\n[output truncated: %d of %d bytes shown]", g.maxOutput, len(out))
+}
+
+// cleanPaths checks paths stay in the root. No paths means all of it.
+func cleanPaths(paths []string) ([]string, error) {
+	var out []string
+	for _, p := range paths {
+		if p == "" || p == "." {
+			continue
+		}
+		name, err := cleanPath(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	if len(out) == 0 {
+		out = []string{"."}
+	}
+	return out, nil
+}
+
+// checkRef refuses refs git would read as options.
+func checkRef(ref string) error {
+	if strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n") {
+		return fmt.Errorf("%q is not a valid ref", ref)
+	}
+	return nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
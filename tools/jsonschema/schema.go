@@ -1,17 +1,133 @@
 package jsonschema
 
 import (
+	"encoding/json"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// cache holds one generated schema per type, see Cached.
+var cache sync.Map // reflect.Type -> *cached
+
+// cached is a schema plus its JSON encoding, both built once per type.
+type cached struct {
+	schema map[string]any
+	once   sync.Once
+	raw    json.RawMessage
+}
+
+func load(t reflect.Type) *cached {
+	if c, ok := cache.Load(t); ok {
+		return c.(*cached)
+	}
+	c, _ := cache.LoadOrStore(t, &cached{schema: GenerateSchema(t)})
+	return c.(*cached)
+}
+
+// Cached returns the schema for t, generating it only the first time a type
+// is seen. Tools that share an argument type share the schema too.
+//
+// The returned map is shared between all callers - don't modify it.
+// Use GenerateSchema if you need a copy you can change.
+func Cached(t reflect.Type) map[string]any {
+	return load(t).schema
+}
+
+// CachedJSON returns the schema for t already encoded as JSON, so sending
+// the same tools on every request doesn't re-marshal the schema maps.
+// Like Cached, the result is shared - don't modify it.
+func CachedJSON(t reflect.Type) json.RawMessage {
+	c := load(t)
+	c.once.Do(func() {
+		// A schema is only strings, bools, slices and maps - it can't fail.
+		c.raw, _ = json.Marshal(c.schema)
+	})
+	return c.raw
+}
+
 // GenerateSchema takes a struct type and returns a map[string]any
 // representing the JSON Schema required for OpenAI tool definitions.
+//
+// Fields can be strings, numbers, bools, nested structs, slices and arrays
+// ([]T becomes an array of T's schema), maps with string or integer keys
+// (map[string]T becomes an object whose values are T), pointers to any of
+// those (optional fields), time.Time (a date-time string) and any
+// (accepting any JSON value). Fields of other types - channels, funcs -
+// are left out. See structSchema for the tags that shape each field.
+//
+// Recursive types (tree nodes, linked lists, `type Tree map[string]Tree`)
+// are handled with references: a type that contains itself is emitted
+// once under "$defs" and referred to with {"$ref": "#/$defs/Name"}. If the
+// root type itself is recursive, the references point at the root ("#").
+// Without this, a field like `Next *Node` would recurse forever.
+//
+// Output is deterministic for a given type: properties are keyed by name
+// (encoding/json sorts map keys), "required" follows struct field order,
+// and $defs names are assigned in the order types are first seen.
 func GenerateSchema(t reflect.Type) map[string]any {
-	// Handle pointers (dereference them)
-	if t.Kind() == reflect.Ptr {
+	g := &generator{
+		root:      deref(t),
+		visiting:  make(map[reflect.Type]bool),
+		recursive: make(map[reflect.Type]bool),
+		names:     make(map[reflect.Type]string),
+		taken:     make(map[string]bool),
+		defs:      make(map[string]any),
+	}
+
+	schema := g.generate(t)
+	if schema != nil && len(g.defs) > 0 {
+		schema["$defs"] = g.defs
+	}
+	return schema
+}
+
+// Of returns the schema for v's type - shorthand for
+// GenerateSchema(reflect.TypeOf(v)), handy for response formats:
+//
+//	req.ResponseFormat = llm.NewJSONSchemaFormat("invoice", jsonschema.Of(Invoice{}))
+func Of(v any) map[string]any {
+	return GenerateSchema(reflect.TypeOf(v))
+}
+
+// generator carries the state for one GenerateSchema call.
+type generator struct {
+	root      reflect.Type
+	visiting  map[reflect.Type]bool   // named types on the current path (cycle detection)
+	recursive map[reflect.Type]bool   // named types found to contain themselves
+	names     map[reflect.Type]string // $defs name for each recursive type
+	taken     map[string]bool         // $defs names in use, to disambiguate same-named types
+	defs      map[string]any
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
+	return t
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
+func (g *generator) generate(t reflect.Type) map[string]any {
+	// Handle pointers (dereference them)
+	t = deref(t)
+
+	// Types encoding/json writes in their own way.
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "integer", "description": "nanoseconds"}
+	case rawType:
+		return map[string]any{} // any JSON value
+	}
 
 	// Base cases for primitive types
 	switch t.Kind() {
@@ -19,58 +135,331 @@ func GenerateSchema(t reflect.Type) map[string]any {
 		return map[string]any{"type": "string"}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
 	case reflect.Float32, reflect.Float64:
 		return map[string]any{"type": "number"}
 	case reflect.Bool:
 		return map[string]any{"type": "boolean"}
+	case reflect.Interface:
+		return map[string]any{} // any JSON value
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+	default:
+		return nil // channels, funcs, complex numbers
 	}
 
-	// Complex case: Structs
-	if t.Kind() == reflect.Struct {
-		properties := make(map[string]any)
-		required := []string{}
+	// Only a named type can contain itself - a struct, or a slice or map
+	// like `type Tree map[string]Tree` - so unnamed slices and maps need
+	// no tracking.
+	if t.Kind() != reflect.Struct && t.Name() == "" {
+		return g.composite(t)
+	}
+
+	// Already emitted as a definition - just point at it.
+	if name, ok := g.names[t]; ok && g.defs[name] != nil {
+		return g.ref(t)
+	}
+
+	// We're inside this type already: it's recursive. Emit a reference
+	// now and let the outer call store the full schema in $defs.
+	if g.visiting[t] {
+		g.recursive[t] = true
+		return g.ref(t)
+	}
+
+	g.visiting[t] = true
+	schema := g.composite(t)
+	delete(g.visiting, t)
+
+	if schema != nil && g.recursive[t] && t != g.root {
+		g.defs[g.name(t)] = schema
+		return g.ref(t)
+	}
+	return schema
+}
+
+// composite describes a slice, array, map or struct, recursing into its
+// elements through generate.
+func (g *generator) composite(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		// []T and [n]T are arrays - except []byte, which encoding/json
+		// writes as a base64 string.
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		items := g.generate(t.Elem())
+		if items == nil {
+			return nil
+		}
+		schema := map[string]any{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			schema["minItems"] = t.Len()
+			schema["maxItems"] = t.Len()
+		}
+		return schema
+
+	case reflect.Map:
+		// map[string]T is an object with arbitrary keys. encoding/json also
+		// accepts integer keys, written as strings.
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil
+		}
+		values := g.generate(t.Elem())
+		if values == nil {
+			return nil
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}
+
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return nil
+}
+
+// structSchema describes a struct as an object. Which fields it has and
+// which are required follow encoding/json:
+//
+//   - Fields need a json tag; "-" skips the field.
+//   - Embedded structs without a tag have their fields promoted, the way
+//     encoding/json flattens them.
+//   - A field is required unless it is a pointer, tagged omitempty (or
+//     omitzero), or has a default. The tag required:"true" or
+//     required:"false" overrides.
+//
+// Other tags add to the field's schema:
+//
+//	description:"City name"  the field's description
+//	enum:"c,f"               the allowed values, comma-separated
+//	default:"c"              the value used when the field is left out (see ApplyDefaults)
+//
+// Constraint tags become the JSON Schema keywords of the same name, so the
+// model sees the exact limits and Validate enforces them. On a slice they
+// apply to the items, except the ones about the slice itself:
+//
+//	minimum:"1" maximum:"10"           numbers (also exclusiveMinimum, exclusiveMaximum, multipleOf)
+//	minLength:"2" maxLength:"64"       strings, in characters
+//	pattern:"^[A-Z]{3}$"               strings, a Go regexp (double backslashes: "\\d+")
+//	format:"email"                     strings: date-time, date, time, email, uuid, uri, ipv4, ipv6, hostname
+//	minItems:"1" maxItems:"5"          slices (also uniqueItems:"true")
+func (g *generator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	g.addFields(t, properties, &required)
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// addFields adds t's fields to properties, recursing into embedded structs.
+func (g *generator) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	// Iterate over struct fields
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
 
-		// Iterate over struct fields
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
+		// Get the JSON tag name (e.g. `json:"city"`)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		if jsonTag == "" {
+			// An untagged embedded struct is flattened by encoding/json.
+			if et := deref(field.Type); field.Anonymous && et.Kind() == reflect.Struct {
+				g.addFields(et, properties, required)
+			}
+			continue // Skip other fields without JSON tags
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		// Handle "omitempty"
+		name, opts, _ := strings.Cut(jsonTag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		isRequired := field.Type.Kind() != reflect.Ptr
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "omitempty" || opt == "omitzero" {
+				isRequired = false
+			}
+		}
+		if _, ok := field.Tag.Lookup("default"); ok {
+			isRequired = false // Execute fills it in when it's left out
+		}
+		if req, err := strconv.ParseBool(field.Tag.Get("required")); err == nil {
+			isRequired = req
+		}
 
-			// Get the JSON tag name (e.g. `json:"city"`)
-			jsonTag := field.Tag.Get("json")
-			if jsonTag == "" || jsonTag == "-" {
-				continue // Skip fields without JSON tags
+		// Recursively generate schema for the field's type
+		fieldSchema := g.generate(field.Type)
+		if fieldSchema == nil {
+			continue // unsupported type, leave it out rather than emit null
+		}
+		if isRequired {
+			*required = append(*required, name)
+		}
+
+		// Tags that add keywords. A $ref can't carry siblings in older
+		// drafts, so wrap it first.
+		if hasKeywordTags(field.Tag) {
+			if _, isRef := fieldSchema["$ref"]; isRef {
+				fieldSchema = map[string]any{"allOf": []any{fieldSchema}}
 			}
+		}
 
-			// Handle "omitempty"
-			name := jsonTag
-			if strings.Contains(jsonTag, ",") {
-				parts := strings.Split(jsonTag, ",")
-				name = parts[0]
-				// If not omitempty, it's required
-				if !strings.Contains(jsonTag, "omitempty") {
-					required = append(required, name)
+		// Add description if present (e.g. `description:"City name"`).
+		if desc := field.Tag.Get("description"); desc != "" {
+			fieldSchema["description"] = desc
+		}
+
+		// enum and the value constraints (minimum, pattern, ...) apply to
+		// the values - for a slice, its items. default is the whole field's
+		// value; a comma-separated list for a slice.
+		target := fieldSchema
+		if items, ok := fieldSchema["items"].(map[string]any); ok {
+			target = items
+		}
+		valueType := deref(field.Type)
+		if valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array {
+			valueType = deref(valueType.Elem())
+		}
+		for _, key := range valueConstraints {
+			if v, ok := field.Tag.Lookup(key); ok {
+				if key == "pattern" || key == "format" {
+					target[key] = v
+				} else if n, ok := parseNumber(v); ok {
+					target[key] = n
+				}
+			}
+		}
+		if fieldSchema["type"] == "array" {
+			for _, key := range arrayConstraints {
+				if v, ok := field.Tag.Lookup(key); ok {
+					if key == "uniqueItems" {
+						if b, err := strconv.ParseBool(v); err == nil {
+							fieldSchema[key] = b
+						}
+					} else if n, ok := parseNumber(v); ok {
+						fieldSchema[key] = n
+					}
 				}
+			}
+		}
+		if enum, ok := field.Tag.Lookup("enum"); ok {
+			var values []any
+			for _, v := range strings.Split(enum, ",") {
+				values = append(values, parseValue(strings.TrimSpace(v), valueType))
+			}
+			target["enum"] = values
+		}
+		if def, ok := field.Tag.Lookup("default"); ok {
+			if fieldSchema["type"] == "array" {
+				values := []any{}
+				if def != "" {
+					for _, v := range strings.Split(def, ",") {
+						values = append(values, parseValue(strings.TrimSpace(v), valueType))
+					}
+				}
+				fieldSchema["default"] = values
 			} else {
-				// No commas means required by default in our logic
-				required = append(required, name)
+				fieldSchema["default"] = parseValue(def, valueType)
 			}
+		}
 
-			// Recursively generate schema for the field's type
-			fieldSchema := GenerateSchema(field.Type)
+		properties[name] = fieldSchema
+	}
+}
 
-			// Add description if present (e.g. `description:"City name"`)
-			if desc := field.Tag.Get("description"); desc != "" {
-				fieldSchema["description"] = desc
-			}
+// valueConstraints are the constraint tags copied into the schema as
+// keywords of the same name, for a field's values.
+var valueConstraints = []string{
+	"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+	"minLength", "maxLength", "pattern", "format",
+}
 
-			properties[name] = fieldSchema
+// arrayConstraints are the constraint tags for slice and array fields.
+var arrayConstraints = []string{"minItems", "maxItems", "uniqueItems"}
+
+// hasKeywordTags reports whether a field has tags that add keywords next
+// to its type.
+func hasKeywordTags(tag reflect.StructTag) bool {
+	keys := append([]string{"description", "enum", "default"}, valueConstraints...)
+	for _, key := range append(keys, arrayConstraints...) {
+		if _, ok := tag.Lookup(key); ok {
+			return true
 		}
+	}
+	return false
+}
 
-		return map[string]any{
-			"type":       "object",
-			"properties": properties,
-			"required":   required,
+// parseNumber reads a numeric constraint tag. Whole numbers come back as
+// int64 so they encode as 3, not 3.0.
+func parseNumber(s string) (any, bool) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// parseValue turns a tag value into a JSON value of the field's type:
+// "3" is a number for an int field, a string for a string field.
+func parseValue(s string, t reflect.Type) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
 		}
 	}
+	return s
+}
 
-	return nil
+// ref returns a fresh {"$ref": ...} pointing at t's definition.
+// A fresh map every time, since callers may add keys to it.
+func (g *generator) ref(t reflect.Type) map[string]any {
+	if t == g.root {
+		return map[string]any{"$ref": "#"}
+	}
+	return map[string]any{"$ref": "#/$defs/" + g.name(t)}
+}
+
+// name picks a stable $defs key for t. Types with the same name from
+// different packages get a numeric suffix in the order they're seen.
+func (g *generator) name(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	base := t.Name()
+	if base == "" {
+		base = "Type"
+	}
+	name := base
+	for n := 2; g.taken[name]; n++ {
+		name = base + strconv.Itoa(n)
+	}
+	g.names[t] = name
+	g.taken[name] = true
+	return name
 }
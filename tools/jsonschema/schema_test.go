@@ -0,0 +1,186 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Named recursive types for the fuzzer to build around. reflect.StructOf
+// can't make a type that refers to itself, so these are declared here -
+// structs, and slices and maps that recurse without a struct in between.
+type (
+	fuzzTree struct {
+		Value    string      `json:"value"`
+		Children []*fuzzTree `json:"children"`
+	}
+	fuzzList struct {
+		Next *fuzzList `json:"next"`
+		N    int       `json:"n"`
+	}
+	fuzzA struct {
+		B *fuzzB `json:"b"`
+	}
+	fuzzB struct {
+		A     *fuzzA           `json:"a"`
+		Index map[string]fuzzA `json:"index,omitempty"`
+	}
+	fuzzMapTree map[string]fuzzMapTree
+	fuzzNested  []fuzzNested
+	fuzzM       map[string]fuzzS
+	fuzzS       []*fuzzM
+)
+
+// fuzzLeaves are the field types a generated struct is built from.
+var fuzzLeaves = []reflect.Type{
+	reflect.TypeOf(""),
+	reflect.TypeOf(0),
+	reflect.TypeOf(uint8(0)),
+	reflect.TypeOf(0.0),
+	reflect.TypeOf(false),
+	reflect.TypeOf(time.Time{}),
+	reflect.TypeOf([]byte(nil)),
+	reflect.TypeOf((*any)(nil)).Elem(),
+	reflect.TypeOf(make(chan int)), // unsupported, left out
+	reflect.TypeOf(fuzzTree{}),
+	reflect.TypeOf(fuzzList{}),
+	reflect.TypeOf(fuzzA{}),
+	reflect.TypeOf(fuzzB{}),
+	reflect.TypeOf(fuzzMapTree{}),
+	reflect.TypeOf(fuzzNested{}),
+	reflect.TypeOf(fuzzM{}),
+	reflect.TypeOf(fuzzS{}),
+}
+
+// fuzzType builds a struct type from data: each byte picks a field's type
+// and how it's wrapped, and some bytes nest a further struct. The low four
+// bits pick the leaf, so bytes from 80 up are shifted along to reach the
+// leaves past the fifteenth.
+func fuzzType(data []byte, depth int) (reflect.Type, []byte) {
+	var fields []reflect.StructField
+	for len(data) > 0 && len(fields) < 8 {
+		b := data[0]
+		data = data[1:]
+		if b == 0xff {
+			break // end of this struct
+		}
+		var t reflect.Type
+		if b%16 == 15 && depth < 4 {
+			t, data = fuzzType(data, depth+1)
+		} else {
+			t = fuzzLeaves[(int(b%16)+int(b/80)*15)%len(fuzzLeaves)]
+		}
+		switch b / 16 % 5 {
+		case 1:
+			t = reflect.PointerTo(t)
+		case 2:
+			t = reflect.SliceOf(t)
+		case 3:
+			t = reflect.MapOf(reflect.TypeOf(""), t)
+		case 4:
+			t = reflect.ArrayOf(2, t)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("F%d", len(fields)),
+			Type: t,
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:"f%d"`, len(fields))),
+		})
+	}
+	return reflect.StructOf(fields), data
+}
+
+func FuzzGenerateSchema(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{9, 10, 11, 12})                 // each recursive type
+	f.Add([]byte{0x19, 0x2a, 0x3b, 0x4c})        // wrapped in pointer, slice, map, array
+	f.Add([]byte{0x0f, 0x0b, 0x1f, 0x0c, 0xff})  // nested structs reaching the mutual pair
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 12}) // every leaf
+	f.Add([]byte{0x2f, 0x2f, 0x2f, 0x2f, 0x09, 0xff, 0xff, 0xff, 0xff, 0x0a})
+	f.Add([]byte{0x0d, 0x0e, 0x50, 0x51})       // recursive map and slice, the mutual map/slice pair
+	f.Add([]byte{0x1d, 0x2e, 0x60, 0x71, 0x0d}) // the same, wrapped, and repeated
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		typ, _ := fuzzType(data, 0)
+
+		done := make(chan map[string]any, 1)
+		go func() { done <- GenerateSchema(typ) }()
+		var schema map[string]any
+		select {
+		case schema = <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("GenerateSchema(%v) did not terminate", typ)
+		}
+		if schema == nil {
+			t.Fatalf("GenerateSchema(%v) = nil", typ)
+		}
+
+		first, err := json.Marshal(schema)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+
+		// Every $ref resolves.
+		defs, _ := schema["$defs"].(map[string]any)
+		walk(schema, func(ref string) {
+			if ref == "#" {
+				return
+			}
+			name, ok := strings.CutPrefix(ref, "#/$defs/")
+			if !ok || defs[name] == nil {
+				t.Errorf("$ref %q doesn't resolve in $defs %v\nschema: %s", ref, keys(defs), first)
+			}
+		})
+
+		// Output, property order included, is the same every time.
+		for range 3 {
+			again, _ := json.Marshal(GenerateSchema(typ))
+			if string(again) != string(first) {
+				t.Fatalf("schema changed between runs:\n%s\n%s", first, again)
+			}
+		}
+
+		// "required" follows field order.
+		var want []string
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			if field.Type.Kind() == reflect.Ptr || GenerateSchema(field.Type) == nil {
+				continue
+			}
+			want = append(want, field.Tag.Get("json"))
+		}
+		got, _ := schema["required"].([]string)
+		if !slices.Equal(got, want) && !(len(got) == 0 && len(want) == 0) {
+			t.Errorf("required = %v, want %v", got, want)
+		}
+	})
+}
+
+// walk calls fn with every "$ref" in a schema.
+func walk(v any, fn func(ref string)) {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			fn(ref)
+		}
+		for _, e := range v {
+			walk(e, fn)
+		}
+	case []any:
+		for _, e := range v {
+			walk(e, fn)
+		}
+	}
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	slices.Sort(out)
+	return out
+}
@@ -0,0 +1,634 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError is one way a value breaks its schema.
+type FieldError struct {
+	Path    string // where in the value, like "items[2].name". Empty for the value itself.
+	Message string // what's wrong, like "must be at most 10, got 12"
+}
+
+func (e FieldError) String() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError lists everything wrong with a value, not just the first
+// problem, so an LLM can fix all of its arguments in one retry.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid arguments: " + e.Errors[0].String()
+	}
+	var b strings.Builder
+	b.WriteString("invalid arguments:")
+	for _, fe := range e.Errors {
+		b.WriteString("\n- " + fe.String())
+	}
+	return b.String()
+}
+
+// Validate checks a JSON document against a schema and returns a
+// *ValidationError describing every violation, or nil if it conforms.
+// Malformed JSON is reported as an ordinary error.
+//
+// It understands the parts of JSON Schema that GenerateSchema emits plus
+// the common constraint keywords: type, properties, required,
+// additionalProperties, items, enum, const, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength,
+// pattern, minItems, maxItems, uniqueItems, allOf, anyOf, oneOf and local
+// $refs, and format for the common formats (date-time, date, time, email,
+// uuid, uri, ipv4, ipv6, hostname). Other keywords (description, ...) are
+// ignored.
+func Validate(schema map[string]any, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after the JSON value")
+	}
+
+	vd := &validator{root: schema}
+	vd.check(schema, v, "")
+	if len(vd.errs) > 0 {
+		return &ValidationError{Errors: vd.errs}
+	}
+	return nil
+}
+
+// validator collects errors for one Validate call.
+type validator struct {
+	root map[string]any
+	errs []FieldError
+}
+
+func (vd *validator) fail(path, format string, args ...any) {
+	vd.errs = append(vd.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// matches reports whether v conforms to s, without recording errors - for
+// anyOf/oneOf, where failing branches are expected.
+func (vd *validator) matches(s map[string]any, v any) bool {
+	sub := &validator{root: vd.root}
+	sub.check(s, v, "")
+	return len(sub.errs) == 0
+}
+
+func (vd *validator) check(s map[string]any, v any, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		target := vd.resolve(ref)
+		if target == nil {
+			vd.fail(path, "schema has unresolvable $ref %q", ref)
+			return
+		}
+		s = target
+	}
+
+	if t, ok := s["type"]; ok && !typeMatches(t, v) {
+		vd.fail(path, "must be %s, got %s", describeType(t), jsonType(v))
+		return // the other keywords would only repeat the problem
+	}
+	if enum, ok := values(s["enum"]); ok && !contains(enum, v) {
+		vd.fail(path, "must be one of %s, got %s", list(enum), show(v))
+	}
+	if c, ok := s["const"]; ok && !equal(c, v) {
+		vd.fail(path, "must be %s, got %s", show(c), show(v))
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		vd.checkNumber(s, v, path)
+	case string:
+		vd.checkString(s, v, path)
+	case []any:
+		vd.checkArray(s, v, path)
+	case map[string]any:
+		vd.checkObject(s, v, path)
+	}
+
+	for _, sub := range schemas(s["allOf"]) {
+		vd.check(sub, v, path)
+	}
+	if alts := schemas(s["anyOf"]); len(alts) > 0 {
+		ok := false
+		for _, sub := range alts {
+			if vd.matches(sub, v) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			vd.fail(path, "doesn't match any of the allowed forms")
+		}
+	}
+	if one := schemas(s["oneOf"]); len(one) > 0 {
+		n := 0
+		for _, sub := range one {
+			if vd.matches(sub, v) {
+				n++
+			}
+		}
+		if n != 1 {
+			vd.fail(path, "must match exactly one of the allowed forms, matches %d", n)
+		}
+	}
+}
+
+func (vd *validator) checkNumber(s map[string]any, v json.Number, path string) {
+	f, err := v.Float64()
+	if err != nil {
+		return // out of float64 range; nothing sensible to compare
+	}
+	if min, ok := number(s["minimum"]); ok && f < min {
+		vd.fail(path, "must be at least %v, got %v", min, v)
+	}
+	if max, ok := number(s["maximum"]); ok && f > max {
+		vd.fail(path, "must be at most %v, got %v", max, v)
+	}
+	if min, ok := number(s["exclusiveMinimum"]); ok && f <= min {
+		vd.fail(path, "must be greater than %v, got %v", min, v)
+	}
+	if max, ok := number(s["exclusiveMaximum"]); ok && f >= max {
+		vd.fail(path, "must be less than %v, got %v", max, v)
+	}
+	if m, ok := number(s["multipleOf"]); ok && m > 0 {
+		if q := f / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			vd.fail(path, "must be a multiple of %v, got %v", m, v)
+		}
+	}
+}
+
+func (vd *validator) checkString(s map[string]any, v string, path string) {
+	n := utf8.RuneCountInString(v)
+	if min, ok := number(s["minLength"]); ok && float64(n) < min {
+		vd.fail(path, "must be at least %v characters, got %d", min, n)
+	}
+	if max, ok := number(s["maxLength"]); ok && float64(n) > max {
+		vd.fail(path, "must be at most %v characters, got %d", max, n)
+	}
+	if f, ok := s["format"].(string); ok {
+		if check, known := formats[f]; known && !check(v) {
+			vd.fail(path, "must be a valid %s, got %s", f, show(v))
+		}
+	}
+	if p, ok := s["pattern"].(string); ok {
+		re, err := compile(p)
+		if err != nil {
+			vd.fail(path, "schema has invalid pattern %q", p)
+		} else if !re.MatchString(v) {
+			vd.fail(path, "must match the pattern %s", p)
+		}
+	}
+}
+
+func (vd *validator) checkArray(s map[string]any, v []any, path string) {
+	if min, ok := number(s["minItems"]); ok && float64(len(v)) < min {
+		vd.fail(path, "must have at least %v items, got %d", min, len(v))
+	}
+	if max, ok := number(s["maxItems"]); ok && float64(len(v)) > max {
+		vd.fail(path, "must have at most %v items, got %d", max, len(v))
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	dup:
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if equal(v[i], v[j]) {
+					vd.fail(path, "items must be unique, %s appears twice", show(v[i]))
+					break dup
+				}
+			}
+		}
+	}
+	if items, ok := toSchema(s["items"]); ok {
+		for i, item := range v {
+			vd.check(items, item, path+"["+strconv.Itoa(i)+"]")
+		}
+	}
+}
+
+func (vd *validator) checkObject(s map[string]any, v map[string]any, path string) {
+	required := map[string]bool{}
+	for _, name := range stringList(s["required"]) {
+		required[name] = true
+		if _, ok := v[name]; !ok {
+			vd.fail(join(path, name), "is required")
+		}
+	}
+
+	props, _ := toSchemaMap(s["properties"])
+	// Sorted, so the error list is the same every time.
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		// null for an optional property means "not given" - models in
+		// strict mode send it for every field they'd otherwise leave out.
+		if v[k] == nil && !required[k] {
+			continue
+		}
+		if ps, ok := props[k]; ok {
+			vd.check(ps, v[k], join(path, k))
+			continue
+		}
+		switch extra := s["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				vd.fail(join(path, k), "is not an allowed property")
+			}
+		case map[string]any:
+			vd.check(extra, v[k], join(path, k))
+		}
+	}
+}
+
+// resolve finds the schema a local $ref points at: "#" or "#/$defs/Name"
+// (also "#/definitions/Name").
+func (vd *validator) resolve(ref string) map[string]any {
+	if ref == "#" {
+		return vd.root
+	}
+	rest, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil
+	}
+	var cur any = vd.root
+	for _, part := range strings.Split(rest, "/") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		cur = m[part]
+	}
+	s, _ := toSchema(cur)
+	return s
+}
+
+// formats checks the "format" values models are commonly asked for.
+// Unknown formats are accepted, as JSON Schema says they should be.
+var formats = map[string]func(string) bool{
+	"date-time": func(s string) bool { _, err := time.Parse(time.RFC3339, s); return err == nil },
+	"date":      func(s string) bool { _, err := time.Parse(time.DateOnly, s); return err == nil },
+	"time": func(s string) bool {
+		_, err := time.Parse("15:04:05Z07:00", s)
+		if err != nil {
+			_, err = time.Parse(time.TimeOnly, s)
+		}
+		return err == nil
+	},
+	"email": func(s string) bool {
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	},
+	"uuid": func(s string) bool { return uuidPattern.MatchString(s) },
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	},
+	"ipv4": func(s string) bool {
+		ip, err := netip.ParseAddr(s)
+		return err == nil && ip.Is4()
+	},
+	"ipv6": func(s string) bool {
+		ip, err := netip.ParseAddr(s)
+		return err == nil && ip.Is6()
+	},
+	"hostname": func(s string) bool { return hostnamePattern.MatchString(s) },
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+)
+
+// patterns caches compiled "pattern" regexps - the same schema is
+// validated on every call of a tool.
+var patterns sync.Map // string -> *regexp.Regexp
+
+func compile(p string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(p); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(p, re)
+	return re, nil
+}
+
+// typeMatches reports whether v has the JSON type t, which is a type name
+// or a list of them.
+func typeMatches(t any, v any) bool {
+	switch t := t.(type) {
+	case string:
+		return hasType(t, v)
+	case []any:
+		for _, name := range t {
+			if s, ok := name.(string); ok && hasType(s, v) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, name := range t {
+			if hasType(name, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true // not a type we understand - don't reject on it
+}
+
+func hasType(name string, v any) bool {
+	switch name {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		if _, err := n.Int64(); err == nil {
+			return true
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	}
+	return jsonType(v) == name
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func describeType(t any) string {
+	switch t := t.(type) {
+	case string:
+		return article(t)
+	case []any:
+		names := make([]string, 0, len(t))
+		for _, n := range t {
+			names = append(names, fmt.Sprint(n))
+		}
+		return strings.Join(names, " or ")
+	case []string:
+		return strings.Join(t, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func article(name string) string {
+	if name == "integer" || name == "object" || name == "array" {
+		return "an " + name
+	}
+	return "a " + name
+}
+
+// equal compares two JSON values. Numbers compare by value, so 1 and 1.0
+// are equal.
+func equal(a, b any) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// values reads a list-valued keyword like "enum", which may be any kind of
+// slice when the schema was built in Go.
+func values(v any) ([]any, bool) {
+	if v, ok := v.([]any); ok {
+		return v, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, true
+}
+
+func contains(list []any, v any) bool {
+	for _, item := range list {
+		if equal(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// number reads a numeric schema keyword or value, whether it came from Go
+// code (int, float64) or decoded JSON (json.Number).
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// show formats a JSON value for an error message.
+func show(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(b) > 60 {
+		return string(b[:57]) + "..."
+	}
+	return string(b)
+}
+
+func list(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = show(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stringList reads a list of strings from a schema keyword like "required",
+// which is []string when built in Go and []any when decoded from JSON.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func toSchema(v any) (map[string]any, bool) {
+	s, ok := v.(map[string]any)
+	return s, ok
+}
+
+// toSchemaMap reads a keyword whose value maps names to schemas, like
+// "properties".
+func toSchemaMap(v any) (map[string]map[string]any, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	out := make(map[string]map[string]any, len(m))
+	for k, s := range m {
+		if s, ok := s.(map[string]any); ok {
+			out[k] = s
+		}
+	}
+	return out, true
+}
+
+// schemas reads a keyword whose value is a list of schemas, like "allOf".
+func schemas(v any) []map[string]any {
+	var out []map[string]any
+	switch v := v.(type) {
+	case []any:
+		for _, s := range v {
+			if s, ok := s.(map[string]any); ok {
+				out = append(out, s)
+			}
+		}
+	case []map[string]any:
+		out = v
+	}
+	return out
+}
+
+// join extends a path with a property name.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// ApplyDefaults fills in the "default" of every top-level property the
+// JSON object leaves out, so a tool gets default:"c" rather than the zero
+// value when the model skips the field. data is returned unchanged if
+// nothing is missing or it isn't an object.
+func ApplyDefaults(schema map[string]any, data []byte) []byte {
+	props, ok := toSchemaMap(schema["properties"])
+	if !ok {
+		return data
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return data
+	}
+
+	changed := false
+	for name, ps := range props {
+		def, ok := ps["default"]
+		if !ok {
+			continue
+		}
+		if v, present := obj[name]; present && string(v) != "null" {
+			continue
+		}
+		raw, err := json.Marshal(def)
+		if err != nil {
+			continue
+		}
+		obj[name] = raw
+		changed = true
+	}
+	if !changed {
+		return data
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return data
+	}
+	return out
+}
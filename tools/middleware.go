@@ -0,0 +1,57 @@
+package tools
+
+import "context"
+
+// Call is one tool call on its way to being executed.
+type Call struct {
+	ID        string // tool_call_id from the LLM
+	Name      string // tool name
+	Arguments string // raw JSON arguments from the LLM
+}
+
+// Handler executes a tool call. Registry.Handle is the innermost one - the
+// one that actually runs the tool.
+type Handler func(ctx context.Context, call Call) (Result, error)
+
+// Middleware wraps a Handler with cross-cutting behavior, the way
+// http.Handler middleware does: logging, metrics, permission checks,
+// argument rewriting, caching. It can inspect or change the call, skip
+// next entirely (returning its own result or error), or post-process what
+// next returns.
+//
+//	func Logging(next tools.Handler) tools.Handler {
+//	    return func(ctx context.Context, call tools.Call) (tools.Result, error) {
+//	        start := time.Now()
+//	        res, err := next(ctx, call)
+//	        log.Printf("tool %s took %s (err=%v)", call.Name, time.Since(start), err)
+//	        return res, err
+//	    }
+//	}
+//
+//	func ReadOnly(next tools.Handler) tools.Handler {
+//	    return func(ctx context.Context, call tools.Call) (tools.Result, error) {
+//	        if strings.HasPrefix(call.Name, "delete_") {
+//	            return tools.Result{}, errors.New("not allowed in read-only mode")
+//	        }
+//	        return next(ctx, call)
+//	    }
+//	}
+//
+// An error from a middleware is reported to the LLM as a tool error, just
+// like an error from the tool.
+type Middleware func(next Handler) Handler
+
+// Chain wraps h in mw. The first middleware is the outermost: it sees the
+// call first and the result last.
+func Chain(h Handler, mw ...Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// Handle is the Handler that runs a registered tool: ExecuteResult with the
+// call's name and arguments.
+func (r *Registry) Handle(ctx context.Context, call Call) (Result, error) {
+	return r.ExecuteResult(ctx, call.Name, call.Arguments)
+}
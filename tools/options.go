@@ -0,0 +1,16 @@
+package tools
+
+// ToolOption configures a tool at registration.
+type ToolOption func(*ToolDefinition)
+
+// WithStrict turns on OpenAI's strict mode for the tool: the model's
+// arguments are guaranteed to match the schema's shape - every field
+// present, right types, enums respected. The schema is converted to the
+// subset strict mode supports first: optional fields become nullable, and
+// constraints strict mode can't enforce (minLength, maxLength, ...) are
+// still checked by Execute. Other providers ignore it.
+func WithStrict() ToolOption {
+	return func(def *ToolDefinition) {
+		def.Strict = true
+	}
+}
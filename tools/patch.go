@@ -0,0 +1,549 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PatcherOption configures NewPatcher.
+type PatcherOption func(*patcher)
+
+type patcher struct {
+	root    string
+	dryRun  bool // every change is only checked, never written
+	maxSize int64
+}
+
+// PatcherDryRunOnly makes every change a dry run: patches are checked and
+// reported, never written. For an agent that proposes changes for a human
+// to apply.
+func PatcherDryRunOnly() PatcherOption {
+	return func(p *patcher) {
+		p.dryRun = true
+	}
+}
+
+// PatcherMaxFileSize refuses to patch files larger than n bytes. The
+// default is 10 MiB.
+func PatcherMaxFileSize(n int64) PatcherOption {
+	return func(p *patcher) {
+		p.maxSize = n
+	}
+}
+
+// ApplyPatchArgs are the apply_patch tool's arguments.
+type ApplyPatchArgs struct {
+	Patch  string `json:"patch" description:"A unified diff, as git diff or diff -u print it. May change several files. Use /dev/null as the old file to create one, as the new file to delete one."`
+	DryRun bool   `json:"dry_run,omitempty" description:"Only check that the patch applies, without changing anything."`
+}
+
+// EditFileArgs are the edit_file tool's arguments.
+type EditFileArgs struct {
+	Path       string `json:"path" description:"File to edit, relative to the project root."`
+	Search     string `json:"search" description:"Exact text to replace, including indentation. Leave empty to create a new file with the replacement as its content."`
+	Replace    string `json:"replace" description:"Text to put in its place."`
+	ReplaceAll bool   `json:"replace_all,omitempty" description:"Replace every occurrence. Otherwise the search text must occur exactly once."`
+	DryRun     bool   `json:"dry_run,omitempty" description:"Only check that the edit applies, without changing anything."`
+}
+
+// NewPatcher returns tools that change files under root: apply_patch,
+// which applies unified diffs, and edit_file, which replaces one exact
+// piece of text with another. Nothing outside root can be touched - paths
+// are resolved inside it, symbolic links included.
+//
+// A patch applies completely or not at all: if any hunk of any file
+// conflicts, nothing is written, and the result says which hunk failed and
+// what the file has there instead, so the model can fix the patch. Hunks
+// may be off by some lines from the positions in their headers, as
+// patches from a model usually are.
+//
+// Every change is recorded as a "file.created", "file.modified" or
+// "file.deleted" Effect.
+//
+//	patcher, err := tools.NewPatcher("./workspace")
+//	a.RegisterToolset(patcher)
+func NewPatcher(root string, opts ...PatcherOption) (Toolset, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return Toolset{}, fmt.Errorf("tools: patcher root: %w", err)
+	}
+	if !info.IsDir() {
+		return Toolset{}, fmt.Errorf("tools: patcher root %s is not a directory", root)
+	}
+	p := &patcher{root: root, maxSize: 10 << 20}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return Toolset{
+		Name: "patcher",
+		Prompt: "To change files, use edit_file for small, targeted edits and apply_patch for larger or multi-file changes. " +
+			"Paths are relative to the project root. Read a file before editing it, so the text you replace matches exactly. " +
+			"If a change is rejected as a conflict, read the file again and retry with its current content.",
+		Tools: []Tool{
+			{Name: "apply_patch", Description: "Apply a unified diff to files in the project.", Func: p.applyPatch},
+			{Name: "edit_file", Description: "Replace an exact piece of text in a file, or create a new file.", Func: p.editFile},
+		},
+	}, nil
+}
+
+// change is the new state of one file, worked out before anything is
+// written.
+type change struct {
+	path    string
+	content []byte // nil when deleting
+	kind    string // "file.created", "file.modified" or "file.deleted"
+	summary string
+}
+
+func (p *patcher) applyPatch(ctx context.Context, args ApplyPatchArgs) (string, error) {
+	files, err := parseUnifiedDiff(args.Patch)
+	if err != nil {
+		return "", err
+	}
+	root, err := os.OpenRoot(p.root)
+	if err != nil {
+		return "", fmt.Errorf("opening project root: %w", err)
+	}
+	defer root.Close()
+
+	var changes []change
+	var conflicts []string
+	for _, f := range files {
+		c, err := p.patchFile(root, f)
+		if err != nil {
+			conflicts = append(conflicts, err.Error())
+			continue
+		}
+		changes = append(changes, c)
+	}
+	if len(conflicts) > 0 {
+		return "", fmt.Errorf("patch not applied, nothing was changed:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return p.commit(ctx, root, changes, args.DryRun)
+}
+
+func (p *patcher) editFile(ctx context.Context, args EditFileArgs) (string, error) {
+	name, err := cleanPath(args.Path)
+	if err != nil {
+		return "", err
+	}
+	root, err := os.OpenRoot(p.root)
+	if err != nil {
+		return "", fmt.Errorf("opening project root: %w", err)
+	}
+	defer root.Close()
+
+	if args.Search == "" {
+		if _, err := root.Stat(name); err == nil {
+			return "", fmt.Errorf("%s already exists; give the text to replace to edit it", name)
+		}
+		c := change{path: name, content: []byte(args.Replace), kind: "file.created",
+			summary: fmt.Sprintf("created %s (%d lines)", name, countLines(args.Replace))}
+		return p.commit(ctx, root, []change{c}, args.DryRun)
+	}
+
+	old, err := p.read(root, name)
+	if err != nil {
+		return "", err
+	}
+	text := string(old)
+	n := strings.Count(text, args.Search)
+	switch {
+	case n == 0:
+		return "", fmt.Errorf("%s: search text not found%s", name, nearMiss(text, args.Search))
+	case n > 1 && !args.ReplaceAll:
+		return "", fmt.Errorf("%s: search text occurs %d times, at lines %s; include more surrounding text to pick one, or set replace_all",
+			name, n, occurrenceLines(text, args.Search))
+	}
+	if !args.ReplaceAll {
+		n = 1
+	}
+	c := change{path: name, content: []byte(strings.Replace(text, args.Search, args.Replace, n)), kind: "file.modified",
+		summary: fmt.Sprintf("%s: replaced %d occurrence(s)", name, n)}
+	return p.commit(ctx, root, []change{c}, args.DryRun)
+}
+
+// commit writes the changes, or only reports them on a dry run.
+func (p *patcher) commit(ctx context.Context, root *os.Root, changes []change, dryRun bool) (string, error) {
+	var b strings.Builder
+	if dryRun || p.dryRun {
+		b.WriteString("Dry run, nothing was changed. The change applies cleanly:\n")
+		for _, c := range changes {
+			b.WriteString("- " + c.summary + "\n")
+		}
+		return b.String(), nil
+	}
+	for i, c := range changes {
+		var err error
+		if c.content == nil {
+			err = root.Remove(c.path)
+		} else {
+			err = writeInRoot(root, c.path, c.content)
+		}
+		if err != nil {
+			done := make([]string, 0, i)
+			for _, d := range changes[:i] {
+				done = append(done, d.path)
+			}
+			return "", fmt.Errorf("writing %s: %w (already changed: %s)", c.path, err, strings.Join(done, ", "))
+		}
+		RecordEffect(ctx, Effect{Kind: c.kind, Target: c.path, Summary: c.summary})
+		b.WriteString("- " + c.summary + "\n")
+	}
+	return "Applied:\n" + b.String(), nil
+}
+
+func (p *patcher) read(root *os.Root, name string) ([]byte, error) {
+	f, err := root.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s does not exist", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, p.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.maxSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, p.maxSize)
+	}
+	return data, nil
+}
+
+// writeInRoot replaces name's content, creating it and its directories
+// as needed.
+func writeInRoot(root *os.Root, name string, data []byte) error {
+	dir := path.Dir(name)
+	if dir != "." {
+		built := ""
+		for _, part := range strings.Split(dir, "/") {
+			built = path.Join(built, part)
+			if err := root.Mkdir(built, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+				return err
+			}
+		}
+	}
+	f, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// cleanPath turns a path from the model into a clean relative one, or
+// rejects it.
+func cleanPath(name string) (string, error) {
+	name = filepath.ToSlash(strings.TrimSpace(name))
+	if name == "" {
+		return "", errors.New("empty path")
+	}
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || clean == "." {
+		return "", fmt.Errorf("path %q is outside the project root", name)
+	}
+	return clean, nil
+}
+
+// filePatch is the part of a unified diff for one file.
+type filePatch struct {
+	oldPath, newPath string // "" for /dev/null
+	hunks            []hunk
+}
+
+type hunk struct {
+	header   string
+	oldStart int      // 1-based line the hunk claims to start at
+	lines    []string // each starting with ' ', '-' or '+'
+	noEOL    bool     // the new side ends without a newline
+}
+
+// parseUnifiedDiff splits a unified diff into files and hunks. Line counts
+// in hunk headers are ignored - a hunk runs until the next header - since
+// hand-written patches often get them wrong.
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []filePatch
+	var cur *filePatch
+	var h *hunk
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			files = append(files, filePatch{oldPath: diffPath(line[4:]), newPath: diffPath(lines[i+1][4:])})
+			cur, h = &files[len(files)-1], nil
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, errors.New("hunk before any ---/+++ file header")
+			}
+			cur.hunks = append(cur.hunks, hunk{header: line, oldStart: hunkStart(line)})
+			h = &cur.hunks[len(cur.hunks)-1]
+		case h != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file", for the line before it.
+			if n := len(h.lines); n > 0 && h.lines[n-1][0] != '-' {
+				h.noEOL = true
+			}
+		case h != nil && line != "" && strings.ContainsRune(" -+", rune(line[0])):
+			h.lines = append(h.lines, line)
+		case h != nil && line == "" && i < len(lines)-1:
+			// An empty context line whose leading space got stripped.
+			h.lines = append(h.lines, " ")
+		default:
+			h = nil // "diff --git", "index ...", commentary
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no files in the patch; expected a unified diff with ---/+++ headers and @@ hunks")
+	}
+	// Each file's section is applied to the file as it is on disk, so a
+	// second section for the same file would undo the first.
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if f.oldPath == "" && f.newPath == "" {
+			return nil, errors.New("a file in the patch is /dev/null on both sides")
+		}
+		name := path.Clean(cmp.Or(f.newPath, f.oldPath))
+		if seen[name] {
+			return nil, fmt.Errorf("%s has more than one ---/+++ section in the patch; put all its hunks under one", name)
+		}
+		seen[name] = true
+	}
+	return files, nil
+}
+
+// diffPath reads the path from a ---/+++ line: without a timestamp, and
+// without git's a/ and b/ prefixes. /dev/null becomes "".
+func diffPath(s string) string {
+	s, _, _ = strings.Cut(s, "\t")
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// hunkStart returns the old-side start line of "@@ -l,s +l,s @@", or 0.
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], "-") {
+		return 0
+	}
+	n, _, _ := strings.Cut(fields[1][1:], ",")
+	start, _ := strconv.Atoi(n)
+	return start
+}
+
+// patchFile works out the new content of one file.
+func (p *patcher) patchFile(root *os.Root, f filePatch) (change, error) {
+	switch {
+	case f.oldPath == "": // a new file
+		name, err := cleanPath(f.newPath)
+		if err != nil {
+			return change{}, err
+		}
+		if _, err := root.Stat(name); err == nil {
+			return change{}, fmt.Errorf("%s: already exists, but the patch creates it", name)
+		}
+		var lines []string
+		noEOL := false
+		for _, h := range f.hunks {
+			for _, l := range h.lines {
+				if l[0] == '+' || l[0] == ' ' {
+					lines = append(lines, l[1:])
+				}
+			}
+			noEOL = h.noEOL
+		}
+		content := strings.Join(lines, "\n")
+		if !noEOL && len(lines) > 0 {
+			content += "\n"
+		}
+		return change{path: name, content: []byte(content), kind: "file.created",
+			summary: fmt.Sprintf("created %s (%d lines)", name, len(lines))}, nil
+
+	case f.newPath == "": // a deletion
+		name, err := cleanPath(f.oldPath)
+		if err != nil {
+			return change{}, err
+		}
+		if _, err := root.Stat(name); err != nil {
+			return change{}, fmt.Errorf("%s: does not exist, but the patch deletes it", name)
+		}
+		return change{path: name, kind: "file.deleted", summary: "deleted " + name}, nil
+	}
+
+	name, err := cleanPath(f.newPath)
+	if err != nil {
+		return change{}, err
+	}
+	if f.oldPath != f.newPath {
+		return change{}, fmt.Errorf("%s: renames (from %s) aren't supported; delete and create instead", name, f.oldPath)
+	}
+	data, err := p.read(root, name)
+	if err != nil {
+		return change{}, err
+	}
+	text := string(data)
+	eol := "\n"
+	if strings.Contains(text, "\r\n") {
+		eol = "\r\n"
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	trailingNL := strings.HasSuffix(text, "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+
+	added, removed := 0, 0
+	offset := 0 // how far earlier hunks moved the lines below them
+	for i, h := range f.hunks {
+		var old, repl []string
+		for _, l := range h.lines {
+			switch l[0] {
+			case ' ':
+				old = append(old, l[1:])
+				repl = append(repl, l[1:])
+			case '-':
+				old = append(old, l[1:])
+				removed++
+			case '+':
+				repl = append(repl, l[1:])
+				added++
+			}
+		}
+		want := max(h.oldStart-1+offset, 0)
+		if h.oldStart == 0 && len(old) == 0 {
+			want = len(lines) // "@@ -0,0 +1,n @@" into an empty file
+		}
+		at := findLines(lines, old, want)
+		if at < 0 {
+			return change{}, hunkConflict(name, i, h, lines, old, want)
+		}
+		lines = append(lines[:at], append(repl, lines[at+len(old):]...)...)
+		offset += len(repl) - len(old)
+		if i == len(f.hunks)-1 && at+len(repl) == len(lines) {
+			trailingNL = !h.noEOL
+		}
+	}
+
+	out := strings.Join(lines, "\n")
+	if trailingNL && len(lines) > 0 {
+		out += "\n"
+	}
+	out = strings.ReplaceAll(out, "\n", eol)
+	return change{path: name, content: []byte(out), kind: "file.modified",
+		summary: fmt.Sprintf("%s: %d hunk(s) applied (+%d -%d)", name, len(f.hunks), added, removed)}, nil
+}
+
+// findLines returns where old occurs in lines, taking the occurrence
+// nearest to want. Lines that differ only in trailing whitespace match.
+// It returns -1 if old doesn't occur.
+func findLines(lines, old []string, want int) int {
+	if len(old) == 0 {
+		return min(want, len(lines))
+	}
+	best := -1
+	for at := 0; at+len(old) <= len(lines); at++ {
+		if !linesMatch(lines[at:at+len(old)], old) {
+			continue
+		}
+		if best < 0 || abs(at-want) < abs(best-want) {
+			best = at
+		}
+	}
+	return best
+}
+
+func linesMatch(a, b []string) bool {
+	for i := range b {
+		if strings.TrimRight(a[i], " \t\r") != strings.TrimRight(b[i], " \t\r") {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// hunkConflict explains why a hunk doesn't apply: the first line it
+// expects that the file doesn't have at that point.
+func hunkConflict(name string, i int, h hunk, lines, old []string, want int) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: hunk %d (%s) does not apply.", name, i+1, h.header)
+	// Line the hunk up where it says it goes and find the first mismatch.
+	for j, l := range old {
+		at := want + j
+		if at >= len(lines) {
+			fmt.Fprintf(&b, " Expected line %d to be %q, but the file has only %d lines.", at+1, l, len(lines))
+			return errors.New(b.String())
+		}
+		if strings.TrimRight(lines[at], " \t\r") != strings.TrimRight(l, " \t\r") {
+			fmt.Fprintf(&b, " Expected line %d to be %q, found %q.", at+1, l, lines[at])
+			return errors.New(b.String())
+		}
+	}
+	b.WriteString(" Its lines aren't anywhere in the file.")
+	return errors.New(b.String())
+}
+
+// nearMiss explains a failed search: whether the text occurs with
+// different whitespace, or where its first line does.
+func nearMiss(text, search string) string {
+	squash := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	if strings.Contains(squash(text), squash(search)) {
+		return "; it occurs with different whitespace or indentation - copy it exactly"
+	}
+	first := strings.TrimSpace(strings.SplitN(search, "\n", 2)[0])
+	if first == "" {
+		return ""
+	}
+	if lines := occurrenceLines(text, first); lines != "" {
+		return fmt.Sprintf("; its first line occurs at line(s) %s, but what follows differs", lines)
+	}
+	return ""
+}
+
+// occurrenceLines lists the line numbers where s occurs in text.
+func occurrenceLines(text, s string) string {
+	var nums []string
+	for from := 0; len(nums) < 10; {
+		i := strings.Index(text[from:], s)
+		if i < 0 {
+			break
+		}
+		nums = append(nums, strconv.Itoa(strings.Count(text[:from+i], "\n")+1))
+		from += i + max(len(s), 1)
+	}
+	return strings.Join(nums, ", ")
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(s, "\n"), "\n") + 1
+}
@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string // before
+		patch   string
+		want    map[string]string // after; files not listed are unchanged
+		gone    []string          // files the patch deletes
+		wantErr string            // substring of the error; "" for success
+	}{
+		{
+			name:  "hunk off by some lines",
+			files: map[string]string{"a.txt": "1\n2\n3\n4\n5\n6\n7\n8\n"},
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n 5\n-6\n+six\n 7\n",
+			want:  map[string]string{"a.txt": "1\n2\n3\n4\n5\nsix\n7\n8\n"},
+		},
+		{
+			name:  "later hunk shifted by an earlier one",
+			files: map[string]string{"a.txt": "a\nb\nc\nd\ne\nf\n"},
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,4 @@\n a\n+a1\n+a2\n b\n@@ -5,2 +7,2 @@\n e\n-f\n+F\n",
+			want:  map[string]string{"a.txt": "a\na1\na2\nb\nc\nd\ne\nF\n"},
+		},
+		{
+			name:  "nearest of several matches",
+			files: map[string]string{"a.txt": "x\ny\nx\ny\nx\ny\n"},
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -5,2 +5,2 @@\n x\n-y\n+Y\n",
+			want:  map[string]string{"a.txt": "x\ny\nx\ny\nx\nY\n"},
+		},
+		{
+			name:  "no newline at end, kept",
+			files: map[string]string{"a.txt": "a\nb"},
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+B\n\\ No newline at end of file\n",
+			want:  map[string]string{"a.txt": "a\nB"},
+		},
+		{
+			name:  "no newline at end, added",
+			files: map[string]string{"a.txt": "a\nb"},
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			want:  map[string]string{"a.txt": "a\nb\n"},
+		},
+		{
+			name:  "no newline at end, removed",
+			files: map[string]string{"a.txt": "a\nb\n"},
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+			want:  map[string]string{"a.txt": "a\nb"},
+		},
+		{
+			name:  "CRLF file keeps its line endings",
+			files: map[string]string{"a.txt": "a\r\nb\r\nc\r\n"},
+			patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:  map[string]string{"a.txt": "a\r\nB\r\nc\r\n"},
+		},
+		{
+			name:  "CRLF patch",
+			files: map[string]string{"a.txt": "a\nb\n"},
+			patch: "--- a/a.txt\r\n+++ b/a.txt\r\n@@ -1,2 +1,2 @@\r\n a\r\n-b\r\n+B\r\n",
+			want:  map[string]string{"a.txt": "a\nB\n"},
+		},
+		{
+			name:  "create",
+			patch: "--- /dev/null\n+++ b/dir/new.txt\n@@ -0,0 +1,2 @@\n+hello\n+world\n",
+			want:  map[string]string{"dir/new.txt": "hello\nworld\n"},
+		},
+		{
+			name:  "create without newline at end",
+			patch: "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n",
+			want:  map[string]string{"new.txt": "hello"},
+		},
+		{
+			name:  "delete",
+			files: map[string]string{"old.txt": "bye\n"},
+			patch: "--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n",
+			gone:  []string{"old.txt"},
+		},
+		{
+			name:  "several files",
+			files: map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			patch: "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+				"diff --git a/b.txt b/b.txt\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-b\n+B\n",
+			want: map[string]string{"a.txt": "A\n", "b.txt": "B\n"},
+		},
+		{
+			name:    "conflict names the line",
+			files:   map[string]string{"a.txt": "a\nb\nc\n"},
+			patch:   "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n a\n-x\n+y\n",
+			wantErr: `a.txt: hunk 1 (@@ -1,2 +1,2 @@) does not apply. Expected line 2 to be "x", found "b".`,
+		},
+		{
+			name:    "conflict past the end",
+			files:   map[string]string{"a.txt": "a\n"},
+			patch:   "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			wantErr: `Expected line 2 to be "b", but the file has only 1 lines.`,
+		},
+		{
+			name:    "conflict in one file changes no file",
+			files:   map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+			patch:   "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-x\n+X\n",
+			wantErr: "patch not applied, nothing was changed",
+		},
+		{
+			name:    "create over an existing file",
+			files:   map[string]string{"a.txt": "a\n"},
+			patch:   "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1 @@\n+b\n",
+			wantErr: "a.txt: already exists, but the patch creates it",
+		},
+		{
+			name:    "delete a missing file",
+			patch:   "--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n",
+			wantErr: "gone.txt: does not exist, but the patch deletes it",
+		},
+		{
+			name:    "same file twice",
+			files:   map[string]string{"a.txt": "a\nb\n"},
+			patch:   "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n--- a/a.txt\n+++ b/a.txt\n@@ -2 +2 @@\n-b\n+B\n",
+			wantErr: "a.txt has more than one ---/+++ section",
+		},
+		{
+			name:    "outside the root",
+			patch:   "--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n",
+			wantErr: "outside the project root",
+		},
+		{
+			name:    "not a diff",
+			patch:   "just some text\n",
+			wantErr: "no files in the patch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			p := &patcher{root: root, maxSize: 10 << 20}
+
+			_, err := p.applyPatch(context.Background(), ApplyPatchArgs{Patch: tt.patch})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				for name, content := range tt.files {
+					if got := readFile(t, root, name); got != content {
+						t.Errorf("%s changed by a failed patch: %q", name, got)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.want {
+				if got := readFile(t, root, name); got != content {
+					t.Errorf("%s = %q, want %q", name, got, content)
+				}
+			}
+			for _, name := range tt.gone {
+				if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+					t.Errorf("%s still exists", name)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPatchDryRun(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := &patcher{root: root, maxSize: 10 << 20}
+	out, err := p.applyPatch(context.Background(), ApplyPatchArgs{
+		Patch:  "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n",
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "Dry run") {
+		t.Errorf("output = %q, want a dry run report", out)
+	}
+	if got := readFile(t, root, "a.txt"); got != "a\n" {
+		t.Errorf("dry run changed the file: %q", got)
+	}
+}
+
+func readFile(t *testing.T, root, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,63 @@
+//go:build linux
+
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// startShell starts cmd on a new pseudo-terminal and returns its master
+// side. Echo is turned off, so what comes back is only the programs'
+// output.
+func startShell(cmd *exec.Cmd) (io.ReadWriteCloser, bool, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, false, fmt.Errorf("opening pty: %w", err)
+	}
+	var n uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		master.Close()
+		return nil, false, fmt.Errorf("pty number: %w", err)
+	}
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, false, fmt.Errorf("unlocking pty: %w", err)
+	}
+	tty, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, false, fmt.Errorf("opening pty: %w", err)
+	}
+	defer tty.Close()
+
+	var t syscall.Termios
+	if err := ioctl(tty.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t))); err == nil {
+		t.Lflag &^= syscall.ECHO
+		t.Oflag &^= syscall.ONLCR
+		ioctl(tty.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&t)))
+	}
+	// A wide window, so programs that format for the terminal don't wrap.
+	ws := struct{ rows, cols, x, y uint16 }{rows: 50, cols: 200}
+	ioctl(tty.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	if err := cmd.Start(); err != nil {
+		master.Close()
+		return nil, false, err
+	}
+	return master, true, nil
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+
+package tools
+
+import (
+	"io"
+	"os"
+	"os/exec"
+)
+
+// startShell starts cmd with pipes where there's no pseudo-terminal
+// support: state still carries over between commands, but programs see no
+// terminal and a timed out command can't be interrupted.
+func startShell(cmd *exec.Cmd) (io.ReadWriteCloser, bool, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, false, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, false, err
+	}
+	cmd.Stdout, cmd.Stderr = w, w
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return nil, false, err
+	}
+	w.Close()
+	return pipeShell{r, stdin}, false, nil
+}
+
+type pipeShell struct {
+	io.Reader
+	io.WriteCloser
+}
+
+func (p pipeShell) Close() error {
+	p.WriteCloser.Close()
+	return p.Reader.(*os.File).Close()
+}
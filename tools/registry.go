@@ -1,10 +1,14 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"go-agent-sdk/llm"
 	"go-agent-sdk/tools/jsonschema"
 	"reflect"
+	"sync"
+	"time"
 )
 
 // ToolDefinition wraps a Go function so the Agent can understand and execute it.
@@ -25,36 +29,79 @@ type ToolDefinition struct {
 	// We need this to create new instances when the LLM calls the tool.
 	ArgsType reflect.Type
 
+	// TakesContext is true when the function's first parameter is a
+	// context.Context. Execute passes the Run context through so the tool
+	// can respect cancellation and deadlines.
+	TakesContext bool
+
+	// Timeout, when positive, caps how long one call may run (see
+	// WithTimeout). Zero means no limit beyond the Run context.
+	Timeout time.Duration
+
+	// Strict asks providers that support it (OpenAI) to guarantee the
+	// arguments match Schema exactly (see WithStrict).
+	Strict bool
+
 	// Schema is the JSON Schema describing the function's parameters.
 	// This gets sent to the LLM so it knows what arguments to provide.
 	// It's a map[string]any (Go's version of a flexible dict) because
 	// JSON Schema has nested objects.
+	//
+	// Generated lazily the first time the tool is offered to the LLM and
+	// shared between every tool with the same argument type - treat it as
+	// read-only. Nil until then; use Registry.Schema to force it.
 	Schema map[string]any
+
+	// schemaJSON is Schema pre-encoded. GetAllTools sends this instead of
+	// the map so the schema is marshaled once, not on every request.
+	schemaJSON json.RawMessage
 }
 
 // Registry stores all the tool definitions the Agent can use.
 // Think of it as a toolbox where each tool has a name tag.
+//
+// A Registry is safe for concurrent use - tools can be executed in parallel
+// while other goroutines read the tool list.
 type Registry struct {
+	mu          sync.RWMutex
 	definitions map[string]ToolDefinition
+	order       []string                 // registration order, so the tool list is stable between requests
+	tools       []llm.Tool               // cached GetAllTools result. nil means it needs rebuilding.
+	prompts     map[string]func() string // toolset name -> prompt
+	toolsets    []string                 // toolset registration order
 }
 
 // NewRegistry creates an empty Registry ready for tools to be added.
 func NewRegistry() *Registry {
 	return &Registry{
 		definitions: make(map[string]ToolDefinition),
+		prompts:     make(map[string]func() string),
 	}
 }
 
 // Register adds a function to the Registry so the Agent can use it.
 // The function must take exactly one argument (a struct with JSON tags)
-// and return a string (or something that can be converted to string).
+// and return either a string, or a string and an error. It may also take
+// a context.Context first, which is how tools that do I/O get cancellation
+// and deadlines from the agent's Run context:
+//
+//	func(args Args) string
+//	func(args Args) (string, error)
+//	func(ctx context.Context, args Args) (string, error)
+//
+// Returning an error is the right choice for tools that do real I/O
+// (HTTP calls, DB queries) - the error is sent back to the LLM as a
+// tool error instead of being smuggled inside the result string.
+//
+// opts configure the tool, e.g. WithTimeout for tools that might hang.
 //
 // What happens here:
 //  1. We validate that 'function' is actually a function (not a string or int)
-//  2. We check it has exactly one argument (the LLM can't call functions with 0 or 2+ args)
-//  3. We extract the argument's type so we can recreate it later
-//  4. We generate a JSON Schema describing that argument type (for the LLM)
-//  5. We store everything using reflection so we can call it dynamically
+//  2. We check it has exactly one argument besides an optional leading context.Context
+//  3. We check the return values are string or (string, error)
+//  4. We extract the argument's type so we can recreate it later
+//  5. We generate a JSON Schema describing that argument type (for the LLM)
+//  6. We store everything using reflection so we can call it dynamically
 //
 // Example:
 //
@@ -67,32 +114,104 @@ func NewRegistry() *Registry {
 //	}
 //
 //	registry.Register("get_weather", "Get current weather", GetWeather)
-func (r *Registry) Register(name string, description string, function any) error {
+func (r *Registry) Register(name string, description string, function any, opts ...ToolOption) error {
+
+	if err := validate(function); err != nil {
+		return err
+	}
 
+	// validate made sure a second parameter means a context.Context first.
 	fnType := reflect.TypeOf(function)
+	takesCtx := fnType.NumIn() == 2
+	argType := fnType.In(fnType.NumIn() - 1)
+
+	// The schema isn't generated here - registering a large toolset should be
+	// cheap, and some tools may never be offered. GetAllTools fills it in.
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Store the tool definition. Re-registering a name replaces the tool
+	// but keeps its position in the list.
+	if _, exists := r.definitions[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	def := ToolDefinition{
+		Name:         name,
+		Description:  description,
+		Func:         reflect.ValueOf(function),
+		ArgsType:     argType,
+		TakesContext: takesCtx,
+	}
+	for _, opt := range opts {
+		opt(&def)
+	}
+	r.definitions[name] = def
+	r.tools = nil
+
+	return nil
+}
+
+// Schema returns the JSON Schema for a registered tool's arguments,
+// generating (and caching) it if needed.
+func (r *Registry) Schema(name string) (map[string]any, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if fnType.Kind() != reflect.Func {
+	def, ok := r.definitions[name]
+	if !ok {
+		return nil, false
+	}
+	if def.Schema == nil {
+		def.Schema = jsonschema.Cached(def.ArgsType)
+		r.definitions[name] = def
+	}
+	return def.Schema, true
+}
+
+// validate checks that function has a signature Register accepts.
+func validate(function any) error {
+	fnType := reflect.TypeOf(function)
+
+	if fnType == nil || fnType.Kind() != reflect.Func {
 		return fmt.Errorf("this is not a valid function please try again")
 	}
 
-	if fnType.NumIn() != 1 {
-		return fmt.Errorf("function must have exactly 1 argument")
+	// An optional context.Context comes first; the args struct is always last.
+	takesCtx := fnType.NumIn() == 2 && fnType.In(0) == contextType
+	if fnType.NumIn() != 1 && !takesCtx {
+		return fmt.Errorf("function must have exactly 1 argument (optionally preceded by context.Context)")
 	}
 
-	argType := fnType.In(0)
+	return checkReturns(fnType)
+}
+
+// errorType is the reflect.Type of the error interface.
+// reflect has no direct way to name an interface type, hence the pointer dance.
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
 
-	// Generate schema using our helper
-	schema := jsonschema.GenerateSchema(argType)
+// contextType is the reflect.Type of context.Context, same trick.
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 
-	// Store the tool definition
-	r.definitions[name] = ToolDefinition{
-		Name:        name,
-		Description: description,
-		Func:        reflect.ValueOf(function),
-		ArgsType:    argType,
-		Schema:      schema,
+// checkReturns makes sure a tool function returns string or (string, error).
+// The first result may also be a Result (or *Result) for rich output, or an
+// interface (like any) holding a string - Execute checks the dynamic value
+// at call time.
+func checkReturns(fnType reflect.Type) error {
+	switch fnType.NumOut() {
+	case 1, 2:
+	default:
+		return fmt.Errorf("function must return string or (string, error)")
 	}
 
+	first := fnType.Out(0)
+	if first.Kind() != reflect.String && first.Kind() != reflect.Interface &&
+		first != resultType && first != resultPtrType {
+		return fmt.Errorf("function must return string or (string, error), first result is %s", first)
+	}
+	if fnType.NumOut() == 2 && fnType.Out(1) != errorType {
+		return fmt.Errorf("function must return string or (string, error), second result is %s", fnType.Out(1))
+	}
 	return nil
 }
 
@@ -111,18 +230,48 @@ func (r *Registry) Register(name string, description string, function any) error
 //   - Schema becomes Function.Parameters (the JSON Schema describing args)
 //   - Type is always "function" (OpenAI's terminology for callable tools)
 //
+// Tools come back in registration order, so consecutive requests are
+// byte-identical (good for provider prompt caching and golden-file tests).
+//
+// The result is built once and cached until the next Register - the agent
+// calls this on every LLM request, so it shouldn't allocate every time.
+// That means the returned slice is shared: don't modify it.
+//
+// Parameters holds the schema as a pre-encoded json.RawMessage rather than
+// a map, so marshaling the request just copies the bytes. Use Schema (or
+// json.Unmarshal) if you need to inspect it.
+//
 // If no tools are registered, returns an empty slice (not nil) to avoid
 // JSON marshaling issues where null might cause API errors.
 func (r *Registry) GetAllTools() []llm.Tool {
+	r.mu.RLock()
+	cached := r.tools
+	r.mu.RUnlock()
+	if cached != nil {
+		return cached
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tools != nil {
+		return r.tools // someone else rebuilt it while we waited for the lock
+	}
 
 	// Initialize empty slice (not nil) - important for JSON marshaling
 	// A nil slice would marshal to "null", empty slice to "[]"
 	// LLM providers expect either a valid array or no field at all
-	result := make([]llm.Tool, 0)
+	result := make([]llm.Tool, 0, len(r.order))
+
+	for _, name := range r.order {
+		def := r.definitions[name]
 
-	// Iterate over all registered tool definitions
-	// We use _ for the key (tool name) since we already have it in the definition
-	for _, def := range r.definitions {
+		// First time this tool is offered - generate its schema now.
+		// jsonschema caches one schema (and its encoding) per argument type.
+		if def.schemaJSON == nil {
+			def.Schema = jsonschema.Cached(def.ArgsType)
+			def.schemaJSON = jsonschema.CachedJSON(def.ArgsType)
+			r.definitions[name] = def
+		}
 
 		// Convert internal ToolDefinition to API llm.Tool format
 		apiTool := llm.Tool{
@@ -130,10 +279,13 @@ func (r *Registry) GetAllTools() []llm.Tool {
 			Function: llm.FunctionDescription{
 				Name:        def.Name,
 				Description: def.Description,
-				Parameters:  def.Schema, // The JSON Schema describing what args the LLM should provide
+				Parameters:  def.schemaJSON, // The JSON Schema describing what args the LLM should provide
+				Strict:      def.Strict,
 			},
 		}
 		result = append(result, apiTool)
 	}
+
+	r.tools = result
 	return result
 }
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"go-agent-sdk/llm"
+	"net/http"
+	"reflect"
+)
+
+// Result is what a tool returns when a string isn't enough - a chart it
+// rendered, a screenshot, a PDF it fetched. Return it (or *Result) instead
+// of a string:
+//
+//	func Screenshot(ctx context.Context, args ScreenshotArgs) (tools.Result, error) {
+//	    png, err := capture(ctx, args.URL)
+//	    if err != nil {
+//	        return tools.Result{}, err
+//	    }
+//	    return tools.Result{
+//	        Text:   "Screenshot of " + args.URL,
+//	        Images: []tools.Image{{Data: png}},
+//	    }, nil
+//	}
+//
+// The agent sends the images and files to the model in the provider's
+// native form (image blocks in Anthropic's tool_result, inline data for
+// Gemini). OpenAI can't take images in tool results, so they follow in a
+// user message instead.
+type Result struct {
+	Text   string
+	Images []Image
+	Files  []File
+}
+
+// Image is an image returned by a tool.
+type Image struct {
+	Data     []byte
+	MIMEType string // detected from Data when empty
+}
+
+// File is a document returned by a tool.
+type File struct {
+	Name     string
+	MIMEType string // e.g. "application/pdf"; detected from Data when empty
+	Data     []byte
+}
+
+// TextResult wraps plain text in a Result.
+func TextResult(text string) Result {
+	return Result{Text: text}
+}
+
+// Parts converts the result to message content parts: the text first, then
+// images, then files.
+func (r Result) Parts() []llm.ContentPart {
+	var parts []llm.ContentPart
+	if r.Text != "" {
+		parts = append(parts, llm.NewTextPart(r.Text))
+	}
+	for _, img := range r.Images {
+		mime := img.MIMEType
+		if mime == "" {
+			mime = http.DetectContentType(img.Data)
+		}
+		parts = append(parts, llm.ContentPart{
+			Type:     "image_url",
+			ImageURL: &llm.ImageURL{URL: llm.DataURL(mime, img.Data)},
+		})
+	}
+	for _, f := range r.Files {
+		mime := f.MIMEType
+		if mime == "" {
+			mime = http.DetectContentType(f.Data)
+		}
+		parts = append(parts, llm.NewFilePart(f.Name, mime, f.Data))
+	}
+	return parts
+}
+
+// IsRich reports whether the result has anything besides text.
+func (r Result) IsRich() bool {
+	return len(r.Images) > 0 || len(r.Files) > 0
+}
+
+// resultType and resultPtrType are the reflect.Types of Result and *Result,
+// which tools may return instead of a string.
+var (
+	resultType    = reflect.TypeOf(Result{})
+	resultPtrType = reflect.TypeOf(&Result{})
+)
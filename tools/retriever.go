@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-agent-sdk/llm"
+	"go-agent-sdk/vectorstore"
+)
+
+// RetrieverOption configures NewRetriever.
+type RetrieverOption func(*retriever)
+
+type retriever struct {
+	store       vectorstore.Store
+	embedder    llm.EmbeddingsProvider
+	name        string
+	description string
+	prompt      string
+	k           int
+	minScore    float64
+	filter      vectorstore.Filter
+}
+
+// RetrieverName sets the tool's name. The default is
+// "search_knowledge_base"; give each retriever its own when an agent has
+// several.
+func RetrieverName(name string) RetrieverOption {
+	return func(r *retriever) {
+		r.name = name
+	}
+}
+
+// RetrieverDescription tells the model what's in the store, so it knows
+// when searching is worth it - "Search the employee handbook: leave,
+// expenses, benefits."
+func RetrieverDescription(description string) RetrieverOption {
+	return func(r *retriever) {
+		r.description = description
+	}
+}
+
+// RetrieverPrompt replaces the guidance added to the system prompt.
+func RetrieverPrompt(prompt string) RetrieverOption {
+	return func(r *retriever) {
+		r.prompt = prompt
+	}
+}
+
+// RetrieverTopK sets how many chunks a search returns. The default is 5.
+func RetrieverTopK(k int) RetrieverOption {
+	return func(r *retriever) {
+		r.k = k
+	}
+}
+
+// RetrieverMinScore leaves out chunks less similar than score to the
+// query, so a search for something the store doesn't cover comes back
+// empty instead of with the least bad chunks.
+func RetrieverMinScore(score float64) RetrieverOption {
+	return func(r *retriever) {
+		r.minScore = score
+	}
+}
+
+// RetrieverFilter only searches records whose metadata matches filter -
+// one tenant's documents, one product's manuals.
+func RetrieverFilter(filter vectorstore.Filter) RetrieverOption {
+	return func(r *retriever) {
+		r.filter = filter
+	}
+}
+
+// RetrieverArgs are the retrieval tool's arguments.
+type RetrieverArgs struct {
+	Query string `json:"query" description:"What to look for, as a question or a few keywords. Be specific."`
+}
+
+// NewRetriever returns a toolset with one tool that searches store:
+// the model's query is embedded with embedder, and the nearest chunks come
+// back numbered, with their metadata so the model can cite them.
+//
+//	embedder := openai.New(key, "text-embedding-3-small")
+//	a.RegisterToolset(tools.NewRetriever(store, embedder,
+//	    tools.RetrieverDescription("Search the product documentation."),
+//	))
+//
+// The store should hold vectors made by the same embedding model.
+func NewRetriever(store vectorstore.Store, embedder llm.EmbeddingsProvider, opts ...RetrieverOption) Toolset {
+	r := &retriever{
+		store:       store,
+		embedder:    embedder,
+		name:        "search_knowledge_base",
+		description: "Search the knowledge base for passages relevant to a query.",
+		k:           5,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	prompt := r.prompt
+	if prompt == "" {
+		prompt = fmt.Sprintf("Use %s to look up facts before answering questions it may cover, rather than relying on memory. "+
+			"Base your answer on the passages it returns and cite their sources. If nothing relevant comes back, say so.", r.name)
+	}
+	return Toolset{
+		Name:   r.name,
+		Prompt: prompt,
+		Tools:  []Tool{{Name: r.name, Description: r.description, Func: r.search}},
+	}
+}
+
+func (r *retriever) search(ctx context.Context, args RetrieverArgs) (string, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return "", fmt.Errorf("query is empty")
+	}
+	vectors, err := r.embedder.CreateEmbeddings(ctx, []string{args.Query})
+	if err != nil {
+		return "", fmt.Errorf("embedding the query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return "", fmt.Errorf("got %d embeddings for the query", len(vectors))
+	}
+	matches, err := r.store.Query(ctx, vectors[0], r.k, r.filter)
+	if err != nil {
+		return "", fmt.Errorf("searching: %w", err)
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, m := range matches {
+		if m.Score < r.minScore {
+			continue
+		}
+		n++
+		fmt.Fprintf(&b, "[%d] %s (relevance %.2f)\n%s\n\n", n, describeSource(m.Record), m.Score, strings.TrimSpace(m.Text))
+	}
+	if n == 0 {
+		return "No relevant passages found.", nil
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// describeSource renders a record's metadata for citing: "source" first,
+// then the other keys in order, then the ID.
+func describeSource(rec vectorstore.Record) string {
+	var parts []string
+	if s := rec.Metadata["source"]; s != "" {
+		parts = append(parts, "source: "+s)
+	}
+	keys := make([]string, 0, len(rec.Metadata))
+	for k := range rec.Metadata {
+		if k != "source" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		parts = append(parts, k+": "+rec.Metadata[k])
+	}
+	parts = append(parts, "id: "+rec.ID)
+	return strings.Join(parts, ", ")
+}
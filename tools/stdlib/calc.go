@@ -0,0 +1,463 @@
+package stdlib
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// CalculateArgs are the calculate tool's arguments.
+type CalculateArgs struct {
+	Expression string `json:"expression" description:"Arithmetic with + - * / % ^ and parentheses, numbers like 1250.50 or 1e6, constants pi and e, and functions sqrt, abs, round(x, digits), floor, ceil, trunc, min, max, ln, log10, log2, exp, sin, cos, tan, asin, acos, atan (radians)."`
+}
+
+// Calculate evaluates an arithmetic expression. Sums, products, quotients
+// and integer powers are exact; functions like sqrt and sin are computed
+// in floating point, and the result says so.
+func Calculate(args CalculateArgs) (string, error) {
+	v, err := Evaluate(args.Expression)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+// Value is the result of Evaluate: an exact rational number, or a
+// floating-point approximation once a function like sqrt was involved.
+type Value struct {
+	Rat   *big.Rat
+	Exact bool
+}
+
+// Float64 returns v as a float64.
+func (v Value) Float64() float64 {
+	f, _ := v.Rat.Float64()
+	return f
+}
+
+// String formats v as a decimal. Exact values that don't terminate, like
+// 1/3, are cut at 15 decimals, followed by the fraction when it's short;
+// approximations get at most 15 significant digits.
+func (v Value) String() string {
+	if !v.Exact {
+		return trimDecimal(fmt.Sprintf("%.15g", v.Float64())) + " (approximate)"
+	}
+	if s, ok := terminating(v.Rat); ok {
+		return s
+	}
+	s := trimDecimal(v.Rat.FloatString(15)) + "..."
+	if v.Rat.Denom().Cmp(big.NewInt(1_000_000)) <= 0 {
+		s += " (exactly " + v.Rat.String() + ")"
+	}
+	return s
+}
+
+// terminating formats r exactly when its decimal expansion ends.
+func terminating(r *big.Rat) (string, bool) {
+	if r.IsInt() {
+		return r.Num().String(), true
+	}
+	d := new(big.Int).Set(r.Denom())
+	digits := 0
+	for _, p := range []int64{2, 5} {
+		n := 0
+		for new(big.Int).Mod(d, big.NewInt(p)).Sign() == 0 {
+			d.Div(d, big.NewInt(p))
+			n++
+		}
+		digits = max(digits, n)
+	}
+	if d.Cmp(big.NewInt(1)) != 0 || digits > 60 {
+		return "", false
+	}
+	return r.FloatString(digits), true
+}
+
+func trimDecimal(s string) string {
+	if strings.ContainsAny(s, "eE") || !strings.Contains(s, ".") {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Evaluate parses and evaluates an arithmetic expression.
+func Evaluate(expr string) (Value, error) {
+	p := &parser{src: []rune(expr)}
+	p.next()
+	v, err := p.expr()
+	if err != nil {
+		return Value{}, err
+	}
+	if p.tok.kind != tokEOF {
+		return Value{}, fmt.Errorf("unexpected %q at position %d", p.tok.text, p.tok.pos+1)
+	}
+	return v, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src []rune
+	pos int
+	tok token
+}
+
+// next reads the next token.
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.' || p.src[p.pos] == '_') {
+			p.pos++
+		}
+		// An exponent: 1e6, 2.5E-3.
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			q := p.pos + 1
+			if q < len(p.src) && (p.src[q] == '+' || p.src[q] == '-') {
+				q++
+			}
+			if q < len(p.src) && unicode.IsDigit(p.src[q]) {
+				for q < len(p.src) && unicode.IsDigit(p.src[q]) {
+					q++
+				}
+				p.pos = q
+			}
+		}
+		p.tok = token{kind: tokNum, text: strings.ReplaceAll(string(p.src[start:p.pos]), "_", ""), pos: start}
+	case unicode.IsLetter(c):
+		for p.pos < len(p.src) && (unicode.IsLetter(p.src[p.pos]) || unicode.IsDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: strings.ToLower(string(p.src[start:p.pos])), pos: start}
+	default:
+		p.pos++
+		text := string(c)
+		switch {
+		case c == '*' && p.pos < len(p.src) && p.src[p.pos] == '*':
+			p.pos++
+			text = "^"
+		case c == '×':
+			text = "*"
+		case c == '÷':
+			text = "/"
+		case c == '−':
+			text = "-"
+		}
+		p.tok = token{kind: tokOp, text: text, pos: start}
+	}
+}
+
+func (p *parser) is(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *parser) expr() (Value, error) {
+	v, err := p.term()
+	for err == nil && (p.is("+") || p.is("-")) {
+		op := p.tok.text
+		p.next()
+		var r Value
+		if r, err = p.term(); err == nil {
+			v = arith(op, v, r)
+		}
+	}
+	return v, err
+}
+
+func (p *parser) term() (Value, error) {
+	v, err := p.unary()
+	for err == nil && (p.is("*") || p.is("/") || p.is("%")) {
+		op := p.tok.text
+		p.next()
+		var r Value
+		if r, err = p.unary(); err != nil {
+			break
+		}
+		if (op == "/" || op == "%") && r.Rat.Sign() == 0 {
+			return v, errors.New("division by zero")
+		}
+		v = arith(op, v, r)
+	}
+	return v, err
+}
+
+func (p *parser) unary() (Value, error) {
+	if p.is("-") || p.is("+") {
+		neg := p.is("-")
+		p.next()
+		v, err := p.unary()
+		if neg && err == nil {
+			v.Rat = new(big.Rat).Neg(v.Rat)
+		}
+		return v, err
+	}
+	return p.power()
+}
+
+func (p *parser) power() (Value, error) {
+	base, err := p.primary()
+	if err != nil || !p.is("^") {
+		return base, err
+	}
+	p.next()
+	exp, err := p.unary() // right-associative: 2^3^2 is 2^9
+	if err != nil {
+		return base, err
+	}
+	return pow(base, exp)
+}
+
+func (p *parser) primary() (Value, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNum:
+		p.next()
+		r, ok := new(big.Rat).SetString(tok.text)
+		if !ok {
+			return Value{}, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return Value{Rat: r, Exact: true}, nil
+
+	case p.is("("):
+		p.next()
+		v, err := p.expr()
+		if err != nil {
+			return v, err
+		}
+		if !p.is(")") {
+			return v, fmt.Errorf("missing ) at position %d", p.tok.pos+1)
+		}
+		p.next()
+		return v, nil
+
+	case tok.kind == tokIdent:
+		p.next()
+		if !p.is("(") {
+			switch tok.text {
+			case "pi":
+				return fromFloat(math.Pi)
+			case "e":
+				return fromFloat(math.E)
+			}
+			return Value{}, fmt.Errorf("unknown name %q", tok.text)
+		}
+		p.next()
+		var args []Value
+		for !p.is(")") {
+			v, err := p.expr()
+			if err != nil {
+				return v, err
+			}
+			args = append(args, v)
+			if p.is(",") {
+				p.next()
+			} else if !p.is(")") {
+				return Value{}, fmt.Errorf("expected , or ) at position %d", p.tok.pos+1)
+			}
+		}
+		p.next()
+		return call(tok.text, args)
+
+	case tok.kind == tokEOF:
+		return Value{}, errors.New("unexpected end of expression")
+	}
+	return Value{}, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+}
+
+func arith(op string, a, b Value) Value {
+	r := new(big.Rat)
+	switch op {
+	case "+":
+		r.Add(a.Rat, b.Rat)
+	case "-":
+		r.Sub(a.Rat, b.Rat)
+	case "*":
+		r.Mul(a.Rat, b.Rat)
+	case "/":
+		r.Quo(a.Rat, b.Rat)
+	case "%":
+		// a - b*trunc(a/b), the sign following a like in most languages.
+		q := new(big.Rat).Quo(a.Rat, b.Rat)
+		t := new(big.Int).Quo(q.Num(), q.Denom())
+		r.Sub(a.Rat, new(big.Rat).Mul(b.Rat, new(big.Rat).SetInt(t)))
+	}
+	return Value{Rat: r, Exact: a.Exact && b.Exact}
+}
+
+// maxExactBits caps the size of an exact power, in bits of numerator or
+// denominator. Past it big.Int.Exp takes minutes and gigabytes - the
+// model can write (9^10000)^10000 - so pow falls back to floating point,
+// which overflows to an error instead.
+const maxExactBits = 1 << 20
+
+// pow is exact for integer exponents of exact bases, within reason.
+func pow(base, exp Value) (Value, error) {
+	if exp.Rat.IsInt() && base.Exact && exp.Exact && exp.Rat.Num().IsInt64() && exactPowFits(base, exp.Rat.Num().Int64()) {
+		n := exp.Rat.Num().Int64()
+		if n < 0 && base.Rat.Sign() == 0 {
+			return Value{}, errors.New("division by zero")
+		}
+		e := big.NewInt(n)
+		e.Abs(e)
+		num := new(big.Int).Exp(base.Rat.Num(), e, nil)
+		den := new(big.Int).Exp(base.Rat.Denom(), e, nil)
+		if n < 0 {
+			num, den = den, num
+		}
+		return Value{Rat: new(big.Rat).SetFrac(num, den), Exact: true}, nil
+	}
+	return fromFloat(math.Pow(base.Float64(), exp.Float64()))
+}
+
+// exactPowFits reports whether base^n stays within maxExactBits.
+func exactPowFits(base Value, n int64) bool {
+	bits := int64(max(base.Rat.Num().BitLen(), base.Rat.Denom().BitLen()))
+	if bits <= 1 {
+		return true // 0, 1, -1 and their inverses stay that size
+	}
+	limit := maxExactBits / bits
+	return -limit <= n && n <= limit
+}
+
+func fromFloat(f float64) (Value, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Value{}, errors.New("the result is not a finite number")
+	}
+	return Value{Rat: new(big.Rat).SetFloat64(f), Exact: false}, nil
+}
+
+var floatFuncs = map[string]func(float64) float64{
+	"ln": math.Log, "log": math.Log, "log10": math.Log10, "log2": math.Log2, "exp": math.Exp,
+	"sin": math.Sin, "cos": math.Cos, "tan": math.Tan, "asin": math.Asin, "acos": math.Acos, "atan": math.Atan,
+}
+
+func call(name string, args []Value) (Value, error) {
+	want := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s takes %d argument(s), got %d", name, n, len(args))
+		}
+		return nil
+	}
+	if fn, ok := floatFuncs[name]; ok {
+		if err := want(1); err != nil {
+			return Value{}, err
+		}
+		return fromFloat(fn(args[0].Float64()))
+	}
+
+	switch name {
+	case "sqrt":
+		if err := want(1); err != nil {
+			return Value{}, err
+		}
+		x := args[0]
+		if x.Rat.Sign() < 0 {
+			return Value{}, errors.New("sqrt of a negative number")
+		}
+		// Perfect squares stay exact: sqrt(2.25) is 1.5.
+		if x.Exact {
+			n, d := new(big.Int).Sqrt(x.Rat.Num()), new(big.Int).Sqrt(x.Rat.Denom())
+			if new(big.Int).Mul(n, n).Cmp(x.Rat.Num()) == 0 && new(big.Int).Mul(d, d).Cmp(x.Rat.Denom()) == 0 {
+				return Value{Rat: new(big.Rat).SetFrac(n, d), Exact: true}, nil
+			}
+		}
+		return fromFloat(math.Sqrt(x.Float64()))
+
+	case "abs":
+		if err := want(1); err != nil {
+			return Value{}, err
+		}
+		return Value{Rat: new(big.Rat).Abs(args[0].Rat), Exact: args[0].Exact}, nil
+
+	case "round", "floor", "ceil", "trunc":
+		if len(args) != 1 && len(args) != 2 {
+			return Value{}, fmt.Errorf("%s takes 1 or 2 arguments, got %d", name, len(args))
+		}
+		digits := int64(0)
+		if len(args) == 2 {
+			if !args[1].Rat.IsInt() || !args[1].Rat.Num().IsInt64() {
+				return Value{}, fmt.Errorf("%s: digits must be a whole number", name)
+			}
+			digits = args[1].Rat.Num().Int64()
+			if digits < -30 || digits > 30 {
+				return Value{}, fmt.Errorf("%s: digits must be between -30 and 30", name)
+			}
+		}
+		return Value{Rat: roundRat(args[0].Rat, digits, name), Exact: args[0].Exact}, nil
+
+	case "min", "max":
+		if len(args) == 0 {
+			return Value{}, fmt.Errorf("%s needs at least one argument", name)
+		}
+		best := args[0]
+		exact := true
+		for _, a := range args {
+			exact = exact && a.Exact
+			if c := a.Rat.Cmp(best.Rat); (name == "min" && c < 0) || (name == "max" && c > 0) {
+				best = a
+			}
+		}
+		return Value{Rat: best.Rat, Exact: exact}, nil
+	}
+	return Value{}, fmt.Errorf("unknown function %q", name)
+}
+
+// roundRat rounds x to digits decimals: half away from zero for round,
+// or towards -inf, +inf or zero.
+func roundRat(x *big.Rat, digits int64, mode string) *big.Rat {
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(abs64(digits)), nil))
+	if digits < 0 {
+		scale.Inv(scale)
+	}
+	y := new(big.Rat).Mul(x, scale)
+	num, den := y.Num(), y.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int)) // truncated
+	switch mode {
+	case "round":
+		twice := new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2))
+		if twice.Cmp(den) >= 0 {
+			q.Add(q, big.NewInt(int64(num.Sign())))
+		}
+	case "floor":
+		if m.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		}
+	case "ceil":
+		if m.Sign() > 0 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return new(big.Rat).Quo(new(big.Rat).SetInt(q), scale)
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,234 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-agent-sdk/llm"
+)
+
+const dateDescription = `A date "2006-01-02", a time "2006-01-02 15:04" or RFC 3339 "2006-01-02T15:04:05+02:00", or "now", "today", "tomorrow" or "yesterday".`
+
+// DateAddArgs are the date_add tool's arguments.
+type DateAddArgs struct {
+	Date         string `json:"date" description:"The starting point. A date \"2006-01-02\", a time \"2006-01-02 15:04\" or RFC 3339, or \"now\", \"today\", \"tomorrow\" or \"yesterday\"."`
+	Years        int    `json:"years,omitempty" description:"Years to add; negative subtracts."`
+	Months       int    `json:"months,omitempty" description:"Months to add; negative subtracts. The 31st plus a month is the last day of a shorter month."`
+	Days         int    `json:"days,omitempty" description:"Calendar days to add; negative subtracts."`
+	BusinessDays int    `json:"business_days,omitempty" description:"Business days (Monday to Friday) to add after the rest; negative subtracts."`
+	Hours        int    `json:"hours,omitempty"`
+	Minutes      int    `json:"minutes,omitempty"`
+	Timezone     string `json:"timezone,omitempty" description:"IANA timezone of the date, e.g. \"Europe/Paris\", if it doesn't say."`
+}
+
+// DateDiffArgs are the date_diff tool's arguments.
+type DateDiffArgs struct {
+	From     string `json:"from" description:"The earlier date or time. A date \"2006-01-02\", a time \"2006-01-02 15:04\" or RFC 3339, or \"now\", \"today\", \"tomorrow\" or \"yesterday\"."`
+	To       string `json:"to" description:"The later date or time, in the same formats."`
+	Timezone string `json:"timezone,omitempty" description:"IANA timezone of dates that don't say, e.g. \"America/New_York\"."`
+}
+
+// DateInfoArgs are the date_info tool's arguments.
+type DateInfoArgs struct {
+	Date      string `json:"date" description:"A date \"2006-01-02\", a time \"2006-01-02 15:04\" or RFC 3339, or \"now\", \"today\", \"tomorrow\" or \"yesterday\"."`
+	Timezone  string `json:"timezone,omitempty" description:"IANA timezone of the date, if it doesn't say."`
+	ConvertTo string `json:"convert_to,omitempty" description:"IANA timezone to show the same moment in, e.g. \"Asia/Tokyo\"."`
+}
+
+func (c *config) dateAdd(ctx context.Context, args DateAddArgs) (string, error) {
+	t, dateOnly, err := c.parseDate(ctx, args.Date, args.Timezone)
+	if err != nil {
+		return "", err
+	}
+	t = addMonths(t, args.Years*12+args.Months)
+	t = t.AddDate(0, 0, args.Days)
+	t = addBusinessDays(t, args.BusinessDays)
+	t = t.Add(time.Duration(args.Hours)*time.Hour + time.Duration(args.Minutes)*time.Minute)
+	if args.Hours != 0 || args.Minutes != 0 {
+		dateOnly = false
+	}
+	return formatDate(t, dateOnly), nil
+}
+
+func (c *config) dateDiff(ctx context.Context, args DateDiffArgs) (string, error) {
+	from, fromDate, err := c.parseDate(ctx, args.From, args.Timezone)
+	if err != nil {
+		return "", fmt.Errorf("from: %w", err)
+	}
+	to, toDate, err := c.parseDate(ctx, args.To, args.Timezone)
+	if err != nil {
+		return "", fmt.Errorf("to: %w", err)
+	}
+	sign := ""
+	if to.Before(from) {
+		from, to = to, from
+		sign = "-"
+	}
+
+	var b strings.Builder
+	days := calendarDays(from, to)
+	fmt.Fprintf(&b, "From %s to %s:\n", formatDate(from, fromDate), formatDate(to, toDate))
+	fmt.Fprintf(&b, "- %s%d days (%d weeks and %d days)\n", sign, days, days/7, days%7)
+	fmt.Fprintf(&b, "- %s%d business days (Monday to Friday after the start, up to and including the end; holidays not excluded)\n", sign, businessDaysBetween(from, to))
+	y, m, d := ymd(from, to)
+	fmt.Fprintf(&b, "- %s%d years, %d months and %d days", sign, y, m, d)
+	if !fromDate || !toDate {
+		dur := to.Sub(from)
+		fmt.Fprintf(&b, "\n- %s%s in total (%s%.2f hours)", sign, dur.Round(time.Second), sign, dur.Hours())
+	}
+	return b.String(), nil
+}
+
+func (c *config) dateInfo(ctx context.Context, args DateInfoArgs) (string, error) {
+	t, dateOnly, err := c.parseDate(ctx, args.Date, args.Timezone)
+	if err != nil {
+		return "", err
+	}
+	year, week := t.ISOWeek()
+	leap := time.Date(t.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay() == 366
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", formatDate(t, dateOnly))
+	fmt.Fprintf(&b, "- ISO week %d of %d, day %d of the year\n", week, year, t.YearDay())
+	fmt.Fprintf(&b, "- %d days in %s %d; %d is %sa leap year\n", daysIn(t.Year(), t.Month()), t.Month(), t.Year(), t.Year(), map[bool]string{true: "", false: "not "}[leap])
+	if !dateOnly {
+		fmt.Fprintf(&b, "- Unix time %d\n", t.Unix())
+	}
+	if args.ConvertTo != "" {
+		loc, err := time.LoadLocation(args.ConvertTo)
+		if err != nil {
+			return "", fmt.Errorf("unknown timezone %q", args.ConvertTo)
+		}
+		fmt.Fprintf(&b, "- In %s: %s\n", args.ConvertTo, formatDate(t.In(loc), false))
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+var dateLayouts = []struct {
+	layout   string
+	dateOnly bool
+}{
+	{time.RFC3339, false},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02 15:04", false},
+	{"2006-01-02", true},
+}
+
+// parseDate reads a date or time in the given timezone, or the default.
+// dateOnly reports that it had no time of day.
+func (c *config) parseDate(ctx context.Context, s, tz string) (t time.Time, dateOnly bool, err error) {
+	loc := c.loc
+	if tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return t, false, fmt.Errorf("unknown timezone %q", tz)
+		}
+	}
+	s = strings.TrimSpace(s)
+	now := llm.Now(ctx).In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch strings.ToLower(s) {
+	case "now":
+		return now.Truncate(time.Second), false, nil
+	case "today":
+		return today, true, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true, nil
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l.layout, s, loc); err == nil {
+			return t, l.dateOnly, nil
+		}
+	}
+	return t, false, fmt.Errorf("can't read the date %q. %s", s, dateDescription)
+}
+
+func formatDate(t time.Time, dateOnly bool) string {
+	if dateOnly {
+		return t.Format("2006-01-02 (Monday)")
+	}
+	return t.Format("2006-01-02 15:04:05 -07:00 MST (Monday)")
+}
+
+// addMonths adds months, keeping the day of the month where it exists and
+// using the month's last day where it doesn't: 31 January plus a month
+// is 28 or 29 February.
+func addMonths(t time.Time, months int) time.Time {
+	if months == 0 {
+		return t
+	}
+	y, m := t.Year(), int(t.Month())-1+months
+	y += m / 12
+	m %= 12
+	if m < 0 {
+		m += 12
+		y--
+	}
+	month := time.Month(m + 1)
+	day := min(t.Day(), daysIn(y, month))
+	return time.Date(y, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func isWeekday(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// addBusinessDays steps n weekdays forwards or backwards, skipping
+// weekends.
+func addBusinessDays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if isWeekday(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// calendarDays counts the midnights between from and to, in from's zone.
+func calendarDays(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = to.In(from.Location())
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func businessDaysBetween(from, to time.Time) int {
+	days := calendarDays(from, to)
+	n := 0
+	d := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, time.UTC)
+	// Whole weeks have five business days each; walk the rest.
+	n += days / 7 * 5
+	d = d.AddDate(0, 0, days/7*7)
+	for range days % 7 {
+		d = d.AddDate(0, 0, 1)
+		if isWeekday(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// ymd splits the span from from to to into years, months and days.
+func ymd(from, to time.Time) (years, months, days int) {
+	to = to.In(from.Location())
+	total := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if addMonths(from, total).After(to) {
+		total--
+	}
+	days = calendarDays(addMonths(from, total), to)
+	return total / 12, total % 12, days
+}
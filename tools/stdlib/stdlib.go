@@ -0,0 +1,89 @@
+// Package stdlib has the tools nearly every agent needs and no model
+// should do in its head: arithmetic, unit conversion and date math.
+// Language models predict digits rather than compute them, so left alone
+// they get long multiplications, currency sums, "what's 37 °C in
+// Fahrenheit" and "how many working days until the 14th" subtly wrong.
+// These tools get them right, the same way every time:
+//
+//	a := agent.New(provider)
+//	if err := stdlib.RegisterStandardTools(a); err != nil {
+//	    return err
+//	}
+//
+// The tools are calculate (exact rational arithmetic, so 0.1 + 0.2 is
+// 0.3, with float functions like sqrt and sin where needed),
+// convert_units, date_add, date_diff and date_info.
+package stdlib
+
+import (
+	"time"
+
+	"go-agent-sdk/tools"
+)
+
+// Registerer is anything tools can be registered on: an *agent.Agent or
+// a *tools.Registry.
+type Registerer interface {
+	RegisterToolset(ts tools.Toolset) error
+}
+
+// Option configures the standard tools.
+type Option func(*config)
+
+type config struct {
+	loc *time.Location
+}
+
+// WithTimezone sets the timezone the date tools use for "today", "now"
+// and dates given without one. The default is UTC.
+func WithTimezone(loc *time.Location) Option {
+	return func(c *config) {
+		c.loc = loc
+	}
+}
+
+// RegisterStandardTools registers the standard toolset on r.
+func RegisterStandardTools(r Registerer, opts ...Option) error {
+	return r.RegisterToolset(Toolset(opts...))
+}
+
+// Toolset returns the standard tools as a toolset named "stdlib", for
+// registering alongside your own or picking from.
+func Toolset(opts ...Option) tools.Toolset {
+	c := &config{loc: time.UTC}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return tools.Toolset{
+		Name: "stdlib",
+		Prompt: "Never do arithmetic, unit conversions or date calculations in your head: use calculate, convert_units " +
+			"and the date tools, and report the numbers they return.",
+		Tools: []tools.Tool{
+			{
+				Name:        "calculate",
+				Description: "Evaluate an arithmetic expression exactly, e.g. \"(1250 * 1.19) / 12\" or \"round(sqrt(2) * 100, 2)\".",
+				Func:        Calculate,
+			},
+			{
+				Name:        "convert_units",
+				Description: "Convert a quantity between units of length, mass, volume, area, speed, time, temperature, energy, pressure or data size.",
+				Func:        ConvertUnits,
+			},
+			{
+				Name:        "date_add",
+				Description: "Add or subtract years, months, days, hours, minutes or business days to a date or time.",
+				Func:        c.dateAdd,
+			},
+			{
+				Name:        "date_diff",
+				Description: "Count the time between two dates or times: days, weeks, business days, and years/months/days.",
+				Func:        c.dateDiff,
+			},
+			{
+				Name:        "date_info",
+				Description: "Describe a date or time: weekday, ISO week, day of year, days in the month, leap year, and the time in another timezone.",
+				Func:        c.dateInfo,
+			},
+		},
+	}
+}
@@ -0,0 +1,215 @@
+package stdlib
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// ConvertArgs are the convert_units tool's arguments.
+type ConvertArgs struct {
+	Value string `json:"value" description:"The quantity, as a decimal number, e.g. \"3.5\"."`
+	From  string `json:"from" description:"Unit to convert from, e.g. \"mi\", \"kg\", \"degF\", \"GiB\", \"km/h\"."`
+	To    string `json:"to" description:"Unit to convert to."`
+}
+
+// ConvertUnits converts a quantity between units of the same kind. The
+// conversion factors are the exact definitions (an inch is 0.0254 m, a
+// pound 0.45359237 kg), so results are exact decimals.
+func ConvertUnits(args ConvertArgs) (string, error) {
+	v, err := Evaluate(args.Value)
+	if err != nil {
+		return "", fmt.Errorf("value: %w", err)
+	}
+	from, err := lookupUnit(args.From)
+	if err != nil {
+		return "", err
+	}
+	to, err := lookupUnit(args.To)
+	if err != nil {
+		return "", err
+	}
+	if from.dim != to.dim {
+		return "", fmt.Errorf("can't convert %s (%s) to %s (%s)", from.name, from.dim, to.name, to.dim)
+	}
+
+	var out *big.Rat
+	if from.dim == "temperature" {
+		out = fromKelvin(to.name, toKelvin(from.name, v.Rat))
+	} else {
+		out = new(big.Rat).Mul(v.Rat, from.factor)
+		out.Quo(out, to.factor)
+	}
+	res := Value{Rat: out, Exact: v.Exact}
+	return fmt.Sprintf("%s %s = %s %s", args.Value, from.name, res, to.name), nil
+}
+
+type unit struct {
+	name   string
+	dim    string
+	factor *big.Rat // in the dimension's base unit
+}
+
+// unitDefs are the units, canonical name first, then aliases, with their
+// size in the base unit (m, kg, m³, m², m/s, s, J, Pa, byte).
+var unitDefs = []struct {
+	names, dim, factor string
+}{
+	{"m meter meters metre metres", "length", "1"},
+	{"km kilometer kilometers kilometre kilometres", "length", "1000"},
+	{"cm centimeter centimeters centimetre centimetres", "length", "1/100"},
+	{"mm millimeter millimeters millimetre millimetres", "length", "1/1000"},
+	{"um µm micrometer micrometers micron microns", "length", "1/1000000"},
+	{"in inch inches \"", "length", "0.0254"},
+	{"ft foot feet '", "length", "0.3048"},
+	{"yd yard yards", "length", "0.9144"},
+	{"mi mile miles", "length", "1609.344"},
+	{"nmi nautical_mile nautical_miles", "length", "1852"},
+
+	{"kg kilogram kilograms kilo kilos", "mass", "1"},
+	{"g gram grams", "mass", "1/1000"},
+	{"mg milligram milligrams", "mass", "1/1000000"},
+	{"t tonne tonnes metric_ton metric_tons", "mass", "1000"},
+	{"lb lbs pound pounds", "mass", "0.45359237"},
+	{"oz ounce ounces", "mass", "0.028349523125"},
+	{"st stone stones", "mass", "6.35029318"},
+	{"us_ton short_ton short_tons", "mass", "907.18474"},
+
+	{"l L liter liters litre litres", "volume", "1/1000"},
+	{"ml mL milliliter milliliters millilitre millilitres", "volume", "1/1000000"},
+	{"m3 m³ cubic_meter cubic_meters cubic_metre cubic_metres", "volume", "1"},
+	{"gal gallon gallons us_gallon us_gallons", "volume", "0.003785411784"},
+	{"imp_gal imperial_gallon imperial_gallons", "volume", "0.00454609"},
+	{"qt quart quarts", "volume", "0.000946352946"},
+	{"pt pint pints", "volume", "0.000473176473"},
+	{"cup cups", "volume", "0.0002365882365"},
+	{"fl_oz floz fluid_ounce fluid_ounces", "volume", "0.0000295735295625"},
+	{"tbsp tablespoon tablespoons", "volume", "0.00001478676478125"},
+	{"tsp teaspoon teaspoons", "volume", "0.00000492892159375"},
+
+	{"m2 m² square_meter square_meters square_metre square_metres", "area", "1"},
+	{"km2 km² square_kilometer square_kilometers square_kilometre square_kilometres", "area", "1000000"},
+	{"ha hectare hectares", "area", "10000"},
+	{"acre acres", "area", "4046.8564224"},
+	{"ft2 ft² sq_ft square_foot square_feet", "area", "0.09290304"},
+	{"mi2 mi² sq_mi square_mile square_miles", "area", "2589988.110336"},
+
+	{"m/s mps meters_per_second", "speed", "1"},
+	{"km/h kph kmh kilometers_per_hour", "speed", "5/18"},
+	{"mph miles_per_hour", "speed", "0.44704"},
+	{"kn kt knot knots", "speed", "463/900"},
+
+	{"s sec second seconds", "time", "1"},
+	{"ms millisecond milliseconds", "time", "1/1000"},
+	{"min minute minutes", "time", "60"},
+	{"h hr hour hours", "time", "3600"},
+	{"d day days", "time", "86400"},
+	{"wk week weeks", "time", "604800"},
+
+	{"J joule joules", "energy", "1"},
+	{"kJ kilojoule kilojoules", "energy", "1000"},
+	{"cal calorie calories", "energy", "4.184"},
+	{"kcal kilocalorie kilocalories Cal", "energy", "4184"},
+	{"Wh watt_hour watt_hours", "energy", "3600"},
+	{"kWh kilowatt_hour kilowatt_hours", "energy", "3600000"},
+	{"BTU btu", "energy", "1055.05585262"},
+
+	{"Pa pascal pascals", "pressure", "1"},
+	{"kPa kilopascal kilopascals", "pressure", "1000"},
+	{"bar bars", "pressure", "100000"},
+	{"psi", "pressure", "44482216152605/6451600000"},
+	{"atm atmosphere atmospheres", "pressure", "101325"},
+	{"mmHg", "pressure", "133.322387415"},
+
+	{"B byte bytes", "data", "1"},
+	{"bit bits b", "data", "1/8"},
+	{"KB kB kilobyte kilobytes", "data", "1000"},
+	{"MB megabyte megabytes", "data", "1000000"},
+	{"GB gigabyte gigabytes", "data", "1000000000"},
+	{"TB terabyte terabytes", "data", "1000000000000"},
+	{"KiB kibibyte kibibytes", "data", "1024"},
+	{"MiB mebibyte mebibytes", "data", "1048576"},
+	{"GiB gibibyte gibibytes", "data", "1073741824"},
+	{"TiB tebibyte tebibytes", "data", "1099511627776"},
+
+	{"degC °C C celsius", "temperature", ""},
+	{"degF °F F fahrenheit", "temperature", ""},
+	{"K kelvin kelvins", "temperature", ""},
+}
+
+var units, unitsFolded = buildUnits()
+
+func buildUnits() (exact, folded map[string]unit) {
+	exact, folded = map[string]unit{}, map[string]unit{}
+	for _, d := range unitDefs {
+		names := strings.Fields(d.names)
+		u := unit{name: names[0], dim: d.dim}
+		if d.factor != "" {
+			u.factor, _ = new(big.Rat).SetString(d.factor)
+		}
+		for _, n := range names {
+			exact[n] = u
+			if _, taken := folded[strings.ToLower(n)]; !taken {
+				folded[strings.ToLower(n)] = u
+			}
+		}
+	}
+	return exact, folded
+}
+
+// lookupUnit matches a unit name exactly, then ignoring case, so "MB" is
+// a megabyte and "Mb" too, while "b" stays a bit and "B" a byte.
+func lookupUnit(name string) (unit, error) {
+	n := strings.TrimSpace(name)
+	n = strings.ReplaceAll(n, " ", "_")
+	if u, ok := units[n]; ok {
+		return u, nil
+	}
+	if u, ok := unitsFolded[strings.ToLower(n)]; ok {
+		return u, nil
+	}
+	if u, ok := unitsFolded[strings.TrimSuffix(strings.ToLower(n), "s")]; ok {
+		return u, nil
+	}
+	return unit{}, fmt.Errorf("unknown unit %q; known units: %s", name, strings.Join(knownUnits(), ", "))
+}
+
+func knownUnits() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, u := range units {
+		if !seen[u.name] {
+			seen[u.name] = true
+			out = append(out, u.name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func toKelvin(name string, v *big.Rat) *big.Rat {
+	k := new(big.Rat).Set(v)
+	switch name {
+	case "degC":
+		k.Add(k, big.NewRat(27315, 100))
+	case "degF":
+		k.Sub(k, big.NewRat(32, 1))
+		k.Mul(k, big.NewRat(5, 9))
+		k.Add(k, big.NewRat(27315, 100))
+	}
+	return k
+}
+
+func fromKelvin(name string, k *big.Rat) *big.Rat {
+	v := new(big.Rat).Set(k)
+	switch name {
+	case "degC":
+		v.Sub(v, big.NewRat(27315, 100))
+	case "degF":
+		v.Sub(v, big.NewRat(27315, 100))
+		v.Mul(v, big.NewRat(9, 5))
+		v.Add(v, big.NewRat(32, 1))
+	}
+	return v
+}
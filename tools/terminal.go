@@ -0,0 +1,453 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-agent-sdk/llm"
+)
+
+// TerminalOption configures NewTerminal.
+type TerminalOption func(*Terminal)
+
+// TerminalShell runs this shell instead of the default: bash if it's on
+// the PATH, sh otherwise. It must be POSIX-compatible.
+func TerminalShell(path string, args ...string) TerminalOption {
+	return func(t *Terminal) {
+		t.shell = append([]string{path}, args...)
+	}
+}
+
+// TerminalEnv adds "KEY=value" pairs to the environment every shell
+// starts with, on top of the process's own.
+func TerminalEnv(env ...string) TerminalOption {
+	return func(t *Terminal) {
+		t.env = append(t.env, env...)
+	}
+}
+
+// TerminalTimeout is how long one command may run before it's
+// interrupted. The model can ask for less, not more. The default is
+// 2 minutes.
+func TerminalTimeout(d time.Duration) TerminalOption {
+	return func(t *Terminal) {
+		t.timeout = d
+	}
+}
+
+// TerminalIdleTimeout closes a shell nobody has used for d. The default is
+// 15 minutes.
+func TerminalIdleTimeout(d time.Duration) TerminalOption {
+	return func(t *Terminal) {
+		t.idle = d
+	}
+}
+
+// TerminalMaxOutput caps how many bytes of one command's output the tool
+// returns; the end is kept, since that's where errors are. The default is
+// 64 KiB.
+func TerminalMaxOutput(n int) TerminalOption {
+	return func(t *Terminal) {
+		t.maxOutput = n
+	}
+}
+
+// TerminalArgs are the terminal tool's arguments.
+type TerminalArgs struct {
+	Command string `json:"command" description:"Shell command to run. Runs in the same shell as earlier commands, so cd, exported variables and shell functions carry over."`
+	Timeout int    `json:"timeout,omitempty" description:"Seconds to wait before interrupting the command. Defaults to the longest allowed."`
+	Reset   bool   `json:"reset,omitempty" description:"Start a fresh shell in the starting directory before running the command, discarding all state."`
+}
+
+// Terminal is a stateful shell tool. Each agent run gets its own shell,
+// kept alive between calls, so multi-step work behaves like a person at a
+// terminal: cd into a directory, export a variable, then run the tests.
+// On Linux the shell runs on a pseudo-terminal, so programs behave as they
+// do interactively; elsewhere it runs over pipes.
+//
+// Terminal is not a sandbox. Commands run with the process's privileges
+// and can reach anything it can; root is only where shells start. Run it
+// in a container or VM for untrusted work, and vet commands with a tool
+// middleware (agent.WithToolMiddleware) where that matters.
+//
+//	term, err := tools.NewTerminal("./workspace", tools.TerminalTimeout(5*time.Minute))
+//	if err != nil { ... }
+//	defer term.Close()
+//	a.RegisterToolset(term.Toolset())
+//
+// Every command is recorded as a "command.run" Effect.
+type Terminal struct {
+	root      string
+	shell     []string
+	env       []string
+	timeout   time.Duration
+	idle      time.Duration
+	maxOutput int
+
+	mu       sync.Mutex
+	sessions map[string]*shellSession // by run ID
+	closed   bool
+}
+
+// NewTerminal returns a Terminal whose shells start in root.
+func NewTerminal(root string, opts ...TerminalOption) (*Terminal, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("tools: terminal root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("tools: terminal root %s is not a directory", root)
+	}
+	t := &Terminal{
+		root:      root,
+		timeout:   2 * time.Minute,
+		idle:      15 * time.Minute,
+		maxOutput: 64 << 10,
+		sessions:  make(map[string]*shellSession),
+	}
+	if bash, err := exec.LookPath("bash"); err == nil {
+		t.shell = []string{bash, "--noprofile", "--norc", "--noediting"}
+	} else {
+		t.shell = []string{"sh"}
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+// Toolset returns the terminal tool.
+func (t *Terminal) Toolset() Toolset {
+	return Toolset{
+		Name: "terminal",
+		Prompt: "The terminal tool runs shell commands in a persistent shell: the working directory, environment variables " +
+			"and shell functions carry over from one command to the next. Commands get no input, so use non-interactive " +
+			"flags (such as -y) and avoid pagers and editors. Long-running servers should be started in the background with &.",
+		Tools: []Tool{
+			{Name: "terminal", Description: "Run a command in a persistent shell session.", Func: t.run},
+		},
+	}
+}
+
+// Close ends every shell. The Terminal can't be used afterwards.
+func (t *Terminal) Close() error {
+	t.mu.Lock()
+	sessions := t.sessions
+	t.sessions = nil
+	t.closed = true
+	t.mu.Unlock()
+	for _, s := range sessions {
+		s.close()
+	}
+	return nil
+}
+
+func (t *Terminal) run(ctx context.Context, args TerminalArgs) (string, error) {
+	if strings.TrimSpace(args.Command) == "" {
+		return "", errors.New("command is empty")
+	}
+	timeout := t.timeout
+	if args.Timeout > 0 {
+		timeout = min(timeout, time.Duration(args.Timeout)*time.Second)
+	}
+	tc, _ := llm.TraceFromContext(ctx)
+	s, err := t.session(tc.RunID, args.Reset)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := s.exec(ctx, args.Command, timeout)
+	if err != nil {
+		return "", err
+	}
+	if res.exited {
+		t.drop(tc.RunID, s)
+	}
+	RecordEffect(ctx, Effect{Kind: "command.run", Target: args.Command,
+		Summary: res.status(), Data: map[string]any{"exit_code": res.code, "dir": res.dir}})
+
+	out := res.output
+	if total := len(out) + res.dropped; t.maxOutput > 0 && total > t.maxOutput {
+		out = fmt.Sprintf("[output truncated: last %d of %d bytes shown]\n", t.maxOutput, total) + out[max(len(out)-t.maxOutput, 0):]
+	}
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out + "[" + res.status() + "]", nil
+}
+
+// session returns the run's shell, starting one if needed.
+func (t *Terminal) session(runID string, reset bool) (*shellSession, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, errors.New("terminal is closed")
+	}
+	s := t.sessions[runID]
+	if s != nil && (reset || s.done()) {
+		s.close()
+		s = nil
+	}
+	if s == nil {
+		var err error
+		if s, err = t.start(); err != nil {
+			return nil, fmt.Errorf("starting shell: %w", err)
+		}
+		t.sessions[runID] = s
+	}
+	s.touch(t.idle, func() { t.drop(runID, s) })
+	return s, nil
+}
+
+func (t *Terminal) drop(runID string, s *shellSession) {
+	t.mu.Lock()
+	if t.sessions[runID] == s {
+		delete(t.sessions, runID)
+	}
+	t.mu.Unlock()
+	s.close()
+}
+
+func (t *Terminal) start() (*shellSession, error) {
+	cmd := exec.Command(t.shell[0], t.shell[1:]...)
+	cmd.Dir = t.root
+	cmd.Env = append(os.Environ(), "TERM=dumb", "PS1=", "PS2=", "HISTFILE=", "PAGER=cat", "GIT_PAGER=cat")
+	cmd.Env = append(cmd.Env, t.env...)
+	conn, tty, err := startShell(cmd)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [8]byte
+	rand.Read(nonce[:])
+	s := &shellSession{
+		cmd:    cmd,
+		conn:   conn,
+		tty:    tty,
+		marker: "__terminal_done_" + hex.EncodeToString(nonce[:]),
+		notify: make(chan struct{}, 1),
+		exited: make(chan struct{}),
+	}
+	if t.maxOutput > 0 {
+		// Room for the status line after the output: the marker, the exit
+		// code and a directory of up to PATH_MAX.
+		s.keep = t.maxOutput + len(s.marker) + 4<<10
+	}
+	go s.read()
+	return s, nil
+}
+
+// shellSession is one long-lived shell.
+type shellSession struct {
+	cmd    *exec.Cmd
+	conn   io.ReadWriteCloser
+	tty    bool
+	marker string // printed after each command, with its status and directory
+	seq    int    // commands run, so each has its own marker
+
+	run sync.Mutex // one command at a time
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	keep    int // most bytes of output buf holds; 0 keeps it all
+	dropped int // bytes of the current command's output dropped to stay under keep
+	idle    *time.Timer
+	notify  chan struct{} // signalled when output arrives
+	exited  chan struct{} // closed when the shell's output ends
+	closed  bool
+}
+
+type shellResult struct {
+	output   string
+	dropped  int // bytes from before output, thrown away while the command ran
+	code     int
+	dir      string
+	timedOut bool
+	exited   bool
+}
+
+func (r shellResult) status() string {
+	switch {
+	case r.exited:
+		return "shell exited; the next command starts a fresh one"
+	case r.timedOut:
+		return "timed out and interrupted, cwd " + r.dir
+	default:
+		return fmt.Sprintf("exit code %d, cwd %s", r.code, r.dir)
+	}
+}
+
+func (s *shellSession) read() {
+	chunk := make([]byte, 32<<10)
+	for {
+		n, err := s.conn.Read(chunk)
+		if n > 0 {
+			s.mu.Lock()
+			s.buf.Write(chunk[:n])
+			if extra := s.buf.Len() - s.keep; s.keep > 0 && extra > 0 {
+				// A command printing without end mustn't take all the
+				// memory; only the end of its output is returned anyway.
+				s.buf.Next(extra)
+				s.dropped += extra
+			}
+			s.mu.Unlock()
+			select {
+			case s.notify <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			close(s.exited)
+			return
+		}
+	}
+}
+
+// exec runs command in the shell and waits for it to finish.
+func (s *shellSession) exec(ctx context.Context, command string, timeout time.Duration) (shellResult, error) {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	s.seq++
+	marker := fmt.Sprintf("%s_%d", s.marker, s.seq)
+	done := regexp.MustCompile(regexp.QuoteMeta(marker) + ` (\d+) (.*)\n`)
+	// Printed again after an interrupt, since Ctrl-C throws away input
+	// the shell hasn't read yet.
+	status := fmt.Sprintf("printf '\\n%%s %%d %%s\\n' %s \"$?\" \"$PWD\"\n", marker)
+
+	s.mu.Lock()
+	s.buf.Reset()
+	s.dropped = 0
+	s.mu.Unlock()
+	// The braces keep the command in this shell, so cd and exports stick;
+	// its stdin is /dev/null so nothing waits for input that won't come.
+	if _, err := io.WriteString(s.conn, "{\n"+command+"\n} </dev/null\n"+status); err != nil {
+		return shellResult{exited: true}, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	interrupted := false
+	for {
+		if res, ok := s.finished(done); ok {
+			res.timedOut = interrupted
+			return res, nil
+		}
+		select {
+		case <-s.notify:
+		case <-s.exited:
+			if res, ok := s.finished(done); ok {
+				return res, nil
+			}
+			out, dropped := s.output()
+			return shellResult{output: out, dropped: dropped, exited: true}, nil
+		case <-ctx.Done():
+			s.interrupt()
+			return shellResult{}, ctx.Err()
+		case <-timer.C:
+			if interrupted || !s.tty {
+				// Nothing more we can do for this shell.
+				s.close()
+				out, dropped := s.output()
+				return shellResult{output: out, dropped: dropped, timedOut: true, exited: true}, nil
+			}
+			s.interrupt()
+			// Give the shell a moment to take the interrupt, and lead with
+			// a newline it may swallow doing so.
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(s.conn, "\n"+status)
+			interrupted = true
+			timer.Reset(5 * time.Second)
+		}
+	}
+}
+
+// finished reports the result once the marker has come out.
+func (s *shellSession) finished(done *regexp.Regexp) (shellResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := normalizeNewlines(s.buf.String())
+	m := done.FindStringSubmatchIndex(out)
+	if m == nil {
+		return shellResult{}, false
+	}
+	code, _ := strconv.Atoi(out[m[2]:m[3]])
+	// The marker's printf starts with a newline of its own.
+	return shellResult{
+		output:  strings.TrimRight(out[:m[0]], "\n"),
+		dropped: s.dropped,
+		code:    code,
+		dir:     out[m[4]:m[5]],
+	}, true
+}
+
+// output returns what the command has printed so far, and how many bytes
+// before that were dropped.
+func (s *shellSession) output() (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return normalizeNewlines(s.buf.String()), s.dropped
+}
+
+// interrupt sends Ctrl-C, as a person at the terminal would.
+func (s *shellSession) interrupt() {
+	if s.tty {
+		s.conn.Write([]byte{3})
+	}
+}
+
+func (s *shellSession) done() bool {
+	select {
+	case <-s.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// touch restarts the idle timer.
+func (s *shellSession) touch(idle time.Duration, expire func()) {
+	if idle <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idle == nil {
+		s.idle = time.AfterFunc(idle, expire)
+	} else {
+		s.idle.Reset(idle)
+	}
+}
+
+func (s *shellSession) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	if s.idle != nil {
+		s.idle.Stop()
+	}
+	s.mu.Unlock()
+	if s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+	}
+	s.conn.Close()
+	go s.cmd.Wait()
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// WithTimeout limits how long one call of the tool may run. When it's up
+// the call fails with a *TimeoutError - which the agent reports to the LLM
+// like any other tool error - and the Run carries on, instead of stalling
+// until the outer context expires:
+//
+//	a.RegisterTool("fetch_page", "Fetch a web page", FetchPage, tools.WithTimeout(10*time.Second))
+//
+// Tools that take a context.Context get one with the deadline, so their
+// I/O is cancelled too. A tool that ignores its context can't be stopped -
+// Go has no way to kill a goroutine - so it keeps running in the
+// background until it returns, and its result is thrown away.
+func WithTimeout(d time.Duration) ToolOption {
+	return func(def *ToolDefinition) {
+		def.Timeout = d
+	}
+}
+
+// TimeoutError is returned when a tool runs longer than its WithTimeout.
+// It unwraps to context.DeadlineExceeded.
+type TimeoutError struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("tool %s timed out after %s", e.Name, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// callWithTimeout calls the tool's function, giving up after def.Timeout.
+// in is built by the caller; if the tool takes a context, in[0] is
+// replaced with one carrying the deadline.
+func callWithTimeout(ctx context.Context, def ToolDefinition, in []reflect.Value) ([]reflect.Value, error) {
+	tctx, cancel := context.WithTimeout(ctx, def.Timeout)
+	defer cancel()
+	if def.TakesContext {
+		in[0] = reflect.ValueOf(&tctx).Elem()
+	}
+
+	type outcome struct {
+		results []reflect.Value
+		panic   any
+	}
+	// Buffered, so a tool that finishes after we gave up doesn't block forever.
+	done := make(chan outcome, 1)
+	go func() {
+		var out outcome
+		defer func() {
+			out.panic = recover()
+			done <- out
+		}()
+		out.results = def.Func.Call(in)
+	}()
+
+	select {
+	case out := <-done:
+		if out.panic != nil {
+			panic(out.panic) // same as calling the tool directly
+		}
+		// A tool that noticed the deadline and returned ctx.Err() timed out
+		// too - report it the same way as one we stopped waiting for.
+		if len(out.results) == 2 && !out.results[1].IsNil() && tctx.Err() != nil && ctx.Err() == nil {
+			return nil, &TimeoutError{Name: def.Name, Timeout: def.Timeout}
+		}
+		return out.results, nil
+	case <-tctx.Done():
+		if err := ctx.Err(); err != nil {
+			return nil, err // the caller gave up, not the timeout
+		}
+		return nil, &TimeoutError{Name: def.Name, Timeout: def.Timeout}
+	}
+}
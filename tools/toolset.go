@@ -0,0 +1,87 @@
+package tools
+
+import "fmt"
+
+// Toolset is a group of related tools plus the instructions for using them.
+// Guidance like "never modify data with the SQL tool" belongs next to the
+// SQL tool's code, not in every agent's system prompt - a Toolset keeps it
+// there, and the agent appends Prompt to its system prompt for as long as
+// the toolset is registered:
+//
+//	var SQLTools = tools.Toolset{
+//	    Name:   "sql",
+//	    Prompt: "When using the SQL tools, only run SELECT queries. Never modify data.",
+//	    Tools: []tools.Tool{
+//	        {Name: "sql_query", Description: "Run a read-only SQL query", Func: Query},
+//	        {Name: "sql_schema", Description: "Describe a table", Func: Describe},
+//	    },
+//	}
+//
+//	a.RegisterToolset(SQLTools)
+type Toolset struct {
+	Name   string // identifies the toolset; registering the same name again replaces it
+	Prompt string // appended to the system prompt. Empty means no guidance.
+	Tools  []Tool
+
+	// PromptFunc, if set, is called before every request and its result
+	// used instead of Prompt - for guidance that changes as the
+	// conversation goes, like which step of a process it's on.
+	PromptFunc func() string
+}
+
+// Tool is one function in a Toolset, with the same rules as Register.
+type Tool struct {
+	Name        string
+	Description string
+	Func        any
+	Options     []ToolOption // e.g. WithTimeout
+}
+
+// RegisterToolset registers every tool in ts and remembers its prompt.
+// If a tool is invalid nothing is registered.
+func (r *Registry) RegisterToolset(ts Toolset) error {
+	// Check every tool first, so a bad one doesn't leave half a toolset behind.
+	for _, t := range ts.Tools {
+		if err := validate(t.Func); err != nil {
+			return fmt.Errorf("toolset %s: tool %s: %w", ts.Name, t.Name, err)
+		}
+	}
+	for _, t := range ts.Tools {
+		if err := r.Register(t.Name, t.Description, t.Func, t.Options...); err != nil {
+			return fmt.Errorf("toolset %s: tool %s: %w", ts.Name, t.Name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.prompts[ts.Name]; !exists {
+		r.toolsets = append(r.toolsets, ts.Name)
+	}
+	prompt := ts.PromptFunc
+	if prompt == nil {
+		static := ts.Prompt
+		prompt = func() string { return static }
+	}
+	r.prompts[ts.Name] = prompt
+	return nil
+}
+
+// Prompts returns the prompts of every registered toolset, in registration
+// order, skipping empty ones.
+func (r *Registry) Prompts() []string {
+	r.mu.RLock()
+	funcs := make([]func() string, 0, len(r.toolsets))
+	for _, name := range r.toolsets {
+		funcs = append(funcs, r.prompts[name])
+	}
+	r.mu.RUnlock()
+
+	// Called outside the lock, so a PromptFunc may use the registry.
+	var out []string
+	for _, fn := range funcs {
+		if p := fn(); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-agent-sdk/llm"
+)
+
+// NominatimURL is OpenStreetMap's public Nominatim search endpoint.
+const NominatimURL = "https://nominatim.openstreetmap.org/search"
+
+// Nominatim is a Geocoder backed by OpenStreetMap's Nominatim. The public
+// instance allows at most one request a second and asks for a
+// User-Agent naming your application; Nominatim keeps to the first and
+// sends the second. For heavy use, run your own instance.
+type Nominatim struct {
+	endpoint   string
+	httpClient *http.Client
+	userAgent  string
+	language   string
+	interval   time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NominatimOption configures NewNominatim.
+type NominatimOption func(*Nominatim)
+
+// NominatimEndpoint sets the search URL, for a self-hosted instance or a
+// test server.
+func NominatimEndpoint(endpoint string) NominatimOption {
+	return func(n *Nominatim) {
+		n.endpoint = endpoint
+	}
+}
+
+// NominatimHTTPClient sets the HTTP client. nil means
+// llm.DefaultHTTPClient.
+func NominatimHTTPClient(hc *http.Client) NominatimOption {
+	return func(n *Nominatim) {
+		n.httpClient = hc
+	}
+}
+
+// NominatimUserAgent sets the User-Agent header.
+func NominatimUserAgent(ua string) NominatimOption {
+	return func(n *Nominatim) {
+		n.userAgent = ua
+	}
+}
+
+// NominatimLanguage sets the language of place names, such as "de". The
+// default is the local name of each place.
+func NominatimLanguage(lang string) NominatimOption {
+	return func(n *Nominatim) {
+		n.language = lang
+	}
+}
+
+// NominatimInterval sets the minimum time between requests. The default,
+// one second, is the public instance's limit; self-hosted instances can
+// use 0.
+func NominatimInterval(d time.Duration) NominatimOption {
+	return func(n *Nominatim) {
+		n.interval = d
+	}
+}
+
+// NewNominatim returns a Geocoder using Nominatim.
+func NewNominatim(opts ...NominatimOption) *Nominatim {
+	n := &Nominatim{endpoint: NominatimURL, userAgent: "go-agent-sdk weather tool", interval: time.Second}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+type nominatimPlace struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+}
+
+// Geocode implements Geocoder.
+func (n *Nominatim) Geocode(ctx context.Context, query string) ([]Place, error) {
+	if err := n.wait(ctx); err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("q", query)
+	q.Set("format", "jsonv2")
+	q.Set("limit", "5")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("weather: nominatim: %w", err)
+	}
+	req.Header.Set("User-Agent", n.userAgent)
+	if n.language != "" {
+		req.Header.Set("Accept-Language", n.language)
+	}
+	hc := n.httpClient
+	if hc == nil {
+		hc = llm.DefaultHTTPClient()
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("weather: nominatim: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("weather: nominatim: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var found []nominatimPlace
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return nil, fmt.Errorf("weather: nominatim: decoding response: %w", err)
+	}
+	places := make([]Place, 0, len(found))
+	for _, f := range found {
+		lat, err1 := strconv.ParseFloat(f.Lat, 64)
+		lon, err2 := strconv.ParseFloat(f.Lon, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		places = append(places, Place{Name: f.DisplayName, Latitude: lat, Longitude: lon})
+	}
+	return places, nil
+}
+
+// wait holds the request until the interval since the last one is up.
+func (n *Nominatim) wait(ctx context.Context) error {
+	n.mu.Lock()
+	next := n.last.Add(n.interval)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	n.last = next
+	n.mu.Unlock()
+
+	if d := time.Until(next); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go-agent-sdk/llm"
+)
+
+// OpenMeteoURL is Open-Meteo's free forecast endpoint.
+const OpenMeteoURL = "https://api.open-meteo.com/v1/forecast"
+
+// OpenMeteo is a Forecaster backed by Open-Meteo, which needs no API key
+// for non-commercial use.
+type OpenMeteo struct {
+	endpoint   string
+	httpClient *http.Client
+	userAgent  string
+}
+
+// OpenMeteoOption configures NewOpenMeteo.
+type OpenMeteoOption func(*OpenMeteo)
+
+// OpenMeteoEndpoint sets the forecast URL, for the commercial API
+// (https://customer-api.open-meteo.com/v1/forecast?apikey=...), a
+// self-hosted instance or a test server.
+func OpenMeteoEndpoint(endpoint string) OpenMeteoOption {
+	return func(o *OpenMeteo) {
+		o.endpoint = endpoint
+	}
+}
+
+// OpenMeteoHTTPClient sets the HTTP client. nil means
+// llm.DefaultHTTPClient.
+func OpenMeteoHTTPClient(hc *http.Client) OpenMeteoOption {
+	return func(o *OpenMeteo) {
+		o.httpClient = hc
+	}
+}
+
+// OpenMeteoUserAgent sets the User-Agent header.
+func OpenMeteoUserAgent(ua string) OpenMeteoOption {
+	return func(o *OpenMeteo) {
+		o.userAgent = ua
+	}
+}
+
+// NewOpenMeteo returns a Forecaster using Open-Meteo.
+func NewOpenMeteo(opts ...OpenMeteoOption) *OpenMeteo {
+	o := &OpenMeteo{endpoint: OpenMeteoURL}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type openMeteoResponse struct {
+	Timezone string `json:"timezone"`
+	Current  struct {
+		Time          string  `json:"time"`
+		Temperature   float64 `json:"temperature_2m"`
+		FeelsLike     float64 `json:"apparent_temperature"`
+		Humidity      float64 `json:"relative_humidity_2m"`
+		Precipitation float64 `json:"precipitation"`
+		WindSpeed     float64 `json:"wind_speed_10m"`
+		WindDirection float64 `json:"wind_direction_10m"`
+		Code          int     `json:"weather_code"`
+	} `json:"current"`
+	Daily struct {
+		Time                     []string  `json:"time"`
+		Code                     []int     `json:"weather_code"`
+		TemperatureMax           []float64 `json:"temperature_2m_max"`
+		TemperatureMin           []float64 `json:"temperature_2m_min"`
+		Precipitation            []float64 `json:"precipitation_sum"`
+		PrecipitationProbability []float64 `json:"precipitation_probability_max"`
+	} `json:"daily"`
+}
+
+// Forecast implements Forecaster.
+func (o *OpenMeteo) Forecast(ctx context.Context, lat, lon float64, days int, units Units) (Forecast, error) {
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(lat, 'f', 4, 64))
+	q.Set("longitude", strconv.FormatFloat(lon, 'f', 4, 64))
+	q.Set("current", "temperature_2m,apparent_temperature,relative_humidity_2m,precipitation,wind_speed_10m,wind_direction_10m,weather_code")
+	q.Set("timezone", "auto")
+	if days > 0 {
+		q.Set("daily", "weather_code,temperature_2m_max,temperature_2m_min,precipitation_sum,precipitation_probability_max")
+		q.Set("forecast_days", strconv.Itoa(days))
+	}
+	if units == Imperial {
+		q.Set("temperature_unit", "fahrenheit")
+		q.Set("wind_speed_unit", "mph")
+		q.Set("precipitation_unit", "inch")
+	}
+	sep := "?"
+	if strings.Contains(o.endpoint, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.endpoint+sep+q.Encode(), nil)
+	if err != nil {
+		return Forecast{}, fmt.Errorf("weather: open-meteo: %w", err)
+	}
+	if o.userAgent != "" {
+		req.Header.Set("User-Agent", o.userAgent)
+	}
+	hc := o.httpClient
+	if hc == nil {
+		hc = llm.DefaultHTTPClient()
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return Forecast{}, fmt.Errorf("weather: open-meteo: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return Forecast{}, fmt.Errorf("weather: open-meteo: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var r openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return Forecast{}, fmt.Errorf("weather: open-meteo: decoding response: %w", err)
+	}
+
+	f := Forecast{
+		Timezone: r.Timezone,
+		Units:    units,
+		Current: Conditions{
+			Time:          r.Current.Time,
+			Temperature:   r.Current.Temperature,
+			FeelsLike:     r.Current.FeelsLike,
+			Humidity:      r.Current.Humidity,
+			Precipitation: r.Current.Precipitation,
+			WindSpeed:     r.Current.WindSpeed,
+			WindDirection: r.Current.WindDirection,
+			Code:          r.Current.Code,
+		},
+	}
+	d := r.Daily
+	at := func(s []float64, i int) float64 {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+	for i, date := range d.Time {
+		day := Day{
+			Date:                     date,
+			TemperatureMax:           at(d.TemperatureMax, i),
+			TemperatureMin:           at(d.TemperatureMin, i),
+			Precipitation:            at(d.Precipitation, i),
+			PrecipitationProbability: at(d.PrecipitationProbability, i),
+		}
+		if i < len(d.Code) {
+			day.Code = d.Code[i]
+		}
+		f.Days = append(f.Days, day)
+	}
+	return f, nil
+}
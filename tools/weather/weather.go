@@ -0,0 +1,257 @@
+// Package weather is a weather toolset backed by real, key-free services:
+// Nominatim (OpenStreetMap) to find places and Open-Meteo for current
+// conditions and forecasts. It works as-is for demos and as a realistic
+// integration test of tool calling against live APIs:
+//
+//	a := agent.New(provider)
+//	if err := a.RegisterToolset(weather.Toolset()); err != nil {
+//	    return err
+//	}
+//
+// Both backends sit behind interfaces, Geocoder and Forecaster, so tests
+// can plug in fixed data and production can plug in a paid service.
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-agent-sdk/tools"
+)
+
+// Place is a geocoded location.
+type Place struct {
+	Name      string // display name, e.g. "Paris, Île-de-France, France"
+	Latitude  float64
+	Longitude float64
+}
+
+// Geocoder finds places by name.
+type Geocoder interface {
+	// Geocode returns the places matching query, best match first, or
+	// none.
+	Geocode(ctx context.Context, query string) ([]Place, error)
+}
+
+// Units are the units a forecast is given in.
+type Units int
+
+const (
+	Metric   Units = iota // °C, km/h, mm
+	Imperial              // °F, mph, inches
+)
+
+func (u Units) temperature() string {
+	if u == Imperial {
+		return "°F"
+	}
+	return "°C"
+}
+
+func (u Units) speed() string {
+	if u == Imperial {
+		return "mph"
+	}
+	return "km/h"
+}
+
+func (u Units) precipitation() string {
+	if u == Imperial {
+		return "in"
+	}
+	return "mm"
+}
+
+// Conditions are the weather at one moment.
+type Conditions struct {
+	Time          string // local time at the place, "2006-01-02T15:04"
+	Temperature   float64
+	FeelsLike     float64
+	Humidity      float64 // percent
+	Precipitation float64
+	WindSpeed     float64
+	WindDirection float64 // degrees, where the wind comes from
+	Code          int     // WMO weather code
+}
+
+// Day is one day of a forecast.
+type Day struct {
+	Date                     string // "2006-01-02"
+	Code                     int    // WMO weather code
+	TemperatureMax           float64
+	TemperatureMin           float64
+	Precipitation            float64
+	PrecipitationProbability float64 // percent
+}
+
+// Forecast is the weather at a place: now and the coming days.
+type Forecast struct {
+	Timezone string // IANA name of the place's timezone
+	Units    Units
+	Current  Conditions
+	Days     []Day
+}
+
+// Forecaster looks up the weather at a point.
+type Forecaster interface {
+	// Forecast returns the current conditions and days days of forecast
+	// (0 for none), starting today.
+	Forecast(ctx context.Context, lat, lon float64, days int, units Units) (Forecast, error)
+}
+
+// Option configures Toolset.
+type Option func(*config)
+
+type config struct {
+	geocoder   Geocoder
+	forecaster Forecaster
+	units      Units
+	httpClient *http.Client
+	userAgent  string
+}
+
+// WithGeocoder replaces Nominatim.
+func WithGeocoder(g Geocoder) Option {
+	return func(c *config) {
+		c.geocoder = g
+	}
+}
+
+// WithForecaster replaces Open-Meteo.
+func WithForecaster(f Forecaster) Option {
+	return func(c *config) {
+		c.forecaster = f
+	}
+}
+
+// WithUnits sets the units the tools answer in. The default is Metric.
+func WithUnits(u Units) Option {
+	return func(c *config) {
+		c.units = u
+	}
+}
+
+// WithHTTPClient sets the client the default backends use, for timeouts
+// and proxies. The default is llm.DefaultHTTPClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *config) {
+		c.httpClient = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent the default backends send.
+// Nominatim's usage policy asks for one identifying your application;
+// the default names this SDK.
+func WithUserAgent(ua string) Option {
+	return func(c *config) {
+		c.userAgent = ua
+	}
+}
+
+// WeatherArgs are the get_weather tool's arguments.
+type WeatherArgs struct {
+	Location string `json:"location" description:"A place name, as specific as you can, e.g. \"Paris, France\" or \"Springfield, Illinois\"."`
+	Days     int    `json:"days,omitempty" description:"Days of forecast to include, starting today, up to 16. 0 gives the current weather only."`
+}
+
+// Toolset returns the weather tools as a toolset named "weather":
+// get_weather, for the weather anywhere by name.
+func Toolset(opts ...Option) tools.Toolset {
+	c := &config{userAgent: "go-agent-sdk weather tool"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.geocoder == nil {
+		c.geocoder = NewNominatim(NominatimHTTPClient(c.httpClient), NominatimUserAgent(c.userAgent))
+	}
+	if c.forecaster == nil {
+		c.forecaster = NewOpenMeteo(OpenMeteoHTTPClient(c.httpClient), OpenMeteoUserAgent(c.userAgent))
+	}
+	return tools.Toolset{
+		Name: "weather",
+		Prompt: "For weather questions, call get_weather rather than guessing; say which place the result is for, " +
+			"since names can be ambiguous.",
+		Tools: []tools.Tool{{
+			Name:        "get_weather",
+			Description: "Get the current weather and optionally a daily forecast for a place.",
+			Func:        c.getWeather,
+		}},
+	}
+}
+
+func (c *config) getWeather(ctx context.Context, args WeatherArgs) (string, error) {
+	if strings.TrimSpace(args.Location) == "" {
+		return "", fmt.Errorf("location is required")
+	}
+	if args.Days < 0 || args.Days > 16 {
+		return "", fmt.Errorf("days must be between 0 and 16")
+	}
+	places, err := c.geocoder.Geocode(ctx, args.Location)
+	if err != nil {
+		return "", err
+	}
+	if len(places) == 0 {
+		return "", fmt.Errorf("no place found for %q; try adding the region or country", args.Location)
+	}
+	p := places[0]
+	f, err := c.forecaster.Forecast(ctx, p.Latitude, p.Longitude, args.Days, c.units)
+	if err != nil {
+		return "", err
+	}
+	return Format(p, f), nil
+}
+
+// Format renders a forecast the way get_weather returns it.
+func Format(p Place, f Forecast) string {
+	u := f.Units
+	var b strings.Builder
+	fmt.Fprintf(&b, "Weather for %s (%.4f, %.4f)", p.Name, p.Latitude, p.Longitude)
+	if f.Timezone != "" {
+		fmt.Fprintf(&b, ", timezone %s", f.Timezone)
+	}
+	cur := f.Current
+	fmt.Fprintf(&b, "\nNow (%s): %s, %.1f%s (feels like %.1f%s), humidity %.0f%%, wind %.0f %s from %s, precipitation %.1f %s",
+		strings.Replace(cur.Time, "T", " ", 1), Describe(cur.Code),
+		cur.Temperature, u.temperature(), cur.FeelsLike, u.temperature(),
+		cur.Humidity, cur.WindSpeed, u.speed(), compass(cur.WindDirection),
+		cur.Precipitation, u.precipitation())
+	for _, d := range f.Days {
+		fmt.Fprintf(&b, "\n%s: %s, %.0f to %.0f%s, precipitation %.1f %s (%.0f%% chance)",
+			d.Date, Describe(d.Code), d.TemperatureMin, d.TemperatureMax, u.temperature(),
+			d.Precipitation, u.precipitation(), d.PrecipitationProbability)
+	}
+	return b.String()
+}
+
+// wmoCodes are the WMO weather interpretation codes Open-Meteo uses.
+var wmoCodes = map[int]string{
+	0: "clear sky", 1: "mainly clear", 2: "partly cloudy", 3: "overcast",
+	45: "fog", 48: "depositing rime fog",
+	51: "light drizzle", 53: "moderate drizzle", 55: "dense drizzle",
+	56: "light freezing drizzle", 57: "dense freezing drizzle",
+	61: "slight rain", 63: "moderate rain", 65: "heavy rain",
+	66: "light freezing rain", 67: "heavy freezing rain",
+	71: "slight snow", 73: "moderate snow", 75: "heavy snow", 77: "snow grains",
+	80: "slight rain showers", 81: "moderate rain showers", 82: "violent rain showers",
+	85: "slight snow showers", 86: "heavy snow showers",
+	95: "thunderstorm", 96: "thunderstorm with slight hail", 99: "thunderstorm with heavy hail",
+}
+
+// Describe names a WMO weather code.
+func Describe(code int) string {
+	if s, ok := wmoCodes[code]; ok {
+		return s
+	}
+	return fmt.Sprintf("weather code %d", code)
+}
+
+func compass(deg float64) string {
+	dirs := []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+	i := int((deg+22.5)/45) % 8
+	if i < 0 {
+		i += 8
+	}
+	return dirs[i]
+}
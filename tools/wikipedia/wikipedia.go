@@ -0,0 +1,323 @@
+// Package wikipedia is a research toolset over Wikipedia's public REST
+// API, which needs no key: a default knowledge source for demos and
+// baseline agents.
+//
+//	a := agent.New(provider)
+//	if err := a.RegisterToolset(wikipedia.Toolset()); err != nil {
+//	    return err
+//	}
+//
+// wikipedia_lookup gets an article's summary by title or topic, falling
+// back to a search when there's no article by that name and listing the
+// options when the name is ambiguous ("Mercury"); wikipedia_search lists
+// the articles matching a query.
+package wikipedia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-agent-sdk/llm"
+	"go-agent-sdk/tools"
+)
+
+// ErrNotFound is returned by Client.Summary when there's no article with
+// the title.
+var ErrNotFound = errors.New("wikipedia: no such article")
+
+// Summary is the lead of an article.
+type Summary struct {
+	Title          string
+	Description    string // the short description, e.g. "Planet in the Solar System"
+	Extract        string // the first paragraph or so, as plain text
+	URL            string
+	WikidataID     string // the article's Wikidata item, e.g. "Q308"
+	Disambiguation bool   // the title names several things; Extract lists none of them
+}
+
+// Result is one search hit.
+type Result struct {
+	Title       string
+	Description string
+	Excerpt     string // the matching text, as plain text
+}
+
+// Client talks to one language edition of Wikipedia.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+}
+
+// Option configures a Client and Toolset.
+type Option func(*Client)
+
+// WithLanguage picks the language edition, such as "de" or "ja". The
+// default is "en".
+func WithLanguage(lang string) Option {
+	return func(c *Client) {
+		c.baseURL = "https://" + lang + ".wikipedia.org"
+	}
+}
+
+// WithBaseURL sets the wiki's address, for another MediaWiki with the
+// REST API or a test server.
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client. The default is
+// llm.DefaultHTTPClient, which an llm.EgressPolicy can restrict.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent. Wikimedia's policy asks for one
+// naming your application and a contact, and may block generic ones; the
+// default names this SDK.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// New returns a Client for English Wikipedia, unless the options say
+// otherwise.
+func New(opts ...Option) *Client {
+	c := &Client{baseURL: "https://en.wikipedia.org", userAgent: "go-agent-sdk wikipedia tool"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type summaryResponse struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Extract      string `json:"extract"`
+	WikibaseItem string `json:"wikibase_item"`
+	ContentURLs  struct {
+		Desktop struct {
+			Page string `json:"page"`
+		} `json:"desktop"`
+	} `json:"content_urls"`
+}
+
+// Summary returns the summary of the article titled title, following
+// redirects, or ErrNotFound.
+func (c *Client) Summary(ctx context.Context, title string) (Summary, error) {
+	key := strings.ReplaceAll(strings.TrimSpace(title), " ", "_")
+	var r summaryResponse
+	if err := c.get(ctx, "/api/rest_v1/page/summary/"+url.PathEscape(key), &r); err != nil {
+		return Summary{}, err
+	}
+	return Summary{
+		Title:          r.Title,
+		Description:    r.Description,
+		Extract:        r.Extract,
+		URL:            r.ContentURLs.Desktop.Page,
+		WikidataID:     r.WikibaseItem,
+		Disambiguation: r.Type == "disambiguation",
+	}, nil
+}
+
+type searchResponse struct {
+	Pages []struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Excerpt     string `json:"excerpt"`
+	} `json:"pages"`
+}
+
+// Search returns up to limit articles matching query, best first.
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	q := url.Values{}
+	q.Set("q", query)
+	q.Set("limit", strconv.Itoa(limit))
+	var r searchResponse
+	if err := c.get(ctx, "/w/rest.php/v1/search/page?"+q.Encode(), &r); err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(r.Pages))
+	for i, p := range r.Pages {
+		results[i] = Result{Title: p.Title, Description: p.Description, Excerpt: plainText(p.Excerpt)}
+	}
+	return results, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("wikipedia: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+	hc := c.httpClient
+	if hc == nil {
+		hc = llm.DefaultHTTPClient()
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("wikipedia: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("wikipedia: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("wikipedia: decoding response: %w", err)
+	}
+	return nil
+}
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// plainText strips the markup search excerpts come with.
+func plainText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(tags.ReplaceAllString(s, "")))
+}
+
+// LookupArgs are the wikipedia_lookup tool's arguments.
+type LookupArgs struct {
+	Topic string `json:"topic" description:"An article title or topic, e.g. \"Marie Curie\" or \"Mercury (planet)\"."`
+}
+
+// SearchArgs are the wikipedia_search tool's arguments.
+type SearchArgs struct {
+	Query string `json:"query" description:"Search terms."`
+	Limit int    `json:"limit,omitempty" description:"How many results, up to 20. The default is 5."`
+}
+
+// Toolset returns the Wikipedia tools as a toolset named "wikipedia".
+func Toolset(opts ...Option) tools.Toolset {
+	c := New(opts...)
+	return tools.Toolset{
+		Name: "wikipedia",
+		Prompt: "Use wikipedia_lookup to check facts about people, places, events and concepts instead of relying on memory, " +
+			"and mention the article you used. If a lookup is ambiguous, pick the option that fits the conversation and look it up.",
+		Tools: []tools.Tool{
+			{
+				Name:        "wikipedia_lookup",
+				Description: "Get the summary of a Wikipedia article by title or topic. Lists the options when the name is ambiguous.",
+				Func:        c.lookup,
+			},
+			{
+				Name:        "wikipedia_search",
+				Description: "Search Wikipedia and list matching articles with a short description of each.",
+				Func:        c.search,
+			},
+		},
+	}
+}
+
+func (c *Client) lookup(ctx context.Context, args LookupArgs) (string, error) {
+	if strings.TrimSpace(args.Topic) == "" {
+		return "", errors.New("topic is required")
+	}
+	s, err := c.Summary(ctx, args.Topic)
+	if errors.Is(err, ErrNotFound) {
+		// Not a title: take the best search hit instead.
+		hits, serr := c.Search(ctx, args.Topic, 1)
+		if serr != nil {
+			return "", serr
+		}
+		if len(hits) == 0 {
+			return "", fmt.Errorf("no Wikipedia article matches %q", args.Topic)
+		}
+		s, err = c.Summary(ctx, hits[0].Title)
+	}
+	if err != nil {
+		return "", err
+	}
+	if s.Disambiguation {
+		return c.disambiguate(ctx, s)
+	}
+	return formatSummary(s), nil
+}
+
+// disambiguate lists what an ambiguous title could mean.
+func (c *Client) disambiguate(ctx context.Context, s Summary) (string, error) {
+	hits, err := c.Search(ctx, strings.TrimSuffix(s.Title, " (disambiguation)"), 10)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q is ambiguous. Look up one of these by its exact title:", s.Title)
+	n := 0
+	for _, h := range hits {
+		if h.Title == s.Title || strings.HasSuffix(h.Title, "(disambiguation)") {
+			continue
+		}
+		fmt.Fprintf(&b, "\n- %s", h.Title)
+		if h.Description != "" {
+			fmt.Fprintf(&b, ": %s", h.Description)
+		}
+		n++
+	}
+	if n == 0 {
+		fmt.Fprintf(&b, "\n(no candidates found; see %s)", s.URL)
+	}
+	return b.String(), nil
+}
+
+func (c *Client) search(ctx context.Context, args SearchArgs) (string, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	hits, err := c.Search(ctx, args.Query, min(limit, 20))
+	if err != nil {
+		return "", err
+	}
+	if len(hits) == 0 {
+		return fmt.Sprintf("No Wikipedia articles match %q.", args.Query), nil
+	}
+	var b strings.Builder
+	for i, h := range hits {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d. %s", i+1, h.Title)
+		if h.Description != "" {
+			fmt.Fprintf(&b, " - %s", h.Description)
+		}
+		if h.Excerpt != "" {
+			fmt.Fprintf(&b, "\n   %s", h.Excerpt)
+		}
+	}
+	return b.String(), nil
+}
+
+func formatSummary(s Summary) string {
+	var b strings.Builder
+	b.WriteString(s.Title)
+	if s.Description != "" {
+		fmt.Fprintf(&b, " (%s)", s.Description)
+	}
+	fmt.Fprintf(&b, "\n\n%s", s.Extract)
+	if s.URL != "" {
+		fmt.Fprintf(&b, "\n\nSource: %s", s.URL)
+	}
+	if s.WikidataID != "" {
+		fmt.Fprintf(&b, "\nWikidata: %s", s.WikidataID)
+	}
+	return b.String()
+}
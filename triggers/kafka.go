@@ -0,0 +1,367 @@
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go-agent-sdk/llm"
+)
+
+// Kafka is a Source and Publisher for Kafka, through a Confluent REST
+// Proxy (API v2) rather than the Kafka protocol, so it needs no client
+// library. It consumes as a member of a consumer group, with automatic
+// commits off: an offset is committed only once every message up to it
+// has been acknowledged, so nothing is skipped after a crash, though
+// messages acknowledged out of order may be delivered again.
+type Kafka struct {
+	base     string
+	group    string
+	topics   []string
+	client   *http.Client
+	user     string
+	pass     string
+	reset    string
+	poll     time.Duration
+	maxBytes int
+
+	buf []kafkaRecord
+
+	mu        sync.Mutex
+	instance  string                     // the consumer instance's base URI
+	pending   map[kafkaPartition][]int64 // offsets delivered and not yet committed, in order
+	acked     map[kafkaPartition]map[int64]bool
+	ready     map[kafkaPartition]int64 // the offset up to which everything is acknowledged
+	committed map[kafkaPartition]int64
+	commitMu  sync.Mutex
+}
+
+type kafkaPartition struct {
+	topic     string
+	partition int
+}
+
+type kafkaRecord struct {
+	Topic     string  `json:"topic"`
+	Key       *string `json:"key"`
+	Value     *string `json:"value"`
+	Partition int     `json:"partition"`
+	Offset    int64   `json:"offset"`
+}
+
+// KafkaOption configures NewKafka.
+type KafkaOption func(*Kafka)
+
+// KafkaHTTPClient sets the HTTP client used to reach the proxy. The
+// default is llm.DefaultHTTPClient.
+func KafkaHTTPClient(c *http.Client) KafkaOption {
+	return func(k *Kafka) {
+		k.client = c
+	}
+}
+
+// KafkaBasicAuth logs in to the proxy with HTTP basic authentication.
+func KafkaBasicAuth(user, pass string) KafkaOption {
+	return func(k *Kafka) {
+		k.user, k.pass = user, pass
+	}
+}
+
+// KafkaOffsetReset sets where a group with no committed offsets starts:
+// "earliest" (the default) or "latest".
+func KafkaOffsetReset(reset string) KafkaOption {
+	return func(k *Kafka) {
+		k.reset = reset
+	}
+}
+
+// KafkaPollTimeout sets how long one fetch waits on the proxy for records.
+// The default is 5s.
+func KafkaPollTimeout(d time.Duration) KafkaOption {
+	return func(k *Kafka) {
+		k.poll = d
+	}
+}
+
+// NewKafka creates a consumer in group on the REST Proxy at baseURL, such
+// as "http://rest-proxy:8082", subscribed to topics. With no topics it
+// only publishes, and creates nothing on the proxy. Close it to leave the
+// group promptly; the proxy drops idle consumers after a few minutes
+// anyway.
+func NewKafka(ctx context.Context, baseURL, group string, topics []string, opts ...KafkaOption) (*Kafka, error) {
+	k := &Kafka{
+		base:      strings.TrimRight(baseURL, "/"),
+		group:     group,
+		topics:    topics,
+		reset:     "earliest",
+		poll:      5 * time.Second,
+		maxBytes:  1 << 20,
+		pending:   make(map[kafkaPartition][]int64),
+		acked:     make(map[kafkaPartition]map[int64]bool),
+		ready:     make(map[kafkaPartition]int64),
+		committed: make(map[kafkaPartition]int64),
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if len(topics) > 0 {
+		if err := k.subscribe(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// subscribe creates the consumer instance and subscribes it to the topics.
+func (k *Kafka) subscribe(ctx context.Context) error {
+	var created struct {
+		BaseURI string `json:"base_uri"`
+	}
+	err := k.do(ctx, http.MethodPost, k.base+"/consumers/"+url.PathEscape(k.group), map[string]any{
+		"format":             "binary",
+		"auto.offset.reset":  k.reset,
+		"auto.commit.enable": "false",
+	}, &created)
+	if err != nil {
+		return fmt.Errorf("triggers: kafka: creating consumer: %w", err)
+	}
+	if created.BaseURI == "" {
+		return errors.New("triggers: kafka: creating consumer: no base_uri in response")
+	}
+	k.mu.Lock()
+	k.instance = created.BaseURI
+	k.mu.Unlock()
+	if err := k.do(ctx, http.MethodPost, created.BaseURI+"/subscription", map[string]any{"topics": k.topics}, nil); err != nil {
+		return fmt.Errorf("triggers: kafka: subscribing: %w", err)
+	}
+	return nil
+}
+
+// Receive implements Source.
+func (k *Kafka) Receive(ctx context.Context) (Message, error) {
+	k.mu.Lock()
+	instance := k.instance
+	k.mu.Unlock()
+	if instance == "" {
+		return Message{}, errors.New("triggers: kafka: not subscribed to any topics")
+	}
+	for len(k.buf) == 0 {
+		if err := k.fetch(ctx); err != nil {
+			return Message{}, err
+		}
+	}
+	r := k.buf[0]
+	k.buf = k.buf[1:]
+	m := Message{Topic: r.Topic, Partition: r.Partition, Offset: r.Offset}
+	var err error
+	if r.Key != nil {
+		if m.Key, err = base64.StdEncoding.DecodeString(*r.Key); err != nil {
+			return Message{}, fmt.Errorf("triggers: kafka: decoding key of %s/%d@%d: %w", r.Topic, r.Partition, r.Offset, err)
+		}
+	}
+	if r.Value != nil {
+		if m.Value, err = base64.StdEncoding.DecodeString(*r.Value); err != nil {
+			return Message{}, fmt.Errorf("triggers: kafka: decoding value of %s/%d@%d: %w", r.Topic, r.Partition, r.Offset, err)
+		}
+	}
+
+	tp := kafkaPartition{r.Topic, r.Partition}
+	k.mu.Lock()
+	k.pending[tp] = append(k.pending[tp], r.Offset)
+	k.mu.Unlock()
+	return m, nil
+}
+
+// fetch polls the proxy for records. An instance the proxy has dropped
+// for being idle is created again; what it had delivered and not
+// committed comes round again.
+func (k *Kafka) fetch(ctx context.Context) error {
+	k.mu.Lock()
+	instance := k.instance
+	k.mu.Unlock()
+	u := fmt.Sprintf("%s/records?timeout=%d&max_bytes=%d", instance, k.poll.Milliseconds(), k.maxBytes)
+	err := k.do(ctx, http.MethodGet, u, nil, &k.buf)
+	var se *kafkaError
+	if errors.As(err, &se) && se.status == http.StatusNotFound {
+		k.mu.Lock()
+		clear(k.pending)
+		clear(k.acked)
+		clear(k.ready)
+		clear(k.committed)
+		k.mu.Unlock()
+		return k.subscribe(ctx)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("triggers: kafka: fetching records: %w", err)
+	}
+	return nil
+}
+
+// Ack implements Source, committing the partition's offset as far as
+// every message before it has been acknowledged too.
+func (k *Kafka) Ack(ctx context.Context, m Message) error {
+	tp := kafkaPartition{m.Topic, m.Partition}
+	k.mu.Lock()
+	if k.acked[tp] == nil {
+		k.acked[tp] = make(map[int64]bool)
+	}
+	k.acked[tp][m.Offset] = true
+	advanced := false
+	for len(k.pending[tp]) > 0 && k.acked[tp][k.pending[tp][0]] {
+		o := k.pending[tp][0]
+		delete(k.acked[tp], o)
+		k.pending[tp] = k.pending[tp][1:]
+		k.ready[tp] = o
+		advanced = true
+	}
+	k.mu.Unlock()
+	if !advanced {
+		return nil
+	}
+
+	// Commit under commitMu, reading ready afresh, so commits never go
+	// backwards however the acknowledgements interleave.
+	k.commitMu.Lock()
+	defer k.commitMu.Unlock()
+	k.mu.Lock()
+	offset := k.ready[tp]
+	last, ok := k.committed[tp]
+	instance := k.instance
+	k.mu.Unlock()
+	if ok && offset <= last {
+		return nil
+	}
+	// The proxy commits the offset after the one given.
+	body := map[string]any{"offsets": []map[string]any{{"topic": tp.topic, "partition": tp.partition, "offset": offset}}}
+	if err := k.do(ctx, http.MethodPost, instance+"/offsets", body, nil); err != nil {
+		return fmt.Errorf("triggers: kafka: committing %s/%d@%d: %w", tp.topic, tp.partition, offset, err)
+	}
+	k.mu.Lock()
+	k.committed[tp] = offset
+	k.mu.Unlock()
+	return nil
+}
+
+// Publish implements Publisher. A nil key leaves the partition to Kafka.
+func (k *Kafka) Publish(ctx context.Context, topic string, key, value []byte) error {
+	rec := map[string]any{"value": base64.StdEncoding.EncodeToString(value)}
+	if key != nil {
+		rec["key"] = base64.StdEncoding.EncodeToString(key)
+	}
+	var resp struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := k.do(ctx, http.MethodPost, k.base+"/topics/"+url.PathEscape(topic), map[string]any{"records": []any{rec}}, &resp); err != nil {
+		return fmt.Errorf("triggers: kafka: publishing to %s: %w", topic, err)
+	}
+	for _, o := range resp.Offsets {
+		if o.Error != "" {
+			return fmt.Errorf("triggers: kafka: publishing to %s: %s", topic, o.Error)
+		}
+	}
+	return nil
+}
+
+// Close deletes the consumer instance, leaving the group.
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	instance := k.instance
+	k.instance = ""
+	k.mu.Unlock()
+	if instance == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := k.do(ctx, http.MethodDelete, instance, nil, nil)
+	if err != nil {
+		return fmt.Errorf("triggers: kafka: closing consumer: %w", err)
+	}
+	return nil
+}
+
+// kafkaError is an error response from the proxy.
+type kafkaError struct {
+	status  int
+	code    int
+	message string
+}
+
+func (e *kafkaError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("HTTP %d", e.status)
+	}
+	return fmt.Sprintf("HTTP %d: %s (error %d)", e.status, e.message, e.code)
+}
+
+// do sends a request to the proxy and decodes the JSON response into out,
+// if it isn't nil.
+func (k *Kafka) do(ctx context.Context, method, u string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil || method == http.MethodDelete {
+		ct := "application/vnd.kafka.v2+json"
+		if strings.HasPrefix(u, k.base+"/topics/") {
+			ct = "application/vnd.kafka.binary.v2+json"
+		}
+		req.Header.Set("Content-Type", ct)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Accept", "application/vnd.kafka.binary.v2+json")
+	} else {
+		req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	}
+	if k.user != "" {
+		req.SetBasicAuth(k.user, k.pass)
+	}
+
+	hc := k.client
+	if hc == nil {
+		hc = llm.DefaultHTTPClient()
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		json.Unmarshal(data, &e)
+		return &kafkaError{status: resp.StatusCode, code: e.ErrorCode, message: e.Message}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,283 @@
+// Package triggers runs an agent for each message arriving on a queue -
+// a Kafka topic or a NATS subject - and publishes what it answered to an
+// output topic. It's the wiring an event-driven system needs around an
+// agent: a worker pool, retries, a dead-letter topic, and
+// acknowledgement only once a message is dealt with.
+//
+//	src, err := triggers.NewKafka(ctx, "http://rest-proxy:8082", "agents", []string{"tickets"})
+//	if err != nil {
+//	    return err
+//	}
+//	c := triggers.New(src, func(ctx context.Context, m triggers.Message) (*agent.Agent, error) {
+//	    return agent.New(provider, agent.WithSystemPrompts(triage)), nil
+//	},
+//	    triggers.WithConcurrency(8),
+//	    triggers.WithOutput(src, "tickets-triaged"),
+//	    triggers.WithDeadLetter(src, "tickets-failed"),
+//	)
+//	err = c.Run(ctx) // until ctx is cancelled
+//
+// Each message gets its own agent from the factory, since an Agent holds
+// one conversation. Delivery is at least once where the queue supports
+// it (Kafka), so a message can be run twice after a crash; at most once
+// where it doesn't (core NATS).
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-agent-sdk/agent"
+)
+
+// Message is one message from a Source.
+type Message struct {
+	Topic     string            // the Kafka topic or NATS subject
+	Key       []byte            // the Kafka key; nil for NATS
+	Value     []byte            // the payload
+	Headers   map[string]string // NATS headers, where the source supports them
+	Partition int               // Kafka only
+	Offset    int64             // Kafka only
+	ReplyTo   string            // the NATS reply subject, if the sender set one
+}
+
+// Source delivers messages.
+type Source interface {
+	// Receive blocks until the next message arrives or ctx is done. It's
+	// called from one goroutine at a time.
+	Receive(ctx context.Context) (Message, error)
+	// Ack reports m as dealt with, so it isn't delivered again. It may be
+	// called from several goroutines, in any order.
+	Ack(ctx context.Context, m Message) error
+}
+
+// Publisher sends messages to a topic.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key, value []byte) error
+}
+
+// AgentFactory returns the agent to run for a message - usually a new one
+// each time, set up the same way.
+type AgentFactory func(ctx context.Context, m Message) (*agent.Agent, error)
+
+// Result is what the Consumer publishes for each message, as JSON: the
+// run's result, or the error once retries ran out.
+type Result struct {
+	Topic     string           `json:"topic"`
+	Partition int              `json:"partition,omitempty"`
+	Offset    int64            `json:"offset,omitempty"`
+	Key       string           `json:"key,omitempty"`
+	Input     string           `json:"input"`
+	Attempts  int              `json:"attempts"`
+	Run       *agent.RunResult `json:"run,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// Consumer reads messages from a Source and runs an agent on each.
+type Consumer struct {
+	src         Source
+	factory     AgentFactory
+	concurrency int
+	retries     int
+	backoff     time.Duration
+	prompt      func(Message) string
+	out         Publisher
+	outTopic    string
+	dead        Publisher
+	deadTopic   string
+	onResult    func(Result)
+	onError     func(error)
+}
+
+// Option configures a Consumer.
+type Option func(*Consumer)
+
+// WithConcurrency sets how many messages are processed at once. The
+// default is 1, which keeps messages in order.
+func WithConcurrency(n int) Option {
+	return func(c *Consumer) {
+		c.concurrency = n
+	}
+}
+
+// WithRetries sets how many more times a failed run is tried, waiting
+// backoff before the first retry and twice as long before each one after.
+// The default is 2 retries from 1s. Each attempt gets a fresh agent.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Consumer) {
+		c.retries, c.backoff = n, backoff
+	}
+}
+
+// WithPrompt sets how a message becomes the agent's input. The default is
+// the payload as it is.
+func WithPrompt(prompt func(Message) string) Option {
+	return func(c *Consumer) {
+		c.prompt = prompt
+	}
+}
+
+// WithOutput publishes a Result for every message to topic on p, failures
+// included, keyed by the message's key.
+func WithOutput(p Publisher, topic string) Option {
+	return func(c *Consumer) {
+		c.out, c.outTopic = p, topic
+	}
+}
+
+// WithDeadLetter publishes the original payload of messages that failed
+// every attempt to topic on p, so they can be inspected and replayed.
+func WithDeadLetter(p Publisher, topic string) Option {
+	return func(c *Consumer) {
+		c.dead, c.deadTopic = p, topic
+	}
+}
+
+// OnResult calls fn with every message's Result, from the worker that
+// processed it.
+func OnResult(fn func(Result)) Option {
+	return func(c *Consumer) {
+		c.onResult = fn
+	}
+}
+
+// OnError calls fn with errors the Consumer carries on after: a failed
+// run, a publish or ack that failed. By default they're dropped.
+func OnError(fn func(error)) Option {
+	return func(c *Consumer) {
+		c.onError = fn
+	}
+}
+
+// New returns a Consumer running an agent from factory for each message
+// from src.
+func New(src Source, factory AgentFactory, opts ...Option) *Consumer {
+	c := &Consumer{
+		src:         src,
+		factory:     factory,
+		concurrency: 1,
+		retries:     2,
+		backoff:     time.Second,
+		prompt:      func(m Message) string { return string(m.Value) },
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.concurrency = max(c.concurrency, 1)
+	return c
+}
+
+// Run consumes messages until ctx is done or the source fails, then waits
+// for the messages in flight. It returns ctx's error or the source's.
+//
+// A message is acknowledged once it's dealt with: run successfully, or
+// failed every attempt and been published as such (and dead-lettered, with
+// WithDeadLetter). If publishing fails, it isn't acknowledged, so a queue
+// that redelivers will bring it back.
+func (c *Consumer) Run(ctx context.Context) error {
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		m, err := c.src.Receive(ctx)
+		if err != nil {
+			<-sem
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("triggers: receiving: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// Finish what was started even when Run is being stopped.
+			c.handle(context.WithoutCancel(ctx), m)
+		}()
+	}
+}
+
+// handle runs one message, publishes the outcome and acknowledges it.
+func (c *Consumer) handle(ctx context.Context, m Message) {
+	res := c.process(ctx, m)
+	if c.onResult != nil {
+		c.onResult(res)
+	}
+	if res.Error != "" {
+		c.report(fmt.Errorf("triggers: %s/%d@%d failed after %d attempts: %s", m.Topic, m.Partition, m.Offset, res.Attempts, res.Error))
+	}
+
+	if c.out != nil {
+		data, err := json.Marshal(res)
+		if err == nil {
+			err = c.out.Publish(ctx, c.outTopic, m.Key, data)
+		}
+		if err != nil {
+			c.report(fmt.Errorf("triggers: publishing result: %w", err))
+			return
+		}
+	}
+	if res.Error != "" && c.dead != nil {
+		if err := c.dead.Publish(ctx, c.deadTopic, m.Key, m.Value); err != nil {
+			c.report(fmt.Errorf("triggers: dead-lettering: %w", err))
+			return
+		}
+	}
+	if err := c.src.Ack(ctx, m); err != nil {
+		c.report(fmt.Errorf("triggers: acknowledging: %w", err))
+	}
+}
+
+// process runs the agent on m, with retries.
+func (c *Consumer) process(ctx context.Context, m Message) Result {
+	res := Result{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Key: string(m.Key), Input: c.prompt(m)}
+	wait := c.backoff
+	var err error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				res.Error = ctx.Err().Error()
+				return res
+			case <-t.C:
+			}
+			wait *= 2
+		}
+		res.Attempts = attempt + 1
+		var a *agent.Agent
+		if a, err = c.factory(ctx, m); err != nil {
+			continue
+		}
+		var run *agent.RunResult
+		if run, err = a.RunWithResult(ctx, res.Input); err == nil {
+			res.Run = run
+			return res
+		}
+		// Guardrail and moderation rejections won't change on a retry.
+		var gv *agent.GuardrailViolation
+		var me *agent.ModerationError
+		if errors.As(err, &gv) || errors.As(err, &me) {
+			res.Run = run
+			break
+		}
+	}
+	res.Error = err.Error()
+	return res
+}
+
+func (c *Consumer) report(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
@@ -0,0 +1,174 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PGVector stores records in PostgreSQL with the pgvector extension, one
+// row per record:
+//
+//	CREATE TABLE embeddings (
+//	    id        TEXT PRIMARY KEY,
+//	    embedding vector(1536) NOT NULL,
+//	    content   TEXT NOT NULL,
+//	    metadata  JSONB NOT NULL
+//	)
+//
+// CreateTable makes it, with an HNSW index for cosine distance. Bring
+// your own driver:
+//
+//	db, err := sql.Open("pgx", dsn)
+//	store := vectorstore.NewPGVector(db)
+//	err = store.CreateTable(ctx, 1536)
+type PGVector struct {
+	db    *sql.DB
+	table string
+}
+
+// PGVectorOption configures a PGVector store.
+type PGVectorOption func(*PGVector)
+
+// WithTable uses a table other than "embeddings". The name goes into the
+// queries as it is, so don't take it from user input.
+func WithTable(name string) PGVectorOption {
+	return func(s *PGVector) {
+		s.table = name
+	}
+}
+
+// NewPGVector returns a store on db.
+func NewPGVector(db *sql.DB, opts ...PGVectorOption) *PGVector {
+	s := &PGVector{db: db, table: "embeddings"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateTable creates the extension, the table for vectors of the given
+// dimensions and its index, if they don't exist.
+func (s *PGVector) CreateTable(ctx context.Context, dimensions int) error {
+	stmts := []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, embedding vector(%d) NOT NULL, content TEXT NOT NULL, metadata JSONB NOT NULL)",
+			s.table, dimensions),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_embedding_idx ON %s USING hnsw (embedding vector_cosine_ops)", s.table, s.table),
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("vectorstore: pgvector: %w", err)
+		}
+	}
+	return nil
+}
+
+// Upsert inserts records, replacing those with the same ID, in one
+// transaction.
+func (s *PGVector) Upsert(ctx context.Context, records ...Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("vectorstore: pgvector: %w", err)
+	}
+	defer tx.Rollback() // a no-op after Commit
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, embedding, content, metadata) VALUES ($1, $2::vector, $3, $4::jsonb)
+ON CONFLICT (id) DO UPDATE SET embedding = EXCLUDED.embedding, content = EXCLUDED.content, metadata = EXCLUDED.metadata`, s.table)
+	for _, r := range records {
+		meta, err := metadataJSON(r.Metadata)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, r.ID, vectorLiteral(r.Vector), r.Text, meta); err != nil {
+			return fmt.Errorf("vectorstore: pgvector: upserting %s: %w", r.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("vectorstore: pgvector: %w", err)
+	}
+	return nil
+}
+
+// Query finds the nearest records by cosine distance. The filter is a
+// JSONB containment check, which a GIN index on metadata speeds up.
+func (s *PGVector) Query(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	meta, err := metadataJSON(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT id, content, metadata, 1 - (embedding <=> $1::vector) FROM %s
+WHERE metadata @> $2::jsonb ORDER BY embedding <=> $1::vector LIMIT $3`, s.table),
+		vectorLiteral(vector), meta, k)
+	if err != nil {
+		return nil, fmt.Errorf("vectorstore: pgvector: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var meta []byte
+		if err := rows.Scan(&m.ID, &m.Text, &meta, &m.Score); err != nil {
+			return nil, fmt.Errorf("vectorstore: pgvector: %w", err)
+		}
+		if err := json.Unmarshal(meta, &m.Metadata); err != nil {
+			return nil, fmt.Errorf("vectorstore: pgvector: metadata of %s: %w", m.ID, err)
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("vectorstore: pgvector: %w", err)
+	}
+	return matches, nil
+}
+
+func (s *PGVector) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+	_, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", s.table, strings.Join(placeholders, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("vectorstore: pgvector: %w", err)
+	}
+	return nil
+}
+
+// vectorLiteral writes v the way pgvector parses it: [1,2,3].
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func metadataJSON(m map[string]string) (string, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("vectorstore: %w", err)
+	}
+	return string(data), nil
+}
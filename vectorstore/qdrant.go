@@ -0,0 +1,189 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"go-agent-sdk/llm"
+)
+
+// Payload keys Qdrant records keep the Record's own fields under, next to
+// the metadata.
+const (
+	qdrantIDKey   = "_id"
+	qdrantTextKey = "_text"
+)
+
+// Qdrant stores records in a Qdrant collection over its REST API. Qdrant
+// only takes integers and UUIDs as point IDs, so each record's ID is
+// hashed into a UUID and kept in the payload; the metadata is the rest of
+// the payload, which is what filters match against.
+//
+//	store := vectorstore.NewQdrant("http://localhost:6333", "docs")
+//	err := store.CreateCollection(ctx, 1536)
+type Qdrant struct {
+	baseURL    string
+	collection string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// QdrantOption configures a Qdrant store.
+type QdrantOption func(*Qdrant)
+
+// WithAPIKey sets the api-key header, for Qdrant Cloud and secured
+// servers.
+func WithAPIKey(key string) QdrantOption {
+	return func(q *Qdrant) {
+		q.apiKey = key
+	}
+}
+
+// WithHTTPClient overrides the default HTTP client.
+func WithHTTPClient(hc *http.Client) QdrantOption {
+	return func(q *Qdrant) {
+		q.httpClient = hc
+	}
+}
+
+// NewQdrant returns a store on the collection at baseURL.
+func NewQdrant(baseURL, collection string, opts ...QdrantOption) *Qdrant {
+	q := &Qdrant{baseURL: baseURL, collection: collection, httpClient: &http.Client{}}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// CreateCollection creates the collection for vectors of the given
+// dimensions, compared by cosine similarity. It fails if the collection
+// exists.
+func (q *Qdrant) CreateCollection(ctx context.Context, dimensions int) error {
+	body := map[string]any{"vectors": map[string]any{"size": dimensions, "distance": "Cosine"}}
+	return q.do(ctx, "PUT", "", body, nil)
+}
+
+type qdrantPoint struct {
+	ID      string         `json:"id"`
+	Vector  []float32      `json:"vector,omitempty"`
+	Payload map[string]any `json:"payload,omitempty"`
+	Score   float64        `json:"score,omitempty"`
+}
+
+func (q *Qdrant) Upsert(ctx context.Context, records ...Record) error {
+	points := make([]qdrantPoint, len(records))
+	for i, r := range records {
+		payload := make(map[string]any, len(r.Metadata)+2)
+		for k, v := range r.Metadata {
+			payload[k] = v
+		}
+		payload[qdrantIDKey] = r.ID
+		payload[qdrantTextKey] = r.Text
+		points[i] = qdrantPoint{ID: pointID(r.ID), Vector: r.Vector, Payload: payload}
+	}
+	return q.do(ctx, "PUT", "/points?wait=true", map[string]any{"points": points}, nil)
+}
+
+func (q *Qdrant) Query(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	body := map[string]any{"vector": vector, "limit": k, "with_payload": true}
+	if len(filter) > 0 {
+		var must []map[string]any
+		for key, v := range filter {
+			must = append(must, map[string]any{"key": key, "match": map[string]any{"value": v}})
+		}
+		body["filter"] = map[string]any{"must": must}
+	}
+
+	var resp struct {
+		Result []qdrantPoint `json:"result"`
+	}
+	if err := q.do(ctx, "POST", "/points/search", body, &resp); err != nil {
+		return nil, err
+	}
+	matches := make([]Match, len(resp.Result))
+	for i, p := range resp.Result {
+		m := Match{Score: p.Score}
+		for key, v := range p.Payload {
+			s, _ := v.(string)
+			switch key {
+			case qdrantIDKey:
+				m.ID = s
+			case qdrantTextKey:
+				m.Text = s
+			default:
+				if m.Metadata == nil {
+					m.Metadata = make(map[string]string)
+				}
+				m.Metadata[key] = s
+			}
+		}
+		matches[i] = m
+	}
+	return matches, nil
+}
+
+func (q *Qdrant) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	points := make([]string, len(ids))
+	for i, id := range ids {
+		points[i] = pointID(id)
+	}
+	return q.do(ctx, "POST", "/points/delete?wait=true", map[string]any{"points": points}, nil)
+}
+
+// pointID turns a record ID into the UUID Qdrant keys it by: a name-based
+// (version 5 style) UUID, so the same ID always lands on the same point.
+func pointID(id string) string {
+	sum := sha1.Sum([]byte(id))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// do sends a request to the collection's path and decodes the response
+// into out, if it isn't nil.
+func (q *Qdrant) do(ctx context.Context, method, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("vectorstore: qdrant: %w", err)
+	}
+	u := q.baseURL + "/collections/" + url.PathEscape(q.collection) + path
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("vectorstore: qdrant: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vectorstore: qdrant: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("vectorstore: qdrant: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vectorstore: %w", llm.NewAPIError("qdrant", resp, respBody))
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("vectorstore: qdrant: decoding response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package vectorstore keeps embeddings and finds the ones nearest a query
+// vector - the retrieval half of retrieval-augmented generation.
+//
+// Store is the interface; Memory keeps records in the process, PGVector
+// in PostgreSQL with the pgvector extension, and Qdrant in a Qdrant
+// server. Vectors come from an llm.EmbeddingsProvider:
+//
+//	vectors, err := embedder.CreateEmbeddings(ctx, []string{chunk})
+//	err = store.Upsert(ctx, vectorstore.Record{
+//	    ID: "handbook#12", Vector: vectors[0], Text: chunk,
+//	    Metadata: map[string]string{"source": "handbook.pdf"},
+//	})
+//
+//	matches, err := store.Query(ctx, queryVector, 5, vectorstore.Filter{"source": "handbook.pdf"})
+//
+// Similarity is cosine similarity everywhere, so scores compare across
+// stores.
+package vectorstore
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"go-agent-sdk/llm"
+)
+
+// Record is one stored vector and what it stands for.
+type Record struct {
+	ID       string            `json:"id"`
+	Vector   []float32         `json:"vector,omitempty"`
+	Text     string            `json:"text,omitempty"`     // the chunk the vector was made from
+	Metadata map[string]string `json:"metadata,omitempty"` // source, page, author - anything to filter or cite by
+}
+
+// Match is a record found by Query.
+type Match struct {
+	Record
+	Score float64 `json:"score"` // cosine similarity to the query, higher is closer
+}
+
+// Filter restricts Query to records whose metadata has every one of these
+// key-value pairs. nil or empty matches everything.
+type Filter map[string]string
+
+// Matches reports whether metadata satisfies the filter.
+func (f Filter) Matches(metadata map[string]string) bool {
+	for k, v := range f {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Store keeps records and searches them by vector. Implementations are
+// safe for concurrent use.
+type Store interface {
+	// Upsert adds records, replacing any with the same ID.
+	Upsert(ctx context.Context, records ...Record) error
+	// Query returns up to k records matching filter, nearest to vector
+	// first. Stores may leave Match.Vector empty.
+	Query(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error)
+	// Delete removes records by ID. Unknown IDs are ignored.
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// Memory is a Store held in the process and searched by brute force -
+// for tests, and for corpora of a few tens of thousands of chunks, which
+// it searches in milliseconds.
+type Memory struct {
+	mu      sync.RWMutex
+	records map[string]Record
+}
+
+// NewMemory returns an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{records: make(map[string]Record)}
+}
+
+func (m *Memory) Upsert(ctx context.Context, records ...Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range records {
+		r.Vector = slices.Clone(r.Vector)
+		r.Metadata = maps.Clone(r.Metadata)
+		m.records[r.ID] = r
+	}
+	return nil
+}
+
+func (m *Memory) Query(ctx context.Context, vector []float32, k int, filter Filter) ([]Match, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	m.mu.RLock()
+	matches := make([]Match, 0, len(m.records))
+	for _, r := range m.records {
+		if filter.Matches(r.Metadata) {
+			matches = append(matches, Match{Record: r, Score: llm.CosineSimilarity(vector, r.Vector)})
+		}
+	}
+	m.mu.RUnlock()
+
+	// Ties go by ID, so results don't depend on map order.
+	slices.SortFunc(matches, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.ID, b.ID))
+	})
+	return matches[:min(k, len(matches))], nil
+}
+
+func (m *Memory) Delete(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.records, id)
+	}
+	return nil
+}
+
+// Len returns how many records are stored.
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.records)
+}